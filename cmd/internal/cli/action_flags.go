@@ -7,7 +7,9 @@ package cli
 
 import (
 	"os"
+	"strings"
 
+	"github.com/spf13/pflag"
 	"github.com/sylabs/singularity/pkg/cmdline"
 )
 
@@ -15,19 +17,31 @@ import (
 var (
 	AppName            string
 	BindPaths          []string
+	BindDataPaths      []string
 	Mounts             []string
+	Volumes            []string
+	TmpfsMounts        []string
+	Devices            []string
 	HomePath           string
 	OverlayPath        []string
 	ScratchPath        []string
+	ScratchSize        int
 	WorkdirPath        string
 	PwdPath            string
 	ShellPath          string
 	Hostname           string
 	Network            string
 	NetworkArgs        []string
-	DNS                string
+	DNS                []string
+	DNSSearch          []string
+	AddHosts           []string
 	Security           []string
+	UserSpec           string
 	CgroupsTOML        string
+	CgroupsParent      string
+	CgroupsMemory      string
+	CgroupsMemorySwap  string
+	CgroupsCPUs        string
 	VMRAM              string
 	VMCPU              string
 	VMIP               string
@@ -35,24 +49,40 @@ var (
 	FuseMount          []string
 	SingularityEnv     []string
 	SingularityEnvFile string
+	SingularityEnvJSON string
 	NoMount            []string
+	UIDMap             []string
+	GIDMap             []string
+	RewritePath        string
+	Setgroups          string
+	DevMode            string
+	CommitPath         string
+	RootfsPropagation  string
 
 	IsBoot          bool
 	IsFakeroot      bool
 	IsCleanEnv      bool
+	KeepEnv         []string
+	EnvPassThrough  []string
 	IsCompat        bool
 	IsContained     bool
 	IsContainAll    bool
 	IsWritable      bool
 	IsWritableTmpfs bool
+	IsWritableCwd   bool
 	Nvidia          bool
 	NvCCLI          bool
 	Rocm            bool
 	NoHome          bool
 	NoInit          bool
+	IsInit          bool
+	InitBin         string
 	NoNvidia        bool
 	NoRocm          bool
 	NoUmask         bool
+	Umask           string
+	Ulimits         []string
+	NoEval          bool
 	VM              bool
 	VMErr           bool
 	IsSyOS          bool
@@ -69,6 +99,9 @@ var (
 	NoPrivs   bool
 	AddCaps   string
 	DropCaps  string
+
+	CgroupsCPUShares int
+	CgroupsPidsLimit int
 )
 
 // --app
@@ -88,9 +121,64 @@ var actionBindFlag = cmdline.Flag{
 	DefaultValue: []string{},
 	Name:         "bind",
 	ShortHand:    "B",
-	Usage:        "a user-bind path specification.  spec has the format src[:dest[:opts]], where src and dest are outside and inside paths.  If dest is not given, it is set equal to src.  Mount options ('opts') may be specified as 'ro' (read-only) or 'rw' (read/write, which is the default). Multiple bind paths can be given by a comma separated list.",
+	Usage:        "a user-bind path specification.  spec has the format src[:dest[:opts]], where src and dest are outside and inside paths.  If dest is not given, it is set equal to src.  A leading ~ or ~user and $VAR or ${VAR} environment variables are expanded in src and dest.  Mount options ('opts') may be specified as 'ro' (read-only) or 'rw' (read/write, which is the default), a propagation among 'shared' (as root or with a user namespace), 'slave', 'private' or their recursive 'r' variants, and 'wait' or 'wait-timeout=<duration>' to wait, 30s by default, for src to be a mounted, non-empty file system. 'overlay' mounts src as the read-only lower layer of an overlay at dest, with a temporary upper layer, or with the upper layer stored in <dir> with 'overlay-upper=<dir>', as root or with a user namespace. Multiple bind paths can be given by a comma separated list.",
 	EnvKeys:      []string{"BIND", "BINDPATH"},
 	Tag:          "<spec>",
+	EnvHandler:   envBindHandler,
+}
+
+// envMounts holds the --mount specifications found in the bind
+// environment variables by envBindHandler.
+var envMounts []string
+
+// mountSpecKeys are the keys a --mount specification starts with.
+var mountSpecKeys = []string{"type", "source", "src", "destination", "dst", "target"}
+
+// envBindHandler appends the bind paths set in the bind environment
+// variables to the --bind values. Each line of these variables holds
+// either bind paths or a --mount specification, so the full --mount
+// syntax and options are also available from the environment.
+func envBindHandler(flag *pflag.Flag, value string) error {
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if isMountSpec(line) {
+			envMounts = append(envMounts, line)
+			continue
+		}
+		if err := cmdline.EnvAppendValue(flag, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isMountSpec returns true if spec is a --mount specification rather
+// than a list of bind paths.
+func isMountSpec(spec string) bool {
+	key := strings.SplitN(strings.SplitN(spec, ",", 2)[0], "=", 2)
+	if len(key) != 2 {
+		return false
+	}
+	for _, k := range mountSpecKeys {
+		if strings.TrimPrefix(key[0], `"`) == k {
+			return true
+		}
+	}
+	return false
+}
+
+// --bind-data
+var actionBindDataFlag = cmdline.Flag{
+	ID:           "actionBindDataFlag",
+	Value:        &BindDataPaths,
+	DefaultValue: []string{},
+	Name:         "bind-data",
+	Usage:        "a data image bind path specification.  spec has the format src[:dest[:opts]], where src is a data image (e.g. a SIF holding a squashfs partition) outside the container, and dest the path where its content is mounted read-only inside the container. Options ('opts') may be specified as 'image-src' (path of the data image to mount, default to '/') and 'id' (SIF partition ID). Multiple bind paths can be given by a comma separated list.",
+	EnvKeys:      []string{"BIND_DATA"},
+	Tag:          "<spec>",
 	EnvHandler:   cmdline.EnvAppendValue,
 }
 
@@ -100,13 +188,26 @@ var actionMountFlag = cmdline.Flag{
 	Value:        &Mounts,
 	DefaultValue: []string{},
 	Name:         "mount",
-	Usage:        "a mount specification e.g. 'type=bind,source=/opt,destination=/hostopt'.",
+	Usage:        "a mount specification e.g. 'type=bind,source=/opt,destination=/hostopt,bind-propagation=rslave'.",
 	EnvKeys:      []string{"MOUNT"},
 	Tag:          "<spec>",
 	EnvHandler:   cmdline.EnvAppendValue,
 	StringArray:  true,
 }
 
+// --volume
+var actionVolumeFlag = cmdline.Flag{
+	ID:           "actionVolumeFlag",
+	Value:        &Volumes,
+	DefaultValue: []string{},
+	Name:         "volume",
+	Usage:        "mount a named volume created with 'singularity volume create', spec has the format name:dest[:opts] with the same options as --bind",
+	EnvKeys:      []string{"VOLUME"},
+	Tag:          "<spec>",
+	EnvHandler:   cmdline.EnvAppendValue,
+	StringArray:  true,
+}
+
 // -H|--home
 var actionHomeFlag = cmdline.Flag{
 	ID:           "actionHomeFlag",
@@ -114,11 +215,23 @@ var actionHomeFlag = cmdline.Flag{
 	DefaultValue: CurrentUser.HomeDir,
 	Name:         "home",
 	ShortHand:    "H",
-	Usage:        "a home directory specification.  spec can either be a src path or src:dest pair.  src is the source path of the home directory outside the container and dest overrides the home directory within the container.",
+	Usage:        "a home directory specification.  spec can either be a src path or src:dest pair.  src is the source path of the home directory outside the container and dest overrides the home directory within the container. spec can also be tmpfs[:size=<size>] for an empty home directory backed by a tmpfs, discarded at exit.",
 	EnvKeys:      []string{"HOME"},
 	Tag:          "<spec>",
 }
 
+// --tmpfs
+var actionTmpfsFlag = cmdline.Flag{
+	ID:           "actionTmpfsFlag",
+	Value:        &TmpfsMounts,
+	DefaultValue: []string{},
+	Name:         "tmpfs",
+	Usage:        "mount a tmpfs at path inside the container, options are the docker run --tmpfs ones e.g. '/scratch:size=64m,mode=1777', and default to noexec with mode 1777. May be given multiple times",
+	EnvKeys:      []string{"TMPFS"},
+	Tag:          "<path[:opts]>",
+	StringArray:  true,
+}
+
 // -o|--overlay
 var actionOverlayFlag = cmdline.Flag{
 	ID:           "actionOverlayFlag",
@@ -126,7 +239,7 @@ var actionOverlayFlag = cmdline.Flag{
 	DefaultValue: []string{},
 	Name:         "overlay",
 	ShortHand:    "o",
-	Usage:        "use an overlayFS image for persistent data storage or as read-only layer of container",
+	Usage:        "use an overlayFS image for persistent data storage or as read-only layer of container, add :ro for a read-only layer. May be given multiple times, later overlays are stacked above earlier ones and at most one overlay can be writable, it is always the top layer",
 	EnvKeys:      []string{"OVERLAY", "OVERLAYIMAGE"},
 	Tag:          "<path>",
 }
@@ -138,11 +251,21 @@ var actionScratchFlag = cmdline.Flag{
 	DefaultValue: []string{},
 	Name:         "scratch",
 	ShortHand:    "S",
-	Usage:        "include a scratch directory within the container that is linked to a temporary dir (use -W to force location)",
+	Usage:        "include a writable scratch directory within the container that is linked to a temporary dir, may be given multiple times (use -W to force location)",
 	EnvKeys:      []string{"SCRATCH", "SCRATCHDIR"},
 	Tag:          "<path>",
 }
 
+// --scratch-size
+var actionScratchSizeFlag = cmdline.Flag{
+	ID:           "actionScratchSizeFlag",
+	Value:        &ScratchSize,
+	DefaultValue: 0,
+	Name:         "scratch-size",
+	Usage:        "mount each scratch directory on a dedicated tmpfs of this size in MiB, instead of the shared session directory (ignored with -W/--workdir)",
+	EnvKeys:      []string{"SCRATCH_SIZE"},
+}
+
 // -W|--workdir
 var actionWorkdirFlag = cmdline.Flag{
 	ID:           "actionWorkdirFlag",
@@ -150,7 +273,7 @@ var actionWorkdirFlag = cmdline.Flag{
 	DefaultValue: "",
 	Name:         "workdir",
 	ShortHand:    "W",
-	Usage:        "working directory to be used for /tmp, /var/tmp and $HOME (if -c/--contain was also used)",
+	Usage:        "working directory to be used for /tmp, /var/tmp and $HOME (if -c/--contain was also used), and for the --writable-tmpfs session storage",
 	EnvKeys:      []string{"WORKDIR"},
 	Tag:          "<path>",
 }
@@ -183,7 +306,7 @@ var actionPwdFlag = cmdline.Flag{
 	Value:        &PwdPath,
 	DefaultValue: "",
 	Name:         "pwd",
-	Usage:        "initial working directory for payload process inside the container",
+	Usage:        "initial working directory for payload process inside the container, created if missing when the container is writable",
 	EnvKeys:      []string{"PWD", "TARGET_PWD"},
 	Tag:          "<path>",
 }
@@ -205,7 +328,7 @@ var actionNetworkFlag = cmdline.Flag{
 	Value:        &Network,
 	DefaultValue: "bridge",
 	Name:         "network",
-	Usage:        "specify desired network type separated by commas, each network will bring up a dedicated interface inside container",
+	Usage:        "specify desired network type separated by commas, each network will bring up a dedicated interface inside container ('none' isolates the container without any interface up)",
 	EnvKeys:      []string{"NETWORK"},
 	Tag:          "<name>",
 }
@@ -225,12 +348,34 @@ var actionNetworkArgsFlag = cmdline.Flag{
 var actionDNSFlag = cmdline.Flag{
 	ID:           "actionDnsFlag",
 	Value:        &DNS,
-	DefaultValue: "",
+	DefaultValue: []string{},
 	Name:         "dns",
-	Usage:        "list of DNS server separated by commas to add in resolv.conf",
+	Usage:        "list of DNS server separated by commas to add in resolv.conf (can be specified multiple times)",
 	EnvKeys:      []string{"DNS"},
 }
 
+// --dns-search
+var actionDNSSearchFlag = cmdline.Flag{
+	ID:           "actionDNSSearchFlag",
+	Value:        &DNSSearch,
+	DefaultValue: []string{},
+	Name:         "dns-search",
+	Usage:        "list of DNS search domains separated by commas to add in resolv.conf (can be specified multiple times)",
+	EnvKeys:      []string{"DNS_SEARCH"},
+}
+
+// --add-host
+var actionAddHostFlag = cmdline.Flag{
+	ID:           "actionAddHostFlag",
+	Value:        &AddHosts,
+	DefaultValue: []string{},
+	Name:         "add-host",
+	Usage:        "add a custom host to IP mapping in /etc/hosts, e.g. 'myhost:10.0.0.1' (can be specified multiple times)",
+	EnvKeys:      []string{"ADD_HOST"},
+	Tag:          "<name:ip>",
+	StringArray:  true,
+}
+
 // --security
 var actionSecurityFlag = cmdline.Flag{
 	ID:           "actionSecurityFlag",
@@ -241,6 +386,16 @@ var actionSecurityFlag = cmdline.Flag{
 	EnvKeys:      []string{"SECURITY"},
 }
 
+// --user
+var actionUserFlag = cmdline.Flag{
+	ID:           "actionUserFlag",
+	Value:        &UserSpec,
+	DefaultValue: "",
+	Name:         "user",
+	Usage:        "run the container process as this user and optional group, names are resolved against the container passwd and group files (root only)",
+	Tag:          "<user[:group]>",
+}
+
 // --apply-cgroups
 var actionApplyCgroupsFlag = cmdline.Flag{
 	ID:           "actionApplyCgroupsFlag",
@@ -251,6 +406,69 @@ var actionApplyCgroupsFlag = cmdline.Flag{
 	EnvKeys:      []string{"APPLY_CGROUPS"},
 }
 
+// --cgroup-parent
+var actionCgroupParentFlag = cmdline.Flag{
+	ID:           "actionCgroupParentFlag",
+	Value:        &CgroupsParent,
+	DefaultValue: "",
+	Name:         "cgroup-parent",
+	Usage:        "place the container cgroup under this parent, a slice name e.g. my.slice with systemd cgroups, or a path e.g. /my.slice otherwise (requires cgroups)",
+	EnvKeys:      []string{"CGROUP_PARENT"},
+	Tag:          "<parent>",
+}
+
+// --memory
+var actionMemoryFlag = cmdline.Flag{
+	ID:           "actionMemoryFlag",
+	Value:        &CgroupsMemory,
+	DefaultValue: "",
+	Name:         "memory",
+	Usage:        "memory limit for container processes, with an optional unit suffix (b, k, m, g), e.g. 4G (requires cgroups)",
+	EnvKeys:      []string{"MEMORY"},
+	Tag:          "<size>",
+}
+
+// --memory-swap
+var actionMemorySwapFlag = cmdline.Flag{
+	ID:           "actionMemorySwapFlag",
+	Value:        &CgroupsMemorySwap,
+	DefaultValue: "",
+	Name:         "memory-swap",
+	Usage:        "memory plus swap limit for container processes, -1 for unlimited swap (requires --memory and cgroups)",
+	EnvKeys:      []string{"MEMORY_SWAP"},
+	Tag:          "<size>",
+}
+
+// --cpus
+var actionCPUsFlag = cmdline.Flag{
+	ID:           "actionCPUsFlag",
+	Value:        &CgroupsCPUs,
+	DefaultValue: "",
+	Name:         "cpus",
+	Usage:        "number of CPUs available to container processes, may be fractional e.g. 1.5 (requires cgroups)",
+	EnvKeys:      []string{"CPUS"},
+}
+
+// --cpu-shares
+var actionCPUSharesFlag = cmdline.Flag{
+	ID:           "actionCPUSharesFlag",
+	Value:        &CgroupsCPUShares,
+	DefaultValue: 0,
+	Name:         "cpu-shares",
+	Usage:        "relative CPU weight of container processes (requires cgroups)",
+	EnvKeys:      []string{"CPU_SHARES"},
+}
+
+// --pids-limit
+var actionPidsLimitFlag = cmdline.Flag{
+	ID:           "actionPidsLimitFlag",
+	Value:        &CgroupsPidsLimit,
+	DefaultValue: 0,
+	Name:         "pids-limit",
+	Usage:        "maximum number of container processes, -1 for unlimited (requires cgroups)",
+	EnvKeys:      []string{"PIDS_LIMIT"},
+}
+
 // --vm-ram
 var actionVMRAMFlag = cmdline.Flag{
 	ID:           "actionVMRAMFlag",
@@ -347,6 +565,28 @@ var actionCleanEnvFlag = cmdline.Flag{
 	EnvKeys:      []string{"CLEANENV"},
 }
 
+// --keep-env
+var actionKeepEnvFlag = cmdline.Flag{
+	ID:           "actionKeepEnvFlag",
+	Value:        &KeepEnv,
+	DefaultValue: []string{},
+	Name:         "keep-env",
+	Usage:        "with --cleanenv, keep host environment variables matching this comma separated list of names or glob patterns, e.g. 'SLURM_*,OMP_NUM_THREADS'. Variables set with --env or SINGULARITYENV_ take precedence",
+	EnvKeys:      []string{"KEEP_ENV"},
+	Tag:          "<var,...>",
+}
+
+// --env-pass-through
+var actionEnvPassThroughFlag = cmdline.Flag{
+	ID:           "actionEnvPassThroughFlag",
+	Value:        &EnvPassThrough,
+	DefaultValue: []string{},
+	Name:         "env-pass-through",
+	Usage:        "always pass host environment variables matching this comma separated list of names or glob patterns, e.g. 'PBS_*', overriding the container environment, with or without --cleanenv. Variables set with --env or SINGULARITYENV_ take precedence",
+	EnvKeys:      []string{"ENV_PASS_THROUGH"},
+	Tag:          "<var,...>",
+}
+
 // --compat
 var actionCompatFlag = cmdline.Flag{
 	ID:           "actionCompatFlag",
@@ -430,6 +670,38 @@ var actionWritableTmpfsFlag = cmdline.Flag{
 	EnvKeys:      []string{"WRITABLE_TMPFS"},
 }
 
+// --writable-cwd
+var actionWritableCwdFlag = cmdline.Flag{
+	ID:           "actionWritableCwdFlag",
+	Value:        &IsWritableCwd,
+	DefaultValue: false,
+	Name:         "writable-cwd",
+	Usage:        "makes the current working directory accessible as read-write with non persistent data when it's located in the container image (with overlay support only)",
+	EnvKeys:      []string{"WRITABLE_CWD"},
+}
+
+// --commit
+var actionCommitFlag = cmdline.Flag{
+	ID:           "actionCommitFlag",
+	Value:        &CommitPath,
+	DefaultValue: "",
+	Name:         "commit",
+	Usage:        "on successful exit, save the changes made in the --writable-tmpfs overlay to a new SIF image",
+	EnvKeys:      []string{"COMMIT"},
+	Tag:          "<path>",
+}
+
+// --rootfs-propagation
+var actionRootfsPropagationFlag = cmdline.Flag{
+	ID:           "actionRootfsPropagationFlag",
+	Value:        &RootfsPropagation,
+	DefaultValue: "",
+	Name:         "rootfs-propagation",
+	Usage:        "mount propagation of the container root filesystem and the mounts below it: private, slave or shared",
+	EnvKeys:      []string{"ROOTFS_PROPAGATION"},
+	Tag:          "<private|slave|shared>",
+}
+
 // --no-home
 var actionNoHomeFlag = cmdline.Flag{
 	ID:           "actionNoHomeFlag",
@@ -460,6 +732,27 @@ var actionNoInitFlag = cmdline.Flag{
 	EnvKeys:      []string{"NOSHIMINIT"},
 }
 
+// --init
+var actionInitFlag = cmdline.Flag{
+	ID:           "actionInitFlag",
+	Value:        &IsInit,
+	DefaultValue: false,
+	Name:         "init",
+	Usage:        "run an init process as PID 1 that forwards signals and reaps zombie processes, implies --pid",
+	EnvKeys:      []string{"INIT"},
+}
+
+// --init-bin
+var actionInitBinFlag = cmdline.Flag{
+	ID:           "actionInitBinFlag",
+	Value:        &InitBin,
+	DefaultValue: "",
+	Name:         "init-bin",
+	Usage:        "absolute path of an init binary in the container, e.g. /usr/bin/tini, run as PID 1 in place of the bundled shim, or under the shim for instances, implies --init",
+	EnvKeys:      []string{"INIT_BIN"},
+	Tag:          "<path>",
+}
+
 // hidden flag to disable nvidia bindings when 'always use nv = yes'
 var actionNoNvidiaFlag = cmdline.Flag{
 	ID:           "actionNoNvidiaFlag",
@@ -565,6 +858,62 @@ var actionUserNamespaceFlag = cmdline.Flag{
 	EnvKeys:      []string{"USERNS", "UNSHARE_USERNS"},
 }
 
+// --uidmap
+var actionUIDMapFlag = cmdline.Flag{
+	ID:           "actionUIDMapFlag",
+	Value:        &UIDMap,
+	DefaultValue: []string{},
+	Name:         "uidmap",
+	Usage:        "map a range of user IDs in a new user namespace, spec has the format containerID:hostID:count. Host IDs other than your own user ID must be allocated to you in /etc/subuid. Implies --userns, may be given multiple times",
+	EnvKeys:      []string{"UIDMAP"},
+	Tag:          "<spec>",
+}
+
+// --gidmap
+var actionGIDMapFlag = cmdline.Flag{
+	ID:           "actionGIDMapFlag",
+	Value:        &GIDMap,
+	DefaultValue: []string{},
+	Name:         "gidmap",
+	Usage:        "map a range of group IDs in a new user namespace, spec has the format containerID:hostID:count. Host IDs other than your own group ID must be allocated to you in /etc/subgid. Implies --userns, may be given multiple times",
+	EnvKeys:      []string{"GIDMAP"},
+	Tag:          "<spec>",
+}
+
+// --setgroups
+var actionSetgroupsFlag = cmdline.Flag{
+	ID:           "actionSetgroupsFlag",
+	Value:        &Setgroups,
+	DefaultValue: "",
+	Name:         "setgroups",
+	Usage:        "allow or deny the setgroups syscall in the user namespace, deny keeps your supplementary groups with --fakeroot, --uidmap or --gidmap (requires a setuid installation), allow is the default with these options",
+	EnvKeys:      []string{"SETGROUPS"},
+	Tag:          "<allow|deny>",
+}
+
+// --dev
+var actionDevFlag = cmdline.Flag{
+	ID:           "actionDevFlag",
+	Value:        &DevMode,
+	DefaultValue: "",
+	Name:         "dev",
+	Usage:        "select the container /dev: minimal for essential devices only, full for the host /dev, custom:<dev>[,<dev>...] to add host devices or glob patterns (e.g. /dev/nvidia*) to a minimal /dev",
+	EnvKeys:      []string{"DEV"},
+	Tag:          "<minimal|full|custom:list>",
+}
+
+// --device
+var actionDeviceFlag = cmdline.Flag{
+	ID:           "actionDeviceFlag",
+	Value:        &Devices,
+	DefaultValue: []string{},
+	Name:         "device",
+	Usage:        "add a host block or character device to the container, with the cgroup permissions (a combination of r, w and m, default rwm) allowed when cgroups limits are applied. May be given multiple times",
+	EnvKeys:      []string{"DEVICE"},
+	Tag:          "<path[:permissions]>",
+	StringArray:  true,
+}
+
 // --keep-privs
 var actionKeepPrivsFlag = cmdline.Flag{
 	ID:           "actionKeepPrivsFlag",
@@ -621,7 +970,7 @@ var actionEnvFlag = cmdline.Flag{
 	Value:        &SingularityEnv,
 	DefaultValue: []string{},
 	Name:         "env",
-	Usage:        "pass environment variable to contained process",
+	Usage:        "pass environment variable to contained process, KEY+=VALUE/KEY^=VALUE append/prepend VALUE to the container value, separated by ':' for *PATH variables and by a space otherwise",
 }
 
 // --env-file
@@ -634,6 +983,17 @@ var actionEnvFileFlag = cmdline.Flag{
 	EnvKeys:      []string{"ENV_FILE"},
 }
 
+// --env-json
+var actionEnvJSONFlag = cmdline.Flag{
+	ID:           "actionEnvJSONFlag",
+	Value:        &SingularityEnvJSON,
+	DefaultValue: "",
+	Name:         "env-json",
+	Usage:        "pass environment variables from a JSON object of string values, or from a file containing one, to contained process",
+	EnvKeys:      []string{"ENV_JSON"},
+	Tag:          "<json|file>",
+}
+
 // --no-umask
 var actionNoUmaskFlag = cmdline.Flag{
 	ID:           " actionNoUmask",
@@ -644,6 +1004,49 @@ var actionNoUmaskFlag = cmdline.Flag{
 	EnvKeys:      []string{"NO_UMASK"},
 }
 
+// --umask
+var actionUmaskFlag = cmdline.Flag{
+	ID:           "actionUmaskFlag",
+	Value:        &Umask,
+	DefaultValue: "",
+	Name:         "umask",
+	Usage:        "set this octal umask for the container process, e.g. 0022, instead of propagating the current umask",
+	EnvKeys:      []string{"UMASK"},
+	Tag:          "<mask>",
+}
+
+// --ulimit
+var actionUlimitFlag = cmdline.Flag{
+	ID:           "actionUlimitFlag",
+	Value:        &Ulimits,
+	DefaultValue: []string{},
+	Name:         "ulimit",
+	Usage:        "set a resource limit for the container process, e.g. 'nofile=1024:4096' or 'memlock=unlimited'. Raising a hard limit requires root privileges. May be given multiple times",
+	EnvKeys:      []string{"ULIMIT"},
+	Tag:          "<name=soft[:hard]>",
+	StringArray:  true,
+}
+
+// --no-eval
+var actionNoEvalFlag = cmdline.Flag{
+	ID:           "actionNoEval",
+	Value:        &NoEval,
+	DefaultValue: false,
+	Name:         "no-eval",
+	Usage:        "do not shell evaluate command line arguments passed to OCI container CMD/ENTRYPOINT, including by the runscript of images built by older versions",
+	EnvKeys:      []string{"NO_EVAL"},
+}
+
+// --rewrite-path
+var actionRewritePathFlag = cmdline.Flag{
+	ID:           "actionRewritePath",
+	Value:        &RewritePath,
+	DefaultValue: "",
+	Name:         "rewrite-path",
+	Usage:        "how the default PATH is combined with the container PATH: append, prepend or image-only (default set by 'rewrite path' in singularity.conf)",
+	EnvKeys:      []string{"REWRITE_PATH"},
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(ExecCmd)
@@ -666,14 +1069,26 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionAllowSetuidFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionAppFlag, actionsCmd...)
 		cmdManager.RegisterFlagForCmd(&actionApplyCgroupsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionCgroupParentFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionMemoryFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionMemorySwapFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionCPUsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionCPUSharesFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionPidsLimitFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionBindFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionBindDataFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionCleanEnvFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionKeepEnvFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionEnvPassThroughFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionCompatFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionContainAllFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionContainFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionContainLibsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDisableCacheFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&commonCacheDirFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDNSFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDNSSearchFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionAddHostFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDropCapsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionFakerootFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionFuseMountFlag, actionsInstanceCmd...)
@@ -688,6 +1103,8 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionNoHomeFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoMountFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoInitFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionInitFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionInitBinFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoNvidiaFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoRocmFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoPrivsFlag, actionsInstanceCmd...)
@@ -700,11 +1117,20 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionPidNamespaceFlag, actionsCmd...)
 		cmdManager.RegisterFlagForCmd(&actionPwdFlag, actionsCmd...)
 		cmdManager.RegisterFlagForCmd(&actionScratchFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionScratchSizeFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionTmpfsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionSecurityFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionShellFlag, ShellCmd)
 		cmdManager.RegisterFlagForCmd(&actionSyOSFlag, ShellCmd)
 		cmdManager.RegisterFlagForCmd(&actionTmpDirFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionUserNamespaceFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionVolumeFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionUserFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionUIDMapFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionGIDMapFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionSetgroupsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDevFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDeviceFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionUtsNamespaceFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionVMCPUFlag, actionsCmd...)
 		cmdManager.RegisterFlagForCmd(&actionVMErrFlag, actionsCmd...)
@@ -714,13 +1140,22 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&actionWorkdirFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionWritableFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionWritableTmpfsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionWritableCwdFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionCommitFlag, actionsCmd...)
+		cmdManager.RegisterFlagForCmd(&actionRootfsPropagationFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&commonOldNoHTTPSFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&commonRegistriesConfFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&dockerLoginFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&dockerPasswordFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&dockerUsernameFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionEnvFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionEnvFileFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionEnvJSONFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionNoUmaskFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionUmaskFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionUlimitFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionNoEvalFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionRewritePathFlag, actionsInstanceCmd...)
 	})
 }
@@ -0,0 +1,67 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestEnvBindHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		wantBinds  []string
+		wantMounts []string
+	}{
+		{
+			name:      "bind paths",
+			value:     "/opt:/other:ro,rslave,/srv",
+			wantBinds: []string{"/opt:/other:ro", "rslave", "/srv"},
+		},
+		{
+			name:       "mount spec",
+			value:      "type=bind,source=/opt,destination=/other,ro,bind-propagation=rslave",
+			wantMounts: []string{"type=bind,source=/opt,destination=/other,ro,bind-propagation=rslave"},
+		},
+		{
+			name:       "mixed",
+			value:      "/srv\nsrc=/opt,dst=/other,ro\n\n/tmp:/data:rw",
+			wantBinds:  []string{"/srv", "/tmp:/data:rw"},
+			wantMounts: []string{"src=/opt,dst=/other,ro"},
+		},
+		{
+			name:      "bind path with equal sign",
+			value:     "/opt/a=b:/other",
+			wantBinds: []string{"/opt/a=b:/other"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var binds []string
+			fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			fs.StringSliceVar(&binds, "bind", nil, "")
+
+			envMounts = nil
+			defer func() { envMounts = nil }()
+
+			if err := envBindHandler(fs.Lookup("bind"), tt.value); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(binds) != 0 || len(tt.wantBinds) != 0 {
+				if !reflect.DeepEqual(binds, tt.wantBinds) {
+					t.Errorf("got binds %q, want %q", binds, tt.wantBinds)
+				}
+			}
+			if !reflect.DeepEqual(envMounts, tt.wantMounts) {
+				t.Errorf("got mounts %q, want %q", envMounts, tt.wantMounts)
+			}
+		})
+	}
+}
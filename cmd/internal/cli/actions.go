@@ -21,6 +21,7 @@ import (
 	"github.com/sylabs/singularity/internal/pkg/client/oci"
 	"github.com/sylabs/singularity/internal/pkg/client/oras"
 	"github.com/sylabs/singularity/internal/pkg/client/shub"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/internal/pkg/util/uri"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
@@ -31,7 +32,7 @@ const (
 
 func getCacheHandle(cfg cache.Config) *cache.Handle {
 	h, err := cache.New(cache.Config{
-		ParentDir: os.Getenv(cache.DirEnv),
+		ParentDir: cacheParentDir(),
 		Disable:   cfg.Disable,
 	})
 	if err != nil {
@@ -41,6 +42,23 @@ func getCacheHandle(cfg cache.Config) *cache.Handle {
 	return h
 }
 
+// cacheParentDir returns the cache location set with --cachedir for the
+// current command, or by the environment for the commands without the flag.
+func cacheParentDir() string {
+	dir := cacheDir
+	if dir == "" {
+		dir = os.Getenv(cache.DirEnv)
+	}
+	if dir == "" {
+		return ""
+	}
+	abs, err := fs.Abs(dir)
+	if err != nil {
+		sylog.Fatalf("While resolving cache directory %s: %s", dir, err)
+	}
+	return abs
+}
+
 // actionPreRun will run replaceURIWithImage and will also do the proper path unsetting
 func actionPreRun(cmd *cobra.Command, args []string) {
 	// For compatibility - we still set USER_PATH so it will be visible in the
@@ -75,7 +93,7 @@ func handleOras(ctx context.Context, imgCache *cache.Handle, cmd *cobra.Command,
 	if err != nil {
 		return "", fmt.Errorf("while creating docker credentials: %v", err)
 	}
-	return oras.Pull(ctx, imgCache, pullFrom, tmpDir, ociAuth)
+	return oras.Pull(ctx, imgCache, pullFrom, tmpDir, ociAuth, "")
 }
 
 func handleLibrary(ctx context.Context, imgCache *cache.Handle, pullFrom string) (string, error) {
@@ -0,0 +1,87 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/sylabs/singularity/internal/pkg/util/starter"
+	"github.com/sylabs/singularity/pkg/runtime/engine/config"
+	singularityConfig "github.com/sylabs/singularity/pkg/runtime/engine/singularity/config"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// runAndCommit runs the container with its writable tmpfs overlay upper
+// directory stored on the host, in the --workdir directory or the temporary
+// directory, and commits the changes recorded in it to the --commit SIF
+// image when the container exits successfully. It returns the exit code
+// of the container, or an error exit code if the changes couldn't be
+// committed.
+func runAndCommit(ctx context.Context, engineConfig *singularityConfig.EngineConfig, image, name string, cfg *config.Common, ops ...starter.CommandOp) int {
+	dir, err := ioutil.TempDir(WorkdirPath, "commit-")
+	if err != nil {
+		sylog.Fatalf("While creating --commit directory: %s", err)
+	}
+	if dir, err = filepath.Abs(dir); err != nil {
+		sylog.Fatalf("While creating --commit directory: %s", err)
+	}
+	removeDir := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			sylog.Warningf("While removing --commit directory %s: %s", dir, err)
+		}
+	}
+	for _, d := range []string{"upper", "work"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o755); err != nil {
+			removeDir()
+			sylog.Fatalf("While creating --commit directory: %s", err)
+		}
+	}
+	engineConfig.SetWritableTmpfsDir(dir)
+
+	// terminal signals are also delivered to the container process, they
+	// must not interrupt the commit
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGQUIT)
+
+	ops = append(ops, starter.WithStdin(os.Stdin), starter.WithStdout(os.Stdout), starter.WithStderr(os.Stderr))
+	err = starter.Run(name, cfg, ops...)
+	signal.Stop(sigs)
+
+	code := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			removeDir()
+			sylog.Fatalf("%s", err)
+		}
+		code = exitErr.ExitCode()
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			code = 128 + int(status.Signal())
+		}
+	}
+
+	if code != 0 {
+		sylog.Warningf("Container exited with status %d, changes not committed to %s", code, CommitPath)
+	} else {
+		sylog.Infof("Committing changes to %s", CommitPath)
+		if err := overlayCommit(ctx, image, dir, CommitPath); err != nil {
+			sylog.Errorf("While committing changes: %s", err)
+			code = 255
+		} else {
+			sylog.Infof("Created %s", CommitPath)
+		}
+	}
+
+	removeDir()
+	return code
+}
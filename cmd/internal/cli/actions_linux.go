@@ -6,18 +6,28 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
+	securejoin "github.com/cyphar/filepath-securejoin"
+	units "github.com/docker/go-units"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/internal/pkg/cgroups"
+	"github.com/sylabs/singularity/internal/pkg/fakeroot"
 	"github.com/sylabs/singularity/internal/pkg/image/unpacker"
 	"github.com/sylabs/singularity/internal/pkg/instance"
 	"github.com/sylabs/singularity/internal/pkg/plugin"
@@ -27,10 +37,12 @@ import (
 	"github.com/sylabs/singularity/internal/pkg/util/bin"
 	"github.com/sylabs/singularity/internal/pkg/util/env"
 	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/internal/pkg/util/fs/files"
 	"github.com/sylabs/singularity/internal/pkg/util/gpu"
 	"github.com/sylabs/singularity/internal/pkg/util/shell/interpreter"
 	"github.com/sylabs/singularity/internal/pkg/util/starter"
 	"github.com/sylabs/singularity/internal/pkg/util/user"
+	"github.com/sylabs/singularity/internal/pkg/volume"
 	imgutil "github.com/sylabs/singularity/pkg/image"
 	clicallback "github.com/sylabs/singularity/pkg/plugin/callback/cli"
 	singularitycallback "github.com/sylabs/singularity/pkg/plugin/callback/runtime/engine/singularity"
@@ -89,7 +101,7 @@ func convertImage(filename string, unsquashfsPath string) (tempDir, imageDir str
 	if tmpdir == "" {
 		tmpdir = os.Getenv("SINGULARITY_LOCALCACHEDIR")
 		if tmpdir == "" {
-			tmpdir = os.Getenv("SINGULARITY_CACHEDIR")
+			tmpdir = cacheParentDir()
 		}
 	}
 
@@ -118,6 +130,69 @@ func convertImage(filename string, unsquashfsPath string) (tempDir, imageDir str
 	return tempDir, imageDir, err
 }
 
+// readIdentityFiles returns the content of the /etc/passwd and /etc/group
+// files of a sandbox, a squashfs or SIF image, or a running instance. A nil
+// content is returned for the files which can't be read.
+func readIdentityFiles(image string) (passwd, group []byte) {
+	files := []string{"/etc/passwd", "/etc/group"}
+	content := make([][]byte, len(files))
+
+	readFiles := func(rootfs string) {
+		for i, f := range files {
+			path, err := securejoin.SecureJoin(rootfs, f)
+			if err != nil {
+				continue
+			}
+			if b, err := ioutil.ReadFile(path); err == nil {
+				content[i] = b
+			}
+		}
+	}
+
+	if strings.HasPrefix(image, "instance://") {
+		file, err := instance.Get(instance.ExtractName(image), instance.SingSubDir)
+		if err != nil {
+			return nil, nil
+		}
+		readFiles(fmt.Sprintf("/proc/%d/root", file.Pid))
+		return content[0], content[1]
+	}
+
+	img, err := imgutil.Init(image, false)
+	if err != nil {
+		return nil, nil
+	}
+	defer img.File.Close()
+
+	if img.Type == imgutil.SANDBOX {
+		readFiles(img.Path)
+		return content[0], content[1]
+	}
+
+	part, err := img.GetRootFsPartition()
+	if err != nil || part.Type != imgutil.SQUASHFS {
+		sylog.Debugf("Container identity files can't be read from %s root filesystem", image)
+		return nil, nil
+	}
+	reader, err := imgutil.NewPartitionReader(img, "", 0)
+	if err != nil {
+		return nil, nil
+	}
+
+	tmpDir, err := ioutil.TempDir("", "identity-")
+	if err != nil {
+		return nil, nil
+	}
+	defer os.RemoveAll(tmpDir)
+
+	s := unpacker.NewSquashfs()
+	if err := s.ExtractFiles(files, reader, tmpDir); err != nil {
+		sylog.Debugf("While extracting container identity files: %s", err)
+	}
+	readFiles(tmpDir)
+	return content[0], content[1]
+}
+
 // checkHidepid checks if hidepid is set on /proc mount point, when this
 // option is an instance started with setuid workflow could not even be
 // joined later or stopped correctly.
@@ -173,6 +248,7 @@ func setNoMountFlags(c *singularityConfig.EngineConfig) {
 }
 
 // TODO: Let's stick this in another file so that that CLI is just CLI
+//
 //nolint:maintidx
 func execStarter(cobraCmd *cobra.Command, image string, args []string, name string) {
 	var err error
@@ -215,7 +291,18 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 	generator.SetProcessArgs(args)
 
 	currMask := syscall.Umask(0o022)
-	if !NoUmask {
+	if Umask != "" {
+		if cobraCmd.Flag(actionNoUmaskFlag.Name).Changed {
+			sylog.Fatalf("--umask and --no-umask options are mutually exclusive")
+		}
+		mask, err := strconv.ParseUint(Umask, 8, 32)
+		if err != nil || mask > 0o777 {
+			sylog.Fatalf("Invalid umask %s: must be an octal value between 0000 and 0777", Umask)
+		}
+		sylog.Debugf("Setting umask %04o for the container process", mask)
+		engineConfig.SetUmask(int(mask))
+		engineConfig.SetRestoreUmask(true)
+	} else if !NoUmask {
 		// Save the current umask, to be set for the process run in the container
 		// https://github.com/hpcng/singularity/issues/5214
 		sylog.Debugf("Saving umask %04o for propagation into container", currMask)
@@ -254,6 +341,37 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		engineConfig.SetTargetGID(targetGID)
 	})
 
+	// handle target user and group for root user, resolved in the container
+	checkPrivileges(UserSpec != "", "--user option", func() {
+		if uidParam != "" || gidParam != "" {
+			sylog.Fatalf("--user and uid/gid security features are mutually exclusive")
+		}
+		if IsFakeroot {
+			sylog.Fatalf("--user and --fakeroot are mutually exclusive")
+		}
+
+		passwd, group := readIdentityFiles(image)
+		u, gids, err := user.ResolveSpec(UserSpec, passwd, group)
+		if err != nil {
+			sylog.Fatalf("While resolving --user: %s", err)
+		}
+		if u == 0 && len(gids) == 1 && gids[0] == 0 {
+			// nothing to drop, keep the root capabilities
+			return
+		}
+		sylog.Debugf("Running container process as UID %d and GIDs %v", u, gids)
+
+		targetUID = int(u)
+		uid = u
+		for _, g := range gids {
+			targetGID = append(targetGID, int(g))
+		}
+		gid = gids[0]
+
+		engineConfig.SetTargetUID(targetUID)
+		engineConfig.SetTargetGID(targetGID)
+	})
+
 	if strings.HasPrefix(image, "instance://") {
 		if name != "" {
 			sylog.Fatalf("Starting an instance from another is not allowed")
@@ -277,6 +395,8 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		}
 		engineConfig.SetImage(abspath)
 	}
+	// the image the --commit changes are applied to, before any conversion
+	commitImage := engineConfig.GetImage()
 
 	// privileged installation by default
 	useSuid := true
@@ -361,8 +481,9 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		sylog.Fatalf("while parsing bind path: %s", err)
 	}
 
-	// Now add binds from one or more --mount and env var.
-	for _, m := range Mounts {
+	// Now add binds from one or more --mount and env var, including
+	// --mount specifications set in the bind env vars.
+	for _, m := range append(Mounts, envMounts...) {
 		bps, err := singularityConfig.ParseMountString(m)
 		if err != nil {
 			sylog.Fatalf("while parsing mount %q: %s", m, err)
@@ -370,9 +491,73 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		binds = append(binds, bps...)
 	}
 
+	// Now add data image binds from --bind-data and env var.
+	if len(BindDataPaths) > 0 {
+		bps, err := singularityConfig.ParseBindDataPath(strings.Join(BindDataPaths, ","))
+		if err != nil {
+			sylog.Fatalf("while parsing data bind path: %s", err)
+		}
+		binds = append(binds, bps...)
+	}
+
+	// Expand ~ and environment variables in the paths, for binds which
+	// were quoted or set in the environment
+	if err := expandBindPaths(binds); err != nil {
+		sylog.Fatalf("while parsing bind path: %s", err)
+	}
+
+	// Wait for the sources of binds with the wait option, like FUSE mounts
+	// set up in the background, to be mounted
+	for _, bp := range binds {
+		if err := waitBindSource(bp); err != nil {
+			sylog.Fatalf("%s", err)
+		}
+		if err := createBindOverlayUpper(bp); err != nil {
+			sylog.Fatalf("%s", err)
+		}
+	}
+
+	// Now add binds of named volumes from one or more --volume and env var
+	for _, v := range Volumes {
+		bp, err := volume.BindPath(v)
+		if err != nil {
+			sylog.Fatalf("while parsing volume %q: %s", v, err)
+		}
+		bps, err := singularityConfig.ParseBindPath(bp)
+		if err != nil {
+			sylog.Fatalf("while parsing volume %q: %s", v, err)
+		}
+		binds = append(binds, bps...)
+	}
+
 	engineConfig.SetBindPath(binds)
+
+	tmpfsMounts := make([]singularityConfig.TmpfsMount, 0, len(TmpfsMounts))
+	for _, t := range TmpfsMounts {
+		tm, err := singularityConfig.ParseTmpfsString(t)
+		if err != nil {
+			sylog.Fatalf("while parsing tmpfs %q: %s", t, err)
+		}
+		tmpfsMounts = append(tmpfsMounts, tm)
+	}
+	engineConfig.SetTmpfsMounts(tmpfsMounts)
 	generator.AddProcessEnv("SINGULARITY_BIND", strings.Join(BindPaths, ","))
 
+	if InitBin != "" {
+		if !filepath.IsAbs(InitBin) {
+			sylog.Fatalf("--init-bin requires an absolute path in the container, got %s", InitBin)
+		}
+		IsInit = true
+	}
+	if IsInit {
+		if cobraCmd.Flag(actionNoInitFlag.Name).Changed {
+			sylog.Fatalf("--init and --no-init options are mutually exclusive")
+		}
+		/* --init overrides the --no-init implied by --compat and requires --pid */
+		NoInit = false
+		PidNamespace = true
+	}
+
 	if len(FuseMount) > 0 {
 		/* If --fusemount is given, imply --pid */
 		PidNamespace = true
@@ -381,7 +566,19 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		}
 	}
 	engineConfig.SetNetwork(Network)
-	engineConfig.SetDNS(DNS)
+	for _, ip := range DNS {
+		if net.ParseIP(strings.TrimSpace(ip)) == nil {
+			sylog.Fatalf("DNS server %q is not a valid IP address", ip)
+		}
+	}
+	engineConfig.SetDNS(strings.Join(DNS, ","))
+	engineConfig.SetDNSSearch(DNSSearch)
+	for _, h := range AddHosts {
+		if _, _, err := files.ParseHostEntry(h); err != nil {
+			sylog.Fatalf("while parsing --add-host: %s", err)
+		}
+	}
+	engineConfig.SetAddHosts(AddHosts)
 	engineConfig.SetNetworkArgs(NetworkArgs)
 	engineConfig.SetOverlayImage(OverlayPath)
 	engineConfig.SetWritableImage(IsWritable)
@@ -416,10 +613,47 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		generator.AddProcessEnv("SINGULARITY_SHELL", ShellPath)
 	}
 
-	if name != "" && uid != 0 && CgroupsTOML != "" {
+	engineConfig.SetNoEval(NoEval)
+
+	// the 'rewrite path' directive is validated by the configuration parser
+	engineConfig.SetRewritePath(engineConfig.File.RewritePath)
+	if RewritePath != "" {
+		switch RewritePath {
+		case singularityConfig.RewritePathAppend, singularityConfig.RewritePathPrepend, singularityConfig.RewritePathImageOnly:
+			engineConfig.SetRewritePath(RewritePath)
+		default:
+			sylog.Fatalf("Invalid --rewrite-path value %q: must be append, prepend or image-only", RewritePath)
+		}
+	}
+
+	cgLimits := cgroups.Limits{
+		Memory:     CgroupsMemory,
+		MemorySwap: CgroupsMemorySwap,
+		CPUs:       CgroupsCPUs,
+		CPUShares:  int64(CgroupsCPUShares),
+		PidsLimit:  int64(CgroupsPidsLimit),
+	}
+
+	if len(Devices) > 0 {
+		devices, rules, err := parseDevices(Devices)
+		if err != nil {
+			sylog.Fatalf("While parsing --device: %s", err)
+		}
+		engineConfig.SetDevices(devices)
+		cgLimits.Devices = rules
+	}
+
+	if name != "" && uid != 0 && (CgroupsTOML != "" || CgroupsParent != "" || cgLimits.IsSet()) {
 		sylog.Fatalf("Instances do not currently support rootless cgroups")
 	}
 
+	if CgroupsParent != "" {
+		if err := cgroups.ValidateParent(CgroupsParent, engineConfig.File.SystemdCgroups); err != nil {
+			sylog.Fatalf("Invalid --cgroup-parent: %s", err)
+		}
+		engineConfig.SetCgroupsParent(CgroupsParent)
+	}
+
 	if uid != 0 {
 		sylog.Debugf("Recording rootless XDG_RUNTIME_DIR / DBUS_SESSION_BUS_ADDRESS")
 		engineConfig.SetXdgRuntimeDir(os.Getenv("XDG_RUNTIME_DIR"))
@@ -427,6 +661,15 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 	}
 
 	engineConfig.SetCgroupsTOML(CgroupsTOML)
+	// a cgroup parent alone places the container in a cgroup without limits,
+	// --device rules are only needed if the cgroups file restricts devices
+	if cgLimits.IsSet() || (CgroupsParent != "" && CgroupsTOML == "") || (CgroupsTOML != "" && len(cgLimits.Devices) > 0) {
+		cgJSON, err := getCgroupsJSON(CgroupsTOML, cgLimits)
+		if err != nil {
+			sylog.Fatalf("While setting cgroups limits: %s", err)
+		}
+		engineConfig.SetCgroupsJSON(cgJSON)
+	}
 
 	if IsWritable && IsWritableTmpfs {
 		sylog.Warningf("Disabling --writable-tmpfs flag, mutually exclusive with --writable")
@@ -435,6 +678,39 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		engineConfig.SetWritableTmpfs(IsWritableTmpfs)
 	}
 
+	if CommitPath != "" {
+		if !engineConfig.GetWritableTmpfs() {
+			sylog.Fatalf("--commit requires --writable-tmpfs")
+		}
+		if strings.HasPrefix(image, "instance://") {
+			sylog.Fatalf("--commit can't be used to join an instance")
+		}
+		if IsFakeroot {
+			sylog.Fatalf("--commit can't be used with --fakeroot")
+		}
+		if _, err := os.Stat(CommitPath); err == nil {
+			sylog.Fatalf("Image file already exists: %q - will not overwrite", CommitPath)
+		}
+	}
+
+	if RootfsPropagation != "" {
+		switch RootfsPropagation {
+		case "private", "slave", "shared":
+		default:
+			sylog.Fatalf("Invalid --rootfs-propagation %q: must be private, slave or shared", RootfsPropagation)
+		}
+		if strings.HasPrefix(image, "instance://") {
+			sylog.Fatalf("--rootfs-propagation can't be used to join an instance")
+		}
+		engineConfig.SetRootfsPropagation(RootfsPropagation)
+	}
+
+	if IsWritableCwd && (IsWritable || IsWritableTmpfs) {
+		sylog.Verbosef("Ignoring --writable-cwd, the whole container file system is writable")
+	} else {
+		engineConfig.SetWritableCwd(IsWritableCwd)
+	}
+
 	homeFlag := cobraCmd.Flag("home")
 	engineConfig.SetCustomHome(homeFlag.Changed)
 
@@ -485,8 +761,34 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 	}
 
 	engineConfig.SetScratchDir(ScratchPath)
+	if ScratchSize < 0 {
+		sylog.Fatalf("--scratch-size must be a positive size in MiB")
+	} else if ScratchSize > 0 {
+		if len(ScratchPath) == 0 {
+			sylog.Warningf("--scratch-size has no effect without --scratch")
+		} else if WorkdirPath != "" {
+			sylog.Warningf("--scratch-size is ignored, scratch directories are stored in --workdir %s", WorkdirPath)
+		}
+		engineConfig.SetScratchSize(ScratchSize)
+	}
 	engineConfig.SetWorkdir(WorkdirPath)
 
+	if homeTmpfs, ok, err := parseHomeTmpfs(HomePath); err != nil {
+		sylog.Fatalf("While parsing --home %s: %s", HomePath, err)
+	} else if ok {
+		// the tmpfs is mounted at the default home directory location
+		homeDir := "/root"
+		if !IsFakeroot {
+			pw, err := user.CurrentOriginal()
+			if err != nil {
+				sylog.Fatalf("Couldn't determine user home directory: %s", err)
+			}
+			homeDir = pw.Dir
+		}
+		HomePath = homeDir
+		engineConfig.SetHomeTmpfs(homeTmpfs)
+	}
+
 	homeSlice := strings.Split(HomePath, ":")
 
 	if len(homeSlice) > 2 || len(homeSlice) == 0 {
@@ -504,6 +806,21 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		UserNamespace = true
 	}
 
+	customIDMappings := len(UIDMap) > 0 || len(GIDMap) > 0
+	if customIDMappings {
+		if IsFakeroot {
+			sylog.Fatalf("--uidmap and --gidmap options cannot be used with --fakeroot")
+		}
+		UserNamespace = true
+		engineConfig.SetCustomIDMappings(true)
+	}
+
+	// the --commit directory is used as overlay upper directory, which is
+	// mounted with root privileges in setuid mode
+	if CommitPath != "" && useSuid && !UserNamespace {
+		sylog.Fatalf("--commit requires root or a user namespace, use --userns")
+	}
+
 	/* if name submitted, run as instance */
 	if name != "" {
 		PidNamespace = true
@@ -543,6 +860,11 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		procname = "Singularity runtime parent"
 	}
 
+	// --network none doesn't require --net, the container runs in a new
+	// network namespace with only a loopback interface left down
+	if Network == "none" && cobraCmd.Flags().Changed("network") {
+		NetNamespace = true
+	}
 	if NetNamespace {
 		if IsFakeroot && Network != "none" {
 			engineConfig.SetNetwork("fakeroot")
@@ -566,6 +888,7 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 	if PidNamespace {
 		generator.AddOrReplaceLinuxNamespace("pid", "")
 		engineConfig.SetNoInit(NoInit)
+		engineConfig.SetInitBin(InitBin)
 	}
 	if IpcNamespace {
 		generator.AddOrReplaceLinuxNamespace("ipc", "")
@@ -573,12 +896,69 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 	if UserNamespace {
 		generator.AddOrReplaceLinuxNamespace("user", "")
 
-		if !IsFakeroot {
+		if customIDMappings {
+			addIDMappings(generator, UIDMap, GIDMap, uid, gid)
+		} else if !IsFakeroot {
 			generator.AddLinuxUIDMapping(uid, uid, 1)
 			generator.AddLinuxGIDMapping(gid, gid, 1)
 		}
 	}
 
+	if DevMode != "" {
+		mode, devices, err := parseDevMode(DevMode)
+		if err != nil {
+			sylog.Fatalf("While parsing --dev: %s", err)
+		}
+		engineConfig.SetDev(mode)
+		engineConfig.SetDevDevices(devices)
+	}
+
+	if Setgroups != "" {
+		if Setgroups != singularityConfig.SetgroupsAllow && Setgroups != singularityConfig.SetgroupsDeny {
+			sylog.Fatalf("Invalid --setgroups value %q: must be allow or deny", Setgroups)
+		}
+		if !UserNamespace {
+			sylog.Fatalf("--setgroups requires a user namespace, use --userns, --fakeroot, --uidmap or --gidmap")
+		}
+		// the user namespace ID mappings are written by the unprivileged
+		// user with --userns, which the kernel only permits with setgroups
+		// denied
+		if Setgroups == singularityConfig.SetgroupsAllow && !IsFakeroot && !customIDMappings {
+			sylog.Fatalf("--setgroups allow requires --fakeroot, --uidmap or --gidmap, setgroups is always denied with --userns")
+		}
+		engineConfig.SetSetgroups(Setgroups)
+	}
+
+	// --env-json variables are set as is in the container, without
+	// shell evaluation
+	literalEnv := make(map[string]string)
+	if SingularityEnvJSON != "" {
+		vars, err := parseEnvJSON(SingularityEnvJSON)
+		if err != nil {
+			sylog.Fatalf("While processing --env-json: %s", err)
+		}
+		// --env variables will take precedence over variables
+		// defined by the JSON object
+		overridden := make(map[string]bool)
+		for _, ev := range SingularityEnv {
+			if _, _, op := env.SplitModifier(ev); op == "" {
+				overridden[strings.SplitN(ev, "=", 2)[0]] = true
+			}
+		}
+		for _, ev := range vars {
+			e := strings.SplitN(ev, "=", 2)
+			switch {
+			case overridden[e[0]]:
+			case e[0] == "PATH":
+				literalEnv["SING_USER_DEFINED_PATH"] = e[1]
+			case !env.IsModifiable(e[0]):
+				sylog.Warningf("Overriding %s environment variable with --env-json is not permitted", e[0])
+			default:
+				literalEnv[e[0]] = e[1]
+			}
+		}
+	}
+
 	if SingularityEnvFile != "" {
 		currentEnv := append(
 			os.Environ(),
@@ -594,34 +974,79 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		if err != nil {
 			sylog.Fatalf("While processing %s: %s", SingularityEnvFile, err)
 		}
-		// --env variables will take precedence over variables
-		// defined by the environment file
+		// --env and --env-json variables will take precedence over
+		// variables defined by the environment file, --env-json ones
+		// being injected after them
 		sylog.Debugf("Setting environment variables from file %s", SingularityEnvFile)
 		SingularityEnv = append(env, SingularityEnv...)
 	}
 
 	// process --env and --env-file variables for injection
-	// into the environment by prefixing them with SINGULARITYENV_
-	for _, env := range SingularityEnv {
-		e := strings.SplitN(env, "=", 2)
+	// into the environment by prefixing them with SINGULARITYENV_,
+	// KEY+=VALUE and KEY^=VALUE are resolved against the container
+	// value at startup
+	appendEnv := make(map[string]string)
+	prependEnv := make(map[string]string)
+	for _, ev := range SingularityEnv {
+		if key, value, op := env.SplitModifier(ev); op != "" {
+			switch {
+			case key == "PATH" && op == env.AppendOperator:
+				os.Setenv("SINGULARITYENV_APPEND_PATH", value)
+			case key == "PATH":
+				os.Setenv("SINGULARITYENV_PREPEND_PATH", value)
+			case !env.IsModifiable(key):
+				sylog.Warningf("Modifying %s environment variable with %q is not permitted", key, ev)
+			case op == env.AppendOperator:
+				appendEnv[key] = value
+			default:
+				prependEnv[key] = value
+			}
+			continue
+		}
+		e := strings.SplitN(ev, "=", 2)
 		if len(e) != 2 {
-			sylog.Warningf("Ignore environment variable %q: '=' is missing", env)
+			sylog.Warningf("Ignore environment variable %q: '=' is missing", ev)
 			continue
 		}
 		os.Setenv("SINGULARITYENV_"+e[0], e[1])
 	}
+	engineConfig.SetAppendEnv(appendEnv)
+	engineConfig.SetPrependEnv(prependEnv)
 
 	// Copy and cache environment
 	environment := os.Environ()
 
+	for _, pattern := range KeepEnv {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			sylog.Fatalf("Invalid --keep-env pattern %q: %s", pattern, err)
+		}
+	}
+	for _, pattern := range EnvPassThrough {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			sylog.Fatalf("Invalid --env-pass-through pattern %q: %s", pattern, err)
+		}
+	}
+	if len(KeepEnv) > 0 && !IsCleanEnv {
+		sylog.Warningf("--keep-env has no effect without --cleanenv or --containall")
+	}
+
 	// Clean environment
-	singularityEnv := env.SetContainerEnv(generator, environment, IsCleanEnv, engineConfig.GetHomeDest())
+	singularityEnv, passEnv := env.SetContainerEnv(generator, environment, IsCleanEnv, KeepEnv, EnvPassThrough, engineConfig.GetHomeDest())
 	engineConfig.SetSingularityEnv(singularityEnv)
+	// variables passed through are set as is too, --env-json variables
+	// take precedence over them
+	for k, v := range passEnv {
+		if _, ok := literalEnv[k]; !ok {
+			literalEnv[k] = v
+		}
+	}
+	engineConfig.SetLiteralEnv(literalEnv)
 
 	if pwd, err := os.Getwd(); err == nil {
 		engineConfig.SetCwd(pwd)
 		if PwdPath != "" {
 			generator.SetProcessCwd(PwdPath)
+			engineConfig.SetPwd(PwdPath)
 		} else {
 			if engineConfig.GetContain() {
 				generator.SetProcessCwd(engineConfig.GetHomeDest())
@@ -705,6 +1130,17 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		generator.AddProcessRlimits("RLIMIT_STACK", hard, soft)
 	}
 
+	// resource limits requested with --ulimit are set after the stack
+	// size limit above so a user provided stack limit takes precedence
+	for _, u := range Ulimits {
+		res, soft, hard, err := rlimit.Parse(u)
+		if err != nil {
+			sylog.Fatalf("while parsing ulimit %q: %s", u, err)
+		}
+		sylog.Debugf("Setting %s limit to %d:%d for the container process", res, soft, hard)
+		generator.AddProcessRlimits(res, hard, soft)
+	}
+
 	cfg := &config.Common{
 		EngineName:   singularityConfig.Name,
 		ContainerID:  name,
@@ -763,6 +1199,16 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 			sylog.Verbosef("you will find instance error here: %s", stderr.Name())
 			sylog.Infof("instance started successfully")
 		}
+	} else if CommitPath != "" {
+		os.Exit(runAndCommit(
+			cobraCmd.Context(),
+			engineConfig,
+			commitImage,
+			procname,
+			cfg,
+			starter.UseSuid(useSuid),
+			starter.LoadOverlayModule(loadOverlay),
+		))
 	} else {
 		err := starter.Exec(
 			procname,
@@ -774,6 +1220,54 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 	}
 }
 
+// addIDMappings adds the user namespace ID mappings requested with
+// --uidmap/--gidmap to generator, the current user or group ID is
+// mapped to itself when no mapping is requested for it.
+func addIDMappings(generator *generate.Generator, uidMap, gidMap []string, uid, gid uint32) {
+	if len(uidMap) == 0 {
+		generator.AddLinuxUIDMapping(uid, uid, 1)
+	}
+	for _, spec := range uidMap {
+		m, err := fakeroot.ParseIDMapping(spec)
+		if err != nil {
+			sylog.Fatalf("While parsing --uidmap: %s", err)
+		}
+		generator.AddLinuxUIDMapping(m.HostID, m.ContainerID, m.Size)
+	}
+
+	if len(gidMap) == 0 {
+		generator.AddLinuxGIDMapping(gid, gid, 1)
+	}
+	for _, spec := range gidMap {
+		m, err := fakeroot.ParseIDMapping(spec)
+		if err != nil {
+			sylog.Fatalf("While parsing --gidmap: %s", err)
+		}
+		generator.AddLinuxGIDMapping(m.HostID, m.ContainerID, m.Size)
+	}
+}
+
+// getCgroupsJSON returns the cgroups resources, in OCI runtime spec JSON
+// format, from the cgroups TOML file, if any, with limits applied on top.
+func getCgroupsJSON(tomlPath string, limits cgroups.Limits) (string, error) {
+	resources := specs.LinuxResources{}
+	if tomlPath != "" {
+		var err error
+		resources, err = cgroups.LoadResources(tomlPath)
+		if err != nil {
+			return "", fmt.Errorf("while loading cgroups file %s: %s", tomlPath, err)
+		}
+	}
+	if err := limits.Apply(&resources); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(resources)
+	if err != nil {
+		return "", fmt.Errorf("while encoding cgroups resources: %s", err)
+	}
+	return string(data), nil
+}
+
 // SetGPUConfig sets up EngineConfig entries for NV / ROCm usage, if requested.
 func SetGPUConfig(engineConfig *singularityConfig.EngineConfig) error {
 	if engineConfig.File.AlwaysUseNv && !NoNvidia {
@@ -886,7 +1380,8 @@ func setGPUBinds(engineConfig *singularityConfig.EngineConfig, libs, bins, ipcs
 	if len(files) == 0 {
 		sylog.Warningf("Could not find any %s files on this host!", gpuPlatform)
 	} else {
-		if IsWritable {
+		// missing destinations are created in writable sandboxes only
+		if IsWritable && !fs.IsDir(engineConfig.GetImage()) {
 			sylog.Warningf("%s files may not be bound with --writable", gpuPlatform)
 		}
 		for i, binary := range bins {
@@ -904,3 +1399,358 @@ func setGPUBinds(engineConfig *singularityConfig.EngineConfig, libs, bins, ipcs
 		engineConfig.SetLibrariesPath(libs)
 	}
 }
+
+// parseHomeTmpfs returns true with the tmpfs mount options if home is a tmpfs
+// home specification of the form tmpfs[:size=<size>].
+func parseHomeTmpfs(home string) (string, bool, error) {
+	const (
+		tmpfs = "tmpfs"
+		mode  = "mode=0700"
+	)
+
+	if home == tmpfs {
+		return mode, true, nil
+	}
+	// tmpfs:/dest is a bind of the relative tmpfs directory
+	if !strings.HasPrefix(home, tmpfs+":") || strings.HasPrefix(home, tmpfs+":/") {
+		return "", false, nil
+	}
+
+	options := []string{mode}
+	for _, opt := range strings.Split(strings.TrimPrefix(home, tmpfs+":"), ",") {
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 || kv[0] != "size" {
+			return "", true, fmt.Errorf("invalid tmpfs home option %q, only size=<size> is supported", opt)
+		}
+		size, err := units.RAMInBytes(kv[1])
+		if err != nil || size <= 0 {
+			return "", true, fmt.Errorf("invalid tmpfs home size %q", kv[1])
+		}
+		options = append(options, "size="+strconv.FormatInt(size, 10))
+	}
+	return strings.Join(options, ","), true, nil
+}
+
+// parseDevMode returns the /dev mode and the host devices requested with
+// --dev minimal|full|custom:<dev>[,<dev>...]. Devices are paths or glob
+// patterns below /dev, matching device nodes, directories or symlinks.
+func parseDevMode(dev string) (string, []string, error) {
+	switch dev {
+	case singularityConfig.DevMinimal, singularityConfig.DevFull:
+		return dev, nil, nil
+	}
+
+	list := strings.TrimPrefix(dev, singularityConfig.DevCustom+":")
+	if list == dev || list == "" {
+		return "", nil, fmt.Errorf("invalid value %q: must be minimal, full or custom:<dev>[,<dev>...]", dev)
+	}
+
+	var devices []string
+	seen := make(map[string]bool)
+	for _, pattern := range strings.Split(list, ",") {
+		if pattern != filepath.Clean(pattern) || !strings.HasPrefix(pattern, "/dev/") {
+			return "", nil, fmt.Errorf("invalid device %q: must be an absolute path below /dev", pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid device pattern %q: %s", pattern, err)
+		} else if len(matches) == 0 {
+			return "", nil, fmt.Errorf("no device matching %s found on host", pattern)
+		}
+		for _, path := range matches {
+			// the device parent directory must not resolve outside of /dev
+			parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+			if err != nil {
+				return "", nil, fmt.Errorf("while resolving %s: %s", path, err)
+			}
+			if parent != "/dev" && !strings.HasPrefix(parent, "/dev/") {
+				return "", nil, fmt.Errorf("invalid device %s: located in %s", path, parent)
+			}
+			fi, err := os.Lstat(path)
+			if err != nil {
+				return "", nil, err
+			}
+			if fi.Mode()&(os.ModeDevice|os.ModeDir|os.ModeSymlink) == 0 {
+				return "", nil, fmt.Errorf("invalid device %s: not a device node, directory or symlink", path)
+			}
+			if !seen[path] {
+				seen[path] = true
+				devices = append(devices, path)
+			}
+		}
+	}
+	return singularityConfig.DevCustom, devices, nil
+}
+
+// parseDevices returns the host devices requested with --device
+// <path>[:<permissions>], mapping their path in the container to the host
+// device node, and the device cgroup rules allowing access to them. Paths
+// are below /dev and may be symlinks to a block or character device.
+func parseDevices(devices []string) (map[string]string, []specs.LinuxDeviceCgroup, error) {
+	nodes := make(map[string]string)
+	var rules []specs.LinuxDeviceCgroup
+
+	for _, dev := range devices {
+		path, access := dev, "rwm"
+		if i := strings.Index(dev, ":"); i >= 0 {
+			path, access = dev[:i], dev[i+1:]
+		}
+		if path != filepath.Clean(path) || !strings.HasPrefix(path, "/dev/") {
+			return nil, nil, fmt.Errorf("invalid device %q: must be an absolute path below /dev", path)
+		}
+		if access == "" || strings.Trim(access, "rwm") != "" {
+			return nil, nil, fmt.Errorf("invalid device permissions %q for %s: must be a combination of r, w and m", access, path)
+		}
+
+		src, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("while resolving %s: %s", path, err)
+		}
+		if !strings.HasPrefix(src, "/dev/") {
+			return nil, nil, fmt.Errorf("invalid device %s: located in %s", path, filepath.Dir(src))
+		}
+		fi, err := os.Stat(src)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		devType := "b"
+		if fi.Mode()&os.ModeDevice == 0 {
+			return nil, nil, fmt.Errorf("invalid device %s: not a block or character device", path)
+		} else if fi.Mode()&os.ModeCharDevice != 0 {
+			devType = "c"
+		}
+		st, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			return nil, nil, fmt.Errorf("could not get %s device number", path)
+		}
+		major := int64(unix.Major(st.Rdev))
+		minor := int64(unix.Minor(st.Rdev))
+
+		nodes[path] = src
+		rules = append(rules, specs.LinuxDeviceCgroup{
+			Allow:  true,
+			Type:   devType,
+			Major:  &major,
+			Minor:  &minor,
+			Access: access,
+		})
+	}
+	return nodes, rules, nil
+}
+
+// envNameRe matches valid environment variable names.
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parseEnvJSON returns the KEY=VALUE variables defined by env, a JSON object
+// with string values, or the path of a file containing one. Variables are
+// sorted by name.
+func parseEnvJSON(env string) ([]string, error) {
+	content := []byte(env)
+	if !strings.HasPrefix(strings.TrimSpace(env), "{") {
+		b, err := ioutil.ReadFile(env)
+		if err != nil {
+			return nil, fmt.Errorf("could not read JSON environment file: %s", err)
+		}
+		content = b
+	}
+
+	var values map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("invalid JSON object: %s", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("invalid JSON object: unexpected data after the object")
+	}
+	if values == nil {
+		return nil, fmt.Errorf("invalid JSON object: null")
+	}
+
+	keys := make([]string, 0, len(values))
+	for k, v := range values {
+		if !envNameRe.MatchString(k) {
+			return nil, fmt.Errorf("invalid environment variable name %q", k)
+		}
+		if _, ok := v.(string); !ok {
+			return nil, fmt.Errorf("value of %s must be a string", k)
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	vars := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vars = append(vars, k+"="+values[k].(string))
+	}
+	return vars, nil
+}
+
+// expandBindPath expands a leading ~ or ~user to the home directory of the
+// current or named user, and the $VAR and ${VAR} environment variables in
+// the bind path p, as the shell would do for an unquoted path. References to
+// variables not set in the host environment are kept literally.
+func expandBindPath(p string) (string, error) {
+	if strings.HasPrefix(p, "~") {
+		name := p[1:]
+		rest := ""
+		if i := strings.IndexByte(name, '/'); i >= 0 {
+			name, rest = name[:i], name[i:]
+		}
+
+		var pw *user.User
+		var err error
+		if name == "" {
+			pw, err = user.CurrentOriginal()
+		} else {
+			pw, err = user.GetPwNam(name)
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not expand %s: %s", p, err)
+		}
+		p = pw.Dir + rest
+	}
+
+	return expandSetEnv(p), nil
+}
+
+// expandSetEnv replaces the $VAR and ${VAR} references in s with the value
+// of the variables set in the environment. Unlike os.Expand, references to
+// unset variables and other $ characters are kept as is.
+func expandSetEnv(s string) string {
+	isNameChar := func(c byte) bool {
+		return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+	}
+	isName := func(name string) bool {
+		if name == "" || (name[0] >= '0' && name[0] <= '9') {
+			return false
+		}
+		for i := 0; i < len(name); i++ {
+			if !isNameChar(name[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		// name is the variable name referenced at i, s[i:end] the reference
+		name, end := "", i+1
+		if strings.HasPrefix(s[i+1:], "{") {
+			if j := strings.IndexByte(s[i+2:], '}'); j >= 0 {
+				name, end = s[i+2:i+2+j], i+3+j
+			}
+		} else {
+			for end < len(s) && isNameChar(s[end]) {
+				end++
+			}
+			name = s[i+1 : end]
+		}
+
+		if v, ok := os.LookupEnv(name); ok && isName(name) {
+			b.WriteString(v)
+			i = end - 1
+		} else {
+			b.WriteByte('$')
+		}
+	}
+	return b.String()
+}
+
+// expandBindPaths expands the source and destination of binds with
+// expandBindPath.
+func expandBindPaths(binds []singularityConfig.BindPath) error {
+	for i := range binds {
+		paths := []*string{&binds[i].Source, &binds[i].Destination}
+		if o := binds[i].Options["overlay-upper"]; o != nil {
+			paths = append(paths, &o.Value)
+		}
+		for _, p := range paths {
+			expanded, err := expandBindPath(*p)
+			if err != nil {
+				return err
+			}
+			*p = expanded
+		}
+	}
+	return nil
+}
+
+// createBindOverlayUpper creates the upper and work directories of the
+// overlay of bp, if set by its overlay-upper option, so they are owned by
+// the user.
+func createBindOverlayUpper(bp singularityConfig.BindPath) error {
+	dir := bp.OverlayUpper()
+	if dir == "" {
+		return nil
+	}
+	for _, d := range []string{"upper", "work"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0o755); err != nil {
+			return fmt.Errorf("while creating overlay directory for bind path %s: %s", bp.Source, err)
+		}
+	}
+	return nil
+}
+
+// bindWaitInterval is the interval between checks of a waited bind source.
+var bindWaitInterval = 500 * time.Millisecond
+
+// checkBindSource returns an error if the bind source path isn't a mount
+// point, or is an empty directory.
+func checkBindSource(path string) error {
+	p, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return err
+	}
+	point, err := proc.ParentMount(p)
+	if err != nil {
+		return err
+	}
+	if point != p {
+		return fmt.Errorf("%s is not a mount point", path)
+	}
+	if !fs.IsDir(p) {
+		return nil
+	}
+
+	d, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if _, err := d.Readdirnames(1); err == io.EOF {
+		return fmt.Errorf("%s is mounted but empty", path)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// waitBindSource waits for the source of bp to be a mounted, non-empty file
+// system, if set by its wait or wait-timeout options, and returns an error
+// if it isn't ready in time.
+func waitBindSource(bp singularityConfig.BindPath) error {
+	timeout, err := bp.WaitTimeout()
+	if err != nil || timeout == 0 {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := checkBindSource(bp.Source)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("bind source %s not ready after %s: %s", bp.Source, timeout, err)
+		}
+		sylog.Debugf("Waiting for bind source %s: %s", bp.Source, err)
+		time.Sleep(bindWaitInterval)
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sylabs/singularity/internal/pkg/util/user"
+	singularityConfig "github.com/sylabs/singularity/pkg/runtime/engine/singularity/config"
+	"golang.org/x/sys/unix"
+)
+
+func TestParseHomeTmpfs(t *testing.T) {
+	tests := []struct {
+		home        string
+		options     string
+		isTmpfs     bool
+		expectError bool
+	}{
+		{home: "/home/user"},
+		{home: "tmpfs:/home/user"},
+		{home: "tmpfsdir"},
+		{home: "tmpfs", options: "mode=0700", isTmpfs: true},
+		{home: "tmpfs:size=256m", options: "mode=0700,size=268435456", isTmpfs: true},
+		{home: "tmpfs:size=1g", options: "mode=0700,size=1073741824", isTmpfs: true},
+		{home: "tmpfs:size=lots", isTmpfs: true, expectError: true},
+		{home: "tmpfs:size=0", isTmpfs: true, expectError: true},
+		{home: "tmpfs:mode=0777", isTmpfs: true, expectError: true},
+		{home: "tmpfs:", isTmpfs: true, expectError: true},
+	}
+
+	for _, tt := range tests {
+		options, isTmpfs, err := parseHomeTmpfs(tt.home)
+		if err != nil && !tt.expectError {
+			t.Errorf("unexpected error for %q: %s", tt.home, err)
+		} else if err == nil && tt.expectError {
+			t.Errorf("unexpected success for %q", tt.home)
+		}
+		if isTmpfs != tt.isTmpfs {
+			t.Errorf("unexpected tmpfs home %t for %q", isTmpfs, tt.home)
+		}
+		if err == nil && options != tt.options {
+			t.Errorf("unexpected options %q for %q, expected %q", options, tt.home, tt.options)
+		}
+	}
+}
+
+func TestParseDevMode(t *testing.T) {
+	tests := []struct {
+		dev         string
+		mode        string
+		devices     []string
+		expectError bool
+	}{
+		{dev: "minimal", mode: "minimal"},
+		{dev: "full", mode: "full"},
+		{dev: "custom:/dev/null", mode: "custom", devices: []string{"/dev/null"}},
+		{dev: "custom:/dev/null,/dev/zero,/dev/null", mode: "custom", devices: []string{"/dev/null", "/dev/zero"}},
+		{dev: "custom:/dev/nul*", mode: "custom", devices: []string{"/dev/null"}},
+		{dev: "custom:/dev/fd", mode: "custom", devices: []string{"/dev/fd"}},
+		{dev: "custom", expectError: true},
+		{dev: "custom:", expectError: true},
+		{dev: "host", expectError: true},
+		{dev: "custom:dev/null", expectError: true},
+		{dev: "custom:/dev/../etc/passwd", expectError: true},
+		{dev: "custom:/etc/passwd", expectError: true},
+		{dev: "custom:/dev", expectError: true},
+		{dev: "custom:/dev/singularity-nonexistent", expectError: true},
+		{dev: "custom:/dev/fd/0/x", expectError: true},
+	}
+
+	for _, tt := range tests {
+		mode, devices, err := parseDevMode(tt.dev)
+		if err != nil && !tt.expectError {
+			t.Errorf("unexpected error for %q: %s", tt.dev, err)
+		} else if err == nil && tt.expectError {
+			t.Errorf("unexpected success for %q", tt.dev)
+		}
+		if err != nil {
+			continue
+		}
+		if mode != tt.mode {
+			t.Errorf("unexpected mode %q for %q, expected %q", mode, tt.dev, tt.mode)
+		}
+		if !reflect.DeepEqual(devices, tt.devices) {
+			t.Errorf("unexpected devices %v for %q, expected %v", devices, tt.dev, tt.devices)
+		}
+	}
+}
+
+func TestParseDevices(t *testing.T) {
+	int64ptr := func(i int64) *int64 { return &i }
+
+	type deviceTest struct {
+		name        string
+		devices     []string
+		nodes       map[string]string
+		rules       []specs.LinuxDeviceCgroup
+		expectError bool
+	}
+
+	tests := []deviceTest{
+		{
+			name:    "CharDevice",
+			devices: []string{"/dev/null"},
+			nodes:   map[string]string{"/dev/null": "/dev/null"},
+			rules: []specs.LinuxDeviceCgroup{
+				{Allow: true, Type: "c", Major: int64ptr(1), Minor: int64ptr(3), Access: "rwm"},
+			},
+		},
+		{
+			name:    "Permissions",
+			devices: []string{"/dev/null:rw", "/dev/zero:r"},
+			nodes:   map[string]string{"/dev/null": "/dev/null", "/dev/zero": "/dev/zero"},
+			rules: []specs.LinuxDeviceCgroup{
+				{Allow: true, Type: "c", Major: int64ptr(1), Minor: int64ptr(3), Access: "rw"},
+				{Allow: true, Type: "c", Major: int64ptr(1), Minor: int64ptr(5), Access: "r"},
+			},
+		},
+		{name: "InvalidPermissions", devices: []string{"/dev/null:rx"}, expectError: true},
+		{name: "EmptyPermissions", devices: []string{"/dev/null:"}, expectError: true},
+		{name: "RelativePath", devices: []string{"dev/null"}, expectError: true},
+		{name: "OutsideDev", devices: []string{"/dev/../etc/passwd"}, expectError: true},
+		{name: "NotDevice", devices: []string{"/etc/passwd"}, expectError: true},
+		{name: "Directory", devices: []string{"/dev/shm"}, expectError: true},
+		{name: "SymlinkOutsideDev", devices: []string{"/dev/fd"}, expectError: true},
+		{name: "Nonexistent", devices: []string{"/dev/singularity-nonexistent"}, expectError: true},
+	}
+
+	// block devices are host specific, use the first one found if any
+	blocks, _ := filepath.Glob("/dev/*")
+	for _, path := range blocks {
+		fi, err := os.Stat(path)
+		if err != nil || fi.Mode()&os.ModeDevice == 0 || fi.Mode()&os.ModeCharDevice != 0 {
+			continue
+		}
+		if target, err := filepath.EvalSymlinks(path); err != nil || target != path {
+			continue
+		}
+		st := fi.Sys().(*syscall.Stat_t)
+		tests = append(tests, deviceTest{
+			name:    "BlockDevice",
+			devices: []string{path + ":rw"},
+			nodes:   map[string]string{path: path},
+			rules: []specs.LinuxDeviceCgroup{
+				{Allow: true, Type: "b", Major: int64ptr(int64(unix.Major(st.Rdev))), Minor: int64ptr(int64(unix.Minor(st.Rdev))), Access: "rw"},
+			},
+		})
+		break
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, rules, err := parseDevices(tt.devices)
+			if err != nil && !tt.expectError {
+				t.Fatalf("unexpected error for %v: %s", tt.devices, err)
+			} else if err == nil && tt.expectError {
+				t.Fatalf("unexpected success for %v", tt.devices)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(nodes, tt.nodes) {
+				t.Errorf("unexpected nodes %v, expected %v", nodes, tt.nodes)
+			}
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("unexpected rules %+v, expected %+v", rules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestParseEnvJSON(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "env-json-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	envFile := filepath.Join(tmpDir, "env.json")
+	if err := ioutil.WriteFile(envFile, []byte(`{"FROM_FILE": "yes"}`), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %s", envFile, err)
+	}
+
+	tests := []struct {
+		env         string
+		vars        []string
+		expectError bool
+	}{
+		{env: `{"B": "2", "A": "1"}`, vars: []string{"A=1", "B=2"}},
+		{env: ` {"EMPTY": "", "SPACES": "a b=c"}`, vars: []string{"EMPTY=", "SPACES=a b=c"}},
+		{env: `{}`, vars: []string{}},
+		{env: envFile, vars: []string{"FROM_FILE=yes"}},
+		{env: filepath.Join(tmpDir, "missing.json"), expectError: true},
+		{env: `{"A": 1}`, expectError: true},
+		{env: `{"A": true}`, expectError: true},
+		{env: `{"A": null}`, expectError: true},
+		{env: `{"A": ["1"]}`, expectError: true},
+		{env: `{"1A": "1"}`, expectError: true},
+		{env: `{"A=B": "1"}`, expectError: true},
+		{env: `{"A": "1"`, expectError: true},
+		{env: `{"A": "1"} {"B": "2"}`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		vars, err := parseEnvJSON(tt.env)
+		if err != nil && !tt.expectError {
+			t.Errorf("unexpected error for %q: %s", tt.env, err)
+		} else if err == nil && tt.expectError {
+			t.Errorf("unexpected success for %q", tt.env)
+		}
+		if err == nil && !reflect.DeepEqual(vars, tt.vars) {
+			t.Errorf("unexpected variables %v for %q, expected %v", vars, tt.env, tt.vars)
+		}
+	}
+}
+
+func TestExpandBindPath(t *testing.T) {
+	pw, err := user.CurrentOriginal()
+	if err != nil {
+		t.Fatalf("while getting current user: %s", err)
+	}
+	root, err := user.GetPwUID(0)
+	if err != nil {
+		t.Fatalf("while getting root user: %s", err)
+	}
+
+	os.Setenv("SINGULARITY_TEST_BIND_DIR", "/data/project")
+	defer os.Unsetenv("SINGULARITY_TEST_BIND_DIR")
+	os.Unsetenv("SINGULARITY_TEST_BIND_UNSET")
+
+	tests := []struct {
+		path        string
+		expected    string
+		expectError bool
+	}{
+		{path: "/opt/data", expected: "/opt/data"},
+		{path: "~", expected: pw.Dir},
+		{path: "~/data", expected: pw.Dir + "/data"},
+		{path: "~" + root.Name + "/data", expected: root.Dir + "/data"},
+		{path: "/opt/~data", expected: "/opt/~data"},
+		{path: "$SINGULARITY_TEST_BIND_DIR/in", expected: "/data/project/in"},
+		{path: "${SINGULARITY_TEST_BIND_DIR}_out", expected: "/data/project_out"},
+		{path: "~/$SINGULARITY_TEST_BIND_DIR", expected: pw.Dir + "//data/project"},
+		{path: "$SINGULARITY_TEST_BIND_UNSET/data", expected: "$SINGULARITY_TEST_BIND_UNSET/data"},
+		{path: "${SINGULARITY_TEST_BIND_UNSET}/$SINGULARITY_TEST_BIND_DIR", expected: "${SINGULARITY_TEST_BIND_UNSET}//data/project"},
+		{path: "/data/$1/a$/b$", expected: "/data/$1/a$/b$"},
+		{path: "/data/${SINGULARITY_TEST_BIND_DIR", expected: "/data/${SINGULARITY_TEST_BIND_DIR"},
+		{path: "/data/${}", expected: "/data/${}"},
+		{path: "~singularity-test-unknown-user/data", expectError: true},
+	}
+
+	for _, tt := range tests {
+		p, err := expandBindPath(tt.path)
+		if err != nil && !tt.expectError {
+			t.Errorf("unexpected error for %q: %s", tt.path, err)
+		} else if err == nil && tt.expectError {
+			t.Errorf("unexpected success for %q", tt.path)
+		} else if err == nil && p != tt.expected {
+			t.Errorf("got %q for %q, expected %q", p, tt.path, tt.expected)
+		}
+	}
+}
+
+func TestWaitBindSource(t *testing.T) {
+	defer func(d time.Duration) {
+		bindWaitInterval = d
+	}(bindWaitInterval)
+	bindWaitInterval = 10 * time.Millisecond
+
+	tmpDir, err := ioutil.TempDir("", "wait-bind-")
+	if err != nil {
+		t.Fatalf("while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name        string
+		source      string
+		options     map[string]*singularityConfig.BindOption
+		expectError bool
+	}{
+		{
+			name:   "NoWait",
+			source: tmpDir,
+		},
+		{
+			name:    "MountPoint",
+			source:  "/proc",
+			options: map[string]*singularityConfig.BindOption{"wait": {}},
+		},
+		{
+			name:        "NotMountPoint",
+			source:      tmpDir,
+			options:     map[string]*singularityConfig.BindOption{"wait-timeout": {Value: "50ms"}},
+			expectError: true,
+		},
+		{
+			name:        "Missing",
+			source:      filepath.Join(tmpDir, "missing"),
+			options:     map[string]*singularityConfig.BindOption{"wait-timeout": {Value: "50ms"}},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bp := singularityConfig.BindPath{Source: tt.source, Destination: "/data", Options: tt.options}
+			err := waitBindSource(bp)
+			if err != nil && !tt.expectError {
+				t.Errorf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Errorf("unexpected success")
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@
 package cli
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -31,28 +32,42 @@ import (
 )
 
 var buildArgs struct {
-	sections      []string
-	bindPaths     []string
-	mounts        []string
-	arch          string
-	builderURL    string
-	libraryURL    string
-	keyServerURL  string
-	webURL        string
-	detached      bool
-	encrypt       bool
-	fakeroot      bool
-	fixPerms      bool
-	isJSON        bool
-	noCleanUp     bool
-	noTest        bool
-	remote        bool
-	sandbox       bool
-	update        bool
-	nvidia        bool
-	nvccli        bool
-	rocm          bool
-	writableTmpfs bool // For test section only
+	sections       []string
+	bindPaths      []string
+	mounts         []string
+	arch           string
+	authFile       string
+	builderURL     string
+	libraryURL     string
+	keyServerURL   string
+	webURL         string
+	detached       bool
+	dockerfile     bool
+	encrypt        bool
+	fakeroot       bool
+	fakerootShim   bool
+	fetchFiles     bool
+	fixPerms       bool
+	fixPermsReport string
+	isJSON         bool
+	jsonEvents     bool
+	noCleanUp      bool
+	noDedup        bool
+	noTest         bool
+	oci            bool
+	postTimeout    string
+	remote         bool
+	sandbox        bool
+	strip          bool
+	stripRules     string
+	update         bool
+	nvidia         bool
+	nvccli         bool
+	rocm           bool
+	writableTmpfs  bool // For test section only
+	net            bool // For post and test sections only
+	noNet          bool // For post and test sections only
+	network        string
 }
 
 // -s|--sandbox
@@ -66,6 +81,16 @@ var buildSandboxFlag = cmdline.Flag{
 	EnvKeys:      []string{"SANDBOX"},
 }
 
+// --oci
+var buildOCIFlag = cmdline.Flag{
+	ID:           "buildOCIFlag",
+	Value:        &buildArgs.oci,
+	DefaultValue: false,
+	Name:         "oci",
+	Usage:        "build image as an OCI image layout directory, with a single layer holding the root filesystem",
+	EnvKeys:      []string{"OCI"},
+}
+
 // --section
 var buildSectionFlag = cmdline.Flag{
 	ID:           "buildSectionFlag",
@@ -86,6 +111,16 @@ var buildJSONFlag = cmdline.Flag{
 	EnvKeys:      []string{"JSON"},
 }
 
+// --json-events
+var buildJSONEventsFlag = cmdline.Flag{
+	ID:           "buildJSONEventsFlag",
+	Value:        &buildArgs.jsonEvents,
+	DefaultValue: false,
+	Name:         "json-events",
+	Usage:        "emit build progress events as newline-delimited JSON on standard output, logs stay on standard error",
+	EnvKeys:      []string{"BUILD_JSON_EVENTS"},
+}
+
 // -u|--update
 var buildUpdateFlag = cmdline.Flag{
 	ID:           "buildUpdateFlag",
@@ -160,6 +195,58 @@ var buildLibraryFlag = cmdline.Flag{
 	EnvKeys:      []string{"LIBRARY"},
 }
 
+// --authfile
+var buildAuthFileFlag = cmdline.Flag{
+	ID:           "buildAuthFileFlag",
+	Value:        &buildArgs.authFile,
+	DefaultValue: "",
+	Name:         "authfile",
+	Usage:        "docker/oci registry credential file used to pull base images of docker, oci and oras bootstrap agents (default: ~/.singularity/docker-config.json)",
+	EnvKeys:      []string{"AUTHFILE"},
+	Tag:          "<path>",
+}
+
+// --no-dedup
+var buildNoDedupFlag = cmdline.Flag{
+	ID:           "buildNoDedupFlag",
+	Value:        &buildArgs.noDedup,
+	DefaultValue: false,
+	Name:         "no-dedup",
+	Usage:        "do not detect and deduplicate identical files when creating the SIF squashfs file system (deduplication is enabled by default), speeds up builds at the cost of a larger image",
+	EnvKeys:      []string{"NO_DEDUP"},
+}
+
+// --dockerfile
+var buildDockerfileFlag = cmdline.Flag{
+	ID:           "buildDockerfileFlag",
+	Value:        &buildArgs.dockerfile,
+	DefaultValue: false,
+	Name:         "dockerfile",
+	Usage:        "build from a Dockerfile, or the Dockerfile in a directory, translating its instructions into a definition file with the Dockerfile directory as build context",
+	EnvKeys:      []string{"DOCKERFILE"},
+}
+
+// --strip
+var buildStripFlag = cmdline.Flag{
+	ID:           "buildStripFlag",
+	Value:        &buildArgs.strip,
+	DefaultValue: false,
+	Name:         "strip",
+	Usage:        "after the %post section, remove documentation, locale data and static libraries and strip debug symbols from binaries to produce a slim image",
+	EnvKeys:      []string{"STRIP"},
+}
+
+// --strip-rules
+var buildStripRulesFlag = cmdline.Flag{
+	ID:           "buildStripRulesFlag",
+	Value:        &buildArgs.stripRules,
+	DefaultValue: "",
+	Name:         "strip-rules",
+	Usage:        "with --strip, use the ruleset in this file instead of the default one, one '<keep|remove|strip> <pattern>' rule per line",
+	EnvKeys:      []string{"STRIP_RULES"},
+	Tag:          "<file>",
+}
+
 // --disable-cache
 var buildDisableCacheFlag = cmdline.Flag{
 	ID:           "buildDisableCacheFlag",
@@ -191,6 +278,37 @@ var buildFakerootFlag = cmdline.Flag{
 	EnvKeys:      []string{"FAKEROOT"},
 }
 
+// --fakeroot-shim
+var buildFakerootShimFlag = cmdline.Flag{
+	ID:           "buildFakerootShimFlag",
+	Value:        &buildArgs.fakerootShim,
+	DefaultValue: false,
+	Name:         "fakeroot-shim",
+	Usage:        "with --fakeroot, run %post with the host fakeroot(1) library to fake privileged operations like mknod and chown, slows down %post",
+	EnvKeys:      []string{"FAKEROOT_SHIM"},
+}
+
+// --fetch-files
+var buildFetchFilesFlag = cmdline.Flag{
+	ID:           "buildFetchFilesFlag",
+	Value:        &buildArgs.fetchFiles,
+	DefaultValue: false,
+	Name:         "fetch-files",
+	Usage:        "download http(s) URL sources of %files, caching them by URL and ETag to only download unchanged files once",
+	EnvKeys:      []string{"FETCH_FILES"},
+}
+
+// --post-timeout
+var buildPostTimeoutFlag = cmdline.Flag{
+	ID:           "buildPostTimeoutFlag",
+	Value:        &buildArgs.postTimeout,
+	DefaultValue: "",
+	Name:         "post-timeout",
+	Usage:        "kill the %post and %test sections, failing the build, if they run longer than this duration (e.g. 30m, 1h30m), no timeout by default",
+	EnvKeys:      []string{"POST_TIMEOUT"},
+	Tag:          "<duration>",
+}
+
 // -e|--encrypt
 var buildEncryptFlag = cmdline.Flag{
 	ID:           "buildEncryptFlag",
@@ -212,6 +330,17 @@ var buildFixPermsFlag = cmdline.Flag{
 	EnvKeys:      []string{"FIXPERMS"},
 }
 
+// --fix-perms-report
+var buildFixPermsReportFlag = cmdline.Flag{
+	ID:           "fixPermsReportFlag",
+	Value:        &buildArgs.fixPermsReport,
+	DefaultValue: "",
+	Name:         "fix-perms-report",
+	Usage:        "with --fix-perms, write a JSON report of every path whose permissions were changed, with old and new modes, to this file",
+	EnvKeys:      []string{"FIXPERMS_REPORT"},
+	Tag:          "<file>",
+}
+
 // --nv
 var buildNvFlag = cmdline.Flag{
 	ID:           "nvFlag",
@@ -281,6 +410,37 @@ var buildWritableTmpfsFlag = cmdline.Flag{
 	EnvKeys:      []string{"WRITABLE_TMPFS"},
 }
 
+// --net
+var buildNetFlag = cmdline.Flag{
+	ID:           "buildNetFlag",
+	Value:        &buildArgs.net,
+	DefaultValue: false,
+	Name:         "net",
+	Usage:        "during the %post and %test sections, run in a new network namespace (sets up a bridge network interface by default)",
+	EnvKeys:      []string{"BUILD_NET"},
+}
+
+// --network
+var buildNetworkFlag = cmdline.Flag{
+	ID:           "buildNetworkFlag",
+	Value:        &buildArgs.network,
+	DefaultValue: "bridge",
+	Name:         "network",
+	Usage:        "specify desired network type separated by commas, each network will bring up a dedicated interface inside the build container (requires --net)",
+	EnvKeys:      []string{"BUILD_NETWORK"},
+	Tag:          "<name>",
+}
+
+// --no-net
+var buildNoNetFlag = cmdline.Flag{
+	ID:           "buildNoNetFlag",
+	Value:        &buildArgs.noNet,
+	DefaultValue: false,
+	Name:         "no-net",
+	Usage:        "during the %post and %test sections, run in an isolated network namespace with only a loopback interface",
+	EnvKeys:      []string{"BUILD_NO_NET"},
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(buildCmd)
@@ -291,22 +451,35 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&buildDisableCacheFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildEncryptFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildFakerootFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFakerootShimFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFetchFilesFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildFixPermsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFixPermsReportFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildJSONFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildJSONEventsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildLibraryFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildNoCleanupFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNoDedupFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildNoTestFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildOCIFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildPostTimeoutFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildRemoteFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildSandboxFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildSectionFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildDockerfileFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildStripFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildStripRulesFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildUpdateFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonForceFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&commonRegistriesConfFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&commonCacheDirFlag, buildCmd)
 
 		cmdManager.RegisterFlagForCmd(&dockerUsernameFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&dockerPasswordFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&dockerLoginFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildAuthFileFlag, buildCmd)
 
 		cmdManager.RegisterFlagForCmd(&commonPromptForPassphraseFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonPEMFlag, buildCmd)
@@ -317,6 +490,9 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&buildBindFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildMountFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildWritableTmpfsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNetFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNetworkFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNoNetFlag, buildCmd)
 	})
 }
 
@@ -353,6 +529,9 @@ func checkBuildTarget(path string) error {
 		return fmt.Errorf("failed to get absolute path for %q: %v", path, err)
 	}
 
+	if buildArgs.oci && (buildArgs.sandbox || buildArgs.update) {
+		return fmt.Errorf("--oci can't be combined with --sandbox or --update")
+	}
 	if !buildArgs.sandbox && buildArgs.update {
 		return fmt.Errorf("only sandbox update is supported: --sandbox flag is missing")
 	}
@@ -369,13 +548,16 @@ func checkBuildTarget(path string) error {
 				return fmt.Errorf("could not read sandbox directory %s: %s", abspath, err)
 			} else if len(files) > 0 {
 				required := 0
+				ociLayout := false
 				for _, f := range files {
 					switch f.Name() {
 					case ".singularity.d", "dev", "proc", "sys":
 						required++
+					case "oci-layout":
+						ociLayout = true
 					}
 				}
-				if required != 4 {
+				if required != 4 && !(buildArgs.oci && ociLayout) {
 					return fmt.Errorf("%s is not empty and is not a Singularity sandbox, check its content first and use --force if you want to overwrite it", abspath)
 				}
 			}
@@ -433,15 +615,13 @@ func definitionFromSpec(spec string) (types.Definition, error) {
 	if isValid {
 		sylog.Debugf("Found valid definition: %s\n", spec)
 		// File exists and contains valid definition
-		var defFile *os.File
-		defFile, err = os.Open(spec)
+		var raw []byte
+		raw, err = parser.ReadDefinitionFile(spec)
 		if err != nil {
 			return types.Definition{}, err
 		}
 
-		defer defFile.Close()
-
-		return parser.ParseDefinitionFile(defFile)
+		return parser.ParseDefinitionFile(bytes.NewReader(raw))
 	}
 
 	// File exists and does NOT contain a valid definition
@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	keyclient "github.com/sylabs/scs-key-client/client"
@@ -113,6 +114,9 @@ func runBuild(cmd *cobra.Command, args []string) {
 		}
 		os.Setenv("SINGULARITY_NVCCLI", "1")
 	}
+	if buildArgs.jsonEvents && buildArgs.remote {
+		sylog.Fatalf("--json-events option is not supported for remote build")
+	}
 	if buildArgs.rocm {
 		if buildArgs.remote {
 			sylog.Fatalf("--rocm option is not supported for remote build")
@@ -140,6 +144,38 @@ func runBuild(cmd *cobra.Command, args []string) {
 		}
 		os.Setenv("SINGULARITY_WRITABLE_TMPFS", "1")
 	}
+	if buildArgs.fakerootShim && buildArgs.remote {
+		sylog.Fatalf("--fakeroot-shim option is not supported for remote build")
+	}
+	if buildArgs.noNet {
+		// --no-net takes precedence over --net/--network
+		buildArgs.net = true
+		buildArgs.network = "none"
+	}
+	if buildArgs.net {
+		if buildArgs.remote {
+			sylog.Fatalf("--net option is not supported for remote build")
+		}
+		os.Setenv("SINGULARITY_NET", "1")
+		os.Setenv("SINGULARITY_NETWORK", buildArgs.network)
+	} else {
+		// the run-time network variables are passed through to the %post
+		// and %test sections, they must not apply without --net
+		os.Unsetenv("SINGULARITY_NET")
+		os.Unsetenv("SINGULARITY_NETWORK")
+	}
+	if buildArgs.authFile != "" && buildArgs.remote {
+		sylog.Fatalf("--authfile option is not supported for remote build")
+	}
+	if buildArgs.dockerfile && buildArgs.remote {
+		sylog.Fatalf("--dockerfile option is not supported for remote build")
+	}
+	if buildArgs.strip && buildArgs.remote {
+		sylog.Fatalf("--strip option is not supported for remote build")
+	}
+	if buildArgs.oci && buildArgs.remote {
+		sylog.Fatalf("--oci option is not supported for remote build")
+	}
 
 	if buildArgs.arch != runtime.GOARCH && !buildArgs.remote {
 		sylog.Fatalf("Requested architecture (%s) does not match host (%s). Cannot build locally.", buildArgs.arch, runtime.GOARCH)
@@ -249,7 +285,7 @@ func runBuildRemote(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 					NoCleanUp: buildArgs.noCleanUp,
 					Opts: types.Options{
 						ImgCache: imgCache,
-						NoCache:  disableCache,
+						NoCache:  imgCache.IsDisabled(),
 						TmpDir:   tmpDir,
 						Update:   buildArgs.update,
 						Force:    forceOverwrite,
@@ -295,12 +331,50 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 		}
 	}
 
+	if buildArgs.fixPermsReport != "" {
+		if !buildArgs.fixPerms {
+			sylog.Fatalf("--fix-perms-report requires --fix-perms")
+		}
+		report, err := fs.Abs(buildArgs.fixPermsReport)
+		if err != nil {
+			sylog.Fatalf("While resolving --fix-perms-report path: %v", err)
+		}
+		buildArgs.fixPermsReport = report
+	}
+
+	if buildArgs.stripRules != "" {
+		if !buildArgs.strip {
+			sylog.Fatalf("--strip-rules requires --strip")
+		}
+		rules, err := fs.Abs(buildArgs.stripRules)
+		if err != nil {
+			sylog.Fatalf("While resolving --strip-rules path: %v", err)
+		}
+		if !fs.IsFile(rules) {
+			sylog.Fatalf("Strip ruleset %s doesn't exist or is not a file", rules)
+		}
+		buildArgs.stripRules = rules
+	}
+
+	var postTimeout time.Duration
+	if buildArgs.postTimeout != "" {
+		d, err := time.ParseDuration(buildArgs.postTimeout)
+		if err != nil || d <= 0 {
+			sylog.Fatalf("Invalid --post-timeout %q: must be a positive duration like 30m or 1h30m", buildArgs.postTimeout)
+		}
+		postTimeout = d
+	}
+
+	if buildArgs.fakerootShim && !buildArgs.fakeroot {
+		sylog.Fatalf("--fakeroot-shim requires --fakeroot")
+	}
+
 	imgCache := getCacheHandle(cache.Config{Disable: disableCache})
 	if imgCache == nil {
 		sylog.Fatalf("Failed to create an image cache handle")
 	}
 
-	if syscall.Getuid() != 0 && !buildArgs.fakeroot && fs.IsFile(spec) && !isImage(spec) {
+	if syscall.Getuid() != 0 && !buildArgs.fakeroot && (buildArgs.dockerfile || fs.IsFile(spec) && !isImage(spec)) {
 		sylog.Fatalf("You must be the root user, however you can use --remote or --fakeroot to build from a Singularity recipe file")
 	}
 
@@ -314,8 +388,24 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 		sylog.Fatalf("While creating Docker credentials: %v", err)
 	}
 
+	if buildArgs.authFile != "" {
+		authFile, err := fs.Abs(buildArgs.authFile)
+		if err != nil {
+			sylog.Fatalf("While resolving path of credential file %s: %v", buildArgs.authFile, err)
+		}
+		if !fs.IsFile(authFile) {
+			sylog.Fatalf("Credential file %s doesn't exist or is not a file", authFile)
+		}
+		buildArgs.authFile = authFile
+	}
+
 	// parse definition to determine build source
-	defs, err := build.MakeAllDefs(spec)
+	var defs []types.Definition
+	if buildArgs.dockerfile {
+		defs, err = build.MakeDockerfileDefs(spec)
+	} else {
+		defs, err = build.MakeAllDefs(spec)
+	}
 	if err != nil {
 		sylog.Fatalf("Unable to build from %s: %v", spec, err)
 	}
@@ -366,6 +456,20 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 		buildFormat = "sandbox"
 		sandboxTarget = true
 
+		if buildArgs.noDedup {
+			sylog.Warningf("--no-dedup has no effect when building a sandbox")
+		}
+	}
+	if buildArgs.oci {
+		if keyInfo != nil {
+			sylog.Fatalf("Encryption is not supported for OCI builds")
+		}
+		buildFormat = "oci"
+	}
+
+	var events *build.EventWriter
+	if buildArgs.jsonEvents {
+		events = build.NewEventWriter(os.Stdout)
 	}
 
 	b, err := build.New(
@@ -374,10 +478,11 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 			Dest:      dst,
 			Format:    buildFormat,
 			NoCleanUp: buildArgs.noCleanUp,
+			Events:    events,
 			Opts: types.Options{
 				ImgCache:          imgCache,
 				TmpDir:            tmpDir,
-				NoCache:           disableCache,
+				NoCache:           imgCache.IsDisabled(),
 				Update:            buildArgs.update,
 				Force:             forceOverwrite,
 				Sections:          buildArgs.sections,
@@ -387,9 +492,17 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 				LibraryAuthToken:  authToken,
 				KeyServerOpts:     ko,
 				DockerAuthConfig:  authConf,
+				DockerAuthFile:    buildArgs.authFile,
 				EncryptionKeyInfo: keyInfo,
 				FixPerms:          buildArgs.fixPerms,
+				FixPermsReport:    buildArgs.fixPermsReport,
 				SandboxTarget:     sandboxTarget,
+				NoDedup:           buildArgs.noDedup,
+				Strip:             buildArgs.strip,
+				StripRules:        buildArgs.stripRules,
+				FakerootShim:      buildArgs.fakerootShim,
+				PostTimeout:       postTimeout,
+				FetchFiles:        buildArgs.fetchFiles,
 			},
 		})
 	if err != nil {
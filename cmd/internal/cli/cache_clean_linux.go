@@ -7,6 +7,8 @@ package cli
 
 import (
 	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
@@ -15,6 +17,7 @@ import (
 	"github.com/sylabs/singularity/docs"
 	"github.com/sylabs/singularity/internal/app/singularity"
 	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/pkg/cmdline"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
@@ -25,6 +28,8 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&cacheCleanDaysFlag, cacheCleanCmd)
 		cmdManager.RegisterFlagForCmd(&cacheCleanDryFlag, cacheCleanCmd)
 		cmdManager.RegisterFlagForCmd(&cacheCleanForceFlag, cacheCleanCmd)
+		cmdManager.RegisterFlagForCmd(&cacheCleanJSONFlag, cacheCleanCmd)
+		cmdManager.RegisterFlagForCmd(&commonCacheDirFlag, cacheCleanCmd)
 	})
 }
 
@@ -33,6 +38,7 @@ var (
 	cacheCleanDays  int
 	cacheCleanDry   bool
 	cacheCleanForce bool
+	cacheCleanJSON  bool
 
 	// -T|--type
 	cacheCleanTypesFlag = cmdline.Flag{
@@ -74,6 +80,16 @@ var (
 		Usage:        "suppress any prompts and clean the cache",
 	}
 
+	// -j|--json
+	cacheCleanJSONFlag = cmdline.Flag{
+		ID:           "cacheCleanJSONFlag",
+		Value:        &cacheCleanJSON,
+		DefaultValue: false,
+		Name:         "json",
+		ShortHand:    "j",
+		Usage:        "print the removed cache entries and the reclaimed space in JSON format, requires --dry-run or --force",
+	}
+
 	// cacheCleanCmd is 'singularity cache clean' and will clear your local singularity cache
 	cacheCleanCmd = &cobra.Command{
 		DisableFlagsInUseLine: true,
@@ -90,8 +106,18 @@ var (
 	}
 )
 
+// cacheCleanResult is the JSON output of 'cache clean --json'.
+type cacheCleanResult struct {
+	DryRun  bool                 `json:"dryRun"`
+	Entries []cache.RemovedEntry `json:"entries"`
+	Size    int64                `json:"size"`
+}
+
 func cleanCache() error {
-	if cacheCleanDry {
+	if cacheCleanJSON && !cacheCleanForce && !cacheCleanDry {
+		return errors.New("--json requires --dry-run or --force, the confirmation prompt can't be answered")
+	}
+	if cacheCleanDry && !cacheCleanJSON {
 		fmt.Println("User requested a dry run. Not actually deleting any data!")
 	}
 	if !cacheCleanForce && !cacheCleanDry {
@@ -107,11 +133,35 @@ func cleanCache() error {
 
 	// create a handle to access the current image cache
 	imgCache := getCacheHandle(cache.Config{})
-	err := singularity.CleanSingularityCache(imgCache, cacheCleanDry, cacheCleanTypes, cacheCleanDays)
-	if err != nil {
-		return fmt.Errorf("could not clean cache: %v", err)
+	// the entries removed before an error are still reported
+	removed, cleanErr := singularity.CleanSingularityCache(imgCache, cacheCleanDry, cacheCleanTypes, cacheCleanDays)
+	if cleanErr != nil {
+		cleanErr = fmt.Errorf("could not clean cache: %v", cleanErr)
+	}
+
+	res := cacheCleanResult{DryRun: cacheCleanDry, Entries: removed}
+	if res.Entries == nil {
+		res.Entries = []cache.RemovedEntry{}
+	}
+	for _, e := range removed {
+		res.Size += e.Size
+	}
+
+	if cacheCleanJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(res); err != nil {
+			return fmt.Errorf("while encoding removed cache entries: %v", err)
+		}
+		return cleanErr
+	}
+
+	if cacheCleanDry {
+		fmt.Printf("Would remove %d cache entries, reclaiming %s\n", len(removed), fs.FindSize(res.Size))
+	} else {
+		fmt.Printf("Removed %d cache entries, reclaimed %s\n", len(removed), fs.FindSize(res.Size))
 	}
-	return nil
+	return cleanErr
 }
 
 func cleanCachePrompt() (bool, error) {
@@ -45,6 +45,7 @@ func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterFlagForCmd(&cacheListTypesFlag, CacheListCmd)
 		cmdManager.RegisterFlagForCmd(&cacheListVerboseFlag, CacheListCmd)
+		cmdManager.RegisterFlagForCmd(&commonCacheDirFlag, CacheListCmd)
 	})
 }
 
@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -18,15 +18,27 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"text/tabwriter"
 
+	ocitypes "github.com/containers/image/v5/types"
+	units "github.com/docker/go-units"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/spf13/cobra"
+	scslibclient "github.com/sylabs/scs-library-client/client"
 	"github.com/sylabs/sif/v2/pkg/sif"
 	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/internal/pkg/build/oci"
+	"github.com/sylabs/singularity/internal/pkg/client/library"
 	"github.com/sylabs/singularity/internal/pkg/util/env"
+	"github.com/sylabs/singularity/internal/pkg/util/shell"
+	"github.com/sylabs/singularity/internal/pkg/util/uri"
 	"github.com/sylabs/singularity/pkg/cmdline"
 	"github.com/sylabs/singularity/pkg/image"
 	"github.com/sylabs/singularity/pkg/inspect"
+	"github.com/sylabs/singularity/pkg/syfs"
 	"github.com/sylabs/singularity/pkg/sylog"
+	useragent "github.com/sylabs/singularity/pkg/util/user-agent"
 )
 
 var (
@@ -45,6 +57,12 @@ var (
 	labels      bool
 	deffile     bool
 	jsonfmt     bool
+	healthcheck bool
+	showDigest  bool
+	sifLayers   bool
+
+	inspectRemote     bool
+	inspectLibraryURI string
 )
 
 // -l|--labels
@@ -133,7 +151,7 @@ var inspectEnvironmentFlag = cmdline.Flag{
 	DefaultValue: false,
 	Name:         "environment",
 	ShortHand:    "e",
-	Usage:        "show the environment settings for the image",
+	Usage:        "show the environment settings for the image (with --json, also show the resolved environment variables)",
 }
 
 // -H|--helpfile
@@ -146,6 +164,33 @@ var inspectHelpfileFlag = cmdline.Flag{
 	Usage:        "inspect the runscript helpfile, if it exists",
 }
 
+// --healthcheck
+var inspectHealthcheckFlag = cmdline.Flag{
+	ID:           "inspectHealthcheckFlag",
+	Value:        &healthcheck,
+	DefaultValue: false,
+	Name:         "healthcheck",
+	Usage:        "show the health check inherited from a Docker image HEALTHCHECK, if it exists",
+}
+
+// --digest
+var inspectDigestFlag = cmdline.Flag{
+	ID:           "inspectDigestFlag",
+	Value:        &showDigest,
+	DefaultValue: false,
+	Name:         "digest",
+	Usage:        "show the sha256 digest of a SIF image file, as used by the library to identify images",
+}
+
+// --sif-layers
+var inspectSIFLayersFlag = cmdline.Flag{
+	ID:           "inspectSIFLayersFlag",
+	Value:        &sifLayers,
+	DefaultValue: false,
+	Name:         "sif-layers",
+	Usage:        "list the partitions of a SIF image with their type, filesystem, compression and size",
+}
+
 // --all
 var inspectAllFlag = cmdline.Flag{
 	ID:           "inspectAllFlag",
@@ -155,12 +200,33 @@ var inspectAllFlag = cmdline.Flag{
 	Usage:        "show all available data (imply --json option)",
 }
 
+// --remote
+var inspectRemoteFlag = cmdline.Flag{
+	ID:           "inspectRemoteFlag",
+	Value:        &inspectRemote,
+	DefaultValue: false,
+	Name:         "remote",
+	Usage:        "inspect the labels and metadata of a library:// or docker:// image without downloading it",
+}
+
+// --library
+var inspectLibraryFlag = cmdline.Flag{
+	ID:           "inspectLibraryFlag",
+	Value:        &inspectLibraryURI,
+	DefaultValue: "",
+	Name:         "library",
+	Usage:        "the library to inspect a library:// image from with --remote",
+	EnvKeys:      []string{"LIBRARY"},
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(InspectCmd)
 
 		cmdManager.RegisterFlagForCmd(&inspectAppNameFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectDeffileFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectDigestFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectSIFLayersFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectEnvironmentFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectHelpfileFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectJSONFlag, InspectCmd)
@@ -170,6 +236,15 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&inspectTestFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectAppsListFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectAllFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectHealthcheckFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectRemoteFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectLibraryFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&commonRegistriesConfFlag, InspectCmd)
+
+		cmdManager.RegisterFlagForCmd(&dockerUsernameFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&dockerPasswordFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&dockerLoginFlag, InspectCmd)
 	})
 }
 
@@ -310,6 +385,13 @@ func (c *command) setAttribute(section, value, file string) error {
 		}
 	case "startscript":
 		c.metadata.Data.Attributes.Startscript = value
+	case "healthcheck":
+		hc := new(inspect.Healthcheck)
+		if err := json.Unmarshal([]byte(value), hc); err != nil {
+			sylog.Warningf("Unable to parse healthcheck: %s", err)
+		} else {
+			c.metadata.Data.Attributes.Healthcheck = hc
+		}
 	case "environment":
 		if app != "" {
 			c.metadata.Data.Attributes.Apps[app].Environment[file] = value
@@ -463,6 +545,17 @@ func (c *command) addStartscriptCommand() {
 	}
 }
 
+func (c *command) addHealthcheckCommand() {
+	if c.sifMetadata == nil {
+		c.addSingleFileCommand("healthcheck.json", "healthcheck")
+		return
+	}
+
+	if c.appName == "" {
+		c.metadata.Attributes.Healthcheck = c.sifMetadata.Attributes.Healthcheck
+	}
+}
+
 func (c *command) addTestCommand() {
 	if c.sifMetadata == nil {
 		c.addSingleFileCommand("test", "test")
@@ -610,9 +703,238 @@ func printSortedMap(m map[string]string, fn func(key string)) {
 	}
 }
 
+// getRunscriptProcess returns the entrypoint and cmd run by the runscript
+// of an image built from an OCI image. They are read from the OCI image
+// configuration stored in SIF images, or parsed from the runscript
+// generated for the OCI image otherwise. A nil process is returned if the
+// runscript is not the one generated for an OCI image, e.g. when it was
+// replaced by a definition file %runscript.
+func getRunscriptProcess(img *image.Image, runscript string) *inspect.Process {
+	process := parseOCIRunscript(runscript)
+	if process == nil {
+		return nil
+	}
+	if img.Type == image.SIF {
+		r, err := image.NewSectionReader(img, image.SIFDescOCIConfigJSON, -1)
+		if err == nil {
+			var imgConfig imgspecv1.ImageConfig
+			if err := json.NewDecoder(r).Decode(&imgConfig); err == nil {
+				return newProcess(imgConfig.Entrypoint, imgConfig.Cmd)
+			}
+			sylog.Warningf("Unable to decode %s SIF descriptor: %s", image.SIFDescOCIConfigJSON, err)
+		} else if err != image.ErrNoSection {
+			sylog.Warningf("Unable to read %s SIF descriptor: %s", image.SIFDescOCIConfigJSON, err)
+		}
+	}
+	return process
+}
+
+// parseOCIRunscript returns the entrypoint and cmd set in a runscript
+// generated for an OCI image, or nil for any other runscript.
+func parseOCIRunscript(runscript string) *inspect.Process {
+	entrypoint, cmd, err := shell.ParseOCIRunscript(runscript)
+	if err != nil {
+		return nil
+	}
+	return newProcess(entrypoint, cmd)
+}
+
+// newProcess returns the process running the entrypoint with cmd as
+// default arguments.
+func newProcess(entrypoint, cmd []string) *inspect.Process {
+	args := append([]string{}, entrypoint...)
+	return &inspect.Process{
+		Entrypoint: entrypoint,
+		Cmd:        cmd,
+		Args:       append(args, cmd...),
+	}
+}
+
 // returns true if flags for other forms of information are unset.
 func defaultToLabels() bool {
-	return !(helpfile || deffile || runscript || startscript || testfile || environment || listApps)
+	return !(helpfile || deffile || runscript || startscript || testfile || environment || listApps || healthcheck)
+}
+
+// resolveEnvironment populates the resolved environment of the container
+// and its apps from the inspected environment files.
+func resolveEnvironment(m *inspect.Metadata) {
+	if len(m.Attributes.Environment) > 0 {
+		resolved, err := env.ResolveContainerEnv(m.Attributes.Environment)
+		if err != nil {
+			sylog.Warningf("Resolved environment may be incomplete: %s", err)
+		}
+		m.Attributes.ResolvedEnvironment = resolved
+	}
+
+	for name, app := range m.Attributes.Apps {
+		if len(app.Environment) == 0 {
+			continue
+		}
+		// app environment is sourced after the container environment
+		scripts := make(map[string]string, len(m.Attributes.Environment)+len(app.Environment))
+		for k, v := range m.Attributes.Environment {
+			scripts[k] = v
+		}
+		for k, v := range app.Environment {
+			scripts[k] = v
+		}
+		resolved, err := env.ResolveContainerEnv(scripts)
+		if err != nil {
+			sylog.Warningf("Resolved environment of app %s may be incomplete: %s", name, err)
+		}
+		app.ResolvedEnvironment = resolved
+	}
+}
+
+// getRemoteMetadata returns the labels and metadata of the library:// or
+// docker:// image ref fetched from the library or registry.
+func getRemoteMetadata(cmd *cobra.Command, ref string) (*inspect.Metadata, error) {
+	transport, _ := uri.Split(ref)
+
+	switch transport {
+	case LibraryProtocol:
+		libraryRef, err := library.NormalizeLibraryRef(ref)
+		if err != nil {
+			return nil, fmt.Errorf("malformed library reference: %v", err)
+		}
+		if inspectLibraryURI != "" && libraryRef.Host != "" {
+			return nil, fmt.Errorf("conflicting arguments; do not use --library with a library URI containing host name")
+		}
+
+		libraryURI := inspectLibraryURI
+		if libraryRef.Host != "" {
+			// override libraryURI if ref contains host name
+			if noHTTPS {
+				libraryURI = "http://" + libraryRef.Host
+			} else {
+				libraryURI = "https://" + libraryRef.Host
+			}
+		}
+
+		lc, err := getLibraryClientConfig(libraryURI)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get library client configuration: %v", err)
+		}
+		imageRef := fmt.Sprintf("%s:%s", libraryRef.Path, libraryRef.Tags[0])
+		return singularity.InspectRemoteLibrary(cmd.Context(), lc, imageRef, runtime.GOARCH)
+	case "docker":
+		ociAuth, err := makeDockerCredentials(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("while creating Docker credentials: %v", err)
+		}
+		sysCtx := &ocitypes.SystemContext{
+			OCIInsecureSkipTLSVerify: noHTTPS,
+			DockerAuthConfig:         ociAuth,
+			AuthFilePath:             syfs.DockerConf(),
+			DockerRegistryUserAgent:  useragent.Value(),
+			SystemRegistriesConfPath: oci.RegistriesConfPath(),
+		}
+		if noHTTPS {
+			sysCtx.DockerInsecureSkipTLSVerify = ocitypes.NewOptionalBool(true)
+		}
+		return singularity.InspectRemoteOCI(cmd.Context(), ref, sysCtx)
+	}
+	return nil, fmt.Errorf("unsupported remote image %s: only library:// and docker:// images can be inspected with --remote", ref)
+}
+
+// runInspectRemote displays the labels and metadata of the remote image ref.
+func runInspectRemote(cmd *cobra.Command, ref string) {
+	if !defaultToLabels() || AppName != "" {
+		sylog.Fatalf("Only labels and metadata can be inspected with --remote")
+	}
+
+	m, err := getRemoteMetadata(cmd, ref)
+	if err != nil {
+		sylog.Fatalf("Failed to inspect remote image %s: %s", ref, err)
+	}
+
+	if jsonfmt || allData {
+		jsonObj, err := json.MarshalIndent(m, "", "\t")
+		if err != nil {
+			sylog.Fatalf("Could not format inspected data as JSON")
+		}
+		fmt.Printf("%s\n", string(jsonObj))
+		return
+	}
+
+	printSortedMap(m.Attributes.Labels, func(k string) {
+		fmt.Printf("%s: %s\n", k, m.Attributes.Labels[k])
+	})
+	if len(m.Attributes.Labels) > 0 && len(m.Attributes.Remote) > 0 {
+		fmt.Println()
+	}
+	printSortedMap(m.Attributes.Remote, func(k string) {
+		fmt.Printf("%s: %s\n", k, m.Attributes.Remote[k])
+	})
+}
+
+// runInspectDigest displays the digest of the SIF image at path, the
+// sha256 of the whole file in the sha256.<hex> form used by the library.
+func runInspectDigest(path string) {
+	if !defaultToLabels() || labels || allData || jsonfmt || sifLayers || inspectRemote || AppName != "" {
+		sylog.Fatalf("--digest cannot be combined with other inspect options")
+	}
+
+	img, err := image.Init(path, false)
+	if err != nil {
+		sylog.Fatalf("Failed to open image %s: %s", path, err)
+	}
+	img.File.Close()
+	if img.Type != image.SIF {
+		sylog.Fatalf("Only SIF images have a digest, %s is not a SIF image", path)
+	}
+
+	d, err := scslibclient.ImageHash(img.Path)
+	if err != nil {
+		sylog.Fatalf("Failed to compute digest of %s: %s", path, err)
+	}
+	fmt.Println(d)
+}
+
+// runInspectSIFLayers displays the partitions of the SIF image at path, as
+// a table or in JSON format with --json.
+func runInspectSIFLayers(path string) {
+	if !defaultToLabels() || labels || allData || showDigest || inspectRemote || AppName != "" {
+		sylog.Fatalf("--sif-layers can only be combined with --json")
+	}
+
+	img, err := image.Init(path, false)
+	if err != nil {
+		sylog.Fatalf("Failed to open image %s: %s", path, err)
+	}
+	img.File.Close()
+	if img.Type != image.SIF {
+		sylog.Fatalf("Only SIF images have partitions, %s is not a SIF image", path)
+	}
+
+	layers, err := singularity.GetSIFLayers(img.Path)
+	if err != nil {
+		sylog.Fatalf("Failed to get partitions of %s: %s", path, err)
+	}
+
+	if jsonfmt {
+		jsonObj, err := json.MarshalIndent(inspect.NewSIFLayers(layers), "", "\t")
+		if err != nil {
+			sylog.Fatalf("Could not format inspected data as JSON: %s", err)
+		}
+		fmt.Println(string(jsonObj))
+		return
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 4, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPARTITION\tTYPE\tFILESYSTEM\tCOMPRESSION\tSIZE")
+	for _, l := range layers {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", l.ID, l.Partition, l.Type, orDash(l.Filesystem), orDash(l.Compression), units.BytesSize(float64(l.Size)))
+	}
+	tw.Flush()
+}
+
+// orDash returns s, or "-" if s is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
 }
 
 // InspectCmd represents the 'inspect' command.
@@ -627,6 +949,21 @@ var InspectCmd = &cobra.Command{
 	Example: docs.InspectExample,
 
 	Run: func(cmd *cobra.Command, args []string) {
+		if showDigest {
+			runInspectDigest(args[0])
+			return
+		}
+
+		if sifLayers {
+			runInspectSIFLayers(args[0])
+			return
+		}
+
+		if inspectRemote {
+			runInspectRemote(cmd, args[0])
+			return
+		}
+
 		img, err := image.Init(args[0], false)
 		if err != nil {
 			sylog.Fatalf("Failed to open image %s: %s", args[0], err)
@@ -682,6 +1019,13 @@ var InspectCmd = &cobra.Command{
 			inspectCmd.addEnvironmentCommand()
 		}
 
+		if healthcheck || allData {
+			if AppName == "" {
+				sylog.Debugf("Inspection of healthcheck selected.")
+				inspectCmd.addHealthcheckCommand()
+			}
+		}
+
 		if listApps || allData {
 			sylog.Debugf("Listing all apps in container")
 		}
@@ -697,6 +1041,14 @@ var InspectCmd = &cobra.Command{
 			}
 		}
 
+		if jsonfmt && (environment || allData) {
+			resolveEnvironment(inspectData)
+		}
+
+		if jsonfmt && (runscript || allData) && AppName == "" {
+			inspectData.Attributes.RunscriptProcess = getRunscriptProcess(img, inspectData.Attributes.Runscript)
+		}
+
 		// Output the inspection results (use JSON if requested).
 		if jsonfmt {
 			jsonObj, err := json.MarshalIndent(inspectData, "", "\t")
@@ -741,6 +1093,13 @@ var InspectCmd = &cobra.Command{
 					fmt.Printf("=== %s ===\n%s\n\n", k, appAttr.Environment[k])
 				})
 			}
+			if hc := inspectData.Data.Attributes.Healthcheck; hc != nil {
+				fmt.Printf("Test: %q\n", hc.Test)
+				fmt.Printf("Interval: %s\n", hc.Interval)
+				fmt.Printf("Timeout: %s\n", hc.Timeout)
+				fmt.Printf("StartPeriod: %s\n", hc.StartPeriod)
+				fmt.Printf("Retries: %d\n", hc.Retries)
+			}
 			if len(inspectData.Data.Attributes.Labels) > 0 {
 				printSortedMap(inspectData.Data.Attributes.Labels, func(k string) {
 					fmt.Printf("%s: %s\n", k, inspectData.Data.Attributes.Labels[k])
@@ -0,0 +1,75 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sylabs/singularity/internal/pkg/util/shell"
+	"github.com/sylabs/singularity/pkg/inspect"
+)
+
+func TestParseOCIRunscript(t *testing.T) {
+	ociRunscript := func(entrypoint, cmd []string) string {
+		return "#!/bin/sh\n" +
+			"OCI_ENTRYPOINT='" + shell.EscapeSingleQuotes(shell.ArgsQuoted(entrypoint)) + "'\n" +
+			"OCI_CMD='" + shell.EscapeSingleQuotes(shell.ArgsQuoted(cmd)) + "'\n" +
+			"CMDLINE_ARGS=\"\"\n" +
+			"eval \"set ${SINGULARITY_OCI_RUN}\"\n" +
+			"exec \"$@\"\n"
+	}
+
+	tests := []struct {
+		name      string
+		runscript string
+		want      *inspect.Process
+	}{
+		{
+			name:      "entrypoint and cmd",
+			runscript: ociRunscript([]string{"/docker-entrypoint.sh"}, []string{"nginx", "-g", "daemon off;"}),
+			want: &inspect.Process{
+				Entrypoint: []string{"/docker-entrypoint.sh"},
+				Cmd:        []string{"nginx", "-g", "daemon off;"},
+				Args:       []string{"/docker-entrypoint.sh", "nginx", "-g", "daemon off;"},
+			},
+		},
+		{
+			name:      "cmd only",
+			runscript: ociRunscript(nil, []string{"/bin/sh", "-c", `echo "it's $HOME" \ done`}),
+			want: &inspect.Process{
+				Cmd:  []string{"/bin/sh", "-c", `echo "it's $HOME" \ done`},
+				Args: []string{"/bin/sh", "-c", `echo "it's $HOME" \ done`},
+			},
+		},
+		{
+			name:      "empty",
+			runscript: "#!/bin/sh\nOCI_ENTRYPOINT=''\nOCI_CMD=''\n",
+			want: &inspect.Process{
+				Args: []string{},
+			},
+		},
+		{
+			name:      "definition file runscript",
+			runscript: "#!/bin/sh\n\necho hello\n",
+			want:      nil,
+		},
+		{
+			name:      "invalid script",
+			runscript: "#!/bin/sh\nif then\n",
+			want:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseOCIRunscript(tt.runscript)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOCIRunscript() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -6,9 +6,15 @@
 package cli
 
 import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/docs"
 	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/internal/pkg/instance"
 	"github.com/sylabs/singularity/pkg/cmdline"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
@@ -16,6 +22,8 @@ import (
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterFlagForCmd(&instanceStartPidFileFlag, instanceStartCmd)
+		cmdManager.RegisterFlagForCmd(&instanceStartWaitReadyFlag, instanceStartCmd)
+		cmdManager.RegisterFlagForCmd(&instanceStartRestartFlag, instanceStartCmd)
 	})
 }
 
@@ -31,6 +39,31 @@ var instanceStartPidFileFlag = cmdline.Flag{
 	EnvKeys:      []string{"PID_FILE"},
 }
 
+// --wait-ready
+var instanceStartWaitReady bool
+
+var instanceStartWaitReadyFlag = cmdline.Flag{
+	ID:           "instanceStartWaitReadyFlag",
+	Value:        &instanceStartWaitReady,
+	DefaultValue: false,
+	Name:         "wait-ready",
+	Usage:        "wait until the health check inherited from a Docker image HEALTHCHECK succeeds",
+	EnvKeys:      []string{"WAIT_READY"},
+}
+
+// --restart
+var instanceStartRestart string
+
+var instanceStartRestartFlag = cmdline.Flag{
+	ID:           "instanceStartRestartFlag",
+	Value:        &instanceStartRestart,
+	DefaultValue: "no",
+	Name:         "restart",
+	Usage:        "restart policy (no, on-failure[:max]), with on-failure the command stays in the foreground to restart the instance when it exits",
+	EnvKeys:      []string{"RESTART"},
+	Tag:          "<policy>",
+}
+
 // singularity instance start
 var instanceStartCmd = &cobra.Command{
 	Args:                  cobra.MinimumNArgs(2),
@@ -40,6 +73,16 @@ var instanceStartCmd = &cobra.Command{
 		image := args[0]
 		name := args[1]
 
+		policy, err := instance.ParseRestartPolicy(instanceStartRestart)
+		if err != nil {
+			sylog.Fatalf("While parsing --restart: %s", err)
+		}
+		if policy != nil && os.Getenv(instance.SupervisedEnv) == "" {
+			if err := instance.SetSubreaper(); err != nil {
+				sylog.Warningf("Could not supervise instance exit status, any exit will restart it: %s", err)
+			}
+		}
+
 		a := append([]string{"/.singularity.d/actions/start"}, args[2:]...)
 		setVM(cmd)
 		if VM {
@@ -54,6 +97,38 @@ var instanceStartCmd = &cobra.Command{
 				sylog.Warningf("Failed to write pid file: %v", err)
 			}
 		}
+
+		if instanceStartWaitReady {
+			hc, err := singularity.GetImageHealthcheck(image)
+			if err != nil {
+				sylog.Fatalf("Could not get image health check: %s", err)
+			} else if hc == nil {
+				sylog.Warningf("No health check found in %s, not waiting for instance readiness", image)
+			} else {
+				sylog.Infof("Waiting for instance %s to be ready", name)
+				if err := singularity.WaitInstanceReady(name, hc); err != nil {
+					sylog.Fatalf("Instance %s is not ready: %s", name, err)
+				}
+				sylog.Infof("Instance %s is ready", name)
+			}
+		}
+
+		// restarted instances are started by the supervisor
+		if policy == nil || os.Getenv(instance.SupervisedEnv) != "" {
+			return
+		}
+
+		sylog.Infof("Supervising instance %s with restart policy %s", name, instanceStartRestart)
+		restart := func() error {
+			c := exec.Command(filepath.Join(buildcfg.BINDIR, "singularity"), os.Args[1:]...)
+			c.Stdout = os.Stdout
+			c.Stderr = os.Stderr
+			c.Env = append(os.Environ(), instance.SupervisedEnv+"=1")
+			return c.Run()
+		}
+		if err := instance.Supervise(name, policy, restart); err != nil {
+			sylog.Fatalf("%s", err)
+		}
 	},
 
 	Use:     docs.InstanceStartUse,
@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/docs"
@@ -23,6 +24,7 @@ var (
 	keySearchLongList   bool   // -l option for long-list
 	keyNewpairBitLength int    // -b option for bit length
 	keyGlobalPubKey     bool   // -g option to manage global public keys
+	keyringDir          string // --keyring option to use a specific keyring directory
 )
 
 // -u|--url
@@ -56,6 +58,15 @@ var keyNewpairBitLengthFlag = cmdline.Flag{
 	Usage:        "specify key bit length",
 }
 
+// --bits
+var keyNewpairBitsFlag = cmdline.Flag{
+	ID:           "keyNewpairBitsFlag",
+	Value:        &keyNewpairBitLength,
+	DefaultValue: 4096,
+	Name:         "bits",
+	Usage:        "specify key bit length, same as --bit-length",
+}
+
 // -g|--global
 var keyGlobalPubKeyFlag = cmdline.Flag{
 	ID:           "keyGlobalPubKeyFlag",
@@ -66,6 +77,16 @@ var keyGlobalPubKeyFlag = cmdline.Flag{
 	Usage:        "manage global public keys (import/pull/remove are restricted to root user or unprivileged installation only)",
 }
 
+// --keyring
+var keyringDirFlag = cmdline.Flag{
+	ID:           "keyringDirFlag",
+	Value:        &keyringDir,
+	DefaultValue: "",
+	Name:         "keyring",
+	Usage:        "use the keyring located in this directory instead of the local keyring, takes precedence over SINGULARITY_SYPGPDIR",
+	Tag:          "<dir>",
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(KeyCmd)
@@ -76,6 +97,8 @@ func init() {
 		cmdManager.RegisterFlagForCmd(keyNewPairCommentFlag, KeyNewPairCmd)
 		cmdManager.RegisterFlagForCmd(keyNewPairPasswordFlag, KeyNewPairCmd)
 		cmdManager.RegisterFlagForCmd(keyNewPairPushFlag, KeyNewPairCmd)
+		cmdManager.RegisterFlagForCmd(keyNewPairPassphraseStdinFlag, KeyNewPairCmd)
+		cmdManager.RegisterFlagForCmd(keyNewPairBatchFlag, KeyNewPairCmd)
 
 		cmdManager.RegisterSubCmd(KeyCmd, KeyListCmd)
 		cmdManager.RegisterSubCmd(KeyCmd, KeySearchCmd)
@@ -87,17 +110,44 @@ func init() {
 
 		cmdManager.RegisterFlagForCmd(&keyServerURIFlag, KeySearchCmd, KeyPushCmd, KeyPullCmd)
 		cmdManager.RegisterFlagForCmd(&keySearchLongListFlag, KeySearchCmd)
+		cmdManager.RegisterFlagForCmd(&keyPullFingerprintFlag, KeyPullCmd)
 		cmdManager.RegisterFlagForCmd(&keyNewpairBitLengthFlag, KeyNewPairCmd)
+		cmdManager.RegisterFlagForCmd(&keyNewpairBitsFlag, KeyNewPairCmd)
 		cmdManager.RegisterFlagForCmd(&keyImportWithNewPasswordFlag, KeyImportCmd)
+		cmdManager.RegisterFlagForCmd(&keyImportForceFlag, KeyImportCmd)
 
 		cmdManager.RegisterFlagForCmd(
 			&keyGlobalPubKeyFlag,
 			KeyImportCmd, KeyExportCmd, KeyListCmd, KeyPullCmd, KeyPushCmd, KeyRemoveCmd,
 		)
+		cmdManager.RegisterFlagForCmd(
+			&keyringDirFlag,
+			KeyNewPairCmd, KeyImportCmd, KeyExportCmd, KeyListCmd, KeyPullCmd, KeyPushCmd, KeyRemoveCmd,
+		)
 	})
 }
 
+// checkKeyring makes the local keyring used by cmd the one located in
+// the --keyring directory, if set. The keyring location is passed down
+// with SINGULARITY_SYPGPDIR, which is overridden for this process only.
+func checkKeyring(cmd *cobra.Command, args []string) {
+	if keyringDir == "" {
+		return
+	}
+	if keyGlobalPubKey {
+		sylog.Fatalf("--keyring and --global options are mutually exclusive")
+	}
+	dir, err := filepath.Abs(keyringDir)
+	if err != nil {
+		sylog.Fatalf("While resolving keyring directory %s: %s", keyringDir, err)
+	}
+	sylog.Debugf("Using keyring directory %s", dir)
+	os.Setenv("SINGULARITY_SYPGPDIR", dir)
+}
+
 func checkGlobal(cmd *cobra.Command, args []string) {
+	checkKeyring(cmd, args)
+
 	if !keyGlobalPubKey || os.Geteuid() == 0 || buildcfg.SINGULARITY_SUID_INSTALL == 0 {
 		return
 	}
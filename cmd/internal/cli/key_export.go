@@ -7,6 +7,7 @@
 package cli
 
 import (
+	"errors"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -20,6 +21,7 @@ import (
 var (
 	secretExport bool
 	armor        bool
+	exportForce  bool
 )
 
 // -s|--secret
@@ -42,18 +44,30 @@ var keyExportArmorFlag = cmdline.Flag{
 	Usage:        "ascii armored format",
 }
 
+// -F|--force
+var keyExportForceFlag = cmdline.Flag{
+	ID:           "keyExportForceFlag",
+	Value:        &exportForce,
+	DefaultValue: false,
+	Name:         "force",
+	ShortHand:    "F",
+	Usage:        "overwrite the output file if it exists",
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterFlagForCmd(&keyExportSecretFlag, KeyExportCmd)
 		cmdManager.RegisterFlagForCmd(&keyExportArmorFlag, KeyExportCmd)
+		cmdManager.RegisterFlagForCmd(&keyExportForceFlag, KeyExportCmd)
 	})
 }
 
 // KeyExportCmd is `singularity key export` and exports a public or secret
 // key from local keyring.
 var KeyExportCmd = &cobra.Command{
-	Args:                  cobra.ExactArgs(1),
+	Args:                  cobra.RangeArgs(1, 2),
 	DisableFlagsInUseLine: true,
+	PreRun:                checkKeyring,
 	Run:                   exportRun,
 
 	Use:     docs.KeyExportUse,
@@ -66,23 +80,35 @@ func exportRun(cmd *cobra.Command, args []string) {
 	var opts []sypgp.HandleOpt
 	path := ""
 
+	// the key is selected interactively without fingerprint
+	fingerprint := ""
+	output := args[0]
+	if len(args) == 2 {
+		fp, err := sypgp.NormalizeFingerprint(args[0])
+		if err != nil {
+			sylog.Fatalf("%s", err)
+		}
+		fingerprint = fp
+		output = args[1]
+	}
+
 	if keyGlobalPubKey {
 		path = buildcfg.SINGULARITY_CONFDIR
 		opts = append(opts, sypgp.GlobalHandleOpt())
 	}
 
 	keyring := sypgp.NewHandle(path, opts...)
+	var err error
 	if secretExport {
-		err := keyring.ExportPrivateKey(args[0], armor)
-		if err != nil {
-			sylog.Errorf("key export command failed: %s", err)
-			os.Exit(10)
-		}
+		err = keyring.ExportPrivateKey(output, fingerprint, armor, exportForce)
 	} else {
-		err := keyring.ExportPubKey(args[0], armor)
-		if err != nil {
-			sylog.Errorf("key export command failed: %s", err)
-			os.Exit(10)
-		}
+		err = keyring.ExportPubKey(output, fingerprint, armor, exportForce)
+	}
+	if errors.Is(err, os.ErrExist) {
+		sylog.Errorf("key export command failed: %s, use --force to overwrite it", err)
+		os.Exit(10)
+	} else if err != nil {
+		sylog.Errorf("key export command failed: %s", err)
+		os.Exit(10)
 	}
 }
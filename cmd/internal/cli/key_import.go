@@ -39,6 +39,16 @@ var (
 		Name:         "new-password",
 		Usage:        `set a new password to the private key`,
 	}
+
+	keyImportForce     bool
+	keyImportForceFlag = cmdline.Flag{
+		ID:           "keyImportForceFlag",
+		Value:        &keyImportForce,
+		DefaultValue: false,
+		Name:         "force",
+		ShortHand:    "F",
+		Usage:        `replace keys already present in the keyring`,
+	}
 )
 
 func importRun(cmd *cobra.Command, args []string) {
@@ -51,7 +61,7 @@ func importRun(cmd *cobra.Command, args []string) {
 	}
 
 	keyring := sypgp.NewHandle(path, opts...)
-	if err := keyring.ImportKey(args[0], keyImportWithNewPassword); err != nil {
+	if err := keyring.ImportKey(args[0], keyImportWithNewPassword, keyImportForce); err != nil {
 		sylog.Errorf("key import command failed: %s", err)
 		os.Exit(2)
 	}
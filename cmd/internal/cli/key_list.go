@@ -40,6 +40,7 @@ func init() {
 var KeyListCmd = &cobra.Command{
 	Args:                  cobra.ExactArgs(0),
 	DisableFlagsInUseLine: true,
+	PreRun:                checkKeyring,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := doKeyListCmd(secret); err != nil {
 			sylog.Fatalf("While listing keys: %s", err)
@@ -9,7 +9,11 @@ package cli
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/mail"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/docs"
@@ -71,10 +75,29 @@ var (
 		Usage:        "specify to push the public key to the remote keystore (default true)",
 	}
 
+	keyNewPairPassphraseStdin     bool
+	keyNewPairPassphraseStdinFlag = &cmdline.Flag{
+		ID:           "KeyNewPairPassphraseStdinFlag",
+		Value:        &keyNewPairPassphraseStdin,
+		DefaultValue: false,
+		Name:         "passphrase-stdin",
+		Usage:        "take key passphrase from standard input (requires --batch)",
+	}
+
+	keyNewPairBatch     bool
+	keyNewPairBatchFlag = &cmdline.Flag{
+		ID:           "KeyNewPairBatchFlag",
+		Value:        &keyNewPairBatch,
+		DefaultValue: false,
+		Name:         "batch",
+		Usage:        "generate the key without prompting, --name and --email are required and the key isn't pushed unless --push is set",
+	}
+
 	// KeyNewPairCmd is 'singularity key newpair' and generate a new OpenPGP key pair
 	KeyNewPairCmd = &cobra.Command{
 		Args:                  cobra.ExactArgs(0),
 		DisableFlagsInUseLine: true,
+		PreRun:                checkKeyring,
 		Run:                   runNewPairCmd,
 		Use:                   docs.KeyNewPairUse,
 		Short:                 docs.KeyNewPairShort,
@@ -126,6 +149,13 @@ func runNewPairCmd(cmd *cobra.Command, args []string) {
 
 // collectInput collects passed flags, for missed parameters will ask user input.
 func collectInput(cmd *cobra.Command) (*keyNewPairOptions, error) {
+	if keyNewPairBatch {
+		return collectBatchInput(cmd, os.Stdin)
+	}
+	if keyNewPairPassphraseStdin {
+		return nil, errors.New("--passphrase-stdin requires --batch")
+	}
+
 	var genOpts keyNewPairOptions
 
 	// check flags
@@ -198,3 +228,56 @@ func collectInput(cmd *cobra.Command) (*keyNewPairOptions, error) {
 
 	return &genOpts, nil
 }
+
+// collectBatchInput collects passed flags without asking user input, the
+// passphrase is read from r with --passphrase-stdin.
+func collectBatchInput(cmd *cobra.Command, r io.Reader) (*keyNewPairOptions, error) {
+	var genOpts keyNewPairOptions
+
+	genOpts.Name = strings.TrimSpace(keyNewPairName)
+	if genOpts.Name == "" {
+		return nil, errors.New("--name is required with --batch")
+	}
+
+	if keyNewPairEmail == "" {
+		return nil, errors.New("--email is required with --batch")
+	}
+	addr, err := mail.ParseAddress(keyNewPairEmail)
+	if err != nil || addr.Address != keyNewPairEmail {
+		return nil, fmt.Errorf("invalid email address %q", keyNewPairEmail)
+	}
+	genOpts.Email = keyNewPairEmail
+
+	genOpts.Comment = keyNewPairComment
+	if strings.ContainsAny(genOpts.Name+genOpts.Comment, "()<>") {
+		return nil, errors.New("name and comment can't contain parentheses or angle brackets")
+	}
+
+	if keyNewpairBitLength < 1024 {
+		return nil, fmt.Errorf("invalid key bit length %d: must be at least 1024", keyNewpairBitLength)
+	}
+
+	switch {
+	case keyNewPairPassphraseStdin && cmd.Flags().Changed(keyNewPairPasswordFlag.Name):
+		return nil, errors.New("--password and --passphrase-stdin are mutually exclusive")
+	case keyNewPairPassphraseStdin:
+		p, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("while reading passphrase from stdin: %s", err)
+		}
+		genOpts.Password = strings.TrimSuffix(string(p), "\n")
+		genOpts.Password = strings.TrimSuffix(genOpts.Password, "\r")
+		if genOpts.Password == "" {
+			return nil, errors.New("empty passphrase read from stdin")
+		}
+	default:
+		genOpts.Password = keyNewPairPassword
+	}
+	if genOpts.Password == "" {
+		sylog.Warningf("No passphrase set, the private key is not encrypted")
+	}
+
+	genOpts.PushToKeyStore = keyNewPairPush
+
+	return &genOpts, nil
+}
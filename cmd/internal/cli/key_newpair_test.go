@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/spf13/cobra"
@@ -137,3 +138,136 @@ func TestCollectInput(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectBatchInput(t *testing.T) {
+	defer func() {
+		keyNewPairName = ""
+		keyNewPairEmail = ""
+		keyNewPairComment = ""
+		keyNewPairPassword = ""
+		keyNewPairPush = false
+		keyNewPairPassphraseStdin = false
+		keyNewpairBitLength = 4096
+	}()
+
+	tests := []struct {
+		Name            string
+		KeyName         string
+		Email           string
+		Password        string
+		PassphraseStdin bool
+		Stdin           string
+		Bits            int
+		Options         *keyNewPairOptions
+		Error           string
+	}{
+		{
+			Name:     "Password flag",
+			KeyName:  testName,
+			Email:    testEmail,
+			Password: testPassword,
+			Bits:     4096,
+			Options: &keyNewPairOptions{
+				GenKeyPairOptions: sypgp.GenKeyPairOptions{
+					Name:     testName,
+					Email:    testEmail,
+					Comment:  testComment,
+					Password: testPassword,
+				},
+			},
+		},
+		{
+			Name:            "Passphrase stdin",
+			KeyName:         testName,
+			Email:           testEmail,
+			PassphraseStdin: true,
+			Stdin:           testPassword + "\n",
+			Bits:            4096,
+			Options: &keyNewPairOptions{
+				GenKeyPairOptions: sypgp.GenKeyPairOptions{
+					Name:     testName,
+					Email:    testEmail,
+					Comment:  testComment,
+					Password: testPassword,
+				},
+			},
+		},
+		{
+			Name:            "Empty passphrase stdin",
+			KeyName:         testName,
+			Email:           testEmail,
+			PassphraseStdin: true,
+			Stdin:           "\n",
+			Bits:            4096,
+			Error:           "empty passphrase",
+		},
+		{
+			Name:            "Password and passphrase stdin",
+			KeyName:         testName,
+			Email:           testEmail,
+			Password:        testPassword,
+			PassphraseStdin: true,
+			Stdin:           testPassword,
+			Bits:            4096,
+			Error:           "mutually exclusive",
+		},
+		{
+			Name:  "Missing name",
+			Email: testEmail,
+			Bits:  4096,
+			Error: "--name is required",
+		},
+		{
+			Name:    "Missing email",
+			KeyName: testName,
+			Bits:    4096,
+			Error:   "--email is required",
+		},
+		{
+			Name:    "Invalid email",
+			KeyName: testName,
+			Email:   "John <john@sylabs.io>",
+			Bits:    4096,
+			Error:   "invalid email address",
+		},
+		{
+			Name:    "Invalid name",
+			KeyName: "John (CI)",
+			Email:   testEmail,
+			Bits:    4096,
+			Error:   "can't contain parentheses",
+		},
+		{
+			Name:    "Invalid bit length",
+			KeyName: testName,
+			Email:   testEmail,
+			Bits:    512,
+			Error:   "invalid key bit length",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			c := &cobra.Command{}
+			if tt.Password != "" {
+				c.Flags().AddFlag(&pflag.Flag{Name: keyNewPairPasswordFlag.Name, Changed: true})
+			}
+
+			keyNewPairName = tt.KeyName
+			keyNewPairEmail = tt.Email
+			keyNewPairComment = testComment
+			keyNewPairPassword = tt.Password
+			keyNewPairPassphraseStdin = tt.PassphraseStdin
+			keyNewPairPush = false
+			keyNewpairBitLength = tt.Bits
+
+			o, err := collectBatchInput(c, strings.NewReader(tt.Stdin))
+			if tt.Error != "" {
+				assert.ErrorContains(t, err, tt.Error)
+			} else {
+				assert.NilError(t, err)
+			}
+			assert.DeepEqual(t, tt.Options, o)
+		})
+	}
+}
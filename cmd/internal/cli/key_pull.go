@@ -11,15 +11,29 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/spf13/cobra"
 	"github.com/sylabs/scs-key-client/client"
 	"github.com/sylabs/singularity/docs"
 	"github.com/sylabs/singularity/internal/pkg/buildcfg"
 	"github.com/sylabs/singularity/internal/pkg/remote/endpoint"
+	"github.com/sylabs/singularity/pkg/cmdline"
 	"github.com/sylabs/singularity/pkg/sylog"
 	"github.com/sylabs/singularity/pkg/sypgp"
 )
 
+var keyPullFingerprint string
+
+// --fingerprint
+var keyPullFingerprintFlag = cmdline.Flag{
+	ID:           "keyPullFingerprintFlag",
+	Value:        &keyPullFingerprint,
+	DefaultValue: "",
+	Name:         "fingerprint",
+	Usage:        "fail without importing the key if its fingerprint doesn't match this one (40 hexadecimal characters)",
+	Tag:          "<fingerprint>",
+}
+
 // KeyPullCmd is `singularity key pull' and fetches public keys from a key server
 var KeyPullCmd = &cobra.Command{
 	PreRun:                checkGlobal,
@@ -55,6 +69,19 @@ func doKeyPullCmd(ctx context.Context, fingerprint string, co ...client.Option)
 		mode = os.FileMode(0o644)
 	}
 
+	// a key pulled by its full fingerprint must match it, whatever the
+	// keyserver returns
+	var expected string
+	if keyPullFingerprint != "" {
+		fp, err := sypgp.NormalizeFingerprint(keyPullFingerprint)
+		if err != nil {
+			return err
+		}
+		expected = fp
+	} else if fp, err := sypgp.NormalizeFingerprint(fingerprint); err == nil {
+		expected = fp
+	}
+
 	keyring := sypgp.NewHandle(path, opts...)
 
 	// get matching keyring
@@ -63,6 +90,14 @@ func doKeyPullCmd(ctx context.Context, fingerprint string, co ...client.Option)
 		return fmt.Errorf("unable to pull key from server: %v", err)
 	}
 
+	fmt.Printf("Retrieved key(s):\n")
+	for i, e := range el {
+		sypgp.PrintEntity(i, e)
+	}
+	if err := checkPulledKeys(el, expected); err != nil {
+		return err
+	}
+
 	elstore, err := keyring.LoadPubKeyring()
 	if err != nil {
 		return err
@@ -95,3 +130,17 @@ func doKeyPullCmd(ctx context.Context, fingerprint string, co ...client.Option)
 
 	return nil
 }
+
+// checkPulledKeys returns an error if a key of el doesn't have the expected
+// fingerprint, as returned by sypgp.NormalizeFingerprint, if not empty.
+func checkPulledKeys(el openpgp.EntityList, expected string) error {
+	if expected == "" {
+		return nil
+	}
+	for _, e := range el {
+		if fp := fmt.Sprintf("%X", e.PrimaryKey.Fingerprint); fp != expected {
+			return fmt.Errorf("key fingerprint %s doesn't match the expected fingerprint %s, not importing", fp, expected)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+func TestCheckPulledKeys(t *testing.T) {
+	e, err := openpgp.NewEntity("test", "", "test@example.com", nil)
+	if err != nil {
+		t.Fatalf("while generating key: %s", err)
+	}
+	fp := fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)
+
+	tests := []struct {
+		name     string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name: "NoFingerprint",
+		},
+		{
+			name:     "Match",
+			expected: fp,
+		},
+		{
+			name:     "Mismatch",
+			expected: "0000000000000000000000000000000000000000",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPulledKeys(openpgp.EntityList{e}, tt.expected)
+			if tt.wantErr && err == nil {
+				t.Errorf("expected an error")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
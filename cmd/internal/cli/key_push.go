@@ -25,6 +25,7 @@ import (
 var KeyPushCmd = &cobra.Command{
 	Args:                  cobra.ExactArgs(1),
 	DisableFlagsInUseLine: true,
+	PreRun:                checkKeyring,
 	Run: func(cmd *cobra.Command, args []string) {
 		co, err := getKeyserverClientOpts(keyServerURI, endpoint.KeyserverPushOp)
 		if err != nil {
@@ -80,7 +81,11 @@ func doKeyPushCmd(ctx context.Context, fingerprint string, co ...client.Option)
 		return err
 	}
 
-	fmt.Printf("public key `%v' pushed to server successfully\n", fingerprint)
+	server := keyServerURI
+	if server == "" {
+		server = "the default key server"
+	}
+	fmt.Printf("public key `%v' pushed to %s successfully\n", fingerprint, server)
 
 	return nil
 }
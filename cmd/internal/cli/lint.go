@@ -0,0 +1,88 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/pkg/build/types/parser"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterCmd(lintCmd)
+		cmdManager.RegisterFlagForCmd(&lintWarningsFlag, lintCmd)
+	})
+}
+
+// -w|--warnings
+var (
+	lintWarnings     string
+	lintWarningsFlag = cmdline.Flag{
+		ID:           "lintWarningsFlag",
+		Value:        &lintWarnings,
+		DefaultValue: "report",
+		Name:         "warnings",
+		ShortHand:    "w",
+		Usage:        "how to handle warnings: report them, ignore them, or treat them as errors (report|ignore|error)",
+		EnvKeys:      []string{"LINT_WARNINGS"},
+	}
+)
+
+var lintCmd = &cobra.Command{
+	Use:                   docs.LintUse,
+	Short:                 docs.LintShort,
+	Long:                  docs.LintLong,
+	Example:               docs.LintExample,
+	Args:                  cobra.MinimumNArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		switch lintWarnings {
+		case "report", "ignore", "error":
+		default:
+			sylog.Fatalf("Invalid --warnings value %q, must be one of report, ignore or error", lintWarnings)
+		}
+
+		var errors, warnings int
+		for _, path := range args {
+			raw, err := ioutil.ReadFile(path)
+			if err != nil {
+				sylog.Fatalf("While reading definition file: %v", err)
+			}
+			for _, issue := range parser.Lint(raw, path) {
+				if issue.Severity == parser.LintWarning {
+					if lintWarnings == "ignore" {
+						continue
+					}
+					warnings++
+				} else {
+					errors++
+				}
+				if issue.Line > 0 {
+					fmt.Printf("%s:%d: %s: %s\n", path, issue.Line, issue.Severity, issue.Message)
+				} else {
+					fmt.Printf("%s: %s: %s\n", path, issue.Severity, issue.Message)
+				}
+			}
+		}
+
+		switch {
+		case errors > 0:
+			sylog.Fatalf("Found %d error(s) and %d warning(s)", errors, warnings)
+		case warnings > 0 && lintWarnings == "error":
+			sylog.Fatalf("Found %d warning(s), treated as errors", warnings)
+		case warnings > 0:
+			sylog.Warningf("Found %d warning(s)", warnings)
+		default:
+			sylog.Infof("No issue found")
+		}
+	},
+}
@@ -12,9 +12,14 @@ func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(OverlayCmd)
 		cmdManager.RegisterSubCmd(OverlayCmd, OverlayCreateCmd)
+		cmdManager.RegisterSubCmd(OverlayCmd, OverlayCommitCmd)
 
 		cmdManager.RegisterFlagForCmd(&overlaySizeFlag, OverlayCreateCmd)
 		cmdManager.RegisterFlagForCmd(&overlayCreateDirFlag, OverlayCreateCmd)
+
+		cmdManager.RegisterFlagForCmd(&overlayCommitOutputFlag, OverlayCommitCmd)
+		cmdManager.RegisterFlagForCmd(&commonForceFlag, OverlayCommitCmd)
+		cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, OverlayCommitCmd)
 	})
 }
 
@@ -0,0 +1,87 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/pkg/build"
+	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+var overlayCommitOutput string
+
+// -o|--output
+var overlayCommitOutputFlag = cmdline.Flag{
+	ID:           "overlayCommitOutputFlag",
+	Value:        &overlayCommitOutput,
+	DefaultValue: "",
+	Name:         "output",
+	ShortHand:    "o",
+	Usage:        "path of the SIF image to create",
+	Tag:          "<path>",
+	Required:     true,
+}
+
+// OverlayCommitCmd is the 'overlay commit' command that folds a writable overlay into a new SIF image.
+var OverlayCommitCmd = &cobra.Command{
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := overlayCommit(cmd.Context(), args[0], args[1], overlayCommitOutput); err != nil {
+			sylog.Fatalf("While committing overlay: %s", err)
+		}
+		sylog.Infof("Created %s", overlayCommitOutput)
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.OverlayCommitUse,
+	Short:   docs.OverlayCommitShort,
+	Long:    docs.OverlayCommitLong,
+	Example: docs.OverlayCommitExample,
+}
+
+// overlayCommit builds the SIF image dst from the root filesystem of the
+// image at imgPath with the changes of the writable overlay at overlayPath
+// applied.
+func overlayCommit(ctx context.Context, imgPath, overlayPath, dst string) error {
+	if _, err := os.Stat(dst); err == nil && !forceOverwrite {
+		return fmt.Errorf("image file %s already exists, use --force to overwrite it", dst)
+	}
+	if _, err := os.Stat(overlayPath); err != nil {
+		return fmt.Errorf("while checking overlay %s: %s", overlayPath, err)
+	}
+
+	def, err := types.NewDefinitionFromURI("localimage://" + imgPath)
+	if err != nil {
+		return fmt.Errorf("while creating definition for %s: %s", imgPath, err)
+	}
+
+	b, err := build.New(
+		[]types.Definition{def},
+		build.Config{
+			Dest:   dst,
+			Format: "sif",
+			Opts: types.Options{
+				ImgCache:      getCacheHandle(cache.Config{}),
+				TmpDir:        tmpDir,
+				NoTest:        true,
+				Force:         forceOverwrite,
+				CommitOverlay: overlayPath,
+			},
+		})
+	if err != nil {
+		return fmt.Errorf("unable to create build: %s", err)
+	}
+
+	return b.Full(ctx)
+}
@@ -146,9 +146,18 @@ func isGlobal(e *openpgp.Entity) bool {
 
 // outputVerify outputs a textual representation of r to stdout.
 func outputVerify(f *sif.FileImage, r integrity.VerifyResult) bool {
-	e := r.Entity()
+	outputSigningEntity(r.Entity())
+	outputVerifiedObjects(r.Verified())
 
-	// Print signing entity info.
+	if err := r.Error(); err != nil {
+		fmt.Printf("\nError encountered during signature verification: %v\n", err)
+	}
+
+	return false
+}
+
+// outputSigningEntity outputs a textual representation of the signing entity e to stdout.
+func outputSigningEntity(e *openpgp.Entity) {
 	if e != nil {
 		prefix := color.New(color.FgYellow).Sprint("[REMOTE]")
 
@@ -168,14 +177,16 @@ func outputVerify(f *sif.FileImage, r integrity.VerifyResult) bool {
 		// Always print fingerprint.
 		fmt.Printf("%-18v Fingerprint: %X\n", prefix, e.PrimaryKey.Fingerprint)
 	}
+}
 
-	// Print table of signed objects.
-	if len(r.Verified()) > 0 {
+// outputVerifiedObjects outputs a table of the verified objects ods to stdout.
+func outputVerifiedObjects(ods []sif.Descriptor) {
+	if len(ods) > 0 {
 		fmt.Printf("Objects verified:\n")
 		fmt.Printf("%-4s|%-8s|%-8s|%s\n", "ID", "GROUP", "LINK", "TYPE")
 		fmt.Print("------------------------------------------------\n")
 	}
-	for _, od := range r.Verified() {
+	for _, od := range ods {
 		group := "NONE"
 		if gid := od.GroupID(); gid != 0 {
 			group = fmt.Sprintf("%d", gid)
@@ -192,12 +203,6 @@ func outputVerify(f *sif.FileImage, r integrity.VerifyResult) bool {
 
 		fmt.Printf("%-4d|%-8s|%-8s|%s\n", od.ID(), group, link, od.DataType())
 	}
-
-	if err := r.Error(); err != nil {
-		fmt.Printf("\nError encountered during signature verification: %v\n", err)
-	}
-
-	return false
 }
 
 type key struct {
@@ -275,6 +280,36 @@ func getJSONCallback(kl *keyList) singularity.VerifyCallback {
 	}
 }
 
+// getDetachedKeyList returns the keyList describing the verification of a detached signature
+// made by e, covering the objects ods.
+func getDetachedKeyList(e *openpgp.Entity, ods []sif.Descriptor) keyList {
+	kl := keyList{Signatures: 1}
+
+	name, fp := "unknown", ""
+	var keyLocal, keyCheck bool
+	if e != nil {
+		if id := primaryIdentity(e); id != nil {
+			name = id.Name
+		}
+		fp = hex.EncodeToString(e.PrimaryKey.Fingerprint[:])
+		keyLocal = isLocal(e)
+		keyCheck = true
+	}
+
+	for _, od := range ods {
+		ke := keyEntity{
+			Partition:   od.DataType().String(),
+			Name:        name,
+			Fingerprint: fp,
+			KeyLocal:    keyLocal,
+			KeyCheck:    keyCheck,
+			DataCheck:   true,
+		}
+		kl.SignerKeys = append(kl.SignerKeys, &key{ke})
+	}
+	return kl
+}
+
 // outputJSON outputs a JSON representation of kl to w.
 func outputJSON(w io.Writer, kl keyList) error {
 	e := json.NewEncoder(w)
@@ -1,5 +1,5 @@
 // Copyright (c) 2020, Control Command Inc. All rights reserved.
-// Copyright (c) 2018-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -7,11 +7,14 @@
 package cli
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 
+	digest "github.com/opencontainers/go-digest"
 	"github.com/spf13/cobra"
+	keyclient "github.com/sylabs/scs-key-client/client"
 	"github.com/sylabs/singularity/docs"
 	"github.com/sylabs/singularity/internal/pkg/cache"
 	"github.com/sylabs/singularity/internal/pkg/client/library"
@@ -23,6 +26,7 @@ import (
 	"github.com/sylabs/singularity/internal/pkg/util/uri"
 	"github.com/sylabs/singularity/pkg/cmdline"
 	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/util/singularityconf"
 )
 
 const (
@@ -52,6 +56,16 @@ var (
 	// pullArch is the architecture for which containers will be pulled from the
 	// SCS library.
 	pullArch string
+	// requireSignedPull when true; deletes a library image after pulling it if
+	// it's not signed by a key from the local keyring.
+	requireSignedPull bool
+	// pullExpectedDigest is the digest the pulled image must match.
+	pullExpectedDigest string
+	// pullFromFile is the path of a file listing the images to pull.
+	pullFromFile string
+	// pullConcurrency is the number of images from pullFromFile pulled
+	// concurrently.
+	pullConcurrency int
 )
 
 // --arch
@@ -112,9 +126,50 @@ var pullAllowUnsignedFlag = cmdline.Flag{
 	DefaultValue: false,
 	Name:         "allow-unsigned",
 	ShortHand:    "U",
-	Usage:        "do not require a signed container",
+	Usage:        "do not require a signed container, override --require-signed",
 	EnvKeys:      []string{"ALLOW_UNSIGNED"},
-	Deprecated:   `pull no longer exits with an error code in case of unsigned image. Now the flag only suppress warning message.`,
+}
+
+// --require-signed
+var pullRequireSignedFlag = cmdline.Flag{
+	ID:           "pullRequireSignedFlag",
+	Value:        &requireSignedPull,
+	DefaultValue: false,
+	Name:         "require-signed",
+	Usage:        "require a library container signed by a key from the local keyring, delete it otherwise",
+	EnvKeys:      []string{"REQUIRE_SIGNED"},
+}
+
+// --expected-digest
+var pullExpectedDigestFlag = cmdline.Flag{
+	ID:           "pullExpectedDigestFlag",
+	Value:        &pullExpectedDigest,
+	DefaultValue: "",
+	Name:         "expected-digest",
+	Usage:        "digest the pulled image must match, e.g. sha256:<hex>. The SIF file digest for library, oras, shub and http(s) images, and the manifest digest for OCI images",
+	EnvKeys:      []string{"EXPECTED_DIGEST"},
+	Tag:          "<digest>",
+}
+
+// --from-file
+var pullFromFileFlag = cmdline.Flag{
+	ID:           "pullFromFileFlag",
+	Value:        &pullFromFile,
+	DefaultValue: "",
+	Name:         "from-file",
+	Usage:        "pull the images listed in a file, one URI per line optionally followed by the output file name",
+	EnvKeys:      []string{"PULL_FROM_FILE"},
+	Tag:          "<file>",
+}
+
+// --concurrency
+var pullConcurrencyFlag = cmdline.Flag{
+	ID:           "pullConcurrencyFlag",
+	Value:        &pullConcurrency,
+	DefaultValue: 1,
+	Name:         "concurrency",
+	Usage:        "number of images pulled concurrently with --from-file, OCI images are converted one at a time",
+	EnvKeys:      []string{"PULL_CONCURRENCY"},
 }
 
 // --allow-unauthenticated
@@ -137,7 +192,9 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&pullLibraryURIFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullNameFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, PullCmd)
+		cmdManager.RegisterFlagForCmd(&commonRegistriesConfFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, PullCmd)
+		cmdManager.RegisterFlagForCmd(&commonCacheDirFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullDisableCacheFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullDirFlag, PullCmd)
 
@@ -148,14 +205,18 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&buildNoCleanupFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullAllowUnsignedFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullAllowUnauthenticatedFlag, PullCmd)
+		cmdManager.RegisterFlagForCmd(&pullRequireSignedFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullArchFlag, PullCmd)
+		cmdManager.RegisterFlagForCmd(&pullExpectedDigestFlag, PullCmd)
+		cmdManager.RegisterFlagForCmd(&pullFromFileFlag, PullCmd)
+		cmdManager.RegisterFlagForCmd(&pullConcurrencyFlag, PullCmd)
 	})
 }
 
 // PullCmd singularity pull
 var PullCmd = &cobra.Command{
 	DisableFlagsInUseLine: true,
-	Args:                  cobra.RangeArgs(1, 2),
+	Args:                  cobra.RangeArgs(0, 2),
 	Run:                   pullRun,
 	Use:                   docs.PullUse,
 	Short:                 docs.PullShort,
@@ -164,15 +225,39 @@ var PullCmd = &cobra.Command{
 }
 
 func pullRun(cmd *cobra.Command, args []string) {
-	ctx := cmd.Context()
-
 	imgCache := getCacheHandle(cache.Config{Disable: disableCache})
 	if imgCache == nil {
 		sylog.Fatalf("Failed to create an image cache handle")
 	}
 
+	// --allow-unsigned only overrides --require-signed, not the
+	// configuration set by the system administrator
+	requireSigned := requireSignedPull && !unauthenticatedPull
+	if singularityconf.GetCurrentConfig().RequireSignedPull {
+		if unauthenticatedPull {
+			sylog.Warningf("Ignoring --allow-unsigned, signed images are required by the system administrator")
+		}
+		requireSigned = true
+	}
+
+	if pullFromFile != "" {
+		if len(args) > 0 {
+			sylog.Fatalf("Conflicting arguments; do not use --from-file with a URI")
+		}
+		if pullImageName != "" || pullExpectedDigest != "" {
+			sylog.Fatalf("--name and --expected-digest can't be used with --from-file")
+		}
+		if err := pullList(cmd, imgCache, pullFromFile, requireSigned); err != nil {
+			sylog.Fatalf("%s", err)
+		}
+		return
+	}
+	if len(args) == 0 {
+		sylog.Fatalf("Missing URI; pull requires a URI or the --from-file flag")
+	}
+
 	pullFrom := args[len(args)-1]
-	transport, ref := uri.Split(pullFrom)
+	_, ref := uri.Split(pullFrom)
 	if ref == "" {
 		sylog.Fatalf("Bad URI %s", pullFrom)
 	}
@@ -181,11 +266,7 @@ func pullRun(cmd *cobra.Command, args []string) {
 	if pullTo == "" {
 		pullTo = args[0]
 		if len(args) == 1 {
-			if transport == "" {
-				pullTo = uri.GetName("library://" + pullFrom)
-			} else {
-				pullTo = uri.GetName(pullFrom) // TODO: If not library/shub & no name specified, simply put to cache
-			}
+			pullTo = defaultPullName(pullFrom)
 		}
 	}
 
@@ -201,15 +282,45 @@ func pullRun(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	var expectedDigest digest.Digest
+	if pullExpectedDigest != "" {
+		expectedDigest, err = digest.Parse(pullExpectedDigest)
+		if err != nil {
+			sylog.Fatalf("Invalid expected digest %s: %v", pullExpectedDigest, err)
+		}
+	}
+
+	if err := pullImage(cmd, imgCache, pullTo, pullFrom, expectedDigest, requireSigned); err != nil {
+		sylog.Fatalf("%s", err)
+	}
+}
+
+// defaultPullName returns the name of the file an image is pulled to when
+// no name is given.
+func defaultPullName(pullFrom string) string {
+	transport, _ := uri.Split(pullFrom)
+	if transport == "" {
+		return uri.GetName("library://" + pullFrom)
+	}
+	return uri.GetName(pullFrom) // TODO: If not library/shub & no name specified, simply put to cache
+}
+
+// pullImage pulls the image pullFrom to the file pullTo. The file is removed
+// if it doesn't match expectedDigest, or if it's an unsigned library image
+// and requireSigned is true.
+func pullImage(cmd *cobra.Command, imgCache *cache.Handle, pullTo, pullFrom string, expectedDigest digest.Digest, requireSigned bool) error {
+	ctx := cmd.Context()
+	transport, _ := uri.Split(pullFrom)
+
 	switch transport {
 	case LibraryProtocol, "":
 		ref, err := library.NormalizeLibraryRef(pullFrom)
 		if err != nil {
-			sylog.Fatalf("Malformed library reference: %v", err)
+			return fmt.Errorf("malformed library reference: %v", err)
 		}
 
 		if pullLibraryURI != "" && ref.Host != "" {
-			sylog.Fatalf("Conflicting arguments; do not use --library with a library URI containing host name")
+			return fmt.Errorf("conflicting arguments; do not use --library with a library URI containing host name")
 		}
 
 		var libraryURI string
@@ -226,51 +337,106 @@ func pullRun(cmd *cobra.Command, args []string) {
 
 		lc, err := getLibraryClientConfig(libraryURI)
 		if err != nil {
-			sylog.Fatalf("Unable to get library client configuration: %v", err)
+			return fmt.Errorf("unable to get library client configuration: %v", err)
 		}
-		co, err := getKeyserverClientOpts("", endpoint.KeyserverVerifyOp)
-		if err != nil {
-			sylog.Fatalf("Unable to get keyserver client configuration: %v", err)
+		// signatures are verified against the local keyring only when
+		// signed images are required
+		var co []keyclient.Option
+		if !requireSigned {
+			co, err = getKeyserverClientOpts("", endpoint.KeyserverVerifyOp)
+			if err != nil {
+				return fmt.Errorf("unable to get keyserver client configuration: %v", err)
+			}
 		}
 
 		_, err = library.PullToFile(ctx, imgCache, pullTo, ref, pullArch, tmpDir, lc, co)
 		if err != nil && err != library.ErrLibraryPullUnsigned {
-			sylog.Fatalf("While pulling library image: %v", err)
+			return fmt.Errorf("while pulling library image: %v", err)
 		}
 		if err == library.ErrLibraryPullUnsigned {
+			if requireSigned {
+				if err := os.Remove(pullTo); err != nil {
+					sylog.Errorf("While removing unsigned image %s: %v", pullTo, err)
+				}
+				return fmt.Errorf("image %s is not signed by a trusted key, it has been deleted", pullTo)
+			}
 			sylog.Warningf("Skipping container verification")
 		}
 	case ShubProtocol:
 		_, err := shub.PullToFile(ctx, imgCache, pullTo, pullFrom, tmpDir, noHTTPS)
 		if err != nil {
-			sylog.Fatalf("While pulling shub image: %v\n", err)
+			return fmt.Errorf("while pulling shub image: %v", err)
 		}
 	case OrasProtocol:
 		ociAuth, err := makeDockerCredentials(cmd)
 		if err != nil {
-			sylog.Fatalf("Unable to make docker oci credentials: %s", err)
+			return fmt.Errorf("unable to make docker oci credentials: %s", err)
 		}
 
-		_, err = oras.PullToFile(ctx, imgCache, pullTo, pullFrom, tmpDir, ociAuth)
+		_, err = oras.PullToFile(ctx, imgCache, pullTo, pullFrom, tmpDir, ociAuth, "")
 		if err != nil {
-			sylog.Fatalf("While pulling image from oci registry: %v", err)
+			return fmt.Errorf("while pulling image from oci registry: %v", err)
 		}
 	case HTTPProtocol, HTTPSProtocol:
 		_, err := net.PullToFile(ctx, imgCache, pullTo, pullFrom, tmpDir)
 		if err != nil {
-			sylog.Fatalf("While pulling from image from http(s): %v\n", err)
+			return fmt.Errorf("while pulling from image from http(s): %v", err)
 		}
 	case oci.IsSupported(transport):
 		ociAuth, err := makeDockerCredentials(cmd)
 		if err != nil {
-			sylog.Fatalf("While creating Docker credentials: %v", err)
+			return fmt.Errorf("while creating Docker credentials: %v", err)
+		}
+
+		if expectedDigest != "" {
+			pullFrom, err = oci.PinDigest(ctx, pullFrom, tmpDir, ociAuth, noHTTPS, expectedDigest)
+			if err != nil {
+				return fmt.Errorf("while checking image digest: %v", err)
+			}
+			// the conversion of the verified OCI image to SIF is not
+			// checked against the OCI manifest digest
+			expectedDigest = ""
 		}
 
 		_, err = oci.PullToFile(ctx, imgCache, pullTo, pullFrom, tmpDir, ociAuth, noHTTPS, buildArgs.noCleanUp)
 		if err != nil {
-			sylog.Fatalf("While making image from oci registry: %v", err)
+			return fmt.Errorf("while making image from oci registry: %v", err)
 		}
 	default:
-		sylog.Fatalf("Unsupported transport type: %s", transport)
+		return fmt.Errorf("unsupported transport type: %s", transport)
+	}
+
+	if expectedDigest != "" {
+		if err := checkFileDigest(pullTo, expectedDigest); err != nil {
+			if err := os.Remove(pullTo); err != nil {
+				sylog.Errorf("While removing image %s: %v", pullTo, err)
+			}
+			return fmt.Errorf("image %s has been deleted: %v", pullTo, err)
+		}
+		sylog.Infof("Image %s matches expected digest %s", pullTo, expectedDigest)
+	}
+
+	if requireSignedPull && transport != LibraryProtocol && transport != "" {
+		sylog.Warningf("--require-signed is only supported for library:// images, image %s was not verified", pullTo)
+	}
+	return nil
+}
+
+// checkFileDigest returns an error if the digest of the file at path
+// doesn't match expected.
+func checkFileDigest(path string, expected digest.Digest) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	d, err := expected.Algorithm().FromReader(f)
+	if err != nil {
+		return fmt.Errorf("while computing digest: %v", err)
+	}
+	if d != expected {
+		return fmt.Errorf("digest %s doesn't match expected digest %s", d, expected)
 	}
+	return nil
 }
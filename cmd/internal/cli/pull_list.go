@@ -0,0 +1,197 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/internal/pkg/client/oci"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/internal/pkg/util/uri"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// pullConvertMu serializes the conversions of OCI images to SIF by pullList,
+// the build changes process wide state like the umask and signal handlers.
+var pullConvertMu sync.Mutex
+
+// pullListEntry is an image listed in a --from-file list, pulled once and
+// saved to each of the named files.
+type pullListEntry struct {
+	URI   string
+	Names []string
+}
+
+// parsePullList parses the list of images read from r, one URI per line
+// optionally followed by the name of the output file. Blank lines and lines
+// starting with '#' are ignored. Images listed several times are only
+// returned once, in the order of their first occurrence.
+func parsePullList(r io.Reader) ([]pullListEntry, error) {
+	var entries []pullListEntry
+	index := make(map[string]int)
+	owner := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("line %d: expected a URI optionally followed by a file name, got %q", line, text)
+		}
+		pullFrom := fields[0]
+		if _, ref := uri.Split(pullFrom); ref == "" {
+			return nil, fmt.Errorf("line %d: bad URI %s", line, pullFrom)
+		}
+
+		name := defaultPullName(pullFrom)
+		if len(fields) == 2 {
+			name = fields[1]
+		}
+		if name != filepath.Base(name) || name == "." || name == ".." {
+			return nil, fmt.Errorf("line %d: %q is not a file name", line, name)
+		}
+
+		if prev, ok := owner[name]; ok {
+			if prev != pullFrom {
+				return nil, fmt.Errorf("line %d: file %s is already used by %s", line, name, prev)
+			}
+			sylog.Debugf("Ignoring duplicate image %s to %s on line %d", pullFrom, name, line)
+			continue
+		}
+		owner[name] = pullFrom
+
+		i, ok := index[pullFrom]
+		if !ok {
+			i = len(entries)
+			index[pullFrom] = i
+			entries = append(entries, pullListEntry{URI: pullFrom})
+		}
+		entries[i].Names = append(entries[i].Names, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// pullList pulls the images listed in the file at path to the --dir
+// directory, pullConcurrency images at a time, and reports a summary. OCI
+// images are downloaded concurrently, but converted to SIF one at a time.
+// An error is returned if any of the images couldn't be pulled.
+func pullList(cmd *cobra.Command, imgCache *cache.Handle, path string, requireSigned bool) error {
+	if pullConcurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %d", pullConcurrency)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("while opening image list: %s", err)
+	}
+	entries, err := parsePullList(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("while parsing image list %s: %s", path, err)
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no images listed in %s", path)
+	}
+
+	if pullDir != "" {
+		if err := os.MkdirAll(pullDir, 0o755); err != nil {
+			return fmt.Errorf("while creating directory %s: %s", pullDir, err)
+		}
+	}
+
+	errs := make([]error, len(entries))
+	work := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < pullConcurrency && w < len(entries); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				errs[i] = pullListImage(cmd, imgCache, entries[i], requireSigned)
+			}
+		}()
+	}
+	for i := range entries {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	failed := 0
+	for i, e := range entries {
+		if errs[i] != nil {
+			failed++
+			sylog.Errorf("Failed to pull %s: %s", e.URI, errs[i])
+			continue
+		}
+		sylog.Infof("Pulled %s to %s", e.URI, strings.Join(e.Names, ", "))
+	}
+	sylog.Infof("%d of %d images pulled successfully", len(entries)-failed, len(entries))
+
+	if failed > 0 {
+		return fmt.Errorf("failed to pull %d of %d images", failed, len(entries))
+	}
+	return nil
+}
+
+// pullListImage pulls the image of entry to its first file, and copies it
+// to the other files.
+func pullListImage(cmd *cobra.Command, imgCache *cache.Handle, entry pullListEntry, requireSigned bool) error {
+	dests := make([]string, 0, len(entry.Names))
+	for _, name := range entry.Names {
+		dest := filepath.Join(pullDir, name)
+		if _, err := os.Stat(dest); !os.IsNotExist(err) && !forceOverwrite {
+			return fmt.Errorf("image file already exists: %q - will not overwrite", dest)
+		}
+		dests = append(dests, dest)
+	}
+
+	if transport, _ := uri.Split(entry.URI); transport != "" && oci.IsSupported(transport) == transport {
+		ociAuth, err := makeDockerCredentials(cmd)
+		if err != nil {
+			return fmt.Errorf("while creating Docker credentials: %v", err)
+		}
+		if err := oci.Fetch(cmd.Context(), imgCache, entry.URI, tmpDir, ociAuth, noHTTPS); err != nil {
+			return err
+		}
+		pullConvertMu.Lock()
+		err = pullImage(cmd, imgCache, dests[0], entry.URI, "", requireSigned)
+		pullConvertMu.Unlock()
+		if err != nil {
+			return err
+		}
+	} else if err := pullImage(cmd, imgCache, dests[0], entry.URI, "", requireSigned); err != nil {
+		return err
+	}
+
+	for _, dest := range dests[1:] {
+		if forceOverwrite {
+			if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("while removing %s: %s", dest, err)
+			}
+		}
+		if err := fs.CopyFile(dests[0], dest, 0o777); err != nil {
+			return fmt.Errorf("while copying %s to %s: %s", dests[0], dest, err)
+		}
+	}
+	return nil
+}
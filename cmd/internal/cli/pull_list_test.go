@@ -0,0 +1,84 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePullList(t *testing.T) {
+	tests := []struct {
+		name        string
+		list        string
+		entries     []pullListEntry
+		expectError bool
+	}{
+		{
+			name: "Empty",
+			list: "\n# comment\n  \n",
+		},
+		{
+			name: "DefaultNames",
+			list: "docker://alpine:3.15\nlibrary://alpine:latest\n",
+			entries: []pullListEntry{
+				{URI: "docker://alpine:3.15", Names: []string{"alpine_3.15.sif"}},
+				{URI: "library://alpine:latest", Names: []string{"alpine_latest.sif"}},
+			},
+		},
+		{
+			name: "CustomNames",
+			list: "docker://alpine:3.15 alpine.sif\n  # comment\ndocker://busybox  busybox.sif  \n",
+			entries: []pullListEntry{
+				{URI: "docker://alpine:3.15", Names: []string{"alpine.sif"}},
+				{URI: "docker://busybox", Names: []string{"busybox.sif"}},
+			},
+		},
+		{
+			name: "Duplicates",
+			list: "docker://alpine:3.15\ndocker://busybox b.sif\ndocker://alpine:3.15\ndocker://alpine:3.15 a.sif\n",
+			entries: []pullListEntry{
+				{URI: "docker://alpine:3.15", Names: []string{"alpine_3.15.sif", "a.sif"}},
+				{URI: "docker://busybox", Names: []string{"b.sif"}},
+			},
+		},
+		{
+			name:        "SameFile",
+			list:        "docker://alpine:3.15 a.sif\ndocker://busybox a.sif\n",
+			expectError: true,
+		},
+		{
+			name:        "TooManyFields",
+			list:        "docker://alpine:3.15 a.sif b.sif\n",
+			expectError: true,
+		},
+		{
+			name:        "BadURI",
+			list:        "docker:\n",
+			expectError: true,
+		},
+		{
+			name:        "PathName",
+			list:        "docker://alpine:3.15 ../alpine.sif\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parsePullList(strings.NewReader(tt.list))
+			if err != nil && !tt.expectError {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Fatalf("unexpected success")
+			}
+			if !reflect.DeepEqual(entries, tt.entries) {
+				t.Errorf("got entries %v, want %v", entries, tt.entries)
+			}
+		})
+	}
+}
@@ -37,6 +37,8 @@ var (
 	global                  bool
 	remoteUseExclusive      bool
 	remoteAddInsecure       bool
+	remoteStatusVerbose     bool
+	remoteStatusJSON        bool
 )
 
 // assemble values of remoteConfig for user/sys locations
@@ -162,6 +164,26 @@ var remoteAddInsecureFlag = cmdline.Flag{
 	Usage:        "allow connection to an insecure http remote",
 }
 
+// -v|--verbose
+var remoteStatusVerboseFlag = cmdline.Flag{
+	ID:           "remoteStatusVerboseFlag",
+	Value:        &remoteStatusVerbose,
+	DefaultValue: false,
+	Name:         "verbose",
+	ShortHand:    "v",
+	Usage:        "report latency and TLS details of services",
+}
+
+// -j|--json
+var remoteStatusJSONFlag = cmdline.Flag{
+	ID:           "remoteStatusJSONFlag",
+	Value:        &remoteStatusJSON,
+	DefaultValue: false,
+	Name:         "json",
+	ShortHand:    "j",
+	Usage:        "print status in JSON format",
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(RemoteCmd)
@@ -194,6 +216,9 @@ func init() {
 
 		cmdManager.RegisterFlagForCmd(&remoteKeyserverOrderFlag, RemoteAddKeyserverCmd)
 		cmdManager.RegisterFlagForCmd(&remoteKeyserverInsecureFlag, RemoteAddKeyserverCmd)
+
+		cmdManager.RegisterFlagForCmd(&remoteStatusVerboseFlag, RemoteStatusCmd)
+		cmdManager.RegisterFlagForCmd(&remoteStatusJSONFlag, RemoteStatusCmd)
 	})
 }
 
@@ -394,7 +419,7 @@ var RemoteStatusCmd = &cobra.Command{
 			name = args[0]
 		}
 
-		if err := singularity.RemoteStatus(remoteConfig, name); err != nil {
+		if err := singularity.RemoteStatus(remoteConfig, name, remoteStatusVerbose, remoteStatusJSON); err != nil {
 			sylog.Fatalf("%s", err)
 		}
 	},
@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2019-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -6,12 +6,31 @@
 package cli
 
 import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
 	"github.com/spf13/cobra"
 	"github.com/sylabs/sif/v2/pkg/siftool"
 	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
 	"github.com/sylabs/singularity/pkg/cmdline"
 )
 
+// -j|--json
+var sifHeaderJSON bool
+
+var sifHeaderJSONFlag = cmdline.Flag{
+	ID:           "sifHeaderJSONFlag",
+	Value:        &sifHeaderJSON,
+	DefaultValue: false,
+	Name:         "json",
+	ShortHand:    "j",
+	Usage:        "print the global header and the data object descriptors as JSON",
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmd := &cobra.Command{
@@ -24,6 +43,56 @@ func init() {
 		}
 		siftool.AddCommands(cmd)
 
+		for _, c := range cmd.Commands() {
+			switch c.Name() {
+			case "del":
+				protectSystemPartition(c)
+			case "header":
+				addHeaderJSON(c)
+				cmdManager.RegisterFlagForCmd(&sifHeaderJSONFlag, c)
+			}
+		}
+
 		cmdManager.RegisterCmd(cmd)
 	})
 }
+
+// protectSystemPartition prevents the sif del command to delete
+// the only system partition of a SIF image.
+func protectSystemPartition(cmd *cobra.Command) {
+	preRun := cmd.PreRunE
+
+	cmd.PreRunE = func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return fmt.Errorf("while converting id: %w", err)
+		}
+		if err := singularity.CheckSIFObjectDelete(args[1], uint32(id)); err != nil {
+			return err
+		}
+		if preRun != nil {
+			return preRun(cmd, args)
+		}
+		return nil
+	}
+}
+
+// addHeaderJSON makes the sif header command print the global header and
+// the data object descriptors of a SIF image as JSON with --json.
+func addHeaderJSON(cmd *cobra.Command) {
+	run := cmd.RunE
+
+	cmd.Example += "\n" + strings.Replace(cmd.Example, " header ", " header --json ", 1)
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if !sifHeaderJSON {
+			return run(cmd, args)
+		}
+		h, err := singularity.GetSIFHeader(args[0])
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		return enc.Encode(h)
+	}
+}
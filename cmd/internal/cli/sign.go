@@ -7,6 +7,9 @@ package cli
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/docs"
@@ -17,8 +20,9 @@ import (
 )
 
 var (
-	privKey int // -k encryption key (index from 'key list --secret') specification
-	signAll bool
+	privKey     int // -k encryption key (index from 'key list --secret') specification
+	signAll     bool
+	detachedSig string // --detached signature file path
 )
 
 // -g|--group-id
@@ -82,6 +86,16 @@ var signAllFlag = cmdline.Flag{
 	Deprecated:   "now the default behavior",
 }
 
+// --detached
+var signDetachedFlag = cmdline.Flag{
+	ID:           "signDetachedFlag",
+	Value:        &detachedSig,
+	DefaultValue: "",
+	Name:         "detached",
+	Usage:        "write a detached signature covering the whole image to this file, instead of attaching signatures to the image",
+	Tag:          "<path>",
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(SignCmd)
@@ -92,6 +106,8 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&signSifDescIDFlag, SignCmd)
 		cmdManager.RegisterFlagForCmd(&signKeyIdxFlag, SignCmd)
 		cmdManager.RegisterFlagForCmd(&signAllFlag, SignCmd)
+		cmdManager.RegisterFlagForCmd(&signDetachedFlag, SignCmd)
+		cmdManager.RegisterFlagForCmd(&keyringDirFlag, SignCmd)
 	})
 }
 
@@ -99,6 +115,7 @@ func init() {
 var SignCmd = &cobra.Command{
 	DisableFlagsInUseLine: true,
 	Args:                  cobra.ExactArgs(1),
+	PreRun:                checkKeyring,
 
 	Run: func(cmd *cobra.Command, args []string) {
 		// args[0] contains image path
@@ -124,6 +141,11 @@ func doSignCmd(cmd *cobra.Command, cpath string) {
 	f = decryptSelectedEntityInteractive(f)
 	opts = append(opts, singularity.OptSignEntitySelector(f))
 
+	if detachedSig != "" {
+		doSignDetachedCmd(cmd, cpath, opts)
+		return
+	}
+
 	// Set group option, if applicable.
 	if cmd.Flag(signSifGroupIDFlag.Name).Changed || cmd.Flag(signOldSifGroupIDFlag.Name).Changed {
 		opts = append(opts, singularity.OptSignGroup(sifGroupID))
@@ -141,3 +163,35 @@ func doSignCmd(cmd *cobra.Command, cpath string) {
 	}
 	fmt.Printf("Signature created and applied to %s\n", cpath)
 }
+
+func doSignDetachedCmd(cmd *cobra.Command, cpath string, opts []singularity.SignOpt) {
+	for _, f := range []cmdline.Flag{signSifGroupIDFlag, signOldSifGroupIDFlag, signSifDescSifIDFlag, signSifDescIDFlag} {
+		if cmd.Flag(f.Name).Changed {
+			sylog.Fatalf("--%s can't be used with --detached, a detached signature covers all objects of the image", f.Name)
+		}
+	}
+
+	// Write the signature to a temporary file, so that an existing signature
+	// is not truncated when signing fails.
+	f, err := ioutil.TempFile(filepath.Dir(detachedSig), filepath.Base(detachedSig)+".tmp-")
+	if err != nil {
+		sylog.Fatalf("Failed to create signature file: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	fmt.Printf("Signing image: %s\n", cpath)
+	if err := singularity.SignDetached(cpath, f, opts...); err != nil {
+		f.Close()
+		sylog.Fatalf("Failed to sign container: %s", err)
+	}
+	if err := f.Close(); err != nil {
+		sylog.Fatalf("Failed to write signature file: %s", err)
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		sylog.Fatalf("Failed to write signature file: %s", err)
+	}
+	if err := os.Rename(f.Name(), detachedSig); err != nil {
+		sylog.Fatalf("Failed to write signature file: %s", err)
+	}
+	fmt.Printf("Detached signature of %s created at %s\n", cpath, detachedSig)
+}
@@ -25,6 +25,7 @@ import (
 	scskeyclient "github.com/sylabs/scs-key-client/client"
 	scslibclient "github.com/sylabs/scs-library-client/client"
 	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/pkg/build/oci"
 	"github.com/sylabs/singularity/internal/pkg/buildcfg"
 	"github.com/sylabs/singularity/internal/pkg/plugin"
 	"github.com/sylabs/singularity/internal/pkg/remote"
@@ -57,6 +58,8 @@ var (
 	forceOverwrite      bool
 	noHTTPS             bool
 	tmpDir              string
+	cacheDir            string
+	registriesConf      string
 )
 
 const (
@@ -215,6 +218,28 @@ var commonTmpDirFlag = cmdline.Flag{
 	EnvKeys:      []string{"TMPDIR"},
 }
 
+// --cachedir
+var commonCacheDirFlag = cmdline.Flag{
+	ID:           "commonCacheDirFlag",
+	Value:        &cacheDir,
+	DefaultValue: "",
+	Name:         "cachedir",
+	Usage:        "use the cache located in this directory instead of the default cache",
+	EnvKeys:      []string{"CACHEDIR"},
+	Tag:          "<path>",
+}
+
+// --registries-conf
+var commonRegistriesConfFlag = cmdline.Flag{
+	ID:           "commonRegistriesConfFlag",
+	Value:        &registriesConf,
+	DefaultValue: "",
+	Name:         "registries-conf",
+	Usage:        "use this containers registries.conf file for docker:// images, instead of the 'registries conf path' directive or the default locations",
+	EnvKeys:      []string{"REGISTRIES_CONF"},
+	Tag:          "<path>",
+}
+
 // -c|--config
 var singConfigFileFlag = cmdline.Flag{
 	ID:           "singConfigFileFlag",
@@ -343,7 +368,11 @@ func Init(loadPlugins bool) {
 	// set persistent pre run function here to avoid initialization loop error
 	singularityCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
 		persistentPreRun(cmd, args)
-		return cmdManager.UpdateCmdFlagFromEnv(cmd, envPrefix)
+		if err := cmdManager.UpdateCmdFlagFromEnv(cmd, envPrefix); err != nil {
+			return err
+		}
+		oci.SetRegistriesConfPath(registriesConf)
+		return nil
 	}
 
 	cmdManager.RegisterFlagForCmd(&singDebugFlag, singularityCmd)
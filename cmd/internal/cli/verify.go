@@ -27,6 +27,16 @@ var (
 	verifyLegacy bool
 )
 
+// --detached
+var verifyDetachedFlag = cmdline.Flag{
+	ID:           "verifyDetachedFlag",
+	Value:        &detachedSig,
+	DefaultValue: "",
+	Name:         "detached",
+	Usage:        "verify the image against the detached signature in this file, instead of the signatures attached to the image",
+	Tag:          "<path>",
+}
+
 // -u|--url
 var verifyServerURIFlag = cmdline.Flag{
 	ID:           "verifyServerURIFlag",
@@ -131,6 +141,8 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&verifyJSONFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyAllFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyLegacyFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyDetachedFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&keyringDirFlag, VerifyCmd)
 	})
 }
 
@@ -138,6 +150,7 @@ func init() {
 var VerifyCmd = &cobra.Command{
 	DisableFlagsInUseLine: true,
 	Args:                  cobra.ExactArgs(1),
+	PreRun:                checkKeyring,
 
 	Run: func(cmd *cobra.Command, args []string) {
 		// args[0] contains image path
@@ -163,6 +176,11 @@ func doVerifyCmd(cmd *cobra.Command, cpath string) {
 		opts = append(opts, singularity.OptVerifyUseKeyServer(co...))
 	}
 
+	if detachedSig != "" {
+		doVerifyDetachedCmd(cmd, cpath, opts)
+		return
+	}
+
 	// Set group option, if applicable.
 	if cmd.Flag(verifySifGroupIDFlag.Name).Changed || cmd.Flag(verifyOldSifGroupIDFlag.Name).Changed {
 		opts = append(opts, singularity.OptVerifyGroup(sifGroupID))
@@ -211,3 +229,41 @@ func doVerifyCmd(cmd *cobra.Command, cpath string) {
 		fmt.Printf("Container verified: %s\n", cpath)
 	}
 }
+
+func doVerifyDetachedCmd(cmd *cobra.Command, cpath string, opts []singularity.VerifyOpt) {
+	for _, f := range []cmdline.Flag{verifySifGroupIDFlag, verifyOldSifGroupIDFlag, verifySifDescSifIDFlag, verifySifDescIDFlag, verifyAllFlag, verifyLegacyFlag} {
+		if cmd.Flag(f.Name).Changed {
+			sylog.Fatalf("--%s can't be used with --detached, a detached signature covers all objects of the image", f.Name)
+		}
+	}
+
+	sig, err := os.Open(detachedSig)
+	if err != nil {
+		sylog.Fatalf("Failed to open signature file: %s", err)
+	}
+	defer sig.Close()
+
+	if !jsonVerify {
+		fmt.Printf("Verifying image: %s\n", cpath)
+	}
+
+	e, ods, verifyErr := singularity.VerifyDetached(cmd.Context(), cpath, sig, opts...)
+
+	if jsonVerify {
+		// Always output JSON.
+		if err := outputJSON(os.Stdout, getDetachedKeyList(e, ods)); err != nil {
+			sylog.Fatalf("Failed to output JSON: %v", err)
+		}
+	} else {
+		outputSigningEntity(e)
+		outputVerifiedObjects(ods)
+	}
+
+	if verifyErr != nil {
+		sylog.Fatalf("Failed to verify container: %s", verifyErr)
+	}
+
+	if !jsonVerify {
+		fmt.Printf("Container verified: %s\n", cpath)
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/pkg/volume"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterCmd(VolumeCmd)
+		cmdManager.RegisterSubCmd(VolumeCmd, VolumeCreateCmd)
+		cmdManager.RegisterSubCmd(VolumeCmd, VolumeListCmd)
+		cmdManager.RegisterSubCmd(VolumeCmd, VolumeRemoveCmd)
+	})
+}
+
+// VolumeCmd is the 'volume' command that allows to manage named volumes.
+var VolumeCmd = &cobra.Command{
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return errors.New("invalid command")
+	},
+	DisableFlagsInUseLine: true,
+
+	Use:     docs.VolumeUse,
+	Short:   docs.VolumeShort,
+	Long:    docs.VolumeLong,
+	Example: docs.VolumeExample,
+}
+
+// VolumeCreateCmd is the 'volume create' command.
+var VolumeCreateCmd = &cobra.Command{
+	Args:                  cobra.ExactArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		v, err := volume.Create(args[0])
+		if err != nil {
+			sylog.Fatalf("Could not create volume: %s", err)
+		}
+		sylog.Infof("Volume %s created in %s", v.Name, v.Path)
+	},
+
+	Use:     docs.VolumeCreateUse,
+	Short:   docs.VolumeCreateShort,
+	Long:    docs.VolumeCreateLong,
+	Example: docs.VolumeCreateExample,
+}
+
+// VolumeListCmd is the 'volume list' command.
+var VolumeListCmd = &cobra.Command{
+	Args:                  cobra.ExactArgs(0),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		volumes, err := volume.List()
+		if err != nil {
+			sylog.Fatalf("Could not list volumes: %s", err)
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 8, 4, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tPATH")
+		for _, v := range volumes {
+			fmt.Fprintf(tw, "%s\t%s\n", v.Name, v.Path)
+		}
+		tw.Flush()
+	},
+
+	Use:     docs.VolumeListUse,
+	Aliases: []string{"ls"},
+	Short:   docs.VolumeListShort,
+	Long:    docs.VolumeListLong,
+	Example: docs.VolumeListExample,
+}
+
+// VolumeRemoveCmd is the 'volume remove' command.
+var VolumeRemoveCmd = &cobra.Command{
+	Args:                  cobra.MinimumNArgs(1),
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, name := range args {
+			if err := volume.Remove(name); err != nil {
+				sylog.Errorf("Could not remove volume: %s", err)
+				failed = true
+				continue
+			}
+			sylog.Infof("Volume %s removed", name)
+		}
+		if failed {
+			os.Exit(255)
+		}
+	},
+
+	Use:     docs.VolumeRemoveUse,
+	Aliases: []string{"rm"},
+	Short:   docs.VolumeRemoveShort,
+	Long:    docs.VolumeRemoveLong,
+	Example: docs.VolumeRemoveExample,
+}
@@ -241,6 +241,28 @@ static int get_last_cap(void) {
     return --last_cap;
 }
 
+/*
+ * setgroups_denied returns true if the setgroups syscall is denied
+ * in the current user namespace, the supplementary groups are kept
+ * in this case
+ */
+static bool setgroups_denied(void) {
+    char buffer[8];
+    ssize_t n;
+    int fd = open("/proc/self/setgroups", O_RDONLY);
+
+    if ( fd < 0 ) {
+        /* kernel without setgroups control */
+        return false;
+    }
+
+    memset(buffer, 0, sizeof(buffer));
+    n = read(fd, buffer, sizeof(buffer) - 1);
+    close(fd);
+
+    return n > 0 && strncmp(buffer, "deny", 4) == 0;
+}
+
 static void apply_privileges(struct privileges *privileges, struct capabilities *current) {
     uid_t currentUID = getuid();
     uid_t targetUID = currentUID;
@@ -310,9 +332,13 @@ static void apply_privileges(struct privileges *privileges, struct capabilities
                 fatalf("Failed to set GID %d: %s\n", targetGID, strerror(errno));
             }
 
-            debugf("Set %d additional group IDs\n", privileges->numGID);
-            if ( setgroups(privileges->numGID, privileges->targetGID) < 0 ) {
-                fatalf("Failed to set additional groups: %s\n", strerror(errno));
+            if ( setgroups_denied() ) {
+                debugf("Keep additional group IDs, setgroups is denied\n");
+            } else {
+                debugf("Set %d additional group IDs\n", privileges->numGID);
+                if ( setgroups(privileges->numGID, privileges->targetGID) < 0 ) {
+                    fatalf("Failed to set additional groups: %s\n", strerror(errno));
+                }
             }
         }
     }
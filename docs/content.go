@@ -40,11 +40,21 @@ Enterprise Performance Computing (EPC)`
 
       default:    The compressed Singularity read only image format (default)
       sandbox:    This is a read-write container within a directory structure
+      oci:        An OCI image layout directory, for OCI-native tooling
 
   note: It is a common workflow to use the "sandbox" mode for development of the
   container, and then build it as a default Singularity image for production 
   use. The default format is immutable.
 
+  With --oci, the target is an OCI image layout directory holding a single
+  image, tagged 'latest', made of one gzip compressed layer with the whole root
+  filesystem. The architecture of the image and of its platform in index.json
+  is the architecture of the container binaries, or of the host if it can't be
+  determined, and its OS is always 'linux'. The container labels are set in the
+  image configuration, and its entrypoint is /.singularity.d/actions/run, which
+  sources the container environment and runs the runscript, like
+  'singularity run'. Encrypted OCI images are not supported.
+
   BUILD SPEC:
 
   The build spec target is a definition (def) file, local image, or URI that can 
@@ -61,7 +71,16 @@ Enterprise Performance Computing (EPC)`
       library://  an image library (default https://cloud.sylabs.io/library)
       docker://   a Docker/OCI registry (default Docker Hub)
       shub://     a Singularity registry (default Singularity Hub)
-      oras://     an OCI registry that holds SIF files using ORAS`
+      oras://     an OCI registry that holds SIF files or rootfs tarballs
+                  using ORAS
+
+  With --dockerfile, the build spec is a Dockerfile, or a directory containing
+  a Dockerfile, translated into a definition file. The FROM, RUN, COPY, ADD,
+  ENV, ARG, WORKDIR, LABEL, ENTRYPOINT and CMD instructions are supported,
+  including multi-stage builds. COPY and ADD sources are relative to the
+  Dockerfile directory, and the files copied after a RUN instruction are
+  staged by %files then moved in %post, to keep the instruction order.
+  Unsupported instructions are reported as errors.`
 
 	BuildExample string = `
 
@@ -134,6 +153,7 @@ Enterprise Performance Computing (EPC)`
       %files
           /path/on/host/file.txt /path/on/container/file.txt
           relative_file.txt /path/on/container/relative_file.txt
+          --chown 1000:1000 --chmod 0640 app.conf /etc/app.conf
 
       %environment
           LUKE=goodguy
@@ -144,6 +164,25 @@ Enterprise Performance Computing (EPC)`
       %help
           This is a text file to be displayed with the run-help command.
 
+  A %files entry can start with --chown uid[:gid] and --chmod mode options, to
+  set the numeric ownership and the octal mode of the copied files, and of the
+  content of copied directories, in the container.
+
+  With the --fetch-files option, the source of a %files entry can be an
+  http(s) URL, copied by default to the container root under the last element
+  of the URL path. Downloads are kept in the cache, keyed by the URL and the
+  ETag, or Last-Modified date, returned by the server, so that unchanged files
+  are only downloaded once across builds:
+
+      %files
+          https://example.com/installer-1.0.tar.gz /opt/installer.tar.gz
+
+  Except for %files, the content of a section can be read from a file at build
+  time, with a relative path resolved from the definition file directory:
+
+      %runscript include ./run.sh
+      %apprun foo include /path/to/foo.sh
+
   COMMANDS:
 
       Build a sif file from a Singularity recipe file:
@@ -155,7 +194,44 @@ Enterprise Performance Computing (EPC)`
       Build a base sandbox from DockerHub, make changes to it, then build sif
           $ singularity build --sandbox /tmp/debian docker://debian:latest
           $ singularity exec --writable /tmp/debian apt-get install python
-          $ singularity build /tmp/debian2.sif /tmp/debian`
+          $ singularity build /tmp/debian2.sif /tmp/debian
+
+      Build a sif image from the Dockerfile in the current directory:
+          $ singularity build --dockerfile /tmp/app.sif .
+
+      Build an OCI image layout directory and copy it to a registry:
+          $ singularity build --oci /tmp/app-oci/ /path/to/app.def
+          $ skopeo copy oci:/tmp/app-oci:latest docker://registry.example.com/app:latest`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// Lint
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	LintUse   string = `lint [lint options...] <definition file>...`
+	LintShort string = `Check definition files for mistakes`
+	LintLong  string = `
+  The lint command checks definition files without building them, and reports
+  the issues found as <file>:<line>: <severity>: <message>.
+
+  Errors are problems which make the build fail: unknown header keywords or
+  sections, header keywords without value, a missing Bootstrap keyword or
+  unknown bootstrap agent, keywords required by the bootstrap agent (e.g.
+  From for docker), app sections without an app name, missing included files,
+  and %files sources not found. As done by build, %files sources are looked up
+  from the current directory, and included files from the definition file
+  directory.
+
+  Warnings are likely mistakes which don't make the build fail: empty
+  sections, sections or header keywords defined twice, labels without value,
+  and %files sources only downloaded with build --fetch-files.
+
+  The command exits with a non-zero status if an error is found. Use the
+  --warnings flag to ignore warnings, or to treat them as errors.`
+	LintExample string = `
+  $ singularity lint my.def
+
+  To fail on warnings too, e.g. in a CI pipeline:
+
+  $ singularity lint --warnings error my.def`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// Cache
@@ -164,7 +240,11 @@ Enterprise Performance Computing (EPC)`
 	CacheShort string = `Manage the local cache`
 	CacheLong  string = `
   Manage your local Singularity cache. You can list/clean using the specific 
-  types.`
+  types.
+
+  The --cachedir option of cache commands, build, pull and action commands
+  uses the cache located in the given directory instead of the default cache,
+  it takes precedence over 'SINGULARITY_CACHEDIR'.`
 	CacheExample string = `
   All group commands have their own help output:
 
@@ -181,13 +261,27 @@ Enterprise Performance Computing (EPC)`
   SINGULARITY_CACHEDIR is not set). By default the entire cache is cleaned, use
   --days and --type flags to override this behavior. Note: if you use Singularity
   as root, cache will be stored in '/root/.singularity/.cache', to clean that
-  cache, you will need to run 'cache clean' as root, or with 'sudo'.`
+  cache, you will need to run 'cache clean' as root, or with 'sudo'.
+
+  OCI blobs (layers) are stored once in the blob cache and shared by all the
+  images referencing them. With --days, the images older than the given number
+  of days are removed from the blob cache, and only the blobs no longer
+  referenced by any remaining image are removed.
+
+  With --dry-run, the cache entries which would be removed are listed with their
+  size, followed by the total space which would be reclaimed, and nothing is
+  deleted. With --json, the entries and the reclaimed space are printed in JSON
+  format, --json requires --dry-run or --force as no confirmation is asked.`
 	CacheCleanExample string = `
   All group commands have their own help output:
 
   $ singularity help cache clean --days 30
   $ singularity help cache clean --type=library,oci
-  $ singularity cache clean --help`
+  $ singularity cache clean --help
+
+  Show what would be removed from the cache, in JSON format:
+
+  $ singularity cache clean --dry-run --json --days 30`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// Cache List
@@ -212,7 +306,11 @@ Enterprise Performance Computing (EPC)`
 	KeyLong  string = `
   Manage your trusted, public and private keys in your local or in the global keyring
   (local keyring: '~/.singularity/sypgp' if 'SINGULARITY_SYPGPDIR' is not set,
-  global keyring: '%[1]s/singularity/global-pgp-public')`
+  global keyring: '%[1]s/singularity/global-pgp-public')
+
+  The --keyring option of key commands, sign and verify uses the keyring located
+  in the given directory instead of the local keyring, it takes precedence over
+  'SINGULARITY_SYPGPDIR'.`
 	KeyExample string = `
   All group commands have their own help output:
 
@@ -229,20 +327,29 @@ Enterprise Performance Computing (EPC)`
 	KeyImportShort string = `Import a local key into the local or global keyring`
 	KeyImportLong  string = `
   The 'key import' command allows you to add a key to your local or global keyring
-  from a specific file.`
+  from a specific file. Public and secret keys are imported from files in either
+  binary or ASCII armored format, the passphrase of secret keys is asked for
+  during import. Keys already present in the keyring are only replaced when
+  --force is specified.`
 	KeyImportExample string = `
   $ singularity key import ./my-key.asc
 
+  # Replace a key already present in the keyring
+  $ singularity key import --force ./my-key.asc
+
   # Import into global keyring (root user only)
   $ singularity key import --global ./my-key.asc`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// key export
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
-	KeyExportUse   string = `export [export options...] <output-file>`
+	KeyExportUse   string = `export [export options...] [<fingerprint>] <output-file>`
 	KeyExportShort string = `Export a public or private key into a specific file`
 	KeyExportLong  string = `
-  The 'key export' command allows you to export a key and save it to a file.`
+  The 'key export' command allows you to export a key and save it to a file.
+  The key to export is selected by its fingerprint, or interactively if no
+  fingerprint is given. An existing output file is only overwritten when
+  --force is specified.`
 	KeyExportExample string = `
   Exporting a private key:
   
@@ -250,7 +357,11 @@ Enterprise Performance Computing (EPC)`
 
   Exporting a public key:
   
-  $ singularity key export ./public.asc`
+  $ singularity key export ./public.asc
+
+  Exporting an ASCII armored private key by fingerprint:
+
+  $ singularity key export --armor --secret 8883491F4268F173C6E5DC49EDECE4F3F38D871E ./private.asc`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// key newpair
@@ -263,7 +374,15 @@ Enterprise Performance Computing (EPC)`
   $HOME/.singularity/sypgp).`
 	KeyNewPairExample string = `
   $ singularity key newpair
-  $ singularity key newpair --password=psk --name=your-name --comment="key comment" --email=mail@email.com --push=false`
+  $ singularity key newpair --password=psk --name=your-name --comment="key comment" --email=mail@email.com --push=false
+
+  With --batch, nothing is prompted: --name and --email are required, the
+  comment is optional, and the key is only pushed to the keystore with
+  --push. The passphrase is given with --password or read from standard input
+  with --passphrase-stdin, the key isn't encrypted without one:
+
+  $ echo "$KEY_PASSPHRASE" | singularity key newpair --batch --bits 4096 \
+      --name="CI runner" --email=ci@example.com --passphrase-stdin`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// key list
@@ -309,9 +428,17 @@ Enterprise Performance Computing (EPC)`
   your keyring when running commands such as 'singularity verify', and thus
   adding a key to your keyring implies a level of trust. Because of this, it is
   recommended that you verify the fingerprint of the key with its owner prior
-  to running this command.`
+  to running this command.
+
+  The keys retrieved are displayed with their user IDs and fingerprints before
+  being imported. When a key is pulled by its full fingerprint, or when the
+  --fingerprint option is set, nothing is imported if the retrieved key has a
+  different fingerprint.`
 	KeyPullExample string = `
-  $ singularity key pull 8883491F4268F173C6E5DC49EDECE4F3F38D871E`
+  $ singularity key pull 8883491F4268F173C6E5DC49EDECE4F3F38D871E
+
+  Search a key by email and import it only if it has the expected fingerprint:
+  $ singularity key pull --fingerprint 8883491F4268F173C6E5DC49EDECE4F3F38D871E john@example.com`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// key push
@@ -320,9 +447,17 @@ Enterprise Performance Computing (EPC)`
 	KeyPushShort string = `Upload a public key to a key server`
 	KeyPushLong  string = `
   The 'key push' command allows you to connect to a key server and upload public
-  keys from the local or the global keyring.`
+  keys from the local or the global keyring.
+
+  The key is pushed to the default key server of the current remote endpoint,
+  or to the key server selected with --url. A key server listed by
+  'singularity remote list' is used with its configured credentials
+  and TLS settings.`
 	KeyPushExample string = `
-  $ singularity key push 8883491F4268F173C6E5DC49EDECE4F3F38D871E`
+  $ singularity key push 8883491F4268F173C6E5DC49EDECE4F3F38D871E
+
+  Push a key to another key server of the remote endpoint:
+  $ singularity key push --url https://keys.example.com 8883491F4268F173C6E5DC49EDECE4F3F38D871E`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// key remove
@@ -481,7 +616,161 @@ Enterprise Performance Computing (EPC)`
   $ cat hello_world.py | singularity exec /tmp/debian.sif python
   $ sudo singularity exec --writable /tmp/debian.sif apt-get update
   $ singularity exec instance://my_instance ps -ef
-  $ singularity exec library://centos cat /etc/os-release`
+  $ singularity exec library://centos cat /etc/os-release
+
+  Scratch directories are writable, empty on each run and discarded on exit.
+  They are stored in the session directory, or in -W/--workdir when given,
+  whether or not -c/--contain is used. --scratch-size mounts each of them on
+  a dedicated tmpfs of the given size in MiB instead, which can't exceed
+  'sessiondir max size' in singularity.conf in setuid mode:
+
+  $ singularity exec --contain --scratch /scratch --scratch-size 1024 /tmp/debian.sif df -h /scratch
+
+  With --writable-tmpfs, the changes made in the container are also stored in
+  -W/--workdir when given, as root or with a user namespace (--userns or
+  --fakeroot), in a session-* directory removed when the container exits.
+  This directory is created with 0700 permissions and owned by the calling
+  user, and the files it holds have the host owners of the container files,
+  subordinate IDs with --fakeroot for container users other than root. In
+  setuid mode, the changes stay in the session directory, limited by
+  'sessiondir max size' in singularity.conf:
+
+  $ singularity exec --userns --workdir /nvme/scratch --writable-tmpfs /tmp/debian.sif apt-get install -y git
+
+  The container process inherits the umask of the calling process, with or
+  without --fakeroot, so that files are created with the same permissions as
+  on the host. --umask sets another umask for the container process, while
+  --no-umask, implied by --compat, sets the default 0022 umask:
+
+  $ singularity exec --umask 0027 /tmp/debian.sif touch /data/shared_file
+
+  With --fakeroot, --uidmap or --gidmap, the setgroups syscall is allowed in
+  the user namespace and your supplementary groups are replaced by the
+  container group. --setgroups deny keeps them instead, so that group based
+  access to host files, e.g. on NFS, still works. They show up as nogroup in
+  the container unless they are mapped, and can't be dropped by the container
+  process. Allowing setgroups lets a container process drop a group used to
+  deny access to a file, which is why it's always denied with --userns:
+
+  $ singularity exec --fakeroot --setgroups deny /tmp/debian.sif ls /nfs/project
+
+  --writable-cwd makes the current working directory of the container process
+  writable when it's located in the read-only image, by mounting a temporary
+  overlay on it, without making the rest of the image writable. Changes are
+  kept in the session directory and discarded when the container exits. The
+  current directory is usually bound from the host and already writable, so
+  this applies when it isn't: with --contain or --no-mount cwd combined with
+  --pwd, or when the host directory doesn't exist in the container. A
+  directory bound with --bind, the home directory or a scratch directory is
+  left as is, read-only binds included. The overlay isn't mounted on the root
+  directory, or over a directory containing other mount points:
+
+  $ singularity exec --contain --pwd /opt/app --writable-cwd /tmp/app.sif ./analyze
+
+  --dev selects the container /dev: minimal creates a /dev with the essential
+  devices only, as --contain does, full binds the host /dev, even with
+  --contain, and custom adds the listed host devices to a minimal /dev.
+  Devices are paths or glob patterns below /dev, matching device nodes,
+  directories or symlinks. The 'mount dev' directive of singularity.conf
+  still applies, a minimal /dev is used instead of the host /dev when it's
+  set to minimal, and no /dev is mounted when it's set to no:
+
+  $ singularity exec --dev "custom:/dev/nvidia*" /tmp/cuda.sif nvidia-smi
+
+  --env-json passes the variables of a JSON object, given on the command line
+  or in a file, to the container. Values must be strings and are used as is,
+  without shell evaluation. --env-json variables take precedence over
+  --env-file variables, and --env variables take precedence over both:
+
+  $ singularity exec --env-json '{"STAGE": "test", "RUN_ID": "42"}' /tmp/debian.sif env
+
+  --commit saves the changes made in the --writable-tmpfs overlay to a new SIF
+  image once the container exits successfully, whiteouts of removed files
+  included. The changes are stored in the --workdir directory, or the
+  temporary directory, while the container runs instead of the size-limited
+  session directory. Nothing is saved if the container exits with an error.
+  As the changes directory is used as overlay upper directory, --commit
+  requires root or a user namespace:
+
+  $ singularity exec --userns --writable-tmpfs --commit /tmp/debian-git.sif /tmp/debian.sif apt-get install -y git
+
+  A leading ~ or ~user, and $VAR or ${VAR} environment variables are expanded
+  in the source and destination of --bind, --bind-data and --mount paths, even
+  when quoted or set in SINGULARITY_BIND. ~ is the home directory of the
+  calling user on the host, and variables are taken from the host environment;
+  references to unset variables are kept literally:
+
+  $ singularity exec --bind '~/data:/data,$SCRATCH/run:/run-dir' /tmp/debian.sif ls /data
+
+  A --pwd directory missing from the container is created when the container
+  file system is writable, and is an error otherwise. In setuid mode, it's
+  only created with --writable-tmpfs on top of an image file:
+
+  $ singularity exec --writable-tmpfs --pwd /work /tmp/debian.sif pwd
+
+  --rootfs-propagation sets the mount propagation of the container root
+  filesystem, and of the mounts below it, once the container is set up. With
+  'shared', mounts created later in the container are propagated to the mount
+  namespaces created by container processes, and back, as needed by
+  containerized storage services mounting file systems for other processes.
+  With 'slave', mounts created in the container are not propagated, but it
+  still receives the mounts propagated from the session set up by
+  Singularity, and with 'private', nothing is propagated. By default the
+  propagation set up by Singularity, needed by host FUSE mounts from
+  --fusemount, is kept:
+
+  $ singularity exec --rootfs-propagation shared /tmp/debian.sif glusterfsd
+
+  By default the container shares the network of the host. --network none,
+  which implies --net, runs the container in a new, empty network namespace
+  without any network interface up, not even the loopback interface, isolating
+  it from the host and from other containers. Unlike the other network types,
+  it doesn't require root privileges, and also works with --userns. With
+  'singularity oci', the same isolation is obtained by adding a network
+  namespace without path to the bundle config.json:
+
+  $ singularity exec --network none /tmp/debian.sif ip link
+
+  A bind source mounted in the background, like a FUSE file system from
+  sshfs, may not be ready when the container starts, which would then see an
+  empty directory. The 'wait' bind option waits up to 30 seconds for the
+  source to be a mount point holding files, and 'wait-timeout' sets another
+  delay. The container doesn't start if the source isn't ready in time:
+
+  $ sshfs host:/data /mnt/data &
+  $ singularity exec --bind /mnt/data:/data:ro,wait-timeout=1m /tmp/debian.sif ls /data
+
+  The 'overlay' bind option mounts a host directory writable in the
+  container without modifying it: the source is the read-only lower layer of
+  an overlay, and changes are stored in a temporary upper layer in the session
+  directory, discarded when the container exits. Its size is limited by
+  'sessiondir max size' in singularity.conf. With 'overlay-upper=<dir>',
+  changes are instead kept in the 'upper' directory of <dir>, created if
+  needed. As the overlay would be mounted with root privileges in setuid
+  mode, these options require root or a user namespace (--userns or
+  --fakeroot):
+
+  $ singularity exec --userns --bind /refdata:/data:overlay /tmp/debian.sif index /data
+  $ singularity exec --userns --bind /refdata:/data:overlay-upper=$HOME/changes /tmp/debian.sif index /data
+
+  By default, host environment variables are passed to the container, except
+  PATH, HOME and SINGULARITY_* variables, and the container environment can
+  override them. With --cleanenv, only the variables matching --keep-env are
+  passed. Variables matching --env-pass-through are passed in both cases, and
+  override the container environment, while --env and SINGULARITYENV_*
+  variables still take precedence over them. Combined with --cleanenv, only
+  the matching variables cross into the container:
+
+  $ singularity exec --cleanenv --env-pass-through 'PBS_*' /tmp/debian.sif env
+
+  --device adds a host block or character device below /dev to the container,
+  its node is created at the same path in a minimal /dev, e.g. with --contain,
+  and is already present when the host /dev is mounted. When cgroups limits
+  are applied, with --apply-cgroups or the resource limit flags, access to the
+  device is allowed with the given permissions, a combination of r, w and m
+  (mknod), rwm by default:
+
+  $ singularity exec --contain --device /dev/loop5:rw /tmp/debian.sif mkfs.ext4 /dev/loop5`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// instance
@@ -534,6 +823,23 @@ Enterprise Performance Computing (EPC)`
   will be executed with the instance start command as well. You can optionally
   pass arguments to startscript
 
+  With --restart on-failure[:max], the instance start command doesn't return
+  once the instance is started but supervises it, restarting the instance each
+  time it exits with a non-zero status or is killed by a signal, up to max
+  times if specified. The command returns once the instance exits
+  successfully. An instance stopped with the instance stop command, or by
+  sending SIGINT or SIGTERM to the supervising command, is not restarted. As
+  the supervisor runs in the foreground, it is intended to be run by a process
+  manager, like a systemd service unit. The number of restarts is displayed by
+  the instance list command.
+
+  The environment flags work as with run and exec: the host environment is
+  passed to the instance unless --cleanenv is set, and --env, --env-file,
+  --env-json and --env-pass-through set variables of the instance environment.
+  The variables set by these flags, or by SINGULARITYENV_ variables, are also
+  set for the commands run in the instance with instance://, unless the
+  joining command sets the same variables.
+
   singularity instance start accepts the following container formats` + formats
 	InstanceStartExample string = `
   $ singularity instance start /tmp/my-sql.sif mysql
@@ -549,7 +855,10 @@ Enterprise Performance Computing (EPC)`
   Singularity my-sql.sif>
 
   $ singularity instance stop /tmp/my-sql.sif mysql
-  Stopping /tmp/my-sql.sif mysql`
+  Stopping /tmp/my-sql.sif mysql
+
+  $ singularity instance start --cleanenv --env-file mysql.env /tmp/my-sql.sif mysql
+  $ singularity exec instance://mysql printenv MYSQL_DATABASE`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// instance stop
@@ -579,7 +888,7 @@ Enterprise Performance Computing (EPC)`
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// pull
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
-	PullUse   string = `pull [pull options...] [output file] <URI>`
+	PullUse   string = `pull [pull options...] [output file] <URI> | --from-file <file>`
 	PullShort string = `Pull an image from a URI`
 	PullLong  string = `
   The 'pull' command allows you to download or build a container from a given
@@ -598,7 +907,32 @@ Enterprise Performance Computing (EPC)`
       oras://registry/namespace/image:tag
 
   http, https: Pull an image using the http(s?) protocol
-      https://library.sylabs.io/v1/imagefile/library/default/alpine:latest`
+      https://library.sylabs.io/v1/imagefile/library/default/alpine:latest
+
+  With --from-file, the images listed in a file are pulled to the --dir
+  directory, created if needed. Each line holds a URI, optionally followed by
+  the name of the output file. Blank lines and lines starting with '#' are
+  ignored. An image listed several times is only pulled once, and images are
+  pulled --concurrency at a time sharing the same cache, OCI images being
+  converted to SIF one at a time. A summary is reported
+  once all images are pulled, and the command fails if any of them couldn't be
+  pulled.
+
+  docker:// images are pulled following the containers registries.conf file
+  given with --registries-conf, or else set by the 'registries conf path'
+  directive of singularity.conf, or else found at
+  $HOME/.config/containers/registries.conf or
+  /etc/containers/registries.conf. The supported fields are:
+
+    unqualified-search-registries  registries searched in order for images
+                                   without a registry host name, instead of
+                                   docker.io
+    [[registry]] location, prefix  the registry, or namespace, configured
+    [[registry]] insecure          allow HTTP and unverified TLS connections
+    [[registry]] blocked           refuse to pull images from the registry
+    [[registry.mirror]]            mirrors tried before the registry
+
+  Short name aliases and short-name-mode are not supported.`
 	PullExample string = `
   From Sylabs cloud library
   $ singularity pull alpine.sif library://alpine:latest
@@ -610,7 +944,13 @@ Enterprise Performance Computing (EPC)`
   $ singularity pull singularity-images.sif shub://vsoch/singularity-images
 
   From supporting OCI registry (e.g. Azure Container Registry)
-  $ singularity pull image.sif oras://<username>.azurecr.io/namespace/image:tag`
+  $ singularity pull image.sif oras://<username>.azurecr.io/namespace/image:tag
+
+  From a list of images
+  $ cat images.txt
+  docker://alpine:3.15 alpine.sif
+  library://lolcow
+  $ singularity pull --from-file images.txt --dir out/ --concurrency 2`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// push
@@ -721,10 +1061,18 @@ Enterprise Performance Computing (EPC)`
   The sign command allows a user to add one or more digital signatures to a SIF
   image. By default, one digital signature is added for each object group in
   the file.
+
+  With --detached, the image is left unmodified and a standalone signature
+  covering all the data objects of the image is written to the given file
+  instead, to distribute alongside the image where signatures can't be stored
+  in the image. The signature covers the global header and the descriptors and
+  content of all data objects except signatures, but not their timestamps, so
+  it remains valid when signatures are attached to the image.
   
   To generate a key pair, see 'singularity help key newpair'`
 	SignExample string = `
-  $ singularity sign container.sif`
+  $ singularity sign container.sif
+  $ singularity sign --detached container.sif.sig container.sif`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// verify
@@ -737,9 +1085,14 @@ Enterprise Performance Computing (EPC)`
   multiple data objects signed. By default the command searches for the primary 
   partition signature. If found, a list of all verification blocks applied on 
   the primary partition is gathered so that data integrity (hashing) and 
-  signature verification is done for all those blocks.`
+  signature verification is done for all those blocks.
+
+  With --detached, the image is verified against the detached signature in the
+  given file, created with 'singularity sign --detached', instead of the
+  signatures attached to the image.`
 	VerifyExample string = `
-  $ singularity verify container.sif`
+  $ singularity verify container.sif
+  $ singularity verify --detached container.sif.sig container.sif`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// Run-help
@@ -782,9 +1135,38 @@ Enterprise Performance Computing (EPC)`
   Inspect will show you labels, environment variables, apps and scripts associated 
   with the image determined by the flags you pass. By default, they will be shown in 
   plain text. If you would like to list them in json format, you should use the --json flag.
+
+  All JSON outputs have a schemaVersion field, the version of their format.
+  Within a major version, the format only evolves in a backward-compatible
+  way: fields can be added, but are never removed, renamed or changed.
   `
 	InspectExample string = `
   $ singularity inspect ubuntu.sif
+
+  To inspect the labels and registry metadata of a library:// or docker://
+  image without downloading it, use the --remote flag:
+
+  $ singularity inspect --remote docker://alpine:latest
+
+  To print the digest of a SIF image, the sha256 of the whole file in the
+  sha256.<hex> form used by the library, use the --digest flag:
+
+  $ singularity inspect --digest ubuntu.sif
+
+  The digest covers every byte of the file, including the SIF header, its
+  creation and modification times, and any signature, so it changes when
+  the image is signed or modified, but not when it is copied or renamed.
+
+  To list the partitions of a SIF image with their type (squashfs, ext3,
+  encrypted...), filesystem, compression algorithm and size, use the
+  --sif-layers flag, with --json for a JSON output:
+
+  $ singularity inspect --sif-layers ubuntu.sif
+
+  For an image built from a docker or OCI image, the JSON output of the
+  runscript also holds the entrypoint and cmd it runs in a structured form:
+
+  $ singularity inspect --json --runscript nginx.sif
   
   If you want to list the applications (apps) installed in a container (located at
   /scif/apps) you should run inspect command with --list-apps <container-image> flag.
@@ -1043,7 +1425,8 @@ Enterprise Performance Computing (EPC)`
 	OverlayUse   string = `overlay`
 	OverlayShort string = `Manage an EXT3 writable overlay image`
 	OverlayLong  string = `
-  The overlay command allows management of EXT3 writable overlay images.`
+  The overlay command allows management of EXT3 writable overlay images, and
+  folding the changes recorded in a writable overlay into a new SIF image.`
 	OverlayExample string = `
   All overlay commands have their own help output:
 
@@ -1061,6 +1444,73 @@ Enterprise Performance Computing (EPC)`
 
   To create a single EXT3 writable overlay image:
   $ singularity overlay create --size 1024 /tmp/my_overlay.img`
+
+	OverlayCommitUse   string = `commit <options> image overlay`
+	OverlayCommitShort string = `Fold the changes of a writable overlay into a new SIF image`
+	OverlayCommitLong  string = `
+  The overlay commit command creates a new SIF image made of the root filesystem
+  of an image with the changes recorded in a writable overlay applied. Files
+  removed through the overlay are removed from the new image, and directories
+  replaced through the overlay replace the original ones.
+
+  The overlay can be an overlay directory, or an EXT3 overlay image, standalone
+  or embedded in a SIF image. EXT3 overlay images are mounted to read their
+  content, so they can only be committed by root. Ownership of the overlay
+  files is only preserved when run by root.`
+	OverlayCommitExample string = `
+  To save the changes made while running with a writable overlay:
+  $ singularity run --overlay rw.img image.sif
+  $ sudo singularity overlay commit image.sif rw.img -o new.sif
+
+  With an overlay directory:
+  $ singularity run --overlay my_overlay/ image.sif
+  $ singularity overlay commit image.sif my_overlay/ -o new.sif`
+)
+
+// Documentation for volume command group.
+const (
+	VolumeUse   string = `volume <subcommand>`
+	VolumeShort string = `Manage named volumes`
+	VolumeLong  string = `
+  The volume command allows management of named volumes. A named volume is a
+  host directory of the current user, stored in the volume root directory
+  ($HOME/.singularity/volumes if SINGULARITY_VOLUMEDIR is not set), that is
+  mounted in a container by name with the --volume option of action and
+  instance start commands. Volume directories are only accessible by their
+  owner and only the volumes owned by the current user are used.`
+	VolumeExample string = `
+  All volume commands have their own help output:
+
+  $ singularity help volume create
+  $ singularity volume create --help`
+
+	VolumeCreateUse   string = `create <name>`
+	VolumeCreateShort string = `Create a named volume`
+	VolumeCreateLong  string = `
+  The volume create command creates an empty named volume. Volume names start
+  with an alphanumeric character followed by alphanumeric characters, '_',
+  '.' or '-'.`
+	VolumeCreateExample string = `
+  $ singularity volume create data
+  $ singularity exec --volume data:/data image.sif touch /data/file
+
+  To mount the volume read-only:
+  $ singularity exec --volume data:/data:ro image.sif ls /data`
+
+	VolumeListUse   string = `list`
+	VolumeListShort string = `List named volumes`
+	VolumeListLong  string = `
+  The volume list command lists the named volumes of the current user with
+  the host directory backing them.`
+	VolumeListExample string = `
+  $ singularity volume list`
+
+	VolumeRemoveUse   string = `remove <name> [<name>...]`
+	VolumeRemoveShort string = `Remove named volumes`
+	VolumeRemoveLong  string = `
+  The volume remove command removes named volumes along with their content.`
+	VolumeRemoveExample string = `
+  $ singularity volume remove data`
 )
 
 // Documentation for sif/siftool command.
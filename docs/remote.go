@@ -136,9 +136,17 @@ const (
   and reports the availability of services and their versions. If no endpoint is
   specified, it will check the status of the default remote (SylabsCloud). If you
   have logged in with an authentication token the validity of that token will be
-  checked.`
+  checked. With --verbose, the round-trip latency and TLS details (protocol
+  version, cipher suite and server certificate) of each service are reported.
+  With --json, the status is printed in JSON format.`
 	RemoteStatusExample string = `
-  $ singularity remote status SylabsCloud`
+  $ singularity remote status SylabsCloud
+
+  To report latency and TLS details of the default remote services
+  $ singularity remote status --verbose
+
+  To get the status in JSON format
+  $ singularity remote status --json SylabsCloud`
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// remote add-keyserver command
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -147,8 +155,12 @@ const (
 	RemoteAddKeyserverLong  string = `
   The 'remote add-keyserver' command allows to define additional keyserver. The --order
   option can define the order of the keyserver for all related key operations, therefore
-  when specifying '--order 1' the keyserver is becoming the primary keyserver. If no endpoint
-  is specified, it will use the default remote endpoint (SylabsCloud).`
+  when specifying '--order 1' the keyserver is becoming the primary keyserver. Key search,
+  pull and verification operations query the configured keyservers in order until one of
+  them succeeds, key push operations use the primary keyserver unless another
+  configured keyserver is selected with 'key push --url'. The configured
+  keyservers are displayed by 'remote list'. If no endpoint is specified, it will use the
+  default remote endpoint (SylabsCloud).`
 	RemoteAddKeyserverExample string = `
   $ singularity remote add-keyserver https://keys.example.com
 
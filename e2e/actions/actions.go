@@ -210,11 +210,21 @@ func (c actionTests) actionExec(t *testing.T) {
 			argv: []string{"--workdir", testdata, "--contain", c.env.ImagePath, "test", "-f", tmpfilePath},
 			exit: 0,
 		},
+		{
+			name: "WorkdirWritableTmpfs",
+			argv: []string{"--workdir", testdata, "--writable-tmpfs", c.env.ImagePath, "touch", "/workdir-tmpfs"},
+			exit: 0,
+		},
 		{
 			name: "PwdGood",
 			argv: []string{"--pwd", "/etc", c.env.ImagePath, "true"},
 			exit: 0,
 		},
+		{
+			name: "PwdMissingReadOnly",
+			argv: []string{"--pwd", "/pwd-missing", c.env.ImagePath, "true"},
+			exit: 255,
+		},
 		{
 			name: "Home",
 			argv: []string{"--home", testdata, c.env.ImagePath, "test", "-f", tmpfile.Name()},
@@ -442,6 +452,13 @@ func (c actionTests) STDPipe(t *testing.T) {
 			output:  "/etc",
 			exit:    0,
 		},
+		{
+			name:    "PwdMissingWritableTmpfs",
+			command: "exec",
+			argv:    []string{"--writable-tmpfs", "--pwd", "/pwd-missing/dir", c.env.ImagePath, "pwd"},
+			output:  "/pwd-missing/dir",
+			exit:    0,
+		},
 		{
 			name:    "Arguments",
 			command: "run",
@@ -692,6 +709,52 @@ func (c actionTests) RunFromURI(t *testing.T) {
 	}
 }
 
+// RunFromURICache checks that running an OCI image URI converts it to SIF
+// once, and reuses the cached SIF image on the following runs unless the
+// cache is disabled.
+func (c actionTests) RunFromURICache(t *testing.T) {
+	e2e.EnsureRegistry(t)
+
+	cacheDir, cleanCache := e2e.MakeCacheDir(t, "")
+	defer cleanCache(t)
+	c.env.ImgCacheDir = cacheDir
+
+	imageURI := "docker://" + c.env.TestRegistry + "/my-busybox"
+
+	tests := []struct {
+		name    string
+		argv    []string
+		matchFn e2e.SingularityCmdResultOp
+	}{
+		{
+			name:    "FirstRun",
+			argv:    []string{"--no-https", imageURI, "true"},
+			matchFn: e2e.ExpectError(e2e.ContainMatch, "Converting OCI blobs to SIF format"),
+		},
+		{
+			name:    "SecondRun",
+			argv:    []string{"--no-https", imageURI, "true"},
+			matchFn: e2e.ExpectError(e2e.UnwantedContainMatch, "Converting OCI blobs to SIF format"),
+		},
+		{
+			name:    "DisableCache",
+			argv:    []string{"--no-https", "--disable-cache", imageURI, "true"},
+			matchFn: e2e.ExpectError(e2e.ContainMatch, "Converting OCI blobs to SIF format"),
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(tt.argv...),
+			e2e.ExpectExit(0, tt.matchFn),
+		)
+	}
+}
+
 // PersistentOverlay test the --overlay function
 func (c actionTests) PersistentOverlay(t *testing.T) {
 	e2e.EnsureImage(t, c.env)
@@ -1051,6 +1114,21 @@ func (c actionTests) actionNetwork(t *testing.T) {
 			e2e.ExpectExit(tt.expectExit),
 		)
 	}
+
+	// --network none implies --net, only the loopback interface is present
+	for _, profile := range []e2e.Profile{e2e.UserProfile, e2e.UserNamespaceProfile} {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest("NoneNetworkWithoutNet/"+profile.String()),
+			e2e.WithProfile(profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs("--network", "none", c.env.ImagePath, "cat", "/proc/net/dev"),
+			e2e.ExpectExit(
+				0,
+				e2e.ExpectOutput(e2e.RegexMatch, `\A[^\n]*\n[^\n]*\n\s*lo:[^\n]*\n?\z`),
+			),
+		)
+	}
 }
 
 //nolint:maintidx
@@ -1137,6 +1215,7 @@ func (c actionTests) actionBinds(t *testing.T) {
 	tests := []struct {
 		name    string
 		args    []string
+		env     []string
 		postRun func(*testing.T)
 		exit    int
 	}{
@@ -1495,6 +1574,42 @@ func (c actionTests) actionBinds(t *testing.T) {
 			postRun: checkHostFile(filepath.Join(hostCanaryDir, "file3")),
 			exit:    0,
 		},
+		{
+			name: "EnvBindReadOnly",
+			args: []string{
+				sandbox,
+				"touch", "/canary/file4",
+			},
+			env:  []string{"SINGULARITY_BIND=" + canaryDirBind + ":ro"},
+			exit: 1,
+		},
+		{
+			name: "EnvMountReadOnly",
+			args: []string{
+				sandbox,
+				"touch", "/canary/file4",
+			},
+			env:  []string{"SINGULARITY_BIND=" + canaryDirMount + ",ro"},
+			exit: 1,
+		},
+		{
+			name: "EnvMountPropagation",
+			args: []string{
+				sandbox,
+				"test", "-f", contCanaryFile,
+			},
+			env:  []string{"SINGULARITY_BIND=" + canaryDirMount + ",bind-propagation=rslave"},
+			exit: 0,
+		},
+		{
+			name: "EnvMountInvalidPropagation",
+			args: []string{
+				sandbox,
+				"true",
+			},
+			env:  []string{"SINGULARITY_BIND=" + canaryDirMount + ",bind-propagation=bad"},
+			exit: 255,
+		},
 	}
 
 	for _, profile := range e2e.Profiles {
@@ -1509,6 +1624,7 @@ func (c actionTests) actionBinds(t *testing.T) {
 					e2e.WithProfile(profile),
 					e2e.WithCommand("exec"),
 					e2e.WithArgs(tt.args...),
+					e2e.WithEnv(tt.env),
 					e2e.PostRun(tt.postRun),
 					e2e.ExpectExit(tt.exit),
 				)
@@ -2129,6 +2245,123 @@ func (c actionTests) actionUmask(t *testing.T) {
 			e2e.ExpectOutput(e2e.ExactMatch, "0022"),
 		),
 	)
+
+	// Check the mode of the files created in the container, with and
+	// without fakeroot, as seen from the container
+	syscall.Umask(0o027)
+
+	createFile := "d=$(mktemp -d) && touch $d/file && stat -c %a $d/file; ret=$?; rm -rf $d; exit $ret"
+
+	tests := []struct {
+		name string
+		args []string
+		mode string
+	}{
+		{
+			name: "Inherited",
+			mode: "640",
+		},
+		{
+			name: "Umask",
+			args: []string{"--umask", "0077"},
+			mode: "600",
+		},
+		{
+			name: "NoUmask",
+			args: []string{"--no-umask"},
+			mode: "644",
+		},
+	}
+
+	for _, profile := range []e2e.Profile{e2e.UserProfile, e2e.FakerootProfile} {
+		for _, tt := range tests {
+			args := append([]string{}, tt.args...)
+			args = append(args, c.env.ImagePath, "sh", "-c", createFile)
+			c.env.RunSingularity(
+				t,
+				e2e.AsSubtest(profile.String()+"/"+tt.name),
+				e2e.WithProfile(profile),
+				e2e.WithDir(u.Dir),
+				e2e.WithCommand("exec"),
+				e2e.WithArgs(args...),
+				e2e.ExpectExit(
+					0,
+					e2e.ExpectOutput(e2e.ExactMatch, tt.mode),
+				),
+			)
+		}
+	}
+}
+
+// actionUser tests that --user runs the container process as a user and
+// group resolved in the container.
+func (c actionTests) actionUser(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	tests := []struct {
+		name    string
+		profile e2e.Profile
+		user    string
+		exit    int
+		ops     []e2e.SingularityCmdResultOp
+	}{
+		{
+			name:    "UserName",
+			profile: e2e.RootProfile,
+			user:    "daemon",
+			ops:     []e2e.SingularityCmdResultOp{e2e.ExpectOutput(e2e.ExactMatch, "2:2")},
+		},
+		{
+			name:    "UserNameGroupName",
+			profile: e2e.RootProfile,
+			user:    "nobody:daemon",
+			ops:     []e2e.SingularityCmdResultOp{e2e.ExpectOutput(e2e.ExactMatch, "65534:2")},
+		},
+		{
+			name:    "NumericUnknownUser",
+			profile: e2e.RootProfile,
+			user:    "12345",
+			ops:     []e2e.SingularityCmdResultOp{e2e.ExpectOutput(e2e.ExactMatch, "12345:12345")},
+		},
+		{
+			name:    "NumericUserGroup",
+			profile: e2e.RootProfile,
+			user:    "12345:54321",
+			ops:     []e2e.SingularityCmdResultOp{e2e.ExpectOutput(e2e.ExactMatch, "12345:54321")},
+		},
+		{
+			name:    "UnknownUser",
+			profile: e2e.RootProfile,
+			user:    "notexist",
+			exit:    255,
+			ops:     []e2e.SingularityCmdResultOp{e2e.ExpectError(e2e.ContainMatch, "user notexist not found")},
+		},
+		{
+			name:    "UnknownGroup",
+			profile: e2e.RootProfile,
+			user:    "daemon:notexist",
+			exit:    255,
+			ops:     []e2e.SingularityCmdResultOp{e2e.ExpectError(e2e.ContainMatch, "group notexist not found")},
+		},
+		{
+			name:    "Unprivileged",
+			profile: e2e.UserProfile,
+			user:    "daemon",
+			exit:    255,
+			ops:     []e2e.SingularityCmdResultOp{e2e.ExpectError(e2e.ContainMatch, "--user option requires root privileges")},
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(tt.profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs("--user", tt.user, c.env.ImagePath, "sh", "-c", "echo $(id -u):$(id -g)"),
+			e2e.ExpectExit(tt.exit, tt.ops...),
+		)
+	}
 }
 
 func (c actionTests) actionNoMount(t *testing.T) {
@@ -2324,6 +2557,230 @@ func (c actionTests) actionCompat(t *testing.T) {
 	}
 }
 
+// actionCommit tests that --commit saves the --writable-tmpfs
+// changes to a new image on successful exit only.
+func (c actionTests) actionCommit(t *testing.T) {
+	require.Filesystem(t, "overlay")
+	e2e.EnsureImage(t, c.env)
+
+	dir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "commit-", "")
+	defer e2e.Privileged(cleanup)(t)
+
+	added := filepath.Join(dir, "added.sif")
+	removed := filepath.Join(dir, "removed.sif")
+	failed := filepath.Join(dir, "failed.sif")
+
+	tests := []struct {
+		name     string
+		profile  e2e.Profile
+		args     []string
+		exitCode int
+	}{
+		{
+			name:    "AddFile",
+			profile: e2e.UserNamespaceProfile,
+			args:    []string{"--writable-tmpfs", "--commit", added, c.env.ImagePath, "touch", "/committed"},
+		},
+		{
+			name:    "AddedFile",
+			profile: e2e.UserProfile,
+			args:    []string{added, "test", "-f", "/committed"},
+		},
+		{
+			name:    "RemoveFile",
+			profile: e2e.RootProfile,
+			args:    []string{"--writable-tmpfs", "--commit", removed, added, "rm", "/committed"},
+		},
+		{
+			name:     "RemovedFile",
+			profile:  e2e.UserProfile,
+			args:     []string{removed, "test", "-e", "/committed"},
+			exitCode: 1,
+		},
+		{
+			name:     "ExitError",
+			profile:  e2e.UserNamespaceProfile,
+			args:     []string{"--writable-tmpfs", "--commit", failed, c.env.ImagePath, "sh", "-c", "touch /committed; exit 3"},
+			exitCode: 3,
+		},
+		{
+			name:     "ExistingImage",
+			profile:  e2e.UserProfile,
+			args:     []string{"--writable-tmpfs", "--commit", added, c.env.ImagePath, "true"},
+			exitCode: 255,
+		},
+		{
+			name:     "NoWritableTmpfs",
+			profile:  e2e.UserProfile,
+			args:     []string{"--commit", failed, c.env.ImagePath, "true"},
+			exitCode: 255,
+		},
+		{
+			name:     "Setuid",
+			profile:  e2e.UserProfile,
+			args:     []string{"--writable-tmpfs", "--commit", failed, c.env.ImagePath, "touch", "/committed"},
+			exitCode: 255,
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(tt.profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(tt.args...),
+			e2e.ExpectExit(tt.exitCode),
+		)
+	}
+
+	if _, err := os.Stat(failed); err == nil {
+		t.Errorf("image %s was committed after an error", failed)
+	}
+}
+
+// rootfsPropagation tests the --rootfs-propagation option by
+// reading the optional fields of the container root mount in mountinfo.
+func (c actionTests) rootfsPropagation(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	// prints the optional fields of the root mount line
+	rootMount := `sed -n 's/^[0-9]* [0-9]* [0-9:]* [^ ]* \/ [^ ]* \(.*\)- .*/[\1]/p' /proc/self/mountinfo`
+
+	tests := []struct {
+		name        string
+		propagation string
+		expect      e2e.SingularityCmdResultOp
+		exitCode    int
+	}{
+		{
+			name:        "Shared",
+			propagation: "shared",
+			expect:      e2e.ExpectOutput(e2e.ContainMatch, "shared:"),
+		},
+		{
+			name:        "Private",
+			propagation: "private",
+			expect:      e2e.ExpectOutput(e2e.ExactMatch, "[]"),
+		},
+		{
+			name:        "Invalid",
+			propagation: "rshared",
+			exitCode:    255,
+		},
+	}
+
+	for _, profile := range []e2e.Profile{e2e.UserProfile, e2e.RootProfile} {
+		t.Run(profile.String(), func(t *testing.T) {
+			for _, tt := range tests {
+				var ops []e2e.SingularityCmdResultOp
+				if tt.expect != nil {
+					ops = append(ops, tt.expect)
+				}
+				c.env.RunSingularity(
+					t,
+					e2e.AsSubtest(tt.name),
+					e2e.WithProfile(profile),
+					e2e.WithCommand("exec"),
+					e2e.WithArgs("--rootfs-propagation", tt.propagation, c.env.ImagePath, "sh", "-c", rootMount),
+					e2e.ExpectExit(tt.exitCode, ops...),
+				)
+			}
+		})
+	}
+}
+
+// bindOverlay tests binds with the overlay and overlay-upper options, which
+// make a host directory writable in the container without modifying it.
+func (c actionTests) bindOverlay(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	for _, profile := range []e2e.Profile{e2e.UserProfile, e2e.UserNamespaceProfile, e2e.RootProfile} {
+		// a host directory overlay is mounted as root in setuid mode, so it
+		// requires root or a user namespace
+		refused := profile.String() == e2e.UserProfile.String()
+
+		t.Run(profile.String(), func(t *testing.T) {
+			workspace, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "bind-overlay-", "")
+			defer e2e.Privileged(cleanup)
+
+			source := filepath.Join(workspace, "source")
+			if err := os.Mkdir(source, 0o755); err != nil {
+				t.Fatalf("failed to create source directory: %s", err)
+			}
+			if err := ioutil.WriteFile(filepath.Join(source, "file"), []byte("reference\n"), 0o644); err != nil {
+				t.Fatalf("failed to write source file: %s", err)
+			}
+			// the source directory is read-only for the user
+			if err := os.Chmod(source, 0o555); err != nil {
+				t.Fatalf("failed to change source directory permissions: %s", err)
+			}
+			defer os.Chmod(source, 0o755)
+			upperDir := filepath.Join(workspace, "changes")
+
+			checkSource := func(t *testing.T) {
+				b, err := ioutil.ReadFile(filepath.Join(source, "file"))
+				if err != nil || string(b) != "reference\n" {
+					t.Errorf("source file modified: %q (%v)", b, err)
+				}
+				if _, err := os.Stat(filepath.Join(source, "new")); !os.IsNotExist(err) {
+					t.Errorf("file created in source directory")
+				}
+			}
+
+			write := "echo modified > /data/file && echo new > /data/new && cat /data/file /data/new"
+
+			tests := []struct {
+				name    string
+				bind    string
+				postRun func(*testing.T)
+				exit    int
+			}{
+				{
+					name:    "Tmpfs",
+					bind:    source + ":/data:overlay",
+					postRun: checkSource,
+				},
+				{
+					name: "UpperDir",
+					bind: source + ":/data:overlay-upper=" + upperDir,
+					postRun: func(t *testing.T) {
+						checkSource(t)
+						if _, err := os.Stat(filepath.Join(upperDir, "upper", "new")); err != nil {
+							t.Errorf("file not stored in upper directory: %s", err)
+						}
+					},
+				},
+				{
+					name: "ReadOnly",
+					bind: source + ":/data:ro,overlay",
+					exit: 255,
+				},
+			}
+
+			for _, tt := range tests {
+				exit, postRun := tt.exit, tt.postRun
+				if refused {
+					exit, postRun = 255, checkSource
+				}
+				var ops []e2e.SingularityCmdResultOp
+				if exit == 0 {
+					ops = append(ops, e2e.ExpectOutput(e2e.ExactMatch, "modified\nnew"))
+				}
+				c.env.RunSingularity(
+					t,
+					e2e.AsSubtest(tt.name),
+					e2e.WithProfile(profile),
+					e2e.WithCommand("exec"),
+					e2e.WithArgs("--bind", tt.bind, c.env.ImagePath, "sh", "-c", write),
+					e2e.PostRun(postRun),
+					e2e.ExpectExit(exit, ops...),
+				)
+			}
+		})
+	}
+}
+
 // E2ETests is the main func to trigger the test suite
 func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	c := actionTests{
@@ -2334,6 +2791,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 
 	return testhelper.Tests{
 		"action URI":            c.RunFromURI,          // action_URI
+		"action URI cache":      c.RunFromURICache,     // action_URI cache reuse
 		"exec":                  c.actionExec,          // singularity exec
 		"persistent overlay":    c.PersistentOverlay,   // Persistent Overlay
 		"run":                   c.actionRun,           // singularity run
@@ -2364,7 +2822,11 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 		"bind image":            c.bindImage,           // test bind image with --bind and --mount
 		"umask":                 c.actionUmask,         // test umask propagation
 		"no-mount":              c.actionNoMount,       // test --no-mount
+		"user":                  c.actionUser,          // test --user
 		"compat":                c.actionCompat,        // test --compat
+		"commit":                c.actionCommit,        // test --commit
+		"rootfs propagation":    c.rootfsPropagation,   // test --rootfs-propagation
+		"bind overlay":          c.bindOverlay,         // test --bind with overlay options
 		"invalidRemote":         np(c.invalidRemote),   // GHSA-5mv9-q7fq-9394
 	}
 }
@@ -6,6 +6,9 @@
 package cache
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
 	"path"
 	"path/filepath"
 	"testing"
@@ -254,6 +257,202 @@ func (c cacheTests) testInteractiveCacheCmds(t *testing.T) {
 	}
 }
 
+// testCacheDirIsolation checks that --cachedir overrides the cache location
+// set by the environment, and that commands using different cache
+// directories don't share their cache.
+func (c cacheTests) testCacheDirIsolation(t *testing.T) {
+	envCacheDir, cleanEnvCache := e2e.MakeCacheDir(t, "")
+	defer cleanEnvCache(t)
+	cacheDirA, cleanCacheA := e2e.MakeCacheDir(t, "")
+	defer cleanCacheA(t)
+	cacheDirB, cleanCacheB := e2e.MakeCacheDir(t, "")
+	defer cleanCacheB(t)
+
+	tempDir, imgStoreCleanup := e2e.MakeTempDir(t, "", "", "image store")
+	defer imgStoreCleanup(t)
+	imagePath := filepath.Join(tempDir, imgName)
+
+	c.env.ImgCacheDir = envCacheDir
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("pull A"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("pull"),
+		e2e.WithArgs("--force", "--cachedir", cacheDirA, imagePath, imgURL),
+		e2e.ExpectExit(0),
+	)
+	ensureCached(t, "pull A", imagePath, cacheDirA)
+	ensureNotCached(t, "pull A", imagePath, cacheDirB)
+	ensureNotCached(t, "pull A", imagePath, envCacheDir)
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("pull B"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("pull"),
+		e2e.WithArgs("--force", "--cachedir", cacheDirB, imagePath, imgURL),
+		e2e.ExpectExit(0),
+	)
+	ensureCached(t, "pull B", imagePath, cacheDirB)
+	ensureNotCached(t, "pull B", imagePath, envCacheDir)
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("clean A"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("cache clean"),
+		e2e.WithArgs("--force", "--cachedir", cacheDirA),
+		e2e.ExpectExit(0),
+	)
+	ensureNotCached(t, "clean A", imagePath, cacheDirA)
+	ensureCached(t, "clean A", imagePath, cacheDirB)
+}
+
+// testBuildDisableCache checks that a build with --disable-cache doesn't read
+// the cache, by corrupting every cached file before building again.
+func (c cacheTests) testBuildDisableCache(t *testing.T) {
+	cacheDir, cleanCache := e2e.MakeCacheDir(t, "")
+	defer cleanCache(t)
+	c.env.ImgCacheDir = cacheDir
+
+	tempDir, imgStoreCleanup := e2e.MakeTempDir(t, "", "", "image store")
+	defer imgStoreCleanup(t)
+	imagePath := filepath.Join(tempDir, imgName)
+
+	sources := []struct {
+		name string
+		uri  string
+	}{
+		{"library", imgURL},
+		{"oci", "docker://" + c.env.TestRegistry + "/my-busybox"},
+	}
+
+	for _, src := range sources {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest("populate "+src.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("build"),
+			e2e.WithArgs("--force", "--no-https", imagePath, src.uri),
+			e2e.ExpectExit(0),
+		)
+	}
+
+	corrupted := 0
+	err := filepath.Walk(filepath.Join(cacheDir, "cache"), func(path string, fi os.FileInfo, err error) error {
+		if err != nil || !fi.Mode().IsRegular() {
+			return err
+		}
+		corrupted++
+		return ioutil.WriteFile(path, []byte("corrupted cache entry"), fi.Mode().Perm())
+	})
+	if err != nil {
+		t.Fatalf("while corrupting cache: %s", err)
+	}
+	if corrupted == 0 {
+		t.Fatalf("no cache entries found in %s", cacheDir)
+	}
+
+	for _, src := range sources {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest("disable cache "+src.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("build"),
+			e2e.WithArgs("--force", "--no-https", "--disable-cache", imagePath, src.uri),
+			e2e.ExpectExit(0),
+		)
+	}
+}
+
+// testCleanDryRunJSON checks that 'cache clean --dry-run --json' reports
+// the cached image with its size without removing it, and that the
+// actual cleanup reports the same entry.
+func (c cacheTests) testCleanDryRunJSON(t *testing.T) {
+	cacheDir, cleanCache := e2e.MakeCacheDir(t, "")
+	defer cleanCache(t)
+	c.env.ImgCacheDir = cacheDir
+
+	tempDir, imgStoreCleanup := e2e.MakeTempDir(t, "", "", "image store")
+	defer imgStoreCleanup(t)
+	imagePath := filepath.Join(tempDir, imgName)
+
+	prepTest(t, c.env, "clean dry-run json", cacheDir, imagePath)
+
+	shasum, err := client.ImageHash(imagePath)
+	if err != nil {
+		t.Fatalf("couldn't compute hash of image %s: %v", imagePath, err)
+	}
+	fi, err := os.Stat(imagePath)
+	if err != nil {
+		t.Fatalf("couldn't stat image %s: %v", imagePath, err)
+	}
+
+	checkResult := func(dryRun bool) e2e.SingularityCmdResultOp {
+		return func(t *testing.T, r *e2e.SingularityCmdResult) {
+			var res struct {
+				DryRun  bool                 `json:"dryRun"`
+				Entries []cache.RemovedEntry `json:"entries"`
+				Size    int64                `json:"size"`
+			}
+			if err := json.Unmarshal(r.Stdout, &res); err != nil {
+				t.Fatalf("while decoding JSON output %q: %s", r.Stdout, err)
+			}
+			if res.DryRun != dryRun {
+				t.Errorf("got dryRun %v, want %v", res.DryRun, dryRun)
+			}
+			var size int64
+			found := false
+			for _, e := range res.Entries {
+				size += e.Size
+				if e.Type == cache.LibraryCacheType && e.Name == shasum {
+					found = true
+					if e.Size != fi.Size() {
+						t.Errorf("got size %d for %s, want %d", e.Size, imgName, fi.Size())
+					}
+				}
+			}
+			if !found {
+				t.Errorf("library cache entry %s not reported in %s", shasum, r.Stdout)
+			}
+			if res.Size != size {
+				t.Errorf("got total size %d, want %d", res.Size, size)
+			}
+		}
+	}
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("dry run"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("cache clean"),
+		e2e.WithArgs("--dry-run", "--json"),
+		e2e.ExpectExit(0, checkResult(true)),
+	)
+	ensureCached(t, "dry run", imagePath, cacheDir)
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("no prompt answer"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("cache clean"),
+		e2e.WithArgs("--json"),
+		e2e.ExpectExit(255),
+	)
+	ensureCached(t, "no prompt answer", imagePath, cacheDir)
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("force"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("cache clean"),
+		e2e.WithArgs("--force", "--json"),
+		e2e.ExpectExit(0, checkResult(false)),
+	)
+	ensureNotCached(t, "force", imagePath, cacheDir)
+}
+
 // ensureNotCached checks the entry related to an image is not in the cache
 func ensureNotCached(t *testing.T, testName string, imagePath string, cacheParentDir string) {
 	shasum, err := client.ImageHash(imagePath)
@@ -297,6 +496,9 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	return testhelper.Tests{
 		"interactive commands":     np(c.testInteractiveCacheCmds),
 		"non-interactive commands": np(c.testNoninteractiveCacheCmds),
+		"cachedir isolation":       np(c.testCacheDirIsolation),
+		"build disable cache":      np(c.testBuildDisableCache),
+		"clean dry-run json":       np(c.testCleanDryRunJSON),
 		"issue5097":                np(c.issue5097),
 		"issue5350":                np(c.issue5350),
 	}
@@ -434,6 +434,207 @@ func (c ctx) singularityEnvFile(t *testing.T) {
 	}
 }
 
+// singularityEnvJSON checks that --env-json variables take precedence over
+// --env-file variables, that --env variables take precedence over them, and
+// that their values are not evaluated by the shell.
+func (c ctx) singularityEnvJSON(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	dir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "envjson-", "")
+	defer cleanup(t)
+	envFile := filepath.Join(dir, "env.file")
+	if err := ioutil.WriteFile(envFile, []byte("FOO=file\nBAR=file\n"), 0o644); err != nil {
+		t.Fatalf("could not write %s: %s", envFile, err)
+	}
+	jsonFile := filepath.Join(dir, "env.json")
+	if err := ioutil.WriteFile(jsonFile, []byte(`{"FOO": "json file"}`), 0o644); err != nil {
+		t.Fatalf("could not write %s: %s", jsonFile, err)
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		exit     int
+		matchVal string
+	}{
+		{
+			name:     "JSON",
+			args:     []string{"--env-json", `{"FOO": "json", "BAR": "bar"}`},
+			matchVal: "json bar",
+		},
+		{
+			name:     "JSONFile",
+			args:     []string{"--env-json", jsonFile},
+			matchVal: "json file",
+		},
+		{
+			name:     "JSONOverEnvFile",
+			args:     []string{"--env-file", envFile, "--env-json", `{"FOO": "json"}`},
+			matchVal: "json file",
+		},
+		{
+			name:     "EnvOverJSON",
+			args:     []string{"--env", "FOO=env", "--env-json", `{"FOO": "json", "BAR": "json"}`},
+			matchVal: "env json",
+		},
+		{
+			name:     "LiteralValues",
+			args:     []string{"--env-json", `{"FOO": "$HOME ` + "`id`" + ` $(id)", "BAR": "end\\"}`},
+			matchVal: "$HOME `id` $(id) end\\",
+		},
+		{
+			name: "NonStringValue",
+			args: []string{"--env-json", `{"FOO": 1}`},
+			exit: 255,
+		},
+		{
+			name: "InvalidJSON",
+			args: []string{"--env-json", `{"FOO": "json"`},
+			exit: 255,
+		},
+	}
+
+	for _, tt := range tests {
+		// printf doesn't interpret backslashes of the values like echo
+		args := append(tt.args, c.env.ImagePath, "/bin/sh", "-c", `printf '%s\n' "$FOO${BAR:+ $BAR}"`)
+
+		expect := e2e.ExpectOutput(e2e.ExactMatch, tt.matchVal)
+		if tt.exit != 0 {
+			expect = e2e.ExpectError(e2e.ContainMatch, "--env-json")
+		}
+
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(args...),
+			e2e.ExpectExit(tt.exit, expect),
+		)
+	}
+}
+
+// singularityAppEnv checks that the environment of an app, defined by its
+// %appenv and %appinstall sections, is isolated from the other apps.
+func (c ctx) singularityAppEnv(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	imageDir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "appenv-", "")
+	defer cleanup(t)
+	imagePath := filepath.Join(imageDir, "container")
+
+	def := `Bootstrap: localimage
+From: ` + c.env.ImagePath + `
+
+%appenv foo
+	export APPVAR=foo
+	export FOO_ONLY=foo
+
+%appinstall foo
+	export INSTALLVAR=foo
+	echo "$INSTALLVAR" > installvar
+
+%appenv bar
+	export APPVAR=bar
+
+%appinstall bar
+	echo "${INSTALLVAR:-}" > installvar
+
+%apprun bar
+	echo "$APPVAR ${FOO_ONLY:-unset}"
+`
+	defFile, err := e2e.WriteTempFile(imageDir, "deffile", def)
+	if err != nil {
+		t.Fatalf("Unable to create test definition file: %v", err)
+	}
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("build"),
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("build"),
+		e2e.WithArgs(imagePath, defFile),
+		e2e.ExpectExit(0),
+	)
+
+	tests := []struct {
+		name     string
+		command  string
+		app      string
+		args     []string
+		matchVal string
+	}{
+		{
+			name:     "foo APPVAR",
+			command:  "exec",
+			app:      "foo",
+			args:     []string{"/bin/sh", "-c", "echo $APPVAR"},
+			matchVal: "foo",
+		},
+		{
+			name:     "bar APPVAR",
+			command:  "exec",
+			app:      "bar",
+			args:     []string{"/bin/sh", "-c", "echo $APPVAR"},
+			matchVal: "bar",
+		},
+		{
+			name:     "bar FOO_ONLY",
+			command:  "exec",
+			app:      "bar",
+			args:     []string{"/bin/sh", "-c", "echo ${FOO_ONLY:-unset}"},
+			matchVal: "unset",
+		},
+		{
+			name:     "bar run",
+			command:  "run",
+			app:      "bar",
+			matchVal: "bar unset",
+		},
+		{
+			name:     "no app FOO_ONLY",
+			command:  "exec",
+			args:     []string{"/bin/sh", "-c", "echo ${FOO_ONLY:-unset}"},
+			matchVal: "unset",
+		},
+		{
+			name:     "foo install",
+			command:  "exec",
+			app:      "foo",
+			args:     []string{"cat", "/scif/apps/foo/installvar"},
+			matchVal: "foo",
+		},
+		{
+			name:     "bar install",
+			command:  "exec",
+			app:      "bar",
+			args:     []string{"cat", "/scif/apps/bar/installvar"},
+			matchVal: "",
+		},
+	}
+
+	for _, tt := range tests {
+		args := []string{}
+		if tt.app != "" {
+			args = append(args, "--app", tt.app)
+		}
+		args = append(args, imagePath)
+		args = append(args, tt.args...)
+
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand(tt.command),
+			e2e.WithArgs(args...),
+			e2e.ExpectExit(
+				0,
+				e2e.ExpectOutput(e2e.ExactMatch, tt.matchVal),
+			),
+		)
+	}
+}
+
 // E2ETests is the main func to trigger the test suite
 func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	c := ctx{
@@ -444,6 +645,8 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 		"environment manipulation": c.singularityEnv,
 		"environment option":       c.singularityEnvOption,
 		"environment file":         c.singularityEnvFile,
+		"environment JSON":         c.singularityEnvJSON,
+		"app environment":          c.singularityAppEnv,
 		"issue 5057":               c.issue5057, // https://github.com/sylabs/hpcng/issues/5057
 		"issue 5426":               c.issue5426, // https://github.com/sylabs/hpcng/issues/5426
 		"issue 43":                 c.issue43,   // https://github.com/sylabs/singularity/issues/43
@@ -102,6 +102,84 @@ func (c ctx) testNvidiaLegacy(t *testing.T) {
 	}
 }
 
+func (c ctx) testNvidiaLegacySandbox(t *testing.T) {
+	require.Nvidia(t)
+	// Use Ubuntu 20.04 as this is a recent distro officially supported by Nvidia CUDA.
+	// We can't use our test image as it's alpine based and we need a compatible glibc.
+	imageURL := "docker://ubuntu:20.04"
+
+	tmpdir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "nvidia-legacy-sandbox", "run sandbox with nvidia")
+	defer cleanup(t)
+
+	sandboxImage := filepath.Join(tmpdir, "sandbox")
+
+	c.env.RunSingularity(
+		t,
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("build"),
+		e2e.WithArgs("--force", "--sandbox", sandboxImage, imageURL),
+		e2e.ExpectExit(0),
+	)
+
+	// nvidia-smi must be bound in as with a SIF image, including in a
+	// writable sandbox where its destination is created then removed,
+	// except in setuid mode where missing destinations are not created
+	tests := []struct {
+		name    string
+		profile e2e.Profile
+		args    []string
+		exit    int
+	}{
+		{
+			name:    "User",
+			profile: e2e.UserProfile,
+			args:    []string{"--nv", sandboxImage, "nvidia-smi"},
+		},
+		{
+			name:    "UserContain",
+			profile: e2e.UserProfile,
+			args:    []string{"--contain", "--nv", sandboxImage, "nvidia-smi"},
+		},
+		{
+			name:    "UserWritable",
+			profile: e2e.UserProfile,
+			args:    []string{"--writable", "--nv", sandboxImage, "nvidia-smi"},
+			exit:    255,
+		},
+		{
+			name:    "UserNamespaceWritable",
+			profile: e2e.UserNamespaceProfile,
+			args:    []string{"--writable", "--nv", sandboxImage, "nvidia-smi"},
+		},
+		{
+			name:    "FakerootWritable",
+			profile: e2e.FakerootProfile,
+			args:    []string{"--writable", "--nv", sandboxImage, "nvidia-smi"},
+		},
+		{
+			name:    "RootWritable",
+			profile: e2e.RootProfile,
+			args:    []string{"--writable", "--nv", sandboxImage, "nvidia-smi"},
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(tt.profile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(tt.args...),
+			e2e.PostRun(func(t *testing.T) {
+				if _, err := os.Lstat(filepath.Join(sandboxImage, "usr", "bin", "nvidia-smi")); !os.IsNotExist(err) {
+					t.Errorf("nvidia-smi left in sandbox after exec: %v", err)
+				}
+			}),
+			e2e.ExpectExit(tt.exit),
+		)
+	}
+}
+
 func (c ctx) testNvCCLI(t *testing.T) {
 	require.Nvidia(t)
 	require.NvCCLI(t)
@@ -524,11 +602,12 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	}
 
 	return testhelper.Tests{
-		"nvidia":       c.testNvidiaLegacy,
-		"nvccli":       c.testNvCCLI,
-		"rocm":         c.testRocm,
-		"build nvidia": c.testBuildNvidiaLegacy,
-		"build nvccli": c.testBuildNvCCLI,
-		"build rocm":   c.testBuildRocm,
+		"nvidia":         c.testNvidiaLegacy,
+		"nvidia sandbox": c.testNvidiaLegacySandbox,
+		"nvccli":         c.testNvCCLI,
+		"rocm":           c.testRocm,
+		"build nvidia":   c.testBuildNvidiaLegacy,
+		"build nvccli":   c.testBuildNvCCLI,
+		"build rocm":     c.testBuildRocm,
 	}
 }
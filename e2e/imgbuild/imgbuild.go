@@ -6,6 +6,7 @@
 package imgbuild
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
@@ -1472,6 +1473,158 @@ func (c imgBuildTests) buildLibraryHost(t *testing.T) {
 	)
 }
 
+// buildPrivateBaseImage checks that credentials are honored when building
+// from a base image stored in a private repository of the test registry.
+func (c imgBuildTests) buildPrivateBaseImage(t *testing.T) {
+	e2e.EnsureRegistry(t)
+
+	tmpdir, cleanup := c.tempDir(t, "build-private-base-test")
+	defer cleanup()
+
+	definition := fmt.Sprintf("Bootstrap: docker\nFrom: %s/private/e2e/busybox\n", c.env.TestRegistry)
+	defFile := e2e.RawDefFile(t, tmpdir, strings.NewReader(definition))
+	defer os.Remove(defFile)
+
+	authFile := filepath.Join(tmpdir, "auth.json")
+	creds := base64.StdEncoding.EncodeToString([]byte(e2e.DefaultUsername + ":" + e2e.DefaultPassword))
+	authConfig := fmt.Sprintf(`{"auths":{%q:{"auth":%q}}}`, c.env.TestRegistry, creds)
+	if err := os.WriteFile(authFile, []byte(authConfig), 0o600); err != nil {
+		t.Fatalf("while writing %s: %s", authFile, err)
+	}
+
+	imagePath := filepath.Join(tmpdir, "image")
+
+	tests := []struct {
+		name    string
+		args    []string
+		envs    []string
+		exit    int
+		errorOp e2e.SingularityCmdResultOp
+	}{
+		{
+			name: "NoCredentials",
+			args: []string{"--no-https", imagePath, defFile},
+			exit: 255,
+		},
+		{
+			name: "AuthFile",
+			args: []string{"--no-https", "--authfile", authFile, imagePath, defFile},
+			exit: 0,
+		},
+		{
+			name: "AuthFileNotFound",
+			args: []string{"--no-https", "--authfile", filepath.Join(tmpdir, "missing.json"), imagePath, defFile},
+			exit: 255,
+			errorOp: e2e.ExpectError(
+				e2e.ContainMatch,
+				"doesn't exist or is not a file",
+			),
+		},
+		{
+			name: "EnvCredentials",
+			args: []string{"--no-https", imagePath, defFile},
+			envs: []string{
+				"SINGULARITY_DOCKER_USERNAME=" + e2e.DefaultUsername,
+				"SINGULARITY_DOCKER_PASSWORD=" + e2e.DefaultPassword,
+			},
+			exit: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.RootProfile),
+			e2e.WithCommand("build"),
+			e2e.WithArgs(append([]string{"-F"}, tt.args...)...),
+			e2e.WithEnv(tt.envs),
+			e2e.ExpectExit(tt.exit, tt.errorOp),
+		)
+	}
+}
+
+// buildDockerfile checks that a Dockerfile is translated and built, and
+// that unsupported instructions are reported.
+func (c imgBuildTests) buildDockerfile(t *testing.T) {
+	tmpdir, cleanup := c.tempDir(t, "build-dockerfile-test")
+	defer cleanup()
+
+	if err := os.WriteFile(filepath.Join(tmpdir, "hello.txt"), []byte(testFileContent), 0o644); err != nil {
+		t.Fatalf("while writing build context file: %s", err)
+	}
+
+	dockerfile := `FROM busybox:1.31.1 AS builder
+WORKDIR /build
+COPY hello.txt .
+RUN cp hello.txt world.txt
+
+FROM busybox:1.31.1
+ENV GREETING="hello world"
+COPY --from=builder /build/world.txt /data/
+ENTRYPOINT ["/bin/echo"]
+CMD ["default"]
+`
+	if err := os.WriteFile(filepath.Join(tmpdir, "Dockerfile"), []byte(dockerfile), 0o644); err != nil {
+		t.Fatalf("while writing Dockerfile: %s", err)
+	}
+
+	imagePath := filepath.Join(tmpdir, "image-dockerfile")
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("build"),
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("build"),
+		e2e.WithArgs("--dockerfile", imagePath, tmpdir),
+		e2e.ExpectExit(0),
+	)
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("copy"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs(imagePath, "cat", "/data/world.txt"),
+		e2e.ExpectExit(0, e2e.ExpectOutput(e2e.ExactMatch, strings.TrimSpace(testFileContent))),
+	)
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("env"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs(imagePath, "/bin/sh", "-c", "echo $GREETING"),
+		e2e.ExpectExit(0, e2e.ExpectOutput(e2e.ExactMatch, "hello world")),
+	)
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("run default"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("run"),
+		e2e.WithArgs(imagePath),
+		e2e.ExpectExit(0, e2e.ExpectOutput(e2e.ExactMatch, "default")),
+	)
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("run args"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("run"),
+		e2e.WithArgs(imagePath, "custom"),
+		e2e.ExpectExit(0, e2e.ExpectOutput(e2e.ExactMatch, "custom")),
+	)
+
+	unsupported := filepath.Join(tmpdir, "Dockerfile.unsupported")
+	if err := os.WriteFile(unsupported, []byte("FROM busybox:1.31.1\nSHELL [\"/bin/ash\", \"-c\"]\n"), 0o644); err != nil {
+		t.Fatalf("while writing Dockerfile: %s", err)
+	}
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("unsupported instruction"),
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("build"),
+		e2e.WithArgs("-F", "--dockerfile", imagePath, unsupported),
+		e2e.ExpectExit(255, e2e.ExpectError(e2e.ContainMatch, "SHELL instruction is not supported")),
+	)
+}
+
 // E2ETests is the main func to trigger the test suite
 func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	c := imgBuildTests{
@@ -1480,6 +1633,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 
 	return testhelper.Tests{
 		"bad path":                        c.badPath,                   // try to build from a non existent path
+		"dockerfile":                      c.buildDockerfile,           // build from a Dockerfile
 		"build encrypt with PEM file":     c.buildEncryptPemFile,       // build encrypted images with certificate
 		"build encrypted with passphrase": c.buildEncryptPassphrase,    // build encrypted images with passphrase
 		"definition":                      c.buildDefinition,           // builds from definition template
@@ -1492,6 +1646,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 		"build with bind mount":           c.buildBindMount,            // build image with bind mount
 		"test with writable tmpfs":        c.testWritableTmpfs,         // build image, using writable tmpfs in the test step
 		"library host":                    c.buildLibraryHost,          // build image with hostname in library URI
+		"private base image":              c.buildPrivateBaseImage,     // build from a private registry image with credentials
 		"issue 3848":                      c.issue3848,                 // https://github.com/hpcng/singularity/issues/3848
 		"issue 4203":                      c.issue4203,                 // https://github.com/sylabs/singularity/issues/4203
 		"issue 4407":                      c.issue4407,                 // https://github.com/sylabs/singularity/issues/4407
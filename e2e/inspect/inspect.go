@@ -6,6 +6,8 @@
 package inspect
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"os"
@@ -165,6 +167,9 @@ func (c ctx) singularityInspect(t *testing.T) {
 				if v != out {
 					t.Errorf("unexpected runscript output, got %s instead of %s", v, out)
 				}
+				if p := meta.Attributes.RunscriptProcess; p != nil {
+					t.Errorf("unexpected runscript process %+v for a definition file image", p)
+				}
 			},
 		},
 		{
@@ -407,6 +412,166 @@ func (c ctx) singularityInspect(t *testing.T) {
 	)
 }
 
+// singularityInspectOCIRunscript checks the runscript process reported
+// for images built from an OCI image.
+func (c ctx) singularityInspectOCIRunscript(t *testing.T) {
+	testDir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "inspect-oci-", "")
+	defer e2e.Privileged(cleanup)(t)
+
+	sifImage := filepath.Join(testDir, "busybox.sif")
+	sandboxImage := filepath.Join(testDir, "busybox")
+
+	c.env.RunSingularity(
+		t,
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("build"),
+		e2e.WithArgs(sifImage, "docker://busybox:1.31.1"),
+		e2e.ExpectExit(0),
+	)
+	c.env.RunSingularity(
+		t,
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("build"),
+		e2e.WithArgs("--sandbox", sandboxImage, sifImage),
+		e2e.ExpectExit(0),
+	)
+
+	want := &inspect.Process{
+		Cmd:  []string{"sh"},
+		Args: []string{"sh"},
+	}
+	compareProcess := func(t *testing.T, r *e2e.SingularityCmdResult) {
+		meta := new(inspect.Metadata)
+		if err := json.Unmarshal(r.Stdout, meta); err != nil {
+			t.Fatalf("unable to parse json output: %s", err)
+		}
+		if got := meta.Attributes.RunscriptProcess; !reflect.DeepEqual(got, want) {
+			t.Errorf("unexpected runscript process, got %+v instead of %+v", got, want)
+		}
+	}
+
+	for name, img := range map[string]string{"SIF": sifImage, "Sandbox": sandboxImage} {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("inspect"),
+			e2e.WithArgs("--json", "--runscript", img),
+			e2e.ExpectExit(0, compareProcess),
+		)
+	}
+}
+
+// singularityInspectDigest checks the digest reported for a SIF image is
+// the sha256 of the image file.
+func (c ctx) singularityInspectDigest(t *testing.T) {
+	f, err := os.Open(c.env.ImagePath)
+	if err != nil {
+		t.Fatalf("while opening image: %s", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		t.Fatalf("while hashing image: %s", err)
+	}
+	want := "sha256." + hex.EncodeToString(h.Sum(nil))
+
+	testDir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "inspect-digest-", "")
+	defer cleanup(t)
+
+	tests := []struct {
+		name string
+		args []string
+		exit int
+		op   e2e.SingularityCmdResultOp
+	}{
+		{
+			name: "SIF",
+			args: []string{"--digest", c.env.ImagePath},
+			exit: 0,
+			op:   e2e.ExpectOutput(e2e.ExactMatch, want),
+		},
+		{
+			name: "Sandbox",
+			args: []string{"--digest", testDir},
+			exit: 255,
+			op:   e2e.ExpectError(e2e.ContainMatch, "is not a SIF image"),
+		},
+		{
+			name: "WithLabels",
+			args: []string{"--digest", "--labels", c.env.ImagePath},
+			exit: 255,
+			op:   e2e.ExpectError(e2e.ContainMatch, "cannot be combined with other inspect options"),
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("inspect"),
+			e2e.WithArgs(tt.args...),
+			e2e.ExpectExit(tt.exit, tt.op),
+		)
+	}
+}
+
+// singularityInspectSIFLayers checks the partitions listed for a SIF image.
+func (c ctx) singularityInspectSIFLayers(t *testing.T) {
+	testDir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "inspect-sif-layers-", "")
+	defer cleanup(t)
+
+	tests := []struct {
+		name string
+		args []string
+		exit int
+		op   e2e.SingularityCmdResultOp
+	}{
+		{
+			name: "SIF",
+			args: []string{"--sif-layers", c.env.ImagePath},
+			exit: 0,
+			op:   e2e.ExpectOutput(e2e.RegexMatch, `(?m)^\d+\s+primary system\s+squashfs\s+squashfs\s+\w+\s+\S+$`),
+		},
+		{
+			name: "JSON",
+			args: []string{"--sif-layers", "--json", c.env.ImagePath},
+			exit: 0,
+			op:   e2e.ExpectOutput(e2e.ContainMatch, `"partition": "primary system"`),
+		},
+		{
+			name: "JSONSchemaVersion",
+			args: []string{"--sif-layers", "--json", c.env.ImagePath},
+			exit: 0,
+			op:   e2e.ExpectOutput(e2e.ContainMatch, `"schemaVersion": "`+inspect.SchemaVersion+`"`),
+		},
+		{
+			name: "Sandbox",
+			args: []string{"--sif-layers", testDir},
+			exit: 255,
+			op:   e2e.ExpectError(e2e.ContainMatch, "is not a SIF image"),
+		},
+		{
+			name: "WithLabels",
+			args: []string{"--sif-layers", "--labels", c.env.ImagePath},
+			exit: 255,
+			op:   e2e.ExpectError(e2e.ContainMatch, "can only be combined with --json"),
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("inspect"),
+			e2e.WithArgs(tt.args...),
+			e2e.ExpectExit(tt.exit, tt.op),
+		)
+	}
+}
+
 // E2ETests is the main func to trigger the test suite
 func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	c := ctx{
@@ -414,6 +579,9 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	}
 
 	return testhelper.Tests{
-		"inspect command": c.singularityInspect,
+		"inspect command":       c.singularityInspect,
+		"inspect OCI runscript": c.singularityInspectOCIRunscript,
+		"inspect digest":        c.singularityInspectDigest,
+		"inspect SIF layers":    c.singularityInspectSIFLayers,
 	}
 }
@@ -308,6 +308,74 @@ func (c *ctx) testGhostInstance(t *testing.T) {
 	)
 }
 
+// Test that the environment flags apply to the instance, and to the
+// processes joining it unless they set the same variables.
+func (c *ctx) testEnvFlags(t *testing.T) {
+	const instanceName = "testenv"
+
+	dir, err := ioutil.TempDir(c.env.TestDir, "TestInstanceEnv")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	envFile := filepath.Join(dir, "env")
+	if err := ioutil.WriteFile(envFile, []byte("FILEVAR=file\nOVERVAR=file\n"), 0o644); err != nil {
+		t.Fatalf("Failed to create environment file: %v", err)
+	}
+
+	checkEnv := func(t *testing.T, stdout string, expected ...string) {
+		vars := strings.Split(stdout, "\n")
+		for _, e := range expected {
+			found := false
+			for _, v := range vars {
+				if v == e {
+					found = true
+					break
+				}
+			}
+			if !found {
+				t.Errorf("%s not found in instance environment:\n%s", e, stdout)
+			}
+		}
+	}
+
+	c.env.RunSingularity(
+		t,
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("instance start"),
+		e2e.WithArgs(
+			"--cleanenv",
+			"--env-file", envFile,
+			"--env", "STARTVAR=start,OVERVAR=env",
+			c.env.ImagePath,
+			instanceName,
+		),
+		e2e.PostRun(func(t *testing.T) {
+			if t.Failed() {
+				return
+			}
+
+			stdout, stderr, success := c.execInstance(t, instanceName, "env")
+			if success {
+				checkEnv(t, stdout, "FILEVAR=file", "OVERVAR=env", "STARTVAR=start")
+			}
+
+			c.env.RunSingularity(
+				t,
+				e2e.WithProfile(c.profile),
+				e2e.WithCommand("exec"),
+				e2e.WithArgs("--env", "STARTVAR=exec", "instance://"+instanceName, "env"),
+				e2e.ExpectExit(0, e2e.GetStreams(&stdout, &stderr)),
+			)
+			checkEnv(t, stdout, "FILEVAR=file", "OVERVAR=env", "STARTVAR=exec")
+
+			c.stopInstance(t, instanceName)
+		}),
+		e2e.ExpectExit(0),
+	)
+}
+
 // E2ETests is the main func to trigger the test suite
 func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	c := &ctx{
@@ -335,6 +403,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 				{"CreateManyInstances", c.testCreateManyInstances},
 				{"StopAll", c.testStopAll},
 				{"GhostInstance", c.testGhostInstance},
+				{"EnvFlags", c.testEnvFlags},
 			}
 
 			profiles := []e2e.Profile{
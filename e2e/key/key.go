@@ -166,6 +166,14 @@ func (c *ctx) singularityKeyNewpair(t *testing.T) {
 				"n",
 			},
 		},
+		{
+			name: "newpair batch",
+			args: []string{
+				"newpair", "--batch", "--bits", "2048",
+				"--name", "e2e test key", "--email", "westley@sylabs.io", "--password", "e2etests",
+			},
+			stdout: "NOT pushing newly created key to keystore",
+		},
 	}
 
 	for _, tt := range tests {
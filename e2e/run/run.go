@@ -113,7 +113,7 @@ func (c ctx) testRunPassphraseEncrypted(t *testing.T) {
 	// We create a temporary directory to store the image, making sure tests
 	// will not pollute each other
 	tempDir, cleanup := e2e.MakeTempDir(t, c.env.TestDir, "", "")
-	defer cleanup(t)
+	defer e2e.Privileged(cleanup)(t)
 
 	imgPath := filepath.Join(tempDir, "encrypted_cmdline_passphrase.sif")
 	cmdArgs := []string{"--encrypt", imgPath, "library://alpine:3.11.5"}
@@ -178,6 +178,41 @@ func (c ctx) testRunPassphraseEncrypted(t *testing.T) {
 		e2e.ExpectExit(0),
 	)
 
+	// Ensure a writable tmpfs overlay can be stacked on top of the
+	// decrypted root filesystem
+	cmdArgs = []string{"--writable-tmpfs", imgPath, "touch", "/canary"}
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("env var passphrase with writable tmpfs"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs(cmdArgs...),
+		e2e.WithEnv(append(os.Environ(), passphraseEnvVar)),
+		e2e.ExpectExit(0),
+	)
+
+	// Ensure a writable overlay directory can be stacked on top of
+	// the decrypted root filesystem
+	overlayDir, _ := e2e.MakeTempDir(t, tempDir, "overlay-", "")
+	cmdArgs = []string{"--overlay", overlayDir, imgPath, "touch", "/canary"}
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("env var passphrase with overlay"),
+		e2e.WithProfile(e2e.RootProfile),
+		e2e.WithCommand("exec"),
+		e2e.WithArgs(cmdArgs...),
+		e2e.WithEnv(append(os.Environ(), passphraseEnvVar)),
+		e2e.PostRun(func(t *testing.T) {
+			if t.Failed() {
+				return
+			}
+			if _, err := os.Stat(filepath.Join(overlayDir, "upper", "canary")); err != nil {
+				t.Errorf("canary file not found in overlay: %s", err)
+			}
+		}),
+		e2e.ExpectExit(0),
+	)
+
 	// Specifying the passphrase on the command line should always fail
 	cmdArgs = []string{"--passphrase", e2e.Passphrase, imgPath}
 	c.env.RunSingularity(
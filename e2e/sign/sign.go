@@ -201,6 +201,67 @@ func (c ctx) singularitySignKeyidxOption(t *testing.T) {
 	)
 }
 
+func (c ctx) singularitySignDetachedOption(t *testing.T) {
+	imgPath, cleanup := c.prepareImage(t)
+	defer cleanup(t)
+
+	sigPath := imgPath + ".sig"
+
+	c.env.KeyringDir = c.keyringDir
+	c.env.ImgCacheDir = c.imgCache
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("sign detached"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sign"),
+		e2e.WithArgs("--detached", sigPath, imgPath),
+		e2e.ConsoleRun(c.passphraseInput...),
+		e2e.ExpectExit(
+			0,
+			e2e.ExpectOutput(e2e.ContainMatch, "Detached signature of "+imgPath+" created at "+sigPath),
+		),
+	)
+
+	verify := func(name string) {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("verify"),
+			e2e.WithArgs("--local", "--detached", sigPath, imgPath),
+			e2e.ExpectExit(
+				0,
+				e2e.ExpectOutput(e2e.ContainMatch, "Container verified: "+imgPath),
+			),
+		)
+	}
+	verify("verify detached")
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("sign attached"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sign"),
+		e2e.WithArgs(imgPath),
+		e2e.ConsoleRun(c.passphraseInput...),
+		e2e.ExpectExit(0),
+	)
+	verify("verify detached after sign")
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("detached with group ID"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sign"),
+		e2e.WithArgs("--detached", sigPath, "--group-id", "1", imgPath),
+		e2e.ExpectExit(
+			255,
+			e2e.ExpectError(e2e.ContainMatch, "--group-id can't be used with --detached"),
+		),
+	)
+}
+
 func (c *ctx) generateKeypair(t *testing.T) {
 	keyGenInput := []e2e.SingularityConsoleOp{
 		e2e.ConsoleSendLine("e2e sign test key"),
@@ -264,6 +325,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 			t.Run("singularitySignIDOption", c.singularitySignIDOption)
 			t.Run("singularitySignGroupIDOption", c.singularitySignGroupIDOption)
 			t.Run("singularitySignKeyidxOption", c.singularitySignKeyidxOption)
+			t.Run("singularitySignDetachedOption", c.singularitySignDetachedOption)
 		},
 	}
 }
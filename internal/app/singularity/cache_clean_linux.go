@@ -16,11 +16,11 @@ import (
 
 var errInvalidCacheHandle = errors.New("invalid cache handle")
 
-// cleanCache cleans the given type of cache cacheType. It will return a
-// error if one occurs.
-func cleanCache(imgCache *cache.Handle, cacheType string, dryRun bool, days int) error {
+// cleanCache cleans the given type of cache cacheType. It will return the
+// removed entries, and a error if one occurs.
+func cleanCache(imgCache *cache.Handle, cacheType string, dryRun bool, days int) ([]cache.RemovedEntry, error) {
 	if imgCache == nil {
-		return fmt.Errorf("invalid image cache handle")
+		return nil, fmt.Errorf("invalid image cache handle")
 	}
 	return imgCache.CleanCache(cacheType, dryRun, days)
 }
@@ -30,10 +30,11 @@ func cleanCache(imgCache *cache.Handle, cacheType string, dryRun bool, days int)
 // provide a summary of what would have been done. If cacheCleanTypes
 // contains something, only clean that type. The special value "all" is
 // interpreted as "all types of entries". If cacheName contains
-// something, clean only cache entries matching that name.
-func CleanSingularityCache(imgCache *cache.Handle, dryRun bool, cacheCleanTypes []string, days int) error {
+// something, clean only cache entries matching that name. The entries
+// removed, or which would be removed in dry run mode, are returned.
+func CleanSingularityCache(imgCache *cache.Handle, dryRun bool, cacheCleanTypes []string, days int) ([]cache.RemovedEntry, error) {
 	if imgCache == nil {
-		return errInvalidCacheHandle
+		return nil, errInvalidCacheHandle
 	}
 
 	// Default is all caches
@@ -45,12 +46,15 @@ func CleanSingularityCache(imgCache *cache.Handle, dryRun bool, cacheCleanTypes
 		cachesToClean = cacheCleanTypes
 	}
 
+	var removed []cache.RemovedEntry
 	for _, cacheType := range cachesToClean {
 		sylog.Debugf("Cleaning %s cache...", cacheType)
-		if err := cleanCache(imgCache, cacheType, dryRun, days); err != nil {
-			return err
+		entries, err := cleanCache(imgCache, cacheType, dryRun, days)
+		removed = append(removed, entries...)
+		if err != nil {
+			return removed, err
 		}
 	}
 
-	return nil
+	return removed, nil
 }
@@ -0,0 +1,79 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/opencontainers/go-digest"
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// detachedVersion identifies the format of the content covered by detached signatures.
+const detachedVersion = "SIF-DETACHED-SIGNATURE 1"
+
+// detachedObjects returns the data objects of f covered by a detached signature, that is all
+// objects except signatures, sorted by ID.
+func detachedObjects(f *sif.FileImage) ([]sif.Descriptor, error) {
+	ods, err := f.GetDescriptors(func(od sif.Descriptor) (bool, error) {
+		return od.DataType() != sif.DataSignature, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ods) == 0 {
+		return nil, sif.ErrNoObjects
+	}
+	sort.Slice(ods, func(i, j int) bool { return ods[i].ID() < ods[j].ID() })
+	return ods, nil
+}
+
+// detachedContent returns the content of f covered by a detached signature, along with the data
+// objects it covers. The content is made of the following lines, each terminated by a newline
+// character:
+//
+//	SIF-DETACHED-SIGNATURE 1
+//	header <digest>
+//	object <id> <descriptor digest> <data digest>
+//
+// with one object line per data object of f, except signatures, in ascending ID order. The header
+// digest is computed over the integrity-protected fields of the global header, the descriptor
+// digest over the integrity-protected fields of the object descriptor, and the data digest over
+// the object data, all with SHA-256, in the "sha256:<hex>" form.
+//
+// Timestamps of the global header and signature objects are not covered, so a detached signature
+// remains valid when signatures are added to or removed from the image.
+func detachedContent(f *sif.FileImage) ([]byte, []sif.Descriptor, error) {
+	ods, err := detachedObjects(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := new(bytes.Buffer)
+	fmt.Fprintln(b, detachedVersion)
+
+	d, err := digest.Canonical.FromReader(f.GetHeaderIntegrityReader())
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Fprintf(b, "header %s\n", d)
+
+	for _, od := range ods {
+		md, err := digest.Canonical.FromReader(od.GetIntegrityReader())
+		if err != nil {
+			return nil, nil, fmt.Errorf("while hashing object %d descriptor: %w", od.ID(), err)
+		}
+		dd, err := digest.Canonical.FromReader(od.GetReader())
+		if err != nil {
+			return nil, nil, fmt.Errorf("while hashing object %d data: %w", od.ID(), err)
+		}
+		fmt.Fprintf(b, "object %d %s %s\n", od.ID(), md, dd)
+	}
+
+	return b.Bytes(), ods, nil
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/manifest"
+	ocitypes "github.com/containers/image/v5/types"
+	scs "github.com/sylabs/scs-library-client/client"
+	"github.com/sylabs/singularity/internal/pkg/build/oci"
+	"github.com/sylabs/singularity/pkg/inspect"
+)
+
+// InspectRemoteOCI returns the labels and registry metadata of the Docker
+// image uri. Only the image manifest and configuration are fetched, layers
+// are not downloaded.
+func InspectRemoteOCI(ctx context.Context, uri string, sysCtx *ocitypes.SystemContext) (*inspect.Metadata, error) {
+	name, err := oci.ResolveShortName(ctx, strings.TrimPrefix(uri, "docker://"), sysCtx)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := docker.ParseReference("//" + name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image reference %s: %s", uri, err)
+	}
+
+	img, err := ref.NewImage(ctx, sysCtx)
+	if err != nil {
+		return nil, fmt.Errorf("while fetching image manifest: %s", err)
+	}
+	defer img.Close()
+
+	info, err := img.Inspect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("while fetching image configuration: %s", err)
+	}
+
+	m := inspect.NewMetadata()
+	m.Attributes.Labels = info.Labels
+	m.Attributes.Remote = map[string]string{
+		"architecture": info.Architecture,
+		"os":           info.Os,
+		"layers":       strconv.Itoa(len(info.Layers)),
+	}
+	if info.Variant != "" {
+		m.Attributes.Remote["variant"] = info.Variant
+	}
+	if info.Created != nil {
+		m.Attributes.Remote["created"] = info.Created.UTC().Format(time.RFC3339)
+	}
+	if rawManifest, _, err := img.Manifest(ctx); err == nil {
+		if d, err := manifest.Digest(rawManifest); err == nil {
+			m.Attributes.Remote["digest"] = d.String()
+		}
+	}
+	var size int64
+	for _, l := range img.LayerInfos() {
+		if l.Size > 0 {
+			size += l.Size
+		}
+	}
+	m.Attributes.Remote["size"] = strconv.FormatInt(size, 10)
+
+	return m, nil
+}
+
+// InspectRemoteLibrary returns the metadata of the library image imageRef
+// for the architecture arch, as returned by the library API. Library
+// images are not downloaded, and their labels are not available.
+func InspectRemoteLibrary(ctx context.Context, scsConfig *scs.Config, imageRef, arch string) (*inspect.Metadata, error) {
+	libraryClient, err := scs.NewClient(scsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create a new client: %s", err)
+	}
+
+	img, err := libraryClient.GetImage(ctx, arch, imageRef)
+	if err != nil {
+		return nil, fmt.Errorf("while getting image %s: %s", imageRef, err)
+	}
+
+	m := inspect.NewMetadata()
+	m.Attributes.Remote = map[string]string{
+		"hash":     img.Hash,
+		"size":     strconv.FormatInt(img.Size, 10),
+		"uploaded": strconv.FormatBool(img.Uploaded),
+	}
+	if img.Description != "" {
+		m.Attributes.Remote["description"] = img.Description
+	}
+	if img.Architecture != nil {
+		m.Attributes.Remote["architecture"] = *img.Architecture
+	}
+	if img.Signed != nil {
+		m.Attributes.Remote["signed"] = strconv.FormatBool(*img.Signed)
+	}
+	if img.Encrypted != nil {
+		m.Attributes.Remote["encrypted"] = strconv.FormatBool(*img.Encrypted)
+	}
+	if len(img.Fingerprints) > 0 {
+		m.Attributes.Remote["fingerprints"] = strings.Join(img.Fingerprints, ",")
+	}
+	if len(img.Tags) > 0 {
+		m.Attributes.Remote["tags"] = strings.Join(img.Tags, ",")
+	}
+	if !img.CreatedAt.IsZero() {
+		m.Attributes.Remote["created"] = img.CreatedAt.UTC().Format(time.RFC3339)
+	}
+
+	return m, nil
+}
@@ -26,6 +26,7 @@ type instanceInfo struct {
 	IP         string `json:"ip"`
 	LogErrPath string `json:"logErrPath"`
 	LogOutPath string `json:"logOutPath"`
+	Restarts   int    `json:"restarts"`
 }
 
 // PrintInstanceList fetches instance list, applying name and
@@ -61,13 +62,13 @@ func PrintInstanceList(w io.Writer, name, user string, formatJSON bool, showLogs
 	}
 
 	if !formatJSON {
-		_, err := fmt.Fprintln(tabWriter, "INSTANCE NAME\tPID\tIP\tIMAGE")
+		_, err := fmt.Fprintln(tabWriter, "INSTANCE NAME\tPID\tIP\tRESTARTS\tIMAGE")
 		if err != nil {
 			return fmt.Errorf("could not write list header: %v", err)
 		}
 
 		for _, i := range ii {
-			_, err = fmt.Fprintf(tabWriter, "%s\t%d\t%s\t%s\n", i.Name, i.Pid, i.IP, i.Image)
+			_, err = fmt.Fprintf(tabWriter, "%s\t%d\t%s\t%d\t%s\n", i.Name, i.Pid, i.IP, i.Restarts, i.Image)
 			if err != nil {
 				return fmt.Errorf("could not write instance info: %v", err)
 			}
@@ -83,6 +84,7 @@ func PrintInstanceList(w io.Writer, name, user string, formatJSON bool, showLogs
 		instances[i].IP = ii[i].IP
 		instances[i].LogErrPath = ii[i].LogErrPath
 		instances[i].LogOutPath = ii[i].LogOutPath
+		instances[i].Restarts = ii[i].Restarts
 	}
 
 	enc := json.NewEncoder(w)
@@ -167,6 +169,10 @@ func StopInstance(name, user string, sig syscall.Signal, timeout time.Duration)
 }
 
 func killInstance(i *instance.File, sig syscall.Signal, stoppedPID chan<- int) {
+	if err := i.DetachSupervisor(); err != nil {
+		sylog.Warningf("Could not notify supervisor of instance %s: %s", i.Name, err)
+	}
+
 	sylog.Infof("Stopping %s instance of %s (PID=%d)\n", i.Name, i.Image, i.Pid)
 	syscall.Kill(i.Pid, sig)
 
@@ -0,0 +1,110 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/sylabs/singularity/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/pkg/inspect"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// Docker default values used when the health check doesn't set them.
+const (
+	defaultHealthcheckInterval = 30 * time.Second
+	defaultHealthcheckTimeout  = 30 * time.Second
+	defaultHealthcheckRetries  = 3
+)
+
+// GetImageHealthcheck returns the health check inherited from the Docker
+// image HEALTHCHECK, or nil if the image doesn't define one.
+func GetImageHealthcheck(image string) (*inspect.Healthcheck, error) {
+	cmd := exec.Command(filepath.Join(buildcfg.BINDIR, "singularity"), "inspect", "--json", "--healthcheck", image)
+	cmd.Stderr = os.Stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("while inspecting %s: %s", image, err)
+	}
+
+	metadata := new(inspect.Metadata)
+	if err := json.Unmarshal(out, metadata); err != nil {
+		return nil, fmt.Errorf("while decoding inspect metadata: %s", err)
+	}
+	return metadata.Attributes.Healthcheck, nil
+}
+
+// healthcheckArgs returns the command used to run the health check test.
+func healthcheckArgs(hc *inspect.Healthcheck) ([]string, error) {
+	if len(hc.Test) < 2 {
+		return nil, fmt.Errorf("health check test %q has no command", hc.Test)
+	}
+
+	switch hc.Test[0] {
+	case "CMD":
+		return hc.Test[1:], nil
+	case "CMD-SHELL":
+		return []string{"/bin/sh", "-c", hc.Test[1]}, nil
+	}
+	return nil, fmt.Errorf("health check test %q is not supported", hc.Test[0])
+}
+
+// WaitInstanceReady runs the health check test within the instance name
+// until it succeeds. Following the Docker semantic, failures occurring
+// during the start period are not counted and an error is returned after
+// the configured number of consecutive failures.
+func WaitInstanceReady(name string, hc *inspect.Healthcheck) error {
+	args, err := healthcheckArgs(hc)
+	if err != nil {
+		return err
+	}
+
+	interval := hc.Interval
+	if interval <= 0 {
+		interval = defaultHealthcheckInterval
+	}
+	timeout := hc.Timeout
+	if timeout <= 0 {
+		timeout = defaultHealthcheckTimeout
+	}
+	retries := hc.Retries
+	if retries <= 0 {
+		retries = defaultHealthcheckRetries
+	}
+
+	exe := filepath.Join(buildcfg.BINDIR, "singularity")
+	execArgs := append([]string{"exec", "instance://" + name}, args...)
+	startEnd := time.Now().Add(hc.StartPeriod)
+	failures := 0
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err := exec.CommandContext(ctx, exe, execArgs...).Run()
+		cancel()
+
+		if err == nil {
+			sylog.Debugf("Health check succeeded for instance %s", name)
+			return nil
+		}
+		sylog.Debugf("Health check failed for instance %s: %s", name, err)
+
+		if time.Now().After(startEnd) {
+			failures++
+			if failures >= retries {
+				return fmt.Errorf("health check failed %d consecutive times for instance %s", failures, name)
+			}
+		}
+
+		time.Sleep(interval)
+	}
+}
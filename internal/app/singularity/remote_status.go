@@ -7,30 +7,64 @@
 package singularity
 
 import (
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/sylabs/singularity/internal/pkg/remote"
 	"github.com/sylabs/singularity/internal/pkg/remote/endpoint"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
 
-const statusLine = "%s\t%s\t%s\t%s\n"
+const (
+	statusLine        = "%s\t%s\t%s\t%s\n"
+	verboseStatusLine = "%s\t%s\t%s\t%s\t%s\n"
+)
 
 type status struct {
-	name    string
-	uri     string
-	status  string
-	version string
+	Name    string     `json:"name"`
+	URI     string     `json:"uri"`
+	Status  string     `json:"status"`
+	Version string     `json:"version,omitempty"`
+	Latency float64    `json:"latencyMs,omitempty"`
+	TLS     *tlsStatus `json:"tls,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+type tlsStatus struct {
+	Version     string    `json:"version"`
+	CipherSuite string    `json:"cipherSuite"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotAfter    time.Time `json:"notAfter"`
+}
+
+type remoteStatus struct {
+	Remote   string    `json:"remote"`
+	Services []*status `json:"services"`
+	Token    string    `json:"token"`
+}
+
+var tlsVersions = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
 }
 
 // RemoteStatus checks status of services related to an endpoint
 // If the supplied remote name is an empty string, it will attempt
-// to use the default remote.
-func RemoteStatus(usrConfigFile, name string) (err error) {
+// to use the default remote. When verbose is true, the round-trip
+// latency and TLS details of services are also reported, when
+// jsonOut is true, the status is printed in JSON format.
+func RemoteStatus(usrConfigFile, name string, verbose, jsonOut bool) (err error) {
 	if name != "" {
 		sylog.Infof("Checking status of remote: %s", name)
 	} else {
@@ -59,6 +93,7 @@ func RemoteStatus(usrConfigFile, name string) (err error) {
 
 	var e *endpoint.Config
 	if name == "" {
+		name = c.DefaultRemote
 		e, err = c.GetDefault()
 	} else {
 		e, err = c.GetRemote(name)
@@ -73,13 +108,16 @@ func RemoteStatus(usrConfigFile, name string) (err error) {
 		return fmt.Errorf("while retrieving services: %s", err)
 	}
 
+	// details are always part of the JSON output
+	detailed := verbose || jsonOut
+
 	ch := make(chan *status)
 	for name, sp := range sps {
 		name := name
 		for _, service := range sp {
 			service := service
 			go func() {
-				ch <- doStatusCheck(name, service)
+				ch <- doStatusCheck(name, service, detailed)
 			}()
 		}
 	}
@@ -92,7 +130,7 @@ func RemoteStatus(usrConfigFile, name string) (err error) {
 			if s == nil {
 				continue
 			}
-			smap[s.name] = s
+			smap[s.Name] = s
 		}
 	}
 
@@ -103,39 +141,148 @@ func RemoteStatus(usrConfigFile, name string) (err error) {
 	}
 	sort.Strings(names)
 
+	token, tokenErr := doTokenCheck(e)
+
+	if jsonOut {
+		rs := remoteStatus{Remote: name, Token: token, Services: make([]*status, 0, len(names))}
+		for _, n := range names {
+			rs.Services = append(rs.Services, smap[n])
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(rs); err != nil {
+			return fmt.Errorf("while encoding remote status: %s", err)
+		}
+		return tokenErr
+	}
+
 	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintf(tw, statusLine, "SERVICE", "STATUS", "VERSION", "URI")
+	if verbose {
+		fmt.Fprintf(tw, verboseStatusLine, "SERVICE", "STATUS", "VERSION", "LATENCY", "URI")
+	} else {
+		fmt.Fprintf(tw, statusLine, "SERVICE", "STATUS", "VERSION", "URI")
+	}
 	for _, n := range names {
 		s := smap[n]
-		fmt.Fprintf(tw, statusLine, strings.Title(s.name), s.status, s.version, s.uri)
+		if verbose {
+			latency := ""
+			if s.Latency > 0 {
+				latency = fmt.Sprintf("%.0fms", s.Latency)
+			}
+			fmt.Fprintf(tw, verboseStatusLine, strings.Title(s.Name), s.Status, s.Version, latency, s.URI)
+		} else {
+			fmt.Fprintf(tw, statusLine, strings.Title(s.Name), s.Status, s.Version, s.URI)
+		}
 	}
 	tw.Flush()
 
-	return doTokenCheck(e)
+	if verbose {
+		for _, n := range names {
+			s := smap[n]
+			fmt.Printf("\n%s:\n", strings.Title(s.Name))
+			if s.Error != "" {
+				fmt.Printf("  Error:        %s\n", s.Error)
+			}
+			if s.TLS == nil {
+				fmt.Printf("  TLS:          none\n")
+				continue
+			}
+			fmt.Printf("  TLS:          %s, %s\n", s.TLS.Version, s.TLS.CipherSuite)
+			fmt.Printf("  Certificate:  %s\n", s.TLS.Subject)
+			fmt.Printf("  Issuer:       %s\n", s.TLS.Issuer)
+			fmt.Printf("  Expires:      %s\n", s.TLS.NotAfter.Format(time.RFC3339))
+		}
+	}
+
+	switch token {
+	case tokenNone:
+		fmt.Println("\nNo authentication token set (logged out).")
+	case tokenInvalid:
+		fmt.Println("\nAuthentication token is invalid (please login again).")
+	case tokenValid:
+		fmt.Println("\nValid authentication token set (logged in).")
+	}
+	return tokenErr
 }
 
-func doStatusCheck(name string, sp endpoint.Service) *status {
+func doStatusCheck(name string, sp endpoint.Service, detailed bool) *status {
 	uri := sp.URI()
+
+	start := time.Now()
 	version, err := sp.Status()
+	latency := time.Since(start)
+
+	if err == endpoint.ErrStatusNotSupported {
+		return nil
+	}
+
+	s := &status{Name: name, URI: uri, Status: "OK", Version: version}
 	if err != nil {
-		if err == endpoint.ErrStatusNotSupported {
-			return nil
+		s.Status = "N/A"
+		s.Error = err.Error()
+	} else {
+		s.Latency = float64(latency.Microseconds()) / 1000
+	}
+
+	if detailed {
+		tlsStatus, err := doTLSCheck(uri)
+		if err != nil && s.Error == "" {
+			s.Error = err.Error()
 		}
-		return &status{name: name, uri: uri, status: "N/A"}
+		s.TLS = tlsStatus
 	}
-	return &status{name: name, uri: uri, status: "OK", version: version}
+	return s
 }
 
-func doTokenCheck(e *endpoint.Config) error {
+// doTLSCheck returns the TLS details of the server at uri, or nil for
+// a non https URI.
+func doTLSCheck(uri string) (*tlsStatus, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("while parsing %s: %s", uri, err)
+	}
+	if u.Scheme != "https" {
+		return nil, nil
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	if err != nil {
+		return nil, fmt.Errorf("while establishing TLS connection to %s: %s", host, err)
+	}
+	defer conn.Close()
+
+	state := conn.ConnectionState()
+	ts := &tlsStatus{
+		Version:     tlsVersions[state.Version],
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		ts.Subject = cert.Subject.String()
+		ts.Issuer = cert.Issuer.String()
+		ts.NotAfter = cert.NotAfter
+	}
+	return ts, nil
+}
+
+const (
+	tokenNone    = "none"
+	tokenInvalid = "invalid"
+	tokenValid   = "valid"
+)
+
+func doTokenCheck(e *endpoint.Config) (string, error) {
 	if e.Token == "" {
-		fmt.Println("\nNo authentication token set (logged out).")
-		return nil
+		return tokenNone, nil
 	}
 	if err := e.VerifyToken(""); err != nil {
-		fmt.Println("\nAuthentication token is invalid (please login again).")
-		return err
-
+		return tokenInvalid, err
 	}
-	fmt.Println("\nValid authentication token set (logged in).")
-	return nil
+	return tokenValid, nil
 }
@@ -0,0 +1,124 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// SIFHeader describes the global header and the data object descriptors
+// of a SIF image.
+type SIFHeader struct {
+	LaunchScript      string          `json:"launchScript,omitempty"`
+	Version           string          `json:"version"`
+	PrimaryArch       string          `json:"primaryArch,omitempty"`
+	ID                string          `json:"id"`
+	CreatedAt         time.Time       `json:"createdAt"`
+	ModifiedAt        time.Time       `json:"modifiedAt"`
+	DescriptorsFree   int64           `json:"descriptorsFree"`
+	DescriptorsTotal  int64           `json:"descriptorsTotal"`
+	DescriptorsOffset int64           `json:"descriptorsOffset"`
+	DescriptorsSize   int64           `json:"descriptorsSize"`
+	DataOffset        int64           `json:"dataOffset"`
+	DataSize          int64           `json:"dataSize"`
+	Descriptors       []SIFDescriptor `json:"descriptors"`
+}
+
+// SIFDescriptor describes a data object of a SIF image. Group and Link are
+// omitted when the object isn't part of a group or linked to another object.
+type SIFDescriptor struct {
+	ID          uint32    `json:"id"`
+	Type        string    `json:"type"`
+	Group       uint32    `json:"group,omitempty"`
+	Link        uint32    `json:"link,omitempty"`
+	LinkIsGroup bool      `json:"linkIsGroup,omitempty"`
+	Offset      int64     `json:"offset"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created"`
+	ModifiedAt  time.Time `json:"modified"`
+	Name        string    `json:"name,omitempty"`
+	// partition objects
+	FSType   string `json:"fsType,omitempty"`
+	PartType string `json:"partType,omitempty"`
+	Arch     string `json:"arch,omitempty"`
+	// signature objects
+	HashType    string `json:"hashType,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// GetSIFHeader returns the global header and the data object descriptors
+// of the SIF image found at path, which is opened read-only.
+func GetSIFHeader(path string) (*SIFHeader, error) {
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return nil, fmt.Errorf("while loading SIF image %s: %w", path, err)
+	}
+	defer f.UnloadContainer()
+
+	h := &SIFHeader{
+		LaunchScript:      f.LaunchScript(),
+		Version:           f.Version(),
+		ID:                f.ID(),
+		CreatedAt:         f.CreatedAt().UTC(),
+		ModifiedAt:        f.ModifiedAt().UTC(),
+		DescriptorsFree:   f.DescriptorsFree(),
+		DescriptorsTotal:  f.DescriptorsTotal(),
+		DescriptorsOffset: f.DescriptorsOffset(),
+		DescriptorsSize:   f.DescriptorsSize(),
+		DataOffset:        f.DataOffset(),
+		DataSize:          f.DataSize(),
+		Descriptors:       []SIFDescriptor{},
+	}
+	if arch := f.PrimaryArch(); arch != "unknown" {
+		h.PrimaryArch = arch
+	}
+
+	var derr error
+	f.WithDescriptors(func(d sif.Descriptor) bool {
+		desc := SIFDescriptor{
+			ID:         d.ID(),
+			Type:       d.DataType().String(),
+			Group:      d.GroupID(),
+			Offset:     d.Offset(),
+			Size:       d.Size(),
+			CreatedAt:  d.CreatedAt().UTC(),
+			ModifiedAt: d.ModifiedAt().UTC(),
+			Name:       d.Name(),
+		}
+		desc.Link, desc.LinkIsGroup = d.LinkedID()
+
+		switch d.DataType() {
+		case sif.DataPartition:
+			fs, pt, arch, err := d.PartitionMetadata()
+			if err != nil {
+				derr = fmt.Errorf("while getting partition %d metadata: %w", d.ID(), err)
+				return true
+			}
+			desc.FSType = fs.String()
+			desc.PartType = pt.String()
+			desc.Arch = arch
+		case sif.DataSignature:
+			ht, fp, err := d.SignatureMetadata()
+			if err != nil {
+				derr = fmt.Errorf("while getting signature %d metadata: %w", d.ID(), err)
+				return true
+			}
+			desc.HashType = ht.String()
+			desc.Fingerprint = fmt.Sprintf("%X", fp)
+		}
+
+		h.Descriptors = append(h.Descriptors, desc)
+		return false
+	})
+	if derr != nil {
+		return nil, derr
+	}
+	return h, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"bytes"
+	"crypto"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+func TestGetSIFHeader(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "sif-header-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	created := time.Unix(1650000000, 0).UTC()
+	fp := bytes.Repeat([]byte{0xab}, 20)
+
+	part, err := sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader([]byte("partition")),
+		sif.OptPartitionMetadata(sif.FsSquash, sif.PartPrimSys, "amd64"),
+		sif.OptObjectTime(created),
+	)
+	if err != nil {
+		t.Fatalf("while creating descriptor input: %s", err)
+	}
+	sig, err := sif.NewDescriptorInput(sif.DataSignature, bytes.NewReader([]byte("signature")),
+		sif.OptSignatureMetadata(crypto.SHA256, fp),
+		sif.OptLinkedID(1),
+		sif.OptObjectTime(created),
+	)
+	if err != nil {
+		t.Fatalf("while creating descriptor input: %s", err)
+	}
+
+	path := filepath.Join(tmpDir, "image.sif")
+	f, err := sif.CreateContainerAtPath(path,
+		sif.OptCreateWithDescriptors(part, sig),
+		sif.OptCreateWithTime(created),
+		sif.OptCreateWithID("e0b0a2a1-c6cf-4f0f-8b4d-2b1a4f2f9b6c"),
+	)
+	if err != nil {
+		t.Fatalf("while creating SIF image: %s", err)
+	}
+	if err := f.UnloadContainer(); err != nil {
+		t.Fatalf("while unloading SIF image: %s", err)
+	}
+
+	h, err := GetSIFHeader(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if h.ID != "e0b0a2a1-c6cf-4f0f-8b4d-2b1a4f2f9b6c" {
+		t.Errorf("unexpected ID %q", h.ID)
+	}
+	if h.PrimaryArch != "amd64" {
+		t.Errorf("unexpected primary architecture %q", h.PrimaryArch)
+	}
+	if !h.CreatedAt.Equal(created) {
+		t.Errorf("unexpected creation time %s", h.CreatedAt)
+	}
+	if len(h.Descriptors) != 2 {
+		t.Fatalf("got %d descriptors, want 2", len(h.Descriptors))
+	}
+
+	p := h.Descriptors[0]
+	if p.ID != 1 || p.Type != sif.DataPartition.String() || p.FSType != "Squashfs" || p.PartType != sif.PartPrimSys.String() || p.Arch != "amd64" {
+		t.Errorf("unexpected partition descriptor %+v", p)
+	}
+	if p.Size != int64(len("partition")) || p.Group != 1 || !p.CreatedAt.Equal(created) {
+		t.Errorf("unexpected partition descriptor %+v", p)
+	}
+
+	s := h.Descriptors[1]
+	if s.ID != 2 || s.Type != sif.DataSignature.String() || s.Link != 1 || s.LinkIsGroup {
+		t.Errorf("unexpected signature descriptor %+v", s)
+	}
+	if s.HashType != "SHA-256" || s.Fingerprint != "ABABABABABABABABABABABABABABABABABABABAB" {
+		t.Errorf("unexpected signature metadata %q %q", s.HashType, s.Fingerprint)
+	}
+
+	if _, err := GetSIFHeader(filepath.Join(tmpDir, "missing.sif")); err == nil {
+		t.Errorf("unexpected success for a missing image")
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/pkg/image"
+	"github.com/sylabs/singularity/pkg/inspect"
+)
+
+// squashfsSuperBlockSize is the size of a squashfs super block, holding
+// the compression algorithm.
+const squashfsSuperBlockSize = 96
+
+// partitionNames maps SIF partition types to the names reported in
+// inspect.SIFLayer.Partition.
+var partitionNames = map[sif.PartType]string{
+	sif.PartSystem:  "system",
+	sif.PartPrimSys: "primary system",
+	sif.PartData:    "data",
+	sif.PartOverlay: "overlay",
+}
+
+// GetSIFLayers returns the partitions of the SIF image found at path, which
+// is opened read-only, in the order of their descriptors.
+func GetSIFLayers(path string) ([]inspect.SIFLayer, error) {
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return nil, fmt.Errorf("while loading SIF image %s: %w", path, err)
+	}
+	defer f.UnloadContainer()
+
+	descs, err := f.GetDescriptors(sif.WithDataType(sif.DataPartition))
+	if err != nil && err != sif.ErrObjectNotFound {
+		return nil, fmt.Errorf("while getting partitions: %w", err)
+	}
+
+	layers := make([]inspect.SIFLayer, 0, len(descs))
+	for _, d := range descs {
+		fs, pt, arch, err := d.PartitionMetadata()
+		if err != nil {
+			return nil, fmt.Errorf("while getting partition %d metadata: %w", d.ID(), err)
+		}
+
+		l := inspect.SIFLayer{
+			ID:        d.ID(),
+			Partition: partitionNames[pt],
+			Arch:      arch,
+			Size:      d.Size(),
+		}
+		if l.Partition == "" {
+			l.Partition = "unknown"
+		}
+
+		switch fs {
+		case sif.FsSquash:
+			l.Type = "squashfs"
+			l.Filesystem = "squashfs"
+			if l.Compression, err = squashfsCompression(d.GetReader()); err != nil {
+				return nil, fmt.Errorf("while reading partition %d: %w", d.ID(), err)
+			}
+		case sif.FsExt3:
+			l.Type = "ext3"
+			l.Filesystem = "ext3"
+		case sif.FsEncryptedSquashfs:
+			l.Type = "encrypted"
+			l.Filesystem = "squashfs"
+		case sif.FsRaw:
+			l.Type = "raw"
+		case sif.FsImmuObj:
+			l.Type = "archive"
+		default:
+			l.Type = "unknown"
+		}
+
+		layers = append(layers, l)
+	}
+	return layers, nil
+}
+
+// squashfsCompression returns the compression algorithm of the squashfs
+// filesystem read from r.
+func squashfsCompression(r io.Reader) (string, error) {
+	b := make([]byte, squashfsSuperBlockSize)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	comp, err := image.GetSquashfsComp(b)
+	if err != nil {
+		return "", err
+	}
+	if comp == "" {
+		comp = "unknown"
+	}
+	return comp, nil
+}
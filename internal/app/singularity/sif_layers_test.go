@@ -0,0 +1,92 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+	"github.com/sylabs/singularity/pkg/inspect"
+)
+
+// squashfsSuperBlock returns a squashfs v4 super block using the
+// compression algorithm comp.
+func squashfsSuperBlock(comp uint16) []byte {
+	b := make([]byte, squashfsSuperBlockSize)
+	copy(b, "hsqs")
+	binary.LittleEndian.PutUint16(b[20:], comp)
+	binary.LittleEndian.PutUint16(b[28:], 4)
+	return b
+}
+
+func TestGetSIFLayers(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "sif-layers-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	parts := []struct {
+		data []byte
+		fs   sif.FSType
+		pt   sif.PartType
+	}{
+		{squashfsSuperBlock(4), sif.FsSquash, sif.PartPrimSys},
+		{[]byte("ext3 partition"), sif.FsExt3, sif.PartOverlay},
+		{[]byte("encrypted partition"), sif.FsEncryptedSquashfs, sif.PartSystem},
+		{squashfsSuperBlock(6), sif.FsSquash, sif.PartData},
+	}
+
+	var inputs []sif.DescriptorInput
+	for _, p := range parts {
+		di, err := sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader(p.data),
+			sif.OptPartitionMetadata(p.fs, p.pt, "amd64"),
+		)
+		if err != nil {
+			t.Fatalf("while creating descriptor input: %s", err)
+		}
+		inputs = append(inputs, di)
+	}
+	di, err := sif.NewDescriptorInput(sif.DataGeneric, bytes.NewReader([]byte("data")))
+	if err != nil {
+		t.Fatalf("while creating descriptor input: %s", err)
+	}
+	inputs = append(inputs, di)
+
+	path := filepath.Join(tmpDir, "image.sif")
+	f, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(inputs...))
+	if err != nil {
+		t.Fatalf("while creating SIF image: %s", err)
+	}
+	if err := f.UnloadContainer(); err != nil {
+		t.Fatalf("while unloading SIF image: %s", err)
+	}
+
+	layers, err := GetSIFLayers(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []inspect.SIFLayer{
+		{ID: 1, Partition: "primary system", Type: "squashfs", Filesystem: "squashfs", Compression: "xz", Arch: "amd64", Size: squashfsSuperBlockSize},
+		{ID: 2, Partition: "overlay", Type: "ext3", Filesystem: "ext3", Arch: "amd64", Size: int64(len("ext3 partition"))},
+		{ID: 3, Partition: "system", Type: "encrypted", Filesystem: "squashfs", Arch: "amd64", Size: int64(len("encrypted partition"))},
+		{ID: 4, Partition: "data", Type: "squashfs", Filesystem: "squashfs", Compression: "zstd", Arch: "amd64", Size: squashfsSuperBlockSize},
+	}
+	if !reflect.DeepEqual(layers, expected) {
+		t.Errorf("got layers %+v, want %+v", layers, expected)
+	}
+
+	if _, err := GetSIFLayers(filepath.Join(tmpDir, "missing.sif")); err == nil {
+		t.Errorf("unexpected success for a missing image")
+	}
+}
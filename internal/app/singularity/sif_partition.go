@@ -0,0 +1,53 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+// CheckSIFObjectDelete returns an error if the data object id doesn't exist
+// in the SIF image found at path, or if it is the only system partition of
+// the image, which would leave the image without a root filesystem.
+func CheckSIFObjectDelete(path string, id uint32) error {
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return fmt.Errorf("while loading SIF image %s: %w", path, err)
+	}
+	defer f.UnloadContainer()
+
+	d, err := f.GetDescriptor(sif.WithID(id))
+	if err != nil {
+		return fmt.Errorf("while getting data object %d: %w", id, err)
+	}
+	if d.DataType() != sif.DataPartition {
+		return nil
+	}
+
+	_, pt, _, err := d.PartitionMetadata()
+	if err != nil {
+		return fmt.Errorf("while getting partition %d metadata: %w", id, err)
+	}
+	if pt != sif.PartSystem && pt != sif.PartPrimSys {
+		return nil
+	}
+
+	count := 0
+	for _, pt := range []sif.PartType{sif.PartSystem, sif.PartPrimSys} {
+		ds, err := f.GetDescriptors(sif.WithPartitionType(pt))
+		if err != nil {
+			return fmt.Errorf("while getting system partitions: %w", err)
+		}
+		count += len(ds)
+	}
+	if count == 1 {
+		return fmt.Errorf("data object %d is the only system partition of %s and can't be deleted", id, path)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/sif/v2/pkg/sif"
+)
+
+func createTestSIF(t *testing.T, path string, parts []sif.PartType) {
+	var dis []sif.DescriptorInput
+
+	for _, pt := range parts {
+		di, err := sif.NewDescriptorInput(sif.DataPartition, bytes.NewReader([]byte("partition")),
+			sif.OptPartitionMetadata(sif.FsSquash, pt, "amd64"),
+		)
+		if err != nil {
+			t.Fatalf("while creating descriptor input: %s", err)
+		}
+		dis = append(dis, di)
+	}
+
+	di, err := sif.NewDescriptorInput(sif.DataGeneric, bytes.NewReader([]byte("generic")))
+	if err != nil {
+		t.Fatalf("while creating descriptor input: %s", err)
+	}
+	dis = append(dis, di)
+
+	f, err := sif.CreateContainerAtPath(path, sif.OptCreateWithDescriptors(dis...))
+	if err != nil {
+		t.Fatalf("while creating SIF image: %s", err)
+	}
+	if err := f.UnloadContainer(); err != nil {
+		t.Fatalf("while unloading SIF image: %s", err)
+	}
+}
+
+func TestCheckSIFObjectDelete(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "sif-partition-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name        string
+		parts       []sif.PartType
+		id          uint32
+		expectError bool
+	}{
+		{
+			name:        "OnlySystemPartition",
+			parts:       []sif.PartType{sif.PartPrimSys},
+			id:          1,
+			expectError: true,
+		},
+		{
+			name:  "OneOfSystemPartitions",
+			parts: []sif.PartType{sif.PartPrimSys, sif.PartSystem},
+			id:    1,
+		},
+		{
+			name:  "OverlayPartition",
+			parts: []sif.PartType{sif.PartPrimSys, sif.PartOverlay},
+			id:    2,
+		},
+		{
+			name:  "GenericObject",
+			parts: []sif.PartType{sif.PartPrimSys},
+			id:    2,
+		},
+		{
+			name:        "UnknownObject",
+			parts:       []sif.PartType{sif.PartPrimSys},
+			id:          5,
+			expectError: true,
+		},
+		{
+			name:        "InvalidObject",
+			parts:       []sif.PartType{sif.PartPrimSys},
+			id:          0,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, tt.name+".sif")
+			createTestSIF(t, path, tt.parts)
+
+			err := CheckSIFObjectDelete(path, tt.id)
+			if err != nil && !tt.expectError {
+				t.Errorf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Errorf("unexpected success")
+			}
+		})
+	}
+}
@@ -6,13 +6,22 @@
 package singularity
 
 import (
+	"bytes"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/sylabs/sif/v2/pkg/integrity"
 	"github.com/sylabs/sif/v2/pkg/sif"
 	"github.com/sylabs/singularity/pkg/sypgp"
 )
 
 type signer struct {
-	opts []integrity.SignerOpt
+	e        *openpgp.Entity
+	timeFunc func() time.Time
+	opts     []integrity.SignerOpt
 }
 
 // SignOpt are used to configure s.
@@ -27,6 +36,7 @@ func OptSignEntitySelector(f sypgp.EntitySelector) SignOpt {
 			return err
 		}
 
+		s.e = e
 		s.opts = append(s.opts, integrity.OptSignWithEntity(e))
 
 		return nil
@@ -52,6 +62,16 @@ func OptSignObjects(ids ...uint32) SignOpt {
 	}
 }
 
+// OptSignWithTime specifies fn as the func to obtain the signature timestamp, which defaults to
+// the current time.
+func OptSignWithTime(fn func() time.Time) SignOpt {
+	return func(s *signer) error {
+		s.timeFunc = fn
+		s.opts = append(s.opts, integrity.OptSignWithTime(fn))
+		return nil
+	}
+}
+
 // Sign adds one or more digital signatures to the SIF image found at path, according to opts. Key
 // material must be provided via OptSignEntitySelector.
 //
@@ -80,3 +100,36 @@ func Sign(path string, opts ...SignOpt) error {
 	}
 	return is.Sign()
 }
+
+// SignDetached writes to w an ASCII armored detached signature of the SIF image found at path,
+// according to opts. Key material must be provided via OptSignEntitySelector, OptSignGroup and
+// OptSignObjects have no effect as a detached signature always covers all the data objects of
+// the image. The image itself is not modified.
+//
+// The signed content is described by detachedContent, it doesn't cover signature objects so
+// that signatures can still be added to the image once a detached signature is produced.
+func SignDetached(path string, w io.Writer, opts ...SignOpt) error {
+	// Apply options to signer.
+	s := signer{}
+	for _, opt := range opts {
+		if err := opt(&s); err != nil {
+			return err
+		}
+	}
+	if s.e == nil {
+		return integrity.ErrNoKeyMaterial
+	}
+
+	// Load container.
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return err
+	}
+	defer f.UnloadContainer()
+
+	content, _, err := detachedContent(f)
+	if err != nil {
+		return err
+	}
+	return openpgp.ArmoredDetachSign(w, s.e, bytes.NewReader(content), &packet.Config{Time: s.timeFunc})
+}
@@ -6,6 +6,7 @@
 package singularity
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -13,9 +14,11 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/sylabs/sif/v2/pkg/integrity"
+	"github.com/sylabs/sif/v2/pkg/sif"
 	"github.com/sylabs/singularity/pkg/sypgp"
 )
 
@@ -100,3 +103,48 @@ func TestSign(t *testing.T) {
 		})
 	}
 }
+
+func TestSignDetached(t *testing.T) {
+	mockEntityOpt := OptSignEntitySelector(mockEntitySelector(t))
+
+	// The test key has expired, so sign at a time it was valid.
+	timeOpt := OptSignWithTime(func() time.Time { return time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC) })
+
+	tests := []struct {
+		name    string
+		path    string
+		opts    []SignOpt
+		wantErr error
+	}{
+		{
+			name:    "ErrNoKeyMaterial",
+			path:    filepath.Join("testdata", "images", "one-group.sif"),
+			wantErr: integrity.ErrNoKeyMaterial,
+		},
+		{
+			name:    "ErrNoObjects",
+			path:    filepath.Join("testdata", "images", "empty.sif"),
+			opts:    []SignOpt{mockEntityOpt, timeOpt},
+			wantErr: sif.ErrNoObjects,
+		},
+		{
+			name: "Defaults",
+			path: filepath.Join("testdata", "images", "one-group.sif"),
+			opts: []SignOpt{mockEntityOpt, timeOpt},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+
+			if got, want := SignDetached(tt.path, &b, tt.opts...), tt.wantErr; !errors.Is(got, want) {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+			if tt.wantErr == nil && !bytes.HasPrefix(b.Bytes(), []byte("-----BEGIN PGP SIGNATURE-----")) {
+				t.Errorf("unexpected detached signature %q", b.String())
+			}
+		})
+	}
+}
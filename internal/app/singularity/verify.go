@@ -7,13 +7,17 @@
 package singularity
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"errors"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
 	"github.com/sylabs/scs-key-client/client"
 	"github.com/sylabs/sif/v2/pkg/integrity"
 	"github.com/sylabs/sif/v2/pkg/sif"
@@ -33,6 +37,7 @@ type verifier struct {
 	all       bool
 	legacy    bool
 	cb        VerifyCallback
+	timeFunc  func() time.Time
 }
 
 // VerifyOpt are used to configure v.
@@ -91,6 +96,15 @@ func OptVerifyCallback(cb VerifyCallback) VerifyOpt {
 	}
 }
 
+// OptVerifyWithTime specifies fn as the func to obtain the time at which a detached signature is
+// verified, which defaults to the current time. It has no effect on Verify.
+func OptVerifyWithTime(fn func() time.Time) VerifyOpt {
+	return func(v *verifier) error {
+		v.timeFunc = fn
+		return nil
+	}
+}
+
 // newVerifier constructs a new verifier based on opts.
 func newVerifier(opts []VerifyOpt) (verifier, error) {
 	v := verifier{}
@@ -102,11 +116,8 @@ func newVerifier(opts []VerifyOpt) (verifier, error) {
 	return v, nil
 }
 
-// getOpts returns integrity.VerifierOpt necessary to validate f.
-func (v verifier) getOpts(ctx context.Context, f *sif.FileImage) ([]integrity.VerifierOpt, error) {
-	var iopts []integrity.VerifierOpt
-
-	// Add keyring.
+// keyRing returns the keyring providing key material to verify signatures.
+func (v verifier) keyRing(ctx context.Context) (openpgp.KeyRing, error) {
 	var kr openpgp.KeyRing
 	if v.opts != nil {
 		hkr, err := sypgp.NewHybridKeyRing(ctx, v.opts...)
@@ -128,8 +139,18 @@ func (v verifier) getOpts(ctx context.Context, f *sif.FileImage) ([]integrity.Ve
 	if err != nil {
 		return nil, err
 	}
-	kr = sypgp.NewMultiKeyRing(gkr, kr)
+	return sypgp.NewMultiKeyRing(gkr, kr), nil
+}
 
+// getOpts returns integrity.VerifierOpt necessary to validate f.
+func (v verifier) getOpts(ctx context.Context, f *sif.FileImage) ([]integrity.VerifierOpt, error) {
+	var iopts []integrity.VerifierOpt
+
+	// Add keyring.
+	kr, err := v.keyRing(ctx)
+	if err != nil {
+		return nil, err
+	}
 	iopts = append(iopts, integrity.OptVerifyWithKeyRing(kr))
 
 	// Add group IDs, if applicable.
@@ -264,3 +285,42 @@ func VerifyFingerprints(ctx context.Context, path string, fingerprints []string,
 	}
 	return nil
 }
+
+// VerifyDetached verifies the ASCII armored detached signature read from sig against the SIF
+// image found at path, and returns the signing entity along with the data objects covered by
+// the signature.
+//
+// By default, the singularity public keyring provides key material. To supplement this with a
+// keyserver, use OptVerifyUseKeyServer. To verify the signature at another time than the current
+// time, use OptVerifyWithTime. A detached signature always covers all the data objects of the
+// image, OptVerifyGroup, OptVerifyObject, OptVerifyAll, OptVerifyLegacy and OptVerifyCallback
+// have no effect.
+func VerifyDetached(ctx context.Context, path string, sig io.Reader, opts ...VerifyOpt) (*openpgp.Entity, []sif.Descriptor, error) {
+	v, err := newVerifier(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Load container.
+	f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.UnloadContainer()
+
+	kr, err := v.keyRing(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, ods, err := detachedContent(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	e, err := openpgp.CheckArmoredDetachedSignature(kr, bytes.NewReader(content), sig, &packet.Config{Time: v.timeFunc})
+	if err != nil {
+		return e, nil, err
+	}
+	return e, ods, nil
+}
@@ -6,6 +6,7 @@
 package singularity
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"net/http"
@@ -14,6 +15,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/ProtonMail/go-crypto/openpgp/armor"
@@ -553,3 +555,81 @@ func TestVerifyFingerPrint(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyDetached(t *testing.T) {
+	// Start up a mock HKP server.
+	e := getTestEntity(t)
+	s := httptest.NewServer(mockHKP{e: e})
+	defer s.Close()
+
+	// Create an option that points to the mock HKP server.
+	keyServerOpt := OptVerifyUseKeyServer(client.OptBaseURL(s.URL))
+
+	// The test key has expired, so sign and verify at a time it was valid.
+	timeFunc := func() time.Time { return time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC) }
+
+	// Signing modifies the file, so work with a temporary file.
+	path, err := tempFileFrom(filepath.Join("testdata", "images", "one-group.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+
+	var sig bytes.Buffer
+	if err := SignDetached(path, &sig, OptSignEntitySelector(mockEntitySelector(t)), OptSignWithTime(timeFunc)); err != nil {
+		t.Fatalf("failed to sign image: %v", err)
+	}
+
+	verify := func(t *testing.T, wantErr bool) {
+		t.Helper()
+
+		got, ods, err := VerifyDetached(context.Background(), path, bytes.NewReader(sig.Bytes()), keyServerOpt, OptVerifyWithTime(timeFunc))
+		if (err != nil) != wantErr {
+			t.Fatalf("got error %v, want error %v", err, wantErr)
+		}
+		if wantErr {
+			return
+		}
+		if got == nil || !reflect.DeepEqual(got.PrimaryKey, e.PrimaryKey) {
+			t.Errorf("got entity %+v, want %+v", got, e)
+		}
+		if len(ods) != 2 || ods[0].ID() != 1 || ods[1].ID() != 2 {
+			t.Errorf("unexpected verified objects %v", ods)
+		}
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		verify(t, false)
+	})
+
+	// Adding signatures to the image doesn't invalidate the detached signature.
+	t.Run("Signed", func(t *testing.T) {
+		if err := Sign(path, OptSignEntitySelector(mockEntitySelector(t)), OptSignWithTime(timeFunc)); err != nil {
+			t.Fatalf("failed to sign image: %v", err)
+		}
+		verify(t, false)
+	})
+
+	// Modifying a data object does.
+	t.Run("Modified", func(t *testing.T) {
+		f, err := sif.LoadContainerFromPath(path, sif.OptLoadWithFlag(os.O_RDONLY))
+		if err != nil {
+			t.Fatal(err)
+		}
+		od, err := f.GetDescriptor(sif.WithID(1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.UnloadContainer()
+
+		fp, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fp.Close()
+		if _, err := fp.WriteAt([]byte{0xff}, od.Offset()); err != nil {
+			t.Fatal(err)
+		}
+		verify(t, true)
+	})
+}
@@ -89,14 +89,16 @@ export SCIF_APPDATA SCIF_APPNAME SCIF_APPROOT SCIF_APPMETA SCIF_APPINPUT SCIF_AP
 %s
 `
 
+	// the install script of each app runs in a subshell, so that the
+	// variables it sets are not seen by the install scripts of other apps
 	scifInstallBase = `
+(
 cd /
 . %[1]s/scif/env/01-base.sh
 
 cd %[1]s
 %[2]s
-
-cd /
+)
 `
 )
 
@@ -280,11 +282,17 @@ func writeEnvFile(b *types.Bundle, a *App) error {
 		return err
 	}
 
+	// an environment file of the app in the base image doesn't apply
+	// to the app as defined in this build
+	envFile := filepath.Join(appMeta(b, a), "/env/90-environment.sh")
 	if a.Env == "" {
+		if err := os.Remove(envFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 		return nil
 	}
 
-	return ioutil.WriteFile(filepath.Join(appMeta(b, a), "/env/90-environment.sh"), []byte(a.Env), 0o755)
+	return ioutil.WriteFile(envFile, []byte(a.Env), 0o755)
 }
 
 func globalAppEnv(b *types.Bundle, a *App) string {
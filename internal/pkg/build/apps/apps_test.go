@@ -0,0 +1,90 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package apps
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestWriteEnvFile(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "apps-rootfs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	b := &types.Bundle{RootfsPath: rootfs}
+	foo := &App{Name: "foo", Env: "export VAR=foo\n"}
+	bar := &App{Name: "bar"}
+
+	// bar environment file left by the base image
+	for _, a := range []*App{foo, bar} {
+		if err := createAppRoot(b, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stale := filepath.Join(appMeta(b, bar), "env", "90-environment.sh")
+	if err := ioutil.WriteFile(stale, []byte("export VAR=stale\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, a := range []*App{foo, bar} {
+		if err := writeEnvFile(b, a); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	content, err := ioutil.ReadFile(filepath.Join(appMeta(b, foo), "env", "90-environment.sh"))
+	if err != nil {
+		t.Fatalf("foo environment file not written: %s", err)
+	}
+	if string(content) != foo.Env {
+		t.Errorf("unexpected foo environment %q, want %q", content, foo.Env)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale bar environment file not removed")
+	}
+	if _, err := os.Stat(filepath.Join(appMeta(b, bar), "env", "01-base.sh")); err != nil {
+		t.Errorf("bar base environment file not written: %s", err)
+	}
+}
+
+func TestHandlePostIsolation(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+
+	pl := New()
+	pl.HandleSection("appinstall foo", "export VAR=foo\necho \"foo:${VAR:-}\"")
+	pl.HandleSection("appinstall bar", "echo \"bar:${VAR:-}\"")
+
+	b := &types.Bundle{Recipe: types.Definition{AppOrder: []string{"foo", "bar"}}}
+	post, err := pl.HandlePost(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// replace the app paths which don't exist on the host
+	post = strings.ReplaceAll(post, ". /scif/apps/foo/scif/env/01-base.sh", ":")
+	post = strings.ReplaceAll(post, ". /scif/apps/bar/scif/env/01-base.sh", ":")
+	post = strings.ReplaceAll(post, "cd /scif/apps/", "cd /tmp #")
+
+	out, err := exec.Command(sh, "-e", "-c", post).CombinedOutput()
+	if err != nil {
+		t.Fatalf("unexpected error: %s: %s", err, out)
+	}
+	if got, want := strings.TrimSpace(string(out)), "foo:foo\nbar:"; got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	da "github.com/docker/docker/pkg/archive"
+	digest "github.com/opencontainers/go-digest"
+	imgspec "github.com/opencontainers/image-spec/specs-go"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/internal/pkg/util/machine"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// ociDefaultPath is the PATH set in the configuration of OCI images, the
+// container environment scripts run by the entrypoint can modify it.
+const ociDefaultPath = "PATH=/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// ociRefName is the reference name of the image in the OCI image layout.
+const ociRefName = "latest"
+
+// OCIAssembler assembles an OCI image layout directory, holding an image
+// made of a single layer with the whole root filesystem.
+type OCIAssembler struct{}
+
+// Assemble creates an OCI image layout directory from a Bundle.
+func (a *OCIAssembler) Assemble(b *types.Bundle, path string) error {
+	sylog.Infof("Creating OCI image layout...")
+
+	if _, err := os.Stat(path); err == nil {
+		os.RemoveAll(path)
+	}
+
+	blobsDir := filepath.Join(path, "blobs", string(digest.SHA256))
+	if err := os.MkdirAll(blobsDir, 0o755); err != nil {
+		return fmt.Errorf("while creating OCI image layout: %v", err)
+	}
+
+	layer, diffID, err := writeOCILayer(b.RootfsPath, blobsDir)
+	if err != nil {
+		return fmt.Errorf("while creating OCI image layer: %v", err)
+	}
+
+	arch := machine.ArchFromContainer(b.RootfsPath)
+	if arch == "" {
+		sylog.Infof("Architecture not recognized, use native")
+		arch = runtime.GOARCH
+	}
+	sylog.Verbosef("Set OCI image architecture to %s", arch)
+
+	created := time.Now().UTC()
+	img := imgspecv1.Image{
+		Created:      &created,
+		Architecture: arch,
+		OS:           "linux",
+		Config: imgspecv1.ImageConfig{
+			Env:    []string{ociDefaultPath},
+			Labels: ociLabels(b.RootfsPath),
+		},
+		RootFS: imgspecv1.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{diffID},
+		},
+		History: []imgspecv1.History{
+			{
+				Created:   &created,
+				CreatedBy: "singularity build",
+			},
+		},
+	}
+	// the run action sources the container environment scripts before
+	// executing the runscript, as 'singularity run' does
+	if fs.IsFile(filepath.Join(b.RootfsPath, ".singularity.d", "actions", "run")) {
+		img.Config.Entrypoint = []string{"/.singularity.d/actions/run"}
+	}
+
+	config, err := writeOCIBlob(blobsDir, imgspecv1.MediaTypeImageConfig, img)
+	if err != nil {
+		return err
+	}
+
+	manifest, err := writeOCIBlob(blobsDir, imgspecv1.MediaTypeImageManifest, imgspecv1.Manifest{
+		Versioned: imgspec.Versioned{SchemaVersion: 2},
+		MediaType: imgspecv1.MediaTypeImageManifest,
+		Config:    config,
+		Layers:    []imgspecv1.Descriptor{layer},
+	})
+	if err != nil {
+		return err
+	}
+	manifest.Platform = &imgspecv1.Platform{
+		Architecture: arch,
+		OS:           "linux",
+	}
+	manifest.Annotations = map[string]string{
+		imgspecv1.AnnotationRefName: ociRefName,
+	}
+
+	index := imgspecv1.Index{
+		Versioned: imgspec.Versioned{SchemaVersion: 2},
+		MediaType: imgspecv1.MediaTypeImageIndex,
+		Manifests: []imgspecv1.Descriptor{manifest},
+	}
+	if err := writeOCIJSON(filepath.Join(path, "index.json"), index); err != nil {
+		return err
+	}
+	layout := imgspecv1.ImageLayout{Version: imgspecv1.ImageLayoutVersion}
+	return writeOCIJSON(filepath.Join(path, imgspecv1.ImageLayoutFile), layout)
+}
+
+// writeOCILayer writes the gzip compressed tar archive of the root
+// filesystem rootfs to the blobs directory, and returns its descriptor and
+// the digest of the uncompressed archive.
+func writeOCILayer(rootfs, blobsDir string) (imgspecv1.Descriptor, digest.Digest, error) {
+	tr, err := da.TarWithOptions(rootfs, &da.TarOptions{Compression: da.Uncompressed})
+	if err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+	defer tr.Close()
+
+	f, err := ioutil.TempFile(blobsDir, "layer-")
+	if err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	compressed := digest.SHA256.Digester()
+	uncompressed := digest.SHA256.Digester()
+	cw := &countWriter{w: io.MultiWriter(f, compressed.Hash())}
+	zw := gzip.NewWriter(cw)
+
+	if _, err := io.Copy(io.MultiWriter(zw, uncompressed.Hash()), tr); err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+	if err := zw.Close(); err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+	if err := f.Close(); err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+
+	d := compressed.Digest()
+	if err := os.Rename(f.Name(), filepath.Join(blobsDir, d.Encoded())); err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+	desc := imgspecv1.Descriptor{
+		MediaType: imgspecv1.MediaTypeImageLayerGzip,
+		Digest:    d,
+		Size:      cw.n,
+	}
+	return desc, uncompressed.Digest(), nil
+}
+
+// countWriter counts the bytes written to w.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// writeOCIBlob writes v in JSON format to the blobs directory, and returns
+// its descriptor with the given media type.
+func writeOCIBlob(blobsDir, mediaType string, v interface{}) (imgspecv1.Descriptor, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return imgspecv1.Descriptor{}, fmt.Errorf("while encoding %s: %v", mediaType, err)
+	}
+	d := digest.FromBytes(b)
+	if err := ioutil.WriteFile(filepath.Join(blobsDir, d.Encoded()), b, 0o644); err != nil {
+		return imgspecv1.Descriptor{}, fmt.Errorf("while writing %s: %v", mediaType, err)
+	}
+	return imgspecv1.Descriptor{
+		MediaType: mediaType,
+		Digest:    d,
+		Size:      int64(len(b)),
+	}, nil
+}
+
+// writeOCIJSON writes v in JSON format to the file at path.
+func writeOCIJSON(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("while encoding %s: %v", filepath.Base(path), err)
+	}
+	if err := ioutil.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("while writing %s: %v", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// ociLabels returns the labels of the container from its labels.json file,
+// or nil if they can't be read.
+func ociLabels(rootfs string) map[string]string {
+	b, err := ioutil.ReadFile(filepath.Join(rootfs, ".singularity.d", "labels.json"))
+	if err != nil {
+		return nil
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(b, &labels); err != nil {
+		sylog.Warningf("Labels not added to the OCI image configuration: %s", err)
+		return nil
+	}
+	return labels
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sylabs/singularity/internal/pkg/build/assemblers"
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func readOCIJSON(t *testing.T, path string, v interface{}) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("while reading %s: %s", path, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		t.Fatalf("while decoding %s: %s", path, err)
+	}
+}
+
+// TestOCIAssembler checks the OCI image layout assembled from a root
+// filesystem holding a file and labels.
+func TestOCIAssembler(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "oci-assembler-")
+	if err != nil {
+		t.Fatalf("while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	b, err := types.NewBundle(tmpDir, tmpDir)
+	if err != nil {
+		t.Fatalf("unable to make bundle: %v", err)
+	}
+	defer b.Remove()
+
+	if err := os.MkdirAll(filepath.Join(b.RootfsPath, ".singularity.d"), 0o755); err != nil {
+		t.Fatalf("while creating metadata directory: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(b.RootfsPath, ".singularity.d", "labels.json"), []byte(`{"maintainer": "test"}`), 0o644); err != nil {
+		t.Fatalf("while writing labels: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(b.RootfsPath, "hello"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("while writing file: %s", err)
+	}
+
+	dest := filepath.Join(tmpDir, "layout")
+	a := &assemblers.OCIAssembler{}
+	if err := a.Assemble(b, dest); err != nil {
+		t.Fatalf("failed to assemble: %s", err)
+	}
+
+	var layout imgspecv1.ImageLayout
+	readOCIJSON(t, filepath.Join(dest, imgspecv1.ImageLayoutFile), &layout)
+	if layout.Version != imgspecv1.ImageLayoutVersion {
+		t.Errorf("got layout version %q, want %q", layout.Version, imgspecv1.ImageLayoutVersion)
+	}
+
+	blob := func(d digest.Digest) string {
+		return filepath.Join(dest, "blobs", d.Algorithm().String(), d.Encoded())
+	}
+
+	var index imgspecv1.Index
+	readOCIJSON(t, filepath.Join(dest, "index.json"), &index)
+	if len(index.Manifests) != 1 {
+		t.Fatalf("got %d manifests, want 1", len(index.Manifests))
+	}
+	if p := index.Manifests[0].Platform; p == nil || p.OS != "linux" || p.Architecture == "" {
+		t.Errorf("unexpected manifest platform %+v", p)
+	}
+
+	var manifest imgspecv1.Manifest
+	readOCIJSON(t, blob(index.Manifests[0].Digest), &manifest)
+	if len(manifest.Layers) != 1 {
+		t.Fatalf("got %d layers, want 1", len(manifest.Layers))
+	}
+
+	var config imgspecv1.Image
+	readOCIJSON(t, blob(manifest.Config.Digest), &config)
+	if config.Config.Labels["maintainer"] != "test" {
+		t.Errorf("got labels %v, want maintainer label", config.Config.Labels)
+	}
+
+	f, err := os.Open(blob(manifest.Layers[0].Digest))
+	if err != nil {
+		t.Fatalf("while opening layer: %s", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("while decompressing layer: %s", err)
+	}
+	digester := digest.SHA256.Digester()
+	tr := tar.NewReader(io.TeeReader(zr, digester.Hash()))
+
+	found := false
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("while reading layer: %s", err)
+		}
+		if h.Name == "hello" {
+			found = true
+		}
+	}
+	io.Copy(ioutil.Discard, zr)
+	if !found {
+		t.Errorf("file hello not found in layer")
+	}
+	if len(config.RootFS.DiffIDs) != 1 || config.RootFS.DiffIDs[0] != digester.Digest() {
+		t.Errorf("got diff IDs %v, want [%s]", config.RootFS.DiffIDs, digester.Digest())
+	}
+}
@@ -50,13 +50,15 @@ type Build struct {
 type Config struct {
 	// Dest is the location for container after build is complete.
 	Dest string
-	// Format is the format of built container, e.g. SIF, sandbox.
+	// Format is the format of built container, e.g. SIF, sandbox, OCI.
 	Format string
 	// NoCleanUp allows a user to prevent a bundle from being cleaned
 	// up after a failed build, useful for debugging.
 	NoCleanUp bool
 	// Opts for bundles.
 	Opts types.Options
+	// Events receives the build progress events, nil to disable them.
+	Events *EventWriter
 }
 
 // NewBuild creates a new Build struct from a spec (URI, definition file, etc...).
@@ -103,6 +105,14 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 
 	lastStageIndex := len(defs) - 1
 
+	var stripRules []stripRule
+	if conf.Opts.Strip {
+		stripRules, err = loadStripRules(conf.Opts.StripRules)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// create stages
 	for i, d := range defs {
 		// verify every definition has a header if there are multiple stages
@@ -130,6 +140,10 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 		}
 		s.name = d.Header["stage"]
 		s.b.Recipe = d
+		s.events = conf.Events
+		if lastStageIndex == i {
+			s.stripRules = stripRules
+		}
 
 		if conf.Format == "sandbox" && lastStageIndex == i {
 			// rootfs path changed during bundle creation it means that chown
@@ -187,6 +201,8 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 	switch conf.Format {
 	case "sandbox":
 		b.stages[lastStageIndex].a = &assemblers.SandboxAssembler{Copy: sandboxCopy}
+	case "oci":
+		b.stages[lastStageIndex].a = &assemblers.OCIAssembler{}
 	case "sif":
 		mksquashfsPath, err := squashfs.GetPath()
 		if err != nil {
@@ -325,9 +341,19 @@ func (b Build) cleanUp() {
 }
 
 // Full runs a standard build from start to finish.
-func (b *Build) Full(ctx context.Context) error {
+func (b *Build) Full(ctx context.Context) (err error) {
 	sylog.Infof("Starting build...")
 
+	// current is the stage being built, if any
+	current := (*stage)(nil)
+	defer func() {
+		result, msg := eventStatus(err)
+		if current != nil {
+			b.Conf.Events.Emit(Event{Type: StageEndEvent, Stage: current.name, Status: result, Error: msg})
+		}
+		b.Conf.Events.Emit(Event{Type: StatusEvent, Status: result, Error: msg, Message: b.Conf.Dest})
+	}()
+
 	// monitor build for termination signal and clean up
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -373,6 +399,9 @@ func (b *Build) Full(ctx context.Context) error {
 
 	// build each stage one after the other
 	for i, stage := range b.stages {
+		current = &b.stages[i]
+		stage.events.Emit(Event{Type: StageStartEvent, Stage: stage.name})
+
 		if err := stage.runSectionScript("pre", stage.b.Recipe.BuildData.Pre); err != nil {
 			return err
 		}
@@ -382,6 +411,7 @@ func (b *Build) Full(ctx context.Context) error {
 		if update {
 			// updating, extract dest container to bundle
 			sylog.Infof("Building into existing container: %s", b.Conf.Dest)
+			stage.step("update")
 			p, err := sources.GetLocalPacker(ctx, b.Conf.Dest, stage.b)
 			if err != nil {
 				return err
@@ -396,16 +426,26 @@ func (b *Build) Full(ctx context.Context) error {
 			if b.Conf.Opts.ImgCache == nil {
 				return fmt.Errorf("undefined image cache")
 			}
+			stage.step("get")
 			if err := stage.c.Get(ctx, stage.b); err != nil {
 				return fmt.Errorf("conveyor failed to get: %v", err)
 			}
 
+			stage.step("pack")
 			_, err := stage.c.Pack(ctx)
 			if err != nil {
 				return fmt.Errorf("packer failed to pack: %v", err)
 			}
 		}
 
+		if stage.b.Opts.CommitOverlay != "" && i == len(b.stages)-1 {
+			sylog.Infof("Applying overlay %s", stage.b.Opts.CommitOverlay)
+			stage.step("overlay")
+			if err := stage.commitOverlay(stage.b.Opts.CommitOverlay); err != nil {
+				return fmt.Errorf("while applying overlay: %v", err)
+			}
+		}
+
 		// create apps in bundle
 		a := apps.New()
 		for k, v := range stage.b.Recipe.CustomData {
@@ -421,6 +461,7 @@ func (b *Build) Full(ctx context.Context) error {
 
 		// copy potential files from previous stage
 		if stage.b.RunSection("files") {
+			stage.step("files-from-stage")
 			if err := stage.copyFilesFrom(b); err != nil {
 				return fmt.Errorf("unable to copy files from stage to container fs: %v", err)
 			}
@@ -432,7 +473,8 @@ func (b *Build) Full(ctx context.Context) error {
 
 		// copy files from host
 		if stage.b.RunSection("files") {
-			if err := stage.copyFiles(); err != nil {
+			stage.step("files")
+			if err := stage.copyFiles(ctx); err != nil {
 				return fmt.Errorf("unable to copy files from host to container fs: %v", err)
 			}
 		}
@@ -464,7 +506,16 @@ func (b *Build) Full(ctx context.Context) error {
 			}
 		}
 
+		if stage.stripRules != nil {
+			sylog.Infof("Stripping container root filesystem")
+			stage.step("strip")
+			if err := stage.stripRootfs(); err != nil {
+				return fmt.Errorf("while stripping container: %v", err)
+			}
+		}
+
 		sylog.Debugf("Inserting Metadata")
+		stage.step("metadata")
 		if err := stage.insertMetadata(); err != nil {
 			return fmt.Errorf("while inserting metadata to bundle: %v", err)
 		}
@@ -472,11 +523,17 @@ func (b *Build) Full(ctx context.Context) error {
 		if err := stage.runTestScript(configFile, sessionResolv, sessionHosts); err != nil {
 			return fmt.Errorf("failed to execute %%test script: %v", err)
 		}
+
+		if i < len(b.stages)-1 {
+			stage.events.Emit(Event{Type: StageEndEvent, Stage: stage.name, Status: StatusSuccess})
+			current = nil
+		}
 	}
 
 	syscall.Umask(oldumask)
 
 	sylog.Debugf("Calling assembler")
+	b.stages[len(b.stages)-1].step("assemble")
 	if err := b.stages[len(b.stages)-1].Assemble(b.Conf.Dest); err != nil {
 		return err
 	}
@@ -498,13 +555,12 @@ func makeDef(spec string) (types.Definition, error) {
 	}
 
 	// default to reading file as definition
-	defFile, err := os.Open(spec)
+	raw, err := parser.ReadDefinitionFile(spec)
 	if err != nil {
-		return types.Definition{}, fmt.Errorf("unable to open file %s: %v", spec, err)
+		return types.Definition{}, fmt.Errorf("unable to read file %s: %v", spec, err)
 	}
-	defer defFile.Close()
 
-	d, err := parser.ParseDefinitionFile(defFile)
+	d, err := parser.ParseDefinitionFile(bytes.NewReader(raw))
 	if err != nil {
 		return types.Definition{}, fmt.Errorf("while parsing definition: %s: %v", spec, err)
 	}
@@ -528,13 +584,12 @@ func MakeAllDefs(spec string) ([]types.Definition, error) {
 	}
 
 	// default to reading file as definition
-	defFile, err := os.Open(spec)
+	raw, err := parser.ReadDefinitionFile(spec)
 	if err != nil {
-		return nil, fmt.Errorf("unable to open file %s: %v", spec, err)
+		return nil, fmt.Errorf("unable to read file %s: %v", spec, err)
 	}
-	defer defFile.Close()
 
-	d, err := parser.All(defFile)
+	d, err := parser.All(bytes.NewReader(raw))
 	if err != nil {
 		return nil, fmt.Errorf("while parsing definition: %s: %v", spec, err)
 	}
@@ -542,6 +597,32 @@ func MakeAllDefs(spec string) ([]types.Definition, error) {
 	return d, nil
 }
 
+// MakeDockerfileDefs gets definition objects from the Dockerfile at path,
+// or the Dockerfile in the directory path, using the Dockerfile directory
+// as build context.
+func MakeDockerfileDefs(path string) ([]types.Definition, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("while resolving path %s: %v", path, err)
+	}
+	if fs.IsDir(path) {
+		path = filepath.Join(path, "Dockerfile")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %s: %v", path, err)
+	}
+	defer f.Close()
+
+	d, err := parser.ParseDockerfile(f, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("while parsing Dockerfile: %s: %v", path, err)
+	}
+
+	return d, nil
+}
+
 func (b *Build) findStageIndex(name string) (int, error) {
 	for i, s := range b.stages {
 		if name == s.name {
@@ -37,6 +37,8 @@ func NewConveyorPacker(def types.Definition) (ConveyorPacker, error) {
 		return nil, fmt.Errorf("no bootstrap specification found")
 	}
 
+	// the agents must be kept in sync with bootstrapHeaders in
+	// pkg/build/types/parser/lint.go, used by the build linter
 	switch bs {
 	case "library":
 		return &sources.LibraryConveyorPacker{}, nil
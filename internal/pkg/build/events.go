@@ -0,0 +1,155 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventType defines the type of a build event.
+type EventType string
+
+const (
+	// StageStartEvent is emitted when a build stage starts.
+	StageStartEvent EventType = "stage-start"
+	// StageEndEvent is emitted when a build stage ends.
+	StageEndEvent EventType = "stage-end"
+	// SectionStartEvent is emitted when a definition section script starts.
+	SectionStartEvent EventType = "section-start"
+	// SectionEndEvent is emitted when a definition section script ends.
+	SectionEndEvent EventType = "section-end"
+	// StepEvent is emitted when a build step starts.
+	StepEvent EventType = "step"
+	// LogEvent is emitted for each output line of a section script.
+	LogEvent EventType = "log"
+	// StatusEvent is emitted once with the final build status.
+	StatusEvent EventType = "status"
+)
+
+const (
+	// StatusSuccess is the status of a successful build, stage or section.
+	StatusSuccess = "success"
+	// StatusFailure is the status of a failed build, stage or section.
+	StatusFailure = "failure"
+)
+
+// Event describes a build progress event.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Type    EventType `json:"type"`
+	Stage   string    `json:"stage,omitempty"`
+	Section string    `json:"section,omitempty"`
+	Step    string    `json:"step,omitempty"`
+	Stream  string    `json:"stream,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Status  string    `json:"status,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// EventWriter writes build events as newline-delimited JSON. All methods
+// are no-op on a nil EventWriter.
+type EventWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewEventWriter returns an EventWriter writing events to w.
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{enc: json.NewEncoder(w)}
+}
+
+// Emit writes the event e, its time is set to the current time if unset.
+func (w *EventWriter) Emit(e Event) {
+	if w == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.enc.Encode(e)
+}
+
+// eventStatus returns the status and error message of events for err.
+func eventStatus(err error) (string, string) {
+	if err != nil {
+		return StatusFailure, err.Error()
+	}
+	return StatusSuccess, ""
+}
+
+// sectionOutput returns the writers used for the standard output and error
+// streams of a section script. With events enabled, both streams are written
+// to the standard error, to not mix with events, and each line is emitted as
+// a log event. The returned function flushes remaining partial lines.
+func (w *EventWriter) sectionOutput(stage, section string) (stdout io.Writer, stderr io.Writer, flush func()) {
+	if w == nil {
+		return os.Stdout, os.Stderr, func() {}
+	}
+
+	o := &logWriter{w: w, stage: stage, section: section, stream: "stdout"}
+	e := &logWriter{w: w, stage: stage, section: section, stream: "stderr"}
+
+	return o, e, func() {
+		o.flush()
+		e.flush()
+	}
+}
+
+// logWriter copies a section script stream to the standard error and
+// emits a log event for each line written.
+type logWriter struct {
+	w       *EventWriter
+	stage   string
+	section string
+	stream  string
+	buf     bytes.Buffer
+}
+
+func (l *logWriter) Write(p []byte) (int, error) {
+	n, err := os.Stderr.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	l.buf.Write(p)
+	for {
+		line, err := l.buf.ReadString('\n')
+		if err != nil {
+			// put back the partial line
+			l.buf.Reset()
+			l.buf.WriteString(line)
+			break
+		}
+		l.emit(line[:len(line)-1])
+	}
+	return len(p), nil
+}
+
+func (l *logWriter) flush() {
+	if l.buf.Len() > 0 {
+		l.emit(l.buf.String())
+		l.buf.Reset()
+	}
+}
+
+func (l *logWriter) emit(line string) {
+	l.w.Emit(Event{
+		Type:    LogEvent,
+		Stage:   l.stage,
+		Section: l.section,
+		Stream:  l.stream,
+		Message: line,
+	})
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func decodeEvents(t *testing.T, b *bytes.Buffer) []Event {
+	var events []Event
+
+	scanner := bufio.NewScanner(b)
+	for scanner.Scan() {
+		var e Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("while decoding event %q: %s", scanner.Text(), err)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("event %q has no time", scanner.Text())
+		}
+		e.Time = time.Time{}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestEventWriter(t *testing.T) {
+	// section output is copied to stderr
+	stderr := os.Stderr
+	devnull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("while opening %s: %s", os.DevNull, err)
+	}
+	defer devnull.Close()
+	os.Stderr = devnull
+	defer func() { os.Stderr = stderr }()
+
+	var b bytes.Buffer
+	w := NewEventWriter(&b)
+
+	s := &stage{name: "final", events: w}
+	s.sectionStart("post")
+	stdout, errout, flush := w.sectionOutput(s.name, "post")
+	fmt.Fprint(stdout, "first line\nsecond ")
+	fmt.Fprint(errout, "error line\n")
+	fmt.Fprint(stdout, "line\nlast line")
+	flush()
+	s.sectionEnd("post", errors.New("exit status 1"))
+
+	expected := []Event{
+		{Type: SectionStartEvent, Stage: "final", Section: "post"},
+		{Type: LogEvent, Stage: "final", Section: "post", Stream: "stdout", Message: "first line"},
+		{Type: LogEvent, Stage: "final", Section: "post", Stream: "stderr", Message: "error line"},
+		{Type: LogEvent, Stage: "final", Section: "post", Stream: "stdout", Message: "second line"},
+		{Type: LogEvent, Stage: "final", Section: "post", Stream: "stdout", Message: "last line"},
+		{Type: SectionEndEvent, Stage: "final", Section: "post", Status: StatusFailure, Error: "exit status 1"},
+	}
+
+	events := decodeEvents(t, &b)
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("unexpected events %+v, expected %+v", events, expected)
+	}
+}
+
+func TestNilEventWriter(t *testing.T) {
+	var w *EventWriter
+
+	// must not panic
+	w.Emit(Event{Type: StepEvent})
+
+	stdout, stderr, flush := w.sectionOutput("", "post")
+	if stdout != os.Stdout || stderr != os.Stderr {
+		t.Errorf("unexpected section output writers")
+	}
+	flush()
+}
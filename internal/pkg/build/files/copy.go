@@ -49,16 +49,19 @@ func makeParentDir(path string) error {
 // dstRel is a destination path inside dstRootfs.
 // An empty dstRel "" means copy the src file to the same path in the rootfs.
 // All symlinks encountered in the copy will be dereferenced (cp -L behavior).
-func CopyFromHost(src, dstRel, dstRootfs string) error {
+// The paths of the copied files and directories in dstRootfs are returned.
+func CopyFromHost(src, dstRel, dstRootfs string) ([]string, error) {
 	// resolve any globbing in filepath
 	paths, err := filepath.Glob(src)
 	if err != nil {
-		return fmt.Errorf("while expanding source path: %s: %s", src, err)
+		return nil, fmt.Errorf("while expanding source path: %s: %s", src, err)
 	}
 	if len(paths) == 0 {
-		return fmt.Errorf("no source files found matching: %s", src)
+		return nil, fmt.Errorf("no source files found matching: %s", src)
 	}
 
+	copied := make([]string, 0, len(paths))
+
 	for _, srcGlobbed := range paths {
 		// If the dstRel is "" then we are copying to the full source path, appended to the rootfs prefix
 		dstRelGlobbed := dstRel
@@ -69,12 +72,18 @@ func CopyFromHost(src, dstRel, dstRootfs string) error {
 		// Resolve our destination within the container rootfs
 		dstResolved, err := secureJoinKeepSlash(dstRootfs, dstRelGlobbed)
 		if err != nil {
-			return fmt.Errorf("while resolving destination: %s: %s", dstRelGlobbed, err)
+			return nil, fmt.Errorf("while resolving destination: %s: %s", dstRelGlobbed, err)
 		}
 
 		// Create any parent dirs for dst that don't already exist
 		if err := makeParentDir(dstResolved); err != nil {
-			return fmt.Errorf("while creating parent dir: %v", err)
+			return nil, fmt.Errorf("while creating parent dir: %v", err)
+		}
+
+		// cp copies into an existing destination directory
+		dstCopied := filepath.Clean(dstResolved)
+		if fs.IsDir(dstResolved) {
+			dstCopied = filepath.Join(dstResolved, filepath.Base(srcGlobbed))
 		}
 
 		args := []string{"-fLr", srcGlobbed, dstResolved}
@@ -82,17 +91,17 @@ func CopyFromHost(src, dstRel, dstRootfs string) error {
 		// copy each file into bundle rootfs
 		cp, err := bin.FindBin("cp")
 		if err != nil {
-			return err
+			return nil, err
 		}
 		copy := exec.Command(cp, args...)
 		copy.Stdout = &output
 		copy.Stderr = &stderr
 		if err := copy.Run(); err != nil {
-			return fmt.Errorf("while copying %s to %s: %v: %s", paths, dstResolved, args, stderr.String())
+			return nil, fmt.Errorf("while copying %s to %s: %v: %s", paths, dstResolved, args, stderr.String())
 		}
-
+		copied = append(copied, dstCopied)
 	}
-	return nil
+	return copied, nil
 }
 
 // CopyFromStage should be used to copy files into the rootfs from a previous stage.
@@ -101,7 +110,8 @@ func CopyFromHost(src, dstRel, dstRootfs string) error {
 // Symlinks are only dereferenced for the specified source or files that resolve
 // directly from a specified glob pattern. Any additional links inside a directory
 // being copied are not dereferenced.
-func CopyFromStage(src, dst, srcRootfs, dstRootfs string) error {
+// The paths of the copied files and directories in dstRootfs are returned.
+func CopyFromStage(src, dst, srcRootfs, dstRootfs string) ([]string, error) {
 	// An absolute path on the host is required for globbing.
 	// Make sure the glob pattern doesn't climb out of the srcRootfs, by making it absolute w.r.t.
 	// the srcRootfs, and cleaning any '../' components that lead above the srcRootfs '/' before we
@@ -118,12 +128,14 @@ func CopyFromStage(src, dst, srcRootfs, dstRootfs string) error {
 	// resolve any bash globbing in filepath
 	paths, err := filepath.Glob(hostSrc)
 	if err != nil {
-		return fmt.Errorf("while expanding source path: %s: %s", src, err)
+		return nil, fmt.Errorf("while expanding source path: %s: %s", src, err)
 	}
 	if len(paths) == 0 {
-		return fmt.Errorf("no source files found matching: %s", src)
+		return nil, fmt.Errorf("no source files found matching: %s", src)
 	}
 
+	copied := make([]string, 0, len(paths))
+
 	// We manually dereference first-level src symlinks only.
 	for _, srcGlobbed := range paths {
 		// Now re-resolve the source files after globbing by using securejoin,
@@ -132,7 +144,7 @@ func CopyFromStage(src, dst, srcRootfs, dstRootfs string) error {
 		srcGlobbedRel := strings.TrimPrefix(srcGlobbed, srcRootfs)
 		srcResolved, err := secureJoinKeepSlash(srcRootfs, srcGlobbedRel)
 		if err != nil {
-			return fmt.Errorf("while resolving source: %s: %s", srcGlobbedRel, err)
+			return nil, fmt.Errorf("while resolving source: %s: %s", srcGlobbedRel, err)
 		}
 
 		// If the dst is "" then we are copying to the same path in dstRootfs, as src is in srcRootfs.
@@ -143,11 +155,11 @@ func CopyFromStage(src, dst, srcRootfs, dstRootfs string) error {
 		// Resolve the destination path, keeping any final slash
 		dstResolved, err := secureJoinKeepSlash(dstRootfs, dstGlobbed)
 		if err != nil {
-			return fmt.Errorf("while resolving destination: %s: %s", dstGlobbed, err)
+			return nil, fmt.Errorf("while resolving destination: %s: %s", dstGlobbed, err)
 		}
 		// Create any parent dirs for dstResolved that don't already exist.
 		if err := makeParentDir(dstResolved); err != nil {
-			return fmt.Errorf("while creating parent dir: %v", err)
+			return nil, fmt.Errorf("while creating parent dir: %v", err)
 		}
 
 		// If we are copying into a directory then we must use the original source filename,
@@ -161,9 +173,48 @@ func CopyFromStage(src, dst, srcRootfs, dstRootfs string) error {
 
 		err = archive.CopyWithTar(srcResolved, dstResolved)
 		if err != nil {
-			return fmt.Errorf("while copying %s to %s: %s", paths, dstResolved, err)
+			return nil, fmt.Errorf("while copying %s to %s: %s", paths, dstResolved, err)
 		}
+		copied = append(copied, filepath.Clean(dstResolved))
+	}
+	return copied, nil
+}
 
+// ChownAll changes the ownership of path, and of its content if it's a
+// directory, to uid:gid. Symlinks are not followed.
+func ChownAll(path string, uid, gid int) error {
+	return filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := os.Lchown(p, uid, gid); err != nil {
+			return fmt.Errorf("while changing ownership of %s: %s", p, err)
+		}
+		return nil
+	})
+}
+
+// ChmodAll changes the permissions of path, and of its content if it's a
+// directory, to mode. Symlinks are skipped.
+func ChmodAll(path string, mode os.FileMode) error {
+	var paths []string
+	err := filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			paths = append(paths, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// content first, the mode may not allow to access the directory
+	for i := len(paths) - 1; i >= 0; i-- {
+		if err := os.Chmod(paths[i], mode); err != nil {
+			return fmt.Errorf("while changing permissions of %s: %s", paths[i], err)
+		}
 	}
 	return nil
 }
@@ -16,6 +16,16 @@ import (
 
 var sourceFileContent = "Source File Content\n"
 
+// containsPath returns true if path is one of paths.
+func containsPath(paths []string, path string) bool {
+	for _, p := range paths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
 func TestMakeParentDir(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -340,11 +350,15 @@ func TestCopyFromHost(t *testing.T) {
 			}
 			defer os.RemoveAll(dstRoot)
 
-			if err := CopyFromHost(tt.src, tt.dst, dstRoot); err != nil {
+			copied, err := CopyFromHost(tt.src, tt.dst, dstRoot)
+			if err != nil {
 				t.Errorf("unexpected failure running %s test: %s", t.Name(), err)
 			}
 
 			dstFinal := filepath.Join(dstRoot, tt.expectPath)
+			if !containsPath(copied, dstFinal) {
+				t.Errorf("destination %s not in copied paths %v", dstFinal, copied)
+			}
 			// verify file was copied
 			_, err = os.Stat(dstFinal)
 			if err != nil && !os.IsNotExist(err) {
@@ -421,7 +435,7 @@ func TestCopyFromHostNested(t *testing.T) {
 	defer os.RemoveAll(dstDir)
 
 	// Copy our source innerDir over into the destination dir
-	if err := CopyFromHost(innerDir, "innerDir", dstDir); err != nil {
+	if _, err := CopyFromHost(innerDir, "innerDir", dstDir); err != nil {
 		t.Errorf("unexpected failure copying directory: %s", err)
 	}
 
@@ -753,11 +767,15 @@ func TestCopyFromStage(t *testing.T) {
 
 			// Manually concatenating because we need to preserve any trailing slash that is
 			// stripped by Join.
-			if err := CopyFromStage(tt.srcRel, tt.dstRel, srcRoot, dstRoot); err != nil {
+			copied, err := CopyFromStage(tt.srcRel, tt.dstRel, srcRoot, dstRoot)
+			if err != nil {
 				t.Errorf("unexpected failure running %s test: %s", t.Name(), err)
 			}
 
 			dstFinal := filepath.Join(dstRoot, tt.expectPath)
+			if !containsPath(copied, dstFinal) {
+				t.Errorf("destination %s not in copied paths %v", dstFinal, copied)
+			}
 			// verify file was copied
 			_, err = os.Stat(dstFinal)
 			if err != nil && !os.IsNotExist(err) {
@@ -834,7 +852,7 @@ func TestCopyFromStageNested(t *testing.T) {
 	defer os.RemoveAll(dstRoot)
 
 	// Copy our source innerDir over into the destination dir
-	if err := CopyFromStage("innerDir", "", srcRoot, dstRoot); err != nil {
+	if _, err := CopyFromStage("innerDir", "", srcRoot, dstRoot); err != nil {
 		t.Errorf("unexpected failure copying directory: %s", err)
 	}
 
@@ -899,3 +917,44 @@ func TestCopyFromStageNested(t *testing.T) {
 		})
 	}
 }
+
+func TestChownChmodAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "attrs-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sub := filepath.Join(dir, "sub")
+	file := filepath.Join(sub, "file")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(sourceFileContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("file", filepath.Join(sub, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ChownAll(sub, os.Getuid(), os.Getgid()); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	// a mode denying access to the directory is applied to its content first
+	if err := ChmodAll(sub, 0o600); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, path := range []string{sub, file} {
+		fi, err := os.Lstat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0o600 {
+			t.Errorf("%s has mode %o, want 600", path, fi.Mode().Perm())
+		}
+	}
+	if err := os.Chmod(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
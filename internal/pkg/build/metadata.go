@@ -96,9 +96,11 @@ func insertEnvScript(b *types.Bundle) error {
 	return nil
 }
 
-// runscript and starscript should use this function to properly handle args and shebangs
-func handleShebangScript(s types.Script) (string, string) {
-	shebang := "#!/bin/sh"
+// runscript, startscript and test should use this function to properly
+// handle args and shebangs, an interpreter requested with the '-c' section
+// option takes precedence over the script shebang if it exists in rootfs
+func handleShebangScript(name string, rootfs string, s types.Script) (string, string) {
+	shebang := "#!" + defaultInterpreter
 	script := ""
 	if strings.HasPrefix(strings.TrimSpace(s.Script), "#!") {
 		// separate and cleanup shebang
@@ -111,9 +113,20 @@ func handleShebangScript(s types.Script) (string, string) {
 		script = s.Script
 	}
 
-	if s.Args != "" {
+	interpreter, params, err := sectionInterpreter(name, s)
+	if err != nil {
+		sylog.Warningf("Ignoring %%%s interpreter: %s", name, err)
+	} else if len(interpreter) > 0 {
+		if interpreterExists(rootfs, interpreter[0]) {
+			return "#!" + strings.Join(interpreter, " "), script
+		}
+		sylog.Warningf("%%%s interpreter %s not found in container, falling back to %s", name, interpreter[0], defaultInterpreter)
+		return "#!" + defaultInterpreter, script
+	}
+
+	if len(params) > 0 {
 		// add arg after trimming comments
-		shebang += " " + strings.Split(s.Args, "#")[0]
+		shebang += " " + strings.Join(params, " ")
 	}
 	return shebang, script
 }
@@ -121,7 +134,7 @@ func handleShebangScript(s types.Script) (string, string) {
 func insertRunScript(b *types.Bundle) error {
 	if b.RunSection("runscript") && b.Recipe.ImageData.Runscript.Script != "" {
 		sylog.Infof("Adding runscript")
-		shebang, script := handleShebangScript(b.Recipe.ImageData.Runscript)
+		shebang, script := handleShebangScript("runscript", b.RootfsPath, b.Recipe.ImageData.Runscript)
 		err := ioutil.WriteFile(filepath.Join(b.RootfsPath, "/.singularity.d/runscript"), []byte(shebang+"\n\n"+script+"\n"), 0o755)
 		if err != nil {
 			return err
@@ -133,7 +146,7 @@ func insertRunScript(b *types.Bundle) error {
 func insertStartScript(b *types.Bundle) error {
 	if b.RunSection("startscript") && b.Recipe.ImageData.Startscript.Script != "" {
 		sylog.Infof("Adding startscript")
-		shebang, script := handleShebangScript(b.Recipe.ImageData.Startscript)
+		shebang, script := handleShebangScript("startscript", b.RootfsPath, b.Recipe.ImageData.Startscript)
 		err := ioutil.WriteFile(filepath.Join(b.RootfsPath, "/.singularity.d/startscript"), []byte(shebang+"\n\n"+script+"\n"), 0o755)
 		if err != nil {
 			return err
@@ -145,7 +158,8 @@ func insertStartScript(b *types.Bundle) error {
 func insertTestScript(b *types.Bundle) error {
 	if b.RunSection("test") && b.Recipe.ImageData.Test.Script != "" {
 		sylog.Infof("Adding testscript")
-		err := ioutil.WriteFile(filepath.Join(b.RootfsPath, "/.singularity.d/test"), []byte("#!/bin/sh\n\n"+b.Recipe.ImageData.Test.Script+"\n"), 0o755)
+		shebang, script := handleShebangScript("test", b.RootfsPath, b.Recipe.ImageData.Test)
+		err := ioutil.WriteFile(filepath.Join(b.RootfsPath, "/.singularity.d/test"), []byte(shebang+"\n\n"+script+"\n"), 0o755)
 		if err != nil {
 			return err
 		}
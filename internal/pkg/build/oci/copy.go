@@ -0,0 +1,54 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// maxAuthRetries is the number of times a copy rejected by the registry
+// because of an expired token is retried.
+const maxAuthRetries = 3
+
+// minTokenLifetime is the shortest lifetime of a registry token. A copy
+// rejected by the registry after running for less than that didn't fail
+// because of an expired token, but because of invalid credentials.
+var minTokenLifetime = time.Minute
+
+// copyImage is the function copying images, replaced by tests.
+var copyImage = copy.Image
+
+// CopyImage copies the image src to dest with copy.Image. On long transfers,
+// the registry token can expire before all the blobs are fetched, and the
+// registry rejects the next requests. The copy is then retried from a new
+// image source requesting a fresh token, blobs already copied to dest are
+// reused instead of being fetched again.
+func CopyImage(ctx context.Context, policyCtx *signature.PolicyContext, dest, src types.ImageReference, opts *copy.Options) ([]byte, error) {
+	for i := 0; ; i++ {
+		start := time.Now()
+		manifest, err := copyImage(ctx, policyCtx, dest, src, opts)
+		if err == nil || i == maxAuthRetries || !isExpiredTokenError(err, time.Since(start)) {
+			return manifest, err
+		}
+		sylog.Infof("Registry token expired while copying image, retrying with a fresh token")
+		sylog.Debugf("Copy error: %s", err)
+	}
+}
+
+// isExpiredTokenError returns true if err, returned by a copy which ran for
+// elapsed, was caused by a registry token expiring during the copy.
+func isExpiredTokenError(err error, elapsed time.Duration) bool {
+	var authErr docker.ErrUnauthorizedForCredentials
+	return errors.As(err, &authErr) && elapsed >= minTokenLifetime
+}
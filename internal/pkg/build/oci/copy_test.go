@@ -0,0 +1,90 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/types"
+)
+
+func TestCopyImage(t *testing.T) {
+	defer func(f func(context.Context, *signature.PolicyContext, types.ImageReference, types.ImageReference, *copy.Options) ([]byte, error), d time.Duration) {
+		copyImage = f
+		minTokenLifetime = d
+	}(copyImage, minTokenLifetime)
+
+	authErr := fmt.Errorf("reading blob: %w", docker.ErrUnauthorizedForCredentials{Err: errors.New("unauthorized")})
+	otherErr := errors.New("connection refused")
+
+	tests := []struct {
+		name          string
+		errs          []error
+		tokenLifetime time.Duration
+		expectCalls   int
+		expectError   bool
+	}{
+		{
+			name:        "Success",
+			expectCalls: 1,
+		},
+		{
+			name:        "ExpiredToken",
+			errs:        []error{authErr, authErr},
+			expectCalls: 3,
+		},
+		{
+			name:          "InvalidCredentials",
+			errs:          []error{authErr},
+			tokenLifetime: time.Hour,
+			expectCalls:   1,
+			expectError:   true,
+		},
+		{
+			name:        "OtherError",
+			errs:        []error{otherErr},
+			expectCalls: 1,
+			expectError: true,
+		},
+		{
+			name:        "TooManyRetries",
+			errs:        []error{authErr, authErr, authErr, authErr, authErr},
+			expectCalls: maxAuthRetries + 1,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minTokenLifetime = tt.tokenLifetime
+			calls := 0
+			copyImage = func(context.Context, *signature.PolicyContext, types.ImageReference, types.ImageReference, *copy.Options) ([]byte, error) {
+				calls++
+				if calls <= len(tt.errs) {
+					return nil, tt.errs[calls-1]
+				}
+				return []byte("manifest"), nil
+			}
+
+			_, err := CopyImage(context.Background(), nil, nil, nil, nil)
+			if tt.expectError && err == nil {
+				t.Errorf("expected an error")
+			} else if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if calls != tt.expectCalls {
+				t.Errorf("got %d copies, want %d", calls, tt.expectCalls)
+			}
+		})
+	}
+}
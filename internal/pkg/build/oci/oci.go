@@ -14,18 +14,38 @@ import (
 	"strings"
 
 	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/manifest"
 	"github.com/containers/image/v5/oci/layout"
 	"github.com/containers/image/v5/signature"
 	"github.com/containers/image/v5/transports"
 	"github.com/containers/image/v5/types"
+	digest "github.com/opencontainers/go-digest"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
 	"github.com/sylabs/singularity/internal/pkg/cache"
 	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/util/singularityconf"
 )
 
+// RegistriesConfPath returns the registries.conf path set with
+// SetRegistriesConfPath or by the 'registries conf path' directive of
+// singularity.conf, or an empty string to let containers/image look for it
+// at the default locations.
+func RegistriesConfPath() string {
+	if registriesConfPath != "" {
+		return registriesConfPath
+	}
+	if cfg := singularityconf.GetCurrentConfig(); cfg != nil {
+		return cfg.RegistriesConfPath
+	}
+	return ""
+}
+
 // ImageReference wraps containers/image ImageReference type
 type ImageReference struct {
-	source types.ImageReference
+	source   types.ImageReference
+	imgCache *cache.Handle
+	tag      string
 	types.ImageReference
 }
 
@@ -34,6 +54,9 @@ func ConvertReference(ctx context.Context, imgCache *cache.Handle, src types.Ima
 	if imgCache == nil {
 		return nil, fmt.Errorf("undefined image cache")
 	}
+	if imgCache.IsDisabled() {
+		return nil, fmt.Errorf("image cache is disabled")
+	}
 
 	// Our cache dir is an OCI directory. We are using this as a 'blob pool'
 	// storing all incoming containers under unique tags, which are a hash of
@@ -54,6 +77,8 @@ func ConvertReference(ctx context.Context, imgCache *cache.Handle, src types.Ima
 
 	return &ImageReference{
 		source:         src,
+		imgCache:       imgCache,
+		tag:            cacheTag,
 		ImageReference: c,
 	}, nil
 }
@@ -70,8 +95,18 @@ func (t *ImageReference) newImageSource(ctx context.Context, sys *types.SystemCo
 		return nil, err
 	}
 
+	// The cache is locked, shared with concurrent pulls, while the image
+	// is fetched and its blobs are referenced by the cache index, preventing
+	// a concurrent clean from removing the blobs shared with this image
+	unlock, err := t.imgCache.LockOciCache(cache.OciBlobCacheType)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	// First we are fetching into the cache
-	_, err = copy.Image(ctx, policyCtx, t.ImageReference, t.source, &copy.Options{
+	dst := cacheReference{ImageReference: t.ImageReference, imgCache: t.imgCache, tag: t.tag}
+	_, err = CopyImage(ctx, policyCtx, dst, t.source, &copy.Options{
 		ReportWriter: w,
 		SourceCtx:    sys,
 	})
@@ -81,6 +116,64 @@ func (t *ImageReference) newImageSource(ctx context.Context, sys *types.SystemCo
 	return t.ImageReference.NewImageSource(ctx, sys)
 }
 
+// cacheReference wraps the cache oci-layout reference to write images with
+// a cacheDestination.
+type cacheReference struct {
+	types.ImageReference
+	imgCache *cache.Handle
+	tag      string
+}
+
+// NewImageDestination returns a cacheDestination writing to the cache oci-layout.
+func (r cacheReference) NewImageDestination(ctx context.Context, sys *types.SystemContext) (types.ImageDestination, error) {
+	d, err := r.ImageReference.NewImageDestination(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	return &cacheDestination{ImageDestination: d, ref: r}, nil
+}
+
+// cacheDestination writes the image blobs and manifest to the cache
+// oci-layout, and adds the image to the cache index on commit. The
+// oci-layout destination it wraps would rewrite the index read when it was
+// created, dropping the images added by concurrent pulls meanwhile.
+type cacheDestination struct {
+	types.ImageDestination
+	ref  cacheReference
+	desc *imgspecv1.Descriptor
+}
+
+// PutManifest writes the manifest m to the cache, and records the image
+// descriptor added to the index on commit.
+func (d *cacheDestination) PutManifest(ctx context.Context, m []byte, instanceDigest *digest.Digest) error {
+	if err := d.ImageDestination.PutManifest(ctx, m, instanceDigest); err != nil {
+		return err
+	}
+	if instanceDigest != nil {
+		return nil
+	}
+
+	dgst, err := manifest.Digest(m)
+	if err != nil {
+		return err
+	}
+	d.desc = &imgspecv1.Descriptor{
+		MediaType:   manifest.GuessMIMEType(m),
+		Digest:      dgst,
+		Size:        int64(len(m)),
+		Annotations: map[string]string{imgspecv1.AnnotationRefName: d.ref.tag},
+	}
+	return nil
+}
+
+// Commit adds the image to the cache index.
+func (d *cacheDestination) Commit(ctx context.Context, unparsedToplevel types.UnparsedImage) error {
+	if d.desc == nil {
+		return fmt.Errorf("no image manifest written to the cache")
+	}
+	return d.ref.imgCache.AddOciCacheManifest(cache.OciBlobCacheType, *d.desc)
+}
+
 // ParseImageName parses a uri (e.g. docker://ubuntu) into it's transport:reference
 // combination and then returns the proper reference
 func ParseImageName(ctx context.Context, imgCache *cache.Handle, uri string, sys *types.SystemContext) (types.ImageReference, error) {
@@ -284,6 +284,16 @@ func TestConvertReference(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("disabled cache", func(t *testing.T) {
+		disabledCache, err := cache.New(cache.Config{Disable: true})
+		if err != nil {
+			t.Fatalf("failed to create a disabled image cache handle")
+		}
+		if _, err := ConvertReference(context.Background(), disabledCache, createValidImageRef(t, ref), nil); err == nil {
+			t.Fatal("test expected to fail but succeeded")
+		}
+	})
 }
 
 // TestImageNameAndImageSHA tests both ImageName() and ImageSHA()
@@ -0,0 +1,79 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/pkg/sysregistriesv2"
+	"github.com/containers/image/v5/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// registriesConfPath overrides the 'registries conf path' directive when
+// set with SetRegistriesConfPath.
+var registriesConfPath string
+
+// SetRegistriesConfPath sets the registries.conf path returned by
+// RegistriesConfPath, in place of the 'registries conf path' directive.
+func SetRegistriesConfPath(path string) {
+	registriesConfPath = path
+}
+
+// isShortName returns true if the docker image reference ref, without the
+// docker:// prefix, doesn't start with a registry host name, following the
+// same rule as the docker reference parser.
+func isShortName(ref string) bool {
+	i := strings.IndexByte(ref, '/')
+	if i < 0 {
+		return true
+	}
+	domain := ref[:i]
+	return !strings.ContainsAny(domain, ".:") && domain != "localhost"
+}
+
+// ResolveShortName returns the docker image reference ref, without the
+// docker:// prefix, qualified with the first of the unqualified-search
+// registries of registries.conf holding the image, when ref doesn't start
+// with a registry host name. ref is returned unchanged if it's qualified, or
+// if no unqualified-search registries are configured, in which case the
+// image is pulled from docker.io.
+func ResolveShortName(ctx context.Context, ref string, sys *types.SystemContext) (string, error) {
+	if !isShortName(ref) {
+		return ref, nil
+	}
+
+	registries, err := sysregistriesv2.UnqualifiedSearchRegistries(sys)
+	if err != nil {
+		return "", fmt.Errorf("while reading unqualified-search registries: %s", err)
+	}
+	if len(registries) == 0 {
+		return ref, nil
+	}
+
+	var errs []string
+	for _, registry := range registries {
+		name := registry + "/" + ref
+		r, err := docker.ParseReference("//" + name)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+		src, err := r.NewImageSource(ctx, sys)
+		if err != nil {
+			sylog.Debugf("Image %s not found: %s", name, err)
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+			continue
+		}
+		src.Close()
+		sylog.Verbosef("Resolved short name %s to %s", ref, name)
+		return name, nil
+	}
+	return "", fmt.Errorf("image %s not found in unqualified-search registries:\n%s", ref, strings.Join(errs, "\n"))
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import "testing"
+
+func TestIsShortName(t *testing.T) {
+	tests := []struct {
+		ref   string
+		short bool
+	}{
+		{"alpine", true},
+		{"alpine:3.15", true},
+		{"library/alpine:3.15", true},
+		{"user/repo/image", true},
+		{"docker.io/library/alpine", false},
+		{"quay.io/user/image:tag", false},
+		{"registry:5000/image", false},
+		{"localhost/image", false},
+	}
+
+	for _, tt := range tests {
+		if got := isShortName(tt.ref); got != tt.short {
+			t.Errorf("isShortName(%q) = %v, want %v", tt.ref, got, tt.short)
+		}
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/sylabs/singularity/internal/pkg/util/bin"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/pkg/image"
+	"github.com/sylabs/singularity/pkg/sylog"
+	"golang.org/x/sys/unix"
+)
+
+// overlayOpaqueXattrs are the extended attributes marking an overlay upper
+// directory as opaque, hiding the content of the lower directory. The user
+// namespace variant is set by unprivileged overlay mounts.
+var overlayOpaqueXattrs = []string{"trusted.overlay.opaque", "user.overlay.opaque"}
+
+// overlayUpperDir returns the upper directory of the writable overlay at
+// path, an overlay directory or an EXT3 overlay image, standalone or
+// embedded in a SIF image. EXT3 images are mounted read-only, the returned
+// function releases the upper directory.
+func overlayUpperDir(path, tmpDir string) (string, func(), error) {
+	if fs.IsDir(path) {
+		upper := filepath.Join(path, "upper")
+		if fs.IsDir(upper) {
+			return upper, func() {}, nil
+		}
+		return path, func() {}, nil
+	}
+
+	img, err := image.Init(path, false)
+	if err != nil {
+		return "", nil, fmt.Errorf("while opening overlay %s: %s", path, err)
+	}
+	defer img.File.Close()
+
+	var part *image.Section
+	switch img.Type {
+	case image.EXT3:
+		part = &img.Partitions[0]
+	case image.SIF:
+		overlays, err := img.GetOverlayPartitions()
+		if err != nil {
+			return "", nil, fmt.Errorf("while getting SIF overlay partitions: %s", err)
+		}
+		for i := range overlays {
+			if overlays[i].Type == image.EXT3 {
+				part = &overlays[i]
+				break
+			}
+		}
+		if part == nil {
+			return "", nil, fmt.Errorf("no EXT3 overlay partition found in %s", path)
+		}
+	default:
+		return "", nil, fmt.Errorf("overlay %s must be a directory or an EXT3 image", path)
+	}
+
+	if os.Geteuid() != 0 {
+		return "", nil, fmt.Errorf("EXT3 overlay images can only be mounted by root, use an overlay directory instead")
+	}
+	mount, err := bin.FindBin("mount")
+	if err != nil {
+		return "", nil, err
+	}
+
+	mnt, err := ioutil.TempDir(tmpDir, "overlay-mnt-")
+	if err != nil {
+		return "", nil, fmt.Errorf("while creating overlay mount point: %s", err)
+	}
+	opts := fmt.Sprintf("loop,ro,nodev,nosuid,offset=%d,sizelimit=%d", part.Offset, part.Size)
+	errBuf := new(bytes.Buffer)
+	cmd := exec.Command(mount, "-t", "ext3", "-o", opts, path, mnt)
+	cmd.Stderr = errBuf
+	if err := cmd.Run(); err != nil {
+		os.Remove(mnt)
+		return "", nil, fmt.Errorf("while mounting overlay %s: %s\nCommand error: %s", path, err, errBuf)
+	}
+	release := func() {
+		if err := syscall.Unmount(mnt, syscall.MNT_DETACH); err != nil {
+			sylog.Warningf("While unmounting overlay %s: %s", mnt, err)
+			return
+		}
+		os.Remove(mnt)
+	}
+
+	upper := filepath.Join(mnt, "upper")
+	if !fs.IsDir(upper) {
+		release()
+		return "", nil, fmt.Errorf("overlay %s has no upper directory", path)
+	}
+	return upper, release, nil
+}
+
+// isWhiteout returns true if fi describes an overlay whiteout, a 0/0
+// character device recording the removal of the lower file.
+func isWhiteout(fi os.FileInfo) bool {
+	if fi.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	return ok && st.Rdev == 0
+}
+
+// isOpaque returns true if the overlay upper directory at path is opaque.
+func isOpaque(path string) bool {
+	buf := make([]byte, 1)
+	for _, attr := range overlayOpaqueXattrs {
+		n, err := unix.Lgetxattr(path, attr, buf)
+		if err == nil && n == 1 && buf[0] == 'y' {
+			return true
+		}
+	}
+	return false
+}
+
+// applyOverlay applies the changes recorded in the overlay upper directory
+// upper to the root filesystem rootfs: whiteouts remove the corresponding
+// files, opaque directories replace the corresponding directories, and
+// other files are added or replace the corresponding files. Ownership is
+// only preserved when running as root.
+func applyOverlay(upper, rootfs string) error {
+	type dirAttr struct {
+		path string
+		fi   os.FileInfo
+	}
+	var dirs []dirAttr
+
+	chown := os.Geteuid() == 0
+
+	err := filepath.Walk(upper, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(upper, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		dst := filepath.Join(rootfs, rel)
+
+		if isWhiteout(fi) {
+			sylog.Debugf("Removing /%s", rel)
+			return os.RemoveAll(dst)
+		}
+
+		// parents were processed first, dst can't resolve through a
+		// symlink and is replaced if it's not of the same type
+		dfi, err := os.Lstat(dst)
+		if err == nil && (!fi.IsDir() || !dfi.IsDir() || isOpaque(path)) {
+			if err := os.RemoveAll(dst); err != nil {
+				return err
+			}
+			dfi = nil
+		} else if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		switch mode := fi.Mode(); {
+		case mode.IsDir():
+			// the directory must be writable until its content is written,
+			// its permissions are set afterwards
+			if dfi != nil {
+				err = os.Chmod(dst, dfi.Mode().Perm()|0o700)
+			} else {
+				err = os.Mkdir(dst, 0o700)
+			}
+			if err != nil {
+				return err
+			}
+			dirs = append(dirs, dirAttr{dst, fi})
+			return nil
+		case mode.IsRegular():
+			if err := fs.CopyFile(path, dst, mode.Perm()); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(target, dst); err != nil {
+				return err
+			}
+		default:
+			st := fi.Sys().(*syscall.Stat_t)
+			if err := unix.Mknod(dst, st.Mode, int(st.Rdev)); err != nil {
+				return fmt.Errorf("while creating special file /%s: %s", rel, err)
+			}
+		}
+
+		if chown {
+			st := fi.Sys().(*syscall.Stat_t)
+			if err := os.Lchown(dst, int(st.Uid), int(st.Gid)); err != nil {
+				return err
+			}
+		}
+		if fi.Mode()&os.ModeSymlink == 0 {
+			if err := os.Chmod(dst, fi.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+				return err
+			}
+			return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// deepest directories first, so that setting the modification time of
+	// a directory isn't undone by its subdirectories
+	for i := len(dirs) - 1; i >= 0; i-- {
+		d := dirs[i]
+		if chown {
+			st := d.fi.Sys().(*syscall.Stat_t)
+			if err := os.Lchown(d.path, int(st.Uid), int(st.Gid)); err != nil {
+				return err
+			}
+		}
+		if err := os.Chmod(d.path, d.fi.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+		if err := os.Chtimes(d.path, d.fi.ModTime(), d.fi.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commitOverlay applies the changes recorded in the writable overlay at
+// path to the root filesystem of the stage.
+func (s *stage) commitOverlay(path string) error {
+	upper, release, err := overlayUpperDir(path, s.b.TmpDir)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return applyOverlay(upper, s.b.RootfsPath)
+}
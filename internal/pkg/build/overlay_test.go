@@ -0,0 +1,82 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestApplyOverlay(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "overlay-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	rootfs := filepath.Join(tmpDir, "rootfs")
+	upper := filepath.Join(tmpDir, "upper")
+
+	write := func(path, content string) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(filepath.Join(rootfs, "etc", "kept"), "kept")
+	write(filepath.Join(rootfs, "etc", "modified"), "lower")
+	write(filepath.Join(rootfs, "etc", "removed"), "removed")
+	write(filepath.Join(rootfs, "opaque", "hidden"), "hidden")
+	write(filepath.Join(rootfs, "replaced", "file"), "file")
+
+	write(filepath.Join(upper, "etc", "modified"), "upper")
+	write(filepath.Join(upper, "etc", "added"), "added")
+	write(filepath.Join(upper, "opaque", "visible"), "visible")
+	write(filepath.Join(upper, "replaced"), "now a file")
+	if err := os.Symlink("added", filepath.Join(upper, "etc", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := unix.Mknod(filepath.Join(upper, "etc", "removed"), unix.S_IFCHR, 0); err != nil {
+		t.Skipf("can't create whiteout: %s", err)
+	}
+	if err := unix.Setxattr(filepath.Join(upper, "opaque"), "user.overlay.opaque", []byte("y"), 0); err != nil {
+		t.Skipf("can't mark directory as opaque: %s", err)
+	}
+
+	if err := applyOverlay(upper, rootfs); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := map[string]string{
+		"etc/kept":       "kept",
+		"etc/modified":   "upper",
+		"etc/added":      "added",
+		"etc/link":       "added",
+		"opaque/visible": "visible",
+		"replaced":       "now a file",
+	}
+	for path, content := range want {
+		b, err := ioutil.ReadFile(filepath.Join(rootfs, path))
+		if err != nil {
+			t.Errorf("unexpected error reading %s: %s", path, err)
+		} else if string(b) != content {
+			t.Errorf("unexpected content %q for %s, want %q", b, path, content)
+		}
+	}
+	for _, path := range []string{"etc/removed", "opaque/hidden"} {
+		if _, err := os.Lstat(filepath.Join(rootfs, path)); !os.IsNotExist(err) {
+			t.Errorf("%s not removed", path)
+		}
+	}
+}
@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -30,6 +30,14 @@ import (
 	useragent "github.com/sylabs/singularity/pkg/util/user-agent"
 )
 
+// outputRetries is the number of times the build output stream is
+// reopened when the connection to the remote build service drops.
+const outputRetries = 5
+
+// outputRetryDelay is the time to wait before reopening the build output
+// stream, it is a variable so tests can reduce it.
+var outputRetryDelay = 5 * time.Second
+
 // RemoteBuilder contains the build request and response
 type RemoteBuilder struct {
 	BuildClient         *buildclient.Client
@@ -112,15 +120,9 @@ func (rb *RemoteBuilder) Build(ctx context.Context) (err error) {
 	}
 
 	// We're doing an attached build, stream output and then download the resulting file
-	err = rb.BuildClient.GetOutput(ctx, bi.ID, os.Stdout)
-	if err != nil {
-		return errors.Wrap(err, "failed to stream output from remote build service")
-	}
-
-	// Get build status
-	bi, err = rb.BuildClient.GetStatus(ctx, bi.ID)
+	bi, err = rb.streamOutput(ctx, bi.ID)
 	if err != nil {
-		return errors.Wrap(err, "failed to get status from remote build service")
+		return err
 	}
 
 	// Do not try to download image if not complete or image size is 0
@@ -128,7 +130,7 @@ func (rb *RemoteBuilder) Build(ctx context.Context) (err error) {
 		return errors.New("build has not completed")
 	}
 	if bi.ImageSize <= 0 {
-		return errors.New("build image size <= 0")
+		return errors.New("build failed, no image was produced")
 	}
 
 	// If image destination is local file, pull image.
@@ -160,3 +162,41 @@ func (rb *RemoteBuilder) Build(ctx context.Context) (err error) {
 
 	return nil
 }
+
+// streamOutput streams the output of the build buildID to stdout until the
+// build service closes the stream, and returns the final build status. If the
+// connection drops while the build is still running, the stream is reopened
+// up to outputRetries times.
+func (rb *RemoteBuilder) streamOutput(ctx context.Context, buildID string) (buildclient.BuildInfo, error) {
+	for retry := 0; ; retry++ {
+		outputErr := rb.BuildClient.GetOutput(ctx, buildID, os.Stdout)
+		if ctx.Err() != nil {
+			return buildclient.BuildInfo{}, errors.Wrap(ctx.Err(), "remote build cancelled")
+		}
+
+		bi, statusErr := rb.BuildClient.GetStatus(ctx, buildID)
+		if statusErr == nil && (bi.IsComplete || outputErr == nil) {
+			return bi, nil
+		}
+
+		if retry == outputRetries {
+			if outputErr != nil {
+				return bi, errors.Wrap(outputErr, "failed to stream output from remote build service")
+			}
+			return bi, errors.Wrap(statusErr, "failed to get status from remote build service")
+		}
+
+		if outputErr != nil {
+			sylog.Warningf("Lost connection to remote build service: %v", outputErr)
+		} else {
+			sylog.Warningf("Failed to get status from remote build service: %v", statusErr)
+		}
+		sylog.Infof("Reconnecting to remote build service in %s (%d/%d)", outputRetryDelay, retry+1, outputRetries)
+
+		select {
+		case <-ctx.Done():
+			return buildclient.BuildInfo{}, errors.Wrap(ctx.Err(), "remote build cancelled")
+		case <-time.After(outputRetryDelay):
+		}
+	}
+}
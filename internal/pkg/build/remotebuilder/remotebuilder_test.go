@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2020, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -6,9 +6,15 @@
 package remotebuilder
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sylabs/singularity/internal/pkg/test"
 	"github.com/sylabs/singularity/pkg/build/types"
@@ -54,3 +60,66 @@ func TestBuild(t *testing.T) {
 		}))
 	}
 }
+
+func TestStreamOutput(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	outputRetryDelay = time.Millisecond
+	defer func() {
+		outputRetryDelay = 5 * time.Second
+	}()
+
+	tests := []struct {
+		name            string
+		completeAfter   int
+		expectComplete  bool
+		expectSuccess   bool
+		expectedStreams int
+	}{
+		{"CompleteAfterReconnect", 2, true, true, 3},
+		{"NeverComplete", outputRetries + 10, false, false, outputRetries + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams := 0
+			statuses := 0
+
+			// the output stream is never upgraded to a websocket, so each
+			// connection attempt fails as if the connection dropped
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasPrefix(r.URL.Path, "/v1/build-ws/"):
+					streams++
+					w.WriteHeader(http.StatusBadGateway)
+				case strings.HasPrefix(r.URL.Path, "/v1/build/"):
+					statuses++
+					w.Header().Set("Content-Type", "application/json")
+					fmt.Fprintf(w, `{"data":{"id":"1","isComplete":%t,"imageSize":1}}`, statuses > tt.completeAfter)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			rb, err := New("", "", types.Definition{}, false, false, srv.URL, "", runtime.GOARCH, "")
+			if err != nil {
+				t.Fatalf("failed to create remote builder: %s", err)
+			}
+
+			bi, err := rb.streamOutput(context.Background(), "1")
+			if err != nil && tt.expectSuccess {
+				t.Fatalf("unexpected failure: %s", err)
+			} else if err == nil && !tt.expectSuccess {
+				t.Fatalf("unexpected success")
+			}
+			if bi.IsComplete != tt.expectComplete {
+				t.Errorf("unexpected build completion %t", bi.IsComplete)
+			}
+			if streams != tt.expectedStreams {
+				t.Errorf("unexpected number of output streams %d, expected %d", streams, tt.expectedStreams)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/containers/image/v5/copy"
 	"github.com/containers/image/v5/docker"
@@ -33,6 +34,7 @@ import (
 	"github.com/sylabs/singularity/internal/pkg/util/shell"
 	sytypes "github.com/sylabs/singularity/pkg/build/types"
 	"github.com/sylabs/singularity/pkg/image"
+	"github.com/sylabs/singularity/pkg/inspect"
 	"github.com/sylabs/singularity/pkg/syfs"
 	"github.com/sylabs/singularity/pkg/sylog"
 	useragent "github.com/sylabs/singularity/pkg/util/user-agent"
@@ -46,6 +48,9 @@ type OCIConveyorPacker struct {
 	policyCtx *signature.PolicyContext
 	imgConfig imgspecv1.ImageConfig
 	sysCtx    *types.SystemContext
+	// healthcheck holds the Docker HEALTHCHECK not part of the OCI
+	// image specification
+	healthcheck *inspect.Healthcheck
 }
 
 // Get downloads container information from the specified source
@@ -70,10 +75,20 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 		AuthFilePath:             syfs.DockerConf(),
 		DockerRegistryUserAgent:  useragent.Value(),
 		BigFilesTemporaryDir:     b.TmpDir,
+		SystemRegistriesConfPath: oci.RegistriesConfPath(),
+	}
+	if cp.b.Opts.DockerAuthFile != "" {
+		cp.sysCtx.AuthFilePath = cp.b.Opts.DockerAuthFile
 	}
 	if cp.b.Opts.NoHTTPS {
 		cp.sysCtx.DockerInsecureSkipTLSVerify = types.NewOptionalBool(true)
 	}
+	// the blob info cache shared with other containers tools records the
+	// known locations and compressed variants of blobs, keep it private to
+	// this build when the cache is disabled
+	if cp.b.Opts.NoCache {
+		cp.sysCtx.BlobInfoCacheDir = b.TmpDir
+	}
 
 	// add registry and namespace to reference if specified
 	ref := b.Recipe.Header["from"]
@@ -87,8 +102,11 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 
 	switch b.Recipe.Header["bootstrap"] {
 	case "docker":
-		ref = "//" + ref
-		cp.srcRef, err = docker.ParseReference(ref)
+		ref, err = oci.ResolveShortName(ctx, ref, cp.sysCtx)
+		if err != nil {
+			return err
+		}
+		cp.srcRef, err = docker.ParseReference("//" + ref)
 	case "docker-archive":
 		cp.srcRef, err = dockerarchive.ParseReference(ref)
 	case "docker-daemon":
@@ -132,7 +150,7 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 		return fmt.Errorf("invalid image source: %v", err)
 	}
 
-	if !cp.b.Opts.NoCache {
+	if !cp.b.Opts.NoCache && !b.Opts.ImgCache.IsDisabled() {
 		// Grab the modified source ref from the cache
 		cp.srcRef, err = oci.ConvertReference(ctx, b.Opts.ImgCache, cp.srcRef, cp.sysCtx)
 		if err != nil {
@@ -154,6 +172,11 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 		return err
 	}
 
+	cp.healthcheck, err = cp.getHealthcheck(ctx)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -189,12 +212,17 @@ func (cp *OCIConveyorPacker) Pack(ctx context.Context) (*sytypes.Bundle, error)
 		return nil, fmt.Errorf("while inserting oci labels: %v", err)
 	}
 
+	err = cp.insertHealthcheck()
+	if err != nil {
+		return nil, fmt.Errorf("while inserting healthcheck: %v", err)
+	}
+
 	return cp.b, nil
 }
 
 func (cp *OCIConveyorPacker) fetch(ctx context.Context) error {
 	// cp.srcRef contains the cache source reference
-	_, err := copy.Image(ctx, cp.policyCtx, cp.tmpfsRef, cp.srcRef, &copy.Options{
+	_, err := oci.CopyImage(ctx, cp.policyCtx, cp.tmpfsRef, cp.srcRef, &copy.Options{
 		ReportWriter: ioutil.Discard,
 		SourceCtx:    cp.sysCtx,
 	})
@@ -215,6 +243,66 @@ func (cp *OCIConveyorPacker) getConfig(ctx context.Context) (imgspecv1.ImageConf
 	return imgSpec.Config, nil
 }
 
+// getHealthcheck returns the Docker HEALTHCHECK found in the image
+// configuration, if any.
+func (cp *OCIConveyorPacker) getHealthcheck(ctx context.Context) (*inspect.Healthcheck, error) {
+	img, err := cp.srcRef.NewImage(ctx, cp.sysCtx)
+	if err != nil {
+		return nil, err
+	}
+	defer img.Close()
+
+	blob, err := img.ConfigBlob(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return parseHealthcheck(blob)
+}
+
+// parseHealthcheck extracts the health check from a Docker image
+// configuration blob, images built by Docker also carry it in their OCI
+// image configuration. A disabled health check is ignored.
+func parseHealthcheck(blob []byte) (*inspect.Healthcheck, error) {
+	var config struct {
+		Config struct {
+			Healthcheck *struct {
+				Test        []string
+				Interval    time.Duration
+				Timeout     time.Duration
+				StartPeriod time.Duration
+				Retries     int
+			}
+		} `json:"config"`
+	}
+
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	if err := json.Unmarshal(blob, &config); err != nil {
+		return nil, fmt.Errorf("while decoding image configuration: %s", err)
+	}
+
+	hc := config.Config.Healthcheck
+	if hc == nil || len(hc.Test) == 0 || hc.Test[0] == "NONE" {
+		return nil, nil
+	}
+
+	switch hc.Test[0] {
+	case "CMD", "CMD-SHELL":
+	default:
+		sylog.Warningf("Ignoring healthcheck with unsupported test %q", hc.Test[0])
+		return nil, nil
+	}
+
+	return &inspect.Healthcheck{
+		Test:        hc.Test,
+		Interval:    hc.Interval,
+		Timeout:     hc.Timeout,
+		StartPeriod: hc.StartPeriod,
+		Retries:     hc.Retries,
+	}, nil
+}
+
 func (cp *OCIConveyorPacker) insertOCIConfig() error {
 	conf, err := json.Marshal(cp.imgConfig)
 	if err != nil {
@@ -461,6 +549,19 @@ func (cp *OCIConveyorPacker) insertOCILabels() (err error) {
 	return err
 }
 
+func (cp *OCIConveyorPacker) insertHealthcheck() error {
+	if cp.healthcheck == nil {
+		return nil
+	}
+
+	text, err := json.MarshalIndent(cp.healthcheck, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(cp.b.RootfsPath, "/.singularity.d/healthcheck.json"), text, 0o644)
+}
+
 // CleanUp removes any tmpfs owned by the conveyorPacker on the filesystem
 func (cp *OCIConveyorPacker) CleanUp() {
 	cp.b.Remove()
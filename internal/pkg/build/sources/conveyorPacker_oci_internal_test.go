@@ -0,0 +1,159 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/inspect"
+)
+
+func TestOCIRunscript(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not found")
+	}
+
+	printArgs := []string{"printf", `%s\n`}
+
+	tests := []struct {
+		name       string
+		entrypoint []string
+		cmd        []string
+		args       []string
+		expected   []string
+	}{
+		{
+			name:       "EntrypointArgs",
+			entrypoint: printArgs,
+			args:       []string{"$FOO", "a b"},
+			expected:   []string{"bar", "a b"},
+		},
+		{
+			name:       "EntrypointCmd",
+			entrypoint: printArgs,
+			cmd:        []string{"$FOO", "`echo x`"},
+			expected:   []string{"$FOO", "`echo x`"},
+		},
+		{
+			name:     "CmdArgs",
+			cmd:      []string{"false"},
+			args:     []string{"printf", `%s\n`, "$(echo x)"},
+			expected: []string{"x"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootfs, err := ioutil.TempDir("", "runscript-")
+			if err != nil {
+				t.Fatalf("while creating temporary directory: %s", err)
+			}
+			defer os.RemoveAll(rootfs)
+
+			if err := os.Mkdir(filepath.Join(rootfs, ".singularity.d"), 0o755); err != nil {
+				t.Fatalf("while creating .singularity.d: %s", err)
+			}
+
+			cp := &OCIConveyorPacker{
+				b: &types.Bundle{RootfsPath: rootfs},
+				imgConfig: imgspecv1.ImageConfig{
+					Entrypoint: tt.entrypoint,
+					Cmd:        tt.cmd,
+				},
+			}
+			if err := cp.insertRunScript(); err != nil {
+				t.Fatalf("while generating runscript: %s", err)
+			}
+
+			cmd := exec.Command(sh, append([]string{filepath.Join(rootfs, ".singularity.d", "runscript")}, tt.args...)...)
+			cmd.Env = []string{"PATH=" + os.Getenv("PATH"), "FOO=bar"}
+
+			out, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("while running runscript: %s", err)
+			}
+
+			output := strings.Split(strings.TrimSuffix(string(out), "\n"), "\n")
+			if !reflect.DeepEqual(output, tt.expected) {
+				t.Errorf("unexpected arguments %q, expected %q", output, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseHealthcheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		blob        string
+		expected    *inspect.Healthcheck
+		expectError bool
+	}{
+		{
+			name: "NoConfig",
+			blob: "",
+		},
+		{
+			name: "NoHealthcheck",
+			blob: `{"config":{"Cmd":["/bin/sh"]}}`,
+		},
+		{
+			name: "Disabled",
+			blob: `{"config":{"Healthcheck":{"Test":["NONE"]}}}`,
+		},
+		{
+			name: "Unsupported",
+			blob: `{"config":{"Healthcheck":{"Test":["FOO","bar"]}}}`,
+		},
+		{
+			name: "Shell",
+			blob: `{"config":{"Healthcheck":{"Test":["CMD-SHELL","curl -f http://localhost/"],` +
+				`"Interval":5000000000,"Timeout":1000000000,"StartPeriod":2000000000,"Retries":2}}}`,
+			expected: &inspect.Healthcheck{
+				Test:        []string{"CMD-SHELL", "curl -f http://localhost/"},
+				Interval:    5 * time.Second,
+				Timeout:     time.Second,
+				StartPeriod: 2 * time.Second,
+				Retries:     2,
+			},
+		},
+		{
+			name: "Exec",
+			blob: `{"config":{"Healthcheck":{"Test":["CMD","/bin/true"]}}}`,
+			expected: &inspect.Healthcheck{
+				Test: []string{"CMD", "/bin/true"},
+			},
+		},
+		{
+			name:        "Invalid",
+			blob:        `{"config":`,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc, err := parseHealthcheck([]byte(tt.blob))
+			if err != nil && !tt.expectError {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Fatalf("unexpected success")
+			}
+			if !reflect.DeepEqual(hc, tt.expected) {
+				t.Errorf("unexpected healthcheck %+v, expected %+v", hc, tt.expected)
+			}
+		})
+	}
+}
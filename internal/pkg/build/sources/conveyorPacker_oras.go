@@ -1,4 +1,4 @@
-// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// Copyright (c) 2020-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -6,9 +6,15 @@
 package sources
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 
+	umocilayer "github.com/opencontainers/umoci/oci/layer"
 	"github.com/sylabs/singularity/internal/pkg/client/oras"
 	"github.com/sylabs/singularity/pkg/build/types"
 	"github.com/sylabs/singularity/pkg/sylog"
@@ -29,7 +35,15 @@ func (cp *OrasConveyorPacker) Get(ctx context.Context, b *types.Bundle) (err err
 	// full uri for name determination and output
 	fullRef := "oras:" + ref
 
-	imagePath, err := oras.Pull(ctx, b.Opts.ImgCache, fullRef, b.Opts.TmpDir, b.Opts.DockerAuthConfig)
+	rootfs, err := oras.IsRootfs(ctx, fullRef, b.Opts.DockerAuthConfig, b.Opts.DockerAuthFile)
+	if err != nil {
+		return fmt.Errorf("while fetching artifact manifest: %v", err)
+	}
+	if rootfs {
+		return cp.getRootfs(ctx, b, fullRef)
+	}
+
+	imagePath, err := oras.Pull(ctx, b.Opts.ImgCache, fullRef, b.Opts.TmpDir, b.Opts.DockerAuthConfig, b.Opts.DockerAuthFile)
 	if err != nil {
 		return fmt.Errorf("while fetching library image: %v", err)
 	}
@@ -42,3 +56,61 @@ func (cp *OrasConveyorPacker) Get(ctx context.Context, b *types.Bundle) (err err
 	cp.LocalPacker, err = GetLocalPacker(ctx, imagePath, b)
 	return err
 }
+
+// getRootfs downloads the root filesystem tarball held by the artifact
+// fullRef and extracts it into the bundle root filesystem.
+func (cp *OrasConveyorPacker) getRootfs(ctx context.Context, b *types.Bundle, fullRef string) error {
+	tarPath := filepath.Join(b.TmpDir, "rootfs.tar")
+	defer os.Remove(tarPath)
+
+	if _, err := oras.DownloadRootfs(ctx, tarPath, fullRef, b.Opts.DockerAuthConfig, b.Opts.DockerAuthFile); err != nil {
+		return fmt.Errorf("while fetching rootfs tarball: %v", err)
+	}
+
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return fmt.Errorf("while opening rootfs tarball: %v", err)
+	}
+	defer f.Close()
+
+	// layers pushed with a generic media type may be compressed or not
+	br := bufio.NewReader(f)
+	var r io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("while decompressing rootfs tarball: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	mapOptions, err := getMapOptions()
+	if err != nil {
+		return err
+	}
+
+	sylog.Infof("Extracting rootfs tarball")
+	if err := umocilayer.UnpackLayer(b.RootfsPath, r, &umocilayer.UnpackOptions{MapOptions: mapOptions}); err != nil {
+		return fmt.Errorf("while extracting rootfs tarball: %v", err)
+	}
+
+	// insert base metadata after extracting the tarball, missing
+	// directories and files are created
+	if err := makeBaseEnv(b.RootfsPath); err != nil {
+		return fmt.Errorf("while inserting base environment: %v", err)
+	}
+
+	cp.LocalPacker = &rootfsPacker{b: b}
+	return nil
+}
+
+// rootfsPacker packs a bundle whose root filesystem is already populated.
+type rootfsPacker struct {
+	b *types.Bundle
+}
+
+// Pack returns the bundle as is.
+func (p *rootfsPacker) Pack(context.Context) (*types.Bundle, error) {
+	return p.b, nil
+}
@@ -14,7 +14,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	apexlog "github.com/apex/log"
 	"github.com/containers/image/v5/types"
@@ -27,10 +29,29 @@ import (
 	"github.com/sylabs/singularity/pkg/sylog"
 )
 
+// getMapOptions returns the umoci ID mapping options allowing to unpack
+// layers as non-root.
+func getMapOptions() (mapOptions umocilayer.MapOptions, err error) {
+	if os.Geteuid() != 0 {
+		mapOptions.Rootless = true
+
+		uidMap, err := idtools.ParseMapping(fmt.Sprintf("0:%d:1", os.Geteuid()))
+		if err != nil {
+			return mapOptions, fmt.Errorf("error parsing uidmap: %s", err)
+		}
+		mapOptions.UIDMappings = append(mapOptions.UIDMappings, uidMap)
+
+		gidMap, err := idtools.ParseMapping(fmt.Sprintf("0:%d:1", os.Getegid()))
+		if err != nil {
+			return mapOptions, fmt.Errorf("error parsing gidmap: %s", err)
+		}
+		mapOptions.GIDMappings = append(mapOptions.GIDMappings, gidMap)
+	}
+	return mapOptions, nil
+}
+
 // unpackRootfs extracts all of the layers of the given image reference into the rootfs of the provided bundle
 func unpackRootfs(ctx context.Context, b *sytypes.Bundle, tmpfsRef types.ImageReference, sysCtx *types.SystemContext) (err error) {
-	var mapOptions umocilayer.MapOptions
-
 	loggerLevel := sylog.GetLevel()
 
 	// set the apex log level, for umoci
@@ -48,21 +69,9 @@ func unpackRootfs(ctx context.Context, b *sytypes.Bundle, tmpfsRef types.ImageRe
 		apexlog.SetLevel(apexlog.DebugLevel)
 	}
 
-	// Allow unpacking as non-root
-	if os.Geteuid() != 0 {
-		mapOptions.Rootless = true
-
-		uidMap, err := idtools.ParseMapping(fmt.Sprintf("0:%d:1", os.Geteuid()))
-		if err != nil {
-			return fmt.Errorf("error parsing uidmap: %s", err)
-		}
-		mapOptions.UIDMappings = append(mapOptions.UIDMappings, uidMap)
-
-		gidMap, err := idtools.ParseMapping(fmt.Sprintf("0:%d:1", os.Getegid()))
-		if err != nil {
-			return fmt.Errorf("error parsing gidmap: %s", err)
-		}
-		mapOptions.GIDMappings = append(mapOptions.GIDMappings, gidMap)
+	mapOptions, err := getMapOptions()
+	if err != nil {
+		return err
 	}
 
 	engineExt, err := umoci.OpenLayout(b.TmpDir)
@@ -100,7 +109,14 @@ func unpackRootfs(ctx context.Context, b *sytypes.Bundle, tmpfsRef types.ImageRe
 	if b.Opts.FixPerms {
 		sylog.Warningf("The --fix-perms option modifies the filesystem permissions on the resulting container.")
 		sylog.Debugf("Modifying permissions for file/directory owners")
-		return fixPerms(b.RootfsPath)
+		changes, err := fixPerms(b.RootfsPath)
+		if b.Opts.FixPermsReport != "" {
+			if err := writePermReport(b.Opts.FixPermsReport, changes); err != nil {
+				return err
+			}
+			sylog.Infof("Permission changes report written to %s", b.Opts.FixPermsReport)
+		}
+		return err
 	}
 
 	// If `--fix-perms` was not used and this is a sandbox, scan for restrictive
@@ -115,11 +131,39 @@ func unpackRootfs(ctx context.Context, b *sytypes.Bundle, tmpfsRef types.ImageRe
 	return err
 }
 
+// permChange records a permission change made by fixPerms, with the path
+// relative to the container root and the modes formatted in octal.
+type permChange struct {
+	Path    string `json:"path"`
+	OldMode string `json:"oldMode"`
+	NewMode string `json:"newMode"`
+}
+
 // fixPerms will work through the rootfs of this bundle, making sure that all
 // files and directories have permissions set such that the owner can read,
-// modify, delete. This brings us to the situation of <=3.4
-func fixPerms(rootfs string) (err error) {
+// modify, delete. This brings us to the situation of <=3.4. It returns the
+// list of permission changes made.
+func fixPerms(rootfs string) (changes []permChange, err error) {
 	errors := 0
+	changes = make([]permChange, 0)
+
+	chmod := func(path string, oldMode, newMode os.FileMode) {
+		if oldMode == newMode {
+			return
+		}
+		if err := os.Chmod(path, newMode); err != nil {
+			sylog.Errorf("Error setting permission for %s: %s", path, err)
+			errors++
+			return
+		}
+		rel, _ := filepath.Rel(rootfs, path)
+		changes = append(changes, permChange{
+			Path:    filepath.Join("/", rel),
+			OldMode: fmt.Sprintf("%04o", oldMode),
+			NewMode: fmt.Sprintf("%04o", newMode),
+		})
+	}
+
 	err = fs.PermWalk(rootfs, func(path string, f os.FileInfo, err error) error {
 		if err != nil {
 			sylog.Errorf("Unable to access rootfs path %s: %s", path, err)
@@ -131,18 +175,12 @@ func fixPerms(rootfs string) (err error) {
 		// Directories must have the owner 'rx' bits to allow traversal and reading on move, and the 'w' bit
 		// so their content can be deleted by the user when the rootfs/sandbox is deleted
 		case mode.IsDir():
-			if err := os.Chmod(path, f.Mode().Perm()|0o700); err != nil {
-				sylog.Errorf("Error setting permission for %s: %s", path, err)
-				errors++
-			}
+			chmod(path, mode.Perm(), mode.Perm()|0o700)
 		case mode.IsRegular():
 			// Regular files must have the owner 'r' bit so that everything can be read in order to
 			// copy or move the rootfs/sandbox around. Also, the `w` bit as the build does write into
 			// some files (e.g. resolv.conf) in the container rootfs.
-			if err := os.Chmod(path, f.Mode().Perm()|0o600); err != nil {
-				sylog.Errorf("Error setting permission for %s: %s", path, err)
-				errors++
-			}
+			chmod(path, mode.Perm(), mode.Perm()|0o600)
 		}
 		return nil
 	})
@@ -150,7 +188,20 @@ func fixPerms(rootfs string) (err error) {
 	if errors > 0 {
 		err = fmt.Errorf("%d errors were encountered when setting permissions", errors)
 	}
-	return err
+	return changes, err
+}
+
+// writePermReport writes the permission changes made by fixPerms
+// as a JSON document to the file at path.
+func writePermReport(path string, changes []permChange) error {
+	report, err := json.MarshalIndent(changes, "", "\t")
+	if err != nil {
+		return fmt.Errorf("while encoding permission changes report: %s", err)
+	}
+	if err := ioutil.WriteFile(path, append(report, '\n'), 0o644); err != nil {
+		return fmt.Errorf("while writing permission changes report: %s", err)
+	}
+	return nil
 }
 
 // checkPerms will work through the rootfs of this bundle, and find if any
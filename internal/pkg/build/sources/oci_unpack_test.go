@@ -0,0 +1,66 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFixPermsReport(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "fixperms-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	if err := os.MkdirAll(filepath.Join(rootfs, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "etc", "shadow"), nil, 0o000); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "etc", "passwd"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(rootfs, "ro"), 0o555); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := fixPerms(rootfs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []permChange{
+		{Path: "/etc/shadow", OldMode: "0000", NewMode: "0600"},
+		{Path: "/ro", OldMode: "0555", NewMode: "0755"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("unexpected changes:\nwant: %v\ngot: %v", expected, changes)
+	}
+
+	report := filepath.Join(rootfs, "report.json")
+	if err := writePermReport(report, changes); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	b, err := ioutil.ReadFile(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []permChange
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("while decoding report: %s", err)
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Fatalf("unexpected report:\nwant: %v\ngot: %v", expected, decoded)
+	}
+}
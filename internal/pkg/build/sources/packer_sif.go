@@ -52,6 +52,9 @@ func unpackSIF(b *types.Bundle, img *image.Image) (err error) {
 		}
 
 		s := unpacker.NewSquashfs()
+		// stage squashfs data in the build temporary directory
+		// instead of the sandbox destination parent directory
+		s.TmpDir = b.TmpDir
 
 		// extract root filesystem
 		if err := s.ExtractAll(reader, b.RootfsPath); err != nil {
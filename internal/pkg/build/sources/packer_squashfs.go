@@ -30,6 +30,8 @@ func (p *SquashfsPacker) Pack(context.Context) (*types.Bundle, error) {
 	}
 
 	s := unpacker.NewSquashfs()
+	// stage squashfs data in the build temporary directory
+	s.TmpDir = p.b.TmpDir
 
 	// extract root filesystem
 	if err := s.ExtractAll(reader, p.b.RootfsPath); err != nil {
@@ -6,15 +6,23 @@
 package build
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/sylabs/singularity/internal/pkg/build/files"
 	"github.com/sylabs/singularity/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/internal/pkg/client/net"
+	"github.com/sylabs/singularity/internal/pkg/fakeroot"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/pkg/build/types"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
@@ -29,12 +37,19 @@ type stage struct {
 	a Assembler
 	// b is an intermediate structure that encapsulates all information for the container, e.g., metadata, filesystems.
 	b *types.Bundle
+	// events receives the build progress events, if enabled.
+	events *EventWriter
+	// stripRules are applied to the root filesystem after %post, if set.
+	stripRules []stripRule
 }
 
 const (
 	sLabelsPath  = "/.build.labels"
 	sEnvironment = "SINGULARITY_ENVIRONMENT=/.singularity.d/env/91-environment.sh"
 	sLabels      = "SINGULARITY_LABELS=" + sLabelsPath
+	// sFakerootLib is the location of the fakeroot shim library in the
+	// root filesystem while %post runs.
+	sFakerootLib = "/.post.fakeroot.so"
 )
 
 // Assemble assembles the bundle to the specified path.
@@ -42,7 +57,7 @@ func (s *stage) Assemble(path string) error {
 	return s.a.Assemble(s.b, path)
 }
 
-// runSetupScript executes the stage's pre script on host.
+// runSectionScript executes the stage's pre or setup script on host.
 func (s *stage) runSectionScript(name string, script types.Script) error {
 	if s.b.RunSection(name) && script.Script != "" {
 		if syscall.Getuid() != 0 {
@@ -57,20 +72,25 @@ func (s *stage) runSectionScript(name string, script types.Script) error {
 		}
 		defer os.Remove(scriptPath)
 
-		args, err := getSectionScriptArgs(name, scriptPath, script)
+		args, err := getSectionScriptArgs(name, scriptPath, "/", script)
 		if err != nil {
 			return fmt.Errorf("while processing section %%%s arguments: %s", name, err)
 		}
 
 		// Run script section here
 		cmd := exec.Command(args[0], args[1:]...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		stdout, stderr, flush := s.events.sectionOutput(s.name, name)
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
 		cmd.Env = os.Environ()
 		cmd.Env = append(cmd.Env, sEnvironment, sRootfs)
 
 		sylog.Infof("Running %s scriptlet", name)
-		if err := cmd.Run(); err != nil {
+		s.sectionStart(name)
+		err = cmd.Run()
+		flush()
+		s.sectionEnd(name, err)
+		if err != nil {
 			return fmt.Errorf("failed to run %%%s script: %v", name, err)
 		}
 	}
@@ -96,7 +116,28 @@ func (s *stage) runPostScript(configFile, sessionResolv, sessionHosts string) er
 		}
 		defer os.Remove(scriptPath)
 
-		args, err := getSectionScriptArgs("post", "/.post.script", script)
+		if s.b.Opts.FakerootShim {
+			shim, err := fakeroot.StartShim()
+			if err != nil {
+				return fmt.Errorf("while starting fakeroot shim: %s", err)
+			}
+			defer func() {
+				if err := shim.Stop(); err != nil {
+					sylog.Warningf("%s", err)
+				}
+			}()
+
+			// the library must be reachable from the container, like the script
+			libPath := filepath.Join(s.b.RootfsPath, sFakerootLib)
+			if err := fs.CopyFile(shim.Lib, libPath, 0o644); err != nil {
+				return fmt.Errorf("while copying fakeroot shim library: %s", err)
+			}
+			defer os.Remove(libPath)
+
+			cmdArgs = append(cmdArgs, "--env", "LD_PRELOAD="+sFakerootLib, "--env", "FAKEROOTKEY="+shim.Key)
+		}
+
+		args, err := getSectionScriptArgs("post", "/.post.script", s.b.RootfsPath, script)
 		if err != nil {
 			return fmt.Errorf("while processing section %%post arguments: %s", err)
 		}
@@ -106,13 +147,18 @@ func (s *stage) runPostScript(configFile, sessionResolv, sessionHosts string) er
 		cmdArgs = append(cmdArgs, s.b.RootfsPath)
 		cmdArgs = append(cmdArgs, args...)
 		cmd := exec.Command(exe, cmdArgs...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		stdout, stderr, flush := s.events.sectionOutput(s.name, "post")
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
 		cmd.Dir = "/"
-		cmd.Env = currentEnvNoSingularity([]string{"NV", "NVCCLI", "ROCM", "BINDPATH", "MOUNT"})
+		cmd.Env = currentEnvNoSingularity([]string{"NV", "NVCCLI", "ROCM", "BINDPATH", "MOUNT", "NET", "NETWORK"})
 
 		sylog.Infof("Running post scriptlet")
-		return cmd.Run()
+		s.sectionStart("post")
+		err = s.runWithTimeout(cmd, "post")
+		flush()
+		s.sectionEnd("post", err)
+		return err
 	}
 	return nil
 }
@@ -132,14 +178,90 @@ func (s *stage) runTestScript(configFile, sessionResolv, sessionHosts string) er
 
 		cmdArgs = append(cmdArgs, s.b.RootfsPath)
 		cmd := exec.Command(exe, cmdArgs...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		stdout, stderr, flush := s.events.sectionOutput(s.name, "test")
+		cmd.Stdout = stdout
+		cmd.Stderr = stderr
 		cmd.Dir = "/"
-		cmd.Env = currentEnvNoSingularity([]string{"NV", "NVCCLI", "ROCM", "BINDPATH", "MOUNT", "WRITABLE_TMPFS"})
+		cmd.Env = currentEnvNoSingularity([]string{"NV", "NVCCLI", "ROCM", "BINDPATH", "MOUNT", "WRITABLE_TMPFS", "NET", "NETWORK"})
 
 		sylog.Infof("Running testscript")
+		s.sectionStart("test")
+		err := s.runWithTimeout(cmd, "test")
+		flush()
+		s.sectionEnd("test", err)
+		return err
+	}
+	return nil
+}
+
+// runWithTimeout runs the command cmd of the section name. When the build
+// has a --post-timeout, the command runs in its own process group, killed
+// with the container processes if the section is still running once the
+// timeout expires.
+func (s *stage) runWithTimeout(cmd *exec.Cmd, name string) error {
+	timeout := s.b.Opts.PostTimeout
+	if timeout <= 0 {
 		return cmd.Run()
 	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	timer := time.AfterFunc(timeout, func() {
+		sylog.Errorf("%%%s section still running after %s, killing it", name, timeout)
+		if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+			sylog.Warningf("While killing %%%s section processes: %s", name, err)
+		}
+	})
+	err := cmd.Wait()
+	if !timer.Stop() {
+		return fmt.Errorf("%%%s section timed out after %s", name, timeout)
+	}
+	return err
+}
+
+// sectionStart emits the start event of the section name.
+func (s *stage) sectionStart(name string) {
+	s.events.Emit(Event{Type: SectionStartEvent, Stage: s.name, Section: name})
+}
+
+// sectionEnd emits the end event of the section name with its status.
+func (s *stage) sectionEnd(name string, err error) {
+	result, msg := eventStatus(err)
+	s.events.Emit(Event{Type: SectionEndEvent, Stage: s.name, Section: name, Status: result, Error: msg})
+}
+
+// step emits a build step event for the stage.
+func (s *stage) step(name string) {
+	s.events.Emit(Event{Type: StepEvent, Stage: s.name, Step: name})
+}
+
+// setFileAttributes applies the ownership and permissions requested with
+// the --chown and --chmod options of a %files entry to the copied files.
+func setFileAttributes(transfer types.FileTransport, copied []string) error {
+	if transfer.Chown != "" {
+		uid, gid, err := types.ParseChown(transfer.Chown)
+		if err != nil {
+			return err
+		}
+		for _, path := range copied {
+			if err := files.ChownAll(path, uid, gid); err != nil {
+				return err
+			}
+		}
+	}
+	if transfer.Chmod != "" {
+		mode, err := types.ParseChmod(transfer.Chmod)
+		if err != nil {
+			return err
+		}
+		for _, path := range copied {
+			if err := files.ChmodAll(path, mode); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
@@ -176,7 +298,11 @@ func (s *stage) copyFilesFrom(b *Build) error {
 			}
 			// copy each file into bundle rootfs
 			sylog.Infof("Copying %v to %v", transfer.Src, transfer.Dst)
-			if err := files.CopyFromStage(transfer.Src, transfer.Dst, srcRootfsPath, dstRootfsPath); err != nil {
+			copied, err := files.CopyFromStage(transfer.Src, transfer.Dst, srcRootfsPath, dstRootfsPath)
+			if err != nil {
+				return err
+			}
+			if err := setFileAttributes(transfer, copied); err != nil {
 				return err
 			}
 		}
@@ -185,7 +311,47 @@ func (s *stage) copyFilesFrom(b *Build) error {
 	return nil
 }
 
-func (s *stage) copyFiles() error {
+// fetchFile downloads the http(s) source of transfer through the cache and
+// returns a copy source named after the last element of the URL path, with
+// the destination defaulting to that name at the container root.
+func (s *stage) fetchFile(ctx context.Context, transfer types.FileTransport) (src, dst string, err error) {
+	if !s.b.Opts.FetchFiles {
+		return "", "", fmt.Errorf("%%files source %s is a URL, use --fetch-files to download it", transfer.Src)
+	}
+
+	u, err := url.Parse(transfer.Src)
+	if err != nil {
+		return "", "", fmt.Errorf("while parsing %s: %s", transfer.Src, err)
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return "", "", fmt.Errorf("%%files source %s doesn't name a file", transfer.Src)
+	}
+
+	downloaded, err := net.FetchFile(ctx, s.b.Opts.ImgCache, transfer.Src, s.b.TmpDir)
+	if err != nil {
+		return "", "", err
+	}
+
+	// the downloaded file is named after its cache hash, it's copied through
+	// a symlink giving it its URL name
+	dir, err := ioutil.TempDir(s.b.TmpDir, "fetch-")
+	if err != nil {
+		return "", "", err
+	}
+	src = filepath.Join(dir, name)
+	if err := os.Symlink(downloaded, src); err != nil {
+		return "", "", err
+	}
+
+	dst = transfer.Dst
+	if dst == "" {
+		dst = "/" + name
+	}
+	return src, dst, nil
+}
+
+func (s *stage) copyFiles(ctx context.Context) error {
 	def := s.b.Recipe
 	filesSection := types.Files{}
 	for _, f := range def.BuildData.Files {
@@ -202,9 +368,20 @@ func (s *stage) copyFiles() error {
 			sylog.Warningf("Attempt to copy file with no name, skipping.")
 			continue
 		}
+		src, dst := transfer.Src, transfer.Dst
+		if net.IsNetPullRef(src) {
+			var err error
+			if src, dst, err = s.fetchFile(ctx, transfer); err != nil {
+				return err
+			}
+		}
 		// copy each file into bundle rootfs
-		sylog.Infof("Copying %v to %v", transfer.Src, transfer.Dst)
-		if err := files.CopyFromHost(transfer.Src, transfer.Dst, s.b.RootfsPath); err != nil {
+		sylog.Infof("Copying %v to %v", transfer.Src, dst)
+		copied, err := files.CopyFromHost(src, dst, s.b.RootfsPath)
+		if err != nil {
+			return err
+		}
+		if err := setFileAttributes(transfer, copied); err != nil {
 			return err
 		}
 	}
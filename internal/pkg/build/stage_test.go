@@ -0,0 +1,69 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestRunWithTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		script      string
+		expectError string
+	}{
+		{
+			name:   "NoTimeout",
+			script: "true",
+		},
+		{
+			name:        "NoTimeoutFailure",
+			script:      "exit 1",
+			expectError: "exit status 1",
+		},
+		{
+			name:    "WithinTimeout",
+			timeout: time.Minute,
+			script:  "true",
+		},
+		{
+			name:        "WithinTimeoutFailure",
+			timeout:     time.Minute,
+			script:      "exit 1",
+			expectError: "exit status 1",
+		},
+		{
+			name:        "TimedOut",
+			timeout:     100 * time.Millisecond,
+			script:      "sleep 30 & sleep 30",
+			expectError: "%post section timed out after 100ms",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &stage{b: &types.Bundle{Opts: types.Options{PostTimeout: tt.timeout}}}
+
+			start := time.Now()
+			err := s.runWithTimeout(exec.Command("/bin/sh", "-c", tt.script), "post")
+			if elapsed := time.Since(start); elapsed > 10*time.Second {
+				t.Errorf("section ran for %s", elapsed)
+			}
+
+			if tt.expectError == "" && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			} else if tt.expectError != "" && (err == nil || !strings.Contains(err.Error(), tt.expectError)) {
+				t.Errorf("got error %v, want %q", err, tt.expectError)
+			}
+		})
+	}
+}
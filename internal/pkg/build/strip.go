@@ -0,0 +1,243 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"debug/elf"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/util/bin"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// stripAction is the action applied by a strip rule.
+type stripAction string
+
+const (
+	// stripKeep excludes the matching paths from the other rules.
+	stripKeep stripAction = "keep"
+	// stripRemove removes the matching paths.
+	stripRemove stripAction = "remove"
+	// stripDebug strips debug symbols from the matching ELF files.
+	stripDebug stripAction = "strip"
+)
+
+// stripBatchSize is the maximum number of files passed to a single
+// strip command.
+const stripBatchSize = 64
+
+// defaultStripRules is the ruleset applied by build --strip when no
+// ruleset file is provided.
+const defaultStripRules = `# documentation
+remove /usr/share/doc
+remove /usr/share/gtk-doc
+remove /usr/share/info
+remove /usr/share/man
+# translations and locale data, the C locale is built in the C library
+remove /usr/share/locale
+remove /usr/share/i18n
+# static libraries and separate debug symbols
+remove *.a
+remove /usr/lib/debug
+# debug symbols of executables and shared libraries
+strip *
+`
+
+// stripRule applies an action to the container paths matching a pattern.
+// A pattern containing a slash is matched against the absolute path in
+// the container, otherwise it is matched against the base name of paths
+// anywhere in the container. A rule matching a directory applies to its
+// whole content.
+type stripRule struct {
+	action  stripAction
+	pattern string
+}
+
+// match returns true if the container path matches the rule pattern.
+func (r stripRule) match(path string) bool {
+	name := filepath.Base(path)
+	if strings.Contains(r.pattern, "/") {
+		name = path
+	}
+	ok, _ := filepath.Match(r.pattern, name)
+	return ok
+}
+
+// parseStripRules parses a ruleset with one "<action> <pattern>" rule
+// per line, empty lines and lines starting with # are ignored.
+func parseStripRules(r io.Reader) ([]stripRule, error) {
+	var rules []stripRule
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected \"<action> <pattern>\", got %q", n, line)
+		}
+
+		rule := stripRule{action: stripAction(fields[0]), pattern: filepath.Clean(fields[1])}
+		switch rule.action {
+		case stripKeep, stripRemove, stripDebug:
+		default:
+			return nil, fmt.Errorf("line %d: unknown action %q, supported actions are %s, %s and %s", n, fields[0], stripKeep, stripRemove, stripDebug)
+		}
+		if strings.Contains(rule.pattern, "/") && !filepath.IsAbs(rule.pattern) {
+			return nil, fmt.Errorf("line %d: pattern %q must be an absolute path or a file name", n, fields[1])
+		}
+		if _, err := filepath.Match(rule.pattern, ""); err != nil {
+			return nil, fmt.Errorf("line %d: bad pattern %q: %s", n, fields[1], err)
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, scanner.Err()
+}
+
+// loadStripRules loads the strip ruleset from the file path, or the
+// default ruleset if path is empty.
+func loadStripRules(path string) ([]stripRule, error) {
+	if path == "" {
+		return parseStripRules(strings.NewReader(defaultStripRules))
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("while opening strip ruleset: %s", err)
+	}
+	defer f.Close()
+
+	rules, err := parseStripRules(f)
+	if err != nil {
+		return nil, fmt.Errorf("while parsing strip ruleset %s: %s", path, err)
+	}
+	return rules, nil
+}
+
+// stripActions returns the actions of the rules matching the container
+// path along with the actions inherited from its parent directory, keep
+// rules taking precedence over the other rules.
+func stripActions(rules []stripRule, path string, parent map[stripAction]bool) map[stripAction]bool {
+	actions := make(map[stripAction]bool)
+	for a := range parent {
+		actions[a] = true
+	}
+	for _, r := range rules {
+		if r.match(path) {
+			actions[r.action] = true
+		}
+	}
+	if actions[stripKeep] {
+		return map[stripAction]bool{stripKeep: true}
+	}
+	return actions
+}
+
+// isELF returns true if the file at path is an ELF executable or
+// shared object.
+func isELF(path string) bool {
+	f, err := elf.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	return f.Type == elf.ET_EXEC || f.Type == elf.ET_DYN
+}
+
+// stripRootfs applies the strip rules of the stage to its root filesystem.
+func (s *stage) stripRootfs() error {
+	rootfs := s.b.RootfsPath
+
+	// actions inherited by the content of matching directories
+	inherited := make(map[string]map[stripAction]bool)
+	var removeDirs, stripFiles []string
+	removed := 0
+
+	err := filepath.Walk(rootfs, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(rootfs, path)
+		if err != nil {
+			return err
+		}
+		cpath := filepath.Join("/", rel)
+		if cpath == "/" {
+			return nil
+		}
+
+		actions := stripActions(s.stripRules, cpath, inherited[filepath.Dir(cpath)])
+
+		if info.IsDir() {
+			inherited[cpath] = actions
+			if actions[stripRemove] {
+				removeDirs = append(removeDirs, path)
+			}
+			return nil
+		}
+
+		switch {
+		case actions[stripRemove]:
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("while removing %s: %s", cpath, err)
+			}
+			removed++
+		case actions[stripDebug] && info.Mode().IsRegular() && isELF(path):
+			stripFiles = append(stripFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// remove directories deepest first, directories with
+	// kept content are not empty and are left in place
+	for i := len(removeDirs) - 1; i >= 0; i-- {
+		if err := os.Remove(removeDirs[i]); err == nil {
+			removed++
+		}
+	}
+	sylog.Infof("Removed %d files and directories from the container", removed)
+
+	if len(stripFiles) == 0 {
+		return nil
+	}
+
+	strip, err := bin.FindBin("strip")
+	if err != nil {
+		sylog.Warningf("Debug symbols not stripped from %d binaries: %s", len(stripFiles), err)
+		return nil
+	}
+	for i := 0; i < len(stripFiles); i += stripBatchSize {
+		end := i + stripBatchSize
+		if end > len(stripFiles) {
+			end = len(stripFiles)
+		}
+		var out bytes.Buffer
+		cmd := exec.Command(strip, append([]string{"--strip-debug"}, stripFiles[i:end]...)...)
+		cmd.Stdout = &out
+		cmd.Stderr = &out
+		if err := cmd.Run(); err != nil {
+			// strip processes all the files even if some fail
+			sylog.Warningf("Could not strip some binaries: %s", strings.TrimSpace(out.String()))
+		}
+	}
+	sylog.Infof("Stripped debug symbols from %d binaries", len(stripFiles))
+
+	return nil
+}
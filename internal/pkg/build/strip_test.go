@@ -0,0 +1,133 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestParseStripRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   string
+		want    []stripRule
+		wantErr bool
+	}{
+		{
+			name:  "rules",
+			rules: "# comment\n\nremove /usr/share/doc/\nkeep  /usr/share/locale/en*\nstrip *\n",
+			want: []stripRule{
+				{action: stripRemove, pattern: "/usr/share/doc"},
+				{action: stripKeep, pattern: "/usr/share/locale/en*"},
+				{action: stripDebug, pattern: "*"},
+			},
+		},
+		{
+			name:    "unknown action",
+			rules:   "delete /usr/share/doc\n",
+			wantErr: true,
+		},
+		{
+			name:    "missing pattern",
+			rules:   "remove\n",
+			wantErr: true,
+		},
+		{
+			name:    "relative path",
+			rules:   "remove usr/share/doc\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad pattern",
+			rules:   "remove [\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseStripRules(strings.NewReader(tt.rules))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStripRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseStripRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := loadStripRules(""); err != nil {
+		t.Errorf("unexpected error while loading default rules: %s", err)
+	}
+}
+
+func TestStripRootfs(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "strip-rootfs-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	files := []string{
+		"usr/share/doc/pkg/README",
+		"usr/share/locale/fr/LC_MESSAGES/pkg.mo",
+		"usr/share/locale/en_GB/LC_MESSAGES/pkg.mo",
+		"usr/lib/libfoo.a",
+		"usr/lib/libfoo.so",
+		"opt/app/lib/libapp.a",
+		"opt/app/README",
+	}
+	for _, f := range files {
+		path := filepath.Join(rootfs, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(f), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rules := "remove /usr/share/doc\nremove /usr/share/locale\nkeep /usr/share/locale/en*\nremove *.a\nkeep /opt/app\nstrip *\n"
+
+	s := &stage{b: &types.Bundle{RootfsPath: rootfs}}
+	s.stripRules, err = parseStripRules(strings.NewReader(rules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.stripRootfs(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	removed := []string{
+		"usr/share/doc",
+		"usr/share/locale/fr",
+		"usr/lib/libfoo.a",
+	}
+	for _, f := range removed {
+		if _, err := os.Lstat(filepath.Join(rootfs, f)); !os.IsNotExist(err) {
+			t.Errorf("%s not removed", f)
+		}
+	}
+
+	kept := []string{
+		"usr/share/locale/en_GB/LC_MESSAGES/pkg.mo",
+		"usr/lib/libfoo.so",
+		"opt/app/lib/libapp.a",
+		"opt/app/README",
+	}
+	for _, f := range kept {
+		if _, err := os.Lstat(filepath.Join(rootfs, f)); err != nil {
+			t.Errorf("%s not kept: %s", f, err)
+		}
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"strings"
 
 	ocitypes "github.com/containers/image/v5/types"
+	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/sylabs/singularity/internal/pkg/cache"
 	"github.com/sylabs/singularity/internal/pkg/util/env"
 	"github.com/sylabs/singularity/pkg/build/types"
@@ -107,31 +108,70 @@ func createScript(path string, content []byte) error {
 	return nil
 }
 
-func getSectionScriptArgs(name string, script string, s types.Script) ([]string, error) {
-	args := []string{"/bin/sh", "-ex"}
-	// trim potential trailing comment from args and append to args list
-	sectionParams := strings.Fields(strings.Split(s.Args, "#")[0])
+// defaultInterpreter is the interpreter used to run section scripts when
+// none is requested, or when the requested one is not available.
+const defaultInterpreter = "/bin/sh"
 
-	commandOption := false
+// sectionInterpreter returns the interpreter and its arguments requested
+// with the '-c' option of a section header (e.g. '%post -c /bin/bash'),
+// along with the section parameters preceding '-c'. The returned
+// interpreter is empty if the '-c' option is not used.
+func sectionInterpreter(name string, s types.Script) (interpreter []string, params []string, err error) {
+	// trim potential trailing comment from args
+	params = strings.Fields(strings.Split(s.Args, "#")[0])
 
-	// look for -c option, we assume that everything after is part of -c
-	// arguments and we just inject script path as the last arguments of -c
-	for i, param := range sectionParams {
+	for i, param := range params {
 		if param == "-c" {
-			if len(sectionParams)-1 < i+1 {
-				return nil, fmt.Errorf("bad %s section '-c' parameter: missing arguments", name)
+			if len(params)-1 < i+1 {
+				return nil, nil, fmt.Errorf("bad %s section '-c' parameter: missing arguments", name)
 			}
-			// replace shell "[args...]" arguments list by single
-			// argument "shell [args...] script"
-			shellArgs := strings.Join(sectionParams[i+1:], " ")
-			sectionParams = append(sectionParams[0:i+1], shellArgs+" "+script)
-			commandOption = true
-			break
+			return params[i+1:], params[0:i], nil
 		}
 	}
+	return nil, params, nil
+}
+
+// interpreterExists returns if the interpreter path is an executable
+// file within the root filesystem rootfs. Symlinks are resolved
+// relatively to rootfs.
+func interpreterExists(rootfs string, path string) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	p, err := securejoin.SecureJoin(rootfs, path)
+	if err != nil {
+		return false
+	}
+	fi, err := os.Stat(p)
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	return unix.Access(p, unix.X_OK) == nil
+}
+
+// getSectionScriptArgs returns the command line used to execute the
+// section script with the interpreter requested via the '-c' section
+// option, the interpreter is looked up in rootfs and if not found,
+// the default interpreter is used instead.
+func getSectionScriptArgs(name string, script string, rootfs string, s types.Script) ([]string, error) {
+	args := []string{defaultInterpreter, "-ex"}
+
+	interpreter, params, err := sectionInterpreter(name, s)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, params...)
+
+	if len(interpreter) > 0 && !interpreterExists(rootfs, interpreter[0]) {
+		sylog.Warningf("%%%s interpreter %s not found, falling back to %s", name, interpreter[0], defaultInterpreter)
+		interpreter = nil
+	}
 
-	args = append(args, sectionParams...)
-	if !commandOption {
+	if len(interpreter) > 0 {
+		// replace shell "[args...]" arguments list by single
+		// argument "shell [args...] script"
+		args = append(args, "-c", strings.Join(interpreter, " ")+" "+script)
+	} else {
 		args = append(args, script)
 	}
 
@@ -0,0 +1,154 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func createRootfs(t *testing.T) string {
+	rootfs, err := ioutil.TempDir("", "rootfs-")
+	if err != nil {
+		t.Fatalf("while creating rootfs: %s", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(rootfs, "usr", "bin"), 0o755); err != nil {
+		t.Fatalf("while creating rootfs: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "usr", "bin", "bash"), []byte{}, 0o755); err != nil {
+		t.Fatalf("while creating rootfs: %s", err)
+	}
+	// absolute symlink must be resolved relatively to rootfs
+	if err := os.Symlink("/usr/bin", filepath.Join(rootfs, "bin")); err != nil {
+		t.Fatalf("while creating rootfs: %s", err)
+	}
+
+	return rootfs
+}
+
+func TestGetSectionScriptArgs(t *testing.T) {
+	rootfs := createRootfs(t)
+	defer os.RemoveAll(rootfs)
+
+	tests := []struct {
+		name         string
+		args         string
+		expectedArgs []string
+		expectError  bool
+	}{
+		{
+			name:         "NoArgs",
+			args:         "",
+			expectedArgs: []string{"/bin/sh", "-ex", "/.post.script"},
+		},
+		{
+			name:         "Interpreter",
+			args:         "-c /bin/bash",
+			expectedArgs: []string{"/bin/sh", "-ex", "-c", "/bin/bash /.post.script"},
+		},
+		{
+			name:         "InterpreterArguments",
+			args:         "-c /bin/bash -e #comment",
+			expectedArgs: []string{"/bin/sh", "-ex", "-c", "/bin/bash -e /.post.script"},
+		},
+		{
+			name:         "InterpreterNotFound",
+			args:         "-c /bin/zsh",
+			expectedArgs: []string{"/bin/sh", "-ex", "/.post.script"},
+		},
+		{
+			name:         "InterpreterRelative",
+			args:         "-c bash",
+			expectedArgs: []string{"/bin/sh", "-ex", "/.post.script"},
+		},
+		{
+			name:        "MissingInterpreter",
+			args:        "-c",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := getSectionScriptArgs("post", "/.post.script", rootfs, types.Script{Args: tt.args})
+			if err != nil && !tt.expectError {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Fatalf("unexpected success")
+			}
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("unexpected arguments %q, expected %q", args, tt.expectedArgs)
+			}
+		})
+	}
+}
+
+func TestHandleShebangScript(t *testing.T) {
+	rootfs := createRootfs(t)
+	defer os.RemoveAll(rootfs)
+
+	tests := []struct {
+		name            string
+		script          types.Script
+		expectedShebang string
+		expectedScript  string
+	}{
+		{
+			name:            "Default",
+			script:          types.Script{Script: "echo"},
+			expectedShebang: "#!/bin/sh",
+			expectedScript:  "echo",
+		},
+		{
+			name:            "Shebang",
+			script:          types.Script{Script: "#!/bin/bash\necho"},
+			expectedShebang: "#!/bin/bash",
+			expectedScript:  "echo",
+		},
+		{
+			name:            "ShellArgs",
+			script:          types.Script{Script: "echo", Args: "-x #comment"},
+			expectedShebang: "#!/bin/sh -x",
+			expectedScript:  "echo",
+		},
+		{
+			name:            "Interpreter",
+			script:          types.Script{Script: "echo", Args: "-c /bin/bash -e"},
+			expectedShebang: "#!/bin/bash -e",
+			expectedScript:  "echo",
+		},
+		{
+			name:            "InterpreterOverShebang",
+			script:          types.Script{Script: "#!/bin/sh\necho", Args: "-c /bin/bash"},
+			expectedShebang: "#!/bin/bash",
+			expectedScript:  "echo",
+		},
+		{
+			name:            "InterpreterNotFound",
+			script:          types.Script{Script: "echo", Args: "-c /bin/zsh"},
+			expectedShebang: "#!/bin/sh",
+			expectedScript:  "echo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shebang, script := handleShebangScript("runscript", rootfs, tt.script)
+			if shebang != tt.expectedShebang {
+				t.Errorf("unexpected shebang %q, expected %q", shebang, tt.expectedShebang)
+			}
+			if script != tt.expectedScript {
+				t.Errorf("unexpected script %q, expected %q", script, tt.expectedScript)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	imgspecs "github.com/opencontainers/image-spec/specs-go"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/util/fs/lock"
+)
+
+// The OCI blob cache is an OCI image layout, blobs are stored once by
+// digest and shared by all the images referencing them. The images are
+// listed in the layout index, tagged with the hash of their source, and
+// the blobs are reference counted from the index when the cache is
+// cleaned.
+
+// ociManifest holds the descriptors referenced by an OCI or Docker image
+// manifest or image index.
+type ociManifest struct {
+	Config    *imgspecv1.Descriptor  `json:"config,omitempty"`
+	Layers    []imgspecv1.Descriptor `json:"layers,omitempty"`
+	Manifests []imgspecv1.Descriptor `json:"manifests,omitempty"`
+}
+
+// LockOciCache acquires a shared lock on the OCI cache cacheType and returns
+// the function releasing it. The lock must be held while an image is written
+// to the cache, as the cache is cleaned with an exclusive lock so that the
+// blobs of an image being pulled are never seen as unreferenced. Concurrent
+// pulls share the lock, their images are added to the layout index with
+// AddOciCacheManifest.
+func (h *Handle) LockOciCache(cacheType string) (unlock func(), err error) {
+	return h.lockOciCache(cacheType, cacheType, lock.Shared)
+}
+
+// lockOciCache acquires the lock name of the OCI cache cacheType with
+// lockFn, and returns the function releasing it.
+func (h *Handle) lockOciCache(cacheType, name string, lockFn func(string) (int, error)) (unlock func(), err error) {
+	if !stringInSlice(cacheType, OciCacheTypes) {
+		return nil, errInvalidCacheType
+	}
+	if h.disabled {
+		return func() {}, nil
+	}
+
+	path := filepath.Join(h.rootDir, lockDirName, name)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not create %s cache lock file: %v", cacheType, err)
+	}
+	f.Close()
+
+	// Unlike entry locks, the lock file is never removed as processes
+	// may be waiting on it at any time
+	fd, err := lockFn(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not lock %s cache: %v", cacheType, err)
+	}
+	return func() {
+		if err := lock.Release(fd); err != nil {
+			sylog.Debugf("Could not release %s cache lock: %v", cacheType, err)
+		}
+	}, nil
+}
+
+// AddOciCacheManifest adds the image manifest desc, already written to the
+// OCI cache cacheType, to the cache layout index. The image previously
+// tagged with the same name is untagged. The index is read and rewritten
+// with a dedicated exclusive lock, held only meanwhile, so that the images
+// added by concurrent pulls are all kept.
+func (h *Handle) AddOciCacheManifest(cacheType string, desc imgspecv1.Descriptor) error {
+	unlock, err := h.lockOciCache(cacheType, cacheType+".index", lock.Exclusive)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	dir := h.getCacheTypeDir(cacheType)
+	index, err := readOciIndex(dir)
+	if os.IsNotExist(err) {
+		index = &imgspecv1.Index{Versioned: imgspecs.Versioned{SchemaVersion: 2}}
+	} else if err != nil {
+		return fmt.Errorf("could not read %s cache index: %v", cacheType, err)
+	}
+
+	name := desc.Annotations[imgspecv1.AnnotationRefName]
+	added := false
+	for i, m := range index.Manifests {
+		if name != "" && m.Annotations[imgspecv1.AnnotationRefName] == name {
+			delete(index.Manifests[i].Annotations, imgspecv1.AnnotationRefName)
+		}
+		if !added && m.Digest == desc.Digest && index.Manifests[i].Annotations[imgspecv1.AnnotationRefName] == "" {
+			index.Manifests[i] = desc
+			added = true
+		}
+	}
+	if !added {
+		index.Manifests = append(index.Manifests, desc)
+	}
+
+	layout := filepath.Join(dir, imgspecv1.ImageLayoutFile)
+	if _, err := os.Stat(layout); os.IsNotExist(err) {
+		b, err := json.Marshal(imgspecv1.ImageLayout{Version: imgspecv1.ImageLayoutVersion})
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(layout, b, 0o644); err != nil {
+			return fmt.Errorf("could not write %s cache layout: %v", cacheType, err)
+		}
+	}
+	if err := writeOciIndex(dir, index); err != nil {
+		return fmt.Errorf("could not write %s cache index: %v", cacheType, err)
+	}
+	return nil
+}
+
+// blobPath returns the path of the blob dgst in the OCI layout dir.
+func blobPath(dir string, dgst digest.Digest) string {
+	return filepath.Join(dir, "blobs", dgst.Algorithm().String(), dgst.Encoded())
+}
+
+// readOciIndex reads the index of the OCI layout dir.
+func readOciIndex(dir string) (*imgspecv1.Index, error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, "index.json"))
+	if err != nil {
+		return nil, err
+	}
+	index := new(imgspecv1.Index)
+	if err := json.Unmarshal(b, index); err != nil {
+		return nil, fmt.Errorf("while parsing %s: %v", filepath.Join(dir, "index.json"), err)
+	}
+	return index, nil
+}
+
+// writeOciIndex atomically replaces the index of the OCI layout dir.
+func writeOciIndex(dir string, index *imgspecv1.Index) error {
+	b, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, "index.json.")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, "index.json"))
+}
+
+// ociBlobRefs returns the number of references to each blob of the OCI
+// layout dir from the manifests descs, following image indexes.
+func ociBlobRefs(dir string, descs []imgspecv1.Descriptor) map[digest.Digest]int {
+	refs := make(map[digest.Digest]int)
+
+	var walk func(descs []imgspecv1.Descriptor)
+	walk = func(descs []imgspecv1.Descriptor) {
+		for _, desc := range descs {
+			refs[desc.Digest]++
+			// a manifest is only walked once, the blobs it references
+			// are counted once per manifest
+			if refs[desc.Digest] > 1 {
+				continue
+			}
+
+			b, err := ioutil.ReadFile(blobPath(dir, desc.Digest))
+			if err != nil {
+				sylog.Warningf("Could not read cached manifest %s: %v", desc.Digest, err)
+				continue
+			}
+			var m ociManifest
+			if err := json.Unmarshal(b, &m); err != nil {
+				sylog.Warningf("Could not parse cached manifest %s: %v", desc.Digest, err)
+				continue
+			}
+			if m.Config != nil {
+				refs[m.Config.Digest]++
+			}
+			for _, l := range m.Layers {
+				refs[l.Digest]++
+			}
+			walk(m.Manifests)
+		}
+	}
+	walk(descs)
+
+	return refs
+}
+
+// cleanOciCache removes the images of the OCI cache cacheType older than
+// the given number of days, or untagged, then removes the blobs which are
+// no longer referenced by any image. With days < 0 the whole cache is
+// removed.
+func (h *Handle) cleanOciCache(cacheType string, dryRun bool, days int) ([]RemovedEntry, error) {
+	unlock, err := h.lockOciCache(cacheType, cacheType, lock.Exclusive)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	dir := h.getCacheTypeDir(cacheType)
+	if days < 0 {
+		return h.cleanDir(cacheType, dir, dryRun, days)
+	}
+
+	index, err := readOciIndex(dir)
+	if os.IsNotExist(err) {
+		sylog.Infof("No cached files to remove at %s", dir)
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read %s cache index: %v", cacheType, err)
+	}
+
+	var kept []imgspecv1.Descriptor
+	for _, desc := range index.Manifests {
+		name := desc.Annotations[imgspecv1.AnnotationRefName]
+		if name != "" {
+			fi, err := os.Stat(blobPath(dir, desc.Digest))
+			if err == nil && time.Since(fi.ModTime()) < time.Duration(days*24)*time.Hour {
+				sylog.Debugf("Skipping image %s: less than %d days old", name, days)
+				kept = append(kept, desc)
+				continue
+			}
+		} else {
+			name = desc.Digest.String()
+		}
+		sylog.Infof("Removing %s cache image: %s", cacheType, name)
+	}
+
+	if !dryRun && len(kept) != len(index.Manifests) {
+		index.Manifests = kept
+		if err := writeOciIndex(dir, index); err != nil {
+			return nil, fmt.Errorf("could not write %s cache index: %v", cacheType, err)
+		}
+	}
+
+	refs := ociBlobRefs(dir, kept)
+
+	var removed []RemovedEntry
+	errCount := 0
+	algDirs, err := ioutil.ReadDir(filepath.Join(dir, "blobs"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("could not read %s cache blobs: %v", cacheType, err)
+	}
+	for _, algDir := range algDirs {
+		files, err := ioutil.ReadDir(filepath.Join(dir, "blobs", algDir.Name()))
+		if err != nil {
+			return removed, fmt.Errorf("could not read %s cache blobs: %v", cacheType, err)
+		}
+		for _, f := range files {
+			dgst := digest.NewDigestFromEncoded(digest.Algorithm(algDir.Name()), f.Name())
+			if refs[dgst] > 0 {
+				sylog.Debugf("Skipping %s: referenced %d time(s)", dgst, refs[dgst])
+				continue
+			}
+			entry := RemovedEntry{Type: cacheType, Name: f.Name(), Size: f.Size()}
+			sylog.Infof("Removing %s cache entry: %s (%s)", cacheType, f.Name(), fs.FindSize(entry.Size))
+			if !dryRun {
+				if err := os.Remove(blobPath(dir, dgst)); err != nil {
+					sylog.Errorf("Could not remove cache entry '%s': %v", f.Name(), err)
+					errCount++
+					continue
+				}
+			}
+			removed = append(removed, entry)
+		}
+	}
+
+	if errCount > 0 {
+		return removed, fmt.Errorf("failed to remove %d cache entries", errCount)
+	}
+	return removed, nil
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+)
+
+// putBlob writes content as a blob of the OCI layout dir.
+func putBlob(t *testing.T, dir string, content []byte) imgspecv1.Descriptor {
+	dgst := digest.FromBytes(content)
+	path := blobPath(dir, dgst)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return imgspecv1.Descriptor{Digest: dgst, Size: int64(len(content))}
+}
+
+// putImage mimics an image pull into the OCI blob cache of h, adding an
+// image tagged name made of the config and layers blobs to the index.
+func putImage(t *testing.T, h *Handle, name string, blobs ...string) imgspecv1.Descriptor {
+	dir, err := h.GetOciCacheDir(OciBlobCacheType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := ociManifest{}
+	for i, b := range blobs {
+		desc := putBlob(t, dir, []byte(b))
+		if i == 0 {
+			m.Config = &desc
+		} else {
+			m.Layers = append(m.Layers, desc)
+		}
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc := putBlob(t, dir, b)
+	desc.MediaType = imgspecv1.MediaTypeImageManifest
+	desc.Annotations = map[string]string{imgspecv1.AnnotationRefName: name}
+
+	if err := h.AddOciCacheManifest(OciBlobCacheType, desc); err != nil {
+		t.Fatal(err)
+	}
+	return desc
+}
+
+func TestCleanOciCache(t *testing.T) {
+	parentDir, err := ioutil.TempDir("", "cache-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(parentDir)
+
+	h, err := New(Config{ParentDir: parentDir})
+	if err != nil {
+		t.Fatalf("failed to create cache: %s", err)
+	}
+	dir, err := h.GetOciCacheDir(OciBlobCacheType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldImage := putImage(t, h, "old", "old config", "base layer", "old layer")
+	newImage := putImage(t, h, "new", "new config", "base layer", "new layer")
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(blobPath(dir, oldImage.Digest), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	removed := []string{"old config", "old layer"}
+	kept := []string{"new config", "base layer", "new layer"}
+
+	// dry run doesn't remove anything, but reports the blobs to remove
+	entries, err := h.CleanCache(OciBlobCacheType, true, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	wantEntries := map[string]int64{
+		oldImage.Digest.Encoded(): oldImage.Size,
+	}
+	for _, b := range removed {
+		wantEntries[digest.FromString(b).Encoded()] = int64(len(b))
+	}
+	if len(entries) != len(wantEntries) {
+		t.Errorf("got %d entries for dry run, want %d", len(entries), len(wantEntries))
+	}
+	for _, e := range entries {
+		if size, ok := wantEntries[e.Name]; !ok || e.Size != size || e.Type != OciBlobCacheType {
+			t.Errorf("unexpected entry %+v for dry run", e)
+		}
+	}
+	for _, b := range append(removed, kept...) {
+		if _, err := os.Stat(blobPath(dir, digest.FromString(b))); err != nil {
+			t.Errorf("blob %q removed by dry run: %s", b, err)
+		}
+	}
+
+	if _, err := h.CleanCache(OciBlobCacheType, false, 1); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, b := range removed {
+		if _, err := os.Stat(blobPath(dir, digest.FromString(b))); !os.IsNotExist(err) {
+			t.Errorf("blob %q not removed", b)
+		}
+	}
+	for _, b := range kept {
+		if _, err := os.Stat(blobPath(dir, digest.FromString(b))); err != nil {
+			t.Errorf("blob %q not kept: %s", b, err)
+		}
+	}
+	if _, err := os.Stat(blobPath(dir, oldImage.Digest)); !os.IsNotExist(err) {
+		t.Errorf("old manifest not removed")
+	}
+	if _, err := os.Stat(blobPath(dir, newImage.Digest)); err != nil {
+		t.Errorf("new manifest not kept: %s", err)
+	}
+
+	index, err := readOciIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != 1 || index.Manifests[0].Digest != newImage.Digest {
+		t.Errorf("unexpected index manifests %v", index.Manifests)
+	}
+
+	// without days the whole cache is removed
+	if _, err := h.CleanCache(OciBlobCacheType, false, -1); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) > 0 {
+		t.Errorf("cache not cleaned, %d entries remaining", len(entries))
+	}
+}
+
+func TestAddOciCacheManifest(t *testing.T) {
+	parentDir, err := ioutil.TempDir("", "cache-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(parentDir)
+
+	h, err := New(Config{ParentDir: parentDir})
+	if err != nil {
+		t.Fatalf("failed to create cache: %s", err)
+	}
+	dir, err := h.GetOciCacheDir(OciBlobCacheType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// an image pulled again from the same source takes over the tag
+	first := putImage(t, h, "image", "config", "first layer")
+	other := putImage(t, h, "other", "other config", "first layer")
+	second := putImage(t, h, "image", "config", "second layer")
+
+	if _, err := os.Stat(filepath.Join(dir, imgspecv1.ImageLayoutFile)); err != nil {
+		t.Errorf("OCI layout file not written: %s", err)
+	}
+	index, err := readOciIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[digest.Digest]string{first.Digest: "", other.Digest: "other", second.Digest: "image"}
+	if len(index.Manifests) != len(want) {
+		t.Errorf("unexpected index manifests %v", index.Manifests)
+	}
+	for _, m := range index.Manifests {
+		if name, ok := want[m.Digest]; !ok || m.Annotations[imgspecv1.AnnotationRefName] != name {
+			t.Errorf("unexpected index manifest %v", m)
+		}
+	}
+}
+
+func TestConcurrentOciPullClean(t *testing.T) {
+	parentDir, err := ioutil.TempDir("", "cache-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(parentDir)
+
+	h, err := New(Config{ParentDir: parentDir})
+	if err != nil {
+		t.Fatalf("failed to create cache: %s", err)
+	}
+	dir, err := h.GetOciCacheDir(OciBlobCacheType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const pulls = 8
+
+	var wg sync.WaitGroup
+	for i := 0; i < pulls; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			unlock, err := h.LockOciCache(OciBlobCacheType)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer unlock()
+			putImage(t, h, fmt.Sprintf("image%d", i), fmt.Sprintf("config%d", i), "base layer", fmt.Sprintf("layer%d", i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := h.CleanCache(OciBlobCacheType, false, 1); err != nil {
+				t.Errorf("unexpected clean error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	index, err := readOciIndex(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != pulls {
+		t.Errorf("unexpected number of cached images %d, expected %d", len(index.Manifests), pulls)
+	}
+	for i := 0; i < pulls; i++ {
+		for _, b := range []string{fmt.Sprintf("config%d", i), "base layer", fmt.Sprintf("layer%d", i)} {
+			if _, err := os.Stat(blobPath(dir, digest.FromString(b))); err != nil {
+				t.Errorf("blob %q of image%d removed: %s", b, i, err)
+			}
+		}
+	}
+}
@@ -47,6 +47,10 @@ const (
 	OrasCacheType = "oras"
 	// NetCacheType specifies the cache holds images pulled from http(s) internet sources
 	NetCacheType = "net"
+
+	// lockDirName specifies the name of the directory, relative to the cache
+	// root directory, holding the lock files of the cache entries being created
+	lockDirName = ".lock"
 )
 
 var (
@@ -135,13 +139,28 @@ func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 	}
 
 	if !pathExists {
+		// Serialize the creation of the entry with concurrent processes, the
+		// lock is held until the entry is finalized or cleaned
+		if err := e.lock(filepath.Join(h.rootDir, lockDirName, cacheType+"-"+hash)); err != nil {
+			return nil, fmt.Errorf("could not lock cache entry '%s': %v", e.Path, err)
+		}
+
+		// Another process may have created the entry while we were waiting
+		if fs.IsFile(e.Path) {
+			e.unlock()
+			e.Exists = true
+			return e, nil
+		}
+
 		e.Exists = false
 		f, err := fs.MakeTmpFile(cacheDir, "tmp_", 0o700)
 		if err != nil {
+			e.unlock()
 			return nil, err
 		}
 		err = f.Close()
 		if err != nil {
+			e.unlock()
 			return nil, err
 		}
 		e.TmpPath = f.Name()
@@ -158,15 +177,52 @@ func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 	return e, nil
 }
 
-func (h *Handle) CleanCache(cacheType string, dryRun bool, days int) (err error) {
-	dir := h.getCacheTypeDir(cacheType)
+// RemovedEntry describes a cache entry removed by CleanCache, or which
+// would be removed in dry run mode.
+type RemovedEntry struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// CleanCache removes the entries of the cache cacheType older than the
+// given number of days, or all the entries with days < 0. For OCI cache
+// types, images are removed along with the blobs no longer shared with
+// any other image. The removed entries are returned, in dry run mode the
+// entries which would be removed are returned and nothing is removed.
+func (h *Handle) CleanCache(cacheType string, dryRun bool, days int) ([]RemovedEntry, error) {
+	if stringInSlice(cacheType, OciCacheTypes) {
+		return h.cleanOciCache(cacheType, dryRun, days)
+	}
+	return h.cleanDir(cacheType, h.getCacheTypeDir(cacheType), dryRun, days)
+}
 
+// entrySize returns the size of the cache entry f in directory dir, the
+// size of all the files it holds if the entry is a directory.
+func entrySize(dir string, f os.FileInfo) int64 {
+	if !f.IsDir() {
+		return f.Size()
+	}
+	var size int64
+	filepath.Walk(path.Join(dir, f.Name()), func(_ string, fi os.FileInfo, err error) error {
+		if err == nil && fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// cleanDir removes the entries of the cache cacheType directory dir older
+// than the given number of days, or all the entries with days < 0.
+func (h *Handle) cleanDir(cacheType, dir string, dryRun bool, days int) ([]RemovedEntry, error) {
 	files, err := ioutil.ReadDir(dir)
 	if (err != nil && os.IsNotExist(err)) || len(files) == 0 {
 		sylog.Infof("No cached files to remove at %s", dir)
-		return nil
+		return nil, nil
 	}
 
+	var removed []RemovedEntry
 	errCount := 0
 	for _, f := range files {
 
@@ -177,22 +233,25 @@ func (h *Handle) CleanCache(cacheType string, dryRun bool, days int) (err error)
 			}
 		}
 
-		sylog.Infof("Removing %s cache entry: %s", cacheType, f.Name())
+		entry := RemovedEntry{Type: cacheType, Name: f.Name(), Size: entrySize(dir, f)}
+		sylog.Infof("Removing %s cache entry: %s (%s)", cacheType, f.Name(), fs.FindSize(entry.Size))
 		if !dryRun {
 			// We RemoveAll in case the entry is a directory from Singularity <3.6
 			err := os.RemoveAll(path.Join(dir, f.Name()))
 			if err != nil {
 				sylog.Errorf("Could not remove cache entry '%s': %v", f.Name(), err)
 				errCount = errCount + 1
+				continue
 			}
 		}
+		removed = append(removed, entry)
 	}
 
 	if errCount > 0 {
-		return fmt.Errorf("failed to remove %d cache entries", errCount)
+		return removed, fmt.Errorf("failed to remove %d cache entries", errCount)
 	}
 
-	return err
+	return removed, err
 }
 
 // cleanAllCaches is an utility function that wipes all files in the
@@ -278,7 +337,7 @@ func New(cfg Config) (h *Handle, err error) {
 		return nil, fmt.Errorf("failed initializing caching directory: %s", err)
 	}
 	// Initialize the subdirectories of the cache
-	for _, ct := range FileCacheTypes {
+	for _, ct := range append(FileCacheTypes, lockDirName) {
 		dir := h.getCacheTypeDir(ct)
 		if err = initCacheDir(dir); err != nil {
 			return nil, fmt.Errorf("failed initializing caching directory: %s", err)
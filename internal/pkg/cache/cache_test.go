@@ -0,0 +1,95 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestCleanCacheDryRun checks that a dry run reports exactly the entries
+// removed by the actual cleanup, with their size.
+func TestCleanCacheDryRun(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "cache-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	h, err := New(Config{ParentDir: tmpDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := h.GetFileCacheDir(LibraryCacheType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	if err := ioutil.WriteFile(filepath.Join(dir, "old"), []byte("old entry"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "old"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "new"), []byte("new entry"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// directory entries from Singularity <3.6 are sized with their content
+	if err := os.MkdirAll(filepath.Join(dir, "olddir", "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "olddir", "sub", "file"), []byte("old directory entry"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(filepath.Join(dir, "olddir"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []RemovedEntry{
+		{Type: LibraryCacheType, Name: "old", Size: int64(len("old entry"))},
+		{Type: LibraryCacheType, Name: "olddir", Size: int64(len("old directory entry"))},
+	}
+
+	check := func(name string, got []RemovedEntry) {
+		if len(got) != len(want) {
+			t.Fatalf("%s: got entries %+v, want %+v", name, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: got entry %+v, want %+v", name, got[i], want[i])
+			}
+		}
+	}
+
+	entries, err := h.CleanCache(LibraryCacheType, true, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	check("dry run", entries)
+	for _, name := range []string{"old", "new", "olddir"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("entry %s removed by dry run: %s", name, err)
+		}
+	}
+
+	entries, err = h.CleanCache(LibraryCacheType, false, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	check("clean", entries)
+	for _, name := range []string{"old", "olddir"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("entry %s not removed", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "new")); err != nil {
+		t.Errorf("entry new not kept: %s", err)
+	}
+}
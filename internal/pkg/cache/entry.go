@@ -12,6 +12,7 @@ import (
 
 	"github.com/sylabs/singularity/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/util/fs/lock"
 )
 
 // Entry is a structure representing an entry in the cache. An entry is a file under the
@@ -27,6 +28,41 @@ type Entry struct {
 	// tmpPath is the temporary location that should be used for a new cache entry as it
 	// is created
 	TmpPath string
+	// lockPath is the path of the lock file held while a new entry is created
+	lockPath string
+	// lockFd is the file descriptor of the held lock file
+	lockFd int
+}
+
+// lock acquires an exclusive lock on the lock file at path, waiting for
+// any concurrent process creating the same entry to release it.
+func (e *Entry) lock(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	fd, err := lock.Exclusive(path)
+	if err != nil {
+		return err
+	}
+	e.lockPath = path
+	e.lockFd = fd
+	return nil
+}
+
+// unlock releases the lock file held by the entry, if any. The lock file
+// is kept, removing it would let a waiting process and a new one hold a lock
+// on different files for the same entry.
+func (e *Entry) unlock() {
+	if e.lockPath == "" {
+		return
+	}
+	if err := lock.Release(e.lockFd); err != nil {
+		sylog.Debugf("Could not release cache lock file '%s': %v", e.lockPath, err)
+	}
+	e.lockPath = ""
 }
 
 // Finalize an entry by renaming it to its permanent path atomically
@@ -39,11 +75,15 @@ func (e *Entry) Finalize() error {
 	if err != nil {
 		return fmt.Errorf("could not finalize cached file: %v", err)
 	}
+	e.unlock()
 	return nil
 }
 
-// CleanTmp should be defer'd when an Entry is created and will remove any temporary file
+// CleanTmp should be defer'd when an Entry is created and will remove any temporary file,
+// and release the entry lock if the entry has not been finalized
 func (e *Entry) CleanTmp() {
+	defer e.unlock()
+
 	// If there is no TmpPath / file there then there is nothing to clean up
 	if e.TmpPath == "" || !fs.IsFile(e.TmpPath) {
 		return
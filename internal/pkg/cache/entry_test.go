@@ -0,0 +1,113 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pullEntry mimics an image pull, writing content to the cache entry
+// if it doesn't exist yet.
+func pullEntry(h *Handle, hash string, content []byte, downloads *int32) error {
+	e, err := h.GetEntry(LibraryCacheType, hash)
+	if err != nil {
+		return err
+	}
+	defer e.CleanTmp()
+
+	if e.Exists {
+		return nil
+	}
+	atomic.AddInt32(downloads, 1)
+
+	// write the content slowly to widen the race window
+	f, err := os.OpenFile(e.TmpPath, os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	for _, b := range content {
+		if _, err := f.Write([]byte{b}); err != nil {
+			f.Close()
+			return err
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return e.Finalize()
+}
+
+func TestConcurrentPull(t *testing.T) {
+	parentDir, err := ioutil.TempDir("", "cache-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(parentDir)
+
+	h, err := New(Config{ParentDir: parentDir})
+	if err != nil {
+		t.Fatalf("failed to create cache: %s", err)
+	}
+
+	const pulls = 8
+
+	content := []byte("concurrent pull content")
+	downloads := int32(0)
+	errs := make(chan error, pulls)
+
+	var wg sync.WaitGroup
+	for i := 0; i < pulls; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- pullEntry(h, "sha256.deadbeef", content, &downloads)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected pull error: %s", err)
+		}
+	}
+
+	if downloads != 1 {
+		t.Errorf("unexpected number of downloads %d, expected 1", downloads)
+	}
+
+	e, err := h.GetEntry(LibraryCacheType, "sha256.deadbeef")
+	if err != nil {
+		t.Fatalf("failed to get cache entry: %s", err)
+	}
+	defer e.CleanTmp()
+
+	if !e.Exists {
+		t.Fatalf("cache entry doesn't exist")
+	}
+	b, err := ioutil.ReadFile(e.Path)
+	if err != nil {
+		t.Fatalf("failed to read cache entry: %s", err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Errorf("corrupted cache entry content %q, expected %q", b, content)
+	}
+
+	// no temporary files should remain
+	tmpFiles, _ := filepath.Glob(filepath.Join(h.getCacheTypeDir(LibraryCacheType), "tmp_*"))
+	if len(tmpFiles) > 0 {
+		t.Errorf("temporary files remaining: %v", tmpFiles)
+	}
+}
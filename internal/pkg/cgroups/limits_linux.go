@@ -0,0 +1,113 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cgroups
+
+import (
+	"fmt"
+	"strconv"
+
+	units "github.com/docker/go-units"
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// defaultCPUPeriod is the CPU CFS period, in microseconds, used to compute
+// the CPU quota corresponding to a number of CPUs.
+const defaultCPUPeriod = 100000
+
+// Limits describes Docker style resource limits, as set from command line
+// flags. Empty or zero values leave the corresponding limit unset.
+type Limits struct {
+	// Memory is the memory limit, with an optional unit suffix (e.g. 4G).
+	Memory string
+	// MemorySwap is the memory plus swap limit, with an optional unit
+	// suffix, or -1 for unlimited swap.
+	MemorySwap string
+	// CPUs is the number of CPUs available, may be fractional (e.g. 1.5).
+	CPUs string
+	// CPUShares is the relative CPU weight.
+	CPUShares int64
+	// PidsLimit is the maximum number of processes, -1 for unlimited.
+	PidsLimit int64
+	// Devices are device access rules appended to the device rules of
+	// the resources, so that they take precedence. They are not limits
+	// and don't make IsSet return true.
+	Devices []specs.LinuxDeviceCgroup
+}
+
+// IsSet returns true if at least one limit is set.
+func (l Limits) IsSet() bool {
+	return l.Memory != "" || l.MemorySwap != "" || l.CPUs != "" || l.CPUShares != 0 || l.PidsLimit != 0
+}
+
+// Apply sets the limits in resources, overriding values already present.
+func (l Limits) Apply(resources *specs.LinuxResources) error {
+	if l.Memory != "" || l.MemorySwap != "" {
+		if resources.Memory == nil {
+			resources.Memory = &specs.LinuxMemory{}
+		}
+	}
+	if l.Memory != "" {
+		memory, err := units.RAMInBytes(l.Memory)
+		if err != nil {
+			return fmt.Errorf("invalid memory limit %q: %s", l.Memory, err)
+		}
+		if memory <= 0 {
+			return fmt.Errorf("invalid memory limit %q: must be greater than 0", l.Memory)
+		}
+		resources.Memory.Limit = &memory
+	}
+	if l.MemorySwap != "" {
+		if resources.Memory.Limit == nil {
+			return fmt.Errorf("memory swap limit requires a memory limit")
+		}
+		swap := int64(-1)
+		if l.MemorySwap != "-1" {
+			var err error
+			swap, err = units.RAMInBytes(l.MemorySwap)
+			if err != nil {
+				return fmt.Errorf("invalid memory swap limit %q: %s", l.MemorySwap, err)
+			}
+			if swap < *resources.Memory.Limit {
+				return fmt.Errorf("memory swap limit %q must be greater than or equal to the memory limit", l.MemorySwap)
+			}
+		}
+		resources.Memory.Swap = &swap
+	}
+
+	if l.CPUs != "" || l.CPUShares != 0 {
+		if resources.CPU == nil {
+			resources.CPU = &specs.LinuxCPU{}
+		}
+	}
+	if l.CPUs != "" {
+		cpus, err := strconv.ParseFloat(l.CPUs, 64)
+		if err != nil || cpus <= 0 {
+			return fmt.Errorf("invalid number of CPUs %q: must be a number greater than 0", l.CPUs)
+		}
+		period := uint64(defaultCPUPeriod)
+		quota := int64(cpus * defaultCPUPeriod)
+		resources.CPU.Period = &period
+		resources.CPU.Quota = &quota
+	}
+	if l.CPUShares != 0 {
+		if l.CPUShares < 0 {
+			return fmt.Errorf("invalid CPU shares %d: must be greater than 0", l.CPUShares)
+		}
+		shares := uint64(l.CPUShares)
+		resources.CPU.Shares = &shares
+	}
+
+	if l.PidsLimit != 0 {
+		if l.PidsLimit < -1 {
+			return fmt.Errorf("invalid pids limit %d: must be greater than 0, or -1 for unlimited", l.PidsLimit)
+		}
+		resources.Pids = &specs.LinuxPids{Limit: l.PidsLimit}
+	}
+
+	resources.Devices = append(resources.Devices, l.Devices...)
+
+	return nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cgroups
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestLimitsApply(t *testing.T) {
+	memory := Int64ptr(4 * 1024 * 1024 * 1024)
+	swap := Int64ptr(-1)
+	quota := Int64ptr(150000)
+	period := uint64(defaultCPUPeriod)
+	shares := uint64(512)
+
+	tests := []struct {
+		name        string
+		limits      Limits
+		resources   specs.LinuxResources
+		expected    specs.LinuxResources
+		expectError bool
+	}{
+		{
+			name: "NoLimits",
+		},
+		{
+			name:   "Memory",
+			limits: Limits{Memory: "4G", MemorySwap: "-1"},
+			expected: specs.LinuxResources{
+				Memory: &specs.LinuxMemory{Limit: memory, Swap: swap},
+			},
+		},
+		{
+			name:   "CPU",
+			limits: Limits{CPUs: "1.5", CPUShares: 512},
+			expected: specs.LinuxResources{
+				CPU: &specs.LinuxCPU{Shares: &shares, Quota: quota, Period: &period},
+			},
+		},
+		{
+			name:   "Pids",
+			limits: Limits{PidsLimit: 100},
+			expected: specs.LinuxResources{
+				Pids: &specs.LinuxPids{Limit: 100},
+			},
+		},
+		{
+			name:   "SwapWithFileMemory",
+			limits: Limits{MemorySwap: "-1"},
+			resources: specs.LinuxResources{
+				Memory: &specs.LinuxMemory{Limit: memory},
+			},
+			expected: specs.LinuxResources{
+				Memory: &specs.LinuxMemory{Limit: memory, Swap: swap},
+			},
+		},
+		{
+			name: "Devices",
+			limits: Limits{Devices: []specs.LinuxDeviceCgroup{
+				{Allow: true, Type: "b", Major: Int64ptr(7), Minor: Int64ptr(5), Access: "rw"},
+			}},
+			resources: specs.LinuxResources{
+				Devices: []specs.LinuxDeviceCgroup{{Allow: false, Access: "rwm"}},
+			},
+			expected: specs.LinuxResources{
+				Devices: []specs.LinuxDeviceCgroup{
+					{Allow: false, Access: "rwm"},
+					{Allow: true, Type: "b", Major: Int64ptr(7), Minor: Int64ptr(5), Access: "rw"},
+				},
+			},
+		},
+		{
+			name:        "InvalidMemory",
+			limits:      Limits{Memory: "4Z"},
+			expectError: true,
+		},
+		{
+			name:        "SwapWithoutMemory",
+			limits:      Limits{MemorySwap: "8G"},
+			expectError: true,
+		},
+		{
+			name:        "SwapLowerThanMemory",
+			limits:      Limits{Memory: "4G", MemorySwap: "2G"},
+			expectError: true,
+		},
+		{
+			name:        "InvalidCPUs",
+			limits:      Limits{CPUs: "0"},
+			expectError: true,
+		},
+		{
+			name:        "InvalidPids",
+			limits:      Limits{PidsLimit: -2},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Apply(&tt.resources)
+			if err != nil && !tt.expectError {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Fatalf("unexpected success")
+			}
+			if tt.expectError {
+				return
+			}
+			if !reflect.DeepEqual(tt.resources, tt.expected) {
+				t.Errorf("unexpected resources %+v, expected %+v", tt.resources, tt.expected)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 
@@ -25,6 +26,10 @@ import (
 
 var ErrUnitialized = errors.New("cgroups manager is not initialized")
 
+// systemdSliceRe matches a systemd slice unit name, without the ':'
+// separating the components of a systemd cgroups path.
+var systemdSliceRe = regexp.MustCompile(`^[a-zA-Z0-9_.\\-]+\.slice$`)
+
 // Manager provides functions to modify, freeze, thaw, and destroy a cgroup.
 // Singularity's cgroups.Manager is a wrapper around runc/libcontainer/cgroups.
 // The manager supports v1 cgroups, and v2 cgroups with a unified hierarchy.
@@ -209,10 +214,8 @@ func (m *Manager) Destroy() (err error) {
 // checkRootless identifies if rootless cgroups are required / supported
 func checkRootless(group string, systemd bool) (rootless bool, err error) {
 	if os.Getuid() == 0 {
-		if systemd {
-			if !strings.HasPrefix(group, "system.slice:") {
-				return false, fmt.Errorf("systemd cgroups require a cgroups path beginning with 'system.slice:'")
-			}
+		if systemd && !isSliceGroup(group) {
+			return false, fmt.Errorf("systemd cgroups require a cgroups path of the form '<name>.slice:<prefix>:<name>'")
 		}
 		return false, nil
 	}
@@ -227,13 +230,47 @@ func checkRootless(group string, systemd bool) (rootless bool, err error) {
 		return false, fmt.Errorf("rootless cgroups require a D-Bus session - check that XDG_RUNTIME_DIR and DBUS_SESSION_BUS_ADDRESS are set")
 	}
 
-	if !strings.HasPrefix(group, "user.slice:") {
-		return false, fmt.Errorf("rootless cgroups require a cgroups path beginning with 'user.slice:'")
+	if !isSliceGroup(group) {
+		return false, fmt.Errorf("rootless cgroups require a cgroups path of the form '<name>.slice:<prefix>:<name>'")
 	}
 
 	return true, nil
 }
 
+// isSliceGroup returns true if group is a systemd cgroups path placed
+// under a slice.
+func isSliceGroup(group string) bool {
+	parts := strings.SplitN(group, ":", 3)
+	return len(parts) == 3 && systemdSliceRe.MatchString(parts[0])
+}
+
+// ValidateParent checks that parent can hold container cgroups. With systemd
+// it must be a slice name, e.g. my.slice, otherwise an absolute cgroupfs
+// path, e.g. /my.slice. A missing parent is created with the first cgroup
+// placed under it.
+func ValidateParent(parent string, systemd bool) error {
+	if systemd {
+		if !systemdSliceRe.MatchString(parent) || parent == "-.slice" {
+			return fmt.Errorf("%q is not a systemd slice name, e.g. my.slice", parent)
+		}
+		return nil
+	}
+	if !filepath.IsAbs(parent) || filepath.Clean(parent) != parent || parent == "/" {
+		return fmt.Errorf("%q is not an absolute cgroup path below the root cgroup, e.g. /my.slice", parent)
+	}
+	return nil
+}
+
+// ParentGroup returns the cgroup name/path of the container with the given
+// pid, placed under the parent cgroup. With systemd, parent is a slice name
+// and the container runs in a singularity-<pid>.scope unit of the slice.
+func ParentGroup(parent string, pid int, systemd bool) string {
+	if systemd {
+		return parent + ":singularity:" + strconv.Itoa(pid)
+	}
+	return filepath.Join(parent, "singularity", strconv.Itoa(pid))
+}
+
 // newManager creates a new Manager, with the associated resources and cgroup.
 // The Manager is ready to manage the cgroup but does not apply limits etc.
 func newManager(resources *specs.LinuxResources, group string, systemd bool) (manager *Manager, err error) {
@@ -307,9 +344,9 @@ func NewManagerWithSpec(spec *specs.LinuxResources, pid int, group string, syste
 	}
 	if group == "" && systemd {
 		if os.Getuid() == 0 {
-			group = "system.slice:singularity:" + strconv.Itoa(pid)
+			group = ParentGroup("system.slice", pid, true)
 		} else {
-			group = "user.slice:singularity:" + strconv.Itoa(pid)
+			group = ParentGroup("user.slice", pid, true)
 		}
 	}
 
@@ -219,3 +219,46 @@ func testManager(t *testing.T, systemd bool) (pid int, manager *Manager, cleanup
 
 	return pid, manager, cleanup
 }
+
+func TestValidateParent(t *testing.T) {
+	tests := []struct {
+		name        string
+		parent      string
+		systemd     bool
+		expectError bool
+	}{
+		{name: "Slice", parent: "my.slice", systemd: true},
+		{name: "NestedSlice", parent: "my-jobs.slice", systemd: true},
+		{name: "SliceNoSuffix", parent: "my", systemd: true, expectError: true},
+		{name: "SlicePath", parent: "/my.slice", systemd: true, expectError: true},
+		{name: "SliceColon", parent: "my:x.slice", systemd: true, expectError: true},
+		{name: "RootSlice", parent: "-.slice", systemd: true, expectError: true},
+		{name: "Path", parent: "/my.slice"},
+		{name: "NestedPath", parent: "/jobs/my"},
+		{name: "RelativePath", parent: "my.slice", expectError: true},
+		{name: "UncleanPath", parent: "/jobs/../my", expectError: true},
+		{name: "RootPath", parent: "/", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateParent(tt.parent, tt.systemd)
+			if tt.expectError && err == nil {
+				t.Errorf("expected an error for %q", tt.parent)
+			} else if !tt.expectError && err != nil {
+				t.Errorf("unexpected error for %q: %s", tt.parent, err)
+			}
+		})
+	}
+}
+
+func TestParentGroup(t *testing.T) {
+	if got, want := ParentGroup("my.slice", 42, true), "my.slice:singularity:42"; got != want {
+		t.Errorf("got systemd group %q, want %q", got, want)
+	}
+	if got, want := ParentGroup("/my.slice", 42, false), "/my.slice/singularity/42"; got != want {
+		t.Errorf("got cgroupfs group %q, want %q", got, want)
+	}
+	if !isSliceGroup(ParentGroup("my.slice", 42, true)) {
+		t.Errorf("systemd group under my.slice not recognized as a slice group")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package net
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/pkg/sylog"
+	useragent "github.com/sylabs/singularity/pkg/util/user-agent"
+)
+
+// fetchValidator returns the ETag of the file at netURL, as returned by an
+// HTTP HEAD request, or its Last-Modified date if the server doesn't send
+// an ETag. An empty string is returned if the server sends neither.
+func fetchValidator(ctx context.Context, netURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, netURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", useragent.Value())
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+
+	// some servers don't implement HEAD requests, the file is then
+	// downloaded without caching
+	if res.StatusCode != http.StatusOK {
+		sylog.Debugf("HEAD request for %s returned %s", netURL, res.Status)
+		return "", nil
+	}
+	if etag := res.Header.Get("ETag"); etag != "" {
+		return "etag:" + etag, nil
+	}
+	if date := res.Header.Get("Last-Modified"); date != "" {
+		return "date:" + date, nil
+	}
+	return "", nil
+}
+
+// FetchFile downloads the file at the http(s) URL netURL through the net
+// cache, keyed by the URL and the ETag of the file, or its Last-Modified
+// date, so that an unchanged file is only downloaded once. The file is
+// downloaded to a temporary file in tmpDir when the cache is disabled, or
+// when the server doesn't identify the file version. The path of the
+// downloaded file is returned.
+func FetchFile(ctx context.Context, imgCache *cache.Handle, netURL, tmpDir string) (string, error) {
+	if !IsNetPullRef(netURL) {
+		return "", fmt.Errorf("not a valid url reference: %s", netURL)
+	}
+
+	validator, err := fetchValidator(ctx, netURL)
+	if err != nil {
+		return "", fmt.Errorf("while checking %s: %v", netURL, err)
+	}
+
+	if imgCache == nil || imgCache.IsDisabled() || validator == "" {
+		f, err := ioutil.TempFile(tmpDir, "fetch-")
+		if err != nil {
+			return "", fmt.Errorf("unable to create tmp file: %v", err)
+		}
+		f.Close()
+		sylog.Infof("Downloading %s", netURL)
+		if err := DownloadImage(ctx, f.Name(), netURL); err != nil {
+			return "", fmt.Errorf("while downloading %s: %v", netURL, err)
+		}
+		return f.Name(), nil
+	}
+
+	h := sha256.New()
+	h.Write([]byte(netURL + "\n" + validator))
+	hash := hex.EncodeToString(h.Sum(nil))
+	sylog.Debugf("Cache hash for %s is: %s", netURL, hash)
+
+	cacheEntry, err := imgCache.GetEntry(cache.NetCacheType, hash)
+	if err != nil {
+		return "", fmt.Errorf("unable to check if %v exists in cache: %v", hash, err)
+	}
+	defer cacheEntry.CleanTmp()
+
+	if cacheEntry.Exists {
+		sylog.Infof("Using %s from cache", netURL)
+		return cacheEntry.Path, nil
+	}
+
+	sylog.Infof("Downloading %s", netURL)
+	if err := DownloadImage(ctx, cacheEntry.TmpPath, netURL); err != nil {
+		return "", fmt.Errorf("while downloading %s: %v", netURL, err)
+	}
+	if err := cacheEntry.Finalize(); err != nil {
+		return "", err
+	}
+	return cacheEntry.Path, nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package net
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/singularity/internal/pkg/cache"
+	useragent "github.com/sylabs/singularity/pkg/util/user-agent"
+)
+
+func TestMain(m *testing.M) {
+	useragent.InitValue("singularity", "3.0.0-alpha.1-303-gaed8d30-dirty")
+
+	os.Exit(m.Run())
+}
+
+func TestFetchFile(t *testing.T) {
+	var etag, content string
+	gets := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if etag != "" {
+			w.Header().Set("ETag", etag)
+		}
+		if r.Method == http.MethodGet {
+			gets++
+			w.Write([]byte(content))
+		}
+	}))
+	defer srv.Close()
+
+	tmpDir, err := ioutil.TempDir("", "fetch-test-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	imgCache, err := cache.New(cache.Config{ParentDir: tmpDir})
+	if err != nil {
+		t.Fatalf("failed to create cache: %s", err)
+	}
+
+	tests := []struct {
+		name        string
+		etag        string
+		content     string
+		expectGets  int
+		expectCache bool
+	}{
+		{
+			name:        "FirstDownload",
+			etag:        `"v1"`,
+			content:     "version 1",
+			expectGets:  1,
+			expectCache: true,
+		},
+		{
+			name:        "Cached",
+			etag:        `"v1"`,
+			content:     "version 1",
+			expectGets:  1,
+			expectCache: true,
+		},
+		{
+			name:        "NewETag",
+			etag:        `"v2"`,
+			content:     "version 2",
+			expectGets:  2,
+			expectCache: true,
+		},
+		{
+			name:       "NoETag",
+			content:    "version 3",
+			expectGets: 3,
+		},
+		{
+			name:       "NoETagAgain",
+			content:    "version 3",
+			expectGets: 4,
+		},
+	}
+
+	cacheDir, err := imgCache.GetFileCacheDir(cache.NetCacheType)
+	if err != nil {
+		t.Fatalf("failed to get cache directory: %s", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			etag = tt.etag
+			content = tt.content
+
+			path, err := FetchFile(context.Background(), imgCache, srv.URL+"/file.tar.gz", tmpDir)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read downloaded file: %s", err)
+			}
+			if string(b) != tt.content {
+				t.Errorf("got content %q, want %q", b, tt.content)
+			}
+			if gets != tt.expectGets {
+				t.Errorf("got %d downloads, want %d", gets, tt.expectGets)
+			}
+			if inCache := filepath.Dir(path) == cacheDir; inCache != tt.expectCache {
+				t.Errorf("file %s in cache: %v, want %v", path, inCache, tt.expectCache)
+			}
+		})
+	}
+}
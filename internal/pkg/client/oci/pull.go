@@ -1,5 +1,5 @@
 // Copyright (c) 2020, Control Command Inc. All rights reserved.
-// Copyright (c) 2018-2020, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -10,8 +10,11 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"strings"
 
+	"github.com/containers/image/v5/docker/reference"
 	ocitypes "github.com/containers/image/v5/types"
+	digest "github.com/opencontainers/go-digest"
 	"github.com/sylabs/singularity/internal/pkg/build"
 	"github.com/sylabs/singularity/internal/pkg/build/oci"
 	"github.com/sylabs/singularity/internal/pkg/cache"
@@ -21,8 +24,8 @@ import (
 	useragent "github.com/sylabs/singularity/pkg/util/user-agent"
 )
 
-// pull will build a SIF image into the cache if directTo="", or a specific file if directTo is set.
-func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom, tmpDir string, ociAuth *ocitypes.DockerAuthConfig, noHTTPS, noCleanUp bool) (imagePath string, err error) {
+// getSystemContext returns the containers/image system context used to fetch images.
+func getSystemContext(tmpDir string, ociAuth *ocitypes.DockerAuthConfig, noHTTPS bool) *ocitypes.SystemContext {
 	// DockerInsecureSkipTLSVerify is set only if --no-https is specified to honor
 	// configuration from /etc/containers/registries.conf because DockerInsecureSkipTLSVerify
 	// can have three possible values true/false and undefined, so we left it as undefined instead
@@ -34,10 +37,36 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom, tmpDi
 		AuthFilePath:             syfs.DockerConf(),
 		DockerRegistryUserAgent:  useragent.Value(),
 		BigFilesTemporaryDir:     tmpDir,
+		SystemRegistriesConfPath: oci.RegistriesConfPath(),
 	}
 	if noHTTPS {
 		sysCtx.DockerInsecureSkipTLSVerify = ocitypes.NewOptionalBool(true)
 	}
+	return sysCtx
+}
+
+// resolveShortName qualifies a docker:// image URI without registry host
+// name with the unqualified-search registries of registries.conf, other
+// URIs are returned unchanged.
+func resolveShortName(ctx context.Context, pullFrom string, sysCtx *ocitypes.SystemContext) (string, error) {
+	if !strings.HasPrefix(pullFrom, "docker://") {
+		return pullFrom, nil
+	}
+	ref, err := oci.ResolveShortName(ctx, strings.TrimPrefix(pullFrom, "docker://"), sysCtx)
+	if err != nil {
+		return "", err
+	}
+	return "docker://" + ref, nil
+}
+
+// pull will build a SIF image into the cache if directTo="", or a specific file if directTo is set.
+func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom, tmpDir string, ociAuth *ocitypes.DockerAuthConfig, noHTTPS, noCleanUp bool) (imagePath string, err error) {
+	sysCtx := getSystemContext(tmpDir, ociAuth, noHTTPS)
+
+	pullFrom, err = resolveShortName(ctx, pullFrom, sysCtx)
+	if err != nil {
+		return "", err
+	}
 
 	hash, err := oci.ImageSHA(ctx, pullFrom, sysCtx)
 	if err != nil {
@@ -51,6 +80,7 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom, tmpDi
 		}
 		imagePath = directTo
 	} else {
+		sylog.Debugf("Looking up the cached SIF image of %s by manifest digest sha256:%s", pullFrom, hash)
 
 		cacheEntry, err := imgCache.GetEntry(cache.OciTempCacheType, hash)
 		if err != nil {
@@ -117,3 +147,70 @@ func PullToFile(ctx context.Context, imgCache *cache.Handle, pullTo, pullFrom, t
 
 	return pullTo, nil
 }
+
+// Fetch downloads the blobs of the image pullFrom to the cache, without
+// converting it to SIF, so that a following pull only converts the image.
+// Nothing is done when the cache is disabled.
+func Fetch(ctx context.Context, imgCache *cache.Handle, pullFrom, tmpDir string, ociAuth *ocitypes.DockerAuthConfig, noHTTPS bool) error {
+	if imgCache.IsDisabled() {
+		return nil
+	}
+	sysCtx := getSystemContext(tmpDir, ociAuth, noHTTPS)
+
+	pullFrom, err := resolveShortName(ctx, pullFrom, sysCtx)
+	if err != nil {
+		return err
+	}
+	ref, err := oci.ParseImageName(ctx, imgCache, pullFrom, sysCtx)
+	if err != nil {
+		return err
+	}
+	src, err := ref.NewImageSource(ctx, sysCtx)
+	if err != nil {
+		return fmt.Errorf("while fetching %s: %v", pullFrom, err)
+	}
+	return src.Close()
+}
+
+// PinDigest returns the image URI pullFrom referencing the image with the
+// manifest digest expected. For docker:// images, the reference is pinned to
+// the digest so the fetched manifest, and through it the image configuration
+// and layers, are verified against the expected digest while pulling. For
+// other transports, the digest of the image manifest is checked and pullFrom
+// is returned unchanged.
+func PinDigest(ctx context.Context, pullFrom, tmpDir string, ociAuth *ocitypes.DockerAuthConfig, noHTTPS bool, expected digest.Digest) (string, error) {
+	if err := expected.Validate(); err != nil {
+		return "", fmt.Errorf("invalid expected digest %s: %s", expected, err)
+	}
+
+	if strings.HasPrefix(pullFrom, "docker://") {
+		pullFrom, err := resolveShortName(ctx, pullFrom, getSystemContext(tmpDir, ociAuth, noHTTPS))
+		if err != nil {
+			return "", err
+		}
+		named, err := reference.ParseNormalizedNamed(strings.TrimPrefix(pullFrom, "docker://"))
+		if err != nil {
+			return "", fmt.Errorf("invalid image reference %s: %s", pullFrom, err)
+		}
+		if d, ok := named.(reference.Digested); ok && d.Digest() != expected {
+			return "", fmt.Errorf("image reference %s digest doesn't match expected digest %s", pullFrom, expected)
+		}
+		pinned, err := reference.WithDigest(reference.TrimNamed(named), expected)
+		if err != nil {
+			return "", fmt.Errorf("while pinning %s to digest %s: %s", pullFrom, expected, err)
+		}
+		return "docker://" + pinned.String(), nil
+	}
+
+	if expected.Algorithm() != digest.SHA256 {
+		return "", fmt.Errorf("only sha256 digests are supported for %s", pullFrom)
+	}
+	hash, err := oci.ImageSHA(ctx, pullFrom, getSystemContext(tmpDir, ociAuth, noHTTPS))
+	if err != nil {
+		return "", fmt.Errorf("failed to get checksum for %s: %s", pullFrom, err)
+	}
+	if hash != expected.Encoded() {
+		return "", fmt.Errorf("image %s digest sha256:%s doesn't match expected digest %s", pullFrom, hash, expected)
+	}
+	return pullFrom, nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	digest "github.com/opencontainers/go-digest"
+	useragent "github.com/sylabs/singularity/pkg/util/user-agent"
+)
+
+func TestMain(m *testing.M) {
+	useragent.InitValue("singularity", "3.0.0-alpha.1-303-gaed8d30-dirty")
+
+	os.Exit(m.Run())
+}
+
+// TestPinDigest uses image references qualified with a registry host name, as
+// short names could be resolved with the unqualified-search registries of the
+// host registries.conf, which requires network access.
+func TestPinDigest(t *testing.T) {
+	const hex = "6ac6d38e6e2c3bd9b3108ac1d2ee6fd6e8a4b5e1c437e0bd2f53e1ac5e6b0f6a"
+	expected := digest.NewDigestFromEncoded(digest.SHA256, hex)
+	other := digest.NewDigestFromEncoded(digest.SHA256, "0000000000000000000000000000000000000000000000000000000000000000")
+
+	tests := []struct {
+		name        string
+		pullFrom    string
+		expected    digest.Digest
+		want        string
+		expectError bool
+	}{
+		{
+			name:     "Tag",
+			pullFrom: "docker://docker.io/alpine:3.15",
+			expected: expected,
+			want:     "docker://docker.io/library/alpine@sha256:" + hex,
+		},
+		{
+			name:     "Registry",
+			pullFrom: "docker://quay.io/org/image",
+			expected: expected,
+			want:     "docker://quay.io/org/image@sha256:" + hex,
+		},
+		{
+			name:     "SameDigest",
+			pullFrom: "docker://docker.io/alpine@sha256:" + hex,
+			expected: expected,
+			want:     "docker://docker.io/library/alpine@sha256:" + hex,
+		},
+		{
+			name:        "OtherDigest",
+			pullFrom:    "docker://docker.io/alpine@" + other.String(),
+			expected:    expected,
+			expectError: true,
+		},
+		{
+			name:        "InvalidDigest",
+			pullFrom:    "docker://docker.io/alpine",
+			expected:    digest.Digest("sha256:1234"),
+			expectError: true,
+		},
+		{
+			name:        "InvalidReference",
+			pullFrom:    "docker://docker.io/Alpine",
+			expected:    expected,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PinDigest(context.Background(), tt.pullFrom, "", nil, false, tt.expected)
+			if err != nil && !tt.expectError {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Fatalf("unexpected success")
+			}
+			if got != tt.want {
+				t.Errorf("unexpected pinned reference %q, expected %q", got, tt.want)
+			}
+		})
+	}
+}
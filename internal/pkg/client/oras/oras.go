@@ -57,13 +57,29 @@ const (
 
 var sifLayerMediaTypes = []string{SifLayerMediaTypeV1, SifLayerMediaTypeProto}
 
-func getResolver(ctx context.Context, ociAuth *ocitypes.DockerAuthConfig) (remotes.Resolver, error) {
+// rootfsLayerMediaTypes are the mediaTypes of layers holding a root
+// filesystem tarball, as pushed by the oras CLI or other OCI tools.
+var rootfsLayerMediaTypes = []string{
+	ocispec.MediaTypeImageLayer,
+	ocispec.MediaTypeImageLayerGzip,
+	"application/x-tar",
+	"application/gzip",
+}
+
+// getResolver returns a resolver using the credentials in ociAuth if set,
+// otherwise the credentials stored in authFile, or in the default docker
+// configuration file if authFile is empty.
+func getResolver(ctx context.Context, ociAuth *ocitypes.DockerAuthConfig, authFile string) (remotes.Resolver, error) {
 	opts := docker.ResolverOptions{Credentials: genCredfn(ociAuth)}
 	if ociAuth != nil && (ociAuth.Username != "" || ociAuth.Password != "") {
 		return docker.NewResolver(opts), nil
 	}
 
-	cli, err := auth.NewClient(syfs.DockerConf())
+	if authFile == "" {
+		authFile = syfs.DockerConf()
+	}
+
+	cli, err := auth.NewClient(authFile)
 	if err != nil {
 		sylog.Warningf("Couldn't load auth credential file: %s", err)
 		return docker.NewResolver(opts), nil
@@ -73,13 +89,50 @@ func getResolver(ctx context.Context, ociAuth *ocitypes.DockerAuthConfig) (remot
 }
 
 // DownloadImage downloads a SIF image specified by an oci reference to a file using the included credentials
-func DownloadImage(ctx context.Context, imagePath, ref string, ociAuth *ocitypes.DockerAuthConfig) error {
+func DownloadImage(ctx context.Context, imagePath, ref string, ociAuth *ocitypes.DockerAuthConfig, authFile string) error {
+	if _, err := download(ctx, imagePath, ref, ociAuth, authFile, sifLayerMediaTypes); err != nil {
+		return err
+	}
+
+	// ensure that we have downloaded a SIF
+	if err := ensureSIF(imagePath); err != nil {
+		// remove whatever we downloaded if it is not a SIF
+		os.RemoveAll(imagePath)
+		return err
+	}
+
+	// ensure container is executable
+	if err := os.Chmod(imagePath, 0o755); err != nil {
+		return fmt.Errorf("unable to set image perms: %s", err)
+	}
+
+	return nil
+}
+
+// DownloadRootfs downloads the root filesystem tarball specified by an oci
+// reference to a file using the included credentials, it returns the media
+// type of the downloaded layer.
+func DownloadRootfs(ctx context.Context, tarPath, ref string, ociAuth *ocitypes.DockerAuthConfig, authFile string) (string, error) {
+	mediaType, err := download(ctx, tarPath, ref, ociAuth, authFile, rootfsLayerMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	if mediaType == "" {
+		return "", fmt.Errorf("no layer found corresponding to a root filesystem tarball")
+	}
+	return mediaType, nil
+}
+
+// download downloads the single file layer of the artifact specified by an
+// oci reference matching one of mediaTypes to path, it returns the media
+// type of the downloaded layer.
+func download(ctx context.Context, path, ref string, ociAuth *ocitypes.DockerAuthConfig, authFile string, mediaTypes []string) (string, error) {
 	ref = strings.TrimPrefix(ref, "oras://")
 	ref = strings.TrimPrefix(ref, "//")
 
 	spec, err := reference.Parse(ref)
 	if err != nil {
-		return fmt.Errorf("unable to parse oci reference: %s", err)
+		return "", fmt.Errorf("unable to parse oci reference: %s", err)
 	}
 
 	// append default tag if no object exists
@@ -88,14 +141,14 @@ func DownloadImage(ctx context.Context, imagePath, ref string, ociAuth *ocitypes
 		sylog.Infof("No tag or digest found, using default: %s", SifDefaultTag)
 	}
 
-	resolver, err := getResolver(ctx, ociAuth)
+	resolver, err := getResolver(ctx, ociAuth, authFile)
 	if err != nil {
-		return fmt.Errorf("while getting resolver: %s", err)
+		return "", fmt.Errorf("while getting resolver: %s", err)
 	}
 
 	wd, err := os.Getwd()
 	if err != nil {
-		return fmt.Errorf("failed to get working directory: %s", err)
+		return "", fmt.Errorf("failed to get working directory: %s", err)
 	}
 
 	store := content.NewFile(wd)
@@ -106,18 +159,20 @@ func DownloadImage(ctx context.Context, imagePath, ref string, ociAuth *ocitypes
 	// so we have to allow an overwrite here.
 	store.DisableOverwrite = false
 
-	allowedMediaTypes := oras.WithAllowedMediaTypes(sifLayerMediaTypes)
+	mediaType := ""
+	allowedMediaTypes := oras.WithAllowedMediaTypes(mediaTypes)
 	handlerFunc := func(ctx context.Context, desc ocispec.Descriptor) ([]ocispec.Descriptor, error) {
-		for _, mt := range sifLayerMediaTypes {
+		for _, mt := range mediaTypes {
 			if desc.MediaType == mt {
 				// Ensure descriptor is of a single file
 				// AnnotationUnpack indicates that the descriptor is of a directory
 				if desc.Annotations[content.AnnotationUnpack] == "true" {
-					return nil, fmt.Errorf("descriptor is of a bundled directory, not a SIF image")
+					return nil, fmt.Errorf("descriptor is of a bundled directory, not a single file")
 				}
 				nameOld, _ := content.ResolveName(desc)
-				sylog.Debugf("Will pull oras image %s to %s", nameOld, imagePath)
-				_ = store.MapPath(nameOld, imagePath)
+				sylog.Debugf("Will pull oras artifact %s to %s", nameOld, path)
+				_ = store.MapPath(nameOld, path)
+				mediaType = mt
 			}
 		}
 		return nil, nil
@@ -126,22 +181,10 @@ func DownloadImage(ctx context.Context, imagePath, ref string, ociAuth *ocitypes
 
 	_, err = oras.Copy(orasctx.WithLoggerDiscarded(ctx), resolver, spec.String(), store, "", allowedMediaTypes, pullHandler)
 	if err != nil {
-		return fmt.Errorf("unable to pull from registry: %s", err)
-	}
-
-	// ensure that we have downloaded a SIF
-	if err := ensureSIF(imagePath); err != nil {
-		// remove whatever we downloaded if it is not a SIF
-		os.RemoveAll(imagePath)
-		return err
-	}
-
-	// ensure container is executable
-	if err := os.Chmod(imagePath, 0o755); err != nil {
-		return fmt.Errorf("unable to set image perms: %s", err)
+		return "", fmt.Errorf("unable to pull from registry: %s", err)
 	}
 
-	return nil
+	return mediaType, nil
 }
 
 // UploadImage uploads the image specified by path and pushes it to the provided oci reference,
@@ -173,7 +216,7 @@ func UploadImage(ctx context.Context, path, ref string, ociAuth *ocitypes.Docker
 		sylog.Infof("No tag or digest found, using default: %s", SifDefaultTag)
 	}
 
-	resolver, err := getResolver(ctx, ociAuth)
+	resolver, err := getResolver(ctx, ociAuth, "")
 	if err != nil {
 		return fmt.Errorf("while getting resolver: %s", err)
 	}
@@ -229,60 +272,95 @@ func ensureSIF(filepath string) error {
 // sha512 is currently optional for implementations, this function will return an error when
 // encountering such digests.
 // https://github.com/opencontainers/image-spec/blob/master/descriptor.md#registered-algorithms
-func ImageSHA(ctx context.Context, uri string, ociAuth *ocitypes.DockerAuthConfig) (string, error) {
+func ImageSHA(ctx context.Context, uri string, ociAuth *ocitypes.DockerAuthConfig, authFile string) (string, error) {
+	man, err := fetchManifest(ctx, uri, ociAuth, authFile)
+	if err != nil {
+		return "", err
+	}
+
+	// search image layers for sif image and return sha
+	for _, l := range man.Layers {
+		for _, t := range sifLayerMediaTypes {
+			if l.MediaType == t {
+				// only allow sha256 digests
+				if l.Digest.Algorithm() != digest.SHA256 {
+					return "", fmt.Errorf("SIF layer found with incorrect digest algorithm: %s", l.Digest.Algorithm())
+				}
+				return l.Digest.String(), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no layer found corresponding to SIF image")
+}
+
+// IsRootfs returns true if the artifact specified by an oci reference holds
+// a root filesystem tarball layer rather than a SIF image.
+func IsRootfs(ctx context.Context, uri string, ociAuth *ocitypes.DockerAuthConfig, authFile string) (bool, error) {
+	man, err := fetchManifest(ctx, uri, ociAuth, authFile)
+	if err != nil {
+		return false, err
+	}
+
+	rootfs := false
+	for _, l := range man.Layers {
+		for _, t := range sifLayerMediaTypes {
+			if l.MediaType == t {
+				return false, nil
+			}
+		}
+		for _, t := range rootfsLayerMediaTypes {
+			if l.MediaType == t {
+				rootfs = true
+			}
+		}
+	}
+	return rootfs, nil
+}
+
+// fetchManifest returns the image manifest of the artifact specified by an
+// oci reference.
+func fetchManifest(ctx context.Context, uri string, ociAuth *ocitypes.DockerAuthConfig, authFile string) (ocispec.Manifest, error) {
+	var man ocispec.Manifest
+
 	ref := strings.TrimPrefix(uri, "oras://")
 	ref = strings.TrimPrefix(ref, "//")
 
-	resolver, err := getResolver(ctx, ociAuth)
+	resolver, err := getResolver(ctx, ociAuth, authFile)
 	if err != nil {
-		return "", fmt.Errorf("while getting resolver: %s", err)
+		return man, fmt.Errorf("while getting resolver: %s", err)
 	}
 
 	_, desc, err := resolver.Resolve(ctx, ref)
 	if err != nil {
-		return "", fmt.Errorf("while resolving reference: %v", err)
+		return man, fmt.Errorf("while resolving reference: %v", err)
 	}
 
 	// ensure that we received an image manifest descriptor
 	if desc.MediaType != ocispec.MediaTypeImageManifest {
-		return "", fmt.Errorf("could not get image manifest, received mediaType: %s", desc.MediaType)
+		return man, fmt.Errorf("could not get image manifest, received mediaType: %s", desc.MediaType)
 	}
 
 	fetcher, err := resolver.Fetcher(ctx, ref)
 	if err != nil {
-		return "", fmt.Errorf("while creating fetcher for reference: %v", err)
+		return man, fmt.Errorf("while creating fetcher for reference: %v", err)
 	}
 
 	rc, err := fetcher.Fetch(ctx, desc)
 	if err != nil {
-		return "", fmt.Errorf("while fetching manifest: %v", err)
+		return man, fmt.Errorf("while fetching manifest: %v", err)
 	}
 	defer rc.Close()
 
 	b, err := ioutil.ReadAll(rc)
 	if err != nil {
-		return "", fmt.Errorf("while reading manifest: %v", err)
+		return man, fmt.Errorf("while reading manifest: %v", err)
 	}
 
-	var man ocispec.Manifest
 	if err := json.Unmarshal(b, &man); err != nil {
-		return "", fmt.Errorf("while unmarshalling manifest: %v", err)
-	}
-
-	// search image layers for sif image and return sha
-	for _, l := range man.Layers {
-		for _, t := range sifLayerMediaTypes {
-			if l.MediaType == t {
-				// only allow sha256 digests
-				if l.Digest.Algorithm() != digest.SHA256 {
-					return "", fmt.Errorf("SIF layer found with incorrect digest algorithm: %s", l.Digest.Algorithm())
-				}
-				return l.Digest.String(), nil
-			}
-		}
+		return man, fmt.Errorf("while unmarshalling manifest: %v", err)
 	}
-
-	return "", fmt.Errorf("no layer found corresponding to SIF image")
+	return man, nil
 }
 
 // ImageHash returns the appropriate hash for a provided image file
@@ -12,9 +12,11 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/util/singularityconf"
 	useragent "github.com/sylabs/singularity/pkg/util/user-agent"
 )
 
@@ -47,15 +49,47 @@ type APIResponse struct {
 	Commit  string `json:"commit"`
 }
 
+// shubMirror returns the base URL of the Singularity Hub compatible
+// endpoint set by the 'shub mirror' directive of singularity.conf, or
+// an empty string if there is none.
+func shubMirror() string {
+	if cfg := singularityconf.GetCurrentConfig(); cfg != nil {
+		return strings.TrimSuffix(cfg.ShubMirror, "/")
+	}
+	return ""
+}
+
 // GetManifest will return the image manifest for a container uri
-// from Singularity Hub.
+// from Singularity Hub. If the uri refers to the default registry
+// and the manifest can't be retrieved from it, the mirror set in
+// singularity.conf, if any, is tried.
 func GetManifest(uri URI, noHTTPS bool) (APIResponse, error) {
+	manifest, err := getManifest(uri, noHTTPS)
+	if err == nil || uri.registry != defaultRegistry+shubAPIRoute {
+		return manifest, err
+	}
+
+	mirror := shubMirror()
+	if mirror == "" {
+		return manifest, err
+	}
+
+	sylog.Warningf("Unable to get manifest from %s: %s", defaultRegistry, err)
+	uri.registry = mirror + shubAPIRoute
+	sylog.Infof("Falling back to Singularity Hub mirror: %s", uri.String())
+
+	return getManifest(uri, noHTTPS)
+}
+
+func getManifest(uri URI, noHTTPS bool) (APIResponse, error) {
 	// Create a new http Hub client
 	httpc := http.Client{
 		Timeout: 30 * time.Second,
 	}
 
-	if uri.registry != defaultRegistry+shubAPIRoute {
+	// default registry and mirror are URLs, custom registries from
+	// shub references are host names
+	if !strings.Contains(uri.registry, "://") {
 		uri.registry = "https://" + uri.registry
 	}
 
@@ -75,7 +109,7 @@ func GetManifest(uri URI, noHTTPS bool) (APIResponse, error) {
 	}
 	req.Header.Set("User-Agent", useragent.Value())
 
-	sylog.Debugf("shub request: %s", req.URL.String())
+	sylog.Verbosef("Fetching shub manifest from %s", req.URL.String())
 
 	// Do the request, if status isn't success, return error
 	res, err := httpc.Do(req)
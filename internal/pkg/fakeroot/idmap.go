@@ -0,0 +1,93 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package fakeroot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// ParseIDMapping parses a user namespace ID mapping specification of the
+// form containerID:hostID:count.
+func ParseIDMapping(spec string) (specs.LinuxIDMapping, error) {
+	var ids [3]uint32
+
+	fields := strings.Split(spec, ":")
+	if len(fields) != len(ids) {
+		return specs.LinuxIDMapping{}, fmt.Errorf("invalid ID mapping %q: must be containerID:hostID:count", spec)
+	}
+	for i, f := range fields {
+		id, err := strconv.ParseUint(f, 10, 32)
+		if err != nil {
+			return specs.LinuxIDMapping{}, fmt.Errorf("invalid ID mapping %q: %q is not a valid ID", spec, f)
+		}
+		ids[i] = uint32(id)
+	}
+	if ids[2] == 0 {
+		return specs.LinuxIDMapping{}, fmt.Errorf("invalid ID mapping %q: count must be greater than 0", spec)
+	}
+	if uint64(ids[0])+uint64(ids[2]) > 1<<32-1 || uint64(ids[1])+uint64(ids[2]) > 1<<32-1 {
+		return specs.LinuxIDMapping{}, fmt.Errorf("invalid ID mapping %q: range overflows", spec)
+	}
+
+	return specs.LinuxIDMapping{
+		ContainerID: ids[0],
+		HostID:      ids[1],
+		Size:        ids[2],
+	}, nil
+}
+
+// overlap returns true if ranges [a, a+asize) and [b, b+bsize) overlap.
+func overlap(a, asize, b, bsize uint32) bool {
+	return uint64(a) < uint64(b)+uint64(bsize) && uint64(b) < uint64(a)+uint64(asize)
+}
+
+// GetMappedID returns the container ID the host ID id is mapped to.
+func GetMappedID(mappings []specs.LinuxIDMapping, id uint32) (uint32, error) {
+	for _, m := range mappings {
+		if overlap(m.HostID, m.Size, id, 1) {
+			return m.ContainerID + id - m.HostID, nil
+		}
+	}
+	return 0, fmt.Errorf("host ID %d is not mapped", id)
+}
+
+// CheckIDMappings checks that mappings don't overlap and that each host
+// range is either the ID id of the user with UID uid, or allocated to the
+// user in the subuid/subgid file at path.
+func CheckIDMappings(path string, uid, id uint32, mappings []specs.LinuxIDMapping) error {
+	var allowed *specs.LinuxIDMapping
+
+	for i, m := range mappings {
+		for _, o := range mappings[:i] {
+			if overlap(m.ContainerID, m.Size, o.ContainerID, o.Size) {
+				return fmt.Errorf("ID mapping %d:%d:%d overlaps container IDs of %d:%d:%d", m.ContainerID, m.HostID, m.Size, o.ContainerID, o.HostID, o.Size)
+			}
+			if overlap(m.HostID, m.Size, o.HostID, o.Size) {
+				return fmt.Errorf("ID mapping %d:%d:%d overlaps host IDs of %d:%d:%d", m.ContainerID, m.HostID, m.Size, o.ContainerID, o.HostID, o.Size)
+			}
+		}
+
+		if m.HostID == id && m.Size == 1 {
+			continue
+		}
+
+		if allowed == nil {
+			idRange, err := GetIDRange(path, uid)
+			if err != nil {
+				return fmt.Errorf("while getting ID range allocated in %s: %s", path, err)
+			}
+			allowed = idRange
+		}
+		if uint64(m.HostID) < uint64(allowed.HostID) || uint64(m.HostID)+uint64(m.Size) > uint64(allowed.HostID)+uint64(allowed.Size) {
+			return fmt.Errorf("ID mapping %d:%d:%d is outside of your allocated range %d-%d in %s", m.ContainerID, m.HostID, m.Size, allowed.HostID, uint64(allowed.HostID)+uint64(allowed.Size)-1, path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package fakeroot
+
+import (
+	"os"
+	"testing"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/sylabs/singularity/internal/pkg/test"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/internal/pkg/util/user"
+)
+
+func TestParseIDMapping(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected *specs.LinuxIDMapping
+	}{
+		{spec: "0:1000:1", expected: &specs.LinuxIDMapping{ContainerID: 0, HostID: 1000, Size: 1}},
+		{spec: "1:100000:65536", expected: &specs.LinuxIDMapping{ContainerID: 1, HostID: 100000, Size: 65536}},
+		{spec: ""},
+		{spec: "0:1000"},
+		{spec: "0:1000:1:1"},
+		{spec: "a:1000:1"},
+		{spec: "0:-1:1"},
+		{spec: "0:1000:0"},
+		{spec: "0:4294967295:2"},
+	}
+
+	for _, tt := range tests {
+		m, err := ParseIDMapping(tt.spec)
+		if err != nil && tt.expected != nil {
+			t.Errorf("unexpected error for %q: %s", tt.spec, err)
+		} else if err == nil && tt.expected == nil {
+			t.Errorf("unexpected success for %q", tt.spec)
+		} else if err == nil && m != *tt.expected {
+			t.Errorf("unexpected mapping %+v for %q, expected %+v", m, tt.spec, *tt.expected)
+		}
+	}
+}
+
+func TestGetMappedID(t *testing.T) {
+	mappings := []specs.LinuxIDMapping{
+		{ContainerID: 0, HostID: 1, Size: 1},
+		{ContainerID: 1, HostID: 100000, Size: 10},
+	}
+
+	if id, err := GetMappedID(mappings, 1); err != nil || id != 0 {
+		t.Errorf("unexpected container ID %d for host ID 1: %v", id, err)
+	}
+	if id, err := GetMappedID(mappings, 100005); err != nil || id != 6 {
+		t.Errorf("unexpected container ID %d for host ID 100005: %v", id, err)
+	}
+	if _, err := GetMappedID(mappings, 100010); err == nil {
+		t.Errorf("unexpected success for unmapped host ID 100010")
+	}
+}
+
+func TestCheckIDMappings(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	getPwUID = getPwUIDMock
+	getPwNam = getPwNamMock
+	defer func() {
+		getPwUID = user.GetPwUID
+		getPwNam = user.GetPwNam
+	}()
+
+	f, err := fs.MakeTmpFile("", "subid-", 0o700)
+	if err != nil {
+		t.Fatalf("failed to create temporary file")
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString("daemon:100000:65536\n")
+	f.Close()
+
+	tests := []struct {
+		name        string
+		uid         uint32
+		mappings    []specs.LinuxIDMapping
+		expectError bool
+	}{
+		{
+			name: "OwnID",
+			uid:  2,
+			mappings: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 2, Size: 1},
+			},
+		},
+		{
+			name: "AllocatedRange",
+			uid:  1,
+			mappings: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 1, Size: 1},
+				{ContainerID: 1, HostID: 100000, Size: 65536},
+			},
+		},
+		{
+			name: "PartialAllocatedRange",
+			uid:  1,
+			mappings: []specs.LinuxIDMapping{
+				{ContainerID: 1000, HostID: 1, Size: 1},
+				{ContainerID: 0, HostID: 100010, Size: 1000},
+			},
+		},
+		{
+			name: "OutsideAllocatedRange",
+			uid:  1,
+			mappings: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 165000, Size: 1000},
+			},
+			expectError: true,
+		},
+		{
+			name: "NoAllocatedRange",
+			uid:  2,
+			mappings: []specs.LinuxIDMapping{
+				{ContainerID: 1, HostID: 100000, Size: 1},
+			},
+			expectError: true,
+		},
+		{
+			name: "OverlappingContainerIDs",
+			uid:  1,
+			mappings: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 1, Size: 1},
+				{ContainerID: 0, HostID: 100000, Size: 10},
+			},
+			expectError: true,
+		},
+		{
+			name: "OverlappingHostIDs",
+			uid:  1,
+			mappings: []specs.LinuxIDMapping{
+				{ContainerID: 0, HostID: 100000, Size: 10},
+				{ContainerID: 100, HostID: 100005, Size: 10},
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckIDMappings(f.Name(), tt.uid, tt.uid, tt.mappings)
+			if err != nil && !tt.expectError {
+				t.Errorf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Errorf("unexpected success")
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package fakeroot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/util/bin"
+	"github.com/sylabs/singularity/internal/pkg/util/env"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+)
+
+// findBin is also used for mocking purpose
+var findBin = bin.FindBin
+
+// Prerequisite describes a fakeroot prerequisite which is not satisfied.
+type Prerequisite struct {
+	// Name identifies the prerequisite (helper binary or mapping file).
+	Name string
+	// Problem describes what is wrong with the prerequisite.
+	Problem string
+	// Hint describes how to fix the problem.
+	Hint string
+}
+
+// PreflightError is returned by Preflight and lists all the fakeroot
+// prerequisites which are not satisfied on the host.
+type PreflightError struct {
+	Failed []Prerequisite
+}
+
+func (e *PreflightError) Error() string {
+	var b strings.Builder
+
+	b.WriteString("fakeroot prerequisites are not satisfied:")
+	for _, p := range e.Failed {
+		fmt.Fprintf(&b, "\n  - %s: %s", p.Name, p.Problem)
+		if p.Hint != "" {
+			fmt.Fprintf(&b, "\n    %s", p.Hint)
+		}
+	}
+	return b.String()
+}
+
+// Preflight checks that the host provides everything required to run
+// with fakeroot for the user identified by uid. When helpers is true,
+// the newuidmap/newgidmap helpers used by the unprivileged workflow are
+// checked. When mappings is true, the user entries in the subuid and
+// subgid files are checked. All problems are reported at once through
+// a PreflightError.
+func Preflight(uid uint32, helpers bool, mappings bool) error {
+	var failed []Prerequisite
+
+	if helpers {
+		for _, name := range []string{"newuidmap", "newgidmap"} {
+			if p := checkHelper(name); p != nil {
+				failed = append(failed, *p)
+			}
+		}
+	}
+	if mappings {
+		for _, path := range []string{SubUIDFile, SubGIDFile} {
+			if p := checkMapping(path, uid); p != nil {
+				failed = append(failed, *p)
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return &PreflightError{Failed: failed}
+	}
+	return nil
+}
+
+// checkHelper checks that the named ID mapping helper is installed and
+// setuid root, as required to write the subordinate ID mappings.
+func checkHelper(name string) *Prerequisite {
+	path, err := findBin(name)
+	if err != nil {
+		return &Prerequisite{
+			Name:    name,
+			Problem: fmt.Sprintf("not found in PATH (%s)", env.DefaultPath),
+			Hint:    "install the 'uidmap' package (Debian/Ubuntu) or 'shadow-utils' package (RHEL/Fedora/SUSE)",
+		}
+	}
+
+	if !fs.IsOwner(path, 0) {
+		return &Prerequisite{
+			Name:    path,
+			Problem: "not owned by root",
+			Hint:    fmt.Sprintf("run 'chown root:root %s' as root", path),
+		}
+	}
+
+	if !fs.IsSuid(path) {
+		return &Prerequisite{
+			Name:    path,
+			Problem: "setuid bit is not set",
+			Hint:    fmt.Sprintf("run 'chmod u+s %s' as root", path),
+		}
+	}
+
+	return nil
+}
+
+// checkMapping checks that the subuid/subgid file at path holds a usable
+// mapping entry for the user identified by uid.
+func checkMapping(path string, uid uint32) *Prerequisite {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return &Prerequisite{
+			Name:    path,
+			Problem: "file does not exist",
+			Hint:    "ask your administrator to run 'singularity config fakeroot --add <user>'",
+		}
+	}
+
+	config, err := GetConfig(path, false, getPwNam)
+	if err != nil {
+		return &Prerequisite{Name: path, Problem: err.Error()}
+	}
+	defer config.Close()
+
+	userinfo, err := getPwUID(uid)
+	if err != nil {
+		return &Prerequisite{
+			Name:    path,
+			Problem: fmt.Sprintf("could not retrieve user with UID %d: %s", uid, err),
+		}
+	}
+
+	e, err := config.GetUserEntry(userinfo.Name)
+	if err != nil {
+		return &Prerequisite{
+			Name:    path,
+			Problem: err.Error(),
+			Hint:    fmt.Sprintf("ask your administrator to run 'singularity config fakeroot --add %s'", userinfo.Name),
+		}
+	}
+	if e.disabled {
+		return &Prerequisite{
+			Name:    path,
+			Problem: fmt.Sprintf("mapping entry for %s has been disabled by the administrator", userinfo.Name),
+			Hint:    fmt.Sprintf("ask your administrator to run 'singularity config fakeroot --enable %s'", userinfo.Name),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package fakeroot
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sylabs/singularity/internal/pkg/test"
+	"github.com/sylabs/singularity/internal/pkg/util/bin"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/internal/pkg/util/user"
+)
+
+func TestCheckHelper(t *testing.T) {
+	test.EnsurePrivilege(t)
+
+	f, err := fs.MakeTmpFile("", "newuidmap-", 0o755)
+	if err != nil {
+		t.Fatalf("failed to create temporary file: %s", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	defer func() {
+		findBin = bin.FindBin
+	}()
+
+	tests := []struct {
+		name    string
+		found   bool
+		mode    os.FileMode
+		problem string
+	}{
+		{
+			name:    "NotFound",
+			found:   false,
+			problem: "not found in PATH",
+		},
+		{
+			name:    "NotSetuid",
+			found:   true,
+			mode:    0o755,
+			problem: "setuid bit is not set",
+		},
+		{
+			name:  "Setuid",
+			found: true,
+			mode:  os.ModeSetuid | 0o755,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findBin = func(name string) (string, error) {
+				if !tt.found {
+					return "", fmt.Errorf("%s not found", name)
+				}
+				return f.Name(), nil
+			}
+			if tt.found {
+				if err := os.Chmod(f.Name(), tt.mode); err != nil {
+					t.Fatalf("failed to set mode: %s", err)
+				}
+			}
+
+			p := checkHelper("newuidmap")
+			if tt.problem == "" && p != nil {
+				t.Fatalf("unexpected problem: %s", p.Problem)
+			} else if tt.problem != "" {
+				if p == nil {
+					t.Fatalf("unexpected success")
+				}
+				if !strings.Contains(p.Problem, tt.problem) {
+					t.Errorf("unexpected problem %q, expected %q", p.Problem, tt.problem)
+				}
+				if p.Hint == "" {
+					t.Errorf("no hint reported for %q", p.Problem)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckMapping(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	getPwUID = getPwUIDMock
+	getPwNam = getPwNamMock
+	defer func() {
+		getPwUID = user.GetPwUID
+		getPwNam = user.GetPwNam
+	}()
+
+	f, err := fs.MakeTmpFile("", "subid-", 0o644)
+	if err != nil {
+		t.Fatalf("failed to create temporary file: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	f.WriteString("root:100000:65536\n!daemon:165536:65536\n")
+	f.Close()
+
+	tests := []struct {
+		name string
+		path string
+		uid  uint32
+		hint string
+	}{
+		{
+			name: "MissingFile",
+			path: "/a/bad/path",
+			uid:  0,
+			hint: "--add <user>",
+		},
+		{
+			name: "ValidEntry",
+			path: f.Name(),
+			uid:  0,
+		},
+		{
+			name: "DisabledEntry",
+			path: f.Name(),
+			uid:  1,
+			hint: "--enable daemon",
+		},
+		{
+			name: "NoEntry",
+			path: f.Name(),
+			uid:  2,
+			hint: "--add bin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := checkMapping(tt.path, tt.uid)
+			if tt.hint == "" && p != nil {
+				t.Fatalf("unexpected problem: %s", p.Problem)
+			} else if tt.hint != "" {
+				if p == nil {
+					t.Fatalf("unexpected success")
+				}
+				if !strings.Contains(p.Hint, tt.hint) {
+					t.Errorf("unexpected hint %q, expected %q", p.Hint, tt.hint)
+				}
+			}
+		})
+	}
+}
+
+func TestPreflightError(t *testing.T) {
+	err := &PreflightError{
+		Failed: []Prerequisite{
+			{Name: "newuidmap", Problem: "not found", Hint: "install it"},
+			{Name: "/etc/subuid", Problem: "no entry"},
+		},
+	}
+
+	expected := "fakeroot prerequisites are not satisfied:\n" +
+		"  - newuidmap: not found\n    install it\n" +
+		"  - /etc/subuid: no entry"
+	if err.Error() != expected {
+		t.Errorf("unexpected error message:\n%s\nexpected:\n%s", err.Error(), expected)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package fakeroot
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// shimDaemons lists the names of the fakeroot(1) daemon using SysV IPC,
+// by order of preference.
+var shimDaemons = []string{"faked-sysv", "faked"}
+
+// shimLibs lists the patterns matching the fakeroot(1) preload library
+// communicating with the SysV IPC daemon, by order of preference.
+var shimLibs = []string{
+	"/usr/lib/*/libfakeroot/libfakeroot-sysv.so",
+	"/usr/lib64/libfakeroot/libfakeroot-sysv.so",
+	"/usr/lib/libfakeroot/libfakeroot-sysv.so",
+	"/usr/lib/*/libfakeroot/libfakeroot-0.so",
+	"/usr/lib64/libfakeroot/libfakeroot-0.so",
+	"/usr/lib/libfakeroot/libfakeroot-0.so",
+}
+
+// Shim is a fakeroot(1) daemon running on the host. Processes preloading
+// the fakeroot library with the daemon key have the ownership changes,
+// device nodes and other privileged operations they request faked by the
+// daemon instead of failing.
+type Shim struct {
+	// Key identifies the daemon, it's passed to the preloaded library
+	// through the FAKEROOTKEY environment variable.
+	Key string
+	// Lib is the path of the fakeroot library on the host.
+	Lib string
+
+	pid int
+}
+
+// findShimLib returns the path of the fakeroot preload library on the host.
+func findShimLib() (string, error) {
+	for _, pattern := range shimLibs {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return "", err
+		}
+		if len(matches) > 0 {
+			return matches[0], nil
+		}
+	}
+	return "", fmt.Errorf("fakeroot library not found, install the 'fakeroot' package")
+}
+
+// StartShim starts a fakeroot daemon on the host and returns the Shim
+// used to connect processes to it, the daemon runs until Stop is called.
+func StartShim() (*Shim, error) {
+	var faked string
+	var err error
+	for _, name := range shimDaemons {
+		if faked, err = findBin(name); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fakeroot daemon not found, install the 'fakeroot' package")
+	}
+
+	lib, err := findShimLib()
+	if err != nil {
+		return nil, err
+	}
+
+	// faked forks in the background and prints "<key>:<pid>"
+	out, err := exec.Command(faked).Output()
+	if err != nil {
+		return nil, fmt.Errorf("while starting %s: %s", faked, err)
+	}
+	fields := strings.SplitN(strings.TrimSpace(string(out)), ":", 2)
+	if len(fields) != 2 || fields[0] == "" {
+		return nil, fmt.Errorf("unexpected %s output: %q", faked, out)
+	}
+	pid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected %s output: %q", faked, out)
+	}
+	sylog.Debugf("Started fakeroot daemon %s with key %s and PID %d", faked, fields[0], pid)
+
+	return &Shim{Key: fields[0], Lib: lib, pid: pid}, nil
+}
+
+// Stop terminates the fakeroot daemon, the ownership and device nodes it
+// was faking are forgotten.
+func (s *Shim) Stop() error {
+	if err := syscall.Kill(s.pid, syscall.SIGTERM); err != nil && err != syscall.ESRCH {
+		return fmt.Errorf("while stopping fakeroot daemon %d: %s", s.pid, err)
+	}
+	return nil
+}
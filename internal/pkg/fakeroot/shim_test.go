@@ -0,0 +1,44 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package fakeroot
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShim(t *testing.T) {
+	shim, err := StartShim()
+	if err != nil {
+		t.Skipf("fakeroot shim not available: %s", err)
+	}
+	defer func() {
+		if err := shim.Stop(); err != nil {
+			t.Errorf("unexpected error while stopping shim: %s", err)
+		}
+	}()
+
+	dir, err := ioutil.TempDir("", "fakeroot-shim-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	node := filepath.Join(dir, "null")
+	cmd := exec.Command("/bin/sh", "-c", "mknod \"$1\" c 1 3 && chown 1234:1234 \"$1\" && stat -c '%F %u' \"$1\"", "sh", node)
+	cmd.Env = append(os.Environ(), "LD_PRELOAD="+shim.Lib, "FAKEROOTKEY="+shim.Key)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("unexpected error: %s: %s", err, out)
+	}
+	if got, want := strings.TrimSpace(string(out)), "character special file 1234"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -1,5 +1,5 @@
 // Copyright (c) 2020, Control Command Inc. All rights reserved.
-// Copyright (c) 2019-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2019-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -14,16 +14,24 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sync"
 
 	"github.com/sylabs/singularity/internal/pkg/util/bin"
 	"github.com/sylabs/singularity/pkg/sylog"
 	"github.com/sylabs/singularity/pkg/util/namespaces"
 	"golang.org/x/sys/unix"
+	"golang.org/x/term"
 )
 
 const (
 	stdinFile = "/proc/self/fd/0"
 
+	// unsquashfs progress is displayed for images larger than 1GiB
+	progressMinSize = 1 << 30
+
+	// maximum size of unsquashfs output kept for error reporting
+	maxOutputSize = 64 * 1024
+
 	// exclude 'dev/' directory from extraction for non root users
 	excludeDevRegex = `^(.{0}[^d]|.{1}[^e]|.{2}[^v]|.{3}[^\x2f]).*$`
 )
@@ -58,6 +66,10 @@ func unsquashfsCmd(unsquashfs string, dest string, filename string, filter strin
 // Squashfs represents a squashfs unpacker.
 type Squashfs struct {
 	UnsquashfsPath string
+	// TmpDir is the directory where the squashfs data are staged when
+	// they can't be read directly by unsquashfs, it defaults to the
+	// destination parent directory.
+	TmpDir string
 }
 
 // NewSquashfs initializes and returns a Squahfs unpacker instance
@@ -83,8 +95,11 @@ func (s *Squashfs) extract(files []string, reader io.Reader, dest string) (err e
 
 	if _, ok := reader.(*os.File); !ok {
 		// use the destination parent directory to store the
-		// temporary archive
-		tmpdir := filepath.Dir(dest)
+		// temporary archive if no temporary directory is set
+		tmpdir := s.TmpDir
+		if tmpdir == "" {
+			tmpdir = filepath.Dir(dest)
+		}
 
 		// unsquashfs doesn't support to send file content over
 		// a stdin pipe since it use lseek for every read it does
@@ -167,7 +182,7 @@ func (s *Squashfs) extract(files []string, reader io.Reader, dest string) (err e
 		cmd.Stdin = reader
 	}
 
-	o, err := cmd.CombinedOutput()
+	o, err := runUnsquashfs(cmd, showProgress(reader))
 	if err == nil {
 		return nil
 	}
@@ -191,12 +206,76 @@ func (s *Squashfs) extract(files []string, reader io.Reader, dest string) (err e
 	if stdin {
 		cmd.Stdin = reader
 	}
-	if o, err := cmd.CombinedOutput(); err != nil {
+	if o, err := runUnsquashfs(cmd, showProgress(reader)); err != nil {
 		return fmt.Errorf("extract command failed: %s: %s", string(o), err)
 	}
 	return nil
 }
 
+// runUnsquashfs runs the unsquashfs command and returns the tail of its
+// output. The output could be huge for large images, only the last bytes
+// are kept for error reporting. If progress is true, the unsquashfs
+// standard output, displaying the extraction progress, is also copied
+// to the standard error.
+func runUnsquashfs(cmd *exec.Cmd, progress bool) ([]byte, error) {
+	output := &tailBuffer{max: maxOutputSize}
+
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if progress {
+		cmd.Stdout = io.MultiWriter(os.Stderr, output)
+	}
+
+	err := cmd.Run()
+	return output.Bytes(), err
+}
+
+// showProgress returns if the extraction progress should be displayed
+// for the squashfs data read from reader, which is the case for large
+// images when the standard error is a terminal.
+func showProgress(reader io.Reader) bool {
+	if sylog.GetLevel() < int(sylog.InfoLevel) || !term.IsTerminal(2) {
+		return false
+	}
+
+	size := int64(-1)
+	switch r := reader.(type) {
+	case *os.File:
+		if fi, err := r.Stat(); err == nil {
+			size = fi.Size()
+		}
+	case interface{ Size() int64 }:
+		size = r.Size()
+	}
+	return size >= progressMinSize
+}
+
+// tailBuffer is a writer keeping only the last max bytes written.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// Bytes returns the bytes kept by the buffer.
+func (t *tailBuffer) Bytes() []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.buf
+}
+
 // ExtractAll extracts a squashfs filesystem read from reader to a
 // destination directory.
 func (s *Squashfs) ExtractAll(reader io.Reader, dest string) error {
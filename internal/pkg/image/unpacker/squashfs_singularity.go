@@ -203,14 +203,20 @@ func unsquashfsSandboxCmd(unsquashfs string, dest string, filename string, filte
 		"-B", fmt.Sprintf("%s:%s", tmpdir, rootfsImageDir),
 	}
 
-	if filename != stdinFile {
-		filename = filepath.Join(rootfsImageDir, filepath.Base(filename))
-	}
-
 	roFiles := []string{
 		unsquashfs,
 	}
 
+	if filename != stdinFile {
+		if filepath.Dir(filename) == tmpdir {
+			filename = filepath.Join(rootfsImageDir, filepath.Base(filename))
+		} else {
+			// the staging file is outside of the destination parent
+			// directory, bind it at the same location
+			roFiles = append(roFiles, filename)
+		}
+	}
+
 	// get the library dependencies of unsquashfs
 	libs, err := getLibraryBinds(unsquashfs)
 	if err != nil {
@@ -8,6 +8,8 @@ package unpacker
 
 import (
 	"bufio"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -120,6 +122,47 @@ func testSquashfs(t *testing.T, tmpParent string) {
 	if !isExist(path) {
 		t.Errorf("file extraction failed, %s is missing", path)
 	}
+	os.Remove(path)
+
+	// stage the archive in a separate temporary directory
+	stagingDir, err := ioutil.TempDir(tmpParent, "staging-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	s.TmpDir = stagingDir
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ExtractFiles([]string{"squashfs_test.go"}, bufio.NewReader(archive), dir); err != nil {
+		t.Error(err)
+	}
+	if !isExist(path) {
+		t.Errorf("staged file extraction failed, %s is missing", path)
+	}
+	if entries, err := ioutil.ReadDir(stagingDir); err != nil {
+		t.Error(err)
+	} else if len(entries) > 0 {
+		t.Errorf("staging directory %s is not empty", stagingDir)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{max: 8}
+
+	fmt.Fprint(b, "0123")
+	if string(b.Bytes()) != "0123" {
+		t.Errorf("unexpected buffer content %q", b.Bytes())
+	}
+	fmt.Fprint(b, "456789")
+	if string(b.Bytes()) != "23456789" {
+		t.Errorf("unexpected buffer content %q", b.Bytes())
+	}
+	fmt.Fprint(b, "abcdefghijkl")
+	if string(b.Bytes()) != "efghijkl" {
+		t.Errorf("unexpected buffer content %q", b.Bytes())
+	}
 }
 
 func TestMain(m *testing.M) {
@@ -48,6 +48,8 @@ type File struct {
 	IP         string `json:"ip"`
 	LogErrPath string `json:"logErrPath"`
 	LogOutPath string `json:"logOutPath"`
+	Supervisor int    `json:"supervisor,omitempty"`
+	Restarts   int    `json:"restarts"`
 }
 
 // ProcName returns processus name based on instance name
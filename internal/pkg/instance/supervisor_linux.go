@@ -0,0 +1,194 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package instance
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sylabs/singularity/pkg/sylog"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// SupervisedEnv is set in the environment of instance start processes
+	// spawned by a supervisor to restart an instance, so they don't start
+	// another supervisor.
+	SupervisedEnv = "SINGULARITY_INSTANCE_SUPERVISED"
+
+	restartNo        = "no"
+	restartOnFailure = "on-failure"
+)
+
+var (
+	// supervisePollInterval is the interval between instance state checks.
+	supervisePollInterval = 500 * time.Millisecond
+	// restartDelay is the delay before restarting an exited instance.
+	restartDelay = time.Second
+)
+
+// RestartPolicy describes when a supervised instance is restarted.
+type RestartPolicy struct {
+	// MaxRestarts is the maximum number of restarts, 0 means unlimited.
+	MaxRestarts int
+}
+
+// ParseRestartPolicy parses a restart policy of the form no or
+// on-failure[:max]. A nil policy is returned for the no policy.
+func ParseRestartPolicy(policy string) (*RestartPolicy, error) {
+	if policy == "" || policy == restartNo {
+		return nil, nil
+	}
+
+	kv := strings.SplitN(policy, ":", 2)
+	if kv[0] != restartOnFailure {
+		return nil, fmt.Errorf("unknown restart policy %q, supported policies are %s and %s[:max]", policy, restartNo, restartOnFailure)
+	}
+
+	p := &RestartPolicy{}
+	if len(kv) == 2 {
+		max, err := strconv.Atoi(kv[1])
+		if err != nil || max <= 0 {
+			return nil, fmt.Errorf("invalid maximum restart count %q in restart policy", kv[1])
+		}
+		p.MaxRestarts = max
+	}
+	return p, nil
+}
+
+// SetSubreaper makes the current process the subreaper of the instances it
+// starts, their daemonized parent processes are reparented to it so that
+// Supervise gets their exit status. It must be called before the instance
+// is started.
+func SetSubreaper() error {
+	return unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0)
+}
+
+// Supervise monitors the instance name and calls restart each time the
+// instance exits on failure, with a non-zero exit status or by a signal,
+// until the maximum number of restarts allowed by policy is reached. When
+// the instance exit status is unknown, because the supervisor is not the
+// subreaper of the instance, any exit not requested with DetachSupervisor
+// is considered a failure. A SIGINT or SIGTERM received by the supervisor
+// stops the instance without restarting it. Supervise only returns once the
+// instance is stopped, so it must be run by a foreground process like a
+// systemd service.
+func Supervise(name string, policy *RestartPolicy, restart func() error) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	restarts := 0
+	stopping := false
+
+	file, err := setSupervisor(name, restarts)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(supervisePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case sig := <-sigs:
+			stopping = true
+			if sig != syscall.SIGUSR1 {
+				sylog.Infof("Stopping instance %s (PID=%d)", name, file.Pid)
+				syscall.Kill(file.Pid, syscall.SIGTERM)
+			}
+		case <-ticker.C:
+		}
+
+		exited, failed := instanceExited(file)
+		if !exited {
+			continue
+		}
+		if stopping {
+			sylog.Verbosef("Instance %s stopped after %d restart(s)", name, restarts)
+			return nil
+		}
+		if !failed {
+			sylog.Infof("Instance %s exited successfully after %d restart(s)", name, restarts)
+			return nil
+		}
+		if policy.MaxRestarts > 0 && restarts >= policy.MaxRestarts {
+			return fmt.Errorf("instance %s exited, maximum number of restarts (%d) reached", name, policy.MaxRestarts)
+		}
+
+		restarts++
+		sylog.Warningf("Instance %s exited, restarting it (%d)", name, restarts)
+		time.Sleep(restartDelay)
+
+		if err := restart(); err != nil {
+			return fmt.Errorf("while restarting instance %s: %s", name, err)
+		}
+		if file, err = setSupervisor(name, restarts); err != nil {
+			return err
+		}
+	}
+}
+
+// instanceExited returns whether the instance parent process exited, and
+// if it exited on failure. The exit status is only known when the process
+// is a child of the supervisor, otherwise any exit is considered a failure.
+func instanceExited(file *File) (exited bool, failed bool) {
+	var status syscall.WaitStatus
+
+	pid, err := syscall.Wait4(file.PPid, &status, syscall.WNOHANG, nil)
+	if err == nil {
+		if pid != file.PPid {
+			return false, false
+		}
+		return true, status.Signaled() || status.ExitStatus() != 0
+	}
+	return file.isExited(), true
+}
+
+// setSupervisor records the current process as the supervisor of the
+// instance name along with its restart count.
+func setSupervisor(name string, restarts int) (*File, error) {
+	file, err := Get(name, SingSubDir)
+	if err != nil {
+		return nil, fmt.Errorf("while getting instance %s: %s", name, err)
+	}
+	file.Supervisor = os.Getpid()
+	file.Restarts = restarts
+	if err := file.Update(); err != nil {
+		return nil, fmt.Errorf("while updating instance %s: %s", name, err)
+	}
+	return file, nil
+}
+
+// DetachSupervisor notifies the supervisor of the instance, if any, that
+// the instance is being stopped on purpose and must not be restarted.
+func (i *File) DetachSupervisor() error {
+	if i.Supervisor <= 0 {
+		return nil
+	}
+
+	// the supervisor PID is read from a file owned by the instance
+	// owner, don't signal a process belonging to another user
+	var fst, pst syscall.Stat_t
+	if err := syscall.Stat(i.Path, &fst); err != nil {
+		return err
+	}
+	if err := syscall.Stat(fmt.Sprintf("/proc/%d", i.Supervisor), &pst); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if fst.Uid != pst.Uid {
+		return fmt.Errorf("supervisor process %d is not owned by the instance owner", i.Supervisor)
+	}
+	return syscall.Kill(i.Supervisor, syscall.SIGUSR1)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package instance
+
+import (
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseRestartPolicy(t *testing.T) {
+	tests := []struct {
+		policy  string
+		want    *RestartPolicy
+		wantErr bool
+	}{
+		{policy: "", want: nil},
+		{policy: "no", want: nil},
+		{policy: "on-failure", want: &RestartPolicy{}},
+		{policy: "on-failure:3", want: &RestartPolicy{MaxRestarts: 3}},
+		{policy: "on-failure:0", wantErr: true},
+		{policy: "on-failure:-1", wantErr: true},
+		{policy: "on-failure:x", wantErr: true},
+		{policy: "always", wantErr: true},
+		{policy: "no:3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			got, err := ParseRestartPolicy(tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRestartPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRestartPolicy() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstanceExited(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		wantFailed bool
+	}{
+		{name: "exit 0", script: "exit 0", wantFailed: false},
+		{name: "exit 1", script: "exit 1", wantFailed: true},
+		{name: "signal", script: "kill -KILL $$", wantFailed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command("/bin/sh", "-c", tt.script)
+			if err := cmd.Start(); err != nil {
+				t.Fatalf("while starting process: %s", err)
+			}
+			file := &File{PPid: cmd.Process.Pid}
+
+			for i := 0; ; i++ {
+				exited, failed := instanceExited(file)
+				if exited {
+					if failed != tt.wantFailed {
+						t.Errorf("instanceExited() failed = %v, want %v", failed, tt.wantFailed)
+					}
+					return
+				}
+				if i == 100 {
+					t.Fatalf("process %d didn't exit", file.PPid)
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		})
+	}
+}
@@ -48,43 +48,23 @@ func (ep *Config) KeyserverClientOpts(uri string, op KeyserverOp) ([]keyclient.O
 	if isDefault {
 		uri = primaryKeyserver.URI
 
-		if op == KeyserverVerifyOp {
-			// verify operation can query multiple keyserver, the token
-			// is automatically set by the custom client
+		if op != KeyserverPushOp {
+			// read operations query keyservers in order until one
+			// succeeds, the token is automatically set by the custom
+			// client
 			keyservers = ep.Keyservers
 		} else {
-			// use the primary keyserver
+			// push to the primary keyserver only
 			keyservers = []*ServiceConfig{
 				primaryKeyserver,
 			}
 		}
-	} else if ep.Exclusive {
-		available := make([]string, 0)
-		found := false
-		for _, kc := range ep.Keyservers {
-			if kc.Skip {
-				continue
-			}
-			available = append(available, kc.URI)
-			if remoteutil.SameKeyserver(uri, kc.URI) {
-				found = true
-				break
-			}
-		}
-		if !found {
-			list := strings.Join(available, ", ")
-			return nil, fmt.Errorf(
-				"endpoint is set as exclusive by the system administrator: only %q can be used",
-				list,
-			)
-		}
 	} else {
-		keyservers = []*ServiceConfig{
-			{
-				URI:      uri,
-				External: true,
-			},
+		kc, err := ep.selectKeyserver(uri)
+		if err != nil {
+			return nil, err
 		}
+		keyservers = []*ServiceConfig{kc}
 	}
 
 	co := []keyclient.Option{
@@ -162,3 +142,30 @@ func (ep *Config) BuilderClientConfig(uri string) (*buildclient.Config, error) {
 
 	return config, nil
 }
+
+// selectKeyserver returns the keyserver of the configured list matching uri,
+// used with its credentials and settings. Other keyservers are used without
+// credentials, unless the endpoint is exclusive.
+func (ep *Config) selectKeyserver(uri string) (*ServiceConfig, error) {
+	available := make([]string, 0)
+	for _, kc := range ep.Keyservers {
+		if kc.Skip {
+			continue
+		}
+		if remoteutil.SameKeyserver(uri, kc.URI) {
+			return kc, nil
+		}
+		available = append(available, kc.URI)
+	}
+	if ep.Exclusive {
+		list := strings.Join(available, ", ")
+		return nil, fmt.Errorf(
+			"endpoint is set as exclusive by the system administrator: only %q can be used",
+			list,
+		)
+	}
+	return &ServiceConfig{
+		URI:      uri,
+		External: true,
+	}, nil
+}
@@ -6,6 +6,7 @@
 package endpoint
 
 import (
+	"reflect"
 	"testing"
 
 	useragent "github.com/sylabs/singularity/pkg/util/user-agent"
@@ -130,6 +131,73 @@ func TestKeyserverClientOpts(t *testing.T) {
 	}
 }
 
+func TestSelectKeyserver(t *testing.T) {
+	primary := &ServiceConfig{URI: SCSDefaultKeyserverURI}
+	secondary := &ServiceConfig{URI: "http://localhost:11371", External: true, Insecure: true}
+	skipped := &ServiceConfig{URI: "https://skipped.keys", Skip: true}
+	keyservers := []*ServiceConfig{primary, secondary, skipped}
+
+	tests := []struct {
+		name        string
+		exclusive   bool
+		uri         string
+		expected    *ServiceConfig
+		expectError bool
+	}{
+		{
+			name:     "Primary",
+			uri:      SCSDefaultKeyserverURI,
+			expected: primary,
+		},
+		{
+			name:     "Secondary",
+			uri:      "http://localhost:11371/",
+			expected: secondary,
+		},
+		{
+			name:     "Unconfigured",
+			uri:      "https://custom.keys",
+			expected: &ServiceConfig{URI: "https://custom.keys", External: true},
+		},
+		{
+			name:     "Skipped",
+			uri:      "https://skipped.keys",
+			expected: &ServiceConfig{URI: "https://skipped.keys", External: true},
+		},
+		{
+			name:      "ExclusiveConfigured",
+			exclusive: true,
+			uri:       "http://localhost:11371",
+			expected:  secondary,
+		},
+		{
+			name:        "ExclusiveUnconfigured",
+			exclusive:   true,
+			uri:         "https://custom.keys",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := &Config{Keyservers: keyservers, Exclusive: tt.exclusive}
+			kc, err := ep.selectKeyserver(tt.uri)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(kc, tt.expected) {
+				t.Errorf("got keyserver %+v, expected %+v", kc, tt.expected)
+			}
+		})
+	}
+}
+
 //nolint:dupl
 func TestLibraryClientConfig(t *testing.T) {
 	tests := []struct {
@@ -162,11 +162,14 @@ type keyserverTransport struct {
 func (c *keyserverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 	ctx := req.Context()
 
-	for i, k := range c.keyservers {
-		if k.Skip {
-			continue
+	keyservers := make([]*ServiceConfig, 0, len(c.keyservers))
+	for _, k := range c.keyservers {
+		if !k.Skip {
+			keyservers = append(keyservers, k)
 		}
+	}
 
+	for i, k := range keyservers {
 		cloneReq := req.Clone(ctx)
 
 		if i > 0 {
@@ -191,19 +194,29 @@ func (c *keyserverTransport) RoundTrip(req *http.Request) (*http.Response, error
 			tr.TLSClientConfig.InsecureSkipVerify = k.Insecure
 		}
 
+		last := i == len(keyservers)-1
+
 		resp, err := c.client.Do(cloneReq)
 		if err != nil {
-			if i < len(c.keyservers)-1 {
+			if !last {
+				sylog.Verbosef("Keyserver %s failed: %s", k.URI, err)
 				continue
 			}
 			return resp, err
 		}
 
-		if resp.StatusCode/100 != 2 && i < len(c.keyservers)-1 {
+		if resp.StatusCode/100 != 2 && !last {
+			sylog.Verbosef("Keyserver %s responded with status %d", k.URI, resp.StatusCode)
 			resp.Body.Close()
 			continue
 		}
 
+		if i > 0 && resp.StatusCode/100 == 2 {
+			sylog.Infof("Response received from fallback keyserver %s", k.URI)
+		} else {
+			sylog.Debugf("Response received from keyserver %s", k.URI)
+		}
+
 		return resp, err
 	}
 
@@ -6,6 +6,10 @@
 package endpoint
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 
@@ -187,3 +191,93 @@ func TestAddRemoveKeyserver(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyserverFallback(t *testing.T) {
+	newServer := func(status int, body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			fmt.Fprint(w, body)
+		}))
+	}
+
+	down := newServer(http.StatusInternalServerError, "down")
+	defer down.Close()
+	notFound := newServer(http.StatusNotFound, "not found")
+	defer notFound.Close()
+	up := newServer(http.StatusOK, "up")
+	defer up.Close()
+
+	tests := []struct {
+		name         string
+		keyservers   []*ServiceConfig
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name: "Primary",
+			keyservers: []*ServiceConfig{
+				{URI: up.URL},
+				{URI: down.URL, External: true},
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: "up",
+		},
+		{
+			name: "Fallback",
+			keyservers: []*ServiceConfig{
+				{URI: down.URL},
+				{URI: notFound.URL, External: true},
+				{URI: up.URL, External: true},
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: "up",
+		},
+		{
+			name: "SkipPrimary",
+			keyservers: []*ServiceConfig{
+				{URI: down.URL, Skip: true},
+				{URI: up.URL, External: true},
+			},
+			expectedCode: http.StatusOK,
+			expectedBody: "up",
+		},
+		{
+			name: "AllFailed",
+			keyservers: []*ServiceConfig{
+				{URI: down.URL},
+				{URI: notFound.URL, External: true},
+			},
+			expectedCode: http.StatusNotFound,
+			expectedBody: "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := tt.keyservers[0].URI
+			for _, k := range tt.keyservers {
+				if !k.Skip {
+					primary = k.URI
+					break
+				}
+			}
+
+			resp, err := newClient(tt.keyservers, KeyserverPullOp).Get(primary + "/pks/lookup")
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer resp.Body.Close()
+
+			b, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("while reading response: %s", err)
+			}
+			if resp.StatusCode != tt.expectedCode {
+				t.Errorf("unexpected status code %d, expected %d", resp.StatusCode, tt.expectedCode)
+			}
+			if string(b) != tt.expectedBody {
+				t.Errorf("unexpected response %q, expected %q", b, tt.expectedBody)
+			}
+		})
+	}
+}
@@ -77,26 +77,10 @@ func (e *EngineOperations) PrepareConfig(starterConfig *starter.Config) error {
 		return fmt.Errorf("unable to parse singularity.conf file: %s", err)
 	}
 
-	if starterConfig.GetIsSUID() {
-		if !fileConfig.AllowSetuid {
-			return fmt.Errorf("fakeroot requires to set 'allow setuid = yes' in %s", configurationFile)
-		}
-	} else {
-		sylog.Verbosef("Fakeroot requested with unprivileged workflow, fallback to newuidmap/newgidmap")
-		sylog.Debugf("Search for newuidmap binary")
-		if err := starterConfig.SetNewUIDMapPath(); err != nil {
-			return err
-		}
-		sylog.Debugf("Search for newgidmap binary")
-		if err := starterConfig.SetNewGIDMapPath(); err != nil {
-			return err
-		}
+	if starterConfig.GetIsSUID() && !fileConfig.AllowSetuid {
+		return fmt.Errorf("fakeroot requires to set 'allow setuid = yes' in %s", configurationFile)
 	}
 
-	g.AddOrReplaceLinuxNamespace(specs.UserNamespace, "")
-	g.AddOrReplaceLinuxNamespace(specs.MountNamespace, "")
-	g.AddOrReplaceLinuxNamespace(specs.PIDNamespace, "")
-
 	uid := uint32(os.Getuid())
 	gid := uint32(os.Getgid())
 
@@ -113,6 +97,28 @@ func (e *EngineOperations) PrepareConfig(starterConfig *starter.Config) error {
 		getIDRange = callbacks[0].(fakerootcallback.UserMapping)
 	}
 
+	// report all missing prerequisites at once, mappings provided by
+	// a plugin are not checked against the subuid/subgid files
+	if err := fakerootutil.Preflight(uid, !starterConfig.GetIsSUID(), len(callbacks) == 0); err != nil {
+		return err
+	}
+
+	if !starterConfig.GetIsSUID() {
+		sylog.Verbosef("Fakeroot requested with unprivileged workflow, fallback to newuidmap/newgidmap")
+		sylog.Debugf("Search for newuidmap binary")
+		if err := starterConfig.SetNewUIDMapPath(); err != nil {
+			return err
+		}
+		sylog.Debugf("Search for newgidmap binary")
+		if err := starterConfig.SetNewGIDMapPath(); err != nil {
+			return err
+		}
+	}
+
+	g.AddOrReplaceLinuxNamespace(specs.UserNamespace, "")
+	g.AddOrReplaceLinuxNamespace(specs.MountNamespace, "")
+	g.AddOrReplaceLinuxNamespace(specs.PIDNamespace, "")
+
 	g.AddLinuxUIDMapping(uid, 0, 1)
 	idRange, err := getIDRange(fakerootutil.SubUIDFile, uid)
 	if err != nil {
@@ -72,6 +72,35 @@ func (e *EngineOperations) CleanupContainer(ctx context.Context, fatal error, st
 		}
 	}
 
+	if workdirSession != "" {
+		sylog.Verbosef("Removing workdir session directory %s", workdirSession)
+
+		var err error
+
+		if e.EngineConfig.GetFakeroot() && os.Getuid() != 0 {
+			// files created with fakeroot in the overlay upper
+			// directory are owned by subordinate IDs
+			err = fakerootCleanup(workdirSession)
+		} else {
+			err = os.RemoveAll(workdirSession)
+		}
+		if err != nil {
+			sylog.Errorf("failed to delete workdir session directory %s: %s", workdirSession, err)
+		}
+	}
+
+	for _, stub := range fileStubs {
+		// only remove the empty files created as bind destinations
+		fi, err := os.Lstat(stub)
+		if err != nil || !fi.Mode().IsRegular() || fi.Size() != 0 {
+			continue
+		}
+		sylog.Debugf("Removing %s created in sandbox", stub)
+		if err := os.Remove(stub); err != nil {
+			sylog.Warningf("could not remove %s created in sandbox: %s", stub, err)
+		}
+	}
+
 	if networkSetup != nil {
 		net := e.EngineConfig.GetNetwork()
 		privileged := false
@@ -156,6 +185,9 @@ func umount() (err error) {
 }
 
 func cleanupCrypt(path string) error {
+	// the overlay stacked on top of the decrypted root filesystem
+	// is unmounted first (umount processes mount points in reverse
+	// order), the crypt device can't be closed while still in use
 	if err := umount(); err != nil {
 		return err
 	}
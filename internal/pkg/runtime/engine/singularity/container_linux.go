@@ -7,14 +7,17 @@ package singularity
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
 
+	units "github.com/docker/go-units"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sylabs/singularity/internal/pkg/buildcfg"
 	"github.com/sylabs/singularity/internal/pkg/cgroups"
@@ -55,6 +58,8 @@ var (
 	imageDriver    image.Driver
 	umountPoints   []string
 	cgroupsManager *cgroups.Manager
+	workdirSession string
+	fileStubs      []string
 )
 
 // defaultCNIConfPath is the default directory to CNI network configuration files.
@@ -181,6 +186,8 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 		return err
 	}
 
+	// mount points are unmounted in reverse order during cleanup, so
+	// an overlay final path is unmounted before the root filesystem
 	umountPoints = append(umountPoints, c.session.RootFsPath())
 
 	if c.session.FinalPath() != c.session.RootFsPath() {
@@ -233,6 +240,12 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 	if err := c.addScratchMount(system); err != nil {
 		return err
 	}
+	if err := c.addTmpfsMounts(system); err != nil {
+		return err
+	}
+	if err := c.addWritableCwdMount(system); err != nil {
+		return err
+	}
 	if err := c.addLibsMount(system); err != nil {
 		return err
 	}
@@ -242,6 +255,9 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 	if err := c.addResolvConfMount(system); err != nil {
 		return err
 	}
+	if err := c.addHostsMount(system); err != nil {
+		return err
+	}
 	if err := c.addHostnameMount(system); err != nil {
 		return err
 	}
@@ -260,6 +276,10 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 		return err
 	}
 
+	if err := c.createPwd(); err != nil {
+		return err
+	}
+
 	if engine.EngineConfig.GetNvCCLI() {
 		// If a container has a CUDA install in it then nvidia-container-cli will bind mount
 		// from <session_dir>/final/usr/local/cuda/compat into the main container lib dir.
@@ -279,6 +299,10 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 		}
 	}
 
+	if err := c.setRootfsPropagation(); err != nil {
+		return err
+	}
+
 	// chroot from RPC server current working directory since
 	// it's already in final directory after chdirFinal call
 	sylog.Debugf("Chroot into %s\n", c.session.FinalPath())
@@ -298,7 +322,8 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 	}
 
 	cgTOML := engine.EngineConfig.GetCgroupsTOML()
-	if cgTOML != "" {
+	cgJSON := engine.EngineConfig.GetCgroupsJSON()
+	if cgTOML != "" || cgJSON != "" {
 		// Rootless cgroups setup interacts with systemd over D-Bus.
 		// The session bus address and XDG runtime dir must be set in the environment.
 		if os.Getuid() != 0 {
@@ -306,7 +331,20 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 			os.Setenv("XDG_RUNTIME_DIR", engine.EngineConfig.GetXdgRuntimeDir())
 			os.Setenv("DBUS_SESSION_BUS_ADDRESS", engine.EngineConfig.GetDbusSessionBusAddress())
 		}
-		cgroupsManager, err = cgroups.NewManagerWithFile(cgTOML, pid, "", engine.EngineConfig.File.SystemdCgroups)
+		systemd := engine.EngineConfig.File.SystemdCgroups
+		group := ""
+		if parent := engine.EngineConfig.GetCgroupsParent(); parent != "" {
+			group = cgroups.ParentGroup(parent, pid, systemd)
+		}
+		if cgJSON != "" {
+			resources := new(specs.LinuxResources)
+			if err := json.Unmarshal([]byte(cgJSON), resources); err != nil {
+				return fmt.Errorf("while decoding cgroups config: %v", err)
+			}
+			cgroupsManager, err = cgroups.NewManagerWithSpec(resources, pid, group, systemd)
+		} else {
+			cgroupsManager, err = cgroups.NewManagerWithFile(cgTOML, pid, group, systemd)
+		}
 		if err != nil {
 			return fmt.Errorf("while applying cgroups config: %v", err)
 		}
@@ -538,6 +576,32 @@ func (c *container) setPropagationMount(system *mount.System) error {
 	return c.rpcOps.Mount("", "/", "", pflags, "")
 }
 
+// rootfsPropagationFlags maps the --rootfs-propagation values to their
+// mount flags.
+var rootfsPropagationFlags = map[string]uintptr{
+	"private": syscall.MS_PRIVATE,
+	"slave":   syscall.MS_SLAVE,
+	"shared":  syscall.MS_SHARED,
+}
+
+// setRootfsPropagation applies the requested propagation to the container
+// root mount and the mounts below it, once everything is mounted.
+func (c *container) setRootfsPropagation() error {
+	propagation := c.engine.EngineConfig.GetRootfsPropagation()
+	if propagation == "" {
+		return nil
+	}
+	flags, ok := rootfsPropagationFlags[propagation]
+	if !ok {
+		return fmt.Errorf("unknown root filesystem propagation %q, must be private, slave or shared", propagation)
+	}
+	sylog.Debugf("Set container root filesystem mount propagation to %s", propagation)
+	if err := c.rpcOps.Mount("", c.session.FinalPath(), "", flags|syscall.MS_REC, ""); err != nil {
+		return fmt.Errorf("while setting root filesystem mount propagation to %s: %s", propagation, err)
+	}
+	return nil
+}
+
 // addMountinfo handles the case where hidepid is set on /proc mount
 // point preventing this process from accessing /proc/<rpc_pid>/mountinfo
 // without error, so we bind mount /proc/self/mountinfo from RPC process
@@ -739,6 +803,9 @@ func (c *container) mountImage(mnt *mount.Point) error {
 		if err != nil {
 			return err
 		}
+		// the encrypted file system is always set up read-only,
+		// writable overlays are stacked on top of it
+		flags |= syscall.MS_RDONLY
 	}
 
 	if imageDriver != nil && imageDriver.Features()&image.ImageFeature != 0 {
@@ -788,7 +855,7 @@ func (c *container) mountImage(mnt *mount.Point) error {
 			masterPid = os.Getpid()
 		}
 
-		cryptDev, err = c.rpcOps.Decrypt(offset, path, key, masterPid)
+		cryptDev, err = c.rpcOps.Decrypt(offset, path, key, masterPid, flags&syscall.MS_RDONLY != 0)
 
 		if err != nil {
 			return fmt.Errorf("unable to decrypt the file system: %s", err)
@@ -920,6 +987,62 @@ func (c *container) overlayUpperWork(system *mount.System) error {
 	return nil
 }
 
+// privilegedRPC returns whether the RPC server operations run with more
+// privileges than the calling user, in setuid mode without user namespace.
+func (c *container) privilegedRPC() bool {
+	return os.Geteuid() != 0 && !c.userNS
+}
+
+// sessionBackedRoot returns whether the container root filesystem is a
+// writable tmpfs overlay stored in the session directory on top of an image
+// file, which the user can't modify from the host while it's set up.
+func (c *container) sessionBackedRoot() bool {
+	cfg := c.engine.EngineConfig
+	return cfg.GetWritableTmpfs() && cfg.GetWritableTmpfsDir() == "" && !fs.IsDir(cfg.GetImage())
+}
+
+// hostOverlayAllowed returns whether a host directory can be used as an
+// overlay layer. In setuid mode, the overlay is mounted with root privileges
+// and copy-up runs with root credentials, so like sandbox overlays, it's
+// only allowed to the root user or within a user namespace.
+func (c *container) hostOverlayAllowed() bool {
+	return !c.privilegedRPC()
+}
+
+// createWorkdirSession creates a per-session directory under workdir
+// holding the writable tmpfs overlay upper and work directories, so the
+// backing storage is not limited by the session directory size. The
+// session directory is only accessible by the calling user and is
+// removed by CleanupContainer. It must only be used when hostOverlayAllowed
+// returns true.
+func createWorkdirSession(workdir string) (string, error) {
+	workdir, err := filepath.Abs(filepath.Clean(workdir))
+	if err != nil {
+		return "", fmt.Errorf("can't determine absolute path of workdir %s: %s", workdir, err)
+	}
+	if fi, err := os.Stat(workdir); err != nil {
+		return "", fmt.Errorf("could not use workdir %s: %s", workdir, err)
+	} else if !fi.IsDir() {
+		return "", fmt.Errorf("workdir %s is not a directory", workdir)
+	}
+
+	dir, err := ioutil.TempDir(workdir, "session-")
+	if err != nil {
+		return "", fmt.Errorf("could not create session directory in workdir %s: %s", workdir, err)
+	}
+	workdirSession = dir
+
+	for _, d := range []string{"upper", "work"} {
+		if err := os.Mkdir(filepath.Join(dir, d), 0o755); err != nil {
+			return "", fmt.Errorf("could not create session directory %s: %s", d, err)
+		}
+	}
+
+	sylog.Debugf("Using %s for writable tmpfs overlay storage", dir)
+
+	return dir, nil
+}
+
 func (c *container) addOverlayMount(system *mount.System) error {
 	nb := 0
 	ov := c.session.Layer.(*overlay.Overlay)
@@ -928,15 +1051,48 @@ func (c *container) addOverlayMount(system *mount.System) error {
 	if c.engine.EngineConfig.GetWritableTmpfs() {
 		sylog.Debugf("Setup writable tmpfs overlay")
 
-		if err := c.session.AddDir("/tmpfs/upper"); err != nil {
-			return err
-		}
-		if err := c.session.AddDir("/tmpfs/work"); err != nil {
-			return err
-		}
+		var upper, work string
 
-		upper, _ := c.session.GetPath("/tmpfs/upper")
-		work, _ := c.session.GetPath("/tmpfs/work")
+		if dir := c.engine.EngineConfig.GetWritableTmpfsDir(); dir != "" {
+			// directory created by the CLI to commit the changes, it's
+			// kept on exit
+			if !c.engine.EngineConfig.File.UserBindControl {
+				return fmt.Errorf("committing --writable-tmpfs changes requires 'user bind control = yes'")
+			}
+			if !c.hostOverlayAllowed() {
+				return fmt.Errorf("committing --writable-tmpfs changes requires root or a user namespace")
+			}
+			upper = filepath.Join(dir, "upper")
+			work = filepath.Join(dir, "work")
+			for _, d := range []string{upper, work} {
+				if fi, err := os.Lstat(d); err != nil {
+					return fmt.Errorf("could not use writable tmpfs directory %s: %s", d, err)
+				} else if !fi.IsDir() {
+					return fmt.Errorf("writable tmpfs directory %s is not a directory", d)
+				}
+			}
+			sylog.Debugf("Using %s for writable tmpfs overlay storage", dir)
+		} else if workdir := c.engine.EngineConfig.GetWorkdir(); workdir != "" && c.engine.EngineConfig.File.UserBindControl && c.hostOverlayAllowed() {
+			dir, err := createWorkdirSession(workdir)
+			if err != nil {
+				return err
+			}
+			upper = filepath.Join(dir, "upper")
+			work = filepath.Join(dir, "work")
+		} else {
+			if c.engine.EngineConfig.GetWorkdir() != "" && !c.hostOverlayAllowed() {
+				sylog.Verbosef("Not using workdir for writable tmpfs storage: requires root or a user namespace")
+			}
+			if err := c.session.AddDir("/tmpfs/upper"); err != nil {
+				return err
+			}
+			if err := c.session.AddDir("/tmpfs/work"); err != nil {
+				return err
+			}
+
+			upper, _ = c.session.GetPath("/tmpfs/upper")
+			work, _ = c.session.GetPath("/tmpfs/work")
+		}
 
 		if err := ov.SetUpperDir(upper); err != nil {
 			return fmt.Errorf("failed to add overlay upper: %s", err)
@@ -1284,6 +1440,55 @@ func (c *container) addSessionDevAt(srcpath string, atpath string, system *mount
 	return nil
 }
 
+// checkDeviceSource ensures that the device requested at path is a block or
+// character device located below the host /dev, and returns its resolved
+// location.
+func checkDeviceSource(path, src string) (string, error) {
+	if path != filepath.Clean(path) || !strings.HasPrefix(path, "/dev/") {
+		return "", fmt.Errorf("invalid device %q: must be an absolute path below /dev", path)
+	}
+	resolved, err := filepath.EvalSymlinks(src)
+	if err != nil {
+		return "", fmt.Errorf("while resolving device %s: %s", src, err)
+	}
+	if !strings.HasPrefix(resolved, "/dev/") {
+		return "", fmt.Errorf("invalid device %s: located in %s", path, filepath.Dir(resolved))
+	}
+	fi, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("while checking device %s: %s", path, err)
+	}
+	if fi.Mode()&os.ModeDevice == 0 {
+		return "", fmt.Errorf("invalid device %s: not a block or character device", path)
+	}
+	return resolved, nil
+}
+
+// checkDevPath ensures that a --dev custom entry is a device node, directory
+// or symlink whose parent directory resolves below the host /dev. Device
+// nodes are further checked with checkDeviceSource.
+func checkDevPath(path string) error {
+	if path != filepath.Clean(path) || !strings.HasPrefix(path, "/dev/") {
+		return fmt.Errorf("invalid device %q: must be an absolute path below /dev", path)
+	}
+	parent, err := filepath.EvalSymlinks(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("while resolving device %s: %s", path, err)
+	}
+	if parent != "/dev" && !strings.HasPrefix(parent, "/dev/") {
+		return fmt.Errorf("invalid device %s: located in %s", path, parent)
+	}
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("while checking device %s: %s", path, err)
+	}
+	if fi.Mode()&(os.ModeDir|os.ModeSymlink) != 0 {
+		return nil
+	}
+	_, err = checkDeviceSource(path, path)
+	return err
+}
+
 func (c *container) addSessionDev(devpath string, system *mount.System) error {
 	return c.addSessionDevAt(devpath, devpath, system)
 }
@@ -1303,9 +1508,22 @@ func (c *container) addSessionDevMount(system *mount.System) error {
 func (c *container) addDevMount(system *mount.System) error {
 	sylog.Debugf("Checking configuration file for 'mount dev'")
 
+	devMode := c.engine.EngineConfig.GetDev()
+	if devMode == singularity.DevFull && c.engine.EngineConfig.File.MountDev == "minimal" {
+		sylog.Warningf("Host /dev disallowed by configuration, using a minimal /dev")
+		devMode = singularity.DevMinimal
+	}
+	minimalDev := c.engine.EngineConfig.File.MountDev == "minimal" ||
+		devMode == singularity.DevMinimal ||
+		devMode == singularity.DevCustom ||
+		(c.engine.EngineConfig.GetContain() && devMode != singularity.DevFull)
+
 	if c.engine.EngineConfig.File.MountDev == "no" || c.engine.EngineConfig.GetNoDev() {
 		sylog.Verbosef("Not mounting /dev inside the container, disallowed by configuration")
-	} else if c.engine.EngineConfig.File.MountDev == "minimal" || c.engine.EngineConfig.GetContain() {
+		if len(c.engine.EngineConfig.GetDevices()) > 0 {
+			sylog.Warningf("Not adding --device devices: /dev is not mounted in the container")
+		}
+	} else if minimalDev {
 		sylog.Debugf("Creating temporary staged /dev")
 		if err := c.session.AddDir("/dev"); err != nil {
 			return fmt.Errorf("failed to add /dev session directory: %s", err)
@@ -1462,6 +1680,55 @@ func (c *container) addDevMount(system *mount.System) error {
 			return err
 		}
 
+		if devices := c.engine.EngineConfig.GetDevDevices(); len(devices) > 0 {
+			if !c.engine.EngineConfig.File.UserBindControl {
+				sylog.Warningf("Not adding --dev devices: user bind control is disabled by system administrator")
+			} else {
+				for _, dev := range devices {
+					// devices already staged, e.g. by --nv, are skipped
+					if _, err := c.session.GetPath(dev); err == nil {
+						continue
+					}
+					// the engine configuration is provided by the user, so
+					// don't trust the checks done on the command line
+					if err := checkDevPath(dev); err != nil {
+						return err
+					}
+					if err := c.addSessionDev(dev, system); err != nil {
+						return fmt.Errorf("while adding device %s: %s", dev, err)
+					}
+				}
+			}
+		}
+
+		if devices := c.engine.EngineConfig.GetDevices(); len(devices) > 0 {
+			if !c.engine.EngineConfig.File.UserBindControl {
+				sylog.Warningf("Not adding --device devices: user bind control is disabled by system administrator")
+			} else {
+				paths := make([]string, 0, len(devices))
+				for path := range devices {
+					paths = append(paths, path)
+				}
+				sort.Strings(paths)
+				for _, path := range paths {
+					if _, err := c.session.GetPath(path); err == nil {
+						continue
+					}
+					// the engine configuration is provided by the user, so
+					// don't trust the checks done on the command line
+					src, err := checkDeviceSource(path, devices[path])
+					if err != nil {
+						return err
+					}
+					// the device node is bound at the requested path, even
+					// if it's a symlink to the device on the host
+					if err := c.addSessionDevAt(src, path, system); err != nil {
+						return fmt.Errorf("while adding device %s: %s", path, err)
+					}
+				}
+			}
+		}
+
 		// devices could be added in addUserbindsMount so bind session dev
 		// after that all devices have been added to the mount point list
 		if err := system.RunAfterTag(mount.SharedTag, c.addSessionDevMount); err != nil {
@@ -1633,6 +1900,21 @@ func (c *container) addHomeStagingDir(system *mount.System, source string, dest
 
 	homeStage, _ = c.session.GetPath(dest)
 
+	if tmpfsOptions := c.engine.EngineConfig.GetHomeTmpfs(); tmpfsOptions != "" {
+		sylog.Debugf("Using tmpfs for home directory")
+
+		options, err := c.limitTmpfsSize(fmt.Sprintf("%s,uid=%d,gid=%d", tmpfsOptions, os.Getuid(), os.Getgid()))
+		if err != nil {
+			return "", fmt.Errorf("unable to add home tmpfs: %s", err)
+		}
+		err = system.Points.AddFS(mount.HomeTag, homeStage, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, options)
+		if err != nil {
+			return "", fmt.Errorf("unable to add home tmpfs to mount list: %s", err)
+		}
+		c.session.OverrideDir(dest, homeStage)
+		return homeStage, nil
+	}
+
 	bindSource := !c.engine.EngineConfig.GetContain() || c.engine.EngineConfig.GetCustomHome()
 
 	// use the session home directory is the user home directory doesn't exist (issue #4208)
@@ -1736,11 +2018,33 @@ func (c *container) addHomeMount(system *mount.System) error {
 	return c.addHomeLayer(system, stagingDir, dest)
 }
 
+// bindPropagationFlags maps the propagation options of user binds to
+// their mount flags.
+var bindPropagationFlags = map[string]uintptr{
+	"shared":   syscall.MS_SHARED,
+	"rshared":  syscall.MS_SHARED | syscall.MS_REC,
+	"slave":    syscall.MS_SLAVE,
+	"rslave":   syscall.MS_SLAVE | syscall.MS_REC,
+	"private":  syscall.MS_PRIVATE,
+	"rprivate": syscall.MS_PRIVATE | syscall.MS_REC,
+}
+
+// checkBindPropagation returns an error if the propagation p of the user
+// bind at dst is shared, which could propagate mounts back to the host and
+// undo 'mount slave', unless the container runs as root or in a user
+// namespace.
+func (c *container) checkBindPropagation(p, dst string) error {
+	if (p == "shared" || p == "rshared") && c.privilegedRPC() {
+		return fmt.Errorf("%s propagation of bind mount %s is not permitted in setuid mode, use --userns", p, dst)
+	}
+	return nil
+}
+
 func (c *container) addUserbindsMount(system *mount.System) error {
 	const devPrefix = "/dev"
 	defaultFlags := uintptr(syscall.MS_BIND | c.suidFlag | syscall.MS_NODEV | syscall.MS_REC)
 
-	for _, b := range c.engine.EngineConfig.GetBindPath() {
+	for i, b := range c.engine.EngineConfig.GetBindPath() {
 		// ignore image bind
 		if b.ID() != "" || b.ImageSrc() != "" {
 			continue
@@ -1796,6 +2100,21 @@ func (c *container) addUserbindsMount(system *mount.System) error {
 			continue
 		}
 
+		if b.Overlay() {
+			if err := c.addBindOverlayMount(system, i, src, dst, b.OverlayUpper()); err != nil {
+				return err
+			}
+			if p := b.Propagation(); p != "" {
+				if err := c.checkBindPropagation(p, dst); err != nil {
+					return err
+				}
+				if err := system.Points.AddPropagation(mount.UserbindsTag, dst, bindPropagationFlags[p]); err != nil {
+					return fmt.Errorf("unable to set %s propagation on %s: %s", p, dst, err)
+				}
+			}
+			continue
+		}
+
 		sylog.Debugf("Adding %s to mount list\n", src)
 
 		if err := system.Points.AddBind(mount.UserbindsTag, src, dst, flags); err == mount.ErrMountExists {
@@ -1808,12 +2127,79 @@ func (c *container) addUserbindsMount(system *mount.System) error {
 				c.session.OverrideDir(dst, src)
 			}
 			system.Points.AddRemount(mount.UserbindsTag, dst, flags)
+			if p := b.Propagation(); p != "" {
+				if err := c.checkBindPropagation(p, dst); err != nil {
+					return err
+				}
+				if err := system.Points.AddPropagation(mount.UserbindsTag, dst, bindPropagationFlags[p]); err != nil {
+					return fmt.Errorf("unable to set %s propagation on %s: %s", p, dst, err)
+				}
+			}
 		}
 	}
 
 	return nil
 }
 
+// addBindOverlayMount registers an overlay mounted on dst for the user bind
+// numbered n with the overlay option, with the host directory src as read-only
+// lower directory. Changes are stored in the upper directory of upperDir when
+// set, otherwise in the session directory and discarded when the container
+// exits.
+func (c *container) addBindOverlayMount(system *mount.System, n int, src, dst, upperDir string) error {
+	if c.engine.EngineConfig.File.EnableOverlay == "no" {
+		return fmt.Errorf("overlay bind of %s requires 'enable overlay = yes': set to 'no' by administrator", src)
+	}
+	if !c.hostOverlayAllowed() {
+		return fmt.Errorf("overlay bind of %s requires root or a user namespace (--userns or --fakeroot)", src)
+	}
+	if !fs.IsDir(src) {
+		return fmt.Errorf("overlay bind source %s must be a directory", src)
+	}
+
+	var upper, work string
+	if upperDir != "" {
+		dir, err := filepath.Abs(upperDir)
+		if err != nil {
+			return fmt.Errorf("while determining absolute path of %s: %s", upperDir, err)
+		}
+		// created by the user with the upper directory
+		upper = filepath.Join(dir, "upper")
+		work = filepath.Join(dir, "work")
+	} else {
+		sessionDir := fmt.Sprintf("/bind-overlay/%d", n)
+		if err := c.session.AddDir(sessionDir + "/upper"); err != nil {
+			return err
+		}
+		if err := c.session.AddDir(sessionDir + "/work"); err != nil {
+			return err
+		}
+		upper, _ = c.session.GetPath(sessionDir + "/upper")
+		work, _ = c.session.GetPath(sessionDir + "/work")
+
+		// the overlay directory takes the ownership of the upper directory
+		err := system.RunAfterTag(mount.SessionTag, func(*mount.System) error {
+			if err := c.rpcOps.Chown(upper, os.Getuid(), os.Getgid()); err != nil {
+				return fmt.Errorf("while changing %s ownership: %s", upper, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	sylog.Debugf("Adding overlay of %s with upper directory %s to mount list\n", src, upper)
+
+	if err := system.Points.AddOverlay(mount.UserbindsTag, dst, c.suidFlag|syscall.MS_NODEV, src, upper, work); err == mount.ErrMountExists {
+		sylog.Warningf("While mounting overlay '%s:%s': %s", src, dst, err)
+	} else if err != nil {
+		return fmt.Errorf("unable to add overlay of %s to mount list: %s", src, err)
+	}
+	c.session.OverrideDir(dst, src)
+	return nil
+}
+
 func (c *container) addTmpMount(system *mount.System) error {
 	const (
 		tmpPath    = "/tmp"
@@ -1913,6 +2299,7 @@ func (c *container) addScratchMount(system *mount.System) error {
 
 	workdir := c.engine.EngineConfig.GetWorkdir()
 	hasWorkdir := workdir != ""
+	scratchSize := c.engine.EngineConfig.GetScratchSize()
 
 	if hasWorkdir {
 		workdir = filepath.Clean(workdir)
@@ -1933,6 +2320,17 @@ func (c *container) addScratchMount(system *mount.System) error {
 			if err := fs.MkdirAll(fullSourceDir, 0o750); err != nil {
 				return fmt.Errorf("could not create scratch working directory %s: %s", fullSourceDir, err)
 			}
+		} else if scratchSize > 0 {
+			// a dedicated tmpfs bounds the scratch directory size independently
+			// of the session directory size
+			options, err := c.limitTmpfsSize(fmt.Sprintf("mode=1777,size=%dm", scratchSize))
+			if err != nil {
+				return fmt.Errorf("could not add scratch tmpfs for %s: %s", dir, err)
+			}
+			err = system.Points.AddFS(mount.ScratchTag, fullSourceDir, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, options)
+			if err != nil {
+				return fmt.Errorf("could not add scratch tmpfs for %s: %s", dir, err)
+			}
 		}
 		c.session.OverrideDir(dir, fullSourceDir)
 
@@ -1945,6 +2343,76 @@ func (c *container) addScratchMount(system *mount.System) error {
 	return nil
 }
 
+// addTmpfsMounts adds the tmpfs mount points requested with --tmpfs.
+func (c *container) addTmpfsMounts(system *mount.System) error {
+	tmpfsMounts := c.engine.EngineConfig.GetTmpfsMounts()
+	if len(tmpfsMounts) == 0 {
+		return nil
+	}
+	if !c.engine.EngineConfig.File.UserBindControl {
+		sylog.Warningf("Ignoring --tmpfs: user bind control disabled by system administrator")
+		return nil
+	}
+
+	for _, tm := range tmpfsMounts {
+		flags := uintptr(syscall.MS_NOSUID | syscall.MS_NODEV)
+		if tm.ReadOnly {
+			flags |= syscall.MS_RDONLY
+		}
+		if !tm.Exec {
+			flags |= syscall.MS_NOEXEC
+		}
+
+		data, err := c.limitTmpfsSize(tm.Data)
+		if err != nil {
+			return fmt.Errorf("could not add tmpfs mount %s: %s", tm.Destination, err)
+		}
+
+		sylog.Debugf("Adding tmpfs mount at %s with options %s", tm.Destination, data)
+		if err := system.Points.AddFS(mount.UserbindsTag, tm.Destination, "tmpfs", flags, data); err != nil {
+			return fmt.Errorf("could not add tmpfs mount %s: %s", tm.Destination, err)
+		}
+	}
+	return nil
+}
+
+// limitTmpfsSize returns the tmpfs mount options with a size bounded by
+// 'sessiondir max size' in setuid mode, as the kernel default size is half
+// of the host memory. The maximum size is used when no size is given.
+func (c *container) limitTmpfsSize(options string) (string, error) {
+	if !c.privilegedRPC() {
+		return options, nil
+	}
+
+	maxSizeMiB := c.engine.EngineConfig.File.SessiondirMaxSize
+	maxSize := int64(maxSizeMiB) * 1024 * 1024
+	hasSize := false
+
+	data := []string{}
+	if options != "" {
+		data = strings.Split(options, ",")
+	}
+	for _, opt := range data {
+		if strings.HasPrefix(opt, "nr_blocks=") {
+			return "", fmt.Errorf("tmpfs option nr_blocks is not allowed in setuid mode, use size")
+		} else if !strings.HasPrefix(opt, "size=") {
+			continue
+		}
+		size, err := units.RAMInBytes(strings.TrimPrefix(opt, "size="))
+		if err != nil || size <= 0 {
+			return "", fmt.Errorf("invalid tmpfs %s", opt)
+		} else if size > maxSize {
+			return "", fmt.Errorf("tmpfs %s exceeds the %d MiB allowed in setuid mode by 'sessiondir max size'", opt, maxSizeMiB)
+		}
+		hasSize = true
+	}
+	if !hasSize {
+		data = append(data, fmt.Sprintf("size=%dm", maxSizeMiB))
+	}
+
+	return strings.Join(data, ","), nil
+}
+
 func (c *container) isMounted(dest string) bool {
 	sylog.Debugf("Checking if %s is already mounted", dest)
 
@@ -2035,6 +2503,159 @@ func (c *container) addCwdMount(system *mount.System) error {
 	return system.Points.AddRemount(mount.CwdTag, cwd, flags)
 }
 
+// addWritableCwdMount registers a temporary overlay mounted on the container
+// current working directory when --writable-cwd is requested, once the
+// current working directory and user binds are mounted. Changes are stored
+// in the session directory and discarded when the container exits.
+func (c *container) addWritableCwdMount(system *mount.System) error {
+	if !c.engine.EngineConfig.GetWritableCwd() {
+		return nil
+	}
+	if c.engine.EngineConfig.File.EnableOverlay == "no" {
+		return fmt.Errorf("--writable-cwd requires 'enable overlay = yes': set to 'no' by administrator")
+	}
+
+	if err := c.session.AddDir("/writable-cwd/upper"); err != nil {
+		return err
+	}
+	if err := c.session.AddDir("/writable-cwd/work"); err != nil {
+		return err
+	}
+	upper, _ := c.session.GetPath("/writable-cwd/upper")
+	work, _ := c.session.GetPath("/writable-cwd/work")
+
+	return system.RunAfterTag(mount.CwdTag, func(system *mount.System) error {
+		if ov, ok := c.session.Layer.(*overlay.Overlay); ok && ov.GetUpperDir() != "" {
+			sylog.Verbosef("Not mounting writable CWD: container has a writable overlay")
+			return nil
+		}
+
+		cwd := c.engine.EngineConfig.OciConfig.Process.Cwd
+		dest := fs.EvalRelative(cwd, c.session.FinalPath())
+		dest = filepath.Join(c.session.FinalPath(), dest)
+		if dest == c.session.FinalPath() {
+			sylog.Warningf("Not mounting writable CWD: current working directory is the container root directory")
+			return nil
+		}
+
+		fi, err := c.rpcOps.Stat(dest)
+		if err != nil {
+			sylog.Verbosef("Not mounting writable CWD, while getting %s information: %s", cwd, err)
+			return nil
+		}
+		root, err := c.rpcOps.Stat(c.session.FinalPath())
+		if err != nil {
+			return fmt.Errorf("while getting container root directory information: %s", err)
+		}
+		// a directory bound from the host, a tmpfs or an image bind is not
+		// part of the container image and keeps its own permissions
+		if fi.Sys().(*syscall.Stat_t).Dev != root.Sys().(*syscall.Stat_t).Dev {
+			sylog.Verbosef("Not mounting writable CWD: %s is not located in the container image", cwd)
+			return nil
+		}
+
+		// the overlay would hide the file systems mounted below
+		entries, err := proc.GetMountInfoEntry(c.mountInfoPath)
+		if err != nil {
+			return fmt.Errorf("while reading %s: %s", c.mountInfoPath, err)
+		}
+		for _, e := range entries {
+			if strings.HasPrefix(e.Point, dest+"/") {
+				sylog.Warningf("Not mounting writable CWD: %s is mounted below %s", strings.TrimPrefix(e.Point, c.session.FinalPath()), cwd)
+				return nil
+			}
+		}
+
+		// the overlay directory takes the ownership of the upper directory
+		if err := c.rpcOps.Chown(upper, os.Getuid(), os.Getgid()); err != nil {
+			return fmt.Errorf("while changing %s ownership: %s", upper, err)
+		}
+
+		sylog.Debugf("Mounting writable overlay on current working directory %s", cwd)
+		return system.Points.AddOverlay(mount.OtherTag, cwd, syscall.MS_NOSUID|syscall.MS_NODEV, dest, upper, work)
+	})
+}
+
+// createPwd creates the working directory requested with --pwd when it
+// doesn't exist within the container, once everything is mounted. It can
+// only be created in the container file system, when it's writable with
+// --writable, --writable-tmpfs or a writable overlay. In setuid mode, it's
+// only created with a writable tmpfs stored in the session directory.
+func (c *container) createPwd() error {
+	pwd := c.engine.EngineConfig.GetPwd()
+	if pwd == "" {
+		return nil
+	}
+
+	dest := fs.EvalRelative(pwd, c.session.FinalPath())
+	dest = filepath.Join(c.session.FinalPath(), dest)
+	if _, err := c.rpcOps.Stat(dest); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("while getting working directory %s information: %s", pwd, err)
+	}
+
+	// the missing directories are created below the deepest existing one
+	missing := []string{dest}
+	parent := filepath.Dir(dest)
+	for {
+		if _, err := c.rpcOps.Stat(parent); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("while getting %s information: %s", parent, err)
+		}
+		missing = append(missing, parent)
+		parent = filepath.Dir(parent)
+	}
+
+	// the deepest mount point holding parent tells if it's writable
+	entries, err := proc.GetMountInfoEntry(c.mountInfoPath)
+	if err != nil {
+		return fmt.Errorf("while reading %s: %s", c.mountInfoPath, err)
+	}
+	var mnt proc.MountInfoEntry
+	for _, e := range entries {
+		if (parent == e.Point || strings.HasPrefix(parent, e.Point+"/")) && len(e.Point) >= len(mnt.Point) {
+			mnt = e
+		}
+	}
+	for _, opt := range mnt.Options {
+		if opt == "ro" {
+			return fmt.Errorf("working directory %s doesn't exist within the read-only container, use --writable-tmpfs or a writable overlay to create it", pwd)
+		}
+	}
+
+	fi, err := c.rpcOps.Stat(parent)
+	if err != nil {
+		return fmt.Errorf("while getting %s information: %s", parent, err)
+	}
+	root, err := c.rpcOps.Stat(c.session.FinalPath())
+	if err != nil {
+		return fmt.Errorf("while getting container root directory information: %s", err)
+	}
+	// don't create directories in host directories bound in the container
+	if fi.Sys().(*syscall.Stat_t).Dev != root.Sys().(*syscall.Stat_t).Dev {
+		return fmt.Errorf("working directory %s doesn't exist within the container and is not located in the container image, create it before running the container", pwd)
+	}
+	// in setuid mode, a directory of a writable sandbox or overlay could be
+	// replaced by a symlink to a host directory since the checks above
+	if c.privilegedRPC() && !c.sessionBackedRoot() {
+		return fmt.Errorf("working directory %s doesn't exist within the container, use --writable-tmpfs to create it in setuid mode", pwd)
+	}
+
+	for i := len(missing) - 1; i >= 0; i-- {
+		sylog.Debugf("Creating working directory %s", strings.TrimPrefix(missing[i], c.session.FinalPath()))
+		if err := c.rpcOps.Mkdir(missing[i], 0o755); err != nil {
+			return fmt.Errorf("while creating working directory %s: %s", pwd, err)
+		}
+		if err := c.rpcOps.Chown(missing[i], os.Getuid(), os.Getgid()); err != nil {
+			return fmt.Errorf("while changing %s ownership: %s", missing[i], err)
+		}
+	}
+	sylog.Verbosef("Created working directory %s within the container", pwd)
+	return nil
+}
+
 func (c *container) addLibsMount(system *mount.System) error {
 	libraries := c.engine.EngineConfig.GetLibrariesPath()
 
@@ -2105,6 +2726,18 @@ func (c *container) addFilesMount(system *mount.System) error {
 		return nil
 	}
 
+	// without overlay or underlay, the missing destinations of the files
+	// can only be created in a writable sandbox. In setuid mode, they are
+	// not created, as a sandbox directory could be replaced by a symlink
+	// to a host directory before the privileged creation.
+	if len(files) > 0 && c.engine.EngineConfig.GetWritableImage() && !c.isLayerEnabled() && fs.IsDir(c.engine.EngineConfig.GetImage()) {
+		if c.privilegedRPC() {
+			sylog.Warningf("Missing destinations of bound files are not created in sandbox in setuid mode, use --userns")
+		} else if err := system.RunBeforeTag(mount.FilesTag, c.addFileStubs); err != nil {
+			return err
+		}
+	}
+
 	flags := uintptr(syscall.MS_BIND | syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_RDONLY | syscall.MS_REC)
 
 	for _, file := range files {
@@ -2194,6 +2827,31 @@ func (c *container) addIdentityMount(system *mount.System) error {
 	return nil
 }
 
+// addFileStubs creates the missing destinations of the files bound into a
+// writable sandbox as empty files, so that GPU binaries are bound as with
+// other images. They are recorded to be removed when the container exits.
+func (c *container) addFileStubs(system *mount.System) error {
+	for _, file := range c.engine.EngineConfig.GetFilesPath() {
+		dst := file
+		if splitted := strings.Split(file, ":"); len(splitted) > 1 {
+			dst = splitted[1]
+		}
+
+		rel := fs.EvalRelative(dst, c.session.FinalPath())
+		path := filepath.Join(c.session.FinalPath(), rel)
+		if _, err := c.rpcOps.Lstat(path); !os.IsNotExist(err) {
+			continue
+		}
+		if err := c.rpcOps.WriteFile(path, nil, 0o755); err != nil {
+			sylog.Debugf("Could not create %s in sandbox: %s", dst, err)
+			continue
+		}
+		sylog.Debugf("Created %s in sandbox", dst)
+		fileStubs = append(fileStubs, filepath.Join(c.engine.EngineConfig.GetImage(), rel))
+	}
+	return nil
+}
+
 func (c *container) addResolvConfMount(system *mount.System) error {
 	resolvConf := "/etc/resolv.conf"
 
@@ -2201,9 +2859,20 @@ func (c *container) addResolvConfMount(system *mount.System) error {
 		var err error
 		var content []byte
 
-		dns := c.engine.EngineConfig.GetDNS()
+		var dns []string
+		if d := strings.Replace(c.engine.EngineConfig.GetDNS(), " ", "", -1); d != "" {
+			dns = strings.Split(d, ",")
+		}
+		search := c.engine.EngineConfig.GetDNSSearch()
 
-		if dns == "" {
+		if c.netNS && len(dns) > 0 {
+			// the host nameservers are not relevant within a network
+			// namespace, generate a resolv.conf from scratch
+			content, err = files.ResolvConf(dns, search)
+			if err != nil {
+				return err
+			}
+		} else {
 			r, err := os.Open(resolvConf)
 			if err != nil {
 				return err
@@ -2213,11 +2882,11 @@ func (c *container) addResolvConfMount(system *mount.System) error {
 			if err != nil {
 				return err
 			}
-		} else {
-			dns = strings.Replace(dns, " ", "", -1)
-			content, err = files.ResolvConf(strings.Split(dns, ","))
-			if err != nil {
-				return err
+			if len(dns) > 0 || len(search) > 0 {
+				content, err = files.OverlayResolvConf(content, dns, search)
+				if err != nil {
+					return err
+				}
 			}
 		}
 		if err := c.session.AddFile(resolvConf, content); err != nil {
@@ -2237,6 +2906,51 @@ func (c *container) addResolvConfMount(system *mount.System) error {
 	return nil
 }
 
+// addHostsMount binds a staging /etc/hosts file with the host entries
+// requested with --add-host appended to it. It takes precedence over
+// the /etc/hosts bind path as files are mounted after binds.
+func (c *container) addHostsMount(system *mount.System) error {
+	const (
+		hostsPath = "/etc/hosts"
+		// distinct from the /etc/hosts staging file which may be
+		// already added by addBindsMount
+		stagingPath = "/etc/hosts.add-host"
+	)
+
+	entries := c.engine.EngineConfig.GetAddHosts()
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var base []byte
+	if c.engine.EngineConfig.GetContain() && c.netNS {
+		// same minimal hosts file as the one bound by addBindsMount
+		base = files.DefaultHosts()
+	} else {
+		var err error
+		base, err = ioutil.ReadFile(hostsPath)
+		if err != nil {
+			return fmt.Errorf("while reading %s: %s", hostsPath, err)
+		}
+	}
+
+	content, err := files.AppendHosts(base, entries)
+	if err != nil {
+		return err
+	}
+	if err := c.session.AddFile(stagingPath, content); err != nil {
+		return fmt.Errorf("while adding %s staging file: %s", hostsPath, err)
+	}
+	sessionFile, _ := c.session.GetPath(stagingPath)
+
+	sylog.Debugf("Adding %s to mount list\n", hostsPath)
+	if err := system.Points.AddBind(mount.FilesTag, sessionFile, hostsPath, syscall.MS_BIND); err != nil {
+		return fmt.Errorf("unable to add %s to mount list: %s", hostsPath, err)
+	}
+	sylog.Verbosef("Adding %d host entries to %s", len(entries), hostsPath)
+	return nil
+}
+
 func (c *container) addHostnameMount(system *mount.System) error {
 	hostnameFile := "/etc/hostname"
 
@@ -511,6 +511,78 @@ func (e *EngineOperations) prepareAutofs(starterConfig *starter.Config) error {
 	return nil
 }
 
+// prepareCustomIDMappings checks the user namespace ID mappings requested
+// with --uidmap/--gidmap against the user subuid/subgid allocation and sets
+// the hybrid workflow applying them, as done for fakeroot.
+func (e *EngineOperations) prepareCustomIDMappings(starterConfig *starter.Config) error {
+	uid := uint32(os.Getuid())
+	gid := uint32(os.Getgid())
+
+	if e.EngineConfig.OciConfig.Linux == nil {
+		return fmt.Errorf("no user namespace ID mappings found")
+	}
+	uidMappings := e.EngineConfig.OciConfig.Linux.UIDMappings
+	gidMappings := e.EngineConfig.OciConfig.Linux.GIDMappings
+
+	if uid != 0 {
+		if err := fakerootutil.CheckIDMappings(fakerootutil.SubUIDFile, uid, uid, uidMappings); err != nil {
+			return fmt.Errorf("invalid UID mappings: %s", err)
+		}
+		if err := fakerootutil.CheckIDMappings(fakerootutil.SubGIDFile, uid, gid, gidMappings); err != nil {
+			return fmt.Errorf("invalid GID mappings: %s", err)
+		}
+	}
+
+	targetUID, err := fakerootutil.GetMappedID(uidMappings, uid)
+	if err != nil {
+		return fmt.Errorf("your user ID must be mapped with --uidmap: %s", err)
+	}
+	targetGID, err := fakerootutil.GetMappedID(gidMappings, gid)
+	if err != nil {
+		return fmt.Errorf("your group ID must be mapped with --gidmap: %s", err)
+	}
+
+	if !starterConfig.GetIsSUID() {
+		sylog.Verbosef("Custom ID mappings requested with unprivileged workflow, using newuidmap/newgidmap")
+		sylog.Debugf("Search for newuidmap binary")
+		if err := starterConfig.SetNewUIDMapPath(); err != nil {
+			return err
+		}
+		sylog.Debugf("Search for newgidmap binary")
+		if err := starterConfig.SetNewGIDMapPath(); err != nil {
+			return err
+		}
+	}
+
+	allow, err := e.allowSetgroups(starterConfig)
+	if err != nil {
+		return err
+	}
+	starterConfig.SetHybridWorkflow(true)
+	starterConfig.SetAllowSetgroups(allow)
+
+	starterConfig.SetTargetUID(int(targetUID))
+	starterConfig.SetTargetGID([]int{int(targetGID)})
+
+	return nil
+}
+
+// allowSetgroups returns if the setgroups syscall is allowed in the user
+// namespace of the hybrid workflow, used by --fakeroot and custom ID
+// mappings. When it's denied, the container process keeps the supplementary
+// groups of the user instead of having them replaced by the container
+// group. The setgroups policy can only be set by starter with the setuid
+// workflow, it's set by newgidmap otherwise.
+func (e *EngineOperations) allowSetgroups(starterConfig *starter.Config) (bool, error) {
+	if e.EngineConfig.GetSetgroups() != singularityConfig.SetgroupsDeny {
+		return true, nil
+	}
+	if !starterConfig.GetIsSUID() {
+		return false, fmt.Errorf("--setgroups deny requires a setuid installation with --fakeroot, --uidmap or --gidmap")
+	}
+	return false, nil
+}
+
 // prepareContainerConfig is responsible for getting and applying
 // user supplied configuration for container creation.
 func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config) error {
@@ -547,19 +619,6 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 	}
 
 	if e.EngineConfig.GetFakeroot() {
-		if !starterConfig.GetIsSUID() {
-			// no SUID workflow, check if newuidmap/newgidmap are present
-			sylog.Verbosef("Fakeroot requested with unprivileged workflow, fallback to newuidmap/newgidmap")
-			sylog.Debugf("Search for newuidmap binary")
-			if err := starterConfig.SetNewUIDMapPath(); err != nil {
-				return err
-			}
-			sylog.Debugf("Search for newgidmap binary")
-			if err := starterConfig.SetNewGIDMapPath(); err != nil {
-				return err
-			}
-		}
-
 		uid := uint32(os.Getuid())
 		gid := uint32(os.Getgid())
 
@@ -576,6 +635,25 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 			getIDRange = callbacks[0].(fakerootcallback.UserMapping)
 		}
 
+		// report all missing prerequisites at once, mappings provided by
+		// a plugin are not checked against the subuid/subgid files
+		if err := fakerootutil.Preflight(uid, !starterConfig.GetIsSUID(), len(callbacks) == 0); err != nil {
+			return err
+		}
+
+		if !starterConfig.GetIsSUID() {
+			// no SUID workflow, check if newuidmap/newgidmap are present
+			sylog.Verbosef("Fakeroot requested with unprivileged workflow, fallback to newuidmap/newgidmap")
+			sylog.Debugf("Search for newuidmap binary")
+			if err := starterConfig.SetNewUIDMapPath(); err != nil {
+				return err
+			}
+			sylog.Debugf("Search for newgidmap binary")
+			if err := starterConfig.SetNewGIDMapPath(); err != nil {
+				return err
+			}
+		}
+
 		e.EngineConfig.OciConfig.AddLinuxUIDMapping(uid, 0, 1)
 		idRange, err := getIDRange(fakerootutil.SubUIDFile, uid)
 		if err != nil {
@@ -596,11 +674,19 @@ func (e *EngineOperations) prepareContainerConfig(starterConfig *starter.Config)
 
 		e.EngineConfig.OciConfig.AddOrReplaceLinuxNamespace(specs.UserNamespace, "")
 
+		allow, err := e.allowSetgroups(starterConfig)
+		if err != nil {
+			return err
+		}
 		starterConfig.SetHybridWorkflow(true)
-		starterConfig.SetAllowSetgroups(true)
+		starterConfig.SetAllowSetgroups(allow)
 
 		starterConfig.SetTargetUID(0)
 		starterConfig.SetTargetGID([]int{0})
+	} else if e.EngineConfig.GetCustomIDMappings() {
+		if err := e.prepareCustomIDMappings(starterConfig); err != nil {
+			return err
+		}
 	}
 
 	starterConfig.SetBringLoopbackInterface(true)
@@ -867,6 +953,21 @@ func (e *EngineOperations) prepareInstanceJoinConfig(starterConfig *starter.Conf
 	// one set during instance start
 	e.EngineConfig.OciConfig.AddProcessEnv("HOME", instanceEngineConfig.GetHomeDest())
 
+	// restore the environment variables set with --env, --env-file,
+	// --env-json, --env-pass-through or SINGULARITYENV_ during instance
+	// start, variables set the same way by the joining command take
+	// precedence
+	e.EngineConfig.SetSingularityEnv(mergeEnv(instanceEngineConfig.GetSingularityEnv(), e.EngineConfig.GetSingularityEnv()))
+	literalEnv := mergeEnv(instanceEngineConfig.GetLiteralEnv(), e.EngineConfig.GetLiteralEnv())
+	for k := range e.EngineConfig.GetSingularityEnv() {
+		if _, ok := e.EngineConfig.GetLiteralEnv()[k]; !ok {
+			delete(literalEnv, k)
+		}
+	}
+	e.EngineConfig.SetLiteralEnv(literalEnv)
+	e.EngineConfig.SetAppendEnv(mergeEnv(instanceEngineConfig.GetAppendEnv(), e.EngineConfig.GetAppendEnv()))
+	e.EngineConfig.SetPrependEnv(mergeEnv(instanceEngineConfig.GetPrependEnv(), e.EngineConfig.GetPrependEnv()))
+
 	// restore apparmor profile or apply a new one if provided
 	param := security.GetParam(e.EngineConfig.GetSecurity(), "apparmor")
 	if param != "" {
@@ -924,6 +1025,19 @@ func (e *EngineOperations) prepareInstanceJoinConfig(starterConfig *starter.Conf
 	return nil
 }
 
+// mergeEnv returns the variables of env overridden by the variables of
+// override.
+func mergeEnv(env, override map[string]string) map[string]string {
+	merged := make(map[string]string, len(env)+len(override))
+	for k, v := range env {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
 // openDevFuse is a helper function that opens /dev/fuse once for each
 // plugin that wants to mount a FUSE filesystem.
 func openDevFuse(e *EngineOperations, starterConfig *starter.Config) (bool, error) {
@@ -1247,17 +1361,26 @@ func (e *EngineOperations) loadImages(starterConfig *starter.Config) error {
 	return nil
 }
 
-// loadOverlayImages loads overlay images.
+// loadOverlayImages loads overlay images. Overlay images are stacked in
+// the order they are given, each read-only overlay is placed above the
+// previous ones and the container image, while the writable overlay, if
+// any, is always the overlay upper directory on top of the stack.
 func (e *EngineOperations) loadOverlayImages(starterConfig *starter.Config, writableOverlayPath string) ([]image.Image, error) {
 	images := make([]image.Image, 0)
+	// path of writable overlay followed by read-only overlays
+	writableBelow := ""
 
 	for _, overlayImg := range e.EngineConfig.GetOverlayImage() {
 		writableOverlay := true
 
 		splitted := strings.SplitN(overlayImg, ":", 2)
 		if len(splitted) == 2 {
-			if splitted[1] == "ro" {
+			switch splitted[1] {
+			case "ro":
 				writableOverlay = false
+			case "rw":
+			default:
+				return nil, fmt.Errorf("invalid overlay option %q for %s: must be 'ro' or 'rw'", splitted[1], splitted[0])
 			}
 		}
 
@@ -1281,6 +1404,10 @@ func (e *EngineOperations) loadOverlayImages(starterConfig *starter.Config, writ
 				)
 			}
 			writableOverlayPath = img.Path
+			writableBelow = img.Path
+		} else if writableBelow != "" {
+			sylog.Warningf("Writable overlay %s is always on top of read-only overlay %s, give it last to match the stacking order", writableBelow, img.Path)
+			writableBelow = ""
 		}
 
 		if err := starterConfig.KeepFileDescriptor(int(img.Fd)); err != nil {
@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -35,6 +36,7 @@ import (
 	"github.com/sylabs/singularity/internal/pkg/security"
 	"github.com/sylabs/singularity/internal/pkg/util/env"
 	"github.com/sylabs/singularity/internal/pkg/util/fs/files"
+	"github.com/sylabs/singularity/internal/pkg/util/gpu"
 	"github.com/sylabs/singularity/internal/pkg/util/machine"
 	"github.com/sylabs/singularity/internal/pkg/util/shell"
 	"github.com/sylabs/singularity/internal/pkg/util/shell/interpreter"
@@ -74,11 +76,18 @@ func (e *EngineOperations) StartProcess(masterConn net.Conn) error {
 	shimProcess := false
 
 	if err := os.Chdir(e.EngineConfig.OciConfig.Process.Cwd); err != nil {
+		if pwd := e.EngineConfig.GetPwd(); pwd != "" {
+			return fmt.Errorf("can't change to working directory %s: %s", pwd, err)
+		}
 		if err := os.Chdir(e.EngineConfig.GetHomeDest()); err != nil {
 			os.Chdir("/")
 		}
 	}
 
+	if e.EngineConfig.GetNvLegacy() || e.EngineConfig.GetNvCCLI() {
+		e.checkCudaDriver()
+	}
+
 	if e.EngineConfig.File.MountDev == "minimal" || e.EngineConfig.GetContain() {
 		// If on a terminal, reopen /dev/console so /proc/self/fd/[0-2
 		//   will point to /dev/console.  This is needed so that tty and
@@ -115,7 +124,10 @@ func (e *EngineOperations) StartProcess(masterConn net.Conn) error {
 		namespaces := e.EngineConfig.OciConfig.Linux.Namespaces
 		for _, ns := range namespaces {
 			if ns.Type == specs.PIDNamespace {
-				if !e.EngineConfig.GetNoInit() {
+				// a user provided init binary replaces the shim
+				// process, except for instances which still rely
+				// on the shim to stay alive
+				if !e.EngineConfig.GetNoInit() && (e.EngineConfig.GetInitBin() == "" || isInstance) {
 					shimProcess = true
 				}
 				break
@@ -142,13 +154,11 @@ func (e *EngineOperations) StartProcess(masterConn net.Conn) error {
 		}
 	}
 
-	// restore the stack size limit for setuid workflow
+	// restore the stack size limit for setuid workflow and
+	// apply resource limits requested with --ulimit
 	for _, limit := range e.EngineConfig.OciConfig.Process.Rlimits {
-		if limit.Type == "RLIMIT_STACK" {
-			if err := rlimit.Set(limit.Type, limit.Soft, limit.Hard); err != nil {
-				return fmt.Errorf("while restoring stack size limit: %s", err)
-			}
-			break
+		if err := rlimit.Set(limit.Type, limit.Soft, limit.Hard); err != nil {
+			return fmt.Errorf("while setting %s limit: %s", limit.Type, err)
 		}
 	}
 
@@ -177,6 +187,7 @@ func (e *EngineOperations) StartProcess(masterConn net.Conn) error {
 				// nothing to execute and no error was reported
 				return nil
 			}
+			args = e.initArgs(args)
 		}
 
 		return e.execProcess(args, env)
@@ -190,6 +201,7 @@ func (e *EngineOperations) StartProcess(masterConn net.Conn) error {
 	if err != nil {
 		return err
 	} else if len(args) > 0 {
+		args = e.initArgs(args)
 	cmdexec:
 		// Spawn and wait container process, signal handler
 		cmd := exec.Command(args[0], args[1:]...)
@@ -401,7 +413,7 @@ func (e *EngineOperations) PostStartProcess(ctx context.Context, pid int) error
 
 		// If we are using cgroups with this instance then mark that in the instance config.
 		// We don't store the path, as we will get the cgroup manager by Pid.
-		if e.EngineConfig.GetCgroupsTOML() != "" {
+		if e.EngineConfig.GetCgroupsTOML() != "" || e.EngineConfig.GetCgroupsJSON() != "" {
 			file.Cgroup = true
 		}
 
@@ -599,6 +611,38 @@ func getExecError(err error, args []string, shell string) error {
 	return fmt.Errorf("exec %s failed: %s", args[0], err)
 }
 
+// checkCudaDriver warns when the CUDA toolkit installed in the
+// container likely requires a more recent NVIDIA driver than the
+// one loaded on the host. This is a best effort check, the process
+// is started regardless of the result.
+func (e *EngineOperations) checkCudaDriver() {
+	cuda := gpu.CudaVersion("/")
+	if cuda == "" {
+		sylog.Debugf("No CUDA installation found in container, skipping driver check")
+		return
+	}
+	driver, err := gpu.NvidiaDriverVersion()
+	if err != nil {
+		sylog.Debugf("Skipping CUDA driver check: %s", err)
+		return
+	}
+	sylog.Debugf("Container CUDA version %s, host NVIDIA driver version %s", cuda, driver)
+	if err := gpu.CheckCudaDriver(cuda, driver); err != nil {
+		sylog.Warningf("%s, CUDA applications may fail to run in this container", err)
+	}
+}
+
+// initArgs prepends the init binary set with --init-bin, if any,
+// to the container process arguments.
+func (e *EngineOperations) initArgs(args []string) []string {
+	initBin := e.EngineConfig.GetInitBin()
+	if initBin == "" {
+		return args
+	}
+	sylog.Debugf("Running %s as init process", initBin)
+	return append([]string{initBin, "--"}, args...)
+}
+
 func (e *EngineOperations) execProcess(args, env []string) error {
 	err := syscall.Exec(args[0], args, env)
 	if err == nil {
@@ -625,8 +669,10 @@ func (b *bufferCloser) Close() error {
 // Register a virtual file /.singularity.d/env/inject-singularity-env.sh sourced
 // after /.singularity.d/env/99-base.sh or /environment.
 // This handler turns all SINGUALRITYENV_KEY=VAL defined variables into their form:
-// export KEY=VAL. It can be sourced only once otherwise it returns an empty content.
-func injectEnvHandler(senv map[string]string) interpreter.OpenHandler {
+// export KEY=VAL. Variables of literalEnv are escaped to be set as is, and
+// override the SINGULARITYENV_ variables. It can be sourced only once otherwise
+// it returns an empty content.
+func injectEnvHandler(senv, literalEnv, appendEnv, prependEnv map[string]string) interpreter.OpenHandler {
 	var once sync.Once
 
 	return func(_ string, _ int, _ os.FileMode) (io.ReadWriteCloser, error) {
@@ -657,12 +703,50 @@ func injectEnvHandler(senv map[string]string) interpreter.OpenHandler {
 				}
 				b.WriteString(fmt.Sprintf(snippet, key, shell.EscapeDoubleQuotes(value)))
 			}
+			for _, key := range sortedKeys(literalEnv) {
+				value := literalEnv[key]
+				if key == "LD_LIBRARY_PATH" && value != "" {
+					value += ":/.singularity.d/libs"
+				}
+				b.WriteString(fmt.Sprintf(snippet, key, shell.Escape(value)))
+			}
+
+			// values appended or prepended to the container value
+			// are separated by a colon for path-like variables and
+			// by a space otherwise
+			modifySnippet := `
+			if test -n "${%[1]s:-}"; then
+				export %[1]s=%[2]s
+			else
+				export %[1]s="%[3]s"
+			fi
+			`
+			for _, key := range sortedKeys(appendEnv) {
+				value := shell.EscapeDoubleQuotes(appendEnv[key])
+				modified := fmt.Sprintf(`"${%s}%s%s"`, key, env.ListSeparator(key), value)
+				b.WriteString(fmt.Sprintf(modifySnippet, key, modified, value))
+			}
+			for _, key := range sortedKeys(prependEnv) {
+				value := shell.EscapeDoubleQuotes(prependEnv[key])
+				modified := fmt.Sprintf(`"%s%s${%s}"`, value, env.ListSeparator(key), key)
+				b.WriteString(fmt.Sprintf(modifySnippet, key, modified, value))
+			}
 		})
 
 		return b, nil
 	}
 }
 
+// sortedKeys returns the keys of m in alphanumeric order.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func runtimeVarsHandler(senv map[string]string) interpreter.OpenHandler {
 	var once sync.Once
 
@@ -726,28 +810,45 @@ func getAllEnvBuiltin(shell *interpreter.Shell) interpreter.ShellBuiltin {
 
 // fixPathBuiltin takes the current path value to fix it by injecting
 // missing default path and returns value on shell interpreter output.
-func fixPathBuiltin(ctx context.Context, argv []string) error {
-	hc := interp.HandlerCtx(ctx)
+func fixPathBuiltin(mode string) interpreter.ShellBuiltin {
+	return func(ctx context.Context, argv []string) error {
+		hc := interp.HandlerCtx(ctx)
+
+		currentPath := filepath.SplitList(hc.Env.Get("PATH").String())
+		fmt.Fprintf(hc.Stdout, "%s\n", strings.Join(mergePath(currentPath, mode), string(os.PathListSeparator)))
+		return nil
+	}
+}
+
+// mergePath combines the Singularity default PATH entries with the
+// container PATH entries according to the rewrite path mode.
+func mergePath(currentPath []string, mode string) []string {
+	defaultPath := filepath.SplitList(env.DefaultPath)
+	if len(currentPath) == 0 {
+		return defaultPath
+	} else if mode == singularityConfig.RewritePathImageOnly {
+		return currentPath
+	}
 
-	currentPath := filepath.SplitList(hc.Env.Get("PATH").String())
-	finalPath := currentPath
+	var missing []string
 
-	for _, d := range filepath.SplitList(env.DefaultPath) {
+	for _, d := range defaultPath {
 		found := false
 		for _, p := range currentPath {
 			if d == p {
 				found = true
-				continue
+				break
 			}
 		}
 		if !found {
-			finalPath = append(finalPath, d)
+			missing = append(missing, d)
 		}
 	}
 
-	listSep := string(os.PathListSeparator)
-	fmt.Fprintf(hc.Stdout, "%s\n", strings.Join(finalPath, listSep))
-	return nil
+	if mode == singularityConfig.RewritePathPrepend {
+		return append(missing, currentPath...)
+	}
+	return append(currentPath, missing...)
 }
 
 // hashBuiltin is a noop function for hash bash builtin, since we don't
@@ -800,14 +901,14 @@ func runActionScript(engineConfig *singularityConfig.EngineConfig) ([]string, []
 
 	// inject SINGULARITYENV_ defined variables
 	senv := engineConfig.GetSingularityEnv()
-	shell.RegisterOpenHandler("/.inject-singularity-env.sh", injectEnvHandler(senv))
+	shell.RegisterOpenHandler("/.inject-singularity-env.sh", injectEnvHandler(senv, engineConfig.GetLiteralEnv(), engineConfig.GetAppendEnv(), engineConfig.GetPrependEnv()))
 
 	shell.RegisterOpenHandler("/.singularity.d/env/99-runtimevars.sh", runtimeVarsHandler(senv))
 
 	// register few builtin
 	shell.RegisterShellBuiltin("getallenv", getAllEnvBuiltin(shell))
 	shell.RegisterShellBuiltin("sylog", sylogBuiltin)
-	shell.RegisterShellBuiltin("fixpath", fixPathBuiltin)
+	shell.RegisterShellBuiltin("fixpath", fixPathBuiltin(engineConfig.GetRewritePath()))
 	shell.RegisterShellBuiltin("hash", hashBuiltin)
 	shell.RegisterShellBuiltin("umask_builtin", umaskBuiltin)
 
@@ -825,12 +926,22 @@ func runActionScript(engineConfig *singularityConfig.EngineConfig) ([]string, []
 		return nil, nil, err
 	}
 
-	if len(args) > 0 && args[0] == "/.singularity.d/runscript" {
+	// the runscript generated for OCI images is interpreted to apply
+	// --no-eval, it's also looked for in the startscript run by
+	// instances
+	if len(args) > 0 && (args[0] == "/.singularity.d/runscript" || args[0] == "/.singularity.d/startscript") {
 		b, err := getDockerRunscript(args[0])
 		if err != nil {
 			return nil, nil, err
 		} else if b != nil {
-			interp, err := interpreter.New(b, args[0], args[1:], env)
+			runArgs := args[1:]
+			if engineConfig.GetNoEval() {
+				b, runArgs, err = noEvalDockerRunscript(b, runArgs)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+			interp, err := interpreter.New(b, args[0], runArgs, env)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -848,6 +959,30 @@ func runActionScript(engineConfig *singularityConfig.EngineConfig) ([]string, []
 	return args, env, nil
 }
 
+// noEvalDockerRunscript returns the script and arguments running the
+// ENTRYPOINT and CMD of the docker runscript r without any shell evaluation,
+// whatever the version of the runscript: args are appended verbatim to the
+// ENTRYPOINT, replacing the CMD if there is at least one argument. This is
+// the only place where --no-eval is applied, the generated runscript always
+// evaluates its arguments. The docker runscript is returned unchanged if
+// ENTRYPOINT and CMD can't be determined.
+func noEvalDockerRunscript(r io.Reader, args []string) (io.Reader, []string, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	runArgs, err := shell.OCIRunscriptArgs(string(b), args)
+	if errors.Is(err, shell.ErrNoOCIProcess) {
+		return nil, nil, err
+	} else if err != nil {
+		sylog.Warningf("Unable to determine the OCI ENTRYPOINT and CMD of the runscript, arguments may be evaluated: %s", err)
+		return bytes.NewReader(b), args, nil
+	}
+
+	return strings.NewReader("exec \"$@\"\n"), runArgs, nil
+}
+
 // getDockerRunscript returns the content as a reader of
 // the default runscript set for docker images if any.
 func getDockerRunscript(path string) (io.Reader, error) {
@@ -44,6 +44,7 @@ type CryptArgs struct {
 	Loopdev   string
 	Key       []byte
 	MasterPid int
+	ReadOnly  bool
 }
 
 // ChrootArgs defines the arguments to chroot.
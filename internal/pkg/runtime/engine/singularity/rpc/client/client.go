@@ -41,12 +41,13 @@ func (t *RPC) Mount(source string, target string, filesystem string, flags uintp
 }
 
 // Decrypt calls the DeCrypt RPC using the supplied arguments.
-func (t *RPC) Decrypt(offset uint64, path string, key []byte, masterPid int) (string, error) {
+func (t *RPC) Decrypt(offset uint64, path string, key []byte, masterPid int, readOnly bool) (string, error) {
 	arguments := &args.CryptArgs{
 		Offset:    offset,
 		Loopdev:   path,
 		Key:       key,
 		MasterPid: masterPid,
+		ReadOnly:  readOnly,
 	}
 
 	var reply string
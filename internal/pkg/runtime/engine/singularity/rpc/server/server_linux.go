@@ -129,7 +129,11 @@ func (t *Methods) Decrypt(arguments *args.CryptArgs, reply *string) (err error)
 		}
 	}()
 
-	cryptName, err = cryptDev.Open(arguments.Key, arguments.Loopdev)
+	if arguments.ReadOnly {
+		cryptName, err = cryptDev.OpenReadOnly(arguments.Key, arguments.Loopdev)
+	} else {
+		cryptName, err = cryptDev.Open(arguments.Key, arguments.Loopdev)
+	}
 
 	*reply = "/dev/mapper/" + cryptName
 
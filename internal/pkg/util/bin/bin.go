@@ -28,6 +28,12 @@ func FindBin(name string) (path string, err error) {
 	// Bootstrap related executables that we assume are on PATH
 	case "mount", "mknod", "debootstrap", "pacstrap", "dnf", "yum", "rpm", "curl", "uname", "zypper", "SUSEConnect", "rpmkeys":
 		return findOnPath(name)
+	// Build post-processing executables that we assume are on PATH
+	case "strip":
+		return findOnPath(name)
+	// fakeroot(1) daemons used by the build fakeroot shim
+	case "faked-sysv", "faked":
+		return findOnPath(name)
 	// Configurable executables that are found at build time, can be overridden
 	// in singularity.conf. If config value is "" will look on PATH.
 	case "unsquashfs", "mksquashfs", "go":
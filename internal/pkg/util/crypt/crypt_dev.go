@@ -265,6 +265,16 @@ func getNextAvailableCryptDevice() (string, error) {
 // and returns the name assigned to it that can be later used to close
 // the device.
 func (crypt *Device) Open(key []byte, path string) (string, error) {
+	return crypt.open(key, path, false)
+}
+
+// OpenReadOnly is like Open but sets up a read-only mapping of the
+// encrypted filesystem.
+func (crypt *Device) OpenReadOnly(key []byte, path string) (string, error) {
+	return crypt.open(key, path, true)
+}
+
+func (crypt *Device) open(key []byte, path string, readOnly bool) (string, error) {
 	fd, err := lock.Exclusive("/dev/mapper")
 	if err != nil {
 		return "", fmt.Errorf("unable to acquire lock on /dev/mapper")
@@ -287,7 +297,13 @@ func (crypt *Device) Open(key []byte, path string) (string, error) {
 			return "", err
 		}
 
-		cmd := exec.Command(cryptsetup, "open", "--batch-mode", "--type", "luks2", "--key-file", "-", path, nextCrypt)
+		args := []string{"open", "--batch-mode", "--type", "luks2", "--key-file", "-"}
+		if readOnly {
+			args = append(args, "--readonly")
+		}
+		args = append(args, path, nextCrypt)
+
+		cmd := exec.Command(cryptsetup, args...)
 		cmd.SysProcAttr = &syscall.SysProcAttr{}
 		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: 0, Gid: 0}
 		sylog.Debugf("Running %s %s", cmd.Path, strings.Join(cmd.Args, " "))
@@ -1,4 +1,4 @@
-// Copyright (c) 2018-2020, Sylabs Inc. All rights reserved.
+// Copyright (c) 2018-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -6,6 +6,7 @@
 package env
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/sylabs/singularity/internal/pkg/runtime/engine/config/oci/generate"
@@ -46,8 +47,15 @@ var alwaysOmitKeys = map[string]bool{
 }
 
 // SetContainerEnv cleans environment variables before running the container.
-func SetContainerEnv(g *generate.Generator, hostEnvs []string, cleanEnv bool, homeDest string) map[string]string {
+// When cleanEnv is set, host environment variables matching one of the
+// keepEnv glob patterns are still forwarded to the container. Host
+// environment variables matching one of the passEnv glob patterns are
+// always forwarded, and returned apart from the SINGULARITYENV_ variables so
+// that they override the values set by the container environment as is,
+// without shell evaluation.
+func SetContainerEnv(g *generate.Generator, hostEnvs []string, cleanEnv bool, keepEnv, passEnv []string, homeDest string) (map[string]string, map[string]string) {
 	singEnvKeys := make(map[string]string)
+	passEnvKeys := make(map[string]string)
 
 	// allow override with SINGULARITYENV_LANG
 	if cleanEnv {
@@ -89,7 +97,15 @@ func SetContainerEnv(g *generate.Generator, hostEnvs []string, cleanEnv bool, ho
 			// precedence over the non prefixed variables
 			if _, ok := singEnvKeys[e[0]]; ok {
 				sylog.Verbosef("Skipping %[1]s environment variable, overridden by %[2]s%[1]s", e[0], SingularityEnvPrefix)
-			} else if addHostEnv(e[0], cleanEnv) {
+			} else if matchKeepEnv(e[0], passEnv) {
+				if !IsModifiable(e[0]) {
+					sylog.Warningf("Passing %s environment variable through is not permitted", e[0])
+					continue
+				}
+				sylog.Debugf("Passing %s environment variable through", e[0])
+				passEnvKeys[e[0]] = e[1]
+				g.AddProcessEnv(e[0], e[1])
+			} else if addHostEnv(e[0], cleanEnv, keepEnv) {
 				// transpose host env variables into config
 				sylog.Debugf("Forwarding %s environment variable", e[0])
 				g.AddProcessEnv(e[0], e[1])
@@ -97,22 +113,52 @@ func SetContainerEnv(g *generate.Generator, hostEnvs []string, cleanEnv bool, ho
 		}
 	}
 
+	// SINGULARITYENV_ prefixed environment variables take precedence over
+	// the variables passed through
+	for key := range passEnvKeys {
+		if _, ok := singEnvKeys[key]; ok {
+			delete(passEnvKeys, key)
+		}
+	}
+
 	sylog.Verbosef("Setting HOME=%s", homeDest)
 	sylog.Verbosef("Setting PATH=%s", DefaultPath)
 	g.AddProcessEnv("HOME", homeDest)
 	g.AddProcessEnv("PATH", DefaultPath)
 
-	return singEnvKeys
+	return singEnvKeys, passEnvKeys
+}
+
+// IsModifiable returns if the container value of the environment
+// variable key can be modified by appending or prepending a value.
+func IsModifiable(key string) bool {
+	if permitted, ok := alwaysOmitKeys[key]; ok && !permitted {
+		return false
+	}
+	return true
 }
 
 // addHostEnv processes given key and returns if the environment
 // variable should be added to the container or not.
-func addHostEnv(key string, cleanEnv bool) bool {
+func addHostEnv(key string, cleanEnv bool, keepEnv []string) bool {
 	if _, ok := alwaysPassKeys[key]; ok {
 		return true
 	}
-	if _, ok := alwaysOmitKeys[key]; ok || cleanEnv {
+	if _, ok := alwaysOmitKeys[key]; ok {
 		return false
 	}
+	if cleanEnv {
+		return matchKeepEnv(key, keepEnv)
+	}
 	return true
 }
+
+// matchKeepEnv returns if key matches one of the keepEnv glob patterns.
+func matchKeepEnv(key string, keepEnv []string) bool {
+	for _, pattern := range keepEnv {
+		if matched, _ := filepath.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -22,10 +22,13 @@ func TestSetContainerEnv(t *testing.T) {
 	tt := []struct {
 		name           string
 		cleanEnv       bool
+		keepEnv        []string
+		passEnv        []string
 		homeDest       string
 		env            []string
 		resultEnv      []string
 		singularityEnv map[string]string
+		passedEnv      map[string]string
 	}{
 		{
 			name:     "no SINGULARITYENV_",
@@ -300,19 +303,124 @@ func TestSetContainerEnv(t *testing.T) {
 				"HOST": "myhostenv",
 			},
 		},
+		{
+			name:     "cleanenv with keep-env",
+			cleanEnv: true,
+			keepEnv:  []string{"SLURM_*", "OMP_NUM_THREADS", "HOME"},
+			homeDest: "/home/tester",
+			env: []string{
+				"HOME=/home/john",
+				"PS1=test",
+				"SLURM_JOB_ID=42",
+				"SLURM_NTASKS=4",
+				"MY_SLURM_VAR=foo",
+				"OMP_NUM_THREADS=8",
+				"OMP_PROC_BIND=true",
+			},
+			resultEnv: []string{
+				"LANG=C",
+				"SLURM_JOB_ID=42",
+				"SLURM_NTASKS=4",
+				"OMP_NUM_THREADS=8",
+				"HOME=/home/tester",
+				"PATH=" + DefaultPath,
+			},
+		},
+		{
+			name:     "cleanenv with keep-env overridden by SINGULARITYENV_",
+			cleanEnv: true,
+			keepEnv:  []string{"SLURM_*"},
+			homeDest: "/home/tester",
+			env: []string{
+				"SLURM_JOB_ID=42",
+				"SINGULARITYENV_SLURM_JOB_ID=43",
+			},
+			resultEnv: []string{
+				"LANG=C",
+				"HOME=/home/tester",
+				"PATH=" + DefaultPath,
+			},
+			singularityEnv: map[string]string{
+				"SLURM_JOB_ID": "43",
+			},
+		},
+		{
+			name:     "env-pass-through",
+			passEnv:  []string{"PBS_*", "PATH"},
+			homeDest: "/home/tester",
+			env: []string{
+				"PBS_JOBID=42",
+				"PS1=test",
+				"PATH=/usr/bin",
+			},
+			resultEnv: []string{
+				"PBS_JOBID=42",
+				"PS1=test",
+				"HOME=/home/tester",
+				"PATH=" + DefaultPath,
+			},
+			singularityEnv: map[string]string{},
+			passedEnv: map[string]string{
+				"PBS_JOBID": "42",
+			},
+		},
+		{
+			name:     "cleanenv with env-pass-through",
+			cleanEnv: true,
+			passEnv:  []string{"PBS_*"},
+			homeDest: "/home/tester",
+			env: []string{
+				"PBS_JOBID=42",
+				"PS1=test",
+				"PBS_NODEFILE=/var/spool/nodes",
+				"SINGULARITYENV_PBS_NODEFILE=/nodes",
+			},
+			resultEnv: []string{
+				"LANG=C",
+				"PBS_JOBID=42",
+				"HOME=/home/tester",
+				"PATH=" + DefaultPath,
+			},
+			singularityEnv: map[string]string{
+				"PBS_NODEFILE": "/nodes",
+			},
+			passedEnv: map[string]string{
+				"PBS_JOBID": "42",
+			},
+		},
+		{
+			name:     "env-pass-through with shell characters",
+			passEnv:  []string{"PBS_*"},
+			homeDest: "/home/tester",
+			env: []string{
+				"PBS_O_WORKDIR=$HOME/`id`/$(id)/end\\",
+			},
+			resultEnv: []string{
+				"PBS_O_WORKDIR=$HOME/`id`/$(id)/end\\",
+				"HOME=/home/tester",
+				"PATH=" + DefaultPath,
+			},
+			singularityEnv: map[string]string{},
+			passedEnv: map[string]string{
+				"PBS_O_WORKDIR": "$HOME/`id`/$(id)/end\\",
+			},
+		},
 	}
 	for _, tc := range tt {
 		t.Run(tc.name, func(t *testing.T) {
 			ociConfig := &oci.Config{}
 			generator := generate.New(&ociConfig.Spec)
 
-			senv := SetContainerEnv(generator, tc.env, tc.cleanEnv, tc.homeDest)
+			senv, penv := SetContainerEnv(generator, tc.env, tc.cleanEnv, tc.keepEnv, tc.passEnv, tc.homeDest)
 			if !equal(t, ociConfig.Process.Env, tc.resultEnv) {
 				t.Fatalf("unexpected envs:\n want: %v\ngot: %v", tc.resultEnv, ociConfig.Process.Env)
 			}
 			if tc.singularityEnv != nil && !reflect.DeepEqual(senv, tc.singularityEnv) {
 				t.Fatalf("unexpected singularity env:\n want: %v\ngot: %v", tc.singularityEnv, senv)
 			}
+			if tc.passedEnv != nil && !reflect.DeepEqual(penv, tc.passedEnv) {
+				t.Fatalf("unexpected passed through env:\n want: %v\ngot: %v", tc.passedEnv, penv)
+			}
 		})
 	}
 }
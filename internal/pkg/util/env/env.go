@@ -24,3 +24,37 @@ func SetFromList(environ []string) error {
 	}
 	return nil
 }
+
+// Operators used to append or prepend a value to the container value
+// of an environment variable, as in KEY+=VALUE and KEY^=VALUE.
+const (
+	AppendOperator  = "+="
+	PrependOperator = "^="
+)
+
+// SplitModifier splits the KEY+=VALUE or KEY^=VALUE environment variable
+// assignment env and returns the key, value and operator. An empty
+// operator is returned if env is not such an assignment.
+func SplitModifier(env string) (key, value, op string) {
+	e := strings.SplitN(env, "=", 2)
+	if len(e) != 2 || len(e[0]) < 2 {
+		return "", "", ""
+	}
+	for _, op := range []string{AppendOperator, PrependOperator} {
+		if strings.HasSuffix(e[0], op[:1]) {
+			return e[0][:len(e[0])-1], e[1], op
+		}
+	}
+	return "", "", ""
+}
+
+// ListSeparator returns the separator inserted between the container value
+// of the environment variable key and an appended or prepended value: a
+// colon for path-like variables, named PATH or with a name ending with PATH
+// (e.g. LD_LIBRARY_PATH, MANPATH), a space otherwise.
+func ListSeparator(key string) string {
+	if strings.HasSuffix(key, "PATH") {
+		return ":"
+	}
+	return " "
+}
@@ -65,3 +65,42 @@ func TestSetFromList(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitModifier(t *testing.T) {
+	tt := []struct {
+		env   string
+		key   string
+		value string
+		op    string
+	}{
+		{env: "PATH+=/opt/bin", key: "PATH", value: "/opt/bin", op: AppendOperator},
+		{env: "PATH^=/opt/bin", key: "PATH", value: "/opt/bin", op: PrependOperator},
+		{env: "CFLAGS+=-O2 -g", key: "CFLAGS", value: "-O2 -g", op: AppendOperator},
+		{env: "FOO+=", key: "FOO", value: "", op: AppendOperator},
+		{env: "FOO=+bar"},
+		{env: "FOO=bar"},
+		{env: "+=bar"},
+		{env: "FOO"},
+	}
+
+	for _, tc := range tt {
+		key, value, op := SplitModifier(tc.env)
+		if key != tc.key || value != tc.value || op != tc.op {
+			t.Errorf("unexpected result for %q: got (%q, %q, %q), expected (%q, %q, %q)", tc.env, key, value, op, tc.key, tc.value, tc.op)
+		}
+	}
+}
+
+func TestListSeparator(t *testing.T) {
+	tt := map[string]string{
+		"PATH":            ":",
+		"LD_LIBRARY_PATH": ":",
+		"MANPATH":         ":",
+		"CFLAGS":          " ",
+	}
+	for key, sep := range tt {
+		if s := ListSeparator(key); s != sep {
+			t.Errorf("unexpected separator %q for %s, expected %q", s, key, sep)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package env
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/util/shell/interpreter"
+)
+
+// dynamicKeys lists the environment variables which are set or modified
+// at runtime depending on the host, the user or the command line, they
+// are excluded from the resolved container environment.
+var dynamicKeys = map[string]struct{}{
+	"HOME":            {},
+	"PWD":             {},
+	"OLDPWD":          {},
+	"USER":            {},
+	"LOGNAME":         {},
+	"HOSTNAME":        {},
+	"SHELL":           {},
+	"SHLVL":           {},
+	"PS1":             {},
+	"PROMPT_COMMAND":  {},
+	"LD_LIBRARY_PATH": {},
+	"_":               {},
+	// shell variables
+	"UID":    {},
+	"GID":    {},
+	"IFS":    {},
+	"OPTIND": {},
+}
+
+// IsDynamicKey returns if the environment variable key is set or
+// modified at runtime, independently of the container image.
+// In addition to the keys above, variables prefixed by SINGULARITY_ or
+// SINGULARITYENV_ and the variables always passed from the host
+// (TERM, proxy variables) are considered as dynamic.
+func IsDynamicKey(key string) bool {
+	if _, ok := dynamicKeys[key]; ok {
+		return true
+	}
+	if _, ok := alwaysPassKeys[key]; ok {
+		return true
+	}
+	return strings.HasPrefix(key, SingularityPrefix) || strings.HasPrefix(key, SingularityEnvPrefix)
+}
+
+// ResolveContainerEnv evaluates the container environment scripts, passed
+// as a map of file path to script content, in the same order as they are
+// sourced at runtime and returns the resulting environment variables,
+// excluding dynamic ones (see IsDynamicKey). Command execution is disabled
+// during evaluation, a script requiring it is skipped and reported in the
+// returned error, variables set by the other scripts are still returned.
+func ResolveContainerEnv(scripts map[string]string) (map[string]string, error) {
+	files := make([]string, 0, len(scripts))
+	for f := range scripts {
+		files = append(files, f)
+	}
+	// container scripts are sourced by glob order at runtime, app
+	// scripts are sourced afterward by the apps base script
+	sort.Slice(files, func(i, j int) bool {
+		ai := strings.HasPrefix(files[i], "/scif/apps/")
+		aj := strings.HasPrefix(files[j], "/scif/apps/")
+		if ai != aj {
+			return aj
+		}
+		return files[i] < files[j]
+	})
+
+	resolved := make(map[string]string)
+	var failed []string
+
+	for _, f := range files {
+		// docker files may not be present depending of image source,
+		// so PATH is fixed before sourcing the other environment files
+		if _, ok := resolved["PATH"]; !ok && !strings.HasPrefix(filepath.Base(f), "10-docker") {
+			resolved["PATH"] = DefaultPath
+		}
+
+		current := make([]string, 0, len(resolved))
+		for k, v := range resolved {
+			current = append(current, k+"="+v)
+		}
+
+		env, err := interpreter.EvaluateEnv([]byte(scripts[f]), []string{}, current)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", f, err))
+			continue
+		}
+		for _, e := range env {
+			kv := strings.SplitN(e, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			resolved[kv[0]] = kv[1]
+		}
+	}
+
+	if _, ok := resolved["PATH"]; !ok && len(files) > 0 {
+		resolved["PATH"] = DefaultPath
+	}
+
+	for k := range resolved {
+		if IsDynamicKey(k) {
+			delete(resolved, k)
+		}
+	}
+
+	if len(failed) > 0 {
+		return resolved, fmt.Errorf("could not resolve environment from %s", strings.Join(failed, "; "))
+	}
+	return resolved, nil
+}
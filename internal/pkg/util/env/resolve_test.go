@@ -0,0 +1,104 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package env
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sylabs/singularity/internal/pkg/test"
+)
+
+func TestResolveContainerEnv(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	tests := []struct {
+		name        string
+		scripts     map[string]string
+		expected    map[string]string
+		expectError bool
+	}{
+		{
+			name:     "NoScripts",
+			scripts:  map[string]string{},
+			expected: map[string]string{},
+		},
+		{
+			name: "DefaultPath",
+			scripts: map[string]string{
+				"/.singularity.d/env/90-environment.sh": "export FOO=bar",
+			},
+			expected: map[string]string{
+				"PATH": DefaultPath,
+				"FOO":  "bar",
+			},
+		},
+		{
+			name: "DockerPath",
+			scripts: map[string]string{
+				"/.singularity.d/env/90-environment.sh": "export PATH=$PATH:/opt/bin",
+				"/.singularity.d/env/10-docker2singularity.sh": "export PATH=\"/usr/local/bin:/usr/bin\"\n" +
+					"export LANG=\"C.UTF-8\"",
+			},
+			expected: map[string]string{
+				"PATH": "/usr/local/bin:/usr/bin:/opt/bin",
+				"LANG": "C.UTF-8",
+			},
+		},
+		{
+			name: "AppOrder",
+			scripts: map[string]string{
+				"/scif/apps/foo/scif/env/90-environment.sh": "export FOO=app-$FOO",
+				"/.singularity.d/env/90-environment.sh":     "export FOO=bar",
+			},
+			expected: map[string]string{
+				"PATH": DefaultPath,
+				"FOO":  "app-bar",
+			},
+		},
+		{
+			name: "DynamicKeys",
+			scripts: map[string]string{
+				"/.singularity.d/env/90-environment.sh": "export HOME=/root\n" +
+					"export LD_LIBRARY_PATH=/opt/lib\n" +
+					"export SINGULARITY_FOO=bar\n" +
+					"export http_proxy=http://proxy\n" +
+					"export FOO=bar",
+			},
+			expected: map[string]string{
+				"PATH": DefaultPath,
+				"FOO":  "bar",
+			},
+		},
+		{
+			name: "CommandExecution",
+			scripts: map[string]string{
+				"/.singularity.d/env/90-environment.sh": "export FOO=bar",
+				"/.singularity.d/env/91-environment.sh": "export BAR=$(hostname)",
+			},
+			expected: map[string]string{
+				"PATH": DefaultPath,
+				"FOO":  "bar",
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := ResolveContainerEnv(tt.scripts)
+			if err != nil && !tt.expectError {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Fatalf("unexpected success")
+			}
+			if !reflect.DeepEqual(resolved, tt.expected) {
+				t.Errorf("unexpected environment %v, expected %v", resolved, tt.expected)
+			}
+		})
+	}
+}
@@ -5,13 +5,58 @@
 
 package files
 
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
 var defaultContent = `127.0.0.1   localhost
 ::1         localhost ip6-localhost ip6-loopback
 ff02::1     ip6-allnodes
 ff02::2     ip6-allrouters
 `
 
+// hostnameRegexp matches a RFC 1123 host name.
+var hostnameRegexp = regexp.MustCompile(`^([a-zA-Z0-9]([-a-zA-Z0-9]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([-a-zA-Z0-9]{0,61}[a-zA-Z0-9])?)*$`)
+
 // DefaultHosts creates the default hosts file.
 func DefaultHosts() []byte {
 	return []byte(defaultContent)
 }
+
+// ParseHostEntry parses a host entry of the form name:ip, as
+// accepted by the docker run --add-host option, and returns the
+// host name and IP address.
+func ParseHostEntry(entry string) (name string, ip string, err error) {
+	kv := strings.SplitN(entry, ":", 2)
+	if len(kv) != 2 {
+		return "", "", fmt.Errorf("host entry %q is not of the form name:ip", entry)
+	}
+	name, ip = kv[0], kv[1]
+	if len(name) > 253 || !hostnameRegexp.MatchString(name) {
+		return "", "", fmt.Errorf("%q is not a valid host name", name)
+	}
+	if net.ParseIP(ip) == nil {
+		return "", "", fmt.Errorf("%q is not a valid IP address", ip)
+	}
+	return name, ip, nil
+}
+
+// AppendHosts returns the hosts file content base with the host
+// entries of the form name:ip appended to it.
+func AppendHosts(base []byte, entries []string) (content []byte, err error) {
+	content = append(content, base...)
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+	for _, entry := range entries {
+		name, ip, err := ParseHostEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, fmt.Sprintf("%s\t%s\n", ip, name)...)
+	}
+	return content, nil
+}
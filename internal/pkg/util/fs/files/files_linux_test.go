@@ -98,19 +98,137 @@ func TestResolvConf(t *testing.T) {
 	test.DropPrivilege(t)
 	defer test.ResetPrivilege(t)
 
-	_, err := ResolvConf([]string{})
+	_, err := ResolvConf([]string{}, nil)
 	if err == nil {
 		t.Errorf("should have failed with empty dns")
 	}
-	_, err = ResolvConf([]string{"test"})
+	_, err = ResolvConf([]string{"test"}, nil)
 	if err == nil {
 		t.Errorf("should have failed with bad dns")
 	}
-	content, err := ResolvConf([]string{"8.8.8.8"})
+	content, err := ResolvConf([]string{"8.8.8.8"}, nil)
 	if err != nil {
 		t.Errorf("should have passed with valid dns")
 	}
 	if !bytes.Equal(content, []byte("nameserver 8.8.8.8\n")) {
 		t.Errorf("ResolvConf returns a bad content")
 	}
+	content, err = ResolvConf([]string{"8.8.8.8", "1.1.1.1"}, []string{"example.com", "example.org"})
+	if err != nil {
+		t.Errorf("should have passed with valid dns and search domains")
+	}
+	if !bytes.Equal(content, []byte("nameserver 8.8.8.8\nnameserver 1.1.1.1\nsearch example.com example.org\n")) {
+		t.Errorf("ResolvConf returns a bad content: %q", content)
+	}
+}
+
+func TestOverlayResolvConf(t *testing.T) {
+	base := []byte("# host resolv.conf\nnameserver 10.0.0.1\nsearch host.local\noptions ndots:2")
+
+	tests := []struct {
+		name        string
+		dns         []string
+		search      []string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "NoOverride",
+			expected: "# host resolv.conf\nnameserver 10.0.0.1\nsearch host.local\noptions ndots:2\n",
+		},
+		{
+			name:     "DNS",
+			dns:      []string{"8.8.8.8"},
+			expected: "# host resolv.conf\nsearch host.local\noptions ndots:2\nnameserver 8.8.8.8\n",
+		},
+		{
+			name:     "Search",
+			search:   []string{"example.com"},
+			expected: "# host resolv.conf\nnameserver 10.0.0.1\noptions ndots:2\nsearch example.com\n",
+		},
+		{
+			name:        "BadDNS",
+			dns:         []string{"8.8.8"},
+			expectError: true,
+		},
+		{
+			name:        "BadSearch",
+			search:      []string{""},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := OverlayResolvConf(base, tt.dns, tt.search)
+			if err != nil && !tt.expectError {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Fatalf("unexpected success")
+			}
+			if !tt.expectError && string(content) != tt.expected {
+				t.Errorf("unexpected content %q, expected %q", content, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAppendHosts(t *testing.T) {
+	base := []byte("127.0.0.1 localhost")
+
+	tests := []struct {
+		name        string
+		entries     []string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "NoEntry",
+			expected: "127.0.0.1 localhost\n",
+		},
+		{
+			name:     "IPv4",
+			entries:  []string{"myhost:10.0.0.1", "db.example.com:10.0.0.2"},
+			expected: "127.0.0.1 localhost\n10.0.0.1\tmyhost\n10.0.0.2\tdb.example.com\n",
+		},
+		{
+			name:     "IPv6",
+			entries:  []string{"myhost:fd00::1"},
+			expected: "127.0.0.1 localhost\nfd00::1\tmyhost\n",
+		},
+		{
+			name:        "MissingIP",
+			entries:     []string{"myhost"},
+			expectError: true,
+		},
+		{
+			name:        "BadIP",
+			entries:     []string{"myhost:10.0.0"},
+			expectError: true,
+		},
+		{
+			name:        "BadHostname",
+			entries:     []string{"-myhost:10.0.0.1"},
+			expectError: true,
+		},
+		{
+			name:        "EmptyHostname",
+			entries:     []string{":10.0.0.1"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := AppendHosts(base, tt.entries)
+			if err != nil && !tt.expectError {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Fatalf("unexpected success")
+			}
+			if !tt.expectError && string(content) != tt.expected {
+				t.Errorf("unexpected content %q, expected %q", content, tt.expected)
+			}
+		})
+	}
 }
@@ -8,22 +8,63 @@ package files
 import (
 	"fmt"
 	"net"
+	"strings"
 
 	"github.com/sylabs/singularity/pkg/sylog"
 )
 
-// ResolvConf creates a resolv.conf content with provided dns list and returns it
-func ResolvConf(dns []string) (content []byte, err error) {
+// ResolvConf creates a resolv.conf content with provided dns list and
+// search domains and returns it
+func ResolvConf(dns []string, search []string) (content []byte, err error) {
 	sylog.Verbosef("Creating resolv.conf content\n")
-	if len(dns) == 0 {
+	if len(dns) == 0 && len(search) == 0 {
 		return content, fmt.Errorf("no dns ip provided")
 	}
+	return OverlayResolvConf(nil, dns, search)
+}
+
+// OverlayResolvConf returns the resolv.conf content base with its nameserver
+// entries replaced by the provided dns list, if any, and its search domains
+// replaced by the provided search list, if any. Other entries are preserved.
+func OverlayResolvConf(base []byte, dns []string, search []string) (content []byte, err error) {
 	for _, ip := range dns {
 		if net.ParseIP(ip) == nil {
 			return content, fmt.Errorf("dns ip %s is not a valid IP address", ip)
 		}
+	}
+	for _, domain := range search {
+		if domain == "" || strings.ContainsAny(domain, " \t\n") {
+			return content, fmt.Errorf("dns search domain %q is not valid", domain)
+		}
+	}
+
+	for _, line := range strings.SplitAfter(string(base), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) > 0 {
+			switch fields[0] {
+			case "nameserver":
+				if len(dns) > 0 {
+					continue
+				}
+			case "search", "domain":
+				if len(search) > 0 {
+					continue
+				}
+			}
+		}
+		if line != "" && !strings.HasSuffix(line, "\n") {
+			line += "\n"
+		}
+		content = append(content, line...)
+	}
+
+	for _, ip := range dns {
 		line := fmt.Sprintf("nameserver %s\n", ip)
 		content = append(content, line...)
 	}
+	if len(search) > 0 {
+		line := fmt.Sprintf("search %s\n", strings.Join(search, " "))
+		content = append(content, line...)
+	}
 	return content, nil
 }
@@ -0,0 +1,150 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package gpu
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// nvidiaVersionFile exposes the version of the loaded NVIDIA kernel driver.
+var nvidiaVersionFile = "/proc/driver/nvidia/version"
+
+var (
+	nvidiaDriverRegexp = regexp.MustCompile(`Kernel Module(?:\s+for\s+\S+)?\s+(\d+(?:\.\d+)+)`)
+	cudaTextRegexp     = regexp.MustCompile(`CUDA Version\s+(\d+(?:\.\d+)+)`)
+	cudartRegexp       = regexp.MustCompile(`^libcudart\.so\.(\d+\.\d+(?:\.\d+)*)$`)
+)
+
+// cudaMinDriver maps CUDA toolkit versions to the minimum Linux driver
+// version able to run them. Starting with CUDA 11 all minor releases of a
+// major release run with the driver of the first minor release thanks to
+// CUDA minor version compatibility.
+var cudaMinDriver = []struct {
+	cuda   string
+	driver string
+}{
+	{"12", "525.60.13"},
+	{"11", "450.80.02"},
+	{"10.2", "440.33"},
+	{"10.1", "418.39"},
+	{"10.0", "410.48"},
+	{"9.2", "396.26"},
+	{"9.1", "390.46"},
+	{"9.0", "384.81"},
+	{"8.0", "367.48"},
+	{"7.5", "352.31"},
+	{"7.0", "346.46"},
+}
+
+// cudaLibDirs are the directories, relative to the container root, searched
+// for the CUDA runtime library when no CUDA version file is found.
+var cudaLibDirs = []string{
+	"usr/local/cuda/lib64",
+	"usr/local/cuda/targets/*/lib",
+	"usr/lib64",
+	"usr/lib/*-linux-gnu",
+}
+
+// NvidiaDriverVersion returns the version of the NVIDIA kernel driver
+// loaded on the host.
+func NvidiaDriverVersion() (string, error) {
+	b, err := ioutil.ReadFile(nvidiaVersionFile)
+	if err != nil {
+		return "", fmt.Errorf("could not read NVIDIA driver version: %v", err)
+	}
+	m := nvidiaDriverRegexp.FindSubmatch(b)
+	if m == nil {
+		return "", fmt.Errorf("no NVIDIA driver version found in %s", nvidiaVersionFile)
+	}
+	return string(m[1]), nil
+}
+
+// CudaVersion returns the version of the CUDA toolkit installed in the
+// container root filesystem found at rootfs. It is a best effort detection
+// relying on the toolkit version files first, then on the version of the
+// CUDA runtime library. An empty version is returned if CUDA is not found.
+func CudaVersion(rootfs string) string {
+	cudaDir := filepath.Join(rootfs, "usr/local/cuda")
+
+	if b, err := ioutil.ReadFile(filepath.Join(cudaDir, "version.json")); err == nil {
+		v := struct {
+			Cuda struct {
+				Version string `json:"version"`
+			} `json:"cuda"`
+		}{}
+		if err := json.Unmarshal(b, &v); err == nil && v.Cuda.Version != "" {
+			return v.Cuda.Version
+		}
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(cudaDir, "version.txt")); err == nil {
+		if m := cudaTextRegexp.FindSubmatch(b); m != nil {
+			return string(m[1])
+		}
+	}
+
+	version := ""
+	for _, dir := range cudaLibDirs {
+		libs, _ := filepath.Glob(filepath.Join(rootfs, dir, "libcudart.so.*"))
+		for _, lib := range libs {
+			m := cudartRegexp.FindStringSubmatch(filepath.Base(lib))
+			if m != nil && compareVersions(m[1], version) > 0 {
+				version = m[1]
+			}
+		}
+		if version != "" {
+			break
+		}
+	}
+	return version
+}
+
+// CheckCudaDriver returns an error if the CUDA toolkit version is known to
+// require a more recent NVIDIA driver than the driver version provided.
+func CheckCudaDriver(cuda, driver string) error {
+	for _, req := range cudaMinDriver {
+		if cuda != req.cuda && !strings.HasPrefix(cuda, req.cuda+".") {
+			continue
+		}
+		if compareVersions(driver, req.driver) < 0 {
+			return fmt.Errorf("CUDA %s requires NVIDIA driver >= %s, host driver is %s", cuda, req.driver, driver)
+		}
+		return nil
+	}
+	// CUDA releases more recent than the ones known here need at least
+	// the driver of the most recent known release
+	if compareVersions(cuda, cudaMinDriver[0].cuda) > 0 && compareVersions(driver, cudaMinDriver[0].driver) < 0 {
+		return fmt.Errorf("CUDA %s requires NVIDIA driver >= %s, host driver is %s", cuda, cudaMinDriver[0].driver, driver)
+	}
+	return nil
+}
+
+// compareVersions compares the dot separated numeric versions a and b and
+// returns -1, 0 or 1 if a is respectively lower, equal or greater than b.
+func compareVersions(a, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var an, bn int
+		if i < len(as) {
+			an, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			bn, _ = strconv.Atoi(bs[i])
+		}
+		if an < bn {
+			return -1
+		} else if an > bn {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package gpu
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNvidiaDriverVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "proprietary",
+			content: "NVRM version: NVIDIA UNIX x86_64 Kernel Module  470.57.02  Tue Jul 13 16:14:05 UTC 2021\n",
+			want:    "470.57.02",
+		},
+		{
+			name:    "open",
+			content: "NVRM version: NVIDIA UNIX Open Kernel Module for x86_64  515.43.04  Release Build\n",
+			want:    "515.43.04",
+		},
+		{
+			name:    "garbage",
+			content: "not a driver version\n",
+			wantErr: true,
+		},
+	}
+
+	origVersionFile := nvidiaVersionFile
+	defer func() { nvidiaVersionFile = origVersionFile }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "nvidia-version-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.WriteString(tt.content); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			nvidiaVersionFile = f.Name()
+			got, err := NvidiaDriverVersion()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NvidiaDriverVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NvidiaDriverVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCudaVersion(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "none",
+			want: "",
+		},
+		{
+			name: "version.json",
+			files: map[string]string{
+				"usr/local/cuda/version.json": `{"cuda": {"name": "CUDA SDK", "version": "11.4.2"}}`,
+			},
+			want: "11.4.2",
+		},
+		{
+			name: "version.txt",
+			files: map[string]string{
+				"usr/local/cuda/version.txt": "CUDA Version 10.2.89\n",
+			},
+			want: "10.2.89",
+		},
+		{
+			name: "cudart",
+			files: map[string]string{
+				"usr/local/cuda/lib64/libcudart.so":          "",
+				"usr/local/cuda/lib64/libcudart.so.11.0":     "",
+				"usr/local/cuda/lib64/libcudart.so.11.6.55":  "",
+				"usr/local/cuda/lib64/libcudart_static.a":    "",
+				"usr/lib/x86_64-linux-gnu/libcudart.so.10.1": "",
+			},
+			want: "11.6.55",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rootfs, err := ioutil.TempDir("", "cuda-rootfs-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(rootfs)
+
+			for name, content := range tt.files {
+				path := filepath.Join(rootfs, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := CudaVersion(rootfs); got != tt.want {
+				t.Errorf("CudaVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckCudaDriver(t *testing.T) {
+	tests := []struct {
+		cuda    string
+		driver  string
+		wantErr bool
+	}{
+		{cuda: "11.4.2", driver: "470.57.02", wantErr: false},
+		{cuda: "11.4.2", driver: "450.80.02", wantErr: false},
+		{cuda: "11.0", driver: "440.33.01", wantErr: true},
+		{cuda: "10.2.89", driver: "440.33", wantErr: false},
+		{cuda: "10.2.89", driver: "418.87.01", wantErr: true},
+		{cuda: "12.0.1", driver: "470.57.02", wantErr: true},
+		{cuda: "13.1", driver: "470.57.02", wantErr: true},
+		{cuda: "6.5", driver: "340.29", wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cuda+"/"+tt.driver, func(t *testing.T) {
+			if err := CheckCudaDriver(tt.cuda, tt.driver); (err != nil) != tt.wantErr {
+				t.Errorf("CheckCudaDriver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
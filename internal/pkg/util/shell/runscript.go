@@ -0,0 +1,154 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package shell
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// ErrNotOCIRunscript is returned when a runscript was not generated for
+// an OCI image.
+var ErrNotOCIRunscript = errors.New("not an OCI image runscript")
+
+// ErrNoOCIProcess is returned by OCIRunscriptArgs when the runscript has
+// neither ENTRYPOINT nor CMD, and no argument is given.
+var ErrNoOCIProcess = errors.New("no ENTRYPOINT or CMD to run in the container")
+
+// ParseOCIRunscript returns the ENTRYPOINT and CMD set by the OCI_ENTRYPOINT
+// and OCI_CMD variables of a runscript generated for an OCI image, without
+// any shell evaluation. Parameter expansions and command substitutions,
+// found in values which were not escaped by older versions, are returned
+// verbatim.
+func ParseOCIRunscript(runscript string) (entrypoint, cmd []string, err error) {
+	f, err := syntax.NewParser().Parse(strings.NewReader(runscript), "runscript")
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotOCIRunscript, err)
+	}
+
+	values := make(map[string][]string)
+	for _, stmt := range f.Stmts {
+		call, ok := stmt.Cmd.(*syntax.CallExpr)
+		if !ok || len(call.Args) > 0 {
+			continue
+		}
+		for _, assign := range call.Assigns {
+			if assign.Name.Value != "OCI_ENTRYPOINT" && assign.Name.Value != "OCI_CMD" {
+				continue
+			}
+			value := ""
+			if assign.Value != nil {
+				value = literalWord(assign.Value)
+			}
+			args, err := splitArgs(value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("while parsing %s: %w", assign.Name.Value, err)
+			}
+			values[assign.Name.Value] = args
+		}
+	}
+
+	entrypoint, hasEntrypoint := values["OCI_ENTRYPOINT"]
+	cmd, hasCmd := values["OCI_CMD"]
+	if !hasEntrypoint || !hasCmd {
+		return nil, nil, ErrNotOCIRunscript
+	}
+	return entrypoint, cmd, nil
+}
+
+// OCIRunscriptArgs returns the arguments run by a runscript generated for an
+// OCI image when called with args, without any shell evaluation: args are
+// appended verbatim to the ENTRYPOINT, replacing the CMD if there is at
+// least one argument.
+func OCIRunscriptArgs(runscript string, args []string) ([]string, error) {
+	entrypoint, cmd, err := ParseOCIRunscript(runscript)
+	if err != nil {
+		return nil, err
+	}
+
+	runArgs := append([]string{}, entrypoint...)
+	if len(args) > 0 {
+		runArgs = append(runArgs, args...)
+	} else {
+		runArgs = append(runArgs, cmd...)
+	}
+	if len(runArgs) == 0 {
+		return nil, ErrNoOCIProcess
+	}
+	return runArgs, nil
+}
+
+// splitArgs splits the shell quoted arguments in s.
+func splitArgs(s string) ([]string, error) {
+	f, err := syntax.NewParser().Parse(strings.NewReader(s), "")
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Stmts) == 0 {
+		return nil, nil
+	}
+	call, ok := f.Stmts[0].Cmd.(*syntax.CallExpr)
+	if len(f.Stmts) > 1 || !ok {
+		return nil, fmt.Errorf("%q is not a list of arguments", s)
+	}
+
+	args := make([]string, 0, len(call.Args))
+	for _, word := range call.Args {
+		args = append(args, literalWord(word))
+	}
+	return args, nil
+}
+
+// literalWord returns the value of w after quote removal only, any other
+// part of w is returned as it appears in the source.
+func literalWord(w *syntax.Word) string {
+	var b strings.Builder
+	for _, part := range w.Parts {
+		switch p := part.(type) {
+		case *syntax.Lit:
+			b.WriteString(unescape(p.Value, ""))
+		case *syntax.SglQuoted:
+			b.WriteString(p.Value)
+		case *syntax.DblQuoted:
+			for _, dp := range p.Parts {
+				if lit, ok := dp.(*syntax.Lit); ok {
+					b.WriteString(unescape(lit.Value, "$`\"\\\n"))
+				} else {
+					b.WriteString(printNode(dp))
+				}
+			}
+		default:
+			b.WriteString(printNode(part))
+		}
+	}
+	return b.String()
+}
+
+// unescape removes the backslashes escaping the characters in chars, or
+// any character if chars is empty.
+func unescape(s, chars string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (chars == "" || strings.IndexByte(chars, s[i+1]) >= 0) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// printNode returns the source representation of node.
+func printNode(node syntax.Node) string {
+	var b bytes.Buffer
+	if err := syntax.NewPrinter().Print(&b, node); err != nil {
+		return ""
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package shell
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseOCIRunscript(t *testing.T) {
+	tests := []struct {
+		name       string
+		runscript  string
+		entrypoint []string
+		cmd        []string
+		err        error
+	}{
+		{
+			name:       "EntrypointCmd",
+			runscript:  "#!/bin/sh\nOCI_ENTRYPOINT='" + EscapeSingleQuotes(ArgsQuoted([]string{"/bin/echo", "it's"})) + "'\nOCI_CMD='" + EscapeSingleQuotes(ArgsQuoted([]string{"$HOME", "`id`"})) + "'\nexec \"$@\"\n",
+			entrypoint: []string{"/bin/echo", "it's"},
+			cmd:        []string{"$HOME", "`id`"},
+		},
+		{
+			name:       "EmptyEntrypoint",
+			runscript:  "#!/bin/sh\nOCI_ENTRYPOINT=''\nOCI_CMD='\"/bin/sh\"'\n",
+			entrypoint: []string{},
+			cmd:        []string{"/bin/sh"},
+		},
+		{
+			name:       "UnescapedExpansion",
+			runscript:  "#!/bin/sh\nOCI_ENTRYPOINT='\"/bin/echo\"'\nOCI_CMD='\"$HOME\" \"${USER}\"'\n",
+			entrypoint: []string{"/bin/echo"},
+			cmd:        []string{"$HOME", "${USER}"},
+		},
+		{
+			name:      "NotOCI",
+			runscript: "#!/bin/sh\nexec /bin/sh \"$@\"\n",
+			err:       ErrNotOCIRunscript,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entrypoint, cmd, err := ParseOCIRunscript(tt.runscript)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, expected %v", err, tt.err)
+			}
+			if tt.err != nil {
+				return
+			}
+			if len(entrypoint) != len(tt.entrypoint) || (len(entrypoint) > 0 && !reflect.DeepEqual(entrypoint, tt.entrypoint)) {
+				t.Errorf("got entrypoint %q, expected %q", entrypoint, tt.entrypoint)
+			}
+			if !reflect.DeepEqual(cmd, tt.cmd) {
+				t.Errorf("got cmd %q, expected %q", cmd, tt.cmd)
+			}
+		})
+	}
+}
+
+func TestOCIRunscriptArgs(t *testing.T) {
+	runscript := func(entrypoint, cmd []string) string {
+		return "#!/bin/sh\nOCI_ENTRYPOINT='" + EscapeSingleQuotes(ArgsQuoted(entrypoint)) + "'\n" +
+			"OCI_CMD='" + EscapeSingleQuotes(ArgsQuoted(cmd)) + "'\n" +
+			"eval \"set ${SINGULARITY_OCI_RUN}\"\nexec \"$@\"\n"
+	}
+
+	tests := []struct {
+		name      string
+		runscript string
+		args      []string
+		expected  []string
+		err       error
+	}{
+		{
+			name:      "EntrypointCmd",
+			runscript: runscript([]string{"/bin/echo"}, []string{"$HOME", "`id`"}),
+			expected:  []string{"/bin/echo", "$HOME", "`id`"},
+		},
+		{
+			name:      "EntrypointArgs",
+			runscript: runscript([]string{"/bin/echo"}, []string{"default"}),
+			args:      []string{"$HOME", "$(id)", `"quoted"`, "it's", "a b", "$@", `end\`},
+			expected:  []string{"/bin/echo", "$HOME", "$(id)", `"quoted"`, "it's", "a b", "$@", `end\`},
+		},
+		{
+			name:      "CmdArgs",
+			runscript: runscript(nil, []string{"/bin/sh"}),
+			args:      []string{"/bin/echo", "*"},
+			expected:  []string{"/bin/echo", "*"},
+		},
+		{
+			name:      "NoProcess",
+			runscript: runscript(nil, nil),
+			err:       ErrNoOCIProcess,
+		},
+		{
+			name:      "NotOCI",
+			runscript: "#!/bin/sh\nexec /bin/sh \"$@\"\n",
+			args:      []string{"$HOME"},
+			err:       ErrNotOCIRunscript,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := OCIRunscriptArgs(tt.runscript, tt.args)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, expected %v", err, tt.err)
+			}
+			if !reflect.DeepEqual(args, tt.expected) {
+				t.Errorf("got arguments %q, expected %q", args, tt.expected)
+			}
+		})
+	}
+}
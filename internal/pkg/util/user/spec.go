@@ -0,0 +1,124 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package user
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxID is the maximum valid user or group ID, (uid_t)-1 is reserved.
+const maxID = 1<<32 - 2
+
+// parseID parses a numeric user or group ID.
+func parseID(s string) (uint32, bool) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || id > maxID {
+		return 0, false
+	}
+	return uint32(id), true
+}
+
+// dbEntries returns the colon separated fields of the entries of a
+// passwd or group file content.
+func dbEntries(content []byte, minFields int) [][]string {
+	var entries [][]string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) < minFields {
+			continue
+		}
+		entries = append(entries, fields)
+	}
+	return entries
+}
+
+// ResolveSpec resolves the user specification user[:group] into a user ID
+// and a list of group IDs with the primary group first, like docker run -u.
+// Names are resolved against the passwd and group file contents, which may
+// be nil when they are not available, in which case only numeric IDs are
+// accepted. Without group, the primary group of the user is used, or the
+// group with the same ID for a numeric user ID absent from passwd. The
+// groups listing the user as a member are added as supplementary groups
+// unless a group is specified.
+func ResolveSpec(spec string, passwd, group []byte) (uid uint32, gids []uint32, err error) {
+	kv := strings.SplitN(spec, ":", 2)
+	if kv[0] == "" {
+		return 0, nil, fmt.Errorf("invalid user specification %q: expected user[:group]", spec)
+	}
+
+	users := dbEntries(passwd, 4)
+	groups := dbEntries(group, 3)
+
+	name := ""
+	id, numeric := parseID(kv[0])
+	gid := id
+	for _, u := range users {
+		pwUID, ok := parseID(u[2])
+		if !ok || (u[0] != kv[0] && (!numeric || pwUID != id)) {
+			continue
+		}
+		if pwGID, ok := parseID(u[3]); ok {
+			name, id, gid, numeric = u[0], pwUID, pwGID, true
+			break
+		}
+	}
+	if !numeric {
+		if passwd == nil {
+			return 0, nil, fmt.Errorf("user %s can't be resolved without the container passwd file, use a numeric user ID", kv[0])
+		}
+		return 0, nil, fmt.Errorf("user %s not found in the container passwd file", kv[0])
+	}
+
+	if len(kv) == 2 {
+		g, ok := parseID(kv[1])
+		if !ok {
+			for _, gr := range groups {
+				if gr[0] != kv[1] {
+					continue
+				}
+				g, ok = parseID(gr[2])
+				break
+			}
+		}
+		if !ok {
+			if group == nil {
+				return 0, nil, fmt.Errorf("group %s can't be resolved without the container group file, use a numeric group ID", kv[1])
+			}
+			return 0, nil, fmt.Errorf("group %s not found in the container group file", kv[1])
+		}
+		return id, []uint32{g}, nil
+	}
+
+	gids = []uint32{gid}
+	if name == "" {
+		return id, gids, nil
+	}
+	for _, gr := range groups {
+		if len(gr) < 4 {
+			continue
+		}
+		g, ok := parseID(gr[2])
+		if !ok || g == gid {
+			continue
+		}
+		for _, member := range strings.Split(gr[3], ",") {
+			if strings.TrimSpace(member) == name {
+				gids = append(gids, g)
+				break
+			}
+		}
+	}
+	return id, gids, nil
+}
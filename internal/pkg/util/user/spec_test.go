@@ -0,0 +1,135 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package user
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveSpec(t *testing.T) {
+	passwd := []byte(`root:x:0:0:root:/root:/bin/sh
+daemon:x:2:2:daemon:/sbin:/sbin/nologin
+# comment
+invalid:x:abc:1::/:/bin/sh
+app:x:1000:1000:app:/home/app:/bin/sh
+`)
+	group := []byte(`root:x:0:root
+daemon:x:2:root,bin,daemon
+wheel:x:10:root,app
+app:x:1000:
+docker:x:999:app
+`)
+
+	tests := []struct {
+		name     string
+		spec     string
+		passwd   []byte
+		group    []byte
+		wantUID  uint32
+		wantGIDs []uint32
+		wantErr  bool
+	}{
+		{
+			name:     "user name",
+			spec:     "app",
+			passwd:   passwd,
+			group:    group,
+			wantUID:  1000,
+			wantGIDs: []uint32{1000, 10, 999},
+		},
+		{
+			name:     "user ID in passwd",
+			spec:     "2",
+			passwd:   passwd,
+			group:    group,
+			wantUID:  2,
+			wantGIDs: []uint32{2},
+		},
+		{
+			name:     "user ID not in passwd",
+			spec:     "4242",
+			passwd:   passwd,
+			group:    group,
+			wantUID:  4242,
+			wantGIDs: []uint32{4242},
+		},
+		{
+			name:     "user and group names",
+			spec:     "app:daemon",
+			passwd:   passwd,
+			group:    group,
+			wantUID:  1000,
+			wantGIDs: []uint32{2},
+		},
+		{
+			name:     "user and group IDs",
+			spec:     "4242:4343",
+			passwd:   passwd,
+			group:    group,
+			wantUID:  4242,
+			wantGIDs: []uint32{4343},
+		},
+		{
+			name:     "numeric IDs without files",
+			spec:     "4242:4343",
+			wantUID:  4242,
+			wantGIDs: []uint32{4343},
+		},
+		{
+			name:    "user name without files",
+			spec:    "app",
+			wantErr: true,
+		},
+		{
+			name:    "unknown user",
+			spec:    "nobody",
+			passwd:  passwd,
+			group:   group,
+			wantErr: true,
+		},
+		{
+			name:    "invalid user ID in passwd",
+			spec:    "invalid",
+			passwd:  passwd,
+			group:   group,
+			wantErr: true,
+		},
+		{
+			name:    "unknown group",
+			spec:    "app:nogroup",
+			passwd:  passwd,
+			group:   group,
+			wantErr: true,
+		},
+		{
+			name:    "out of range ID",
+			spec:    "4294967295",
+			passwd:  passwd,
+			group:   group,
+			wantErr: true,
+		},
+		{
+			name:    "empty user",
+			spec:    ":app",
+			passwd:  passwd,
+			group:   group,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uid, gids, err := ResolveSpec(tt.spec, tt.passwd, tt.group)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveSpec() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if uid != tt.wantUID || !reflect.DeepEqual(gids, tt.wantGIDs) {
+				t.Errorf("ResolveSpec() = %d, %v, want %d, %v", uid, gids, tt.wantUID, tt.wantGIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package volume manages named volumes, host directories of the current
+// user stored under a volume root directory and mounted into containers
+// by name.
+package volume
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/pkg/syfs"
+)
+
+const (
+	// DirEnv specifies the environment variable which can set the
+	// volume root directory.
+	DirEnv = "SINGULARITY_VOLUMEDIR"
+	// SubDirName is the name of the default volume root directory,
+	// relative to the singularity user configuration directory.
+	SubDirName = "volumes"
+)
+
+var (
+	// ErrNotFound is returned when a volume doesn't exist.
+	ErrNotFound = errors.New("no such volume")
+	// ErrExist is returned when creating a volume that already exists.
+	ErrExist = errors.New("volume already exists")
+
+	nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+// Volume describes a named volume.
+type Volume struct {
+	// Name is the volume name.
+	Name string
+	// Path is the host directory backing the volume.
+	Path string
+}
+
+// Root returns the volume root directory, set by DirEnv or located in
+// the singularity user configuration directory by default.
+func Root() string {
+	if dir := os.Getenv(DirEnv); dir != "" {
+		return dir
+	}
+	return filepath.Join(syfs.ConfigDir(), SubDirName)
+}
+
+// checkName returns an error if name is not a valid volume name.
+func checkName(name string) error {
+	if !nameRegexp.MatchString(name) {
+		return fmt.Errorf("invalid volume name %q: only alphanumeric characters, '_', '.' and '-' are allowed, starting with an alphanumeric character", name)
+	}
+	return nil
+}
+
+// checkOwner returns an error if the directory at path is not a directory
+// owned by the current user. Volumes are mounted with the privileges of
+// the current user, so directories of other users, possibly substituted
+// in a shared volume root, are never used.
+func checkOwner(path string) error {
+	fi, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is not owned by the current user", path)
+	}
+	return nil
+}
+
+// Create creates the volume name. The volume directory is only accessible
+// by the current user, including in a user namespace where the current
+// user is mapped to the container root user.
+func Create(name string) (*Volume, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+
+	root := Root()
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, fmt.Errorf("while creating volume root %s: %s", root, err)
+	}
+	if err := checkOwner(root); err != nil {
+		return nil, fmt.Errorf("invalid volume root: %s", err)
+	}
+
+	v := &Volume{Name: name, Path: filepath.Join(root, name)}
+	if err := os.Mkdir(v.Path, 0o700); os.IsExist(err) {
+		return nil, fmt.Errorf("%s: %w", name, ErrExist)
+	} else if err != nil {
+		return nil, fmt.Errorf("while creating volume %s: %s", name, err)
+	}
+	return v, nil
+}
+
+// Get returns the volume name.
+func Get(name string) (*Volume, error) {
+	if err := checkName(name); err != nil {
+		return nil, err
+	}
+
+	v := &Volume{Name: name, Path: filepath.Join(Root(), name)}
+	if err := checkOwner(v.Path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotFound)
+	} else if err != nil {
+		return nil, fmt.Errorf("invalid volume %s: %s", name, err)
+	}
+	return v, nil
+}
+
+// List returns the volumes sorted by name.
+func List() ([]*Volume, error) {
+	root := Root()
+
+	entries, err := ioutil.ReadDir(root)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("while reading volume root %s: %s", root, err)
+	}
+
+	var volumes []*Volume
+	for _, e := range entries {
+		if checkName(e.Name()) != nil || checkOwner(filepath.Join(root, e.Name())) != nil {
+			continue
+		}
+		volumes = append(volumes, &Volume{Name: e.Name(), Path: filepath.Join(root, e.Name())})
+	}
+	sort.Slice(volumes, func(i, j int) bool { return volumes[i].Name < volumes[j].Name })
+	return volumes, nil
+}
+
+// Remove removes the volume name and its content.
+func Remove(name string) error {
+	v, err := Get(name)
+	if err != nil {
+		return err
+	}
+	if err := fs.ForceRemoveAll(v.Path); err != nil {
+		return fmt.Errorf("while removing volume %s: %s", name, err)
+	}
+	return nil
+}
+
+// BindPath converts the volume specification name:dest[:options] into
+// the equivalent bind path specification src:dest[:options].
+func BindPath(spec string) (string, error) {
+	parts := strings.SplitN(spec, ":", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return "", fmt.Errorf("invalid volume specification %q: expected name:destination[:options]", spec)
+	}
+
+	v, err := Get(parts[0])
+	if err != nil {
+		return "", err
+	}
+	if strings.ContainsAny(v.Path, ":,") {
+		return "", fmt.Errorf("volume path %s contains a ':' or ',' character and can't be mounted", v.Path)
+	}
+	return v.Path + ":" + parts[1], nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package volume
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setupRoot(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "volume-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "volumes")
+	os.Setenv(DirEnv, root)
+	t.Cleanup(func() {
+		os.Unsetenv(DirEnv)
+		os.RemoveAll(dir)
+	})
+	return root
+}
+
+func TestVolume(t *testing.T) {
+	root := setupRoot(t)
+
+	if volumes, err := List(); err != nil || len(volumes) != 0 {
+		t.Fatalf("unexpected volumes %v (error: %v) with no volume root", volumes, err)
+	}
+
+	for _, name := range []string{"data", "cache.1", "a_b-c"} {
+		v, err := Create(name)
+		if err != nil {
+			t.Fatalf("unexpected error while creating volume %s: %s", name, err)
+		}
+		if v.Path != filepath.Join(root, name) {
+			t.Errorf("unexpected path %s for volume %s", v.Path, name)
+		}
+		fi, err := os.Stat(v.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fi.Mode().Perm() != 0o700 {
+			t.Errorf("unexpected mode %o for volume %s", fi.Mode().Perm(), name)
+		}
+	}
+
+	if _, err := Create("data"); !errors.Is(err, ErrExist) {
+		t.Errorf("expected ErrExist while creating existing volume, got %v", err)
+	}
+
+	for _, name := range []string{"", ".data", "-data", "da/ta", "da:ta", "../data"} {
+		if _, err := Create(name); err == nil {
+			t.Errorf("unexpected success while creating volume %q", name)
+		}
+	}
+
+	// files in the volume root are not volumes
+	if err := ioutil.WriteFile(filepath.Join(root, "file"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	volumes, err := List()
+	if err != nil {
+		t.Fatalf("unexpected error while listing volumes: %s", err)
+	}
+	var names []string
+	for _, v := range volumes {
+		names = append(names, v.Name)
+	}
+	if want := []string{"a_b-c", "cache.1", "data"}; len(names) != len(want) || names[0] != want[0] || names[1] != want[1] || names[2] != want[2] {
+		t.Errorf("unexpected volumes %v, want %v", names, want)
+	}
+
+	if _, err := Get("file"); err == nil {
+		t.Errorf("unexpected success while getting a file as volume")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(root, "data", "content"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove("data"); err != nil {
+		t.Fatalf("unexpected error while removing volume: %s", err)
+	}
+	if _, err := Get("data"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for removed volume, got %v", err)
+	}
+	if err := Remove("data"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound while removing missing volume, got %v", err)
+	}
+}
+
+func TestBindPath(t *testing.T) {
+	root := setupRoot(t)
+
+	if _, err := Create("data"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "destination",
+			spec: "data:/data",
+			want: filepath.Join(root, "data") + ":/data",
+		},
+		{
+			name: "options",
+			spec: "data:/data:ro",
+			want: filepath.Join(root, "data") + ":/data:ro",
+		},
+		{
+			name:    "no destination",
+			spec:    "data",
+			wantErr: true,
+		},
+		{
+			name:    "empty destination",
+			spec:    "data:",
+			wantErr: true,
+		},
+		{
+			name:    "missing volume",
+			spec:    "missing:/data",
+			wantErr: true,
+		},
+		{
+			name:    "invalid name",
+			spec:    "/tmp:/data",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := BindPath(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BindPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("BindPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
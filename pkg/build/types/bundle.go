@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	ocitypes "github.com/containers/image/v5/types"
 	scskeyclient "github.com/sylabs/scs-key-client/client"
@@ -49,6 +50,9 @@ type Options struct {
 	KeyServerOpts []scskeyclient.Option
 	// contains docker credentials if specified.
 	DockerAuthConfig *ocitypes.DockerAuthConfig
+	// DockerAuthFile is the path of the credential file used to authenticate
+	// against registries, the default docker configuration file is used if empty.
+	DockerAuthFile string `json:"dockerAuthFile"`
 	// EncryptionKeyInfo specifies the key used for filesystem
 	// encryption if applicable.
 	// A nil value indicates encryption should not occur.
@@ -72,9 +76,35 @@ type Options struct {
 	// to preserve <=3.4 behavior.
 	// TODO: Deprecate in 3.6, remove in 3.8
 	FixPerms bool
+	// FixPermsReport is the path of a JSON file recording the permission
+	// changes made by FixPerms, empty to not record them.
+	FixPermsReport string
 	// To warn when the above is needed, we need to know if the target of this
 	// bundle will be a sandbox
 	SandboxTarget bool
+	// NoDedup disables the detection and removal of duplicate files
+	// when creating the squashfs file system of a SIF image.
+	NoDedup bool
+	// Strip removes documentation, locale data and static libraries and
+	// strips debug symbols from the root filesystem after %post.
+	Strip bool
+	// StripRules is the path of the ruleset file used by Strip, the
+	// default ruleset is used if empty.
+	StripRules string
+	// FakerootShim runs %post with the fakeroot(1) preload library of the
+	// host, faking the privileged operations the user namespace doesn't
+	// allow, like mknod or chown to unmapped IDs.
+	FakerootShim bool
+	// PostTimeout is the longest time the %post and %test sections can
+	// run before their process group is killed, no timeout if zero.
+	PostTimeout time.Duration
+	// FetchFiles allows http(s) URL sources in %files, downloaded through
+	// the cache so that unchanged files are only downloaded once.
+	FetchFiles bool
+	// CommitOverlay is the path of a writable overlay, directory or EXT3
+	// image, whose changes are applied to the root filesystem once it's
+	// unpacked, empty to not apply any overlay.
+	CommitOverlay string
 }
 
 // NewEncryptedBundle creates an Encrypted Bundle environment.
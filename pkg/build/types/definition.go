@@ -10,6 +10,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
+	"strconv"
 	"strings"
 )
 
@@ -68,6 +70,47 @@ type Files struct {
 type FileTransport struct {
 	Src string `json:"source"`
 	Dst string `json:"destination"`
+	// Chown is the uid[:gid] ownership applied to the copied files, if set.
+	Chown string `json:"chown,omitempty"`
+	// Chmod is the octal mode applied to the copied files, if set.
+	Chmod string `json:"chmod,omitempty"`
+}
+
+// ParseChown parses a %files --chown value of the form uid[:gid], the group
+// ID defaults to the user ID.
+func ParseChown(s string) (uid, gid int, err error) {
+	ids := strings.SplitN(s, ":", 2)
+	uid, err = strconv.Atoi(ids[0])
+	if err != nil || uid < 0 {
+		return 0, 0, fmt.Errorf("invalid --chown value %q: must be a numeric uid[:gid]", s)
+	}
+	if len(ids) == 1 {
+		return uid, uid, nil
+	}
+	gid, err = strconv.Atoi(ids[1])
+	if err != nil || gid < 0 {
+		return 0, 0, fmt.Errorf("invalid --chown value %q: must be a numeric uid[:gid]", s)
+	}
+	return uid, gid, nil
+}
+
+// ParseChmod parses a %files --chmod octal mode value, e.g. 0644.
+func ParseChmod(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > 0o7777 {
+		return 0, fmt.Errorf("invalid --chmod value %q: must be an octal mode, e.g. 0644", s)
+	}
+	perm := os.FileMode(mode).Perm()
+	if mode&0o4000 != 0 {
+		perm |= os.ModeSetuid
+	}
+	if mode&0o2000 != 0 {
+		perm |= os.ModeSetgid
+	}
+	if mode&0o1000 != 0 {
+		perm |= os.ModeSticky
+	}
+	return perm, nil
 }
 
 // Script describes any script section of a definition.
@@ -143,7 +186,14 @@ func writeFilesIfExists(w io.Writer, f []Files) {
 			fmt.Fprintln(w)
 
 			for _, ft := range f.Files {
-				fmt.Fprintf(w, "\t%s\t%s\n", ft.Src, ft.Dst)
+				fmt.Fprint(w, "\t")
+				if ft.Chown != "" {
+					fmt.Fprintf(w, "--chown %s ", ft.Chown)
+				}
+				if ft.Chmod != "" {
+					fmt.Fprintf(w, "--chmod %s ", ft.Chmod)
+				}
+				fmt.Fprintf(w, "%s\t%s\n", ft.Src, ft.Dst)
 			}
 			fmt.Fprintln(w)
 		}
@@ -143,3 +143,57 @@ func TestNewDefinitionFromJSON(t *testing.T) {
 		t.Fatal("Invalid number of labels")
 	}
 }
+
+func TestParseChownChmod(t *testing.T) {
+	chownCases := []struct {
+		value    string
+		uid, gid int
+		wantErr  bool
+	}{
+		{value: "1000:100", uid: 1000, gid: 100},
+		{value: "1000", uid: 1000, gid: 1000},
+		{value: "0:0", uid: 0, gid: 0},
+		{value: "user:group", wantErr: true},
+		{value: "1000:", wantErr: true},
+		{value: "-1:0", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, c := range chownCases {
+		uid, gid, err := ParseChown(c.value)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("ParseChown(%q) succeeded with an invalid value", c.value)
+			}
+			continue
+		}
+		if err != nil || uid != c.uid || gid != c.gid {
+			t.Errorf("ParseChown(%q) = %d, %d, %v, want %d, %d", c.value, uid, gid, err, c.uid, c.gid)
+		}
+	}
+
+	chmodCases := []struct {
+		value   string
+		mode    os.FileMode
+		wantErr bool
+	}{
+		{value: "0644", mode: 0o644},
+		{value: "755", mode: 0o755},
+		{value: "4755", mode: 0o755 | os.ModeSetuid},
+		{value: "1777", mode: 0o777 | os.ModeSticky},
+		{value: "0999", wantErr: true},
+		{value: "17777", wantErr: true},
+		{value: "u+x", wantErr: true},
+	}
+	for _, c := range chmodCases {
+		mode, err := ParseChmod(c.value)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("ParseChmod(%q) succeeded with an invalid value", c.value)
+			}
+			continue
+		}
+		if err != nil || mode != c.mode {
+			t.Errorf("ParseChmod(%q) = %v, %v, want %v", c.value, mode, err, c.mode)
+		}
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"os"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/sylabs/singularity/pkg/build/types"
@@ -35,7 +36,11 @@ type InvalidSectionError struct {
 }
 
 func (e *InvalidSectionError) Error() string {
-	return e.Err.Error() + ": " + strings.Join(e.Sections, ", ")
+	msg := e.Err.Error() + ": " + strings.Join(e.Sections, ", ")
+	if e.Err == errInvalidSection {
+		msg += " (recognized sections: " + strings.Join(recognizedSections(), ", ") + ")"
+	}
+	return msg
 }
 
 // IsInvalidSectionError returns a boolean indicating whether the error
@@ -132,6 +137,41 @@ func getSectionName(line string) string {
 	return lineSplit[0]
 }
 
+// parseFileOptions parses the --chown and --chmod options, given as
+// "--opt value" or "--opt=value", leading the fields of a %files line, and
+// returns the remaining fields.
+func parseFileOptions(fields []string) (types.FileTransport, []string, error) {
+	var ft types.FileTransport
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+		opt := strings.SplitN(fields[0], "=", 2)
+		if len(opt) == 1 {
+			if len(fields) < 2 {
+				return ft, nil, fmt.Errorf("missing value for %s", opt[0])
+			}
+			opt = append(opt, fields[1])
+			fields = fields[2:]
+		} else {
+			fields = fields[1:]
+		}
+
+		switch opt[0] {
+		case "--chown":
+			if _, _, err := types.ParseChown(opt[1]); err != nil {
+				return ft, nil, err
+			}
+			ft.Chown = opt[1]
+		case "--chmod":
+			if _, err := types.ParseChmod(opt[1]); err != nil {
+				return ft, nil, err
+			}
+			ft.Chmod = opt[1]
+		default:
+			return ft, nil, fmt.Errorf("unknown option %s, only --chown and --chmod are supported", opt[0])
+		}
+	}
+	return ft, fields, nil
+}
+
 // parseTokenSection into appropriate components to be placed into a types.Script struct
 func parseTokenSection(tok string, sections map[string]*types.Script, files *[]types.Files, appOrder *[]string) error {
 	split := strings.SplitN(tok, "\n", 2)
@@ -159,16 +199,22 @@ func parseTokenSection(tok string, sections map[string]*types.Script, files *[]t
 			var src, dst string
 			// Split at space, but not within double quotes
 			lineSubs := fileSplitter.FindAllString(line, -1)
-			if len(lineSubs) < 2 {
+			ft, lineSubs, err := parseFileOptions(lineSubs)
+			if err != nil {
+				return fmt.Errorf("%%files line %q: %s", line, err)
+			}
+			if len(lineSubs) == 0 {
+				return fmt.Errorf("%%files line %q: no source file", line)
+			} else if len(lineSubs) < 2 {
 				src = strings.TrimSpace(lineSubs[0])
 				dst = ""
 			} else {
 				src = strings.TrimSpace(lineSubs[0])
 				dst = strings.TrimSpace(lineSubs[1])
 			}
-			src = strings.Trim(src, "\"")
-			dst = strings.Trim(dst, "\"")
-			f.Files = append(f.Files, types.FileTransport{Src: src, Dst: dst})
+			ft.Src = strings.Trim(src, "\"")
+			ft.Dst = strings.Trim(dst, "\"")
+			f.Files = append(f.Files, ft)
 		}
 
 		// look through existing files and append to them if they already exist
@@ -401,7 +447,7 @@ func doHeader(h string, d *types.Definition) error {
 				_, ok = validHeaders[tmpKey]
 			}
 			if !ok {
-				return fmt.Errorf("invalid header keyword found: %s", key)
+				return fmt.Errorf("invalid header keyword found: %s (recognized keywords: %s)", key, strings.Join(recognizedHeaders(), ", "))
 			}
 		}
 		header[key] = val
@@ -563,6 +609,31 @@ var appSections = map[string]bool{
 	"apprun":     true,
 }
 
+// recognizedSections returns the sorted list of the section names a
+// definition file could contain, as they appear in the definition file.
+func recognizedSections() []string {
+	names := make([]string, 0, len(validSections)+len(appSections))
+	for s := range validSections {
+		names = append(names, "%"+s)
+	}
+	for s := range appSections {
+		names = append(names, "%"+s+" <app>")
+	}
+	sort.Strings(names)
+	return names
+}
+
+// recognizedHeaders returns the sorted list of the header keywords a
+// definition file could contain.
+func recognizedHeaders() []string {
+	names := make([]string, 0, len(validHeaders))
+	for h := range validHeaders {
+		names = append(names, strings.Replace(h, "&n", "<n>", 1))
+	}
+	sort.Strings(names)
+	return names
+}
+
 // validHeaders just contains a list of all the valid headers a definition file
 // could contain. If any others are found, an error will generate
 var validHeaders = map[string]bool{
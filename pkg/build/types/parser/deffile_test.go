@@ -188,6 +188,7 @@ func TestParseDefinitionFile(t *testing.T) {
 		{"SectionArgs", "testdata_good/sectionargs/sectionargs", "testdata_good/sectionargs/sectionargs.json"},
 		{"MultipleFiles", "testdata_good/multiplefiles/multiplefiles", "testdata_good/multiplefiles/multiplefiles.json"},
 		{"QuotedFiles", "testdata_good/quotedfiles/quotedfiles", "testdata_good/quotedfiles/quotedfiles.json"},
+		{"FilesOptions", "testdata_good/filesoptions/filesoptions", "testdata_good/filesoptions/filesoptions.json"},
 		{"Shebang", "testdata_good/shebang/shebang", "testdata_good/shebang/shebang.json"},
 	}
 
@@ -236,6 +237,7 @@ func TestParseDefinitionFileFailure(t *testing.T) {
 		{"JSONInput2", "testdata_bad/json_input_2"},
 		{"Empty", "testdata_bad/empty"},
 		{"EmptyComments", "testdata_bad/emptycomments"},
+		{"BadFilesOption", "testdata_bad/bad_files_option"},
 	}
 
 	for _, tt := range tests {
@@ -267,7 +269,8 @@ func TestIsInvalidSectionErrors(t *testing.T) {
 	}
 
 	// Test of Error()
-	expectedStr1 := "invalid section(s) specified: " + strings.Join(dummyKeys, ", ")
+	expectedStr1 := "invalid section(s) specified: " + strings.Join(dummyKeys, ", ") +
+		" (recognized sections: " + strings.Join(recognizedSections(), ", ") + ")"
 	expectedStr2 := "Empty definition file: " + strings.Join(dummyKeys, ", ")
 	if myValidErr1.Error() != expectedStr1 || myValidErr2.Error() != expectedStr2 {
 		t.Fatal("unexpecter result from Error()", myValidErr1.Error())
@@ -329,6 +332,17 @@ func TestDoHeader(t *testing.T) {
 			t.Fatal("Test succeeded while supposed to fail")
 		}
 	}
+
+	// Misspelled keywords are reported along with the recognized ones
+	myerr := doHeader("bootstrap: docker\nfomr: alpine", myData)
+	if myerr == nil {
+		t.Fatal("Test succeeded while supposed to fail")
+	}
+	for _, s := range []string{"fomr", "from", "otherurl<n>"} {
+		if !strings.Contains(myerr.Error(), s) {
+			t.Errorf("error %q doesn't mention %s", myerr, s)
+		}
+	}
 }
 
 func TestIsValidDefinition(t *testing.T) {
@@ -0,0 +1,651 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/util/shell"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// archiveSuffixes are the file suffixes of the archives that ADD would
+// extract in the container.
+var archiveSuffixes = []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar.xz", ".txz"}
+
+// dockerfileCopy describes a COPY or ADD instruction translated into
+// a %files section entry.
+type dockerfileCopy struct {
+	from string
+	src  string
+	dst  string
+}
+
+// dockerfileStage holds the translation of a Dockerfile build stage.
+type dockerfileStage struct {
+	name       string
+	from       string
+	vars       map[string]string
+	workdir    string
+	files      []dockerfileCopy
+	post       []string
+	env        []string
+	labels     [][2]string
+	entrypoint []string
+	cmd        []string
+	runscript  bool
+	// ran is set once a RUN instruction is translated
+	ran bool
+}
+
+// dockerfileTranslator translates a Dockerfile into a definition file.
+type dockerfileTranslator struct {
+	context    string
+	globalVars map[string]string
+	stages     []*dockerfileStage
+}
+
+// ParseDockerfile translates the common subset of Dockerfile instructions
+// (FROM, RUN, COPY, ADD, ENV, ARG, WORKDIR, LABEL, ENTRYPOINT and CMD) read
+// from r into definitions, one per build stage. Relative COPY and ADD sources
+// are resolved in the contextDir directory. An error is returned for the
+// instructions that can't be translated, instructions describing runtime
+// metadata without equivalent are ignored with a warning.
+func ParseDockerfile(r io.Reader, contextDir string) ([]types.Definition, error) {
+	t := &dockerfileTranslator{
+		context:    contextDir,
+		globalVars: make(map[string]string),
+	}
+
+	if _, err := os.Stat(filepath.Join(contextDir, ".dockerignore")); err == nil {
+		sylog.Warningf(".dockerignore file is not supported and is ignored")
+	}
+
+	lines, err := dockerfileInstructions(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lines {
+		if err := t.translate(l.text); err != nil {
+			return nil, fmt.Errorf("Dockerfile line %d: %s", l.n, err)
+		}
+	}
+	if len(t.stages) == 0 {
+		return nil, fmt.Errorf("no FROM instruction found in Dockerfile")
+	}
+
+	var buf bytes.Buffer
+	for _, s := range t.stages {
+		s.write(&buf)
+	}
+	return All(&buf)
+}
+
+type dockerfileLine struct {
+	n    int
+	text string
+}
+
+// dockerfileInstructions returns the instructions of a Dockerfile along
+// with their line number, joining continuation lines and skipping comments.
+func dockerfileInstructions(r io.Reader) ([]dockerfileLine, error) {
+	var lines []dockerfileLine
+	var cur strings.Builder
+	start := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") || (line == "" && cur.Len() == 0) {
+			continue
+		}
+		if cur.Len() == 0 {
+			start = n
+		}
+		if strings.HasSuffix(line, "\\") {
+			cur.WriteString(strings.TrimSuffix(line, "\\"))
+			continue
+		}
+		cur.WriteString(line)
+		lines = append(lines, dockerfileLine{n: start, text: strings.TrimSpace(cur.String())})
+		cur.Reset()
+	}
+	if cur.Len() > 0 {
+		lines = append(lines, dockerfileLine{n: start, text: strings.TrimSpace(cur.String())})
+	}
+	return lines, scanner.Err()
+}
+
+// translate translates a single Dockerfile instruction.
+func (t *dockerfileTranslator) translate(line string) error {
+	fields := strings.SplitN(line, " ", 2)
+	instruction := strings.ToUpper(fields[0])
+	args := ""
+	if len(fields) == 2 {
+		args = strings.TrimSpace(fields[1])
+	}
+
+	if strings.HasPrefix(args, "<<") {
+		return fmt.Errorf("%s with a heredoc is not supported", instruction)
+	}
+
+	if instruction == "FROM" {
+		return t.from(args)
+	}
+
+	var s *dockerfileStage
+	if len(t.stages) > 0 {
+		s = t.stages[len(t.stages)-1]
+	} else if instruction != "ARG" {
+		return fmt.Errorf("%s instruction found before FROM", instruction)
+	}
+
+	switch instruction {
+	case "ARG":
+		return t.arg(s, args)
+	case "RUN":
+		return s.run(args)
+	case "COPY", "ADD":
+		return t.copy(s, instruction, args)
+	case "ENV":
+		return s.setEnv(args)
+	case "WORKDIR":
+		return s.setWorkdir(args)
+	case "LABEL":
+		return s.label(args)
+	case "MAINTAINER":
+		s.labels = append(s.labels, [2]string{"maintainer", args})
+	case "ENTRYPOINT":
+		s.entrypoint = execForm(args)
+		// like docker, setting the entrypoint resets the command
+		s.cmd = nil
+		s.runscript = true
+	case "CMD":
+		s.cmd = execForm(args)
+		s.runscript = true
+	case "USER":
+		sylog.Warningf("USER instruction ignored, RUN instructions are executed as root and containers run as the calling user")
+	case "EXPOSE", "VOLUME", "STOPSIGNAL", "HEALTHCHECK":
+		sylog.Warningf("%s instruction has no equivalent and is ignored", instruction)
+	case "SHELL", "ONBUILD":
+		return fmt.Errorf("%s instruction is not supported", instruction)
+	default:
+		return fmt.Errorf("unknown instruction %s", instruction)
+	}
+	return nil
+}
+
+// from starts a new build stage.
+func (t *dockerfileTranslator) from(args string) error {
+	flags, words := instructionFlags(strings.Fields(args))
+	if len(flags) > 0 {
+		return fmt.Errorf("FROM --%s option is not supported", flags[0][0])
+	}
+
+	s := &dockerfileStage{
+		name:    strconv.Itoa(len(t.stages)),
+		vars:    make(map[string]string),
+		workdir: "/",
+	}
+	switch {
+	case len(words) == 3 && strings.EqualFold(words[1], "AS"):
+		s.name = strings.ToLower(words[2])
+	case len(words) != 1:
+		return fmt.Errorf("expected FROM <image> [AS <name>], got FROM %s", args)
+	}
+
+	s.from = expandVars(words[0], t.globalVars)
+	if t.stage(s.from) != nil {
+		return fmt.Errorf("FROM a previous build stage is not supported")
+	}
+	if t.stage(s.name) != nil {
+		return fmt.Errorf("duplicate build stage name %s", s.name)
+	}
+
+	t.stages = append(t.stages, s)
+	return nil
+}
+
+// stage returns the build stage with the name or index ref.
+func (t *dockerfileTranslator) stage(ref string) *dockerfileStage {
+	for i, s := range t.stages {
+		if s.name == ref || strconv.Itoa(i) == ref {
+			return s
+		}
+	}
+	return nil
+}
+
+// arg declares a build argument, only default values are supported.
+func (t *dockerfileTranslator) arg(s *dockerfileStage, args string) error {
+	words, err := splitWords(args)
+	if err != nil {
+		return err
+	}
+	for _, w := range words {
+		kv := strings.SplitN(w, "=", 2)
+		if s == nil {
+			// global arguments are only used in FROM instructions
+			if len(kv) == 2 {
+				t.globalVars[kv[0]] = kv[1]
+			}
+			continue
+		}
+		if len(kv) == 1 {
+			// a global argument can be consumed by a stage
+			v, ok := t.globalVars[kv[0]]
+			if !ok {
+				continue
+			}
+			kv = append(kv, v)
+		}
+		s.vars[kv[0]] = expandVars(kv[1], s.vars)
+		s.post = append(s.post, fmt.Sprintf("export %s=\"%s\"", kv[0], escapeValue(kv[1])))
+	}
+	return nil
+}
+
+// run adds a command to the %post section, each command is run by its own
+// shell like docker does.
+func (s *dockerfileStage) run(args string) error {
+	flags, _ := instructionFlags(strings.Fields(args))
+	if len(flags) > 0 {
+		return fmt.Errorf("RUN --%s option is not supported", flags[0][0])
+	}
+
+	if argv, ok := jsonArgs(args); ok {
+		s.post = append(s.post, shell.ArgsQuoted(argv))
+	} else {
+		s.post = append(s.post, "/bin/sh -c '"+shell.EscapeSingleQuotes(args)+"'")
+	}
+	s.ran = true
+	return nil
+}
+
+// copy adds files copied from the build context or a previous stage to
+// a %files section. Files are copied before the %post section is run, so
+// the files of a copy following a RUN instruction are staged in the
+// container, then moved to their destination in %post.
+func (t *dockerfileTranslator) copy(s *dockerfileStage, instruction, args string) error {
+	flags, words := instructionFlags(strings.Fields(args))
+	if argv, ok := jsonArgs(strings.Join(words, " ")); ok {
+		words = argv
+	}
+	if len(words) < 2 {
+		return fmt.Errorf("expected %s <src>... <dest>", instruction)
+	}
+
+	from := ""
+	for _, f := range flags {
+		switch {
+		case f[0] == "from" && instruction == "COPY":
+			// stage names are case insensitive
+			src := t.stage(strings.ToLower(f[1]))
+			if src == nil || src == s {
+				return fmt.Errorf("COPY --from=%s: copying from an image instead of a previous build stage is not supported", f[1])
+			}
+			from = src.name
+		case f[0] == "chown":
+			sylog.Warningf("%s --chown option ignored, files are owned by the build user", instruction)
+		default:
+			return fmt.Errorf("%s --%s option is not supported", instruction, f[0])
+		}
+	}
+
+	srcs := words[:len(words)-1]
+	dst := expandVars(words[len(words)-1], s.vars)
+	dirDst := strings.HasSuffix(dst, "/") || dst == "." || strings.HasSuffix(dst, "/.")
+	if !path.IsAbs(dst) {
+		dst = path.Join(s.workdir, dst)
+	}
+	if len(srcs) > 1 && !dirDst {
+		return fmt.Errorf("%s destination must be a directory ending with / when copying multiple sources", instruction)
+	}
+	if dirDst {
+		dst = strings.TrimSuffix(dst, "/") + "/"
+	}
+
+	for _, src := range srcs {
+		src = expandVars(src, s.vars)
+		if instruction == "ADD" {
+			if strings.Contains(src, "://") {
+				return fmt.Errorf("ADD from a URL is not supported, download the file with RUN instead")
+			}
+			for _, suffix := range archiveSuffixes {
+				if strings.HasSuffix(src, suffix) {
+					return fmt.Errorf("ADD of archive %s is not supported, extract it with RUN instead", src)
+				}
+			}
+		}
+
+		c := dockerfileCopy{from: from, src: src, dst: dst}
+		if from == "" {
+			// sources are relative to the build context and can't leave it
+			rel := filepath.Clean(filepath.Join("/", src))
+			c.src = filepath.Join(t.context, rel)
+			if fi, err := os.Stat(c.src); err == nil && fi.IsDir() {
+				// like docker, copy the directory content and not the directory itself
+				c.src = strings.TrimSuffix(c.src, "/") + "/."
+				c.dst = strings.TrimSuffix(dst, "/") + "/"
+			}
+		}
+		if strings.ContainsAny(c.src+c.dst, "\"\n") {
+			return fmt.Errorf("%s paths containing double quotes are not supported", instruction)
+		}
+		if s.ran {
+			s.post = append(s.post, s.stageCopy(&c))
+		}
+		s.files = append(s.files, c)
+	}
+	return nil
+}
+
+// stageCopy replaces the destination of c with a staging directory in the
+// container, and returns the %post command moving the staged files to the
+// original destination.
+func (s *dockerfileStage) stageCopy(c *dockerfileCopy) string {
+	staging := fmt.Sprintf("/.dockerfile-copy-%d", len(s.files))
+	dst := c.dst
+
+	// a directory destination receives the staging directory content
+	src, dir := staging+"/.", dst
+	c.dst = staging + "/"
+	if !strings.HasSuffix(dst, "/") {
+		c.dst = path.Join(staging, path.Base(dst))
+		src, dir = c.dst, path.Dir(dst)
+	}
+
+	return "mkdir -p " + shell.ArgsQuoted([]string{dir}) +
+		" && cp -fPR " + shell.ArgsQuoted([]string{src, dst}) +
+		" && rm -rf " + shell.ArgsQuoted([]string{staging})
+}
+
+// setEnv sets environment variables for the following RUN instructions
+// and the container runtime.
+func (s *dockerfileStage) setEnv(args string) error {
+	words, err := splitWords(args)
+	if err != nil {
+		return err
+	}
+
+	var kvs [][2]string
+	if len(words) > 0 && !strings.Contains(words[0], "=") {
+		// legacy ENV <key> <value> form
+		kv := strings.SplitN(args, " ", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("expected ENV <key>=<value>...")
+		}
+		value, err := splitWords(kv[1])
+		if err != nil {
+			return err
+		}
+		kvs = append(kvs, [2]string{kv[0], strings.Join(value, " ")})
+	} else {
+		for _, w := range words {
+			kv := strings.SplitN(w, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("expected ENV <key>=<value>..., got %s", w)
+			}
+			kvs = append(kvs, [2]string{kv[0], kv[1]})
+		}
+	}
+
+	for _, kv := range kvs {
+		// build arguments don't exist at runtime, the variables known
+		// by the stage are expanded in the exported value
+		value := expandKnownVars(kv[1], s.vars)
+		s.vars[kv[0]] = expandVars(kv[1], s.vars)
+		export := fmt.Sprintf("export %s=\"%s\"", kv[0], escapeValue(value))
+		s.post = append(s.post, export)
+		s.env = append(s.env, export)
+	}
+	return nil
+}
+
+// setWorkdir sets the working directory of the following instructions.
+func (s *dockerfileStage) setWorkdir(args string) error {
+	dir := expandVars(args, s.vars)
+	if dir == "" {
+		return fmt.Errorf("expected WORKDIR <path>")
+	}
+	if !path.IsAbs(dir) {
+		dir = path.Join(s.workdir, dir)
+	}
+	s.workdir = path.Clean(dir)
+
+	quoted := shell.ArgsQuoted([]string{s.workdir})
+	s.post = append(s.post, "mkdir -p "+quoted+" && cd "+quoted)
+	return nil
+}
+
+// label adds labels to the %labels section.
+func (s *dockerfileStage) label(args string) error {
+	words, err := splitWords(args)
+	if err != nil {
+		return err
+	}
+	for _, w := range words {
+		kv := strings.SplitN(w, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("expected LABEL <key>=<value>..., got %s", w)
+		}
+		s.labels = append(s.labels, [2]string{kv[0], expandVars(kv[1], s.vars)})
+	}
+	return nil
+}
+
+// write writes the stage as a definition file.
+func (s *dockerfileStage) write(w io.Writer) {
+	if s.from == "scratch" {
+		fmt.Fprintf(w, "Bootstrap: scratch\n")
+	} else {
+		fmt.Fprintf(w, "Bootstrap: docker\nFrom: %s\n", s.from)
+	}
+	fmt.Fprintf(w, "Stage: %s\n\n", s.name)
+
+	// host files first, then files of each stage, in instruction order
+	var sections []string
+	files := make(map[string][]string)
+	for _, c := range s.files {
+		args := ""
+		if c.from != "" {
+			args = " from " + c.from
+		}
+		if _, ok := files[args]; !ok {
+			sections = append(sections, args)
+		}
+		files[args] = append(files[args], fmt.Sprintf("\"%s\" \"%s\"", c.src, c.dst))
+	}
+	for _, args := range sections {
+		writeSection(w, "files"+args, files[args])
+	}
+
+	writeSection(w, "post", s.post)
+	writeSection(w, "environment", s.env)
+
+	if s.runscript {
+		var runscript []string
+		entrypoint := "exec"
+		if len(s.entrypoint) > 0 {
+			entrypoint += " " + shell.ArgsQuoted(s.entrypoint)
+		}
+		runscript = append(runscript,
+			"if [ \"$#\" -gt 0 ]; then",
+			"    "+entrypoint+" \"$@\"",
+			"fi",
+		)
+		if len(s.entrypoint) > 0 || len(s.cmd) > 0 {
+			runscript = append(runscript, strings.TrimSpace(entrypoint+" "+shell.ArgsQuoted(s.cmd)))
+		}
+		writeSection(w, "runscript", runscript)
+	}
+
+	var labels []string
+	for _, l := range s.labels {
+		labels = append(labels, l[0]+" "+l[1])
+	}
+	writeSection(w, "labels", labels)
+}
+
+// writeSection writes a definition file section if it has content.
+func writeSection(w io.Writer, name string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%%%s\n", name)
+	for _, l := range lines {
+		fmt.Fprintf(w, "    %s\n", l)
+	}
+	fmt.Fprintln(w)
+}
+
+// instructionFlags splits the leading --name=value flags of instruction
+// arguments from the remaining words.
+func instructionFlags(words []string) (flags [][2]string, remaining []string) {
+	for i, w := range words {
+		if !strings.HasPrefix(w, "--") {
+			return flags, words[i:]
+		}
+		kv := strings.SplitN(strings.TrimPrefix(w, "--"), "=", 2)
+		if len(kv) == 1 {
+			kv = append(kv, "")
+		}
+		flags = append(flags, [2]string{kv[0], kv[1]})
+	}
+	return flags, nil
+}
+
+// jsonArgs returns the arguments of an instruction in exec form.
+func jsonArgs(args string) ([]string, bool) {
+	if !strings.HasPrefix(args, "[") {
+		return nil, false
+	}
+	var argv []string
+	if err := json.Unmarshal([]byte(args), &argv); err != nil {
+		return nil, false
+	}
+	return argv, true
+}
+
+// execForm returns the arguments of an ENTRYPOINT or CMD instruction,
+// the shell form is run with /bin/sh -c.
+func execForm(args string) []string {
+	if argv, ok := jsonArgs(args); ok {
+		return argv
+	}
+	return []string{"/bin/sh", "-c", args}
+}
+
+// splitWords splits ENV, ARG and LABEL arguments into words, removing
+// quotes and escape characters but leaving variable references in place.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != '\'' && c == '\\' && i+1 < len(runes):
+			i++
+			if runes[i] == '$' {
+				cur.WriteRune('\\')
+			}
+			cur.WriteRune(runes[i])
+			inWord = true
+		case quote != 0:
+			cur.WriteRune(c)
+		case c == '"' || c == '\'':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %s", s)
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// expandVars substitutes $VAR, ${VAR}, ${VAR:-default} and ${VAR:+value}
+// references with the values of vars, unknown variables are empty.
+func expandVars(s string, vars map[string]string) string {
+	return os.Expand(s, func(name string) string {
+		if kv := strings.SplitN(name, ":-", 2); len(kv) == 2 {
+			if v := vars[kv[0]]; v != "" {
+				return v
+			}
+			return kv[1]
+		}
+		if kv := strings.SplitN(name, ":+", 2); len(kv) == 2 {
+			if vars[kv[0]] != "" {
+				return kv[1]
+			}
+			return ""
+		}
+		return vars[name]
+	})
+}
+
+// expandKnownVars expands the references to the variables of vars found in
+// s, other references, e.g. to the environment of the base image, and
+// escaped ones are kept to be expanded by the shell.
+func expandKnownVars(s string, vars map[string]string) string {
+	s = strings.ReplaceAll(s, `\$`, "\x00")
+	s = os.Expand(s, func(name string) string {
+		key := name
+		if i := strings.IndexAny(name, ":"); i >= 0 {
+			key = name[:i]
+		}
+		if _, ok := vars[key]; !ok {
+			return "${" + name + "}"
+		}
+		return escapeDollars(expandVars("${"+name+"}", vars))
+	})
+	return strings.ReplaceAll(s, "\x00", `\$`)
+}
+
+// escapeDollars escapes the $ characters of an expanded value, so that it's
+// not expanded again by the shell.
+func escapeDollars(s string) string {
+	return strings.ReplaceAll(s, "$", `\$`)
+}
+
+// escapeValue escapes a value for a double quoted shell string, variable
+// references are kept to be expanded by the shell.
+func escapeValue(s string) string {
+	s = strings.ReplaceAll(s, `\$`, "\x00")
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "`", "\\`")
+	return strings.ReplaceAll(s, "\x00", `\$`)
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+const testDockerfile = `# syntax=docker/dockerfile:1
+ARG VERSION=3.15
+FROM alpine:${VERSION} AS Builder
+ARG VERSION
+WORKDIR /src
+COPY main.c .
+RUN apk add gcc musl-dev && \
+    gcc -o hello main.c
+RUN ["echo", "built $VERSION"]
+
+FROM alpine:3.15
+ARG RELEASE=stable
+LABEL org.opencontainers.image.title="hello world" version=1
+ENV GREETING="hello world" PATH=/app:$PATH
+ENV LEGACY value with spaces
+ENV CHANNEL=$RELEASE-$ARCH PRICE=\$5
+COPY --from=BUILDER /src/hello /app/
+COPY static /app/static
+EXPOSE 8080
+ENTRYPOINT ["/app/hello"]
+CMD ["--greeting", "$GREETING"]
+`
+
+func TestParseDockerfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dockerfile-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.c"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "static"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	defs, err := ParseDockerfile(strings.NewReader(testDockerfile), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(defs) != 2 {
+		t.Fatalf("expected 2 stages, got %d", len(defs))
+	}
+
+	builder, final := defs[0], defs[1]
+
+	wantHeader := map[string]string{"bootstrap": "docker", "from": "alpine:3.15", "stage": "builder"}
+	if !reflect.DeepEqual(builder.Header, wantHeader) {
+		t.Errorf("unexpected builder header %v, want %v", builder.Header, wantHeader)
+	}
+	wantFiles := []types.Files{{Files: []types.FileTransport{{Src: filepath.Join(dir, "main.c"), Dst: "/src/"}}}}
+	if !reflect.DeepEqual(builder.BuildData.Files, wantFiles) {
+		t.Errorf("unexpected builder files %v, want %v", builder.BuildData.Files, wantFiles)
+	}
+	for _, s := range []string{
+		`export VERSION="3.15"`,
+		`mkdir -p "/src" && cd "/src"`,
+		`/bin/sh -c 'apk add gcc musl-dev && gcc -o hello main.c'`,
+		`"echo" "built \$VERSION"`,
+	} {
+		if !strings.Contains(builder.BuildData.Post.Script, s) {
+			t.Errorf("builder %%post section doesn't contain %q:\n%s", s, builder.BuildData.Post.Script)
+		}
+	}
+
+	wantFiles = []types.Files{
+		{Args: "from builder", Files: []types.FileTransport{{Src: "/src/hello", Dst: "/app/"}}},
+		{Files: []types.FileTransport{{Src: filepath.Join(dir, "static") + "/.", Dst: "/app/static/"}}},
+	}
+	if !reflect.DeepEqual(final.BuildData.Files, wantFiles) {
+		t.Errorf("unexpected files %v, want %v", final.BuildData.Files, wantFiles)
+	}
+	for _, s := range []string{
+		`export GREETING="hello world"`,
+		`export PATH="/app:${PATH}"`,
+		`export LEGACY="value with spaces"`,
+		`export CHANNEL="stable-${ARCH}"`,
+		`export PRICE="\$5"`,
+	} {
+		if !strings.Contains(final.ImageData.Environment.Script, s) {
+			t.Errorf("%%environment section doesn't contain %q:\n%s", s, final.ImageData.Environment.Script)
+		}
+	}
+	for _, s := range []string{
+		`exec "/app/hello" "$@"`,
+		`exec "/app/hello" "--greeting" "\$GREETING"`,
+	} {
+		if !strings.Contains(final.ImageData.Runscript.Script, s) {
+			t.Errorf("%%runscript section doesn't contain %q:\n%s", s, final.ImageData.Runscript.Script)
+		}
+	}
+	wantLabels := map[string]string{"org.opencontainers.image.title": "hello world", "version": "1"}
+	if !reflect.DeepEqual(final.ImageData.Labels, wantLabels) {
+		t.Errorf("unexpected labels %v, want %v", final.ImageData.Labels, wantLabels)
+	}
+}
+
+func TestParseDockerfileCopyAfterRun(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dockerfile-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, f := range []string{"before", "app.conf"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "static"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	dockerfile := `FROM alpine:3.15
+COPY before /opt/
+RUN adduser -D app
+COPY app.conf /etc/app.conf
+COPY static /srv/
+RUN chown -R app /srv
+`
+	defs, err := ParseDockerfile(strings.NewReader(dockerfile), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(defs) != 1 {
+		t.Fatalf("expected 1 stage, got %d", len(defs))
+	}
+
+	// the copies following a RUN instruction are staged by %files
+	wantFiles := []types.Files{{Files: []types.FileTransport{
+		{Src: filepath.Join(dir, "before"), Dst: "/opt/"},
+		{Src: filepath.Join(dir, "app.conf"), Dst: "/.dockerfile-copy-1/app.conf"},
+		{Src: filepath.Join(dir, "static") + "/.", Dst: "/.dockerfile-copy-2/"},
+	}}}
+	if !reflect.DeepEqual(defs[0].BuildData.Files, wantFiles) {
+		t.Errorf("unexpected files %v, want %v", defs[0].BuildData.Files, wantFiles)
+	}
+
+	// and moved to their destination in instruction order
+	post := defs[0].BuildData.Post.Script
+	last := -1
+	for _, s := range []string{
+		`/bin/sh -c 'adduser -D app'`,
+		`mkdir -p "/etc" && cp -fPR "/.dockerfile-copy-1/app.conf" "/etc/app.conf" && rm -rf "/.dockerfile-copy-1"`,
+		`mkdir -p "/srv/" && cp -fPR "/.dockerfile-copy-2/." "/srv/" && rm -rf "/.dockerfile-copy-2"`,
+		`/bin/sh -c 'chown -R app /srv'`,
+	} {
+		i := strings.Index(post, s)
+		if i < 0 {
+			t.Errorf("%%post section doesn't contain %q:\n%s", s, post)
+		} else if i < last {
+			t.Errorf("%%post section doesn't contain %q in instruction order:\n%s", s, post)
+		} else {
+			last = i
+		}
+	}
+}
+
+func TestParseDockerfileErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerfile string
+	}{
+		{"NoFrom", "RUN true\n"},
+		{"Empty", "# comment\n"},
+		{"Shell", "FROM alpine\nSHELL [\"/bin/bash\", \"-c\"]\n"},
+		{"Onbuild", "FROM alpine\nONBUILD RUN true\n"},
+		{"Unknown", "FROM alpine\nINSTALL gcc\n"},
+		{"Platform", "FROM --platform=linux/arm64 alpine\n"},
+		{"FromStage", "FROM alpine AS base\nFROM base\n"},
+		{"RunMount", "FROM alpine\nRUN --mount=type=cache,target=/var/cache true\n"},
+		{"Heredoc", "FROM alpine\nRUN <<EOF\ntrue\nEOF\n"},
+		{"CopyFromImage", "FROM alpine\nCOPY --from=busybox /bin/busybox /bin/\n"},
+		{"CopyChmod", "FROM alpine\nCOPY --chmod=755 run.sh /\n"},
+		{"CopyMultipleToFile", "FROM alpine\nCOPY a b /dest\n"},
+		{"AddURL", "FROM alpine\nADD https://example.com/file /\n"},
+		{"AddArchive", "FROM alpine\nADD rootfs.tar.gz /\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseDockerfile(strings.NewReader(tt.dockerfile), "/"); err == nil {
+				t.Errorf("unexpected success")
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// includeKeyword is the section argument requesting the section content to be
+// read from a file, e.g. "%runscript include ./run.sh".
+const includeKeyword = "include"
+
+// ResolveIncludes returns raw with the content of the files referenced by
+// section include directives inlined, a directive has the form:
+//
+//     %<section> include <path>
+//     %<app section> <app name> include <path>
+//
+// Relative paths are resolved from the directory of the definition file
+// path. Included files may themselves contain include directives, an error
+// is returned for recursive includes.
+func ResolveIncludes(raw []byte, path string) ([]byte, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("while resolving definition file path %s: %s", path, err)
+	}
+	return resolveIncludes(raw, abs, []string{abs})
+}
+
+// ReadDefinitionFile returns the content of the definition file at path,
+// with section include directives resolved.
+func ReadDefinitionFile(path string) ([]byte, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ResolveIncludes(raw, path)
+}
+
+func resolveIncludes(raw []byte, path string, stack []string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	for _, line := range strings.SplitAfter(string(raw), "\n") {
+		section, include, err := getIncludeDirective(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		} else if include == "" {
+			buf.WriteString(line)
+			continue
+		}
+
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		include = filepath.Clean(include)
+		for _, p := range stack {
+			if p == include {
+				return nil, fmt.Errorf("%s: recursive include of %s", path, include)
+			}
+		}
+
+		content, err := ioutil.ReadFile(include)
+		if err != nil {
+			return nil, fmt.Errorf("%s: while reading %%%s include: %s", path, section, err)
+		}
+		content, err = resolveIncludes(content, include, append(stack, include))
+		if err != nil {
+			return nil, err
+		}
+
+		buf.WriteString("%" + section + "\n")
+		buf.Write(content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// getIncludeDirective returns the section, including the app name for app
+// sections, and the path of the file to include if line is an include
+// directive.
+func getIncludeDirective(line string) (string, string, error) {
+	if !strings.HasPrefix(line, "%") {
+		return "", "", nil
+	}
+
+	fields := strings.Fields(strings.TrimPrefix(line, "%"))
+	if len(fields) < 3 || fields[len(fields)-2] != includeKeyword {
+		return "", "", nil
+	}
+
+	name := strings.ToLower(fields[0])
+	switch {
+	case name == "files" || name == "appfiles":
+		return "", "", fmt.Errorf("%%%s sections can't be included", name)
+	case validSections[name] && len(fields) == 3:
+	case appSections[name] && len(fields) == 4:
+	default:
+		return "", "", nil
+	}
+
+	return strings.Join(fields[:len(fields)-2], " "), fields[len(fields)-1], nil
+}
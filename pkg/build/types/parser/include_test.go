@@ -0,0 +1,125 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"run.sh":       "echo run\n",
+		"sub/app.sh":   "echo app",
+		"sub/post.sh":  "%post include ../run.sh\n",
+		"self.sh":      "%runscript include self.sh\n",
+		"loop/a.sh":    "%test include b.sh\n",
+		"loop/b.sh":    "%test include a.sh\n",
+		"Singularity":  "",
+		"sub/empty.sh": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %s", path, err)
+		}
+	}
+	defPath := filepath.Join(dir, "Singularity")
+
+	tests := []struct {
+		name        string
+		raw         string
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "NoInclude",
+			raw:      "Bootstrap: docker\nFrom: alpine\n\n%runscript\n    echo include run.sh\n",
+			expected: "Bootstrap: docker\nFrom: alpine\n\n%runscript\n    echo include run.sh\n",
+		},
+		{
+			name:     "Runscript",
+			raw:      "Bootstrap: docker\n%runscript include ./run.sh\n%test\n    true\n",
+			expected: "Bootstrap: docker\n%runscript\necho run\n%test\n    true\n",
+		},
+		{
+			name:     "AppSectionNoTrailingNewline",
+			raw:      "%apprun foo include sub/app.sh\n%post\n",
+			expected: "%apprun foo\necho app\n%post\n",
+		},
+		{
+			name:     "AbsolutePath",
+			raw:      "%help include " + filepath.Join(dir, "run.sh"),
+			expected: "%help\necho run\n",
+		},
+		{
+			name:     "Nested",
+			raw:      "%runscript include sub/post.sh\n",
+			expected: "%runscript\n%post\necho run\n",
+		},
+		{
+			name:     "EmptyFile",
+			raw:      "%runscript include sub/empty.sh\n",
+			expected: "%runscript\n",
+		},
+		{
+			name:     "ArgsNotInclude",
+			raw:      "%post -c /bin/sh\n",
+			expected: "%post -c /bin/sh\n",
+		},
+		{
+			name:        "Files",
+			raw:         "%files include run.sh\n",
+			expectError: true,
+		},
+		{
+			name:        "Missing",
+			raw:         "%runscript include missing.sh\n",
+			expectError: true,
+		},
+		{
+			name:        "Self",
+			raw:         "%runscript include self.sh\n",
+			expectError: true,
+		},
+		{
+			name:        "Loop",
+			raw:         "%test include loop/a.sh\n",
+			expectError: true,
+		},
+		{
+			name:        "DefinitionItself",
+			raw:         "%runscript include Singularity\n",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := ResolveIncludes([]byte(tt.raw), defPath)
+			if err != nil && !tt.expectError {
+				t.Fatalf("unexpected error: %s", err)
+			} else if err == nil && tt.expectError {
+				t.Fatalf("unexpected success")
+			}
+			if !tt.expectError && !bytes.Equal(raw, []byte(tt.expected)) {
+				t.Errorf("unexpected result %q, expected %q", raw, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,370 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LintSeverity is the severity of an issue found in a definition file.
+type LintSeverity int
+
+const (
+	// LintWarning reports a likely mistake which doesn't make the build fail.
+	LintWarning LintSeverity = iota
+	// LintError reports a problem which makes the build fail.
+	LintError
+)
+
+func (s LintSeverity) String() string {
+	if s == LintError {
+		return "error"
+	}
+	return "warning"
+}
+
+// LintIssue is an issue found in a definition file by Lint.
+type LintIssue struct {
+	// Line is the line of the issue, starting at 1, or 0 for an issue
+	// which isn't related to a specific line.
+	Line     int
+	Severity LintSeverity
+	Message  string
+}
+
+// bootstrapHeaders lists the bootstrap agents, with the header keywords
+// they require. It must be kept in sync with the agents handled by
+// NewConveyorPacker in internal/pkg/build/conveyorPacker.go.
+var bootstrapHeaders = map[string][]string{
+	"library":        {"from"},
+	"oras":           {"from"},
+	"shub":           {"from"},
+	"docker":         {"from"},
+	"docker-archive": {"from"},
+	"docker-daemon":  {"from"},
+	"oci":            {"from"},
+	"oci-archive":    {"from"},
+	"localimage":     {"from"},
+	"busybox":        {"mirrorurl"},
+	"debootstrap":    {"mirrorurl", "osversion"},
+	"yum":            {"mirrorurl"},
+	"arch":           nil,
+	"zypper":         nil,
+	"scratch":        nil,
+}
+
+// headerNames maps the required header keywords to their documented
+// spelling.
+var headerNames = map[string]string{
+	"from":      "From",
+	"mirrorurl": "MirrorURL",
+	"osversion": "OSVersion",
+}
+
+// stageStartRegexp matches the header line starting a build stage, as
+// split by All.
+var stageStartRegexp = regexp.MustCompile(`(?i)^bootstrap:`)
+
+// osVersionRegexp matches the OS version placeholder of yum mirror URLs.
+var osVersionRegexp = regexp.MustCompile(`(?i)%{OSVERSION}`)
+
+type lintSection struct {
+	line    int
+	name    string
+	args    []string
+	include bool
+	body    []int
+}
+
+type lintStage struct {
+	line        int
+	headers     map[string]string
+	headerLines map[string]int
+	sections    map[string]int
+}
+
+type linter struct {
+	dir    string
+	lines  []string
+	issues []LintIssue
+	stages []string
+}
+
+func (l *linter) add(line int, severity LintSeverity, format string, a ...interface{}) {
+	l.issues = append(l.issues, LintIssue{Line: line, Severity: severity, Message: fmt.Sprintf(format, a...)})
+}
+
+// Lint checks the content raw of the definition file at path, and returns
+// the issues found, sorted by line. Include directives are resolved from
+// the definition file directory, and %files sources from the current
+// directory, as done by build.
+func Lint(raw []byte, path string) []LintIssue {
+	l := &linter{
+		dir:   filepath.Dir(path),
+		lines: strings.Split(string(raw), "\n"),
+	}
+
+	var stage *lintStage
+	var section *lintSection
+	inHeader := true
+
+	for i, line := range l.lines {
+		n := i + 1
+		if stageStartRegexp.MatchString(line) || stage == nil {
+			if stage != nil && (len(stage.headers) > 0 || len(stage.sections) > 0) {
+				l.endSection(section)
+				l.endStage(stage)
+				stage = nil
+			}
+			if stage == nil {
+				stage = &lintStage{
+					line:        n,
+					headers:     make(map[string]string),
+					headerLines: make(map[string]int),
+					sections:    make(map[string]int),
+				}
+			}
+			section = nil
+			inHeader = true
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) > 0 && strings.HasPrefix(fields[0], "%"):
+			l.endSection(section)
+			section = l.startSection(stage, n, fields)
+			inHeader = false
+		case inHeader:
+			l.headerLine(stage, n, line)
+		case section != nil:
+			section.body = append(section.body, n)
+		}
+	}
+	l.endSection(section)
+	l.endStage(stage)
+
+	// report parsing errors not found by the checks above
+	if !hasLintErrors(l.issues) {
+		content, err := ResolveIncludes(raw, path)
+		if err == nil {
+			_, err = All(bytes.NewReader(content))
+		}
+		if err != nil {
+			l.add(0, LintError, "%s", err)
+		}
+	}
+
+	sort.SliceStable(l.issues, func(i, j int) bool {
+		return l.issues[i].Line < l.issues[j].Line
+	})
+	return l.issues
+}
+
+func hasLintErrors(issues []LintIssue) bool {
+	for _, i := range issues {
+		if i.Severity == LintError {
+			return true
+		}
+	}
+	return false
+}
+
+// headerLine checks the header line n of stage.
+func (l *linter) headerLine(stage *lintStage, n int, line string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return
+	}
+	// values continued on the next lines are checked as a whole
+	if n > 1 && strings.HasSuffix(strings.TrimSpace(strings.Split(l.lines[n-2], "#")[0]), "\\") {
+		return
+	}
+
+	kv := strings.SplitN(strings.Split(line, "#")[0], ":", 2)
+	if len(kv) == 1 {
+		l.add(n, LintError, "header line %q has no value, expected <keyword>: <value>", line)
+		return
+	}
+	key, val := strings.ToLower(strings.TrimSpace(kv[0])), strings.TrimSpace(kv[1])
+
+	known := validHeaders[key]
+	if !known {
+		if tmpKey := regexp.MustCompile(`\d+$`).ReplaceAllString(key, "&n"); tmpKey != key {
+			known = validHeaders[tmpKey]
+		}
+	}
+	if !known {
+		l.add(n, LintError, "unknown header keyword %s (recognized keywords: %s)", key, strings.Join(recognizedHeaders(), ", "))
+		return
+	}
+	if val == "" || val == "\\" {
+		l.add(n, LintError, "empty value for header keyword %s", key)
+	}
+	if prev, ok := stage.headerLines[key]; ok {
+		l.add(n, LintWarning, "header keyword %s already set on line %d, this value overrides it", key, prev)
+	}
+	stage.headers[key] = strings.TrimSuffix(val, "\\")
+	stage.headerLines[key] = n
+}
+
+func (l *linter) endStage(stage *lintStage) {
+	if stage == nil || (len(stage.headers) == 0 && len(stage.sections) == 0) {
+		if len(l.stages) == 0 && stage != nil {
+			l.add(0, LintError, "empty definition file")
+		}
+		return
+	}
+
+	bootstrap, ok := stage.headers["bootstrap"]
+	if !ok {
+		l.add(stage.line, LintError, "missing Bootstrap header keyword, which must be the first header of each stage")
+	} else if required, ok := bootstrapHeaders[bootstrap]; !ok && bootstrap != "" {
+		agents := make([]string, 0, len(bootstrapHeaders))
+		for a := range bootstrapHeaders {
+			agents = append(agents, a)
+		}
+		sort.Strings(agents)
+		l.add(stage.headerLines["bootstrap"], LintError, "unknown bootstrap agent %s (valid agents: %s)", bootstrap, strings.Join(agents, ", "))
+	} else {
+		for _, key := range required {
+			if _, ok := stage.headers[key]; !ok {
+				l.add(stage.headerLines["bootstrap"], LintError, "missing %s header keyword, required by the %s bootstrap agent", headerNames[key], bootstrap)
+			}
+		}
+		if bootstrap == "yum" && stage.headers["osversion"] == "" &&
+			(osVersionRegexp.MatchString(stage.headers["mirrorurl"]) || osVersionRegexp.MatchString(stage.headers["updateurl"])) {
+			l.add(stage.headerLines["bootstrap"], LintError, "missing OSVersion header keyword, referenced by %%{OSVERSION} in the mirror URLs")
+		}
+	}
+
+	l.stages = append(l.stages, stage.headers["stage"])
+}
+
+func (l *linter) startSection(stage *lintStage, n int, fields []string) *lintSection {
+	s := &lintSection{
+		line: n,
+		name: strings.ToLower(strings.TrimPrefix(fields[0], "%")),
+		args: fields[1:],
+	}
+
+	switch {
+	case s.name == "":
+		l.add(n, LintError, "missing section name after %%")
+		return nil
+	case validSections[s.name]:
+	case appSections[s.name]:
+		if len(s.args) == 0 {
+			l.add(n, LintError, "missing app name for the %%%s section", s.name)
+			return nil
+		}
+	default:
+		l.add(n, LintError, "unknown section %%%s (recognized sections: %s)", s.name, strings.Join(recognizedSections(), ", "))
+		return nil
+	}
+
+	_, include, err := getIncludeDirective(strings.TrimSpace(l.lines[n-1]))
+	if err != nil {
+		l.add(n, LintError, "%s", err)
+	} else if include != "" {
+		s.include = true
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(l.dir, include)
+		}
+		if _, err := os.Stat(include); err != nil {
+			l.add(n, LintError, "included file %s not found", include)
+		}
+	}
+
+	key := s.name
+	if appSections[s.name] {
+		key += " " + s.args[0]
+	}
+	if s.name != "files" {
+		if prev, ok := stage.sections[key]; ok {
+			l.add(n, LintWarning, "%%%s section already defined on line %d, their content is concatenated", key, prev)
+		}
+	}
+	stage.sections[key] = n
+
+	return s
+}
+
+func (l *linter) endSection(s *lintSection) {
+	if s == nil {
+		return
+	}
+
+	var content []int
+	for _, n := range s.body {
+		if line := strings.TrimSpace(l.lines[n-1]); line != "" && !strings.HasPrefix(line, "#") {
+			content = append(content, n)
+		}
+	}
+	if len(content) == 0 && !s.include {
+		l.add(s.line, LintWarning, "empty %%%s section", s.name)
+		return
+	}
+
+	switch s.name {
+	case "files":
+		if len(s.args) > 0 && strings.ToLower(s.args[0]) == "from" {
+			l.checkFilesFrom(s)
+			return
+		}
+		l.checkFiles(s, content)
+	case "appfiles":
+		l.checkFiles(s, content)
+	case "labels":
+		for _, n := range content {
+			if len(strings.Fields(l.lines[n-1])) < 2 {
+				l.add(n, LintWarning, "label %s has no value", strings.TrimSpace(l.lines[n-1]))
+			}
+		}
+	}
+}
+
+func (l *linter) checkFilesFrom(s *lintSection) {
+	if len(s.args) < 2 || strings.HasPrefix(s.args[1], "#") {
+		l.add(s.line, LintError, "missing stage name after %%files from")
+		return
+	}
+	for _, name := range l.stages {
+		if name == s.args[1] {
+			return
+		}
+	}
+	l.add(s.line, LintError, "%%files from %s: no previous stage named %s", s.args[1], s.args[1])
+}
+
+func (l *linter) checkFiles(s *lintSection, content []int) {
+	for _, n := range content {
+		line := strings.TrimSpace(l.lines[n-1])
+		fields := fileSplitter.FindAllString(line, -1)
+		_, fields, err := parseFileOptions(fields)
+		if err != nil {
+			l.add(n, LintError, "%%%s line %q: %s", s.name, line, err)
+			continue
+		} else if len(fields) == 0 {
+			l.add(n, LintError, "%%%s line %q: no source file", s.name, line)
+			continue
+		}
+
+		src := strings.Trim(strings.TrimSpace(fields[0]), "\"")
+		if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+			l.add(n, LintWarning, "%%%s source %s is a URL, it's only downloaded by build --fetch-files", s.name, src)
+			continue
+		}
+		if matches, err := filepath.Glob(src); err != nil || len(matches) == 0 {
+			l.add(n, LintError, "%%%s source %s not found", s.name, src)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lint-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "post.sh"), []byte("echo post\n"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+	defPath := filepath.Join(dir, "Singularity")
+	file := filepath.Join(dir, "post.sh")
+
+	type issue struct {
+		line     int
+		severity LintSeverity
+		message  string
+	}
+
+	tests := []struct {
+		name     string
+		raw      string
+		expected []issue
+	}{
+		{
+			name: "Valid",
+			raw:  "# comment\nBootstrap: docker\nFrom: alpine\n\n%post include post.sh\n%files\n    " + file + " /opt\n%labels\n    Author me\n",
+		},
+		{
+			name: "MultiStage",
+			raw:  "Bootstrap: docker\nFrom: alpine\nStage: one\n%post\n    true\n\nBootstrap: docker\nFrom: alpine\n%files from one\n    /a /b\n%files from two\n    /a /b\n",
+			expected: []issue{
+				{11, LintError, "no previous stage named two"},
+			},
+		},
+		{
+			name: "Headers",
+			raw:  "Bootstrap: docker\nfrom:\nFomr: alpine\nInclude\nFrom: alpine\nOtherURL1: http://example.com\nBootstrap: docker\n",
+			expected: []issue{
+				{2, LintError, "empty value for header keyword from"},
+				{3, LintError, "unknown header keyword fomr"},
+				{4, LintError, "has no value"},
+				{5, LintWarning, "header keyword from already set on line 2"},
+				{7, LintError, "missing From header keyword"},
+			},
+		},
+		{
+			name: "Bootstrap",
+			raw:  "From: alpine\n%post\n    true\nBootstrap: dokcer\nFrom: alpine\nBootstrap: debootstrap\nOSVersion: focal\nBootstrap: yum\nMirrorURL: http://example.com/%{OSVERSION}\n",
+			expected: []issue{
+				{1, LintError, "missing Bootstrap header keyword"},
+				{4, LintError, "unknown bootstrap agent dokcer"},
+				{6, LintError, "missing MirrorURL header keyword"},
+				{8, LintError, "missing OSVersion header keyword"},
+			},
+		},
+		{
+			name: "Sections",
+			raw:  "Bootstrap: docker\nFrom: alpine\n%psot\n    true\n%appinstall\n%runscript include missing.sh\n%environment\n    # nothing\n%test\n    true\n%test\n    true\n%labels\n    Author\n",
+			expected: []issue{
+				{3, LintError, "unknown section %psot"},
+				{5, LintError, "missing app name for the %appinstall section"},
+				{6, LintError, "included file " + filepath.Join(dir, "missing.sh") + " not found"},
+				{7, LintWarning, "empty %environment section"},
+				{11, LintWarning, "%test section already defined on line 9"},
+				{14, LintWarning, "label Author has no value"},
+			},
+		},
+		{
+			name: "Files",
+			raw:  "Bootstrap: docker\nFrom: alpine\n%files\n    " + filepath.Join(dir, "*.sh") + "\n    " + filepath.Join(dir, "missing") + " /opt\n    https://example.com/file /opt\n%appfiles foo\n    " + filepath.Join(dir, "missing") + "\n",
+			expected: []issue{
+				{5, LintError, "%files source " + filepath.Join(dir, "missing") + " not found"},
+				{6, LintWarning, "only downloaded by build --fetch-files"},
+				{8, LintError, "%appfiles source " + filepath.Join(dir, "missing") + " not found"},
+			},
+		},
+		{
+			name: "Empty",
+			raw:  "# nothing\n",
+			expected: []issue{
+				{0, LintError, "empty definition file"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Lint([]byte(tt.raw), defPath)
+			if len(issues) != len(tt.expected) {
+				t.Fatalf("got issues %+v, want %+v", issues, tt.expected)
+			}
+			for i, e := range tt.expected {
+				if issues[i].Line != e.line || issues[i].Severity != e.severity || !strings.Contains(issues[i].Message, e.message) {
+					t.Errorf("got issue %+v, want %+v", issues[i], e)
+				}
+			}
+		})
+	}
+}
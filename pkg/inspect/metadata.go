@@ -1,32 +1,78 @@
-// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// Copyright (c) 2020-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
 
 package inspect
 
+import "time"
+
 // ContainerType defines the container type (used by default).
 const ContainerType = "container"
 
+// SchemaVersion is the version of the JSON format of the 'inspect --json'
+// outputs, reported in their schemaVersion field. The format only evolves
+// in a backward-compatible way within a major version: new fields bump the
+// minor version, while removing, renaming or changing the meaning of a field
+// requires a new major version.
+const SchemaVersion = "1.0"
+
+// Healthcheck describes the health check inherited from the HEALTHCHECK
+// instruction of a Docker image. Test holds the Docker test form, either
+// ["CMD", arg...] or ["CMD-SHELL", command]. Durations are expressed in
+// nanoseconds, a zero value means the Docker default value.
+type Healthcheck struct {
+	Test        []string      `json:"test,omitempty"`
+	Interval    time.Duration `json:"interval,omitempty"`
+	Timeout     time.Duration `json:"timeout,omitempty"`
+	StartPeriod time.Duration `json:"startPeriod,omitempty"`
+	Retries     int           `json:"retries,omitempty"`
+}
+
+// Process describes the command run by the runscript of a container built
+// from an OCI image, as set by the ENTRYPOINT and CMD of the image.
+type Process struct {
+	Entrypoint []string `json:"entrypoint,omitempty"`
+	Cmd        []string `json:"cmd,omitempty"`
+	// Args is the command run when no argument is passed to the
+	// runscript, the entrypoint followed by the cmd.
+	Args []string `json:"args"`
+}
+
 // AppAttributes describes app metadata attributes.
 type AppAttributes struct {
 	Environment map[string]string `json:"environment,omitempty"`
-	Labels      map[string]string `json:"labels,omitempty"`
-	Runscript   string            `json:"runscript,omitempty"`
-	Test        string            `json:"test,omitempty"`
-	Helpfile    string            `json:"helpfile,omitempty"`
+	// ResolvedEnvironment holds the variables resolved from the app and
+	// container environment files, see Attributes.ResolvedEnvironment.
+	ResolvedEnvironment map[string]string `json:"resolvedEnvironment,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	Runscript           string            `json:"runscript,omitempty"`
+	Test                string            `json:"test,omitempty"`
+	Helpfile            string            `json:"helpfile,omitempty"`
 }
 
 // Attributes describes metadata attributes of Singularity containers.
 type Attributes struct {
 	Apps        map[string]*AppAttributes `json:"apps,omitempty"`
 	Environment map[string]string         `json:"environment,omitempty"`
-	Labels      map[string]string         `json:"labels,omitempty"`
-	Runscript   string                    `json:"runscript,omitempty"`
-	Test        string                    `json:"test,omitempty"`
-	Helpfile    string                    `json:"helpfile,omitempty"`
-	Deffile     string                    `json:"deffile,omitempty"`
-	Startscript string                    `json:"startscript,omitempty"`
+	// ResolvedEnvironment holds the variables set by the container
+	// environment files, excluding those set or modified at runtime
+	// depending on the host, the user or the command line (HOME, PWD,
+	// USER, LD_LIBRARY_PATH, TERM, proxy and SINGULARITY_* variables...).
+	ResolvedEnvironment map[string]string `json:"resolvedEnvironment,omitempty"`
+	Labels              map[string]string `json:"labels,omitempty"`
+	Runscript           string            `json:"runscript,omitempty"`
+	Test                string            `json:"test,omitempty"`
+	Helpfile            string            `json:"helpfile,omitempty"`
+	Deffile             string            `json:"deffile,omitempty"`
+	Startscript         string            `json:"startscript,omitempty"`
+	Healthcheck         *Healthcheck      `json:"healthcheck,omitempty"`
+	// RunscriptProcess holds the entrypoint and cmd run by the runscript
+	// of a container built from an OCI image.
+	RunscriptProcess *Process `json:"runscriptProcess,omitempty"`
+	// Remote holds the metadata returned by the registry or library of
+	// an image inspected remotely.
+	Remote map[string]string `json:"remote,omitempty"`
 }
 
 // Data holds the container metadata attributes.
@@ -36,8 +82,38 @@ type Data struct {
 
 // Metadata describes the JSON format of Singularity container metadata.
 type Metadata struct {
-	Data `json:"data"`
-	Type string `json:"type"`
+	SchemaVersion string `json:"schemaVersion"`
+	Data          `json:"data"`
+	Type          string `json:"type"`
+}
+
+// SIFLayer describes a partition of a SIF image. Type is squashfs, ext3,
+// encrypted, raw or archive, Filesystem is the filesystem of the partition
+// content, which is unknown for raw and archive partitions. Compression is
+// only set for unencrypted squashfs partitions, as the super block of
+// encrypted ones can't be read without the key.
+type SIFLayer struct {
+	ID          uint32 `json:"id"`
+	Partition   string `json:"partition"`
+	Type        string `json:"type"`
+	Filesystem  string `json:"filesystem,omitempty"`
+	Compression string `json:"compression,omitempty"`
+	Arch        string `json:"arch,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// SIFLayers describes the JSON format of the partitions of a SIF image.
+type SIFLayers struct {
+	SchemaVersion string     `json:"schemaVersion"`
+	Layers        []SIFLayer `json:"layers"`
+}
+
+// NewSIFLayers returns the SIFLayers listing layers.
+func NewSIFLayers(layers []SIFLayer) *SIFLayers {
+	return &SIFLayers{
+		SchemaVersion: SchemaVersion,
+		Layers:        layers,
+	}
 }
 
 func (m *Metadata) AddApp(name string) {
@@ -52,6 +128,7 @@ func (m *Metadata) AddApp(name string) {
 // NewMetadata returns an initialized instances of Metadata.
 func NewMetadata() *Metadata {
 	format := new(Metadata)
+	format.SchemaVersion = SchemaVersion
 	format.Type = ContainerType
 	format.Attributes.Labels = make(map[string]string)
 	format.Attributes.Environment = make(map[string]string)
@@ -0,0 +1,88 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package inspect
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestSchema checks the schema version and the fields that the JSON outputs
+// of 'inspect --json' are required to have in all versions 1.x.
+func TestSchema(t *testing.T) {
+	if SchemaVersion != "1.0" {
+		t.Errorf("got schema version %s, want 1.0", SchemaVersion)
+	}
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		required []string
+	}{
+		{
+			name:     "Metadata",
+			value:    NewMetadata(),
+			required: []string{"schemaVersion", "data", "type"},
+		},
+		{
+			name:     "SIFLayers",
+			value:    NewSIFLayers([]SIFLayer{{ID: 1, Partition: "primary system", Type: "squashfs"}}),
+			required: []string{"schemaVersion", "layers"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(b, &fields); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			for _, name := range tt.required {
+				if _, ok := fields[name]; !ok {
+					t.Errorf("missing field %s in %s", name, b)
+				}
+			}
+			var version string
+			if err := json.Unmarshal(fields["schemaVersion"], &version); err != nil || version != SchemaVersion {
+				t.Errorf("got schemaVersion %s, want %q", fields["schemaVersion"], SchemaVersion)
+			}
+		})
+	}
+
+	b, err := json.Marshal(NewMetadata())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var m struct {
+		Data struct {
+			Attributes map[string]json.RawMessage `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m.Data.Attributes == nil {
+		t.Errorf("missing field data.attributes in %s", b)
+	}
+
+	b, err = json.Marshal(SIFLayer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var layer map[string]json.RawMessage
+	if err := json.Unmarshal(b, &layer); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, name := range []string{"id", "partition", "type", "size"} {
+		if _, ok := layer[name]; !ok {
+			t.Errorf("missing field %s in SIF layer %s", name, b)
+		}
+	}
+}
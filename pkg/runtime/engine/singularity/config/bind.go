@@ -1,4 +1,4 @@
-// Copyright (c) 2019-2021, Sylabs Inc. All rights reserved.
+// Copyright (c) 2019-2022, Sylabs Inc. All rights reserved.
 // This software is licensed under a 3-clause BSD license. Please consult the
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 )
 
 // BindOption represents a bind option with its associated
@@ -25,12 +26,30 @@ const (
 // bindOptions is a map of option strings valid in bind specifications.
 // If true, the option is a flag. If false, the option takes a value.
 var bindOptions = map[string]bool{
-	"ro":        flagOption,
-	"rw":        flagOption,
-	"image-src": valueOption,
-	"id":        valueOption,
+	"ro":            flagOption,
+	"rw":            flagOption,
+	"image-src":     valueOption,
+	"id":            valueOption,
+	"shared":        flagOption,
+	"rshared":       flagOption,
+	"slave":         flagOption,
+	"rslave":        flagOption,
+	"private":       flagOption,
+	"rprivate":      flagOption,
+	"wait":          flagOption,
+	"wait-timeout":  valueOption,
+	"overlay":       flagOption,
+	"overlay-upper": valueOption,
 }
 
+// DefaultBindWaitTimeout is how long the source of a bind path with the
+// wait option is waited for.
+const DefaultBindWaitTimeout = 30 * time.Second
+
+// propagationOptions are the bind options setting the mount propagation
+// of a bind path.
+var propagationOptions = []string{"shared", "rshared", "slave", "rslave", "private", "rprivate"}
+
 // BindPath stores a parsed bind path specification. Source and Destination
 // paths are required.
 type BindPath struct {
@@ -66,6 +85,52 @@ func (b *BindPath) Readonly() bool {
 	return b.Options != nil && b.Options["ro"] != nil
 }
 
+// Propagation returns the mount propagation option set for a BindPath, or
+// an empty string if the option wasn't set.
+func (b *BindPath) Propagation() string {
+	for _, p := range propagationOptions {
+		if b.Options != nil && b.Options[p] != nil {
+			return p
+		}
+	}
+	return ""
+}
+
+// Overlay returns true if the overlay or overlay-upper option was set for a
+// BindPath, to mount its source as the lower directory of a writable overlay.
+func (b *BindPath) Overlay() bool {
+	return b.Options != nil && (b.Options["overlay"] != nil || b.Options["overlay-upper"] != nil)
+}
+
+// OverlayUpper returns the value of the option overlay-upper for a BindPath,
+// or an empty string if the option wasn't set.
+func (b *BindPath) OverlayUpper() string {
+	if b.Options != nil && b.Options["overlay-upper"] != nil {
+		return b.Options["overlay-upper"].Value
+	}
+	return ""
+}
+
+// WaitTimeout returns how long the source of a BindPath must be waited for
+// to be a mounted file system, as set by the wait or wait-timeout options,
+// or zero if the source isn't waited for.
+func (b *BindPath) WaitTimeout() (time.Duration, error) {
+	if b.Options == nil {
+		return 0, nil
+	}
+	if o := b.Options["wait-timeout"]; o != nil {
+		d, err := time.ParseDuration(o.Value)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid wait-timeout %q: must be a positive duration like 10s or 2m", o.Value)
+		}
+		return d, nil
+	}
+	if b.Options["wait"] != nil {
+		return DefaultBindWaitTimeout, nil
+	}
+	return 0, nil
+}
+
 // ParseBindPath parses a string specifying one or more (comma separated) bind
 // paths in src[:dst[:options]] format, and returns all encountered bind paths
 // as a slice. Options may be simple flags, e.g. 'rw', or take a value, e.g.
@@ -154,6 +219,38 @@ func ParseBindPath(bindpaths string) ([]BindPath, error) {
 	return binds, nil
 }
 
+// ParseBindDataPath parses a string specifying one or more (comma separated)
+// data image bind paths in src[:dst[:options]] format, and returns them as
+// image bind paths mounted read-only. Only the 'ro', 'image-src' and 'id'
+// options are accepted, 'image-src' defaults to the root of the data image.
+// Any other option is rejected.
+func ParseBindDataPath(bindpaths string) ([]BindPath, error) {
+	binds, err := ParseBindPath(bindpaths)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range binds {
+		if binds[i].Options == nil {
+			binds[i].Options = make(map[string]*BindOption)
+		}
+		if binds[i].Options["rw"] != nil {
+			return nil, fmt.Errorf("data image %s can only be mounted read-only", binds[i].Source)
+		}
+		for opt := range binds[i].Options {
+			if opt != "ro" && opt != "image-src" && opt != "id" {
+				return nil, fmt.Errorf("%s option is not supported for data image %s", opt, binds[i].Source)
+			}
+		}
+		binds[i].Options["ro"] = &BindOption{}
+		if binds[i].Options["image-src"] == nil {
+			binds[i].Options["image-src"] = &BindOption{Value: "/"}
+		}
+	}
+
+	return binds, nil
+}
+
 // newBindPath returns BindPath record based on the provided bind
 // string argument and ensures that the options are valid.
 func newBindPath(bind string) (BindPath, error) {
@@ -194,5 +291,37 @@ func newBindPath(bind string) (BindPath, error) {
 		}
 	}
 
-	return bp, nil
+	return bp, checkBindOptions(bp)
+}
+
+// checkBindOptions ensures that a single propagation option is set, and
+// that the wait and overlay options are valid.
+func checkBindOptions(bp BindPath) error {
+	if _, err := bp.WaitTimeout(); err != nil {
+		return fmt.Errorf("bind path %s: %s", bp.Source, err)
+	}
+	if bp.ImageSrc() != "" && (bp.Options["wait"] != nil || bp.Options["wait-timeout"] != nil) {
+		return fmt.Errorf("wait options can't be used with image bind path %s", bp.Source)
+	}
+	if bp.Overlay() {
+		switch {
+		case bp.ImageSrc() != "" || bp.ID() != "":
+			return fmt.Errorf("overlay options can't be used with image bind path %s", bp.Source)
+		case bp.Readonly():
+			return fmt.Errorf("overlay bind path %s can't be read-only", bp.Source)
+		case bp.Options["overlay-upper"] != nil && bp.OverlayUpper() == "":
+			return fmt.Errorf("empty overlay-upper directory for bind path %s", bp.Source)
+		}
+	}
+
+	propagation := 0
+	for _, p := range propagationOptions {
+		if bp.Options[p] != nil {
+			propagation++
+		}
+	}
+	if propagation > 1 {
+		return fmt.Errorf("only one propagation option can be set for bind path %s", bp.Source)
+	}
+	return nil
 }
@@ -174,6 +174,95 @@ func TestParseBindPath(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:      "srcDstROPropagation",
+			bindpaths: "/opt:/other:ro,rslave,/srv",
+			want: []BindPath{
+				{
+					Source:      "/opt",
+					Destination: "/other",
+					Options: map[string]*BindOption{
+						"ro":     {},
+						"rslave": {},
+					},
+				},
+				{
+					Source:      "/srv",
+					Destination: "/srv",
+				},
+			},
+		},
+		{
+			name:      "multiplePropagation",
+			bindpaths: "/opt:/other:shared,private",
+			want:      []BindPath{},
+			wantErr:   true,
+		},
+		{
+			name:      "srcDstWait",
+			bindpaths: "/mnt/sshfs:/data:ro,wait,/mnt/fuse:/other:wait-timeout=2m",
+			want: []BindPath{
+				{
+					Source:      "/mnt/sshfs",
+					Destination: "/data",
+					Options: map[string]*BindOption{
+						"ro":   {},
+						"wait": {},
+					},
+				},
+				{
+					Source:      "/mnt/fuse",
+					Destination: "/other",
+					Options: map[string]*BindOption{
+						"wait-timeout": {"2m"},
+					},
+				},
+			},
+		},
+		{
+			name:      "invalidWaitTimeout",
+			bindpaths: "/mnt/sshfs:/data:wait-timeout=soon",
+			want:      []BindPath{},
+			wantErr:   true,
+		},
+		{
+			name:      "imageSrcWait",
+			bindpaths: "test.sif:/other:image-src=/opt,wait",
+			want:      []BindPath{},
+			wantErr:   true,
+		},
+		{
+			name:      "srcDstOverlay",
+			bindpaths: "/ref:/data:overlay,/ref2:/other:overlay-upper=/scratch/ref2",
+			want: []BindPath{
+				{
+					Source:      "/ref",
+					Destination: "/data",
+					Options: map[string]*BindOption{
+						"overlay": {},
+					},
+				},
+				{
+					Source:      "/ref2",
+					Destination: "/other",
+					Options: map[string]*BindOption{
+						"overlay-upper": {"/scratch/ref2"},
+					},
+				},
+			},
+		},
+		{
+			name:      "readonlyOverlay",
+			bindpaths: "/ref:/data:ro,overlay",
+			want:      []BindPath{},
+			wantErr:   true,
+		},
+		{
+			name:      "imageSrcOverlay",
+			bindpaths: "test.sif:/other:image-src=/opt,overlay",
+			want:      []BindPath{},
+			wantErr:   true,
+		},
 		{
 			name:      "invalidOption",
 			bindpaths: "/opt:/other:invalid",
@@ -200,3 +289,101 @@ func TestParseBindPath(t *testing.T) {
 		})
 	}
 }
+
+func TestParseBindDataPath(t *testing.T) {
+	tests := []struct {
+		name      string
+		bindpaths string
+		want      []BindPath
+		wantErr   bool
+	}{
+		{
+			name:      "srcDst",
+			bindpaths: "data.sif:/ref",
+			want: []BindPath{
+				{
+					Source:      "data.sif",
+					Destination: "/ref",
+					Options: map[string]*BindOption{
+						"ro":        {},
+						"image-src": {"/"},
+					},
+				},
+			},
+		},
+		{
+			name:      "srcDstMultiple",
+			bindpaths: "data.sif:/ref,other.sif:/other:ro",
+			want: []BindPath{
+				{
+					Source:      "data.sif",
+					Destination: "/ref",
+					Options: map[string]*BindOption{
+						"ro":        {},
+						"image-src": {"/"},
+					},
+				},
+				{
+					Source:      "other.sif",
+					Destination: "/other",
+					Options: map[string]*BindOption{
+						"ro":        {},
+						"image-src": {"/"},
+					},
+				},
+			},
+		},
+		{
+			name:      "srcDstImageSrcId",
+			bindpaths: "data.sif:/ref:image-src=/opt,id=2",
+			want: []BindPath{
+				{
+					Source:      "data.sif",
+					Destination: "/ref",
+					Options: map[string]*BindOption{
+						"ro":        {},
+						"image-src": {"/opt"},
+						"id":        {"2"},
+					},
+				},
+			},
+		},
+		{
+			name:      "readWrite",
+			bindpaths: "data.sif:/ref:rw",
+			wantErr:   true,
+		},
+		{
+			name:      "invalidOption",
+			bindpaths: "data.sif:/ref:invalid",
+			wantErr:   true,
+		},
+		{
+			name:      "propagation",
+			bindpaths: "data.sif:/ref:rshared",
+			wantErr:   true,
+		},
+		{
+			name:      "wait",
+			bindpaths: "data.sif:/ref:wait",
+			wantErr:   true,
+		},
+		{
+			name:      "overlay",
+			bindpaths: "data.sif:/ref:overlay",
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseBindDataPath(tt.bindpaths)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseBindDataPath() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBindDataPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
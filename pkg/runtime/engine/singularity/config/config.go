@@ -27,6 +27,36 @@ const (
 	UnderlayLayer = "underlay"
 )
 
+const (
+	// RewritePathAppend appends the default PATH entries missing from
+	// the container PATH (default).
+	RewritePathAppend = "append"
+	// RewritePathPrepend prepends the default PATH entries missing from
+	// the container PATH.
+	RewritePathPrepend = "prepend"
+	// RewritePathImageOnly uses the container PATH as is, the default
+	// PATH is used only when the container doesn't define one.
+	RewritePathImageOnly = "image-only"
+)
+
+const (
+	// SetgroupsAllow allows the setgroups syscall in the user namespace,
+	// the supplementary groups are replaced by the container group.
+	SetgroupsAllow = "allow"
+	// SetgroupsDeny denies the setgroups syscall in the user namespace,
+	// the supplementary groups of the user are kept.
+	SetgroupsDeny = "deny"
+)
+
+const (
+	// DevMinimal mounts a minimal /dev with only the essential devices.
+	DevMinimal = "minimal"
+	// DevFull binds the host /dev into the container.
+	DevFull = "full"
+	// DevCustom mounts a minimal /dev with additional host devices.
+	DevCustom = "custom"
+)
+
 // EngineConfig stores the JSONConfig, the OciConfig and the File configuration.
 type EngineConfig struct {
 	JSON      *JSONConfig `json:"jsonConfig"`
@@ -70,15 +100,23 @@ type JSONConfig struct {
 	FuseMount             []FuseMount       `json:"fuseMount,omitempty"`
 	ImageList             []image.Image     `json:"imageList,omitempty"`
 	BindPath              []BindPath        `json:"bindpath,omitempty"`
+	TmpfsMounts           []TmpfsMount      `json:"tmpfsMounts,omitempty"`
 	SingularityEnv        map[string]string `json:"singularityEnv,omitempty"`
+	AppendEnv             map[string]string `json:"appendEnv,omitempty"`
+	PrependEnv            map[string]string `json:"prependEnv,omitempty"`
+	LiteralEnv            map[string]string `json:"literalEnv,omitempty"`
 	UnixSocketPair        [2]int            `json:"unixSocketPair,omitempty"`
 	OpenFd                []int             `json:"openFd,omitempty"`
 	TargetGID             []int             `json:"targetGID,omitempty"`
 	Image                 string            `json:"image"`
 	Workdir               string            `json:"workdir,omitempty"`
+	ScratchSize           int               `json:"scratchSize,omitempty"`
 	CgroupsTOML           string            `json:"cgroupsTOML,omitempty"`
+	CgroupsJSON           string            `json:"cgroupsJSON,omitempty"`
+	CgroupsParent         string            `json:"cgroupsParent,omitempty"`
 	HomeSource            string            `json:"homedir,omitempty"`
 	HomeDest              string            `json:"homeDest,omitempty"`
+	HomeTmpfs             string            `json:"homeTmpfs,omitempty"`
 	Command               string            `json:"command,omitempty"`
 	Shell                 string            `json:"shell,omitempty"`
 	TmpDir                string            `json:"tmpdir,omitempty"`
@@ -87,13 +125,19 @@ type JSONConfig struct {
 	Hostname              string            `json:"hostname,omitempty"`
 	Network               string            `json:"network,omitempty"`
 	DNS                   string            `json:"dns,omitempty"`
+	DNSSearch             []string          `json:"dnsSearch,omitempty"`
+	AddHosts              []string          `json:"addHosts,omitempty"`
 	Cwd                   string            `json:"cwd,omitempty"`
+	Pwd                   string            `json:"pwd,omitempty"`
 	SessionLayer          string            `json:"sessionLayer,omitempty"`
 	ConfigurationFile     string            `json:"configurationFile,omitempty"`
 	EncryptionKey         []byte            `json:"encryptionKey,omitempty"`
 	TargetUID             int               `json:"targetUID,omitempty"`
 	WritableImage         bool              `json:"writableImage,omitempty"`
 	WritableTmpfs         bool              `json:"writableTmpfs,omitempty"`
+	WritableTmpfsDir      string            `json:"writableTmpfsDir,omitempty"`
+	RootfsPropagation     string            `json:"rootfsPropagation,omitempty"`
+	WritableCwd           bool              `json:"writableCwd,omitempty"`
 	Contain               bool              `json:"container,omitempty"`
 	NvLegacy              bool              `json:"nvLegacy,omitempty"`
 	NvCCLI                bool              `json:"nvCCLI,omitempty"`
@@ -111,19 +155,27 @@ type JSONConfig struct {
 	NoSys                 bool              `json:"noSys,omitempty"`
 	NoDev                 bool              `json:"noDev,omitempty"`
 	NoDevPts              bool              `json:"noDevPts,omitempty"`
+	Dev                   string            `json:"dev,omitempty"`
+	DevDevices            []string          `json:"devDevices,omitempty"`
+	Devices               map[string]string `json:"devices,omitempty"`
 	NoHome                bool              `json:"noHome,omitempty"`
 	NoTmp                 bool              `json:"noTmp,omitempty"`
 	NoHostfs              bool              `json:"noHostfs,omitempty"`
 	NoCwd                 bool              `json:"noCwd,omitempty"`
 	SkipBinds             []string          `json:"skipBinds,omitempty"`
 	NoInit                bool              `json:"noInit,omitempty"`
+	InitBin               string            `json:"initBin,omitempty"`
 	Fakeroot              bool              `json:"fakeroot,omitempty"`
+	CustomIDMappings      bool              `json:"customIDMappings,omitempty"`
+	Setgroups             string            `json:"setgroups,omitempty"`
 	SignalPropagation     bool              `json:"signalPropagation,omitempty"`
 	RestoreUmask          bool              `json:"restoreUmask,omitempty"`
 	DeleteTempDir         string            `json:"deleteTempDir,omitempty"`
 	Umask                 int               `json:"umask,omitempty"`
 	XdgRuntimeDir         string            `json:"xdgRuntimeDir,omitempty"`
 	DbusSessionBusAddress string            `json:"dbusSessionBusAddress,omitempty"`
+	NoEval                bool              `json:"noEval,omitempty"`
+	RewritePath           string            `json:"rewritePath,omitempty"`
 }
 
 // SetImage sets the container image path to be used by EngineConfig.JSON.
@@ -236,6 +288,18 @@ func (e *EngineConfig) GetScratchDir() []string {
 	return e.JSON.ScratchDir
 }
 
+// SetScratchSize sets the size in MiB of the tmpfs mounted for
+// each scratch directory, 0 means scratch directories are stored
+// in the session directory.
+func (e *EngineConfig) SetScratchSize(size int) {
+	e.JSON.ScratchSize = size
+}
+
+// GetScratchSize retrieves the size in MiB of scratch directories.
+func (e *EngineConfig) GetScratchSize() int {
+	return e.JSON.ScratchSize
+}
+
 // SetHomeSource sets the source home directory path.
 func (e *EngineConfig) SetHomeSource(source string) {
 	e.JSON.HomeSource = source
@@ -256,6 +320,18 @@ func (e *EngineConfig) GetHomeDest() string {
 	return e.JSON.HomeDest
 }
 
+// SetHomeTmpfs sets the tmpfs mount options of a tmpfs backed
+// home directory, an empty string means home isn't a tmpfs.
+func (e *EngineConfig) SetHomeTmpfs(options string) {
+	e.JSON.HomeTmpfs = options
+}
+
+// GetHomeTmpfs retrieves the tmpfs mount options of a tmpfs backed
+// home directory.
+func (e *EngineConfig) GetHomeTmpfs() string {
+	return e.JSON.HomeTmpfs
+}
+
 // SetCustomHome sets if home path is a custom path or not.
 func (e *EngineConfig) SetCustomHome(custom bool) {
 	e.JSON.CustomHome = custom
@@ -276,6 +352,16 @@ func (e *EngineConfig) GetBindPath() []BindPath {
 	return e.JSON.BindPath
 }
 
+// SetTmpfsMounts sets the tmpfs mount points requested by user.
+func (e *EngineConfig) SetTmpfsMounts(mounts []TmpfsMount) {
+	e.JSON.TmpfsMounts = mounts
+}
+
+// GetTmpfsMounts retrieves the tmpfs mount points requested by user.
+func (e *EngineConfig) GetTmpfsMounts() []TmpfsMount {
+	return e.JSON.TmpfsMounts
+}
+
 // SetCommand sets action command to execute.
 func (e *EngineConfig) SetCommand(command string) {
 	e.JSON.Command = command
@@ -496,6 +582,17 @@ func (e *EngineConfig) GetNoInit() bool {
 	return e.JSON.NoInit
 }
 
+// SetInitBin sets the path of an init binary in the container
+// used in place of the shim init process.
+func (e *EngineConfig) SetInitBin(path string) {
+	e.JSON.InitBin = path
+}
+
+// GetInitBin returns the path of the init binary in the container.
+func (e *EngineConfig) GetInitBin() string {
+	return e.JSON.InitBin
+}
+
 // SetNetwork sets a list of commas separated networks to configure inside container.
 func (e *EngineConfig) SetNetwork(network string) {
 	e.JSON.Network = network
@@ -526,6 +623,26 @@ func (e *EngineConfig) GetDNS() string {
 	return e.JSON.DNS
 }
 
+// SetDNSSearch sets the list of DNS search domains to add in resolv.conf.
+func (e *EngineConfig) SetDNSSearch(search []string) {
+	e.JSON.DNSSearch = search
+}
+
+// GetDNSSearch retrieves list of DNS search domains.
+func (e *EngineConfig) GetDNSSearch() []string {
+	return e.JSON.DNSSearch
+}
+
+// SetAddHosts sets the list of name:ip entries to add in /etc/hosts.
+func (e *EngineConfig) SetAddHosts(hosts []string) {
+	e.JSON.AddHosts = hosts
+}
+
+// GetAddHosts retrieves the list of name:ip entries to add in /etc/hosts.
+func (e *EngineConfig) GetAddHosts() []string {
+	return e.JSON.AddHosts
+}
+
 // SetImageList sets image list containing opened images.
 func (e *EngineConfig) SetImageList(list []image.Image) {
 	e.JSON.ImageList = list
@@ -546,6 +663,17 @@ func (e *EngineConfig) GetCwd() string {
 	return e.JSON.Cwd
 }
 
+// SetPwd sets the container working directory requested by the user,
+// created if it doesn't exist and the container is writable.
+func (e *EngineConfig) SetPwd(path string) {
+	e.JSON.Pwd = path
+}
+
+// GetPwd returns the container working directory requested by the user.
+func (e *EngineConfig) GetPwd() string {
+	return e.JSON.Pwd
+}
+
 // SetOpenFd sets a list of open file descriptor.
 func (e *EngineConfig) SetOpenFd(fds []int) {
 	e.JSON.OpenFd = fds
@@ -566,6 +694,41 @@ func (e *EngineConfig) GetWritableTmpfs() bool {
 	return e.JSON.WritableTmpfs
 }
 
+// SetWritableTmpfsDir sets the directory holding the writable tmpfs
+// overlay upper and work directories, kept when the container exits.
+func (e *EngineConfig) SetWritableTmpfsDir(dir string) {
+	e.JSON.WritableTmpfsDir = dir
+}
+
+// GetWritableTmpfsDir returns the directory holding the writable tmpfs
+// overlay upper and work directories.
+func (e *EngineConfig) GetWritableTmpfsDir() string {
+	return e.JSON.WritableTmpfsDir
+}
+
+// SetRootfsPropagation sets the mount propagation, private, slave or
+// shared, applied recursively to the container root mount.
+func (e *EngineConfig) SetRootfsPropagation(propagation string) {
+	e.JSON.RootfsPropagation = propagation
+}
+
+// GetRootfsPropagation returns the mount propagation of the container root
+// mount, empty to keep the propagation set up by the session.
+func (e *EngineConfig) GetRootfsPropagation() string {
+	return e.JSON.RootfsPropagation
+}
+
+// SetWritableCwd sets writable current working directory flag.
+func (e *EngineConfig) SetWritableCwd(writable bool) {
+	e.JSON.WritableCwd = writable
+}
+
+// GetWritableCwd returns if the current working directory is made
+// writable with a temporary overlay or not.
+func (e *EngineConfig) GetWritableCwd() bool {
+	return e.JSON.WritableCwd
+}
+
 // SetSecurity sets security feature arguments.
 func (e *EngineConfig) SetSecurity(security []string) {
 	e.JSON.Security = security
@@ -586,6 +749,29 @@ func (e *EngineConfig) GetCgroupsTOML() string {
 	return e.JSON.CgroupsTOML
 }
 
+// SetCgroupsJSON sets the cgroups resources to apply, in OCI runtime
+// spec JSON format. It takes precedence over the cgroups TOML file.
+func (e *EngineConfig) SetCgroupsJSON(data string) {
+	e.JSON.CgroupsJSON = data
+}
+
+// GetCgroupsJSON returns the cgroups resources to apply, in OCI runtime
+// spec JSON format.
+func (e *EngineConfig) GetCgroupsJSON() string {
+	return e.JSON.CgroupsJSON
+}
+
+// SetCgroupsParent sets the parent cgroup of the container cgroup, a
+// systemd slice name or a cgroupfs path.
+func (e *EngineConfig) SetCgroupsParent(parent string) {
+	e.JSON.CgroupsParent = parent
+}
+
+// GetCgroupsParent returns the parent cgroup of the container cgroup.
+func (e *EngineConfig) GetCgroupsParent() string {
+	return e.JSON.CgroupsParent
+}
+
 // SetTargetUID sets target UID to execute the container process as user ID.
 func (e *EngineConfig) SetTargetUID(uid int) {
 	e.JSON.TargetUID = uid
@@ -649,6 +835,65 @@ func (e *EngineConfig) GetFakeroot() bool {
 	return e.JSON.Fakeroot
 }
 
+// SetCustomIDMappings sets if the user namespace ID mappings were
+// requested by the user.
+func (e *EngineConfig) SetCustomIDMappings(custom bool) {
+	e.JSON.CustomIDMappings = custom
+}
+
+// GetCustomIDMappings returns if the user namespace ID mappings were
+// requested by the user.
+func (e *EngineConfig) GetCustomIDMappings() bool {
+	return e.JSON.CustomIDMappings
+}
+
+// SetDev sets the /dev mode requested with --dev, DevMinimal, DevFull or
+// DevCustom.
+func (e *EngineConfig) SetDev(mode string) {
+	e.JSON.Dev = mode
+}
+
+// GetDev returns the /dev mode requested with --dev, an empty string for
+// the mode selected by configuration.
+func (e *EngineConfig) GetDev() string {
+	return e.JSON.Dev
+}
+
+// SetDevDevices sets the host devices added to a minimal /dev with DevCustom.
+func (e *EngineConfig) SetDevDevices(devices []string) {
+	e.JSON.DevDevices = devices
+}
+
+// GetDevDevices returns the host devices added to a minimal /dev with
+// DevCustom.
+func (e *EngineConfig) GetDevDevices() []string {
+	return e.JSON.DevDevices
+}
+
+// SetDevices sets the host devices requested with --device, mapping their
+// path in the container to the host device node.
+func (e *EngineConfig) SetDevices(devices map[string]string) {
+	e.JSON.Devices = devices
+}
+
+// GetDevices returns the host devices requested with --device, mapping
+// their path in the container to the host device node.
+func (e *EngineConfig) GetDevices() map[string]string {
+	return e.JSON.Devices
+}
+
+// SetSetgroups sets the setgroups policy of the user namespace created
+// with --fakeroot or custom ID mappings, SetgroupsAllow or SetgroupsDeny.
+func (e *EngineConfig) SetSetgroups(policy string) {
+	e.JSON.Setgroups = policy
+}
+
+// GetSetgroups returns the setgroups policy of the user namespace created
+// with --fakeroot or custom ID mappings, an empty string for the default.
+func (e *EngineConfig) GetSetgroups() string {
+	return e.JSON.Setgroups
+}
+
 // GetDeleteTempDir returns the path of the temporary directory containing the root filesystem
 // which must be deleted after use. If no deletion is required, the empty string is returned.
 func (e *EngineConfig) GetDeleteTempDir() string {
@@ -759,6 +1004,42 @@ func (e *EngineConfig) GetSingularityEnv() map[string]string {
 	return e.JSON.SingularityEnv
 }
 
+// SetAppendEnv sets the values to append to container environment
+// variables.
+func (e *EngineConfig) SetAppendEnv(env map[string]string) {
+	e.JSON.AppendEnv = env
+}
+
+// GetAppendEnv returns the values to append to container environment
+// variables.
+func (e *EngineConfig) GetAppendEnv() map[string]string {
+	return e.JSON.AppendEnv
+}
+
+// SetPrependEnv sets the values to prepend to container environment
+// variables.
+func (e *EngineConfig) SetPrependEnv(env map[string]string) {
+	e.JSON.PrependEnv = env
+}
+
+// GetPrependEnv returns the values to prepend to container environment
+// variables.
+func (e *EngineConfig) GetPrependEnv() map[string]string {
+	return e.JSON.PrependEnv
+}
+
+// SetLiteralEnv sets environment variables set in the container as is,
+// without shell evaluation, as a key/value string map.
+func (e *EngineConfig) SetLiteralEnv(env map[string]string) {
+	e.JSON.LiteralEnv = env
+}
+
+// GetLiteralEnv returns environment variables set in the container as is,
+// without shell evaluation, as a key/value string map.
+func (e *EngineConfig) GetLiteralEnv() map[string]string {
+	return e.JSON.LiteralEnv
+}
+
 // SetConfigurationFile sets the singularity configuration file to
 // use instead of the default one.
 func (e *EngineConfig) SetConfigurationFile(filename string) {
@@ -809,3 +1090,23 @@ func (e *EngineConfig) SetDbusSessionBusAddress(address string) {
 func (e *EngineConfig) GetDbusSessionBusAddress() string {
 	return e.JSON.DbusSessionBusAddress
 }
+
+// SetNoEval sets whether to avoid a shell evaluation on args and env vars.
+func (e *EngineConfig) SetNoEval(noEval bool) {
+	e.JSON.NoEval = noEval
+}
+
+// GetNoEval gets whether to avoid a shell evaluation on args and env vars.
+func (e *EngineConfig) GetNoEval() bool {
+	return e.JSON.NoEval
+}
+
+// SetRewritePath sets how the default PATH is merged with the container PATH.
+func (e *EngineConfig) SetRewritePath(mode string) {
+	e.JSON.RewritePath = mode
+}
+
+// GetRewritePath gets how the default PATH is merged with the container PATH.
+func (e *EngineConfig) GetRewritePath() string {
+	return e.JSON.RewritePath
+}
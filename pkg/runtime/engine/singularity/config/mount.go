@@ -26,6 +26,9 @@ import (
 // The fields are in key[=value] format. Flag options have no value, e.g.:
 //   type=bind,source=/opt,destination=/other,rw
 //
+// The mount propagation of the bind is set with the bind-propagation key,
+// taking the same values as the propagation options of bind paths.
+//
 // We only support type=bind at present, so assume this if type is missing and
 // error for other types.
 func ParseMountString(mount string) (bindPaths []BindPath, err error) {
@@ -66,7 +69,16 @@ func ParseMountString(mount string) (bindPaths []BindPath, err error) {
 				}
 				bp.Destination = val
 			case "ro", "readonly":
-				bp.Options["ro"] = &BindOption{}
+				switch val {
+				case "", "true", "1":
+					bp.Options["ro"] = &BindOption{}
+				case "false", "0":
+					bp.Options["rw"] = &BindOption{}
+				default:
+					return []BindPath{}, fmt.Errorf("invalid %s value %q", key, val)
+				}
+			case "rw":
+				bp.Options["rw"] = &BindOption{}
 			// Singularity only - directory inside an image file source to mount from
 			case "image-src":
 				if val == "" {
@@ -80,7 +92,17 @@ func ParseMountString(mount string) (bindPaths []BindPath, err error) {
 				}
 				bp.Options["id"] = &BindOption{Value: val}
 			case "bind-propagation":
-				return []BindPath{}, fmt.Errorf("bind-propagation not supported for individual mounts, check singularity.conf for global setting")
+				valid := false
+				for _, p := range propagationOptions {
+					if val == p {
+						valid = true
+						break
+					}
+				}
+				if !valid {
+					return []BindPath{}, fmt.Errorf("invalid bind-propagation value %q, must be one of %s", val, strings.Join(propagationOptions, ", "))
+				}
+				bp.Options[val] = &BindOption{}
 			default:
 				return []BindPath{}, fmt.Errorf("invalid key %q in mount specification", key)
 			}
@@ -89,6 +111,9 @@ func ParseMountString(mount string) (bindPaths []BindPath, err error) {
 		if bp.Source == "" || bp.Destination == "" {
 			return []BindPath{}, fmt.Errorf("mounts must specify a source and a destination")
 		}
+		if err := checkBindOptions(bp); err != nil {
+			return []BindPath{}, err
+		}
 		bindPaths = append(bindPaths, bp)
 	}
 
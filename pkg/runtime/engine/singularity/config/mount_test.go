@@ -196,10 +196,38 @@ func TestParseMountString(t *testing.T) {
 		},
 		{
 			name:        "bindpropagation",
-			mountString: "type=bind,source=/opt,destination=/opt,bind-propagation=shared",
+			mountString: "type=bind,source=/opt,destination=/opt,bind-propagation=rslave",
+			want: []BindPath{
+				{
+					Source:      "/opt",
+					Destination: "/opt",
+					Options: map[string]*BindOption{
+						"rslave": {},
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:        "invalidBindpropagation",
+			mountString: "type=bind,source=/opt,destination=/opt,bind-propagation=potato",
 			want:        []BindPath{},
 			wantErr:     true,
 		},
+		{
+			name:        "readonlyFalse",
+			mountString: "type=bind,source=/opt,destination=/opt,readonly=false",
+			want: []BindPath{
+				{
+					Source:      "/opt",
+					Destination: "/opt",
+					Options: map[string]*BindOption{
+						"rw": {},
+					},
+				},
+			},
+			wantErr: false,
+		},
 		{
 			name:        "csvEscaped",
 			mountString: `type=bind,"source=/comma,dir","destination=/quote""dir"`,
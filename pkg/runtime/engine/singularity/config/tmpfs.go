@@ -0,0 +1,99 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	units "github.com/docker/go-units"
+)
+
+// TmpfsMount stores a tmpfs mount point requested with --tmpfs.
+type TmpfsMount struct {
+	Destination string `json:"destination"`
+	// Data holds the tmpfs file system options passed to mount.
+	Data     string `json:"data,omitempty"`
+	ReadOnly bool   `json:"readonly,omitempty"`
+	Exec     bool   `json:"exec,omitempty"`
+}
+
+// ParseTmpfsString converts a --tmpfs string of the form path[:options] into
+// a TmpfsMount. Options are a comma separated list compatible with docker run
+// --tmpfs: size, mode, uid, gid, nr_inodes, ro, rw, exec and noexec. Like
+// docker, tmpfs mounts are noexec with mode 1777 by default, and are always
+// nosuid and nodev.
+func ParseTmpfsString(tmpfs string) (TmpfsMount, error) {
+	tm := TmpfsMount{}
+
+	splitted := strings.SplitN(tmpfs, ":", 2)
+	tm.Destination = filepath.Clean(splitted[0])
+	if !filepath.IsAbs(splitted[0]) {
+		return tm, fmt.Errorf("tmpfs destination %q must be an absolute path", splitted[0])
+	} else if tm.Destination == "/" {
+		return tm, fmt.Errorf("tmpfs destination can't be /")
+	}
+
+	mode := "1777"
+	data := []string{}
+
+	if len(splitted) == 2 {
+		for _, opt := range strings.Split(splitted[1], ",") {
+			kv := strings.SplitN(opt, "=", 2)
+			key := kv[0]
+			val := ""
+			if len(kv) > 1 {
+				val = kv[1]
+			}
+
+			switch key {
+			case "ro", "rw", "exec", "noexec", "nosuid", "nodev":
+				if len(kv) > 1 {
+					return tm, fmt.Errorf("tmpfs option %s doesn't take a value", key)
+				}
+				if key == "ro" || key == "rw" {
+					tm.ReadOnly = key == "ro"
+				} else if key == "exec" || key == "noexec" {
+					tm.Exec = key == "exec"
+				}
+			case "size":
+				size, err := units.RAMInBytes(val)
+				if err != nil || size <= 0 {
+					return tm, fmt.Errorf("invalid tmpfs size %q", val)
+				}
+				data = append(data, "size="+strconv.FormatInt(size, 10))
+			case "mode":
+				if _, err := strconv.ParseUint(val, 8, 32); err != nil || len(val) > 4 {
+					return tm, fmt.Errorf("invalid tmpfs mode %q: must be an octal mode", val)
+				}
+				mode = val
+			case "uid", "gid":
+				if _, err := strconv.ParseUint(val, 10, 32); err != nil {
+					return tm, fmt.Errorf("invalid tmpfs %s %q", key, val)
+				}
+				data = append(data, key+"="+val)
+			case "nr_inodes":
+				n, err := units.RAMInBytes(val)
+				if err != nil || n <= 0 {
+					return tm, fmt.Errorf("invalid tmpfs nr_inodes %q", val)
+				}
+				data = append(data, "nr_inodes="+strconv.FormatInt(n, 10))
+			case "suid", "dev":
+				return tm, fmt.Errorf("tmpfs option %s is not supported, tmpfs mounts are always nosuid and nodev", key)
+			case "":
+				return tm, fmt.Errorf("empty tmpfs option in %q", tmpfs)
+			default:
+				return tm, fmt.Errorf("invalid tmpfs option %q", key)
+			}
+		}
+	}
+
+	tm.Data = strings.Join(append([]string{"mode=" + mode}, data...), ",")
+
+	return tm, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2022, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTmpfsString(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpfs   string
+		want    TmpfsMount
+		wantErr bool
+	}{
+		{
+			name:  "destinationOnly",
+			tmpfs: "/run",
+			want:  TmpfsMount{Destination: "/run", Data: "mode=1777"},
+		},
+		{
+			name:  "sizeAndMode",
+			tmpfs: "/scratch/:size=64m,mode=0755",
+			want:  TmpfsMount{Destination: "/scratch", Data: "mode=0755,size=67108864"},
+		},
+		{
+			name:  "dockerOptions",
+			tmpfs: "/run:rw,noexec,nosuid,nodev,size=65536k",
+			want:  TmpfsMount{Destination: "/run", Data: "mode=1777,size=67108864"},
+		},
+		{
+			name:  "flags",
+			tmpfs: "/data:ro,exec,uid=1000,gid=1000,nr_inodes=1k",
+			want:  TmpfsMount{Destination: "/data", Data: "mode=1777,uid=1000,gid=1000,nr_inodes=1024", ReadOnly: true, Exec: true},
+		},
+		{
+			name:    "relativeDestination",
+			tmpfs:   "run:size=64m",
+			wantErr: true,
+		},
+		{
+			name:    "rootDestination",
+			tmpfs:   "/:size=64m",
+			wantErr: true,
+		},
+		{
+			name:    "invalidSize",
+			tmpfs:   "/run:size=lots",
+			wantErr: true,
+		},
+		{
+			name:    "invalidMode",
+			tmpfs:   "/run:mode=999",
+			wantErr: true,
+		},
+		{
+			name:    "invalidUID",
+			tmpfs:   "/run:uid=root",
+			wantErr: true,
+		},
+		{
+			name:    "suid",
+			tmpfs:   "/run:suid",
+			wantErr: true,
+		},
+		{
+			name:    "flagWithValue",
+			tmpfs:   "/run:ro=true",
+			wantErr: true,
+		},
+		{
+			name:    "emptyOption",
+			tmpfs:   "/run:size=64m,,ro",
+			wantErr: true,
+		},
+		{
+			name:    "invalidOption",
+			tmpfs:   "/run:color=turquoise",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTmpfsString(tt.tmpfs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTmpfsString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTmpfsString() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -367,6 +367,25 @@ func (keyring *Handle) appendPrivateKey(e *openpgp.Entity) error {
 	return storePrivKeys(f, openpgp.EntityList{e})
 }
 
+// storePrivKeyring overwrites the private keyring with the listed keys
+func (keyring *Handle) storePrivKeyring(keys openpgp.EntityList) error {
+	if keyring.global {
+		return fmt.Errorf("global keyring can't contain private keys")
+	}
+
+	f, err := createOrTruncateFile(keyring.SecretPath(), 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := storePrivKeys(f, keys); err != nil {
+		return fmt.Errorf("could not store private key: %s", err)
+	}
+
+	return nil
+}
+
 // storePubKeys writes all the public keys in list to the writer w.
 func storePubKeys(w io.Writer, list openpgp.EntityList) error {
 	for _, e := range list {
@@ -937,19 +956,25 @@ func RecryptKey(k *openpgp.Entity, passphrase []byte) error {
 	return k.PrivateKey.Encrypt(passphrase)
 }
 
-// ExportPrivateKey Will export a private key into a file (kpath).
-func (keyring *Handle) ExportPrivateKey(kpath string, armor bool) error {
+// ExportPrivateKey Will export a private key into a file (kpath). The key
+// matching fingerprint is exported, or the key is selected interactively if
+// fingerprint is empty. An existing file is only overwritten if force is true.
+func (keyring *Handle) ExportPrivateKey(kpath, fingerprint string, armor, force bool) error {
 	if err := keyring.PathsCheck(); err != nil {
 		return err
 	}
 
+	if err := checkExportFile(kpath, force); err != nil {
+		return err
+	}
+
 	localEntityList, err := loadKeyring(keyring.SecretPath())
 	if err != nil {
 		return fmt.Errorf("unable to load private keyring: %v", err)
 	}
 
 	// Get a entity to export
-	entityToExport, err := SelectPrivKey(localEntityList)
+	entityToExport, err := selectKey(localEntityList, fingerprint, SelectPrivKey)
 	if err != nil {
 		return err
 	}
@@ -966,7 +991,7 @@ func (keyring *Handle) ExportPrivateKey(kpath string, armor bool) error {
 	}
 
 	// Create the file that we will be exporting to
-	file, err := os.Create(kpath)
+	file, err := createExportFile(kpath, 0o600, force)
 	if err != nil {
 		return err
 	}
@@ -992,23 +1017,29 @@ func (keyring *Handle) ExportPrivateKey(kpath string, armor bool) error {
 	return nil
 }
 
-// ExportPubKey Will export a public key into a file (kpath).
-func (keyring *Handle) ExportPubKey(kpath string, armor bool) error {
+// ExportPubKey Will export a public key into a file (kpath). The key
+// matching fingerprint is exported, or the key is selected interactively if
+// fingerprint is empty. An existing file is only overwritten if force is true.
+func (keyring *Handle) ExportPubKey(kpath, fingerprint string, armor, force bool) error {
 	if err := keyring.PathsCheck(); err != nil {
 		return err
 	}
 
+	if err := checkExportFile(kpath, force); err != nil {
+		return err
+	}
+
 	localEntityList, err := loadKeyring(keyring.PublicPath())
 	if err != nil {
 		return fmt.Errorf("unable to open local keyring: %v", err)
 	}
 
-	entityToExport, err := selectPubKey(localEntityList)
+	entityToExport, err := selectKey(localEntityList, fingerprint, selectPubKey)
 	if err != nil {
 		return err
 	}
 
-	file, err := os.Create(kpath)
+	file, err := createExportFile(kpath, 0o644, force)
 	if err != nil {
 		return fmt.Errorf("unable to create file: %v", err)
 	}
@@ -1030,6 +1061,59 @@ func (keyring *Handle) ExportPubKey(kpath string, armor bool) error {
 	return nil
 }
 
+// NormalizeFingerprint checks that fingerprint is a valid key fingerprint,
+// made of 40 hexadecimal characters optionally prefixed by 0x, and returns
+// it upper cased without prefix.
+func NormalizeFingerprint(fingerprint string) (string, error) {
+	fp := strings.ToUpper(strings.TrimPrefix(fingerprint, "0x"))
+	if len(fp) != 40 {
+		return "", fmt.Errorf("invalid fingerprint %q: must be 40 hexadecimal characters", fingerprint)
+	}
+	if _, err := hex.DecodeString(fp); err != nil {
+		return "", fmt.Errorf("invalid fingerprint %q: must be 40 hexadecimal characters", fingerprint)
+	}
+	return fp, nil
+}
+
+// selectKey returns the key matching fingerprint from el, or the key
+// chosen with the interactive selection function if fingerprint is empty.
+func selectKey(el openpgp.EntityList, fingerprint string, selectFn func(openpgp.EntityList) (*openpgp.Entity, error)) (*openpgp.Entity, error) {
+	if fingerprint == "" {
+		return selectFn(el)
+	}
+
+	fp, err := NormalizeFingerprint(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if e := findKeyByFingerprint(el, fp); e != nil {
+		return e, nil
+	}
+	return nil, fmt.Errorf("no key matching fingerprint %s found", fp)
+}
+
+// checkExportFile returns an error wrapping os.ErrExist if kpath exists
+// and force is false.
+func checkExportFile(kpath string, force bool) error {
+	if force {
+		return nil
+	}
+	if _, err := os.Lstat(kpath); err == nil {
+		return fmt.Errorf("%s: %w", kpath, os.ErrExist)
+	}
+	return nil
+}
+
+// createExportFile creates the file kpath with mode, an existing file is
+// truncated if force is true.
+func createExportFile(kpath string, mode os.FileMode, force bool) (*os.File, error) {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	return os.OpenFile(kpath, flags, mode)
+}
+
 func findEntityByFingerprint(entities openpgp.EntityList, fingerprint []byte) *openpgp.Entity {
 	for _, entity := range entities {
 		if bytes.Equal(entity.PrimaryKey.Fingerprint, fingerprint) {
@@ -1041,8 +1125,9 @@ func findEntityByFingerprint(entities openpgp.EntityList, fingerprint []byte) *o
 }
 
 // importPrivateKey imports the specified openpgp Entity, which should
-// represent a private key. The entity is added to the private keyring.
-func (keyring *Handle) importPrivateKey(entity *openpgp.Entity, setNewPassword bool) error {
+// represent a private key. The entity is added to the private keyring,
+// replacing a key with the same fingerprint if force is true.
+func (keyring *Handle) importPrivateKey(entity *openpgp.Entity, setNewPassword, force bool) error {
 	if entity.PrivateKey == nil {
 		return fmt.Errorf("corrupted key, unable to recover data")
 	}
@@ -1053,7 +1138,8 @@ func (keyring *Handle) importPrivateKey(entity *openpgp.Entity, setNewPassword b
 		return err
 	}
 
-	if findEntityByFingerprint(privateEntityList, entity.PrimaryKey.Fingerprint) != nil {
+	exists := findEntityByFingerprint(privateEntityList, entity.PrimaryKey.Fingerprint) != nil
+	if exists && !force {
 		return &KeyExistsError{fingerprint: entity.PrivateKey.Fingerprint}
 	}
 
@@ -1084,13 +1170,19 @@ func (keyring *Handle) importPrivateKey(entity *openpgp.Entity, setNewPassword b
 		}
 	}
 
+	if exists {
+		fp := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+		return keyring.storePrivKeyring(append(removeKey(privateEntityList, fp), &newEntity))
+	}
+
 	// Store the private key
 	return keyring.appendPrivateKey(&newEntity)
 }
 
 // importPublicKey imports the specified openpgp Entity, which should
-// represent a public key. The entity is added to the public keyring.
-func (keyring *Handle) importPublicKey(entity *openpgp.Entity) error {
+// represent a public key. The entity is added to the public keyring,
+// replacing a key with the same fingerprint if force is true.
+func (keyring *Handle) importPublicKey(entity *openpgp.Entity, force bool) error {
 	// Load the local public keys as entitylist
 	publicEntityList, err := keyring.LoadPubKeyring()
 	if err != nil {
@@ -1098,7 +1190,11 @@ func (keyring *Handle) importPublicKey(entity *openpgp.Entity) error {
 	}
 
 	if findEntityByFingerprint(publicEntityList, entity.PrimaryKey.Fingerprint) != nil {
-		return &KeyExistsError{fingerprint: entity.PrimaryKey.Fingerprint}
+		if !force {
+			return &KeyExistsError{fingerprint: entity.PrimaryKey.Fingerprint}
+		}
+		fp := fmt.Sprintf("%X", entity.PrimaryKey.Fingerprint)
+		return keyring.storePubKeyring(append(removeKey(publicEntityList, fp), entity))
 	}
 
 	return keyring.appendPubKey(entity)
@@ -1106,8 +1202,9 @@ func (keyring *Handle) importPublicKey(entity *openpgp.Entity) error {
 
 // ImportKey imports one or more keys from the specified file. The keys
 // can be either a public or private keys, and the file can be either in
-// binary or ascii-armored format.
-func (keyring *Handle) ImportKey(kpath string, setNewPassword bool) error {
+// binary or ascii-armored format. Keys already present in the keyring
+// are replaced if force is true.
+func (keyring *Handle) ImportKey(kpath string, setNewPassword, force bool) error {
 	// Load the private key as an entitylist
 	pathEntityList, err := loadKeysFromFile(kpath)
 	if err != nil {
@@ -1117,7 +1214,7 @@ func (keyring *Handle) ImportKey(kpath string, setNewPassword bool) error {
 	for _, pathEntity := range pathEntityList {
 		if pathEntity.PrivateKey != nil {
 			// We have a private key
-			err := keyring.importPrivateKey(pathEntity, setNewPassword)
+			err := keyring.importPrivateKey(pathEntity, setNewPassword, force)
 			if err != nil {
 				return err
 			}
@@ -1130,8 +1227,14 @@ func (keyring *Handle) ImportKey(kpath string, setNewPassword bool) error {
 		// both a private and public keys
 		if pathEntity.PrimaryKey != nil {
 			// We have a public key
-			err := keyring.importPublicKey(pathEntity)
-			if err != nil {
+			err := keyring.importPublicKey(pathEntity, force)
+			// the public key of an imported private key may have
+			// been imported previously
+			var kerr *KeyExistsError
+			if errors.As(err, &kerr) && pathEntity.PrivateKey != nil {
+				sylog.Verbosef("Public key with fingerprint %X already belongs to the public keyring", pathEntity.PrimaryKey.Fingerprint)
+				continue
+			} else if err != nil {
 				return err
 			}
 
@@ -10,12 +10,15 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ProtonMail/go-crypto/openpgp"
@@ -812,12 +815,104 @@ func TestGlobalKeyRing(t *testing.T) {
 
 	err = keyring.importPublicKey(&openpgp.Entity{
 		PrimaryKey: getPublicKey(rsaPkDataHex),
-	})
+	}, false)
 	if err != nil {
 		t.Errorf("unexpected error while importing public key into global keyring: %s", err)
 	}
 }
 
+func TestNormalizeFingerprint(t *testing.T) {
+	tests := []struct {
+		fingerprint string
+		want        string
+		wantErr     bool
+	}{
+		{fingerprint: "8883491f4268f173c6e5dc49edece4f3f38d871e", want: "8883491F4268F173C6E5DC49EDECE4F3F38D871E"},
+		{fingerprint: "0x8883491F4268F173C6E5DC49EDECE4F3F38D871E", want: "8883491F4268F173C6E5DC49EDECE4F3F38D871E"},
+		{fingerprint: "8883491F4268F173", wantErr: true},
+		{fingerprint: "Z883491F4268F173C6E5DC49EDECE4F3F38D871E", wantErr: true},
+		{fingerprint: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := NormalizeFingerprint(tt.fingerprint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NormalizeFingerprint(%q) error = %v, wantErr %v", tt.fingerprint, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("NormalizeFingerprint(%q) = %q, want %q", tt.fingerprint, got, tt.want)
+		}
+	}
+}
+
+func TestExportImportKey(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	dir, err := ioutil.TempDir("", "export-import-keyring-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyring := NewHandle(filepath.Join(dir, "src"))
+	e, err := keyring.GenKeyPair(GenKeyPairOptions{Name: "test", Email: "test@test.com", KeyLength: 2048})
+	if err != nil {
+		t.Fatalf("failed to generate key pair: %s", err)
+	}
+	fp := fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)
+
+	pubPath := filepath.Join(dir, "public.asc")
+	if err := keyring.ExportPubKey(pubPath, fp, true, false); err != nil {
+		t.Fatalf("unexpected error while exporting public key: %s", err)
+	}
+	if err := keyring.ExportPubKey(pubPath, fp, true, false); !errors.Is(err, os.ErrExist) {
+		t.Errorf("unexpected error while exporting public key to an existing file: %v", err)
+	}
+	if err := keyring.ExportPubKey(pubPath, fp, true, true); err != nil {
+		t.Errorf("unexpected error while forcing public key export: %s", err)
+	}
+	if err := keyring.ExportPubKey(filepath.Join(dir, "none.asc"), strings.Repeat("0", 40), true, false); err == nil {
+		t.Errorf("unexpected success while exporting a non existent public key")
+	}
+
+	secretPath := filepath.Join(dir, "secret.gpg")
+	if err := keyring.ExportPrivateKey(secretPath, "0x"+strings.ToLower(fp), false, false); err != nil {
+		t.Fatalf("unexpected error while exporting private key: %s", err)
+	}
+
+	dst := NewHandle(filepath.Join(dir, "dst"))
+	for _, path := range []string{pubPath, secretPath} {
+		if err := dst.ImportKey(path, false, false); err != nil {
+			t.Fatalf("unexpected error while importing %s: %s", path, err)
+		}
+	}
+	var kerr *KeyExistsError
+	if err := dst.ImportKey(secretPath, false, false); !errors.As(err, &kerr) {
+		t.Errorf("unexpected error while importing an existing key: %v", err)
+	}
+	if err := dst.ImportKey(secretPath, false, true); err != nil {
+		t.Fatalf("unexpected error while forcing key import: %s", err)
+	}
+
+	for name, load := range map[string]func() (openpgp.EntityList, error){
+		"public":  dst.LoadPubKeyring,
+		"private": dst.LoadPrivKeyring,
+	} {
+		el, err := load()
+		if err != nil {
+			t.Fatalf("failed to load %s keyring: %s", name, err)
+		}
+		if len(el) != 1 || findKeyByFingerprint(el, fp) == nil {
+			t.Errorf("unexpected %s keyring content after import: %d keys", name, len(el))
+		}
+	}
+}
+
 func TestMain(m *testing.M) {
 	// Set TZ to UTC so that the code converting a time.Time value
 	// to a string produces consistent output.
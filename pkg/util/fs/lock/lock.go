@@ -27,6 +27,21 @@ func Exclusive(path string) (fd int, err error) {
 	return fd, nil
 }
 
+// Shared applies a shared lock on path, which can be held by several
+// processes at once but not along with an exclusive lock
+func Shared(path string) (fd int, err error) {
+	fd, err = unix.Open(path, os.O_RDONLY, 0)
+	if err != nil {
+		return fd, err
+	}
+	err = unix.Flock(fd, unix.LOCK_SH)
+	if err != nil {
+		unix.Close(fd)
+		return fd, err
+	}
+	return fd, nil
+}
+
 // Release removes a lock on path referenced by fd
 func Release(fd int) error {
 	defer unix.Close(fd)
@@ -45,6 +45,53 @@ func TestExclusive(t *testing.T) {
 	}
 }
 
+func TestShared(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	if _, err := Shared(""); err == nil {
+		t.Errorf("unexpected success with empty path")
+	}
+
+	// create the temporary test file used for locking
+	f, err := ioutil.TempFile("", "shared-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	fd, err := Shared(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a shared lock can be acquired along with another shared lock
+	fd2, err := Shared(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	Release(fd2)
+
+	// but not along with an exclusive lock
+	ch := make(chan bool, 1)
+	go func() {
+		efd, err := Exclusive(f.Name())
+		if err == nil {
+			Release(efd)
+		}
+		ch <- true
+	}()
+
+	select {
+	case <-time.After(1 * time.Second):
+		Release(fd)
+		<-ch
+	case <-ch:
+		t.Errorf("exclusive lock acquired")
+	}
+}
+
 func TestByteRange(t *testing.T) {
 	test.DropPrivilege(t)
 	defer test.ResetPrivilege(t)
@@ -7,6 +7,9 @@ package rlimit
 
 import (
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"syscall"
 )
 
@@ -68,3 +71,50 @@ func Get(res string) (cur uint64, max uint64, err error) {
 
 	return
 }
+
+// Parse parses a docker style ulimit specification of the form
+// name=soft[:hard], e.g. nofile=1024:4096, and returns the
+// corresponding resource type along with soft and hard limits.
+// The name is the lower case resource name without the RLIMIT_
+// prefix and limits can be set to unlimited. When the hard limit
+// is omitted it takes the soft limit value.
+func Parse(ulimit string) (res string, cur uint64, max uint64, err error) {
+	kv := strings.SplitN(ulimit, "=", 2)
+	if len(kv) != 2 || kv[1] == "" {
+		err = fmt.Errorf("%q is not of the form name=soft[:hard]", ulimit)
+		return
+	}
+
+	res = "RLIMIT_" + strings.ToUpper(kv[0])
+	if _, ok := resource[res]; !ok {
+		err = fmt.Errorf("%s is not a valid resource limit name", kv[0])
+		return
+	}
+
+	limits := strings.SplitN(kv[1], ":", 2)
+	if cur, err = parseLimit(limits[0]); err != nil {
+		return
+	}
+	max = cur
+	if len(limits) == 2 {
+		if max, err = parseLimit(limits[1]); err != nil {
+			return
+		}
+	}
+	if cur > max {
+		err = fmt.Errorf("soft limit %s is greater than hard limit %s", limits[0], limits[1])
+	}
+
+	return
+}
+
+func parseLimit(limit string) (uint64, error) {
+	if limit == "unlimited" || limit == "-1" {
+		return math.MaxUint64, nil
+	}
+	v, err := strconv.ParseUint(limit, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid limit value %q", limit)
+	}
+	return v, nil
+}
@@ -6,6 +6,7 @@
 package rlimit
 
 import (
+	"math"
 	"testing"
 
 	"github.com/sylabs/singularity/internal/pkg/test"
@@ -39,3 +40,40 @@ func TestGetSet(t *testing.T) {
 		t.Errorf("resource limit RLIMIT_FAKE doesn't exist")
 	}
 }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		ulimit  string
+		res     string
+		cur     uint64
+		max     uint64
+		wantErr bool
+	}{
+		{"SoftHard", "nofile=1024:4096", "RLIMIT_NOFILE", 1024, 4096, false},
+		{"SoftOnly", "core=0", "RLIMIT_CORE", 0, 0, false},
+		{"Unlimited", "memlock=unlimited:unlimited", "RLIMIT_MEMLOCK", math.MaxUint64, math.MaxUint64, false},
+		{"SoftUnlimited", "stack=-1", "RLIMIT_STACK", math.MaxUint64, math.MaxUint64, false},
+		{"UnknownName", "fake=1", "", 0, 0, true},
+		{"NoValue", "nofile", "", 0, 0, true},
+		{"EmptyValue", "nofile=", "", 0, 0, true},
+		{"BadValue", "nofile=abc", "", 0, 0, true},
+		{"BadHard", "nofile=1:abc", "", 0, 0, true},
+		{"SoftGreaterThanHard", "nofile=4096:1024", "", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, cur, max, err := Parse(tt.ulimit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tt.ulimit, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if res != tt.res || cur != tt.cur || max != tt.max {
+				t.Errorf("Parse(%q) = %s %d:%d, want %s %d:%d", tt.ulimit, res, cur, max, tt.res, tt.cur, tt.max)
+			}
+		})
+	}
+}
@@ -74,6 +74,10 @@ type File struct {
 	DownloadPartSize        uint     `default:"5242880" directive:"download part size"`
 	DownloadBufferSize      uint     `default:"32768" directive:"download buffer size"`
 	SystemdCgroups          bool     `default:"yes" authorized:"yes,no" directive:"systemd cgroups"`
+	RewritePath             string   `default:"append" authorized:"append,prepend,image-only" directive:"rewrite path"`
+	RequireSignedPull       bool     `default:"no" authorized:"yes,no" directive:"require signed pull"`
+	RegistriesConfPath      string   `directive:"registries conf path"`
+	ShubMirror              string   `directive:"shub mirror"`
 }
 
 const TemplateAsset = `# SINGULARITY.CONF
@@ -470,4 +474,46 @@ download buffer size = {{ .DownloadBufferSize }}
 # Whether to use systemd to manage container cgroups. Required for rootless cgroups
 # functionality. 'no' will manage cgroups directly via cgroupfs.
 systemd cgroups = {{ if eq .SystemdCgroups true }}yes{{ else }}no{{ end }}
+
+# REWRITE PATH: [append/prepend/image-only]
+# DEFAULT: append
+# This option controls how the default PATH used by Singularity
+# (/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin) is combined
+# with the PATH defined by the container environment. With 'append', the
+# default PATH entries missing from the container PATH are appended to it.
+# With 'prepend', they are prepended to it, so binaries from the default
+# locations are found first. With 'image-only', the container PATH is used as
+# is, the default PATH is only used if the container doesn't define a PATH.
+# PATH modifications requested with SINGULARITYENV_PATH,
+# SINGULARITYENV_PREPEND_PATH and SINGULARITYENV_APPEND_PATH are applied
+# afterward. This can be overridden with the --rewrite-path option.
+rewrite path = {{ .RewritePath }}
+
+# REQUIRE SIGNED PULL: [BOOL]
+# DEFAULT: no
+# Whether images pulled from a library:// URI must be signed by a key present
+# in the local or global public keyring. When enabled, an image failing
+# verification is deleted right after the pull. This can't be overridden by
+# users with the pull --allow-unsigned option.
+require signed pull = {{ if eq .RequireSignedPull true }}yes{{ else }}no{{ end }}
+
+# REGISTRIES CONF PATH: [STRING]
+# DEFAULT: Undefined
+# Path to a containers registries.conf file used when pulling or building from
+# docker:// and other OCI registry images, in place of the default
+# $HOME/.config/containers/registries.conf and /etc/containers/registries.conf
+# files. Registry mirrors defined in [[registry.mirror]] tables of this file
+# are tried first, before falling back to the original registry, so that e.g.
+# docker://alpine can be transparently fetched from a docker.io proxy-cache.
+# registries conf path =
+{{ if ne .RegistriesConfPath "" }}registries conf path = {{ .RegistriesConfPath }}{{ end }}
+
+# SHUB MIRROR: [STRING]
+# DEFAULT: Undefined
+# Base URL of a Singularity Hub compatible endpoint, e.g.
+# https://shub.example.org, used as a fallback for shub:// images that don't
+# specify a registry when their manifest can't be retrieved from
+# singularity-hub.org.
+# shub mirror =
+{{ if ne .ShubMirror "" }}shub mirror = {{ .ShubMirror }}{{ end }}
 `